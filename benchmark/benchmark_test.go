@@ -3,7 +3,10 @@
 package benchmark
 
 import (
+	"bytes"
 	"encoding/json"
+	"reflect"
+	"runtime"
 	"testing"
 
 	"google.golang.org/protobuf/proto"
@@ -883,6 +886,37 @@ func BenchmarkDocument_Cramberry_Decode(b *testing.B) {
 	}
 }
 
+// BenchmarkDocument_Cramberry_EncodeAdaptive compares Document encoding
+// with and without adaptive writer sizing when the writer pool has been
+// emptied by garbage collection, as it regularly is in a busy server. Each
+// op encodes a burst of 16 Documents after the pool is emptied.
+func BenchmarkDocument_Cramberry_EncodeAdaptive(b *testing.B) {
+	msg := makeCramberryDocument()
+	for _, adaptive := range []bool{false, true} {
+		name := "Static"
+		if adaptive {
+			name = "Adaptive"
+		}
+		b.Run(name, func(b *testing.B) {
+			cramberry.EnableAdaptiveWriterSizing(adaptive)
+			defer cramberry.EnableAdaptiveWriterSizing(false)
+			_, _ = msg.MarshalCramberry() // seed the average
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				// The pool keeps its writers through one collection
+				runtime.GC()
+				runtime.GC()
+				b.StartTimer()
+				for range 16 {
+					_, _ = msg.MarshalCramberry()
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkDocument_Protobuf_Encode(b *testing.B) {
 	msg := makeProtobufDocument()
 	b.ResetTimer()
@@ -1011,6 +1045,19 @@ func BenchmarkBatch100_Cramberry_Decode(b *testing.B) {
 	}
 }
 
+// BenchmarkBatchItems100_Cramberry_Decode decodes only the []SmallMessage
+// field, so allocations beyond the slice itself come from element strings.
+func BenchmarkBatchItems100_Cramberry_Decode(b *testing.B) {
+	msg := &cramgen.BatchRequest{Items: makeCramberryBatchRequest(100).Items}
+	data, _ := msg.MarshalCramberry()
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var result cramgen.BatchRequest
+		_ = result.UnmarshalCramberry(data)
+	}
+}
+
 func BenchmarkBatch100_Protobuf_Encode(b *testing.B) {
 	msg := makeProtobufBatchRequest(100)
 	b.ResetTimer()
@@ -1262,6 +1309,107 @@ func BenchmarkBatch1000_Reflection_Decode(b *testing.B) {
 // Size Comparison Tests
 // ============================================================================
 
+func TestSizeMatchesMarshal(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"SmallMessage", makeCramberrySmallMessage()},
+		{"Person", makeCramberryPerson()},
+		{"Document", makeCramberryDocument()},
+		{"Event", makeCramberryEvent()},
+		{"Batch100", makeCramberryBatchRequest(100)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := cramberry.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if size := cramberry.Size(tt.value); size != len(data) {
+				t.Errorf("Size() = %d, but Marshal produced %d bytes", size, len(data))
+			}
+		})
+	}
+}
+
+// generatedMessage is implemented by every generated benchmark type.
+type generatedMessage interface {
+	MarshalCramberry() ([]byte, error)
+	UnmarshalCramberry([]byte) error
+}
+
+// TestReflectionMatchesGenerated checks that the reflection-based API and
+// the generated methods agree on the wire for every benchmark type, so the
+// Reflection benchmarks measure the same work as the Cramberry ones.
+func TestReflectionMatchesGenerated(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  generatedMessage
+	}{
+		{"SmallMessage", makeCramberrySmallMessage()},
+		{"Point", makeCramberryPoint()},
+		{"Timestamp", makeCramberryTimestamp()},
+		{"Metrics", makeCramberryMetrics()},
+		{"Address", makeCramberryAddress()},
+		{"ContactInfo", makeCramberryContactInfo()},
+		{"Person", makeCramberryPerson()},
+		{"Document", makeCramberryDocument()},
+		{"Event", makeCramberryEvent()},
+		{"Batch100", makeCramberryBatchRequest(100)},
+	}
+	// Zero values exercise which empty fields each side leaves out
+	for _, msg := range []generatedMessage{
+		&cramgen.Point{}, &cramgen.Timestamp{}, &cramgen.Duration{}, &cramgen.Metrics{},
+		&cramgen.SmallMessage{}, &cramgen.Address{}, &cramgen.ContactInfo{}, &cramgen.Person{},
+		&cramgen.Organization{}, &cramgen.Tag{}, &cramgen.Attachment{}, &cramgen.Comment{},
+		&cramgen.Document{}, &cramgen.EventSource{}, &cramgen.Event{}, &cramgen.LogEntry{},
+		&cramgen.UserProfile{}, &cramgen.BatchRequest{}, &cramgen.BatchResponse{},
+	} {
+		tests = append(tests, struct {
+			name string
+			msg  generatedMessage
+		}{"Zero" + reflect.TypeOf(msg).Elem().Name(), msg})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generated, err := tt.msg.MarshalCramberry()
+			if err != nil {
+				t.Fatalf("MarshalCramberry failed: %v", err)
+			}
+			reflected, err := cramberry.Marshal(tt.msg)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if !bytes.Equal(generated, reflected) {
+				t.Fatalf("encodings differ\ngenerated: %x\nreflected: %x", generated, reflected)
+			}
+			if size := cramberry.Size(tt.msg); size != len(generated) {
+				t.Errorf("Size() = %d, want %d", size, len(generated))
+			}
+
+			// Each side must decode what the other wrote
+			msgType := reflect.TypeOf(tt.msg).Elem()
+			got := reflect.New(msgType).Interface().(generatedMessage)
+			if err := got.UnmarshalCramberry(reflected); err != nil {
+				t.Fatalf("UnmarshalCramberry failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.msg) {
+				t.Errorf("UnmarshalCramberry = %+v, want %+v", got, tt.msg)
+			}
+			got = reflect.New(msgType).Interface().(generatedMessage)
+			if err := cramberry.Unmarshal(generated, got); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.msg) {
+				t.Errorf("Unmarshal = %+v, want %+v", got, tt.msg)
+			}
+		})
+	}
+}
+
 func TestEncodedSizes(t *testing.T) {
 	tests := []struct {
 		name string
@@ -1,5 +1,5 @@
 // Code generated by cramberry. DO NOT EDIT.
-// Source: /Volumes/Tendermint/stealth/cramberry/benchmark/schemas/messages.cram
+// Source: benchmark/schemas/messages.cram
 
 package benchmark
 
@@ -219,6 +219,13 @@ func (m *Point) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Point) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *Point) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -235,6 +242,7 @@ func (m *Point) DecodeFrom(r *cramberry.Reader) {
 			m.Z = r.ReadFloat64()
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Point")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -284,6 +292,13 @@ func (m *Timestamp) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Timestamp) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *Timestamp) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -298,6 +313,7 @@ func (m *Timestamp) DecodeFrom(r *cramberry.Reader) {
 			m.Nanos = r.ReadInt32()
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Timestamp")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -347,6 +363,13 @@ func (m *Duration) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Duration) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *Duration) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -361,6 +384,7 @@ func (m *Duration) DecodeFrom(r *cramberry.Reader) {
 			m.Nanos = r.ReadInt32()
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Duration")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -450,6 +474,13 @@ func (m *Metrics) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Metrics) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *Metrics) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -480,6 +511,7 @@ func (m *Metrics) DecodeFrom(r *cramberry.Reader) {
 			m.ErrorCount = r.ReadInt64()
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Metrics")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -534,6 +566,13 @@ func (m *SmallMessage) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *SmallMessage) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *SmallMessage) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -550,6 +589,7 @@ func (m *SmallMessage) DecodeFrom(r *cramberry.Reader) {
 			m.Active = r.ReadBool()
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of SmallMessage")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -624,6 +664,13 @@ func (m *Address) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Address) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *Address) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -652,6 +699,7 @@ func (m *Address) DecodeFrom(r *cramberry.Reader) {
 			m.Coordinates = &tmp
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Address")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -721,6 +769,13 @@ func (m *ContactInfo) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *ContactInfo) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *ContactInfo) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -753,6 +808,7 @@ func (m *ContactInfo) DecodeFrom(r *cramberry.Reader) {
 			m.BillingAddress = &tmp
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of ContactInfo")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -812,8 +868,10 @@ func (m *Person) EncodeTo(w *cramberry.Writer) {
 	}
 	w.WriteCompactTag(6, cramberry.WireTypeV2Bytes)
 	m.Contact.EncodeTo(w)
-	w.WriteCompactTag(7, cramberry.WireTypeV2Bytes)
-	m.Status.EncodeTo(w)
+	if m.Status != 0 {
+		w.WriteCompactTag(7, cramberry.WireTypeV2SVarint)
+		m.Status.EncodeTo(w)
+	}
 	w.WriteCompactTag(8, cramberry.WireTypeV2Bytes)
 	m.CreatedAt.EncodeTo(w)
 	if m.UpdatedAt != nil {
@@ -831,6 +889,13 @@ func (m *Person) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Person) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *Person) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -865,6 +930,7 @@ func (m *Person) DecodeFrom(r *cramberry.Reader) {
 			m.UpdatedAt = &tmp
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Person")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -922,8 +988,10 @@ func (m *Organization) EncodeTo(w *cramberry.Writer) {
 	m.Headquarters.EncodeTo(w)
 	w.WriteCompactTag(6, cramberry.WireTypeV2Bytes)
 	m.Contact.EncodeTo(w)
-	w.WriteCompactTag(7, cramberry.WireTypeV2Bytes)
-	m.Status.EncodeTo(w)
+	if m.Status != 0 {
+		w.WriteCompactTag(7, cramberry.WireTypeV2SVarint)
+		m.Status.EncodeTo(w)
+	}
 	w.WriteCompactTag(8, cramberry.WireTypeV2Bytes)
 	m.FoundedAt.EncodeTo(w)
 	w.WriteCompactTag(9, cramberry.WireTypeV2Bytes)
@@ -939,6 +1007,13 @@ func (m *Organization) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Organization) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *Organization) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -969,6 +1044,7 @@ func (m *Organization) DecodeFrom(r *cramberry.Reader) {
 			m.CreatedAt.DecodeFrom(r)
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Organization")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -1023,6 +1099,13 @@ func (m *Tag) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Tag) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *Tag) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -1041,6 +1124,7 @@ func (m *Tag) DecodeFrom(r *cramberry.Reader) {
 			m.Color = &tmp
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Tag")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -1113,6 +1197,13 @@ func (m *Attachment) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Attachment) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *Attachment) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -1139,6 +1230,7 @@ func (m *Attachment) DecodeFrom(r *cramberry.Reader) {
 			m.UploadedAt.DecodeFrom(r)
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Attachment")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -1209,6 +1301,13 @@ func (m *Comment) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Comment) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *Comment) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -1230,13 +1329,17 @@ func (m *Comment) DecodeFrom(r *cramberry.Reader) {
 			tmp.DecodeFrom(r)
 			m.EditedAt = &tmp
 		case 6:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.Reactions = make([]int64, n)
 			for i := 0; i < n; i++ {
 				m.Reactions[i] = r.ReadInt64()
 			}
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Comment")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -1295,10 +1398,14 @@ func (m *Document) EncodeTo(w *cramberry.Writer) {
 		w.WriteCompactTag(4, cramberry.WireTypeV2SVarint)
 		w.WriteInt64(m.AuthorId)
 	}
-	w.WriteCompactTag(5, cramberry.WireTypeV2Bytes)
-	m.Status.EncodeTo(w)
-	w.WriteCompactTag(6, cramberry.WireTypeV2Bytes)
-	m.Priority.EncodeTo(w)
+	if m.Status != 0 {
+		w.WriteCompactTag(5, cramberry.WireTypeV2SVarint)
+		m.Status.EncodeTo(w)
+	}
+	if m.Priority != 0 {
+		w.WriteCompactTag(6, cramberry.WireTypeV2SVarint)
+		m.Priority.EncodeTo(w)
+	}
 	if len(m.Tags) > 0 {
 		w.WriteCompactTag(7, cramberry.WireTypeV2Bytes)
 		w.WriteUvarint(uint64(len(m.Tags)))
@@ -1320,13 +1427,13 @@ func (m *Document) EncodeTo(w *cramberry.Writer) {
 			v.EncodeTo(w)
 		}
 	}
-	if m.Metadata != nil {
+	if len(m.Metadata) > 0 {
 		w.WriteCompactTag(10, cramberry.WireTypeV2Bytes)
 		w.WriteUvarint(uint64(len(m.Metadata)))
-		for k, v := range m.Metadata {
+		cramberry.ForEachMapEntry(w, m.Metadata, func(k string, v string) {
 			w.WriteString(k)
 			w.WriteString(v)
-		}
+		})
 	}
 	if len(m.Collaborators) > 0 {
 		w.WriteCompactTag(11, cramberry.WireTypeV2Bytes)
@@ -1356,6 +1463,13 @@ func (m *Document) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Document) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *Document) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -1377,35 +1491,50 @@ func (m *Document) DecodeFrom(r *cramberry.Reader) {
 		case 6:
 			m.Priority.DecodeFrom(r)
 		case 7:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.Tags = make([]Tag, n)
 			for i := 0; i < n; i++ {
 				m.Tags[i].DecodeFrom(r)
 			}
 		case 8:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.Attachments = make([]Attachment, n)
 			for i := 0; i < n; i++ {
 				m.Attachments[i].DecodeFrom(r)
 			}
 		case 9:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.Comments = make([]Comment, n)
 			for i := 0; i < n; i++ {
 				m.Comments[i].DecodeFrom(r)
 			}
 		case 10:
-			n := int(r.ReadUvarint())
-			m.Metadata = make(map[string]string, n)
-			for i := 0; i < n; i++ {
-				var k string
-				k = r.ReadString()
-				var v string
-				v = r.ReadString()
-				m.Metadata[k] = v
+			{
+				n := r.ReadMapHeader()
+				m.Metadata = make(map[string]string, n)
+				_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {
+					var k string
+					k = r.ReadString()
+					var v string
+					v = r.ReadString()
+					m.Metadata[k] = v
+					return nil
+				})
 			}
 		case 11:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.Collaborators = make([]int64, n)
 			for i := 0; i < n; i++ {
 				m.Collaborators[i] = r.ReadInt64()
@@ -1422,6 +1551,7 @@ func (m *Document) DecodeFrom(r *cramberry.Reader) {
 			m.PublishedAt = &tmp
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Document")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -1481,6 +1611,13 @@ func (m *EventSource) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *EventSource) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *EventSource) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -1501,6 +1638,7 @@ func (m *EventSource) DecodeFrom(r *cramberry.Reader) {
 			m.Region = &tmp
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of EventSource")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -1543,8 +1681,10 @@ func (m *Event) EncodeTo(w *cramberry.Writer) {
 		w.WriteCompactTag(1, cramberry.WireTypeV2Bytes)
 		w.WriteString(m.Id)
 	}
-	w.WriteCompactTag(2, cramberry.WireTypeV2Bytes)
-	m.Type.EncodeTo(w)
+	if m.Type != 0 {
+		w.WriteCompactTag(2, cramberry.WireTypeV2SVarint)
+		m.Type.EncodeTo(w)
+	}
 	if m.EntityType != "" {
 		w.WriteCompactTag(3, cramberry.WireTypeV2Bytes)
 		w.WriteString(m.EntityType)
@@ -1557,13 +1697,13 @@ func (m *Event) EncodeTo(w *cramberry.Writer) {
 	m.Source.EncodeTo(w)
 	w.WriteCompactTag(6, cramberry.WireTypeV2Bytes)
 	m.Timestamp.EncodeTo(w)
-	if m.Attributes != nil {
+	if len(m.Attributes) > 0 {
 		w.WriteCompactTag(7, cramberry.WireTypeV2Bytes)
 		w.WriteUvarint(uint64(len(m.Attributes)))
-		for k, v := range m.Attributes {
+		cramberry.ForEachMapEntry(w, m.Attributes, func(k string, v string) {
 			w.WriteString(k)
 			w.WriteString(v)
-		}
+		})
 	}
 	if m.Payload != nil {
 		w.WriteCompactTag(8, cramberry.WireTypeV2Bytes)
@@ -1588,6 +1728,13 @@ func (m *Event) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Event) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *Event) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -1609,14 +1756,17 @@ func (m *Event) DecodeFrom(r *cramberry.Reader) {
 		case 6:
 			m.Timestamp.DecodeFrom(r)
 		case 7:
-			n := int(r.ReadUvarint())
-			m.Attributes = make(map[string]string, n)
-			for i := 0; i < n; i++ {
-				var k string
-				k = r.ReadString()
-				var v string
-				v = r.ReadString()
-				m.Attributes[k] = v
+			{
+				n := r.ReadMapHeader()
+				m.Attributes = make(map[string]string, n)
+				_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {
+					var k string
+					k = r.ReadString()
+					var v string
+					v = r.ReadString()
+					m.Attributes[k] = v
+					return nil
+				})
 			}
 		case 8:
 			var tmp []byte
@@ -1632,6 +1782,7 @@ func (m *Event) DecodeFrom(r *cramberry.Reader) {
 			m.CausationId = &tmp
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Event")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -1685,13 +1836,13 @@ func (m *LogEntry) EncodeTo(w *cramberry.Writer) {
 	}
 	w.WriteCompactTag(5, cramberry.WireTypeV2Bytes)
 	m.Source.EncodeTo(w)
-	if m.Fields != nil {
+	if len(m.Fields) > 0 {
 		w.WriteCompactTag(6, cramberry.WireTypeV2Bytes)
 		w.WriteUvarint(uint64(len(m.Fields)))
-		for k, v := range m.Fields {
+		cramberry.ForEachMapEntry(w, m.Fields, func(k string, v string) {
 			w.WriteString(k)
 			w.WriteString(v)
-		}
+		})
 	}
 	if m.StackTrace != nil {
 		w.WriteCompactTag(7, cramberry.WireTypeV2Bytes)
@@ -1716,6 +1867,13 @@ func (m *LogEntry) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *LogEntry) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *LogEntry) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -1735,14 +1893,17 @@ func (m *LogEntry) DecodeFrom(r *cramberry.Reader) {
 		case 5:
 			m.Source.DecodeFrom(r)
 		case 6:
-			n := int(r.ReadUvarint())
-			m.Fields = make(map[string]string, n)
-			for i := 0; i < n; i++ {
-				var k string
-				k = r.ReadString()
-				var v string
-				v = r.ReadString()
-				m.Fields[k] = v
+			{
+				n := r.ReadMapHeader()
+				m.Fields = make(map[string]string, n)
+				_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {
+					var k string
+					k = r.ReadString()
+					var v string
+					v = r.ReadString()
+					m.Fields[k] = v
+					return nil
+				})
 			}
 		case 7:
 			var tmp string
@@ -1758,6 +1919,7 @@ func (m *LogEntry) DecodeFrom(r *cramberry.Reader) {
 			m.SpanId = &tmp
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of LogEntry")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -1831,8 +1993,10 @@ func (m *UserProfile) EncodeTo(w *cramberry.Writer) {
 	}
 	w.WriteCompactTag(7, cramberry.WireTypeV2Bytes)
 	m.PersonalInfo.EncodeTo(w)
-	w.WriteCompactTag(8, cramberry.WireTypeV2Bytes)
-	m.AccountStatus.EncodeTo(w)
+	if m.AccountStatus != 0 {
+		w.WriteCompactTag(8, cramberry.WireTypeV2SVarint)
+		m.AccountStatus.EncodeTo(w)
+	}
 	if len(m.Roles) > 0 {
 		w.WriteCompactTag(9, cramberry.WireTypeV2Bytes)
 		w.WriteUvarint(uint64(len(m.Roles)))
@@ -1847,21 +2011,21 @@ func (m *UserProfile) EncodeTo(w *cramberry.Writer) {
 			w.WriteString(v)
 		}
 	}
-	if m.Preferences != nil {
+	if len(m.Preferences) > 0 {
 		w.WriteCompactTag(11, cramberry.WireTypeV2Bytes)
 		w.WriteUvarint(uint64(len(m.Preferences)))
-		for k, v := range m.Preferences {
+		cramberry.ForEachMapEntry(w, m.Preferences, func(k string, v string) {
 			w.WriteString(k)
 			w.WriteString(v)
-		}
+		})
 	}
-	if m.Settings != nil {
+	if len(m.Settings) > 0 {
 		w.WriteCompactTag(12, cramberry.WireTypeV2Bytes)
 		w.WriteUvarint(uint64(len(m.Settings)))
-		for k, v := range m.Settings {
+		cramberry.ForEachMapEntry(w, m.Settings, func(k string, v string) {
 			w.WriteString(k)
 			w.WriteString(v)
-		}
+		})
 	}
 	if len(m.Organizations) > 0 {
 		w.WriteCompactTag(13, cramberry.WireTypeV2Bytes)
@@ -1905,6 +2069,13 @@ func (m *UserProfile) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *UserProfile) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *UserProfile) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -1934,51 +2105,72 @@ func (m *UserProfile) DecodeFrom(r *cramberry.Reader) {
 		case 8:
 			m.AccountStatus.DecodeFrom(r)
 		case 9:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.Roles = make([]string, n)
 			for i := 0; i < n; i++ {
 				m.Roles[i] = r.ReadString()
 			}
 		case 10:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.Permissions = make([]string, n)
 			for i := 0; i < n; i++ {
 				m.Permissions[i] = r.ReadString()
 			}
 		case 11:
-			n := int(r.ReadUvarint())
-			m.Preferences = make(map[string]string, n)
-			for i := 0; i < n; i++ {
-				var k string
-				k = r.ReadString()
-				var v string
-				v = r.ReadString()
-				m.Preferences[k] = v
+			{
+				n := r.ReadMapHeader()
+				m.Preferences = make(map[string]string, n)
+				_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {
+					var k string
+					k = r.ReadString()
+					var v string
+					v = r.ReadString()
+					m.Preferences[k] = v
+					return nil
+				})
 			}
 		case 12:
-			n := int(r.ReadUvarint())
-			m.Settings = make(map[string]string, n)
-			for i := 0; i < n; i++ {
-				var k string
-				k = r.ReadString()
-				var v string
-				v = r.ReadString()
-				m.Settings[k] = v
+			{
+				n := r.ReadMapHeader()
+				m.Settings = make(map[string]string, n)
+				_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {
+					var k string
+					k = r.ReadString()
+					var v string
+					v = r.ReadString()
+					m.Settings[k] = v
+					return nil
+				})
 			}
 		case 13:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.Organizations = make([]Organization, n)
 			for i := 0; i < n; i++ {
 				m.Organizations[i].DecodeFrom(r)
 			}
 		case 14:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.Documents = make([]Document, n)
 			for i := 0; i < n; i++ {
 				m.Documents[i].DecodeFrom(r)
 			}
 		case 15:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.RecentActivity = make([]Event, n)
 			for i := 0; i < n; i++ {
 				m.RecentActivity[i].DecodeFrom(r)
@@ -1995,6 +2187,7 @@ func (m *UserProfile) DecodeFrom(r *cramberry.Reader) {
 			m.DeletedAt = &tmp
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of UserProfile")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -2040,13 +2233,13 @@ func (m *BatchRequest) EncodeTo(w *cramberry.Writer) {
 			v.EncodeTo(w)
 		}
 	}
-	if m.Headers != nil {
+	if len(m.Headers) > 0 {
 		w.WriteCompactTag(3, cramberry.WireTypeV2Bytes)
 		w.WriteUvarint(uint64(len(m.Headers)))
-		for k, v := range m.Headers {
+		cramberry.ForEachMapEntry(w, m.Headers, func(k string, v string) {
 			w.WriteString(k)
 			w.WriteString(v)
-		}
+		})
 	}
 	w.WriteCompactTag(4, cramberry.WireTypeV2Bytes)
 	m.SubmittedAt.EncodeTo(w)
@@ -2054,8 +2247,10 @@ func (m *BatchRequest) EncodeTo(w *cramberry.Writer) {
 		w.WriteCompactTag(5, cramberry.WireTypeV2Bytes)
 		m.Timeout.EncodeTo(w)
 	}
-	w.WriteCompactTag(6, cramberry.WireTypeV2Bytes)
-	m.Priority.EncodeTo(w)
+	if m.Priority != 0 {
+		w.WriteCompactTag(6, cramberry.WireTypeV2SVarint)
+		m.Priority.EncodeTo(w)
+	}
 	w.WriteEndMarker()
 }
 
@@ -2067,6 +2262,13 @@ func (m *BatchRequest) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *BatchRequest) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *BatchRequest) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -2078,20 +2280,26 @@ func (m *BatchRequest) DecodeFrom(r *cramberry.Reader) {
 		case 1:
 			m.RequestId = r.ReadString()
 		case 2:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.Items = make([]SmallMessage, n)
 			for i := 0; i < n; i++ {
 				m.Items[i].DecodeFrom(r)
 			}
 		case 3:
-			n := int(r.ReadUvarint())
-			m.Headers = make(map[string]string, n)
-			for i := 0; i < n; i++ {
-				var k string
-				k = r.ReadString()
-				var v string
-				v = r.ReadString()
-				m.Headers[k] = v
+			{
+				n := r.ReadMapHeader()
+				m.Headers = make(map[string]string, n)
+				_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {
+					var k string
+					k = r.ReadString()
+					var v string
+					v = r.ReadString()
+					m.Headers[k] = v
+					return nil
+				})
 			}
 		case 4:
 			m.SubmittedAt.DecodeFrom(r)
@@ -2103,6 +2311,7 @@ func (m *BatchRequest) DecodeFrom(r *cramberry.Reader) {
 			m.Priority.DecodeFrom(r)
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of BatchRequest")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -2172,6 +2381,13 @@ func (m *BatchResponse) UnmarshalCramberry(data []byte) error {
 	return r.Err()
 }
 
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *BatchResponse) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
 // DecodeFrom decodes the message from the reader using V2 format.
 func (m *BatchResponse) DecodeFrom(r *cramberry.Reader) {
 	for {
@@ -2183,13 +2399,19 @@ func (m *BatchResponse) DecodeFrom(r *cramberry.Reader) {
 		case 1:
 			m.RequestId = r.ReadString()
 		case 2:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.Results = make([]SmallMessage, n)
 			for i := 0; i < n; i++ {
 				m.Results[i].DecodeFrom(r)
 			}
 		case 3:
-			n := int(r.ReadUvarint())
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
 			m.Errors = make([]string, n)
 			for i := 0; i < n; i++ {
 				m.Errors[i] = r.ReadString()
@@ -2202,6 +2424,7 @@ func (m *BatchResponse) DecodeFrom(r *cramberry.Reader) {
 			m.CompletedAt.DecodeFrom(r)
 		default:
 			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of BatchResponse")
 			r.SkipValueV2(wireType)
 		}
 		if r.Err() != nil {
@@ -3,14 +3,20 @@
 // Usage:
 //
 //	cramberry generate [options] <schema-file>...
+//	cramberry gen-fuzz [options] <schema-file>...
 //	cramberry validate <schema-file>...
 //	cramberry format <schema-file>...
+//	cramberry explain [options] <schema-file> <type>
+//	cramberry stats [options] <schema-file>
 //	cramberry schema [options] <go-package>...
+//	cramberry retag [options] <schema-file> <go-package>...
 //	cramberry version
 //
 // Generate Command:
 //
-//	Generate code from schema files.
+//	Generate code from schema files. An input file named - reads a schema
+//	from standard input; it requires -out and -package, and the output
+//	file is named after the package.
 //
 //	Options:
 //	  -lang string      Target language: go, typescript, rust (default "go")
@@ -20,15 +26,60 @@
 //	  -suffix string    Add suffix to all type names
 //	  -marshal          Generate marshal/unmarshal methods (default true)
 //	  -json             Generate JSON tags/methods (default true)
+//	  -omit-end-marker  Omit the top-level end marker (Go only)
+//	  -pools            Generate sync.Pool helpers and Reset methods (Go only)
+//	  -optional-wrappers
+//	                    Generate optional scalars as cramberry.Optional values (Go only)
+//	  -preserve-unknown Keep unknown fields when decoding and re-encode them (Go only)
+//	  -required-getters Generate GetXErr and MustGetX for required fields (Go only)
+//	  -validate-on-decode
+//	                    Validate messages with required or bounded fields in UnmarshalCramberry (Go only)
+//	  -presence-decode  Generate DecodeWithPresence methods reporting the fields present (Go only)
+//	  -pack-bools       Pack runs of bool fields into one bitmask on the wire (Go only)
+//	  -json-converters  Generate ToJSON and FromJSON map converters (Go only)
+//	  -wire string      Wire format of generated code: v2 (compact tags) or v1 (classic tags) (Go only)
+//	  -n, -dry-run      Report the files that would be generated without writing them
+//	  -I string         Add import search path (can be repeated)
+//
+// Gen-Fuzz Command:
+//
+//	Generate Go fuzz tests for the messages in schema files. Each schema
+//	produces <name>_fuzz_test.go alongside the code from 'generate'.
+//
+//	Options:
+//	  -out string       Output directory (default ".")
+//	  -package string   Override package name
+//	  -prefix string    Add prefix to all type names
+//	  -suffix string    Add suffix to all type names
 //	  -I string         Add import search path (can be repeated)
 //
 // Validate Command:
 //
-//	Validate schema files without generating code.
+//	Validate schema files without generating code. An input file named -
+//	reads a schema from standard input.
 //
 // Format Command:
 //
-//	Format schema files in place.
+//	Format schema files in place. An input file named - reads a schema
+//	from standard input and writes it to standard output.
+//
+// Explain Command:
+//
+//	Describe a message, enum or interface: its fields, wire types,
+//	estimated encoded sizes and referenced types.
+//
+//	Options:
+//	  -I string         Add import search path (can be repeated)
+//
+// Stats Command:
+//
+//	Report counts of messages, enums, interfaces and fields, the highest
+//	field number and deepest message nesting, and list large messages and
+//	unused enums.
+//
+//	Options:
+//	  -max-fields int   Report messages with more fields than this (default 50)
+//	  -I string         Add import search path (can be repeated)
 //
 // Schema Command:
 //
@@ -40,14 +91,28 @@
 //	  -private          Include unexported types
 //	  -include string   Type name pattern to include (glob, can be repeated)
 //	  -exclude string   Type name pattern to exclude (glob, can be repeated)
+//	  -include-pkg string  Package import path pattern to include (glob, can be repeated)
+//	  -exclude-pkg string  Package import path pattern to exclude (glob, can be repeated)
+//
+// Retag Command:
+//
+//	Rewrite the cramberry struct tags of Go types to match the field
+//	numbers of the schema messages with the same names, and report fields
+//	found in only one of them. Files are edited in place.
+//
+//	Options:
+//	  -I string         Add import search path (can be repeated)
 package main
 
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/blockberries/cramberry/pkg/codegen"
 	"github.com/blockberries/cramberry/pkg/cramberry"
@@ -64,12 +129,20 @@ func main() {
 	switch os.Args[1] {
 	case "generate", "gen", "g":
 		cmdGenerate(os.Args[2:])
+	case "gen-fuzz":
+		cmdGenFuzz(os.Args[2:])
 	case "validate", "val", "v":
 		cmdValidate(os.Args[2:])
 	case "format", "fmt", "f":
 		cmdFormat(os.Args[2:])
+	case "explain":
+		cmdExplain(os.Args[2:])
+	case "stats":
+		cmdStats(os.Args[2:])
 	case "schema", "extract", "s":
 		cmdSchema(os.Args[2:])
+	case "retag":
+		cmdRetag(os.Args[2:])
 	case "version":
 		cmdVersion()
 	case "help", "-h", "--help":
@@ -89,9 +162,13 @@ Usage:
 
 Commands:
   generate    Generate code from schema files
+  gen-fuzz    Generate Go fuzz tests from schema files
   validate    Validate schema files
   format      Format schema files
+  explain     Describe a type defined in a schema file
+  stats       Report schema metrics and potential concerns
   schema      Extract schema from Go source code
+  retag       Align Go struct tags with a schema
   version     Print version information
   help        Print this help message
 
@@ -136,6 +213,30 @@ func (m *importPathFlag) Set(value string) error {
 	return nil
 }
 
+// joinLanguages lists languages separated by commas.
+func joinLanguages(langs []codegen.Language) string {
+	names := make([]string, len(langs))
+	for i, lang := range langs {
+		names[i] = string(lang)
+	}
+	return strings.Join(names, ", ")
+}
+
+// checkStdinArgs exits if standard input is named more than once among
+// the input files, since it can only be read once.
+func checkStdinArgs(inputs []string) {
+	n := 0
+	for _, in := range inputs {
+		if in == schema.StdinPath {
+			n++
+		}
+	}
+	if n > 1 {
+		fmt.Fprintf(os.Stderr, "Error: %s may only be given once\n", schema.StdinPath)
+		os.Exit(1)
+	}
+}
+
 func cmdGenerate(args []string) {
 	fs := flag.NewFlagSet("generate", flag.ExitOnError)
 
@@ -146,6 +247,19 @@ func cmdGenerate(args []string) {
 	suffix := fs.String("suffix", "", "Add suffix to all type names")
 	marshal := fs.Bool("marshal", true, "Generate marshal/unmarshal methods")
 	jsonTags := fs.Bool("json", true, "Generate JSON tags/methods")
+	omitEndMarker := fs.Bool("omit-end-marker", false, "Omit the top-level end marker in MarshalCramberry (Go only)")
+	pools := fs.Bool("pools", false, "Generate sync.Pool helpers and Reset methods for messages (Go only)")
+	optionalWrappers := fs.Bool("optional-wrappers", false, "Generate optional scalar fields as cramberry.Optional values instead of pointers (Go only)")
+	preserveUnknown := fs.Bool("preserve-unknown", false, "Keep unknown fields when decoding messages and write them back when encoding (Go only)")
+	requiredGetters := fs.Bool("required-getters", false, "Generate GetXErr and MustGetX methods that fail when a required field is not set (Go only)")
+	validateOnDecode := fs.Bool("validate-on-decode", false, "Call Validate in UnmarshalCramberry for messages with required or bounded fields (Go only)")
+	presenceDecode := fs.Bool("presence-decode", false, "Generate DecodeWithPresence methods reporting the fields present on the wire (Go only)")
+	packBools := fs.Bool("pack-bools", false, "Write runs of consecutive bool fields as one varint bitmask, read only by code generated with this flag (Go only)")
+	jsonConverters := fs.Bool("json-converters", false, "Generate ToJSON and FromJSON methods converting messages to and from maps keyed by JSON name (Go only)")
+	wireFormat := fs.String("wire", string(codegen.WireFormatV2), "Wire format of generated code: v2 (compact tags) or v1 (classic tags, length-prefixed messages) (Go only)")
+	var dryRun bool
+	fs.BoolVar(&dryRun, "dry-run", false, "Report the files that would be generated without writing them")
+	fs.BoolVar(&dryRun, "n", false, "Shorthand for -dry-run")
 	var searchPaths stringSliceFlag
 	fs.Var(&searchPaths, "I", "Add import search path (can be repeated)")
 	var importPaths importPathFlag
@@ -170,11 +284,28 @@ Options:`)
 		os.Exit(1)
 	}
 
+	checkStdinArgs(fs.Args())
+	if slices.Contains(fs.Args(), schema.StdinPath) {
+		outSet := false
+		fs.Visit(func(f *flag.Flag) {
+			outSet = outSet || f.Name == "out"
+		})
+		if !outSet || *pkg == "" {
+			fmt.Fprintln(os.Stderr, "Error: generating from standard input requires -out and -package")
+			os.Exit(1)
+		}
+	}
+
+	if *wireFormat != string(codegen.WireFormatV1) && *wireFormat != string(codegen.WireFormatV2) {
+		fmt.Fprintf(os.Stderr, "Error: unsupported wire format: %s (must be v1 or v2)\n", *wireFormat)
+		os.Exit(1)
+	}
+
 	// Get generator
 	gen, ok := codegen.Get(codegen.Language(*lang))
 	if !ok {
 		fmt.Fprintf(os.Stderr, "Error: unsupported language: %s\n", *lang)
-		fmt.Fprintln(os.Stderr, "Supported languages: go")
+		fmt.Fprintf(os.Stderr, "Supported languages: %s\n", joinLanguages(codegen.Languages()))
 		os.Exit(1)
 	}
 
@@ -186,15 +317,78 @@ Options:`)
 	opts.TypeSuffix = *suffix
 	opts.GenerateMarshal = *marshal
 	opts.GenerateJSON = *jsonTags
+	opts.OmitTopLevelEndMarker = *omitEndMarker
+	opts.GeneratePools = *pools
+	opts.OptionalWrappers = *optionalWrappers
+	opts.PreserveUnknown = *preserveUnknown
+	opts.RequiredGetters = *requiredGetters
+	opts.ValidateOnDecode = *validateOnDecode
+	opts.GeneratePresenceDecode = *presenceDecode
+	opts.PackBools = *packBools
+	opts.JSONConverters = *jsonConverters
+	opts.WireFormat = codegen.WireFormat(*wireFormat)
 	opts.ImportPaths = importPaths
 
-	// Create output directory
+	// Generate all input files, writing nothing in dry-run mode
+	loader := schema.NewLoader(searchPaths...)
+	outputs, errs := codegen.GenerateFiles(gen, loader, fs.Args(), opts, dryRun)
+
+	for _, out := range outputs {
+		if dryRun {
+			fmt.Printf("Would generate: %s (%d bytes)\n", out.Path, out.Size)
+		} else {
+			fmt.Printf("Generated: %s\n", out.Path)
+		}
+	}
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
+
+func cmdGenFuzz(args []string) {
+	fs := flag.NewFlagSet("gen-fuzz", flag.ExitOnError)
+
+	outDir := fs.String("out", ".", "Output directory")
+	pkg := fs.String("package", "", "Override package name")
+	prefix := fs.String("prefix", "", "Add prefix to all type names")
+	suffix := fs.String("suffix", "", "Add suffix to all type names")
+	var searchPaths stringSliceFlag
+	fs.Var(&searchPaths, "I", "Add import search path (can be repeated)")
+
+	fs.Usage = func() {
+		fmt.Println(`Usage: cramberry gen-fuzz [options] <schema-file>...
+
+Generate Go fuzz tests that exercise the generated decoders.
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no input files")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	opts := codegen.DefaultOptions()
+	opts.Package = *pkg
+	opts.OutputPath = *outDir
+	opts.TypePrefix = *prefix
+	opts.TypeSuffix = *suffix
+
 	if err := os.MkdirAll(*outDir, 0o755); err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating output directory: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Process each input file
 	loader := schema.NewLoader(searchPaths...)
 	hasErrors := false
 
@@ -208,15 +402,10 @@ Options:`)
 			continue
 		}
 
-		// Get imported schemas for same-package detection
-		opts.ImportedSchemas = loader.GetImportedSchemas(inputFile)
-
-		// Generate output filename
 		baseName := filepath.Base(inputFile)
 		baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
-		outputFile := filepath.Join(*outDir, baseName+gen.FileExtension())
+		outputFile := filepath.Join(*outDir, baseName+"_fuzz_test.go")
 
-		// Generate code
 		f, err := os.Create(outputFile)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
@@ -224,10 +413,10 @@ Options:`)
 			continue
 		}
 
-		if err := gen.Generate(f, s, opts); err != nil {
+		if err := codegen.GenerateGoFuzz(f, s, opts); err != nil {
 			f.Close()
 			os.Remove(outputFile)
-			fmt.Fprintf(os.Stderr, "Error generating code: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error generating fuzz tests: %v\n", err)
 			hasErrors = true
 			continue
 		}
@@ -265,6 +454,8 @@ Options:`)
 		os.Exit(1)
 	}
 
+	checkStdinArgs(fs.Args())
+
 	loader := schema.NewLoader(searchPaths...)
 	hasErrors := false
 	hasWarnings := false
@@ -282,7 +473,7 @@ Options:`)
 				}
 			}
 		} else {
-			fmt.Printf("Valid: %s\n", inputFile)
+			fmt.Printf("Valid: %s\n", displayName(inputFile))
 		}
 	}
 
@@ -298,12 +489,21 @@ func cmdFormat(args []string) {
 	fs := flag.NewFlagSet("format", flag.ExitOnError)
 	write := fs.Bool("w", false, "Write result to (source) file instead of stdout")
 	diff := fs.Bool("d", false, "Display diffs instead of rewriting files")
+	gaps := fs.Bool("gaps", false, "Report gaps in the field numbering of each message")
+	renumber := fs.Bool("renumber", false, "Renumber fields to close numbering gaps (changes the wire format)")
+	confirm := fs.Bool("confirm-wire-change", false, "Confirm that -renumber may change the wire format")
 
 	fs.Usage = func() {
 		fmt.Println(`Usage: cramberry format [options] <schema-file>...
 
 Format Cramberry schema files.
 
+-renumber rewrites field numbers so that each message's fields are numbered
+1, 2, 3, ... in their current numeric order. Data encoded with the old
+numbers no longer decodes correctly, so it must be confirmed with
+-confirm-wire-change and is only safe for schemas that have never been used
+for stored or exchanged data.
+
 Options:`)
 		fs.PrintDefaults()
 	}
@@ -320,16 +520,35 @@ Options:`)
 
 	_ = diff // TODO: implement diff output
 
+	if *renumber && !*confirm {
+		fmt.Fprintln(os.Stderr, "Error: -renumber changes field numbers and therefore the wire format;")
+		fmt.Fprintln(os.Stderr, "data encoded with the old numbers will no longer decode correctly.")
+		fmt.Fprintln(os.Stderr, "Pass -confirm-wire-change to renumber anyway.")
+		os.Exit(1)
+	}
+
+	checkStdinArgs(fs.Args())
+	if *write && slices.Contains(fs.Args(), schema.StdinPath) {
+		fmt.Fprintln(os.Stderr, "Error: -w cannot be used with standard input")
+		os.Exit(1)
+	}
+
 	hasErrors := false
 	for _, inputFile := range fs.Args() {
-		content, err := os.ReadFile(inputFile)
+		var content []byte
+		var err error
+		if inputFile == schema.StdinPath {
+			content, err = io.ReadAll(os.Stdin)
+		} else {
+			content, err = os.ReadFile(inputFile)
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", inputFile, err)
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", displayName(inputFile), err)
 			hasErrors = true
 			continue
 		}
 
-		s, parseErrors := schema.ParseFile(inputFile, string(content))
+		s, parseErrors := schema.ParseFile(displayName(inputFile), string(content))
 		if len(parseErrors) > 0 {
 			for _, e := range parseErrors {
 				fmt.Fprintln(os.Stderr, e)
@@ -338,6 +557,21 @@ Options:`)
 			continue
 		}
 
+		if *gaps {
+			for _, gap := range schema.FieldNumberGaps(s) {
+				fmt.Fprintln(os.Stderr, gap)
+			}
+		}
+		if *renumber {
+			changes := schema.RenumberFields(s)
+			if len(changes) > 0 {
+				fmt.Fprintf(os.Stderr, "WARNING: %s: the wire format has changed; renumbered fields:\n", displayName(inputFile))
+				for _, c := range changes {
+					fmt.Fprintf(os.Stderr, "  %s\n", c)
+				}
+			}
+		}
+
 		formatted := schema.FormatSchema(s)
 
 		if *write {
@@ -357,6 +591,123 @@ Options:`)
 	}
 }
 
+// displayName returns the name to report for an input file.
+func displayName(inputFile string) string {
+	if inputFile == schema.StdinPath {
+		return schema.StdinName
+	}
+	return inputFile
+}
+
+func cmdExplain(args []string) {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	var searchPaths stringSliceFlag
+	fs.Var(&searchPaths, "I", "Add import search path (can be repeated)")
+
+	fs.Usage = func() {
+		fmt.Println(`Usage: cramberry explain [options] <schema-file> <type>
+
+Describe a message, enum or interface defined in a schema file.
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Error: expected a schema file and a type name")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	loader := schema.NewLoader(searchPaths...)
+	s, errs := loader.LoadFile(fs.Arg(0))
+	hasErrors := false
+	for _, err := range errs {
+		if valErr, ok := err.(schema.ValidationError); ok && valErr.Severity == schema.SeverityWarning {
+			continue
+		}
+		fmt.Fprintln(os.Stderr, err)
+		hasErrors = true
+	}
+	if hasErrors {
+		os.Exit(1)
+	}
+
+	out, err := schema.Explain(s, fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(out)
+}
+
+func cmdStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	maxFields := fs.Int("max-fields", schema.DefaultLargeMessageFields, "Report messages with more fields than this")
+	var searchPaths stringSliceFlag
+	fs.Var(&searchPaths, "I", "Add import search path (can be repeated)")
+
+	fs.Usage = func() {
+		fmt.Println(`Usage: cramberry stats [options] <schema-file>
+
+Report metrics for a schema file and list potential concerns.
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Error: expected a schema file")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	loader := schema.NewLoader(searchPaths...)
+	s, errs := loader.LoadFile(fs.Arg(0))
+	hasErrors := false
+	for _, err := range errs {
+		if valErr, ok := err.(schema.ValidationError); ok && valErr.Severity == schema.SeverityWarning {
+			continue
+		}
+		fmt.Fprintln(os.Stderr, err)
+		hasErrors = true
+	}
+	if hasErrors {
+		os.Exit(1)
+	}
+
+	st := schema.Stats(s)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "messages:\t%d\n", len(st.Messages))
+	fmt.Fprintf(tw, "enums:\t%d\n", st.Enums)
+	fmt.Fprintf(tw, "interfaces:\t%d\n", st.Interfaces)
+	fmt.Fprintf(tw, "fields:\t%d\n", st.Fields)
+	fmt.Fprintf(tw, "max field number:\t%d\n", st.MaxFieldNumber)
+	fmt.Fprintf(tw, "deepest nesting:\t%d\n", st.MaxDepth)
+	if len(st.Messages) > 0 {
+		fmt.Fprintln(tw, "\nmessage\tfields\tmax number\tdepth")
+		for _, m := range st.Messages {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", m.Name, m.Fields, m.MaxFieldNumber, m.Depth)
+		}
+	}
+	tw.Flush()
+
+	if concerns := st.Concerns(*maxFields); len(concerns) > 0 {
+		fmt.Println("\nconcerns:")
+		for _, c := range concerns {
+			fmt.Printf("  %s\n", c)
+		}
+	}
+}
+
 func cmdSchema(args []string) {
 	fs := flag.NewFlagSet("schema", flag.ExitOnError)
 	outFile := fs.String("out", "", "Output file (default: stdout)")
@@ -366,6 +717,10 @@ func cmdSchema(args []string) {
 	fs.Var(&includePatterns, "include", "Type name pattern to include (glob, can be repeated)")
 	var excludePatterns stringSliceFlag
 	fs.Var(&excludePatterns, "exclude", "Type name pattern to exclude (glob, can be repeated)")
+	var includePkgs stringSliceFlag
+	fs.Var(&includePkgs, "include-pkg", "Package import path pattern to include (glob, can be repeated)")
+	var excludePkgs stringSliceFlag
+	fs.Var(&excludePkgs, "exclude-pkg", "Package import path pattern to exclude (glob, can be repeated)")
 
 	fs.Usage = func() {
 		fmt.Println(`Usage: cramberry schema [options] <go-package>...
@@ -376,6 +731,7 @@ Examples:
   cramberry schema ./...
   cramberry schema -out schema.cram ./pkg/models
   cramberry schema -include "User*" -exclude "*Internal" ./...
+  cramberry schema -include-pkg ".../models/*" ./...
 
 Options:`)
 		fs.PrintDefaults()
@@ -399,9 +755,11 @@ Options:`)
 			ExcludePatterns:  excludePatterns,
 			DetectInterfaces: true,
 		},
-		Patterns:   fs.Args(),
-		OutputPath: *outFile,
-		Package:    *pkg,
+		Patterns:        fs.Args(),
+		IncludePackages: includePkgs,
+		ExcludePackages: excludePkgs,
+		OutputPath:      *outFile,
+		Package:         *pkg,
 	}
 
 	// Extract schema
@@ -416,6 +774,69 @@ Options:`)
 	}
 }
 
+func cmdRetag(args []string) {
+	fs := flag.NewFlagSet("retag", flag.ExitOnError)
+	var searchPaths stringSliceFlag
+	fs.Var(&searchPaths, "I", "Add import search path (can be repeated)")
+
+	fs.Usage = func() {
+		fmt.Println(`Usage: cramberry retag [options] <schema-file> <go-package>...
+
+Rewrite the cramberry tags of Go structs to match the field numbers of the
+schema messages with the same names. Files are edited in place. A struct in
+which a field without a schema field keeps a number the schema gives to
+another field is left unchanged and reported as a conflict.
+
+Examples:
+  cramberry retag schema.cram ./pkg/models
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if fs.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, "Error: expected a schema file and at least one Go package")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	loader := schema.NewLoader(searchPaths...)
+	s, errs := loader.LoadFile(fs.Arg(0))
+	hasErrors := false
+	for _, err := range errs {
+		if valErr, ok := err.(schema.ValidationError); ok && valErr.Severity == schema.SeverityWarning {
+			continue
+		}
+		fmt.Fprintln(os.Stderr, err)
+		hasErrors = true
+	}
+	if hasErrors {
+		os.Exit(1)
+	}
+
+	result, err := extract.RetagPackages(s, fs.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, c := range result.Changes {
+		fmt.Printf("Retagged: %s\n", c)
+	}
+	for _, m := range result.Mismatches {
+		fmt.Fprintf(os.Stderr, "Mismatch: %s\n", m)
+	}
+	for _, c := range result.Conflicts {
+		fmt.Fprintf(os.Stderr, "Conflict: %s\n", c)
+	}
+	if len(result.Conflicts) > 0 {
+		os.Exit(1)
+	}
+}
+
 func cmdVersion() {
 	fmt.Printf("cramberry version %s\n", cramberry.VersionInfo())
 }
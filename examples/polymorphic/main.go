@@ -117,4 +117,18 @@ func main() {
 			fmt.Printf("  Triangle base %.2f, height %.2f\n", s.Base, s.Height)
 		}
 	}
+
+	// A single interface value is encoded through a pointer to it, which
+	// writes the type ID of the concrete shape
+	var shape Shape = &Rectangle{Width: 2.0, Height: 3.0}
+	data, err = cramberry.Marshal(&shape)
+	if err != nil {
+		log.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decodedShape Shape
+	if err := cramberry.Unmarshal(data, &decodedShape); err != nil {
+		log.Fatalf("Unmarshal failed: %v", err)
+	}
+	fmt.Printf("\nTop-level shape: %s with area %.2f\n", decodedShape.Name(), decodedShape.Area())
 }
@@ -87,4 +87,13 @@ func main() {
 	sw2.Flush()
 
 	fmt.Printf("Custom buffer writer: %d bytes\n", buf2.Len())
+
+	// Load a whole bounded stream into a slice
+	fmt.Println("\n--- Reading All Entries ---")
+
+	loaded, err := cramberry.ReadAllDelimited[LogEntry](&buf2, 1000)
+	if err != nil {
+		log.Fatalf("Failed to read stream: %v", err)
+	}
+	fmt.Printf("Loaded %d entries, last: %s\n", len(loaded), loaded[len(loaded)-1].Message)
 }
@@ -0,0 +1,63 @@
+// Package naming converts schema names between the case styles used by
+// generated code. The schema validator and the code generators share it so
+// that a name checked for collisions is the identifier that gets generated.
+package naming
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// titleCaser is used for converting strings to title case.
+var titleCaser = cases.Title(language.English)
+
+// Pascal converts a string to PascalCase.
+func Pascal(s string) string {
+	parts := Split(s)
+	for i, p := range parts {
+		parts[i] = titleCaser.String(strings.ToLower(p))
+	}
+	return strings.Join(parts, "")
+}
+
+// Split splits a name into parts based on underscores and case transitions.
+func Split(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	var parts []string
+	var current strings.Builder
+
+	for i, r := range s {
+		if r == '_' || r == '-' {
+			if current.Len() > 0 {
+				parts = append(parts, current.String())
+				current.Reset()
+			}
+			continue
+		}
+
+		// Check for case transition
+		if i > 0 && isUpper(r) && !isUpper(rune(s[i-1])) {
+			if current.Len() > 0 {
+				parts = append(parts, current.String())
+				current.Reset()
+			}
+		}
+
+		current.WriteRune(r)
+	}
+
+	if current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+
+	return parts
+}
+
+func isUpper(r rune) bool {
+	return r >= 'A' && r <= 'Z'
+}
@@ -0,0 +1,45 @@
+package naming
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPascal(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"user_id", "UserId"},
+		{"userId", "UserId"},
+		{"USER_ID", "UserId"},
+		{"content-type", "ContentType"},
+		{"HTTPServer", "Httpserver"},
+		{"already_Pascal_", "AlreadyPascal"},
+		{"", ""},
+	}
+
+	for _, tc := range tests {
+		if got := Pascal(tc.name); got != tc.expected {
+			t.Errorf("Pascal(%q) = %q, want %q", tc.name, got, tc.expected)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{"user_id", []string{"user", "id"}},
+		{"userID", []string{"user", "ID"}},
+		{"__a--b__", []string{"a", "b"}},
+		{"", nil},
+	}
+
+	for _, tc := range tests {
+		if got := Split(tc.name); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Split(%q) = %q, want %q", tc.name, got, tc.expected)
+		}
+	}
+}
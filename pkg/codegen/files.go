@@ -0,0 +1,81 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blockberries/cramberry/pkg/schema"
+)
+
+// OutputFile describes a file produced by GenerateFiles.
+type OutputFile struct {
+	// Path is the output file path.
+	Path string
+
+	// Size is the length of the generated code in bytes.
+	Size int
+}
+
+// GenerateFiles loads each input schema with loader and generates one file
+// per schema into opts.OutputPath, named after the schema file with the
+// generator's extension. The input schema.StdinPath reads the schema from
+// loader.Stdin; its file is named after opts.Package, which must be set.
+//
+// Code is generated in memory before anything is written, so a schema that
+// fails to generate never leaves a partial file behind. When dryRun is true
+// nothing is written at all: the returned list describes the files that
+// would have been created. Errors are collected per input so that one bad
+// schema does not hide problems in the others.
+func GenerateFiles(gen Generator, loader *schema.Loader, inputs []string, opts Options, dryRun bool) ([]OutputFile, []error) {
+	var outputs []OutputFile
+	var errs []error
+
+	if !dryRun {
+		if err := os.MkdirAll(opts.OutputPath, 0o755); err != nil {
+			return nil, []error{fmt.Errorf("creating output directory: %w", err)}
+		}
+	}
+
+	for _, inputFile := range inputs {
+		baseName := filepath.Base(inputFile)
+		baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
+		if inputFile == schema.StdinPath {
+			if opts.Package == "" {
+				errs = append(errs, fmt.Errorf("%s: a package name is required to generate code from standard input", schema.StdinName))
+				continue
+			}
+			baseName = opts.Package
+		}
+
+		s, loadErrs := loader.LoadFile(inputFile)
+		if len(loadErrs) > 0 {
+			errs = append(errs, loadErrs...)
+			continue
+		}
+
+		// Imported schemas are needed for same-package detection
+		fileOpts := opts
+		fileOpts.ImportedSchemas = loader.GetImportedSchemas(inputFile)
+
+		outputFile := filepath.Join(opts.OutputPath, baseName+gen.FileExtension())
+
+		var buf bytes.Buffer
+		if err := gen.Generate(&buf, s, fileOpts); err != nil {
+			errs = append(errs, fmt.Errorf("%s: generating code: %w", inputFile, err))
+			continue
+		}
+
+		if !dryRun {
+			if err := os.WriteFile(outputFile, buf.Bytes(), 0o644); err != nil {
+				errs = append(errs, fmt.Errorf("writing output file: %w", err))
+				continue
+			}
+		}
+		outputs = append(outputs, OutputFile{Path: outputFile, Size: buf.Len()})
+	}
+
+	return outputs, errs
+}
@@ -0,0 +1,218 @@
+package codegen
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/schema"
+)
+
+// failingGenerator fails to generate any schema.
+type failingGenerator struct{}
+
+func (failingGenerator) Generate(io.Writer, *schema.Schema, Options) error {
+	return errors.New("boom")
+}
+func (failingGenerator) Language() Language    { return "failing" }
+func (failingGenerator) FileExtension() string { return ".txt" }
+
+func writeSchemaFiles(t *testing.T, dir string, files map[string]string) []string {
+	t.Helper()
+	var paths []string
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestGenerateFiles(t *testing.T) {
+	dir := t.TempDir()
+	inputs := writeSchemaFiles(t, dir, map[string]string{
+		"user.cram": "package test;\n\nmessage User {\n    string name = 1;\n}\n",
+	})
+
+	opts := DefaultOptions()
+	opts.OutputPath = filepath.Join(dir, "out")
+
+	outputs, errs := GenerateFiles(NewGoGenerator(), schema.NewLoader(), inputs, opts, false)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(outputs) != 1 {
+		t.Fatalf("expected 1 output, got %d", len(outputs))
+	}
+
+	data, err := os.ReadFile(outputs[0].Path)
+	if err != nil {
+		t.Fatalf("output file not written: %v", err)
+	}
+	if len(data) != outputs[0].Size {
+		t.Errorf("reported size %d, file has %d bytes", outputs[0].Size, len(data))
+	}
+	if filepath.Base(outputs[0].Path) != "user.go" {
+		t.Errorf("unexpected output name %s", outputs[0].Path)
+	}
+}
+
+func TestGenerateFilesDryRun(t *testing.T) {
+	dir := t.TempDir()
+	good := writeSchemaFiles(t, dir, map[string]string{
+		"user.cram": "package test;\n\nmessage User {\n    string name = 1;\n}\n",
+	})
+	bad := writeSchemaFiles(t, dir, map[string]string{
+		"broken.cram": "package test;\n\nmessage {\n",
+	})
+
+	opts := DefaultOptions()
+	opts.OutputPath = filepath.Join(dir, "out")
+
+	outputs, errs := GenerateFiles(NewGoGenerator(), schema.NewLoader(), append(good, bad...), opts, true)
+
+	if len(errs) == 0 {
+		t.Error("expected the broken schema to report an error")
+	}
+	if len(outputs) != 1 || outputs[0].Size == 0 {
+		t.Fatalf("expected one non-empty planned output, got %+v", outputs)
+	}
+	if !strings.HasSuffix(outputs[0].Path, filepath.Join("out", "user.go")) {
+		t.Errorf("unexpected planned path %s", outputs[0].Path)
+	}
+	if _, err := os.Stat(opts.OutputPath); !os.IsNotExist(err) {
+		t.Errorf("dry run created the output directory: %v", err)
+	}
+
+	t.Run("generation errors", func(t *testing.T) {
+		outputs, errs := GenerateFiles(failingGenerator{}, schema.NewLoader(), good, opts, true)
+		if len(outputs) != 0 {
+			t.Errorf("expected no outputs, got %+v", outputs)
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), "boom") {
+			t.Errorf("expected the generator error, got %v", errs)
+		}
+		if _, err := os.Stat(opts.OutputPath); !os.IsNotExist(err) {
+			t.Errorf("dry run created the output directory: %v", err)
+		}
+	})
+}
+
+func TestGenerateFilesStdin(t *testing.T) {
+	dir := t.TempDir()
+	src := "package test;\n\nmessage User {\n    string name = 1;\n}\n\nmessage Group {\n    repeated User users = 1;\n}\n"
+
+	opts := DefaultOptions()
+	opts.OutputPath = dir
+
+	loader := schema.NewLoader()
+	loader.Stdin = strings.NewReader(src)
+	if _, errs := GenerateFiles(NewGoGenerator(), loader, []string{schema.StdinPath}, opts, false); len(errs) != 1 {
+		t.Errorf("expected an error without a package name, got %v", errs)
+	}
+
+	opts.Package = "models"
+	outputs, errs := GenerateFiles(NewGoGenerator(), loader, []string{schema.StdinPath}, opts, false)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(outputs) != 1 || outputs[0].Path != filepath.Join(dir, "models.go") {
+		t.Fatalf("unexpected outputs %+v", outputs)
+	}
+	data, err := os.ReadFile(outputs[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package models", "type User struct", "type Group struct"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("generated code is missing %q", want)
+		}
+	}
+}
+
+func TestGenerateImportedEnums(t *testing.T) {
+	dir := t.TempDir()
+	writeSchemaFiles(t, dir, map[string]string{
+		"common.cram": `package common;
+
+enum Status {
+    UNKNOWN = 0;
+    ACTIVE = 1;
+}
+
+message Address {
+    string street = 1;
+}
+`,
+		"main.cram": `package shop;
+
+import "common.cram" as common;
+
+enum Priority {
+    LOW = 0;
+}
+
+message Note {
+    string text = 1;
+}
+
+message Order {
+    common.Status status = 1;
+    common.Address address = 2;
+    Priority priority = 3;
+    Note note = 4;
+    repeated common.Status history = 5;
+}
+`,
+	})
+	s, errs := schema.NewLoader().LoadFile(filepath.Join(dir, "main.cram"))
+	if len(errs) > 0 {
+		t.Fatalf("load errors: %v", errs)
+	}
+
+	tests := []struct {
+		gen  Generator
+		want []string
+	}{
+		{NewGoGenerator(), []string{
+			// Enums, imported or not, are varints and omitted when zero
+			"if m.Status != 0 {\n\t\tw.WriteCompactTag(1, cramberry.WireTypeV2SVarint)\n\t\tm.Status.EncodeTo(w)",
+			"if m.Priority != 0 {\n\t\tw.WriteCompactTag(3, cramberry.WireTypeV2SVarint)\n\t\tm.Priority.EncodeTo(w)",
+			// Messages, imported or not, are nested
+			"w.WriteCompactTag(2, cramberry.WireTypeV2Bytes)\n\tm.Address.EncodeTo(w)",
+			"w.WriteCompactTag(4, cramberry.WireTypeV2Bytes)\n\tm.Note.EncodeTo(w)",
+			// Repeated imported enums are packed
+			"w.WriteInt32(int32(v))",
+			"m.History[i] = common.Status(r.ReadInt32())",
+		}},
+		{NewTypeScriptGenerator(), []string{
+			"writer.writeSVarint(msg.status)",
+			"writer.writeSVarint(msg.priority)",
+			"result.status = reader.readSVarint()",
+			"decodeAddress(reader)",
+		}},
+		{NewRustGenerator(), []string{
+			"writer.write_svarint(msg.status as i32)",
+			"common::Status::from_i32(reader.read_svarint()?)",
+			"decode_address(reader)?",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.gen.Language()), func(t *testing.T) {
+			var buf strings.Builder
+			if err := tt.gen.Generate(&buf, s, DefaultOptions()); err != nil {
+				t.Fatalf("generate error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("expected %q in output:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
@@ -1,14 +1,28 @@
 // Package codegen provides code generation from Cramberry schema files.
+//
+// Generators are looked up by language in a registry that the built-in Go,
+// TypeScript and Rust generators add themselves to. Other packages can
+// provide generators for further languages by implementing Generator and
+// registering it from an init function:
+//
+//	package python
+//
+//	func init() {
+//		codegen.Register(NewGenerator())
+//	}
+//
+// A program that imports the package, usually for its side effects with a
+// blank import, can then find the generator with Get.
 package codegen
 
 import (
 	"fmt"
 	"io"
+	"slices"
 	"strings"
+	"sync"
 
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
-
+	"github.com/blockberries/cramberry/internal/naming"
 	"github.com/blockberries/cramberry/pkg/schema"
 )
 
@@ -21,15 +35,37 @@ const (
 	LanguageRust       Language = "rust"
 )
 
+// WireFormat selects the field tag format of generated Go code.
+type WireFormat string
+
+const (
+	// WireFormatV2 writes compact tags and ends messages with an end
+	// marker. It is the format of the reflective runtime and of the
+	// TypeScript and Rust generators, and the default.
+	WireFormatV2 WireFormat = "v2"
+
+	// WireFormatV1 writes classic varint tags, fieldNum<<3 | wire type,
+	// and prefixes every message with its length.
+	WireFormatV1 WireFormat = "v1"
+)
+
 // Generator is the interface for code generators.
+//
+// A registered generator is shared by every caller of Get, so Generate
+// must be safe for concurrent use and must not modify the schema or keep
+// references to it after returning. Options the generator doesn't support
+// are ignored; schema features it can't express are reported as errors
+// rather than silently dropped.
 type Generator interface {
 	// Generate produces code from a schema.
 	Generate(w io.Writer, schema *schema.Schema, options Options) error
 
-	// Language returns the target language.
+	// Language returns the target language. It must always return the
+	// same value, which is the key the generator is registered under.
 	Language() Language
 
-	// FileExtension returns the file extension for generated files.
+	// FileExtension returns the file extension for generated files,
+	// including the leading dot.
 	FileExtension() string
 }
 
@@ -62,6 +98,61 @@ type Options struct {
 	// TypeSuffix adds a suffix to all type names.
 	TypeSuffix string
 
+	// OmitTopLevelEndMarker makes MarshalCramberry drop the end marker of
+	// the message it encodes and UnmarshalCramberry accept its absence.
+	// See cramberry.Options.OmitTopLevelEndMarker for the compatibility
+	// implications.
+	OmitTopLevelEndMarker bool
+
+	// GeneratePools generates a sync.Pool per message with GetT and PutT
+	// functions, and a Reset method that PutT uses to clear the message
+	// before it is reused (Go only).
+	GeneratePools bool
+
+	// OptionalWrappers generates optional scalar fields as
+	// cramberry.Optional[T] values instead of pointers, which keeps their
+	// presence without a heap allocation (Go only).
+	OptionalWrappers bool
+
+	// PreserveUnknown adds an unknownFields member to every message.
+	// DecodeFrom keeps the raw bytes of fields it doesn't recognize there,
+	// and EncodeTo writes them back after the known fields, so a message
+	// written by a newer schema survives a decode and re-encode (Go only).
+	PreserveUnknown bool
+
+	// RequiredGetters generates a GetXErr method that returns an error
+	// and a MustGetX method that panics when a required field that can be
+	// nil is not set, such as a required scalar (Go only).
+	RequiredGetters bool
+
+	// ValidateOnDecode makes UnmarshalCramberry call Validate on messages
+	// that have required or bounded fields and return its error, so data
+	// missing a required field fails to decode (Go only).
+	ValidateOnDecode bool
+
+	// GeneratePresenceDecode generates a DecodeWithPresence method that
+	// decodes a message like UnmarshalCramberry and also returns the
+	// numbers of the fields present on the wire (Go only).
+	GeneratePresenceDecode bool
+
+	// PackBools writes each run of two or more consecutive plain bool
+	// fields of a message as one varint bitmask, under the number of the
+	// run's first field, while the Go struct keeps a field per bool. Only
+	// decoders generated with PackBools read the packed form; they still
+	// read bools written one per field (Go only).
+	PackBools bool
+
+	// JSONConverters generates a ToJSON method returning a message's
+	// fields as a map keyed by their JSON names, leaving out unset optional
+	// and pointer fields, and a FromJSON method reading such a map back.
+	// It requires GenerateJSON, and schemas with interface fields are
+	// rejected (Go only).
+	JSONConverters bool
+
+	// WireFormat selects the tag format of the generated encoders and
+	// decoders. The empty value means WireFormatV2 (Go only).
+	WireFormat WireFormat
+
 	// ImportPaths maps schema import aliases to Go import paths.
 	// For example: {"types": "example.com/myapp/types"}
 	// This is used to generate proper import statements for imported types.
@@ -85,40 +176,203 @@ func DefaultOptions() Options {
 }
 
 // registry holds registered generators by language.
-var registry = make(map[Language]Generator)
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[Language]Generator)
+)
 
-// Register registers a generator for a language.
+// Register makes a generator available under its language. It is safe
+// for concurrent use, but is meant to be called from an init function.
+// Register panics if gen is nil or a generator is already registered for
+// the same language; use Unregister first to replace one.
 func Register(gen Generator) {
-	registry[gen.Language()] = gen
+	if gen == nil {
+		panic("codegen: Register generator is nil")
+	}
+	lang := gen.Language()
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[lang]; dup {
+		panic(fmt.Sprintf("codegen: Register called twice for language %q", lang))
+	}
+	registry[lang] = gen
+}
+
+// Unregister removes the generator registered for a language, if any. It
+// is mainly useful in tests that register stub generators.
+func Unregister(lang Language) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registry, lang)
 }
 
 // Get returns the generator for a language.
 func Get(lang Language) (Generator, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
 	gen, ok := registry[lang]
 	return gen, ok
 }
 
-// Languages returns all registered languages.
+// Languages returns all registered languages in sorted order.
 func Languages() []Language {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
 	langs := make([]Language, 0, len(registry))
 	for lang := range registry {
 		langs = append(langs, lang)
 	}
+	slices.Sort(langs)
 	return langs
 }
 
 // Helper functions for code generation
 
-// titleCaser is used for converting strings to title case.
-var titleCaser = cases.Title(language.English)
+// schemaHash returns the schema's fingerprint if it sets option
+// schema_hash = true, or "" otherwise.
+func schemaHash(s *schema.Schema) string {
+	if !s.GenerateSchemaHash() {
+		return ""
+	}
+	return schema.SchemaFingerprint(s)
+}
+
+// usesAnyType reports whether a message field in s uses the dynamic any
+// type, which only the Go generator supports.
+func usesAnyType(s *schema.Schema) bool {
+	var isAny func(t schema.TypeRef) bool
+	isAny = func(t schema.TypeRef) bool {
+		switch typ := t.(type) {
+		case *schema.ScalarType:
+			return typ.Name == "any"
+		case *schema.ArrayType:
+			return isAny(typ.Element)
+		case *schema.MapType:
+			return isAny(typ.Key) || isAny(typ.Value)
+		case *schema.PointerType:
+			return isAny(typ.Element)
+		default:
+			return false
+		}
+	}
+	for _, msg := range s.Messages {
+		for _, f := range msg.Fields {
+			if isAny(f.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lengthFramedMessage returns the first message in s that uses length
+// framing, which only the Go generator supports, or nil if there is none.
+func lengthFramedMessage(s *schema.Schema) *schema.Message {
+	for _, msg := range s.Messages {
+		if msg.Framing() == schema.FramingLength {
+			return msg
+		}
+	}
+	return nil
+}
+
+// deltaField returns the first field in s that sets the delta option,
+// which only the Go generator supports, or nil if there is none.
+func deltaField(s *schema.Schema) (*schema.Message, *schema.Field) {
+	for _, msg := range s.Messages {
+		for _, f := range msg.Fields {
+			if f.Delta() {
+				return msg, f
+			}
+		}
+	}
+	return nil, nil
+}
+
+// defaultField returns the first field in s with a byte literal default,
+// which only the Go generator supports, or nil if there is none.
+func defaultField(s *schema.Schema) (*schema.Message, *schema.Field) {
+	for _, msg := range s.Messages {
+		for _, f := range msg.Fields {
+			if len(f.Default()) > 0 {
+				return msg, f
+			}
+		}
+	}
+	return nil, nil
+}
+
+// fixed32Enum returns the first enum in s that uses fixed32 encoding, which
+// only the Go generator supports, or nil if there is none.
+func fixed32Enum(s *schema.Schema) *schema.Enum {
+	for _, e := range s.Enums {
+		if e.Encoding() == schema.EnumEncodingFixed32 {
+			return e
+		}
+	}
+	return nil
+}
+
+// enumOf returns the enum typ names, or nil if it names a message or an
+// interface. Validated schemas, such as those returned by schema.Loader,
+// record the definition on typ, so enums of imported schemas are found
+// too; otherwise only the enums of s are recognized.
+func enumOf(s *schema.Schema, typ *schema.NamedType) *schema.Enum {
+	if typ.Enum != nil || typ.Message != nil || typ.Interface != nil {
+		return typ.Enum
+	}
+	if typ.Package != "" {
+		return nil
+	}
+	for _, e := range s.Enums {
+		if e.Name == typ.Name {
+			return e
+		}
+	}
+	return nil
+}
+
+// isFixedByteArray reports whether t is a fixed-size array of bytes, such as
+// a hash, which is encoded as a single length-prefixed byte string rather
+// than element by element.
+func isFixedByteArray(t *schema.ArrayType) bool {
+	elem, ok := t.Element.(*schema.ScalarType)
+	return ok && t.Size > 0 && (elem.Name == "byte" || elem.Name == "uint8")
+}
+
+// canonicalEnumValues returns the values of e in declaration order, keeping
+// only the first value declared for each number. With allow_alias set, later
+// values sharing a number are aliases of the canonical one.
+func canonicalEnumValues(e *schema.Enum) []*schema.EnumValue {
+	seen := make(map[int]bool, len(e.Values))
+	values := make([]*schema.EnumValue, 0, len(e.Values))
+	for _, v := range e.Values {
+		if !seen[v.Number] {
+			seen[v.Number] = true
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// enumAliasOf returns the first-declared value of e that shares v's number,
+// or nil if v is itself that value.
+func enumAliasOf(e *schema.Enum, v *schema.EnumValue) *schema.EnumValue {
+	for _, other := range e.Values {
+		if other.Number == v.Number {
+			if other == v {
+				return nil
+			}
+			return other
+		}
+	}
+	return nil
+}
 
 // ToPascalCase converts a string to PascalCase.
 func ToPascalCase(s string) string {
-	parts := splitName(s)
-	for i, p := range parts {
-		parts[i] = titleCaser.String(strings.ToLower(p))
-	}
-	return strings.Join(parts, "")
+	return naming.Pascal(s)
 }
 
 // ToCamelCase converts a string to camelCase.
@@ -132,7 +386,7 @@ func ToCamelCase(s string) string {
 
 // ToSnakeCase converts a string to snake_case.
 func ToSnakeCase(s string) string {
-	parts := splitName(s)
+	parts := naming.Split(s)
 	for i, p := range parts {
 		parts[i] = strings.ToLower(p)
 	}
@@ -141,7 +395,7 @@ func ToSnakeCase(s string) string {
 
 // ToUpperSnakeCase converts a string to UPPER_SNAKE_CASE.
 func ToUpperSnakeCase(s string) string {
-	parts := splitName(s)
+	parts := naming.Split(s)
 	for i, p := range parts {
 		parts[i] = strings.ToUpper(p)
 	}
@@ -150,53 +404,13 @@ func ToUpperSnakeCase(s string) string {
 
 // ToKebabCase converts a string to kebab-case.
 func ToKebabCase(s string) string {
-	parts := splitName(s)
+	parts := naming.Split(s)
 	for i, p := range parts {
 		parts[i] = strings.ToLower(p)
 	}
 	return strings.Join(parts, "-")
 }
 
-// splitName splits a name into parts based on underscores and case transitions.
-func splitName(s string) []string {
-	if s == "" {
-		return nil
-	}
-
-	var parts []string
-	var current strings.Builder
-
-	for i, r := range s {
-		if r == '_' || r == '-' {
-			if current.Len() > 0 {
-				parts = append(parts, current.String())
-				current.Reset()
-			}
-			continue
-		}
-
-		// Check for case transition
-		if i > 0 && isUpper(r) && !isUpper(rune(s[i-1])) {
-			if current.Len() > 0 {
-				parts = append(parts, current.String())
-				current.Reset()
-			}
-		}
-
-		current.WriteRune(r)
-	}
-
-	if current.Len() > 0 {
-		parts = append(parts, current.String())
-	}
-
-	return parts
-}
-
-func isUpper(r rune) bool {
-	return r >= 'A' && r <= 'Z'
-}
-
 // Indent indents each line of s by the given number of tabs.
 func Indent(s string, tabs int) string {
 	indent := strings.Repeat("\t", tabs)
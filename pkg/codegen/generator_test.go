@@ -2,7 +2,12 @@ package codegen
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/blockberries/cramberry/pkg/schema"
@@ -58,50 +63,1216 @@ func TestGoGeneratorSimpleMessage(t *testing.T) {
 	if !strings.Contains(output, `json:"id"`) {
 		t.Error("expected json tag for id")
 	}
+
+	// EncodeTo and DecodeFrom are exported so that messages can be
+	// composed on a shared Writer or Reader
+	if !strings.Contains(output, "func (m *User) EncodeTo(w *cramberry.Writer) {") {
+		t.Error("expected exported EncodeTo method")
+	}
+	if !strings.Contains(output, "func (m *User) DecodeFrom(r *cramberry.Reader) {") {
+		t.Error("expected exported DecodeFrom method")
+	}
+	if !strings.Contains(output, "func (m *User) DecodeCramberry(r *cramberry.Reader) {\n\tm.DecodeFrom(r)\n}") {
+		t.Error("expected DecodeCramberry method")
+	}
+}
+
+func TestGoGeneratorEnum(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Enums: []*schema.Enum{
+			{
+				Name: "Status",
+				Values: []*schema.EnumValue{
+					{Name: "UNKNOWN", Number: 0},
+					{Name: "ACTIVE", Number: 1},
+					{Name: "INACTIVE", Number: 2},
+				},
+			},
+		},
+	}
+
+	gen := NewGoGenerator()
+	var buf bytes.Buffer
+	opts := DefaultOptions()
+
+	err := gen.Generate(&buf, s, opts)
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	output := buf.String()
+
+	// Check enum type
+	if !strings.Contains(output, "type Status int32") {
+		t.Error("expected Status type")
+	}
+
+	// Check enum values
+	if !strings.Contains(output, "StatusUnknown Status = 0") {
+		t.Errorf("expected StatusUnknown, got: %s", output)
+	}
+	if !strings.Contains(output, "StatusActive") {
+		t.Error("expected StatusActive")
+	}
+
+	// Check String method
+	if !strings.Contains(output, "func (e Status) String() string") {
+		t.Error("expected String method")
+	}
+}
+
+func TestGoGeneratorEnumAlias(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Enums: []*schema.Enum{
+			{
+				Name: "Status",
+				Options: []*schema.Option{
+					{Name: "allow_alias", Value: &schema.BoolValue{Value: true}},
+				},
+				Values: []*schema.EnumValue{
+					{Name: "UNKNOWN", Number: 0},
+					{Name: "STARTED", Number: 1},
+					{Name: "RUNNING", Number: 1},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	// Both names are defined as constants
+	for _, want := range []string{"StatusStarted Status = 1", "StatusRunning Status = 1"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected constant %q, got: %s", want, output)
+		}
+	}
+
+	// The switches only use the first-declared name for a shared number
+	if strings.Contains(output, "case StatusRunning:") {
+		t.Errorf("alias must not appear as a switch case, got: %s", output)
+	}
+	if !strings.Contains(output, "case StatusStarted:\n\t\treturn \"STARTED\"") {
+		t.Errorf("expected String to return the first-declared name, got: %s", output)
+	}
+}
+
+func TestGoGeneratorEnumFixed32(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Enums: []*schema.Enum{
+			{
+				Name: "Code",
+				Options: []*schema.Option{
+					{Name: "enum_encoding", Value: &schema.StringValue{Value: schema.EnumEncodingFixed32}},
+				},
+				Values: []*schema.EnumValue{{Name: "NONE", Number: 0}},
+			},
+			{
+				Name:   "Level",
+				Values: []*schema.EnumValue{{Name: "LOW", Number: 0}},
+			},
+		},
+		Messages: []*schema.Message{
+			{
+				Name: "Event",
+				Fields: []*schema.Field{
+					{Name: "code", Number: 1, Type: &schema.NamedType{Name: "Code"}},
+					{Name: "level", Number: 2, Type: &schema.NamedType{Name: "Level"}},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	// Code is fixed32 on the wire; Level keeps the signed varint default
+	for want, count := range map[string]int{
+		"w.WriteFixed32(uint32(e))":                         1,
+		"*e = Code(r.ReadFixed32())":                        1,
+		"w.WriteInt32(int32(e))":                            1,
+		"*e = Level(r.ReadInt32())":                         1,
+		"w.WriteCompactTag(1, cramberry.WireTypeV2Fixed32)": 1,
+		"w.WriteCompactTag(2, cramberry.WireTypeV2SVarint)": 1,
+	} {
+		if got := strings.Count(output, want); got != count {
+			t.Errorf("expected %d occurrence(s) of %q, got %d:\n%s", count, want, got, output)
+		}
+	}
+
+	if err := NewTypeScriptGenerator().Generate(&bytes.Buffer{}, s, DefaultOptions()); err == nil {
+		t.Error("TypeScript generator accepted a fixed32 enum")
+	}
+	if err := NewRustGenerator().Generate(&bytes.Buffer{}, s, DefaultOptions()); err == nil {
+		t.Error("Rust generator accepted a fixed32 enum")
+	}
+}
+
+func TestGoGeneratorRepeatedEnum(t *testing.T) {
+	input := `package test;
+enum Status {
+  UNKNOWN = 0;
+  ACTIVE = 1;
+}
+enum Code {
+  option enum_encoding = "fixed32";
+  NONE = 0;
+}
+message Audit {
+  repeated Status statuses = 1;
+  repeated Code codes = 2;
+  repeated common.Kind kinds = 3;
+}
+`
+	s, errs := schema.ParseFile("audit.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for want, count := range map[string]int{
+		// Local enums are packed inline in their own encoding
+		"for _, v := range m.Statuses {\n\t\t\tw.WriteInt32(int32(v))": 1,
+		"m.Statuses[i] = Status(r.ReadInt32())":                        1,
+		"for _, v := range m.Codes {\n\t\t\tw.WriteFixed32(uint32(v))": 1,
+		"m.Codes[i] = Code(r.ReadFixed32())":                           1,
+		// An imported type may be a message and keeps its own methods
+		"m.Kinds[i].DecodeFrom(r)": 1,
+	} {
+		if got := strings.Count(output, want); got != count {
+			t.Errorf("expected %d occurrence(s) of %q, got %d:\n%s", count, want, got, output)
+		}
+	}
+}
+
+func TestGoGeneratorFixedByteArray(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{
+				Name: "Block",
+				Fields: []*schema.Field{
+					{Name: "hash", Number: 1, Type: &schema.ArrayType{Element: &schema.ScalarType{Name: "byte"}, Size: 32}},
+					{Name: "parent", Number: 2, Type: &schema.ArrayType{Element: &schema.ScalarType{Name: "byte"}, Size: 32}, Optional: true},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"Hash [32]uint8",
+		"Parent *[32]uint8",
+		"w.WriteBytes(m.Hash[:])",
+		"r.ReadBytesInto(m.Hash[:])",
+		"w.WriteBytes(m.Parent[:])",
+		"r.ReadBytesInto(tmp[:])",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	// No per-element loop or nil check on the array value
+	if strings.Contains(output, "range m.Hash") || strings.Contains(output, "m.Hash != nil") {
+		t.Errorf("fixed byte array must be encoded as raw bytes, got:\n%s", output)
+	}
+}
+
+func TestGoGeneratorNilPointerElements(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{Name: "Address", Fields: []*schema.Field{
+				{Name: "city", Number: 1, Type: &schema.ScalarType{Name: "string"}},
+			}},
+			{Name: "Book", Fields: []*schema.Field{
+				{Name: "addresses", Number: 1, Type: &schema.MapType{
+					Key:   &schema.ScalarType{Name: "string"},
+					Value: &schema.PointerType{Element: &schema.NamedType{Name: "Address"}},
+				}},
+				{Name: "primary", Number: 2, Type: &schema.PointerType{Element: &schema.NamedType{Name: "Address"}}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	// Map values write and read nil or present markers; the pointer field
+	// is omitted when nil instead
+	if strings.Count(output, "w.WriteNil()") != 1 || strings.Count(output, "w.WritePresent()") != 1 {
+		t.Errorf("expected one nil and one present marker write, got:\n%s", output)
+	}
+	if strings.Count(output, "if !r.ReadNil()") != 1 {
+		t.Errorf("expected one nil marker read, got:\n%s", output)
+	}
+}
+
+func TestGoGeneratorOptionalMessageField(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{Name: "Address", Fields: []*schema.Field{
+				{Name: "city", Number: 1, Type: &schema.ScalarType{Name: "string"}},
+			}},
+			{Name: "Person", Fields: []*schema.Field{
+				{Name: "home", Number: 1, Type: &schema.NamedType{Name: "Address"}, Optional: true},
+				{Name: "work", Number: 2, Type: &schema.PointerType{Element: &schema.NamedType{Name: "Address"}}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	// Nil fields are omitted on encode
+	for _, want := range []string{"if m.Home != nil {", "if m.Work != nil {"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	// Decode allocates only inside the field's case, so a field whose tag
+	// never appears stays nil
+	decode := output[strings.Index(output, "func (m *Person) DecodeFrom"):]
+	for field, caseLabel := range map[string]string{"m.Home = &": "case 1:", "m.Work = &": "case 2:"} {
+		assign := strings.Index(decode, field)
+		label := strings.Index(decode, caseLabel)
+		if strings.Count(decode, field) != 1 || label < 0 || assign < label {
+			t.Errorf("expected %q only inside %q, got:\n%s", field, caseLabel, decode)
+		}
+	}
+}
+
+func TestGoGeneratorOptionalWrappers(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{Name: "Counter", Fields: []*schema.Field{
+				{Name: "count", Number: 1, Optional: true, Type: &schema.ScalarType{Name: "int32"}},
+				{Name: "label", Number: 2, Optional: true, Type: &schema.ScalarType{Name: "string"}},
+				{Name: "home", Number: 3, Optional: true, Type: &schema.NamedType{Name: "Counter"}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if output := buf.String(); !strings.Contains(output, "*int32") || strings.Contains(output, "cramberry.Optional") {
+		t.Errorf("expected pointer fields without OptionalWrappers:\n%s", output)
+	}
+
+	opts := DefaultOptions()
+	opts.OptionalWrappers = true
+	buf.Reset()
+	if err := NewGoGenerator().Generate(&buf, s, opts); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"Count cramberry.Optional[int32]",
+		"Label cramberry.Optional[string]",
+		"Home *Counter",
+		"if m.Count.Set {",
+		"w.WriteInt32(m.Count.Value)",
+		"m.Count.Value = r.ReadInt32()",
+		"m.Count.Set = true",
+		"m.Label.Set = true",
+		`json:"count,omitzero"`,
+		`json:"home,omitempty"`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "*int32") || strings.Contains(output, "m.Count != nil") {
+		t.Errorf("optional scalar still generated as a pointer:\n%s", output)
+	}
+}
+
+func TestGoGeneratorSchemaHash(t *testing.T) {
+	generate := func(input string) string {
+		t.Helper()
+		s, errs := schema.ParseFile("hash.cram", input)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors: %v", errs)
+		}
+		var buf bytes.Buffer
+		if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+			t.Fatalf("generate error: %v", err)
+		}
+		return buf.String()
+	}
+	hashConst := regexp.MustCompile(`const SchemaHash = "([0-9a-f]{64})"`)
+
+	output := generate("package test;\noption schema_hash = true;\nmessage User { string name = 1; int32 age = 2; }")
+	m := hashConst.FindStringSubmatch(output)
+	if m == nil {
+		t.Fatalf("expected a SchemaHash constant in output:\n%s", output)
+	}
+
+	// Comments and formatting don't change the hash
+	reformatted := generate(`package test;
+
+option schema_hash = true;
+
+/// User is a person.
+message User {
+    int32 age = 2;     // years
+    string name = 1;
+}
+`)
+	if got := hashConst.FindStringSubmatch(reformatted); got == nil || got[1] != m[1] {
+		t.Errorf("SchemaHash changed after reformatting: %v, want %s", got, m[1])
+	}
+
+	if changed := hashConst.FindStringSubmatch(generate("package test;\noption schema_hash = true;\nmessage User { string name = 1; int64 age = 2; }")); changed == nil || changed[1] == m[1] {
+		t.Errorf("SchemaHash did not change with a field type: %v", changed)
+	}
+
+	if output := generate("package test;\nmessage User { string name = 1; }"); strings.Contains(output, "SchemaHash") {
+		t.Errorf("SchemaHash generated without the schema_hash option:\n%s", output)
+	}
+}
+
+func TestGoGeneratorRepeatedMessageDecodesInPlace(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{Name: "Item", Fields: []*schema.Field{
+				{Name: "id", Number: 1, Type: &schema.ScalarType{Name: "int64"}},
+			}},
+			{Name: "Batch", Fields: []*schema.Field{
+				{Name: "items", Number: 1, Repeated: true, Type: &schema.NamedType{Name: "Item"}},
+				{Name: "groups", Number: 2, Type: &schema.ArrayType{Element: &schema.NamedType{Name: "Item"}}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+	decode := output[strings.Index(output, "func (m *Batch) DecodeFrom"):]
+
+	// The slice is made once and each element decoded through its index
+	for _, want := range []string{
+		"m.Items = make([]Item, n)",
+		"m.Items[i].DecodeFrom(r)",
+		"m.Groups = make([]Item, n)",
+		"m.Groups[i].DecodeFrom(r)",
+	} {
+		if !strings.Contains(decode, want) {
+			t.Errorf("expected %q in output:\n%s", want, decode)
+		}
+	}
+	if strings.Contains(decode, "append(") || strings.Contains(decode, "var tmp") {
+		t.Errorf("expected no per-element temporaries:\n%s", decode)
+	}
+}
+
+func TestGoGeneratorOmitsEmptyFields(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Enums: []*schema.Enum{
+			{Name: "Kind", Values: []*schema.EnumValue{{Name: "NONE", Number: 0}}},
+		},
+		Messages: []*schema.Message{
+			{Name: "Inner", Fields: []*schema.Field{
+				{Name: "id", Number: 1, Type: &schema.ScalarType{Name: "int64"}},
+			}},
+			{Name: "Outer", Fields: []*schema.Field{
+				{Name: "kind", Number: 1, Type: &schema.NamedType{Name: "Kind"}},
+				{Name: "labels", Number: 2, Type: &schema.MapType{Key: &schema.ScalarType{Name: "string"}, Value: &schema.ScalarType{Name: "string"}}},
+				{Name: "scores", Number: 3, Type: &schema.ArrayType{Element: &schema.ScalarType{Name: "int32"}}},
+				{Name: "inner", Number: 4, Type: &schema.NamedType{Name: "Inner"}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+	encode := output[strings.Index(output, "func (m *Outer) EncodeTo"):]
+	encode = encode[:strings.Index(encode, "\n}\n")]
+
+	// Zero enums and empty collections are left out, as the reflective
+	// encoder leaves them out; nested messages are always written
+	for _, want := range []string{
+		"if m.Kind != 0 {",
+		"if len(m.Labels) > 0 {",
+		"if len(m.Scores) > 0 {",
+		"cramberry.ForEachMapEntry(w, m.Labels, func(k string, v string) {",
+	} {
+		if !strings.Contains(encode, want) {
+			t.Errorf("expected %q in output:\n%s", want, encode)
+		}
+	}
+	if strings.Contains(encode, "m.Labels != nil") || strings.Contains(encode, "m.Scores != nil") {
+		t.Errorf("expected length checks rather than nil checks:\n%s", encode)
+	}
+	if !strings.Contains(encode, "\tw.WriteCompactTag(4, cramberry.WireTypeV2Bytes)\n\tm.Inner.EncodeTo(w)") {
+		t.Errorf("expected the nested message to be written unconditionally:\n%s", encode)
+	}
+}
+
+func TestGoGeneratorFieldAnnotations(t *testing.T) {
+	input := `package test;
+message Metrics {
+  int32 level = 1 [unit = "percent", min = 0, max = 100];
+  uint32 retries = 2 [min = 0, max = 10];
+  optional float64 ratio = 3 [min = 0.5];
+  int64 count = 4;
+}
+`
+	s, errs := schema.ParseFile("metrics.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"// Unit: percent. Range: 0 to 100.",
+		"// Range: 0 to 10.",
+		"// Minimum: 0.5.",
+		"func (m *Metrics) Validate() error {",
+		"if m.Level < 0 || m.Level > 100 {",
+		`cramberry.NewValidationError("Metrics", "level", "value must be between 0 and 100")`,
+		// Unsigned fields skip the always-false comparison with zero
+		"if m.Retries > 10 {",
+		// Optional fields are checked only when set, and NaN is out of range
+		"if m.Ratio != nil && !(*m.Ratio >= 0.5) {",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "m.Count <") || strings.Contains(output, "m.Count >") {
+		t.Errorf("expected no range check for unbounded field:\n%s", output)
+	}
+}
+
+func TestGoGeneratorItemRange(t *testing.T) {
+	input := `package test;
+message Batch {
+  []string items = 1 [min_items = 1, max_items = 100];
+  repeated int64 ids = 2 [min_items = 2];
+  map[string]string labels = 3 [max_items = 8];
+  map[string]int32 counts = 4 [ordered = true, max_items = 4];
+  []string notes = 5;
+}
+`
+	s, errs := schema.ParseFile("batch.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"// Items: 1 to 100.",
+		"// Minimum items: 2.",
+		"// Maximum items: 8.",
+		"// Validate validates that all required fields are set and that\n// repeated and map fields hold between min_items and max_items elements.",
+		"if len(m.Items) < 1 || len(m.Items) > 100 {",
+		`cramberry.NewValidationError("Batch", "items", "must have between 1 and 100 items")`,
+		"if len(m.Ids) < 2 {",
+		`cramberry.NewValidationError("Batch", "ids", "must have at least 2 items")`,
+		"if len(m.Labels) > 8 {",
+		`cramberry.NewValidationError("Batch", "labels", "must have at most 8 items")`,
+		// Ordered maps are counted with their Len method
+		"if m.Counts.Len() > 4 {",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	_, validate, _ := strings.Cut(output, "func (m *Batch) Validate() error {")
+	if strings.Contains(validate, "m.Notes") {
+		t.Errorf("expected no item check for unbounded field:\n%s", output)
+	}
+}
+
+func TestGoGeneratorOrderedMap(t *testing.T) {
+	input := `package test;
+message Config {
+  map[string]string settings = 1 [ordered = true];
+  map[string]int32 counts = 2;
+}
+`
+	s, errs := schema.ParseFile("config.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	opts := DefaultOptions()
+	opts.GeneratePools = true
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, opts); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"Settings cramberry.OrderedMap[string, string]",
+		"Counts map[string]int32",
+		// Entries are written in insertion order, not sorted
+		"for k, v := range m.Settings.All() {",
+		"cramberry.ForEachMapEntry(w, m.Counts, func(k string, v int32) {",
+		"m.Settings.Clear()",
+		"m.Settings.Set(k, v)",
+		// Reset keeps the ordered map's storage
+		"Settings: m.Settings,",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "clear(m.Settings)") {
+		t.Errorf("expected Reset to clear the ordered map with its method:\n%s", output)
+	}
+}
+
+func TestGoGeneratorDeltaList(t *testing.T) {
+	input := `package test;
+message Doc {
+  []int64 collaborators = 1 [delta = true];
+  repeated int64 revisions = 2 [delta = true];
+  []int64 scores = 3;
+}
+`
+	s, errs := schema.ParseFile("doc.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for want, count := range map[string]int{
+		"w.WritePackedDeltaInt64(m.Collaborators)":                      1,
+		"w.WritePackedDeltaInt64(m.Revisions)":                          1,
+		"m.Collaborators = r.ReadPackedDeltaInt64(r.ReadArrayHeader())": 1,
+		"m.Revisions = r.ReadPackedDeltaInt64(r.ReadArrayHeader())":     1,
+		"WritePackedDeltaInt64(m.Scores)":                               0,
+	} {
+		if got := strings.Count(output, want); got != count {
+			t.Errorf("expected %d occurrence(s) of %q, got %d:\n%s", count, want, got, output)
+		}
+	}
+
+	if err := NewTypeScriptGenerator().Generate(&bytes.Buffer{}, s, DefaultOptions()); err == nil {
+		t.Error("TypeScript generator accepted a delta encoded field")
+	}
+	if err := NewRustGenerator().Generate(&bytes.Buffer{}, s, DefaultOptions()); err == nil {
+		t.Error("Rust generator accepted a delta encoded field")
+	}
+}
+
+func TestGoGeneratorBytesDefault(t *testing.T) {
+	input := `package test;
+message Frame {
+  bytes magic = 1 [default = 0x0A1B2C];
+  bytes prefix = 2 [default = b64"/wA="];
+  bytes body = 3;
+}
+`
+	s, errs := schema.ParseFile("frame.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for want, count := range map[string]int{
+		"m.Magic = []byte{0x0a, 0x1b, 0x2c}": 1,
+		"m.Prefix = []byte{0xff, 0x00}":      1,
+		// Empty values are written so that decoding doesn't restore the default
+		"if len(m.Magic) > 0 {":  0,
+		"if len(m.Prefix) > 0 {": 0,
+		"if len(m.Body) > 0 {":   1,
+	} {
+		if got := strings.Count(output, want); got != count {
+			t.Errorf("expected %d occurrence(s) of %q, got %d:\n%s", count, want, got, output)
+		}
+	}
+	// Defaults are set before the first field is read
+	if strings.Index(output, "m.Magic = []byte{") > strings.Index(output, "r.ReadCompactTag()") {
+		t.Errorf("expected the default to be set before decoding fields:\n%s", output)
+	}
+
+	if err := NewTypeScriptGenerator().Generate(&bytes.Buffer{}, s, DefaultOptions()); err == nil {
+		t.Error("TypeScript generator accepted a bytes default")
+	}
+	if err := NewRustGenerator().Generate(&bytes.Buffer{}, s, DefaultOptions()); err == nil {
+		t.Error("Rust generator accepted a bytes default")
+	}
+}
+
+func TestGoGeneratorWireFormat(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{Name: "Item", Fields: []*schema.Field{
+				{Name: "id", Number: 1, Type: &schema.ScalarType{Name: "int64"}},
+				{Name: "name", Number: 2, Type: &schema.ScalarType{Name: "string"}},
+				{Name: "score", Number: 3, Type: &schema.ScalarType{Name: "float64"}},
+			}},
+		},
+	}
+
+	generate := func(format WireFormat) string {
+		t.Helper()
+		opts := DefaultOptions()
+		opts.WireFormat = format
+		var buf bytes.Buffer
+		if err := NewGoGenerator().Generate(&buf, s, opts); err != nil {
+			t.Fatalf("generate error: %v", err)
+		}
+		return buf.String()
+	}
+
+	v2 := []string{
+		"w.WriteCompactTag(1, cramberry.WireTypeV2SVarint)",
+		"w.WriteCompactTag(2, cramberry.WireTypeV2Bytes)",
+		"w.WriteCompactTag(3, cramberry.WireTypeV2Fixed64)",
+		"w.WriteEndMarker()",
+		"r.ReadCompactTag()",
+		"r.SkipValueV2(wireType)",
+	}
+	v1 := []string{
+		"w.WriteTag(1, cramberry.WireSVarint)",
+		"w.WriteTag(2, cramberry.WireBytes)",
+		"w.WriteTag(3, cramberry.WireFixed64)",
+		"pos := w.BeginMessage()",
+		"w.EndMessage(pos)",
+		"r.ReadTag()",
+		"end := r.BeginMessage()",
+		"r.SkipValue(wireType)",
+	}
+
+	for _, format := range []WireFormat{"", WireFormatV2} {
+		output := generate(format)
+		for _, want := range v2 {
+			if !strings.Contains(output, want) {
+				t.Errorf("format %q: expected %q in output", format, want)
+			}
+		}
+		if strings.Contains(output, "w.WriteTag(") || strings.Contains(output, "r.ReadTag()") {
+			t.Errorf("format %q: expected no V1 tags:\n%s", format, output)
+		}
+	}
+
+	output := generate(WireFormatV1)
+	for _, want := range v1 {
+		if !strings.Contains(output, want) {
+			t.Errorf("format v1: expected %q in output:\n%s", want, output)
+		}
+	}
+	for _, unwanted := range []string{"WriteCompactTag", "ReadCompactTag", "SkipValueV2", "WriteEndMarker"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("format v1: expected no %s in output:\n%s", unwanted, output)
+		}
+	}
+
+	opts := DefaultOptions()
+	opts.WireFormat = "v3"
+	if err := NewGoGenerator().Generate(&bytes.Buffer{}, s, opts); err == nil {
+		t.Error("expected an error for an unknown wire format")
+	}
+}
+
+func TestGoGeneratorPreserveUnknown(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{Name: "User", Fields: []*schema.Field{
+				{Name: "name", Number: 1, Type: &schema.ScalarType{Name: "string"}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if output := buf.String(); strings.Contains(output, "unknownFields") {
+		t.Errorf("unknownFields generated without PreserveUnknown:\n%s", output)
+	}
+
+	opts := DefaultOptions()
+	opts.PreserveUnknown = true
+	buf.Reset()
+	if err := NewGoGenerator().Generate(&buf, s, opts); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"unknownFields []byte",
+		"w.WriteRawBytes(m.unknownFields)",
+		"m.unknownFields = m.unknownFields[:0]",
+		"start := r.Pos()",
+		"m.unknownFields = append(m.unknownFields, r.Data()[start:r.Pos()]...)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	// Unknown fields are written after the known ones, before the end marker
+	encode := output[strings.Index(output, "func (m *User) EncodeTo"):]
+	known := strings.Index(encode, "w.WriteString(m.Name)")
+	raw := strings.Index(encode, "w.WriteRawBytes(m.unknownFields)")
+	end := strings.Index(encode, "w.WriteEndMarker()")
+	if known >= raw || raw >= end {
+		t.Errorf("expected known fields, unknown fields, then the end marker:\n%s", encode)
+	}
+}
+
+func TestGoGeneratorValidateOnDecode(t *testing.T) {
+	input := `package test;
+message Order {
+  required string id = 1;
+}
+message Note {
+  string text = 1;
+}
+`
+	s, errs := schema.ParseFile("order.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	const validateCall = "return m.Validate()"
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if strings.Contains(buf.String(), validateCall) {
+		t.Errorf("Validate called without ValidateOnDecode:\n%s", buf.String())
+	}
+
+	opts := DefaultOptions()
+	opts.ValidateOnDecode = true
+	buf.Reset()
+	if err := NewGoGenerator().Generate(&buf, s, opts); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	// Only Order has a Validate method to call
+	if n := strings.Count(output, validateCall); n != 1 {
+		t.Errorf("found %d Validate calls, want 1:\n%s", n, output)
+	}
+	want := "m.DecodeFrom(r)\n\tif err := r.Err(); err != nil {\n\t\treturn err\n\t}\n\treturn m.Validate()"
+	if !strings.Contains(output, want) {
+		t.Errorf("expected %q in output:\n%s", want, output)
+	}
+}
+
+func TestGoGeneratorRequiredGetters(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{Name: "Account", Fields: []*schema.Field{
+				{Name: "id", Number: 1, Required: true, Type: &schema.ScalarType{Name: "int64"}},
+				{Name: "owner", Number: 2, Required: true, Type: &schema.PointerType{Element: &schema.NamedType{Name: "Account"}}},
+				{Name: "tags", Number: 3, Required: true, Type: &schema.ArrayType{Element: &schema.ScalarType{Name: "string"}}},
+				{Name: "note", Number: 4, Type: &schema.ScalarType{Name: "string"}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if output := buf.String(); strings.Contains(output, "MustGet") || strings.Contains(output, "GetIdErr") {
+		t.Errorf("required getters generated without RequiredGetters:\n%s", output)
+	}
+
+	opts := DefaultOptions()
+	opts.RequiredGetters = true
+	buf.Reset()
+	if err := NewGoGenerator().Generate(&buf, s, opts); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"func (m *Account) GetIdErr() (int64, error) {",
+		"return *m.Id, nil",
+		"func (m *Account) MustGetId() int64 {",
+		"func (m *Account) GetOwnerErr() (*Account, error) {",
+		"return m.Owner, nil",
+		"func (m *Account) MustGetOwner() *Account {",
+		`cramberry.NewValidationError("Account", "id", "required field is missing")`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	// Only fields that are nil when unset get getters
+	for _, unwanted := range []string{"GetTagsErr", "GetNoteErr"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("unexpected %s in output:\n%s", unwanted, output)
+		}
+	}
+}
+
+func TestGoGeneratorPackBools(t *testing.T) {
+	input := `package test;
+message Flags {
+  bool dark_mode = 1;
+  bool beta = 2;
+  bool metrics = 3;
+  string name = 4;
+  bool lonely = 5;
+  optional bool opt_in = 6;
+  bool audit = 7;
+  bool trace = 9;
+  bool debug = 8;
+}
+`
+	s, errs := schema.ParseFile("flags.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if strings.Contains(buf.String(), "PackBools") {
+		t.Errorf("bools packed without PackBools:\n%s", buf.String())
+	}
+
+	opts := DefaultOptions()
+	opts.PackBools = true
+	buf.Reset()
+	if err := NewGoGenerator().Generate(&buf, s, opts); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		// The run of three bools is written under the first one's number
+		"// Fields dark_mode, beta, metrics are packed into one bitmask\n" +
+			"\tif mask := cramberry.PackBools(m.DarkMode, m.Beta, m.Metrics); mask != 0 {\n" +
+			"\t\tw.WriteCompactTag(1, cramberry.WireTypeV2Varint)\n" +
+			"\t\tw.WriteUvarint(mask)\n\t}\n\tif m.Name != \"\" {",
+		"case 1:\n\t\t\tcramberry.UnpackBools(r.ReadUvarint(), &m.DarkMode, &m.Beta, &m.Metrics)",
+		// The other numbers of the run still decode bools written one each
+		"case 2:\n\t\t\tm.Beta = r.ReadBool()",
+		// A run ends where the field numbers stop increasing
+		"cramberry.PackBools(m.Audit, m.Trace)",
+		"w.WriteCompactTag(5, cramberry.WireTypeV2Varint)\n\t\tw.WriteBool(m.Lonely)",
+		"w.WriteCompactTag(8, cramberry.WireTypeV2Varint)\n\t\tw.WriteBool(m.Debug)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	for _, unwanted := range []string{"w.WriteBool(m.Beta)", "w.WriteBool(m.Trace)", "PackBools(m.Lonely", "PackBools(m.OptIn", "m.OptIn, m.Audit"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("unexpected %q in output:\n%s", unwanted, output)
+		}
+	}
 }
 
-func TestGoGeneratorEnum(t *testing.T) {
-	s := &schema.Schema{
-		Package: &schema.Package{Name: "test"},
-		Enums: []*schema.Enum{
-			{
-				Name: "Status",
-				Values: []*schema.EnumValue{
-					{Name: "UNKNOWN", Number: 0},
-					{Name: "ACTIVE", Number: 1},
-					{Name: "INACTIVE", Number: 2},
-				},
-			},
-		},
+func TestGoGeneratorPresenceDecode(t *testing.T) {
+	input := `package test;
+message Patch {
+  string name = 1;
+  int32 size = 2;
+}
+`
+	s, errs := schema.ParseFile("patch.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
 	}
 
-	gen := NewGoGenerator()
 	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if strings.Contains(buf.String(), "DecodeWithPresence") || strings.Contains(buf.String(), "decodeFrom") {
+		t.Errorf("presence decoding generated without GeneratePresenceDecode:\n%s", buf.String())
+	}
+
 	opts := DefaultOptions()
+	opts.GeneratePresenceDecode = true
+	buf.Reset()
+	if err := NewGoGenerator().Generate(&buf, s, opts); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
 
-	err := gen.Generate(&buf, s, opts)
-	if err != nil {
+	for _, want := range []string{
+		"func (m *Patch) DecodeWithPresence(data []byte) (map[int]bool, error) {",
+		"m.decodeFrom(r, present)",
+		"func (m *Patch) DecodeFrom(r *cramberry.Reader) {\n\tm.decodeFrom(r, nil)\n}",
+		"func (m *Patch) decodeFrom(r *cramberry.Reader, present map[int]bool) {",
+		"if present != nil {\n\t\t\tpresent[fieldNum] = true\n\t\t}",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
+func TestGoGeneratorGroups(t *testing.T) {
+	input := `package test;
+message Order {
+  string id = 1;
+  optional group billing_address = 2 {
+    string street = 1;
+    group geo = 2 {
+      float64 lat = 1;
+    }
+  }
+  repeated group lines = 3 {
+    string sku = 1;
+  }
+}
+`
+	s, errs := schema.ParseFile("order.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
 		t.Fatalf("generate error: %v", err)
 	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"type OrderBillingAddress struct",
+		"type OrderBillingAddressGeo struct",
+		"type OrderLines struct",
+		"BillingAddress *OrderBillingAddress `cramberry:\"2",
+		"Geo OrderBillingAddressGeo `cramberry:\"2\"",
+		"Lines []OrderLines `cramberry:\"3\"",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in generated code", want)
+		}
+	}
+}
+
+func TestGoGeneratorJSONDirective(t *testing.T) {
+	input := `package test;
+message Account {
+  ///@json:"accountId"
+  int64 account_id = 1;
+  ///@json:"displayName"
+  optional string display_name = 2;
+  string email = 3;
+}
+`
+	s, errs := schema.ParseFile("account.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
 
+	opts := DefaultOptions()
+	opts.JSONConverters = true
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, opts); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
 	output := buf.String()
 
-	// Check enum type
-	if !strings.Contains(output, "type Status int32") {
-		t.Error("expected Status type")
+	for _, want := range []string{
+		`json:"accountId"`,
+		`json:"displayName,omitempty"`,
+		`json:"email"`,
+		`out["accountId"] = m.AccountId`,
+		`cramberry.JSONField(v, "displayName", &m.DisplayName)`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
 	}
+	if strings.Contains(output, `"account_id"`) {
+		t.Errorf("default JSON name still used:\n%s", output)
+	}
+}
 
-	// Check enum values
-	if !strings.Contains(output, "StatusUnknown Status = 0") {
-		t.Errorf("expected StatusUnknown, got: %s", output)
+func TestGoGeneratorJSONConverters(t *testing.T) {
+	input := `package test;
+message Point {
+  int32 x = 1;
+}
+message Shape {
+  required string name = 1;
+  optional int32 sides = 2;
+  Point center = 3;
+  *Point anchor = 4;
+  []Point vertices = 5;
+  map[string]string tags = 6 [ordered = true];
+}
+`
+	s, errs := schema.ParseFile("shape.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
 	}
-	if !strings.Contains(output, "StatusActive") {
-		t.Error("expected StatusActive")
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if strings.Contains(buf.String(), "ToJSON") {
+		t.Errorf("JSON converters generated without JSONConverters:\n%s", buf.String())
 	}
 
-	// Check String method
-	if !strings.Contains(output, "func (e Status) String() string") {
-		t.Error("expected String method")
+	opts := DefaultOptions()
+	opts.JSONConverters = true
+	buf.Reset()
+	if err := NewGoGenerator().Generate(&buf, s, opts); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"func (m *Shape) ToJSON() map[string]any {",
+		"func (m *Shape) FromJSON(v map[string]any) error {",
+		// Unset pointers are left out
+		"if m.Name != nil {\n\t\tout[\"name\"] = *m.Name",
+		"if m.Sides != nil {\n\t\tout[\"sides\"] = *m.Sides",
+		// Messages convert themselves
+		`out["center"] = m.Center.ToJSON()`,
+		"if m.Anchor != nil {\n\t\tout[\"anchor\"] = m.Anchor.ToJSON()",
+		`out["vertices"] = m.Vertices`,
+		"for k, v := range m.Tags.All() {",
+		"*m = Shape{}",
+		`cramberry.JSONField(v, "sides", &m.Sides)`,
+		"m.Tags.Set(k, entries[k])",
+		`cramberry.JSONMessage(v, "center", &m.Center)`,
+		`cramberry.JSONMessage(v, "anchor", &nested)`,
+		"m.Anchor = &nested",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	opts.GenerateJSON = false
+	if err := NewGoGenerator().Generate(&bytes.Buffer{}, s, opts); err == nil {
+		t.Error("expected an error for JSON converters without JSON tags")
+	}
+
+	s.Interfaces = []*schema.Interface{{Name: "Figure"}}
+	s.Messages[1].Fields = append(s.Messages[1].Fields, &schema.Field{
+		Name: "figure", Number: 7, Type: &schema.ArrayType{Element: &schema.NamedType{Name: "Figure"}},
+	})
+	opts.GenerateJSON = true
+	if err := NewGoGenerator().Generate(&bytes.Buffer{}, s, opts); err == nil || !strings.Contains(err.Error(), "Shape.figure") {
+		t.Errorf("expected an error for an interface field, got %v", err)
+	}
+}
+
+func TestGoGeneratorPools(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{Name: "User", Fields: []*schema.Field{
+				{Name: "name", Number: 1, Type: &schema.ScalarType{Name: "string"}},
+				{Name: "tags", Number: 2, Type: &schema.ArrayType{Element: &schema.ScalarType{Name: "string"}}},
+				{Name: "scores", Number: 3, Type: &schema.MapType{
+					Key:   &schema.ScalarType{Name: "string"},
+					Value: &schema.ScalarType{Name: "int32"},
+				}},
+				{Name: "manager", Number: 4, Type: &schema.PointerType{Element: &schema.NamedType{Name: "User"}}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if output := buf.String(); strings.Contains(output, `"sync"`) || strings.Contains(output, "Reset()") {
+		t.Errorf("pool helpers generated without GeneratePools:\n%s", output)
+	}
+
+	opts := DefaultOptions()
+	opts.GeneratePools = true
+	buf.Reset()
+	if err := NewGoGenerator().Generate(&buf, s, opts); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		`"sync"`,
+		"var userPool = sync.Pool{",
+		"func GetUser() *User {",
+		"func PutUser(m *User) {",
+		"m.Reset()",
+		"func (m *User) Reset() {",
+		"clear(m.Tags)",
+		"clear(m.Scores)",
+		"Tags: m.Tags[:0],",
+		"Scores: m.Scores,",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	// Scalars and pointers are zeroed by the struct assignment
+	if strings.Contains(output, "Manager: ") || strings.Contains(output, "Name: ") {
+		t.Errorf("expected only slices and maps to be retained:\n%s", output)
 	}
 }
 
@@ -147,10 +1318,38 @@ func TestGoGeneratorInterface(t *testing.T) {
 		t.Error("expected isAnimal for Cat")
 	}
 
+	// Check compile-time implementation assertions
+	for _, impl := range []string{"Dog", "Cat"} {
+		if want := "_ Animal = (*" + impl + ")(nil)"; !strings.Contains(output, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+
 	// Check TypeID function
 	if !strings.Contains(output, "func AnimalTypeID(v Animal) cramberry.TypeID") {
 		t.Error("expected AnimalTypeID function")
 	}
+
+	// Check dispatch helpers
+	if !strings.Contains(output, "func MarshalAnimal(v Animal) ([]byte, error)") {
+		t.Error("expected MarshalAnimal function")
+	}
+	if !strings.Contains(output, "func UnmarshalAnimal(data []byte) (Animal, error)") {
+		t.Error("expected UnmarshalAnimal function")
+	}
+	if !strings.Contains(output, "case 129:\n\t\tm := &Cat{}") {
+		t.Error("expected UnmarshalAnimal to construct Cat for type ID 129")
+	}
+
+	// Dispatch helpers depend on the generated marshal methods
+	opts.GenerateMarshal = false
+	buf.Reset()
+	if err := gen.Generate(&buf, s, opts); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if strings.Contains(buf.String(), "MarshalAnimal") {
+		t.Error("MarshalAnimal should not be generated without marshal methods")
+	}
 }
 
 func TestGoGeneratorModifiers(t *testing.T) {
@@ -243,6 +1442,12 @@ func TestGoGeneratorComplexTypes(t *testing.T) {
 	if !strings.Contains(output, "Scores map[string]int32") {
 		t.Error("expected map type")
 	}
+	if !strings.Contains(output, "m.Scores = make(map[string]int32, n)") {
+		t.Errorf("expected map sized from its header, got: %s", output)
+	}
+	if !strings.Contains(output, "_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {") {
+		t.Errorf("expected map decoding through ReadMapEntries, got: %s", output)
+	}
 
 	// Check pointer
 	if !strings.Contains(output, "User *User") {
@@ -250,6 +1455,181 @@ func TestGoGeneratorComplexTypes(t *testing.T) {
 	}
 }
 
+func TestGoGeneratorNestedSlices(t *testing.T) {
+	int32Slice := &schema.ArrayType{Element: &schema.ScalarType{Name: "int32"}}
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{
+				Name: "Matrix",
+				Fields: []*schema.Field{
+					{Name: "rows", Number: 1, Type: &schema.ArrayType{Element: int32Slice}},
+					{Name: "grid", Number: 2, Type: &schema.ArrayType{Element: int32Slice}},
+				},
+			},
+		},
+	}
+
+	gen := NewGoGenerator()
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	// Each field is a slice of slices
+	if !strings.Contains(output, "Rows [][]int32") {
+		t.Errorf("expected Rows [][]int32, got: %s", output)
+	}
+	if !strings.Contains(output, "Grid [][]int32") {
+		t.Errorf("expected Grid [][]int32, got: %s", output)
+	}
+
+	// Each level writes its own length prefix
+	if !strings.Contains(output, "for _, v1 := range v {") {
+		t.Errorf("expected inner encode loop over each row, got: %s", output)
+	}
+
+	// Inner decode loops use their own index variable
+	if !strings.Contains(output, "m.Rows[i] = make([]int32, n)") {
+		t.Errorf("expected inner slice allocation per row, got: %s", output)
+	}
+	if !strings.Contains(output, "m.Rows[i][i1] = r.ReadInt32()") {
+		t.Errorf("expected nested index variables, got: %s", output)
+	}
+	if strings.Contains(output, "m.Grid = &tmp") {
+		t.Errorf("slice field should decode in place, got: %s", output)
+	}
+}
+
+func TestGoGeneratorDecodeWarnings(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{
+				Name: "User",
+				Fields: []*schema.Field{
+					{Name: "id", Number: 1, Type: &schema.ScalarType{Name: "int32"}},
+					{Name: "nick", Number: 2, Type: &schema.ScalarType{Name: "string"}, Deprecated: true},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGoGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		`cramberry:"2,deprecated"`,
+		`r.Warn(cramberry.WarningDeprecatedField, 2, "deprecated field nick is present")`,
+		`r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of User")`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "WarningDeprecatedField") != 1 {
+		t.Errorf("only the deprecated field should warn, got:\n%s", output)
+	}
+}
+
+func TestGoGeneratorFraming(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{
+				Name: "Header",
+				Options: []*schema.Option{
+					{Name: "framing", Value: &schema.StringValue{Value: schema.FramingLength}},
+				},
+				Fields: []*schema.Field{
+					{Name: "id", Number: 1, Type: &schema.ScalarType{Name: "int32"}},
+				},
+			},
+			{
+				Name: "Body",
+				Fields: []*schema.Field{
+					{Name: "text", Number: 1, Type: &schema.ScalarType{Name: "string"}},
+				},
+			},
+		},
+	}
+
+	gen := NewGoGenerator()
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	// Only Header is length-prefixed; Body keeps its end marker
+	for want, count := range map[string]int{
+		"pos := w.BeginMessage()":             1,
+		"w.EndMessage(pos)":                   1,
+		"end := r.BeginMessage()":             1,
+		"for r.Err() == nil && r.Pos() < end": 1,
+		"r.EndMessage(end)":                   1,
+		"w.WriteEndMarker()":                  1,
+	} {
+		if got := strings.Count(output, want); got != count {
+			t.Errorf("expected %d occurrence(s) of %q, got %d", count, want, got)
+		}
+	}
+
+	if err := NewTypeScriptGenerator().Generate(&bytes.Buffer{}, s, DefaultOptions()); err == nil {
+		t.Error("TypeScript generator accepted a length-framed message")
+	}
+}
+
+func TestGoGeneratorAnyField(t *testing.T) {
+	anyType := &schema.ScalarType{Name: "any"}
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{
+				Name: "Envelope",
+				Fields: []*schema.Field{
+					{Name: "payload", Number: 1, Type: anyType},
+					{Name: "items", Number: 2, Type: anyType, Repeated: true},
+					{Name: "named", Number: 3, Type: &schema.MapType{Key: &schema.ScalarType{Name: "string"}, Value: anyType}},
+				},
+			},
+		},
+	}
+
+	gen := NewGoGenerator()
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"Payload any",
+		"Items []any",
+		"Named map[string]any",
+		"if m.Payload != nil {",
+		"w.WriteAny(m.Payload)",
+		"m.Payload = r.ReadAny()",
+		"w.WriteAny(v)",
+		"m.Items[i] = r.ReadAny()",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output: %s", want, output)
+		}
+	}
+
+	// Other generators reject the Go-only any type
+	for _, other := range []Generator{NewTypeScriptGenerator(), NewRustGenerator()} {
+		if err := other.Generate(&bytes.Buffer{}, s, DefaultOptions()); err == nil {
+			t.Errorf("%s generator accepted an any field", other.Language())
+		}
+	}
+}
+
 func TestGoGeneratorOptions(t *testing.T) {
 	s := &schema.Schema{
 		Package: &schema.Package{Name: "test"},
@@ -331,6 +1711,30 @@ func TestGoGeneratorOptions(t *testing.T) {
 			t.Error("expected no json tags")
 		}
 	})
+
+	t.Run("omit top-level end marker", func(t *testing.T) {
+		gen := NewGoGenerator()
+		var buf bytes.Buffer
+		opts := DefaultOptions()
+		opts.OmitTopLevelEndMarker = true
+
+		err := gen.Generate(&buf, s, opts)
+		if err != nil {
+			t.Fatalf("generate error: %v", err)
+		}
+
+		output := buf.String()
+		if !strings.Contains(output, "return data[:len(data)-1], nil") {
+			t.Error("expected MarshalCramberry to drop the end marker")
+		}
+		if !strings.Contains(output, "opts.OmitTopLevelEndMarker = true") {
+			t.Error("expected UnmarshalCramberry to accept a missing end marker")
+		}
+		// Nested encoding is unchanged
+		if !strings.Contains(output, "w.WriteEndMarker()") {
+			t.Error("expected EncodeTo to keep writing the end marker")
+		}
+	})
 }
 
 func TestCaseConversions(t *testing.T) {
@@ -407,6 +1811,78 @@ func TestGeneratorRegistry(t *testing.T) {
 	}
 }
 
+// stubGenerator is a generator for a made-up language, standing in for one
+// provided by another package.
+type stubGenerator struct{ lang Language }
+
+func (g stubGenerator) Generate(w io.Writer, s *schema.Schema, _ Options) error {
+	_, err := fmt.Fprintf(w, "# %s\n", s.Package.Name)
+	return err
+}
+
+func (g stubGenerator) Language() Language    { return g.lang }
+func (g stubGenerator) FileExtension() string { return ".stub" }
+
+func TestRegisterPlugin(t *testing.T) {
+	const lang Language = "stub"
+	Register(stubGenerator{lang})
+	t.Cleanup(func() { Unregister(lang) })
+
+	gen, ok := Get(lang)
+	if !ok {
+		t.Fatal("stub generator not registered")
+	}
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, &schema.Schema{Package: &schema.Package{Name: "test"}}, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if buf.String() != "# test\n" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+
+	langs := Languages()
+	if !slices.Contains(langs, lang) || !slices.Contains(langs, LanguageGo) {
+		t.Errorf("Languages() = %v, want stub and the built-in languages", langs)
+	}
+	if !slices.IsSorted(langs) {
+		t.Errorf("Languages() = %v, want sorted", langs)
+	}
+
+	// A second generator for the same language is rejected
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("registering a language twice did not panic")
+			}
+		}()
+		Register(stubGenerator{lang})
+	}()
+
+	Unregister(lang)
+	if _, ok := Get(lang); ok {
+		t.Error("stub generator still registered after Unregister")
+	}
+	if slices.Contains(Languages(), lang) {
+		t.Error("stub language still listed after Unregister")
+	}
+}
+
+func TestRegisterConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := range 8 {
+		lang := Language(fmt.Sprintf("stub%d", i))
+		wg.Go(func() {
+			Register(stubGenerator{lang})
+			if _, ok := Get(lang); !ok {
+				t.Errorf("%s not registered", lang)
+			}
+			_ = Languages()
+			Unregister(lang)
+		})
+	}
+	wg.Wait()
+}
+
 func TestIndent(t *testing.T) {
 	input := "line1\nline2\nline3"
 	expected := "\t\tline1\n\t\tline2\n\t\tline3"
@@ -0,0 +1,57 @@
+package codegen
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/blockberries/cramberry/pkg/schema"
+)
+
+// GenerateGoFuzz writes a Go test file containing one native fuzz target per
+// message in the schema. Each target feeds arbitrary bytes to the generated
+// UnmarshalCramberry method and, when decoding succeeds, checks that the
+// decoded message can be marshaled again. The output belongs in the same
+// package as the code produced by the Go generator with the same options.
+func GenerateGoFuzz(w io.Writer, s *schema.Schema, opts Options) error {
+	ctx := &goContext{
+		Schema:  s,
+		Options: opts,
+	}
+
+	tmpl, err := template.New("gofuzz").Funcs(ctx.funcMap()).Parse(goFuzzTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	return tmpl.Execute(w, ctx)
+}
+
+const goFuzzTemplate = `// Code generated by cramberry. DO NOT EDIT.
+// Source: {{.Schema.Position.Filename}}
+
+package {{goPackage}}
+
+import (
+	"testing"
+)
+{{range $msg := .Schema.Messages}}
+// Fuzz{{goMessageType $msg}}Unmarshal checks that decoding arbitrary input into
+// {{goMessageType $msg}} never panics and that decoded values re-marshal.
+func Fuzz{{goMessageType $msg}}Unmarshal(f *testing.F) {
+	f.Add([]byte{0x00})
+	if seed, err := (&{{goMessageType $msg}}{}).MarshalCramberry(); err == nil {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var m {{goMessageType $msg}}
+		if err := m.UnmarshalCramberry(data); err != nil {
+			return
+		}
+		if _, err := m.MarshalCramberry(); err != nil {
+			t.Fatalf("re-marshal of decoded {{goMessageType $msg}} failed: %v", err)
+		}
+	})
+}
+{{end}}`
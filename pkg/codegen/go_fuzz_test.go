@@ -0,0 +1,85 @@
+package codegen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/schema"
+)
+
+func TestGenerateGoFuzz(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "fuzzed"},
+		Enums: []*schema.Enum{
+			{
+				Name: "Status",
+				Values: []*schema.EnumValue{
+					{Name: "UNKNOWN", Number: 0},
+					{Name: "ACTIVE", Number: 1},
+				},
+			},
+		},
+		Messages: []*schema.Message{
+			{
+				Name: "User",
+				Fields: []*schema.Field{
+					{Name: "id", Number: 1, Type: &schema.ScalarType{Name: "int64"}},
+					{Name: "name", Number: 2, Type: &schema.ScalarType{Name: "string"}},
+					{Name: "status", Number: 3, Type: &schema.NamedType{Name: "Status"}},
+				},
+			},
+			{
+				Name: "Group",
+				Fields: []*schema.Field{
+					{Name: "owner", Number: 1, Type: &schema.PointerType{Element: &schema.NamedType{Name: "User"}}},
+					{Name: "tags", Number: 2, Type: &schema.ScalarType{Name: "string"}, Repeated: true},
+				},
+			},
+		},
+	}
+
+	var code, fuzz bytes.Buffer
+	if err := NewGoGenerator().Generate(&code, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	if err := GenerateGoFuzz(&fuzz, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate fuzz error: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	codeFile, err := parser.ParseFile(fset, "fuzzed.go", code.String(), 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+	fuzzFile, err := parser.ParseFile(fset, "fuzzed_fuzz_test.go", fuzz.String(), 0)
+	if err != nil {
+		t.Fatalf("generated fuzz file does not parse: %v\n%s", err, fuzz.String())
+	}
+
+	fuzzFuncs := make(map[string]bool)
+	for _, decl := range fuzzFile.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && strings.HasPrefix(fn.Name.Name, "Fuzz") {
+			fuzzFuncs[fn.Name.Name] = true
+		}
+	}
+	for _, name := range []string{"FuzzUserUnmarshal", "FuzzGroupUnmarshal"} {
+		if !fuzzFuncs[name] {
+			t.Errorf("expected fuzz target %s", name)
+		}
+	}
+	if len(fuzzFuncs) != len(s.Messages) {
+		t.Errorf("expected %d fuzz targets, got %d", len(s.Messages), len(fuzzFuncs))
+	}
+
+	// Type-check the fuzz file together with the generated code it exercises.
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("fuzzed", fset, []*ast.File{codeFile, fuzzFile}, nil); err != nil {
+		t.Fatalf("generated fuzz file does not compile: %v", err)
+	}
+}
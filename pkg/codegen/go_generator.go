@@ -1,8 +1,10 @@
 package codegen
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"strings"
 	"text/template"
 
@@ -29,10 +31,27 @@ func (g *GoGenerator) FileExtension() string {
 
 // Generate produces Go code from a schema.
 func (g *GoGenerator) Generate(w io.Writer, s *schema.Schema, opts Options) error {
+	switch opts.WireFormat {
+	case "", WireFormatV1, WireFormatV2:
+	default:
+		return fmt.Errorf("unknown wire format %q: must be %q or %q", opts.WireFormat, WireFormatV1, WireFormatV2)
+	}
+
 	ctx := &goContext{
 		Schema:  s,
 		Options: opts,
 	}
+	if opts.PackBools {
+		ctx.boolRuns = ctx.packedBoolRuns()
+	}
+	if opts.JSONConverters {
+		if !opts.GenerateJSON {
+			return errors.New("JSON converters need JSON field tags; enable GenerateJSON")
+		}
+		if msg, f := ctx.interfaceField(); f != nil {
+			return fmt.Errorf("field %s.%s: JSON converters don't support interface types", msg.Name, f.Name)
+		}
+	}
 
 	tmpl, err := template.New("go").Funcs(ctx.funcMap()).Parse(goTemplate)
 	if err != nil {
@@ -46,6 +65,9 @@ func (g *GoGenerator) Generate(w io.Writer, s *schema.Schema, opts Options) erro
 type goContext struct {
 	Schema  *schema.Schema
 	Options Options
+
+	// boolRuns maps each bool field packed with PackBools to its run
+	boolRuns map[*schema.Field][]*schema.Field
 }
 
 func (c *goContext) funcMap() template.FuncMap {
@@ -60,6 +82,12 @@ func (c *goContext) funcMap() template.FuncMap {
 		"goEnumValueName":      c.goEnumValueName,
 		"fieldTag":             c.fieldTag,
 		"hasRequired":          c.hasRequired,
+		"hasRanges":            hasRanges,
+		"annotationDoc":        annotationDoc,
+		"rangeCheck":           c.rangeCheck,
+		"itemRangeCheck":       c.itemRangeCheck,
+		"validateDoc":          validateDoc,
+		"defaultValue":         defaultValue,
 		"needsPointer":         c.needsPointer,
 		"isPointerField":       c.isPointerField,
 		"isNilCheckable":       c.isNilCheckable,
@@ -74,6 +102,25 @@ func (c *goContext) funcMap() template.FuncMap {
 		"generateMarshal":      func() bool { return c.Options.GenerateMarshal },
 		"generateJSON":         func() bool { return c.Options.GenerateJSON },
 		"generateComments":     func() bool { return c.Options.GenerateComments },
+		"omitEndMarker":        func() bool { return c.Options.OmitTopLevelEndMarker },
+		"generatePools":        func() bool { return c.Options.GeneratePools && len(c.Schema.Messages) > 0 },
+		"preserveUnknown":      func() bool { return c.Options.PreserveUnknown },
+		"requiredGetters":      func() bool { return c.Options.RequiredGetters },
+		"validateOnDecode":     func() bool { return c.Options.ValidateOnDecode },
+		"presenceDecode":       func() bool { return c.Options.GeneratePresenceDecode },
+		"jsonConverters":       func() bool { return c.Options.JSONConverters },
+		"toJSONField":          c.toJSONField,
+		"fromJSONField":        c.fromJSONField,
+		"requiredGetterType":   c.requiredGetterType,
+		"derefRequired":        c.derefRequired,
+		"schemaHash":           func() string { return schemaHash(c.Schema) },
+		"poolVar":              c.poolVar,
+		"resetKind":            c.resetKind,
+		"lengthFramed":         c.lengthFramed,
+		"wireV1":               c.wireV1,
+		"wireName":             func() string { return strings.ToUpper(string(c.wireFormat())) },
+		"canonicalEnumValues":  canonicalEnumValues,
+		"fixed32Enum":          func(e *schema.Enum) bool { return e.Encoding() == schema.EnumEncodingFixed32 },
 		"wireTypeV2":           c.wireTypeV2,
 		"encodeFieldV2":        c.encodeFieldV2,
 		"decodeFieldV2":        c.decodeFieldV2,
@@ -112,16 +159,13 @@ func (c *goContext) wireTypeV2ForType(t schema.TypeRef, repeated bool) string {
 			return "cramberry.WireTypeV2Bytes"
 		}
 	case *schema.NamedType:
-		// Named types (enums, messages) - enums are svarint, messages are bytes.
-		// Only check local enums when the type has no package qualifier.
-		// Cross-package types are assumed to be messages; cross-package enum
-		// detection requires access to imported schemas which is not yet supported.
-		if typ.Package == "" {
-			for _, e := range c.Schema.Enums {
-				if e.Name == typ.Name {
-					return "cramberry.WireTypeV2SVarint"
-				}
+		// Named types (enums, messages) - enums are svarint or fixed32,
+		// messages are bytes
+		if e := enumOf(c.Schema, typ); e != nil {
+			if e.Encoding() == schema.EnumEncodingFixed32 {
+				return "cramberry.WireTypeV2Fixed32"
 			}
+			return "cramberry.WireTypeV2SVarint"
 		}
 		return "cramberry.WireTypeV2Bytes"
 	case *schema.ArrayType, *schema.MapType:
@@ -131,79 +175,281 @@ func (c *goContext) wireTypeV2ForType(t schema.TypeRef, repeated bool) string {
 	}
 }
 
+// wireFormat returns the selected wire format, defaulting to V2.
+func (c *goContext) wireFormat() WireFormat {
+	if c.Options.WireFormat == "" {
+		return WireFormatV2
+	}
+	return c.Options.WireFormat
+}
+
+// wireV1 reports whether the generated code uses the classic V1 tags.
+func (c *goContext) wireV1() bool {
+	return c.wireFormat() == WireFormatV1
+}
+
+// lengthFramed reports whether a message is prefixed with its length rather
+// than ended with an end marker. V1 has no end marker, so it frames every
+// message by length.
+func (c *goContext) lengthFramed(m *schema.Message) bool {
+	return c.wireV1() || m.Framing() == schema.FramingLength
+}
+
+// v1WireTypes maps V2 wire type constants to their V1 equivalents.
+var v1WireTypes = map[string]string{
+	"cramberry.WireTypeV2Varint":  "cramberry.WireVarint",
+	"cramberry.WireTypeV2Fixed64": "cramberry.WireFixed64",
+	"cramberry.WireTypeV2Bytes":   "cramberry.WireBytes",
+	"cramberry.WireTypeV2Fixed32": "cramberry.WireFixed32",
+	"cramberry.WireTypeV2SVarint": "cramberry.WireSVarint",
+}
+
+// writeTag returns the call that writes the tag of a field in the selected
+// wire format.
+func (c *goContext) writeTag(f *schema.Field) string {
+	if c.wireV1() {
+		return fmt.Sprintf("w.WriteTag(%d, %s)", f.Number, v1WireTypes[c.wireTypeV2(f)])
+	}
+	return fmt.Sprintf("w.WriteCompactTag(%d, %s)", f.Number, c.wireTypeV2(f))
+}
+
+// framedV1 reports whether a field is wrapped in a length prefix in V1.
+// Collections and any values are written with an element count or a type
+// ID rather than a byte length, so SkipValue could not step over them as
+// unknown fields without the prefix.
+func (c *goContext) framedV1(f *schema.Field) bool {
+	if !c.wireV1() || c.boolRuns[f] != nil {
+		return false
+	}
+	if f.Repeated {
+		return true
+	}
+	t := f.Type
+	if pt, ok := t.(*schema.PointerType); ok {
+		t = pt.Element
+	}
+	switch typ := t.(type) {
+	case *schema.ArrayType:
+		return !isFixedByteArray(typ)
+	case *schema.MapType:
+		return true
+	case *schema.ScalarType:
+		return typ.Name == "any"
+	default:
+		return false
+	}
+}
+
+// encodeFramedV1 wraps the code that writes the value of a field in a
+// length prefix if framedV1 reports that it needs one.
+func (c *goContext) encodeFramedV1(f *schema.Field, code string) string {
+	if !c.framedV1(f) {
+		return code
+	}
+	return fmt.Sprintf(`frame := w.BeginMessage()
+		%s
+		w.EndMessage(frame)`, code)
+}
+
+// maxPackedBools is the number of bools one varint bitmask holds.
+const maxPackedBools = 64
+
+// packedBoolRuns finds the bool fields that PackBools packs: runs of two or
+// more consecutive plain bool fields of a message, at most 64 long, whose
+// numbers increase. The first field of a run carries its bitmask, so an
+// encoder writing fields in declaration or number order writes it before
+// the others, and a bool written unpacked under that number reads as a
+// mask with only its own bit set.
+func (c *goContext) packedBoolRuns() map[*schema.Field][]*schema.Field {
+	runs := make(map[*schema.Field][]*schema.Field)
+	for _, msg := range c.Schema.Messages {
+		var run []*schema.Field
+		flush := func() {
+			if len(run) >= 2 {
+				for _, f := range run {
+					runs[f] = run
+				}
+			}
+			run = nil
+		}
+		for _, f := range msg.Fields {
+			st, ok := f.Type.(*schema.ScalarType)
+			if !ok || st.Name != "bool" || f.Repeated || f.Optional || f.Required {
+				flush()
+				continue
+			}
+			if len(run) == maxPackedBools || len(run) > 0 && f.Number < run[len(run)-1].Number {
+				flush()
+			}
+			run = append(run, f)
+		}
+		flush()
+	}
+	return runs
+}
+
 // encodeFieldV2 generates the encoding code for a field using V2 format.
+// It returns "" for packed bools other than the first of their run.
 func (c *goContext) encodeFieldV2(f *schema.Field) string {
 	fieldName := "m." + ToPascalCase(f.Name)
-	fieldNum := f.Number
+
+	// Packed bools are written together as a bitmask
+	if run := c.boolRuns[f]; run != nil {
+		if run[0] != f {
+			return ""
+		}
+		names := make([]string, len(run))
+		values := make([]string, len(run))
+		for i, rf := range run {
+			names[i] = rf.Name
+			values[i] = "m." + ToPascalCase(rf.Name)
+		}
+		return fmt.Sprintf(`// Fields %s are packed into one bitmask
+	if mask := cramberry.PackBools(%s); mask != 0 {
+		%s
+		w.WriteUvarint(mask)
+	}`, strings.Join(names, ", "), strings.Join(values, ", "), c.writeTag(f))
+	}
+
+	// Optional wrappers are written only when set
+	if c.isWrapperField(f) {
+		return fmt.Sprintf(`if %s.Set {
+		%s
+		%s
+	}`, fieldName, c.writeTag(f), c.encodeValueV2(f.Type, fieldName+".Value", false, 0))
+	}
+
+	// Ordered maps are written in insertion order rather than key order
+	if mt := c.orderedMap(f); mt != nil {
+		return fmt.Sprintf(`if %s.Len() > 0 {
+		%s
+		%s
+	}`, fieldName, c.writeTag(f), c.encodeFramedV1(f, fmt.Sprintf(`w.WriteUvarint(uint64(%s.Len()))
+		for k, v := range %s.All() {
+			%s
+			%s
+		}`, fieldName, fieldName, c.encodeValueV2(mt.Key, "k", false, 1), c.encodeValueV2(mt.Value, "v", false, 1))))
+	}
+
+	// Delta-encoded lists hold the first value and then the differences
+	if c.deltaList(f) {
+		return fmt.Sprintf(`if len(%s) > 0 {
+		%s
+		%s
+	}`, fieldName, c.writeTag(f), c.encodeFramedV1(f, fmt.Sprintf(`w.WriteUvarint(uint64(len(%s)))
+		w.WritePackedDeltaInt64(%s)`, fieldName, fieldName)))
+	}
 
 	// Handle pointers first
 	if c.isPointerField(f) {
-		return c.encodePointerFieldV2(f, fieldName, fieldNum)
+		return c.encodePointerFieldV2(f, fieldName)
 	}
 
 	// Handle repeated fields
 	if f.Repeated {
-		return c.encodeRepeatedFieldV2(f, fieldName, fieldNum)
+		return c.encodeRepeatedFieldV2(f, fieldName)
 	}
 
 	// Handle regular fields
-	return c.encodeScalarFieldV2(f, fieldName, fieldNum)
+	return c.encodeScalarFieldV2(f, fieldName)
 }
 
-func (c *goContext) encodePointerFieldV2(f *schema.Field, fieldName string, fieldNum int) string {
-	wireType := c.wireTypeV2(f)
-	inner := c.encodeValueV2(f.Type, fieldName, true)
+func (c *goContext) encodePointerFieldV2(f *schema.Field, fieldName string) string {
+	tag := c.writeTag(f)
+	inner := c.encodeFramedV1(f, c.encodeValueV2(f.Type, fieldName, true, 0))
+
+	// Slices and maps are never wrapped in a pointer; empty ones are
+	// omitted along with nil ones
+	if c.needsPointer(f.Type) {
+		return fmt.Sprintf(`if len(%s) > 0 {
+		%s
+		%s
+	}`, fieldName, tag, inner)
+	}
 
 	return fmt.Sprintf(`if %s != nil {
-		w.WriteCompactTag(%d, %s)
 		%s
-	}`, fieldName, fieldNum, wireType, inner)
+		%s
+	}`, fieldName, tag, inner)
 }
 
-func (c *goContext) encodeRepeatedFieldV2(f *schema.Field, fieldName string, fieldNum int) string {
-	wireType := c.wireTypeV2(f)
+func (c *goContext) encodeRepeatedFieldV2(f *schema.Field, fieldName string) string {
+	tag := c.writeTag(f)
 
 	// Check if it's a packable type
 	if c.isPackableType(f.Type) {
 		return fmt.Sprintf(`if len(%s) > 0 {
-		w.WriteCompactTag(%d, %s)
-		w.WriteUvarint(uint64(len(%s)))
+		%s
+		%s
+	}`, fieldName, tag, c.encodeFramedV1(f, fmt.Sprintf(`w.WriteUvarint(uint64(len(%s)))
 		for _, v := range %s {
 			%s
-		}
-	}`, fieldName, fieldNum, wireType, fieldName, fieldName, c.encodePackedElementV2(f.Type))
+		}`, fieldName, fieldName, c.encodePackedElementV2(f.Type))))
 	}
 
 	// Non-packable types (messages, strings, etc.)
 	// Note: range variable v is the value, not a pointer
 	return fmt.Sprintf(`if len(%s) > 0 {
-		w.WriteCompactTag(%d, %s)
-		w.WriteUvarint(uint64(len(%s)))
+		%s
+		%s
+	}`, fieldName, tag, c.encodeFramedV1(f, fmt.Sprintf(`w.WriteUvarint(uint64(len(%s)))
 		for _, v := range %s {
 			%s
-		}
-	}`, fieldName, fieldNum, wireType, fieldName, fieldName, c.encodeValueV2(f.Type, "v", false))
+		}`, fieldName, fieldName, c.encodeValueV2(f.Type, "v", false, 1))))
 }
 
-func (c *goContext) encodeScalarFieldV2(f *schema.Field, fieldName string, fieldNum int) string {
-	wireType := c.wireTypeV2(f)
+func (c *goContext) encodeScalarFieldV2(f *schema.Field, fieldName string) string {
+	tag := c.writeTag(f)
 	zeroCheck := c.zeroCheck(f)
-	inner := c.encodeValueV2(f.Type, fieldName, false)
+	inner := c.encodeFramedV1(f, c.encodeValueV2(f.Type, fieldName, false, 0))
 
-	// For optional fields, always emit if non-zero
-	if zeroCheck != "" {
+	// For optional fields, always emit if non-zero. Fields with a default
+	// are written even when empty, or decoding would restore the default.
+	if zeroCheck != "" && len(f.Default()) == 0 {
 		return fmt.Sprintf(`if %s {
-		w.WriteCompactTag(%d, %s)
 		%s
-	}`, zeroCheck, fieldNum, wireType, inner)
+		%s
+	}`, zeroCheck, tag, inner)
 	}
 
-	// Always emit for required fields
-	return fmt.Sprintf(`w.WriteCompactTag(%d, %s)
-	%s`, fieldNum, wireType, inner)
+	// Always emit for required fields, in a block of their own if the
+	// value declares a length prefix
+	if c.framedV1(f) {
+		return fmt.Sprintf(`{
+		%s
+		%s
+	}`, tag, inner)
+	}
+	return fmt.Sprintf(`%s
+	%s`, tag, inner)
+}
+
+// defaultValue returns a Go expression for the default of a field, or ""
+// if it has none.
+func defaultValue(f *schema.Field) string {
+	def := f.Default()
+	if len(def) == 0 {
+		return ""
+	}
+	elems := make([]string, len(def))
+	for i, b := range def {
+		elems[i] = fmt.Sprintf("0x%02x", b)
+	}
+	return "[]byte{" + strings.Join(elems, ", ") + "}"
+}
+
+// loopVar returns the name of a generated loop variable for the given nesting
+// depth, so that nested collection loops don't shadow their parents.
+func loopVar(name string, depth int) string {
+	if depth == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s%d", name, depth)
 }
 
-func (c *goContext) encodeValueV2(t schema.TypeRef, varName string, isPointer bool) string {
+// encodeValueV2 generates the encoding code for a value. depth is the number
+// of enclosing generated loops and selects fresh loop variable names.
+func (c *goContext) encodeValueV2(t schema.TypeRef, varName string, isPointer bool, depth int) string {
 	switch typ := t.(type) {
 	case *schema.ScalarType:
 		if isPointer {
@@ -215,28 +461,39 @@ func (c *goContext) encodeValueV2(t schema.TypeRef, varName string, isPointer bo
 		// For enums and messages, call EncodeTo (exported for cross-package access)
 		return fmt.Sprintf(`%s.EncodeTo(w)`, varName)
 	case *schema.ArrayType:
-		// Fixed-size arrays
-		if typ.Size > 0 {
-			return fmt.Sprintf(`w.WriteUvarint(uint64(len(%s)))
-		for _, v := range %s {
-			%s
-		}`, varName, varName, c.encodeValueV2(typ.Element, "v", false))
+		if isFixedByteArray(typ) {
+			return fmt.Sprintf("w.WriteBytes(%s[:])", varName)
 		}
-		// Dynamic slices
+		// Fixed-size arrays and dynamic slices both carry a length prefix
+		v := loopVar("v", depth)
 		return fmt.Sprintf(`w.WriteUvarint(uint64(len(%s)))
-		for _, v := range %s {
+		for _, %s := range %s {
 			%s
-		}`, varName, varName, c.encodeValueV2(typ.Element, "v", false))
+		}`, varName, v, varName, c.encodeValueV2(typ.Element, v, false, depth+1))
 	case *schema.MapType:
+		// ForEachMapEntry sorts the keys in deterministic mode, as the reflective
+		// encoder does, so both produce the same bytes
+		keyType := c.goTypeInternal(typ.Key, false)
+		valType := c.goTypeInternal(typ.Value, false)
+		k, v := loopVar("k", depth), loopVar("v", depth)
 		return fmt.Sprintf(`w.WriteUvarint(uint64(len(%s)))
-		for k, v := range %s {
+		cramberry.ForEachMapEntry(w, %s, func(%s %s, %s %s) {
 			%s
 			%s
-		}`, varName, varName, c.encodeValueV2(typ.Key, "k", false), c.encodeValueV2(typ.Value, "v", false))
+		})`, varName, varName, k, keyType, v, valType, c.encodeValueV2(typ.Key, k, false, depth+1), c.encodeValueV2(typ.Value, v, false, depth+1))
 	case *schema.PointerType:
-		// For pointer types, encode the underlying element
-		// The nil check should already be handled at the field level
-		return c.encodeValueV2(typ.Element, varName, true)
+		// For pointer types, encode the underlying element. Fields check
+		// for nil themselves; collection elements write a nil or present
+		// marker first, as the reflective encoder does
+		if depth == 0 {
+			return c.encodeValueV2(typ.Element, varName, true, depth)
+		}
+		return fmt.Sprintf(`if %s == nil {
+			w.WriteNil()
+		} else {
+			w.WritePresent()
+			%s
+		}`, varName, c.encodeValueV2(typ.Element, varName, true, depth))
 	default:
 		// This should not be reached for valid schema types
 		return fmt.Sprintf("/* unsupported type for encode: %T */", t)
@@ -275,6 +532,8 @@ func (c *goContext) encodeScalarV2(typeName, varName string) string {
 		return fmt.Sprintf("w.WriteString(%s)", varName)
 	case "bytes":
 		return fmt.Sprintf("w.WriteBytes(%s)", varName)
+	case "any":
+		return fmt.Sprintf("w.WriteAny(%s)", varName)
 	default:
 		// This should not be reached for valid scalar types
 		return fmt.Sprintf("/* unsupported scalar type: %s */", typeName)
@@ -285,26 +544,79 @@ func (c *goContext) encodePackedElementV2(t schema.TypeRef) string {
 	switch typ := t.(type) {
 	case *schema.ScalarType:
 		return c.encodeScalarV2(typ.Name, "v")
+	case *schema.NamedType:
+		// Enums are written inline, as their EncodeTo method writes them
+		if e := enumOf(c.Schema, typ); e != nil && e.Encoding() == schema.EnumEncodingFixed32 {
+			return "w.WriteFixed32(uint32(v))"
+		}
+		return "w.WriteInt32(int32(v))"
 	default:
 		// Packed encoding only supports scalar types
 		return fmt.Sprintf("/* unsupported packed element type: %T */", t)
 	}
 }
 
-// decodeFieldV2 generates the decoding code for a field using V2 format.
+// decodeFieldV2 generates the decoding code for a field, reading the length
+// prefix that V1 puts around collections.
 func (c *goContext) decodeFieldV2(f *schema.Field) string {
+	code := c.decodeFieldValueV2(f)
+	if !c.framedV1(f) {
+		return code
+	}
+	return fmt.Sprintf(`frame := r.BeginMessage()
+			%s
+			r.EndMessage(frame)`, code)
+}
+
+// decodeFieldValueV2 generates the decoding code for the value of a field.
+func (c *goContext) decodeFieldValueV2(f *schema.Field) string {
 	fieldName := "m." + ToPascalCase(f.Name)
+	if run := c.boolRuns[f]; run != nil && run[0] == f {
+		ptrs := make([]string, len(run))
+		for i, rf := range run {
+			ptrs[i] = "&m." + ToPascalCase(rf.Name)
+		}
+		return fmt.Sprintf("cramberry.UnpackBools(r.ReadUvarint(), %s)", strings.Join(ptrs, ", "))
+	}
+
+	if c.deltaList(f) {
+		return fmt.Sprintf("%s = r.ReadPackedDeltaInt64(r.ReadArrayHeader())", fieldName)
+	}
 
 	// Handle repeated fields first
 	if f.Repeated {
 		return c.decodeRepeatedFieldV2(f, fieldName)
 	}
 
+	if c.isWrapperField(f) {
+		return fmt.Sprintf(`%s
+		%s.Set = true`, c.decodeValueV2(f.Type, fieldName+".Value", 0), fieldName)
+	}
+
+	// Ordered maps are refilled in wire order, which is insertion order
+	if mt := c.orderedMap(f); mt != nil {
+		keyType, valType := c.goType(mt.Key), c.goType(mt.Value)
+		return fmt.Sprintf(`%s.Clear()
+		_ = r.ReadMap(func(r *cramberry.Reader) error {
+			var k %s
+			%s
+			var v %s
+			%s
+			%s.Set(k, v)
+			return nil
+		})`, fieldName, keyType, c.decodeValueV2(mt.Key, "k", 1), valType, c.decodeValueV2(mt.Value, "v", 1), fieldName)
+	}
+
 	// Handle maps - they're reference types, no pointer wrapping needed
 	if _, isMap := f.Type.(*schema.MapType); isMap {
 		return c.decodeMapFieldV2(f, fieldName)
 	}
 
+	// Slices are reference types too and decode in place
+	if arr, isArray := f.Type.(*schema.ArrayType); isArray && arr.Size == 0 {
+		return c.decodeValueV2(f.Type, fieldName, 0)
+	}
+
 	// Handle pointers (optional scalars or message fields)
 	if c.isPointerField(f) {
 		return c.decodePointerFieldV2(f, fieldName)
@@ -316,7 +628,7 @@ func (c *goContext) decodeFieldV2(f *schema.Field) string {
 
 func (c *goContext) decodePointerFieldV2(f *schema.Field, fieldName string) string {
 	goType := c.goTypeInternal(f.Type, false)
-	inner := c.decodeValueV2(f.Type, "tmp")
+	inner := c.decodeValueV2(f.Type, "tmp", 0)
 
 	return fmt.Sprintf(`var tmp %s
 		%s
@@ -324,23 +636,7 @@ func (c *goContext) decodePointerFieldV2(f *schema.Field, fieldName string) stri
 }
 
 func (c *goContext) decodeMapFieldV2(f *schema.Field, fieldName string) string {
-	mapType := f.Type.(*schema.MapType)
-	keyType := c.goTypeInternal(mapType.Key, false)
-	valType := c.goTypeInternal(mapType.Value, false)
-
-	// Use ReadMapHeader() for overflow-safe size reading
-	return fmt.Sprintf(`n := r.ReadMapHeader()
-		if r.Err() != nil {
-			return
-		}
-		%s = make(map[%s]%s, n)
-		for i := 0; i < n; i++ {
-			var k %s
-			%s
-			var v %s
-			%s
-			%s[k] = v
-		}`, fieldName, keyType, valType, keyType, c.decodeValueV2(mapType.Key, "k"), valType, c.decodeValueV2(mapType.Value, "v"), fieldName)
+	return c.decodeValueV2(f.Type, fieldName, 0)
 }
 
 func (c *goContext) decodeRepeatedFieldV2(f *schema.Field, fieldName string) string {
@@ -367,14 +663,16 @@ func (c *goContext) decodeRepeatedFieldV2(f *schema.Field, fieldName string) str
 		%s = make([]%s, n)
 		for i := 0; i < n; i++ {
 			%s
-		}`, fieldName, goType, c.decodeValueV2(f.Type, fieldName+"[i]"))
+		}`, fieldName, goType, c.decodeValueV2(f.Type, fieldName+"[i]", 1))
 }
 
 func (c *goContext) decodeScalarFieldV2(f *schema.Field, fieldName string) string {
-	return c.decodeValueV2(f.Type, fieldName)
+	return c.decodeValueV2(f.Type, fieldName, 0)
 }
 
-func (c *goContext) decodeValueV2(t schema.TypeRef, varName string) string {
+// decodeValueV2 generates the decoding code for a value. depth is the number
+// of enclosing generated loops and selects fresh loop variable names.
+func (c *goContext) decodeValueV2(t schema.TypeRef, varName string, depth int) string {
 	switch typ := t.(type) {
 	case *schema.ScalarType:
 		return c.decodeScalarV2(typ.Name, varName)
@@ -382,44 +680,55 @@ func (c *goContext) decodeValueV2(t schema.TypeRef, varName string) string {
 		// Named types are messages or enums (exported for cross-package access)
 		return fmt.Sprintf(`%s.DecodeFrom(r)`, varName)
 	case *schema.ArrayType:
+		if isFixedByteArray(typ) {
+			return fmt.Sprintf("r.ReadBytesInto(%s[:])", varName)
+		}
 		goType := c.goTypeInternal(typ.Element, true)
-		// Use ReadArrayHeader() for overflow-safe size reading
+		i := loopVar("i", depth)
+		// Use ReadArrayHeader() for overflow-safe size reading; it returns 0
+		// on error, and the error is checked once the field is decoded
 		return fmt.Sprintf(`{
 			n := r.ReadArrayHeader()
-			if r.Err() != nil {
-				return
-			}
 			%s = make([]%s, n)
-			for i := 0; i < n; i++ {
+			for %s := 0; %s < n; %s++ {
 				%s
 			}
-		}`, varName, goType, c.decodeValueV2(typ.Element, varName+"[i]"))
+		}`, varName, goType, i, i, i, c.decodeValueV2(typ.Element, varName+"["+i+"]", depth+1))
 	case *schema.MapType:
 		keyType := c.goTypeInternal(typ.Key, false)
 		valType := c.goTypeInternal(typ.Value, false)
-		// Use ReadMapHeader() for overflow-safe size reading
+		k, v := loopVar("k", depth), loopVar("v", depth)
+		// ReadMapHeader rejects headers that declare more entries than the
+		// data holds, so the map can be sized up front. Errors are recorded
+		// on r, which is checked once the field is decoded
 		return fmt.Sprintf(`{
 			n := r.ReadMapHeader()
-			if r.Err() != nil {
-				return
-			}
 			%s = make(map[%s]%s, n)
-			for i := 0; i < n; i++ {
-				var k %s
+			_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {
+				var %s %s
 				%s
-				var v %s
+				var %s %s
 				%s
-				%s[k] = v
-			}
-		}`, varName, keyType, valType, keyType, c.decodeValueV2(typ.Key, "k"), valType, c.decodeValueV2(typ.Value, "v"), varName)
+				%s[%s] = %s
+				return nil
+			})
+		}`, varName, keyType, valType, k, keyType, c.decodeValueV2(typ.Key, k, depth+1),
+			v, valType, c.decodeValueV2(typ.Value, v, depth+1), varName, k, v)
 	case *schema.PointerType:
 		// For pointer types, allocate and decode the underlying element
 		elemType := c.goTypeInternal(typ.Element, false)
-		return fmt.Sprintf(`{
-			var v %s
+		e := loopVar("v", depth)
+		alloc := fmt.Sprintf(`{
+			var %s %s
 			%s
-			%s = &v
-		}`, elemType, c.decodeValueV2(typ.Element, "v"), varName)
+			%s = &%s
+		}`, e, elemType, c.decodeValueV2(typ.Element, e, depth), varName, e)
+		if depth == 0 {
+			return alloc
+		}
+		// Collection elements start with a nil or present marker, and nil
+		// ones are left nil
+		return fmt.Sprintf(`if !r.ReadNil() %s`, alloc)
 	default:
 		// This should not be reached for valid schema types
 		return fmt.Sprintf("/* unsupported type for decode: %T */", t)
@@ -458,6 +767,8 @@ func (c *goContext) decodeScalarV2(typeName, varName string) string {
 		return fmt.Sprintf("%s = r.ReadString()", varName)
 	case "bytes":
 		return fmt.Sprintf("%s = r.ReadBytes()", varName)
+	case "any":
+		return fmt.Sprintf("%s = r.ReadAny()", varName)
 	default:
 		// This should not be reached for valid scalar types
 		return fmt.Sprintf("/* unsupported scalar type: %s */", typeName)
@@ -468,6 +779,12 @@ func (c *goContext) decodePackedElementV2(t schema.TypeRef, varName string) stri
 	switch typ := t.(type) {
 	case *schema.ScalarType:
 		return c.decodeScalarV2(typ.Name, varName)
+	case *schema.NamedType:
+		// Enums are read inline, as their DecodeFrom method reads them
+		if e := enumOf(c.Schema, typ); e != nil && e.Encoding() == schema.EnumEncodingFixed32 {
+			return fmt.Sprintf("%s = %s(r.ReadFixed32())", varName, c.goType(typ))
+		}
+		return fmt.Sprintf("%s = %s(r.ReadInt32())", varName, c.goType(typ))
 	default:
 		// Packed decoding only supports scalar types
 		return fmt.Sprintf("/* unsupported packed element type: %T */", t)
@@ -491,6 +808,8 @@ func (c *goContext) zeroCheck(f *schema.Field) string {
 			return fmt.Sprintf("%s != \"\"", fieldName)
 		case "bytes":
 			return fmt.Sprintf("len(%s) > 0", fieldName)
+		case "any":
+			return fmt.Sprintf("%s != nil", fieldName)
 		case "int8", "int16", "int32", "int64", "int",
 			"uint8", "uint16", "uint32", "uint64", "uint",
 			"float32", "float64", "byte":
@@ -501,14 +820,27 @@ func (c *goContext) zeroCheck(f *schema.Field) string {
 	case *schema.PointerType:
 		// Schema pointer types need nil check
 		return fmt.Sprintf("%s != nil", fieldName)
-	case *schema.NamedType, *schema.ArrayType, *schema.MapType:
-		return "" // Always encode nested types
+	case *schema.NamedType:
+		// Zero enums are omitted like other scalars; messages are always
+		// encoded, as the reflective encoder does for struct fields
+		if enumOf(c.Schema, typ) != nil {
+			return fmt.Sprintf("%s != 0", fieldName)
+		}
+		return ""
+	case *schema.ArrayType:
+		if typ.Size == 0 {
+			return fmt.Sprintf("len(%s) > 0", fieldName)
+		}
+		return "" // Fixed arrays always have their full length
+	case *schema.MapType:
+		return fmt.Sprintf("len(%s) > 0", fieldName)
 	default:
 		return ""
 	}
 }
 
 // isPackableType returns true if the type can be packed in a contiguous byte sequence.
+// Enums are packable, including those of imported schemas once resolved.
 func (c *goContext) isPackableType(t schema.TypeRef) bool {
 	switch typ := t.(type) {
 	case *schema.ScalarType:
@@ -520,6 +852,8 @@ func (c *goContext) isPackableType(t schema.TypeRef) bool {
 		default:
 			return false
 		}
+	case *schema.NamedType:
+		return enumOf(c.Schema, typ) != nil
 	default:
 		return false
 	}
@@ -559,14 +893,20 @@ func (c *goContext) goType(t schema.TypeRef) string {
 }
 
 func (c *goContext) goFieldType(f *schema.Field) string {
+	if mt := c.orderedMap(f); mt != nil {
+		return fmt.Sprintf("cramberry.OrderedMap[%s, %s]", c.goType(mt.Key), c.goType(mt.Value))
+	}
+
 	t := c.goTypeInternal(f.Type, false)
 
-	// Wrap repeated fields in slice
+	// Wrap repeated fields in slice; the validator rejects repeated []T,
+	// so a list of lists is always written [][]T
 	if f.Repeated {
-		// Don't double-wrap if already a slice
-		if _, isArray := f.Type.(*schema.ArrayType); !isArray {
-			t = "[]" + t
-		}
+		t = "[]" + t
+	}
+
+	if c.isWrapperField(f) {
+		return "cramberry.Optional[" + t + "]"
 	}
 
 	// Optional fields become pointers
@@ -646,6 +986,8 @@ func (c *goContext) goScalarType(name string) string {
 		return "string"
 	case "bytes":
 		return "[]byte"
+	case "any":
+		return "any"
 	default:
 		return name
 	}
@@ -673,6 +1015,27 @@ func (c *goContext) goEnumValueName(e *schema.Enum, v *schema.EnumValue) string
 	return enumName + valueName
 }
 
+// poolVar returns the name of the sync.Pool variable for a message.
+func (c *goContext) poolVar(m *schema.Message) string {
+	return ToCamelCase(c.goMessageType(m)) + "Pool"
+}
+
+// resetKind reports how Reset clears a field: "slice" fields are truncated,
+// "map" and "ordered" map fields are emptied, and all others are zeroed.
+func (c *goContext) resetKind(f *schema.Field) string {
+	t := c.goFieldType(f)
+	switch {
+	case c.orderedMap(f) != nil:
+		return "ordered"
+	case strings.HasPrefix(t, "[]"):
+		return "slice"
+	case strings.HasPrefix(t, "map["):
+		return "map"
+	default:
+		return ""
+	}
+}
+
 func (c *goContext) fieldTag(f *schema.Field) string {
 	var parts []string
 
@@ -684,13 +1047,18 @@ func (c *goContext) fieldTag(f *schema.Field) string {
 	if f.Optional {
 		cramTag += ",omitempty"
 	}
+	if f.Deprecated {
+		cramTag += ",deprecated"
+	}
 	parts = append(parts, fmt.Sprintf(`cramberry:"%s"`, cramTag))
 
 	// JSON tag if enabled
 	if c.Options.GenerateJSON {
-		jsonName := ToSnakeCase(f.Name)
-		jsonTag := jsonName
-		if f.Optional {
+		jsonTag := jsonName(f)
+		if c.isWrapperField(f) {
+			// omitempty never omits a struct; Optional implements IsZero
+			jsonTag += ",omitzero"
+		} else if f.Optional {
 			jsonTag += ",omitempty"
 		}
 		parts = append(parts, fmt.Sprintf(`json:"%s"`, jsonTag))
@@ -699,6 +1067,146 @@ func (c *goContext) fieldTag(f *schema.Field) string {
 	return strings.Join(parts, " ")
 }
 
+// jsonName returns the key of a field in JSON, which a ///@json directive
+// on the field overrides.
+func jsonName(f *schema.Field) string {
+	if name, ok := schema.LookupDirective(f.Directives, "json"); ok && name != "" {
+		return name
+	}
+	return ToSnakeCase(f.Name)
+}
+
+// localMessage reports whether t, or the type it points to, is a message
+// defined in this schema, which has its own ToJSON method.
+func (c *goContext) localMessage(t schema.TypeRef) bool {
+	if pt, ok := t.(*schema.PointerType); ok {
+		t = pt.Element
+	}
+	nt, ok := t.(*schema.NamedType)
+	if !ok || nt.Package != "" {
+		return false
+	}
+	for _, msg := range c.Schema.Messages {
+		if msg.Name == nt.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaceField returns the first field whose type refers to an interface
+// of this schema, which encoding/json can't decode, or nil if there is none.
+func (c *goContext) interfaceField() (*schema.Message, *schema.Field) {
+	ifaces := make(map[string]bool, len(c.Schema.Interfaces))
+	for _, iface := range c.Schema.Interfaces {
+		ifaces[iface.Name] = true
+	}
+	var refers func(t schema.TypeRef) bool
+	refers = func(t schema.TypeRef) bool {
+		switch typ := t.(type) {
+		case *schema.NamedType:
+			return typ.Package == "" && ifaces[typ.Name]
+		case *schema.PointerType:
+			return refers(typ.Element)
+		case *schema.ArrayType:
+			return refers(typ.Element)
+		case *schema.MapType:
+			return refers(typ.Key) || refers(typ.Value)
+		}
+		return false
+	}
+	for _, msg := range c.Schema.Messages {
+		for _, f := range msg.Fields {
+			if refers(f.Type) {
+				return msg, f
+			}
+		}
+	}
+	return nil, nil
+}
+
+// toJSONField generates the statement that adds field f to the map built
+// by ToJSON. Unset optional and pointer fields are left out, and messages
+// defined in the schema are converted with their own ToJSON.
+func (c *goContext) toJSONField(f *schema.Field) string {
+	fieldName := "m." + ToPascalCase(f.Name)
+	key := jsonName(f)
+
+	if c.isWrapperField(f) {
+		return fmt.Sprintf(`if %s.Set {
+		out[%q] = %s.Value
+	}`, fieldName, key, fieldName)
+	}
+	if mt := c.orderedMap(f); mt != nil {
+		return fmt.Sprintf(`{
+		entries := make(map[%s]%s, %s.Len())
+		for k, v := range %s.All() {
+			entries[k] = v
+		}
+		out[%q] = entries
+	}`, c.goType(mt.Key), c.goType(mt.Value), fieldName, fieldName, key)
+	}
+
+	message := !f.Repeated && c.localMessage(f.Type)
+	if c.isNilCheckable(f) {
+		value := "*" + fieldName
+		if message {
+			value = fieldName + ".ToJSON()"
+		}
+		return fmt.Sprintf(`if %s != nil {
+		out[%q] = %s
+	}`, fieldName, key, value)
+	}
+	if message {
+		return fmt.Sprintf("out[%q] = %s.ToJSON()", key, fieldName)
+	}
+	return fmt.Sprintf("out[%q] = %s", key, fieldName)
+}
+
+// fromJSONField generates the statements that set field f from the map
+// passed to FromJSON. Ordered maps are filled in key order, since the map
+// doesn't keep the order of their entries, and messages defined in the
+// schema are converted with their own FromJSON.
+func (c *goContext) fromJSONField(f *schema.Field) string {
+	fieldName := "m." + ToPascalCase(f.Name)
+	key := jsonName(f)
+
+	if !f.Repeated && c.localMessage(f.Type) {
+		if !c.isNilCheckable(f) {
+			return fmt.Sprintf(`if _, err := cramberry.JSONMessage(v, %q, &%s); err != nil {
+		return err
+	}`, key, fieldName)
+		}
+		elem := f.Type
+		if pt, ok := elem.(*schema.PointerType); ok {
+			elem = pt.Element
+		}
+		return fmt.Sprintf(`{
+		var nested %s
+		if ok, err := cramberry.JSONMessage(v, %q, &nested); err != nil {
+			return err
+		} else if ok {
+			%s = &nested
+		}
+	}`, c.goType(elem), key, fieldName)
+	}
+
+	if mt := c.orderedMap(f); mt != nil {
+		return fmt.Sprintf(`{
+		var entries map[%s]%s
+		if err := cramberry.JSONField(v, %q, &entries); err != nil {
+			return err
+		}
+		for _, k := range cramberry.SortedMapKeys(entries) {
+			%s.Set(k, entries[k])
+		}
+	}`, c.goType(mt.Key), c.goType(mt.Value), key, fieldName)
+	}
+	return fmt.Sprintf(`if err := cramberry.JSONField(v, %q, &%s); err != nil {
+		return err
+	}`, key, fieldName)
+}
+
 func (c *goContext) hasRequired(m *schema.Message) bool {
 	for _, f := range m.Fields {
 		if f.Required {
@@ -708,12 +1216,185 @@ func (c *goContext) hasRequired(m *schema.Message) bool {
 	return false
 }
 
+// hasRanges reports whether any field of m has a min, max, min_items or
+// max_items option.
+func hasRanges(m *schema.Message) bool {
+	for _, f := range m.Fields {
+		if a := f.Annotations(); a.HasRange() || a.HasItemRange() {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDoc returns the doc comment of the Validate method of m.
+func validateDoc(m *schema.Message) string {
+	clauses := []string{"all required fields are set"}
+	var values, items bool
+	for _, f := range m.Fields {
+		a := f.Annotations()
+		values = values || a.HasRange()
+		items = items || a.HasItemRange()
+	}
+	if values {
+		clauses = append(clauses, "bounded fields are within their min and max options")
+	}
+	if items {
+		clauses = append(clauses, "repeated and map fields hold between min_items and max_items elements")
+	}
+	return "// Validate validates that " + strings.Join(clauses, " and that\n// ") + "."
+}
+
+// annotationDoc describes the unit and range options of f for its doc
+// comment. It returns "" if the field has none.
+func annotationDoc(f *schema.Field) string {
+	a := f.Annotations()
+	var parts []string
+	if a.Unit != "" {
+		parts = append(parts, "Unit: "+a.Unit+".")
+	}
+	switch {
+	case a.Min != nil && a.Max != nil:
+		parts = append(parts, fmt.Sprintf("Range: %s to %s.", a.Min.Value, a.Max.Value))
+	case a.Min != nil:
+		parts = append(parts, fmt.Sprintf("Minimum: %s.", a.Min.Value))
+	case a.Max != nil:
+		parts = append(parts, fmt.Sprintf("Maximum: %s.", a.Max.Value))
+	}
+	switch {
+	case a.MinItems != nil && a.MaxItems != nil:
+		parts = append(parts, fmt.Sprintf("Items: %s to %s.", a.MinItems.Value, a.MaxItems.Value))
+	case a.MinItems != nil:
+		parts = append(parts, fmt.Sprintf("Minimum items: %s.", a.MinItems.Value))
+	case a.MaxItems != nil:
+		parts = append(parts, fmt.Sprintf("Maximum items: %s.", a.MaxItems.Value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// rangeCheck returns the Validate code that rejects values of f outside its
+// min and max options, or "" if it has neither. The validator guarantees
+// that bounded fields are numeric scalars and that the bounds fit the type.
+func (c *goContext) rangeCheck(m *schema.Message, f *schema.Field) string {
+	a := f.Annotations()
+	if !a.HasRange() {
+		return ""
+	}
+	typ := f.Type.(*schema.ScalarType).Name
+	isFloat := typ == "float32" || typ == "float64"
+
+	name := c.goFieldName(f)
+	value, guard := "m."+name, ""
+	switch {
+	case c.isWrapperField(f):
+		value, guard = "m."+name+".Value", "m."+name+".Set"
+	case c.isNilCheckable(f):
+		value, guard = "*m."+name, "m."+name+" != nil"
+	}
+
+	var reason string
+	switch {
+	case a.Min != nil && a.Max != nil:
+		reason = fmt.Sprintf("value must be between %s and %s", a.Min.Value, a.Max.Value)
+	case a.Min != nil:
+		reason = "value must be at least " + a.Min.Value
+	default:
+		reason = "value must be at most " + a.Max.Value
+	}
+
+	// An unsigned value can't be below zero, and comparing it with zero
+	// draws vet warnings, so that bound is left out.
+	lo, hi := a.Min, a.Max
+	if lo != nil && (strings.HasPrefix(typ, "uint") || typ == "byte") {
+		if n, ok := new(big.Int).SetString(lo.Value, 0); ok && n.Sign() == 0 {
+			lo = nil
+		}
+	}
+	if lo == nil && hi == nil {
+		return ""
+	}
+
+	// Floats are checked by negating the in-range test so that NaN fails it
+	var cond string
+	if isFloat {
+		var in []string
+		if lo != nil {
+			in = append(in, value+" >= "+lo.Value)
+		}
+		if hi != nil {
+			in = append(in, value+" <= "+hi.Value)
+		}
+		cond = "!(" + strings.Join(in, " && ") + ")"
+	} else {
+		var out []string
+		if lo != nil {
+			out = append(out, value+" < "+lo.Value)
+		}
+		if hi != nil {
+			out = append(out, value+" > "+hi.Value)
+		}
+		cond = strings.Join(out, " || ")
+	}
+	if guard != "" {
+		if strings.Contains(cond, " || ") {
+			cond = "(" + cond + ")"
+		}
+		cond = guard + " && " + cond
+	}
+
+	return fmt.Sprintf(`
+	// Field %s must be in range
+	if %s {
+		return cramberry.NewValidationError(%q, %q, %q)
+	}`, f.Name, cond, c.goMessageType(m), f.Name, reason)
+}
+
+// itemRangeCheck returns the Validate code that rejects a repeated, slice
+// or map field f holding fewer than min_items or more than max_items
+// elements, or "" if it has neither option.
+func (c *goContext) itemRangeCheck(m *schema.Message, f *schema.Field) string {
+	a := f.Annotations()
+	if !a.HasItemRange() {
+		return ""
+	}
+	count := "len(m." + c.goFieldName(f) + ")"
+	if c.orderedMap(f) != nil {
+		count = "m." + c.goFieldName(f) + ".Len()"
+	}
+
+	var reason string
+	switch {
+	case a.MinItems != nil && a.MaxItems != nil:
+		reason = fmt.Sprintf("must have between %s and %s items", a.MinItems.Value, a.MaxItems.Value)
+	case a.MinItems != nil:
+		reason = "must have at least " + a.MinItems.Value + " items"
+	default:
+		reason = "must have at most " + a.MaxItems.Value + " items"
+	}
+
+	var out []string
+	if a.MinItems != nil {
+		out = append(out, count+" < "+a.MinItems.Value)
+	}
+	if a.MaxItems != nil {
+		out = append(out, count+" > "+a.MaxItems.Value)
+	}
+
+	return fmt.Sprintf(`
+	// Field %s must have an allowed number of items
+	if %s {
+		return cramberry.NewValidationError(%q, %q, %q)
+	}`, f.Name, strings.Join(out, " || "), c.goMessageType(m), f.Name, reason)
+}
+
 func (c *goContext) needsPointer(t schema.TypeRef) bool {
-	switch t.(type) {
+	switch typ := t.(type) {
 	case *schema.PointerType:
 		return true
-	case *schema.ArrayType, *schema.MapType:
-		return true // slices and maps are already pointer-like
+	case *schema.ArrayType:
+		return typ.Size == 0 // slices are pointer-like, fixed arrays are values
+	case *schema.MapType:
+		return true // maps are already pointer-like
 	default:
 		return false
 	}
@@ -723,18 +1404,38 @@ func (c *goContext) needsPointer(t schema.TypeRef) bool {
 func (c *goContext) isScalarType(t schema.TypeRef) bool {
 	switch typ := t.(type) {
 	case *schema.ScalarType:
-		// bytes is []byte which is a reference type
-		return typ.Name != "bytes"
+		// bytes and any are reference types
+		return typ.Name != "bytes" && typ.Name != "any"
 	default:
 		return false
 	}
 }
 
+// orderedMap returns the type of a map field that keeps insertion order, or
+// nil if f is not one. Such fields are generated as cramberry.OrderedMap.
+func (c *goContext) orderedMap(f *schema.Field) *schema.MapType {
+	if mt, ok := f.Type.(*schema.MapType); ok && f.Ordered() && !f.Repeated {
+		return mt
+	}
+	return nil
+}
+
+// deltaList reports whether a list of int64 values is delta encoded.
+func (c *goContext) deltaList(f *schema.Field) bool {
+	return f.Delta() && f.DeltaElement() != nil
+}
+
+// isWrapperField reports whether an optional scalar field is generated as a
+// cramberry.Optional value rather than a pointer.
+func (c *goContext) isWrapperField(f *schema.Field) bool {
+	return c.Options.OptionalWrappers && f.Optional && !f.Repeated && c.isScalarType(f.Type)
+}
+
 // isPointerField returns true if the field needs pointer handling in encode/decode.
 // Note: Schema PointerType fields are handled directly in decodeValueV2/encodeValueV2,
 // not through the pointer field path.
 func (c *goContext) isPointerField(f *schema.Field) bool {
-	if f.Repeated {
+	if f.Repeated || c.isWrapperField(f) {
 		return false
 	}
 	// Schema pointer types (e.g., *Hash) are handled in decodeValueV2/encodeValueV2
@@ -757,7 +1458,7 @@ func (c *goContext) isPointerField(f *schema.Field) bool {
 // isNilCheckable returns true if the generated field can be compared to nil.
 // Used by Validate() to generate nil checks for required pointer fields.
 func (c *goContext) isNilCheckable(f *schema.Field) bool {
-	if f.Repeated {
+	if f.Repeated || c.isWrapperField(f) {
 		return false
 	}
 
@@ -779,23 +1480,44 @@ func (c *goContext) isNilCheckable(f *schema.Field) bool {
 	return false
 }
 
+// derefRequired reports whether f is a required scalar, which is generated
+// as a pointer that its getters dereference.
+func (c *goContext) derefRequired(f *schema.Field) bool {
+	return f.Required && !f.Optional && c.isScalarType(f.Type)
+}
+
+// requiredGetterType returns the type returned by the getters of the
+// required field f.
+func (c *goContext) requiredGetterType(f *schema.Field) string {
+	if c.derefRequired(f) {
+		return c.goType(f.Type)
+	}
+	return c.goFieldType(f)
+}
+
 // needsCramberryImport returns true if the generated code needs to import cramberry.
 // This is true when:
-// - GenerateMarshal is enabled (for Marshal/Unmarshal methods)
-// - There are messages with required fields (for Validate method)
-// - There are interfaces (for TypeID function)
+//   - GenerateMarshal is enabled (for Marshal/Unmarshal methods)
+//   - There are messages with required or bounded fields (for Validate
+//     method), optional wrapper fields or ordered map fields
+//   - JSON converters are enabled (for FromJSON methods)
+//   - There are interfaces (for TypeID function)
 func (c *goContext) needsCramberryImport() bool {
 	if c.Options.GenerateMarshal {
 		return true
 	}
-	// Check for required fields in any message
+	// Check for required fields and optional wrappers in any message
 	for _, msg := range c.Schema.Messages {
 		for _, f := range msg.Fields {
-			if f.Required {
+			if a := f.Annotations(); f.Required || c.isWrapperField(f) || a.HasRange() || a.HasItemRange() || c.orderedMap(f) != nil {
 				return true
 			}
 		}
 	}
+	// Check for JSON converters, which call cramberry.JSONField
+	if c.Options.JSONConverters && len(c.Schema.Messages) > 0 {
+		return true
+	}
 	// Check for interfaces
 	if len(c.Schema.Interfaces) > 0 {
 		return true
@@ -877,8 +1599,11 @@ const goTemplate = `// Code generated by cramberry. DO NOT EDIT.
 // Source: {{.Schema.Position.Filename}}
 
 package {{goPackage}}
-{{$extImports := externalImports}}{{if or needsCramberryImport $extImports}}
+{{$extImports := externalImports}}{{if or needsCramberryImport $extImports generatePools}}
 import (
+{{- if generatePools}}
+	"sync"
+{{end}}
 {{- if needsCramberryImport}}
 	"github.com/blockberries/cramberry/pkg/cramberry"
 {{- end}}
@@ -887,6 +1612,10 @@ import (
 {{- end}}
 )
 {{end}}
+{{- with schemaHash}}
+// SchemaHash is the fingerprint of the schema this file was generated from.
+const SchemaHash = "{{.}}"
+{{end}}
 {{$ctx := .}}
 {{range $enum := .Schema.Enums}}
 {{if generateComments}}{{range $enum.Comments}}{{if .IsDoc}}{{comment .Text}}
@@ -902,7 +1631,7 @@ const (
 // String returns the string representation of the enum value.
 func (e {{goEnumType $enum}}) String() string {
 	switch e {
-{{- range $enum.Values}}
+{{- range canonicalEnumValues $enum}}
 	case {{goEnumValueName $enum .}}:
 		return "{{.Name}}"
 {{- end}}
@@ -914,7 +1643,7 @@ func (e {{goEnumType $enum}}) String() string {
 // IsValid returns true if the value is a valid enum value.
 func (e {{goEnumType $enum}}) IsValid() bool {
 	switch e {
-{{- range $enum.Values}}
+{{- range canonicalEnumValues $enum}}
 	case {{goEnumValueName $enum .}}:
 		return true
 {{- end}}
@@ -925,12 +1654,20 @@ func (e {{goEnumType $enum}}) IsValid() bool {
 
 // EncodeTo encodes the enum value directly to the writer.
 func (e {{goEnumType $enum}}) EncodeTo(w *cramberry.Writer) {
+{{- if fixed32Enum $enum}}
+	w.WriteFixed32(uint32(e))
+{{- else}}
 	w.WriteInt32(int32(e))
+{{- end}}
 }
 
 // DecodeFrom decodes the enum value from the reader.
 func (e *{{goEnumType $enum}}) DecodeFrom(r *cramberry.Reader) {
+{{- if fixed32Enum $enum}}
+	*e = {{goEnumType $enum}}(r.ReadFixed32())
+{{- else}}
 	*e = {{goEnumType $enum}}(r.ReadInt32())
+{{- end}}
 }
 {{end}}
 {{range $msg := .Schema.Messages}}
@@ -939,12 +1676,18 @@ func (e *{{goEnumType $enum}}) DecodeFrom(r *cramberry.Reader) {
 type {{goMessageType $msg}} struct {
 {{- range $msg.Fields}}
 {{if generateComments}}{{range .Comments}}{{if .IsDoc}}	{{comment .Text}}
-{{end}}{{end}}{{end -}}
+{{end}}{{end}}{{with annotationDoc .}}	{{comment .}}
+{{end}}{{end -}}
 	{{goFieldName .}} {{goFieldType .}} ` + "`{{fieldTag .}}`" + `
 {{- end}}
+{{- if preserveUnknown}}
+
+	// unknownFields holds the encoded fields that DecodeFrom did not recognize.
+	unknownFields []byte
+{{- end}}
 }
 {{if generateMarshal}}
-// MarshalCramberry encodes the message to binary format using optimized V2 encoding.
+// MarshalCramberry encodes the message to binary format using optimized {{wireName}} encoding.
 // This method uses direct field access without reflection for maximum performance.
 func (m *{{goMessageType $msg}}) MarshalCramberry() ([]byte, error) {
 	w := cramberry.GetWriter()
@@ -955,59 +1698,248 @@ func (m *{{goMessageType $msg}}) MarshalCramberry() ([]byte, error) {
 	if w.Err() != nil {
 		return nil, w.Err()
 	}
+{{- if and omitEndMarker (not (lengthFramed $msg))}}
+	// The caller frames the message, so its own end marker is dropped.
+	data := w.BytesCopy()
+	return data[:len(data)-1], nil
+{{- else}}
 	return w.BytesCopy(), nil
+{{- end}}
 }
 
-// EncodeTo encodes the message directly to the writer using V2 format.
+// EncodeTo encodes the message directly to the writer using {{wireName}} format.
 func (m *{{goMessageType $msg}}) EncodeTo(w *cramberry.Writer) {
-{{- range $msg.Fields}}
-	{{encodeFieldV2 .}}
+{{- if lengthFramed $msg}}
+	pos := w.BeginMessage()
+{{- end}}
+{{- range $msg.Fields}}{{with encodeFieldV2 .}}
+	{{.}}
+{{- end}}{{end}}
+{{- if preserveUnknown}}
+	w.WriteRawBytes(m.unknownFields)
 {{- end}}
+{{- if lengthFramed $msg}}
+	w.EndMessage(pos)
+{{- else}}
 	w.WriteEndMarker()
+{{- end}}
 }
 
-// UnmarshalCramberry decodes the message from binary format using optimized V2 decoding.
+// UnmarshalCramberry decodes the message from binary format using optimized {{wireName}} decoding.
 // This method uses direct field access without reflection for maximum performance.
 func (m *{{goMessageType $msg}}) UnmarshalCramberry(data []byte) error {
+{{- if and omitEndMarker (not (lengthFramed $msg))}}
+	opts := cramberry.DefaultOptions
+	opts.OmitTopLevelEndMarker = true
+	r := cramberry.NewReaderWithOptions(data, opts)
+{{- else}}
 	r := cramberry.NewReaderWithOptions(data, cramberry.DefaultOptions)
+{{- end}}
 	m.DecodeFrom(r)
+{{- if and validateOnDecode (or (hasRequired $msg) (hasRanges $msg))}}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	return m.Validate()
+{{- else}}
 	return r.Err()
+{{- end}}
 }
 
-// DecodeFrom decodes the message from the reader using V2 format.
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *{{goMessageType $msg}}) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+{{- if presenceDecode}}
+
+// DecodeWithPresence decodes the message like UnmarshalCramberry and also
+// returns the numbers of its fields that were present in data, telling a
+// field sent as its zero value from one that was not sent.
+func (m *{{goMessageType $msg}}) DecodeWithPresence(data []byte) (map[int]bool, error) {
+{{- if and omitEndMarker (not (lengthFramed $msg))}}
+	opts := cramberry.DefaultOptions
+	opts.OmitTopLevelEndMarker = true
+	r := cramberry.NewReaderWithOptions(data, opts)
+{{- else}}
+	r := cramberry.NewReaderWithOptions(data, cramberry.DefaultOptions)
+{{- end}}
+	present := make(map[int]bool)
+	m.decodeFrom(r, present)
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+{{- if and validateOnDecode (or (hasRequired $msg) (hasRanges $msg))}}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+{{- end}}
+	return present, nil
+}
+
+// DecodeFrom decodes the message from the reader using {{wireName}} format.
 func (m *{{goMessageType $msg}}) DecodeFrom(r *cramberry.Reader) {
+	m.decodeFrom(r, nil)
+}
+
+// decodeFrom decodes the message, adding the number of each field read to
+// present unless it is nil.
+func (m *{{goMessageType $msg}}) decodeFrom(r *cramberry.Reader, present map[int]bool) {
+{{- else}}
+
+// DecodeFrom decodes the message from the reader using {{wireName}} format.
+func (m *{{goMessageType $msg}}) DecodeFrom(r *cramberry.Reader) {
+{{- end}}
+{{- if preserveUnknown}}
+	m.unknownFields = m.unknownFields[:0]
+{{- end}}
+{{- range $f := $msg.Fields}}{{with defaultValue $f}}
+	m.{{goFieldName $f}} = {{.}}
+{{- end}}{{end}}
+{{- if lengthFramed $msg}}
+	end := r.BeginMessage()
+	for r.Err() == nil && r.Pos() < end {
+{{- else}}
 	for {
+{{- end}}
+{{- if preserveUnknown}}
+		start := r.Pos()
+{{- end}}
+{{- if wireV1}}
+		fieldNum, wireType := r.ReadTag()
+{{- else}}
 		fieldNum, wireType := r.ReadCompactTag()
+{{- end}}
 		if fieldNum == 0 {
 			break
 		}
+{{- if presenceDecode}}
+		if present != nil {
+			present[fieldNum] = true
+		}
+{{- end}}
 		switch fieldNum {
 {{- range $msg.Fields}}
 		case {{.Number}}:
+{{- if .Deprecated}}
+			r.Warn(cramberry.WarningDeprecatedField, {{.Number}}, "deprecated field {{.Name}} is present")
+{{- end}}
 			{{decodeFieldV2 .}}
 {{- end}}
 		default:
+{{- if preserveUnknown}}
+			// Keep unknown field so that EncodeTo writes it back
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "kept unknown field of {{$msg.Name}}")
+			{{if wireV1}}r.SkipValue(wireType){{else}}r.SkipValueV2(wireType){{end}}
+			if r.Err() == nil {
+				m.unknownFields = append(m.unknownFields, r.Data()[start:r.Pos()]...)
+			}
+{{- else}}
 			// Skip unknown field for forward compatibility
-			r.SkipValueV2(wireType)
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of {{$msg.Name}}")
+			{{if wireV1}}r.SkipValue(wireType){{else}}r.SkipValueV2(wireType){{end}}
+{{- end}}
 		}
 		if r.Err() != nil {
 			return
 		}
 	}
+{{- if lengthFramed $msg}}
+	r.EndMessage(end)
+{{- end}}
 }
 {{end}}
-{{- if hasRequired $msg}}
-// Validate validates that all required fields are set.
+{{- if or (hasRequired $msg) (hasRanges $msg)}}
+{{validateDoc $msg}}
 func (m *{{goMessageType $msg}}) Validate() error {
 {{- range $msg.Fields}}{{if and .Required (isNilCheckable .)}}
 	// Field {{.Name}} is required
 	if m.{{goFieldName .}} == nil {
 		return cramberry.NewValidationError("{{goMessageType $msg}}", "{{.Name}}", "required field is missing")
 	}
-{{- end}}{{end}}
+{{- end}}{{rangeCheck $msg .}}{{itemRangeCheck $msg .}}{{end}}
 	return nil
 }
 {{end}}
+{{- if requiredGetters}}{{range $msg.Fields}}{{if and .Required (isNilCheckable .)}}
+// Get{{goFieldName .}}Err returns the {{.Name}} field, or an error if the required field is not set.
+func (m *{{goMessageType $msg}}) Get{{goFieldName .}}Err() ({{requiredGetterType .}}, error) {
+	if m.{{goFieldName .}} == nil {
+		var zero {{requiredGetterType .}}
+		return zero, cramberry.NewValidationError("{{goMessageType $msg}}", "{{.Name}}", "required field is missing")
+	}
+	return {{if derefRequired .}}*{{end}}m.{{goFieldName .}}, nil
+}
+
+// MustGet{{goFieldName .}} returns the {{.Name}} field and panics if the required field is not set.
+func (m *{{goMessageType $msg}}) MustGet{{goFieldName .}}() {{requiredGetterType .}} {
+	v, err := m.Get{{goFieldName .}}Err()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+{{end}}{{end}}{{end}}
+{{- if jsonConverters}}
+// ToJSON returns the fields of m keyed by their JSON names. Optional and
+// pointer fields that are not set are left out.
+func (m *{{goMessageType $msg}}) ToJSON() map[string]any {
+	out := make(map[string]any, {{len $msg.Fields}})
+{{- range $msg.Fields}}
+	{{toJSONField .}}
+{{- end}}
+	return out
+}
+
+// FromJSON replaces the fields of m with the values in v, a map returned by
+// ToJSON or decoded by encoding/json. Fields missing from v are left unset.
+func (m *{{goMessageType $msg}}) FromJSON(v map[string]any) error {
+	*m = {{goMessageType $msg}}{}
+{{- range $msg.Fields}}
+	{{fromJSONField .}}
+{{- end}}
+	return nil
+}
+{{end}}
+{{- if generatePools}}
+var {{poolVar $msg}} = sync.Pool{
+	New: func() any { return new({{goMessageType $msg}}) },
+}
+
+// Get{{goMessageType $msg}} returns a {{goMessageType $msg}} from the pool. Pass it to
+// Put{{goMessageType $msg}} once it is no longer referenced.
+func Get{{goMessageType $msg}}() *{{goMessageType $msg}} {
+	return {{poolVar $msg}}.Get().(*{{goMessageType $msg}})
+}
+
+// Put{{goMessageType $msg}} resets m and returns it to the pool.
+func Put{{goMessageType $msg}}(m *{{goMessageType $msg}}) {
+	if m == nil {
+		return
+	}
+	m.Reset()
+	{{poolVar $msg}}.Put(m)
+}
+
+// Reset clears all fields of m. Slices are truncated and maps emptied
+// rather than released, so that their storage can be reused; their old
+// elements are zeroed first so the pool doesn't keep them reachable.
+func (m *{{goMessageType $msg}}) Reset() {
+{{- range $msg.Fields}}{{$kind := resetKind .}}{{if eq $kind "ordered"}}
+	m.{{goFieldName .}}.Clear()
+{{- else if $kind}}
+	clear(m.{{goFieldName .}})
+{{- end}}{{end}}
+	*m = {{goMessageType $msg}}{
+{{- range $msg.Fields}}{{$kind := resetKind .}}{{if eq $kind "slice"}}
+		{{goFieldName .}}: m.{{goFieldName .}}[:0],
+{{- else if or (eq $kind "map") (eq $kind "ordered")}}
+		{{goFieldName .}}: m.{{goFieldName .}},
+{{- end}}{{end}}
+	}
+}
+{{end}}
 {{end}}
 {{range $iface := .Schema.Interfaces}}
 {{if generateComments}}{{range $iface.Comments}}{{if .IsDoc}}{{comment .Text}}
@@ -1020,6 +1952,12 @@ type {{goInterfaceType $iface}} interface {
 {{range $iface.Implementations}}
 func (*{{.Type.Name}}) is{{goInterfaceType $iface}}() {}
 {{end}}
+// Compile-time checks that each implementation satisfies {{goInterfaceType $iface}}.
+var (
+{{- range $iface.Implementations}}
+	_ {{goInterfaceType $iface}} = (*{{.Type.Name}})(nil)
+{{- end}}
+)
 
 // {{goInterfaceType $iface}}TypeID returns the type ID for interface implementations.
 func {{goInterfaceType $iface}}TypeID(v {{goInterfaceType $iface}}) cramberry.TypeID {
@@ -1032,5 +1970,58 @@ func {{goInterfaceType $iface}}TypeID(v {{goInterfaceType $iface}}) cramberry.Ty
 		return 0
 	}
 }
+{{if generateMarshal}}
+// Marshal{{goInterfaceType $iface}} encodes v prefixed with the type ID of its concrete type.
+func Marshal{{goInterfaceType $iface}}(v {{goInterfaceType $iface}}) ([]byte, error) {
+	if v == nil {
+		return nil, cramberry.ErrNilPointer
+	}
+
+	w := cramberry.GetWriter()
+	defer cramberry.PutWriter(w)
+
+	switch m := v.(type) {
+{{- range $iface.Implementations}}
+	case *{{.Type.Name}}:
+		w.WriteTypeID({{.TypeID}})
+		m.EncodeTo(w)
+{{- end}}
+	default:
+		return nil, cramberry.ErrUnregisteredType
+	}
+
+	if w.Err() != nil {
+		return nil, w.Err()
+	}
+	return w.BytesCopy(), nil
+}
+
+// Unmarshal{{goInterfaceType $iface}} decodes a value written by Marshal{{goInterfaceType $iface}},
+// constructing the concrete type selected by the leading type ID.
+func Unmarshal{{goInterfaceType $iface}}(data []byte) ({{goInterfaceType $iface}}, error) {
+	r := cramberry.NewReaderWithOptions(data, cramberry.DefaultOptions)
+	id := r.ReadTypeID()
+	if r.Err() != nil {
+		return nil, r.Err()
+	}
+
+	var v {{goInterfaceType $iface}}
+	switch id {
+{{- range $iface.Implementations}}
+	case {{.TypeID}}:
+		m := &{{.Type.Name}}{}
+		m.DecodeFrom(r)
+		v = m
+{{- end}}
+	default:
+		return nil, cramberry.ErrUnknownType
+	}
+
+	if r.Err() != nil {
+		return nil, r.Err()
+	}
+	return v, nil
+}
+{{end}}
 {{end}}
 `
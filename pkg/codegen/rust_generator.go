@@ -29,6 +29,22 @@ func (g *RustGenerator) FileExtension() string {
 
 // Generate produces Rust code from a schema.
 func (g *RustGenerator) Generate(w io.Writer, s *schema.Schema, opts Options) error {
+	if usesAnyType(s) {
+		return fmt.Errorf("the any field type is not supported by the Rust generator")
+	}
+	if msg := lengthFramedMessage(s); msg != nil {
+		return fmt.Errorf("message %s: length framing is not supported by the Rust generator", msg.Name)
+	}
+	if e := fixed32Enum(s); e != nil {
+		return fmt.Errorf("enum %s: fixed32 encoding is not supported by the Rust generator", e.Name)
+	}
+	if msg, f := deltaField(s); f != nil {
+		return fmt.Errorf("field %s.%s: delta encoding is not supported by the Rust generator", msg.Name, f.Name)
+	}
+	if msg, f := defaultField(s); f != nil {
+		return fmt.Errorf("field %s.%s: default values are not supported by the Rust generator", msg.Name, f.Name)
+	}
+
 	ctx := &rustContext{
 		Schema:  s,
 		Options: opts,
@@ -50,23 +66,26 @@ type rustContext struct {
 
 func (c *rustContext) funcMap() template.FuncMap {
 	return template.FuncMap{
-		"rustType":          c.rustType,
-		"rustFieldType":     c.rustFieldType,
-		"rustEnumType":      c.rustEnumType,
-		"rustMessageType":   c.rustMessageType,
-		"rustInterfaceType": c.rustInterfaceType,
-		"rustFieldName":     c.rustFieldName,
-		"rustEnumValueName": c.rustEnumValueName,
-		"rustWireType":      c.rustWireType,
-		"rustWriteField":    c.rustWriteField,
-		"rustReadField":     c.rustReadField,
-		"comment":           c.rustComment,
-		"toCamel":           ToCamelCase,
-		"toPascal":          ToPascalCase,
-		"toSnake":           ToSnakeCase,
-		"generateComments":  func() bool { return c.Options.GenerateComments },
-		"generateMarshal":   func() bool { return c.Options.GenerateMarshal },
-		"hasSerde":          func() bool { return c.Options.GenerateJSON },
+		"rustType":            c.rustType,
+		"schemaHash":          func() string { return schemaHash(c.Schema) },
+		"rustFieldType":       c.rustFieldType,
+		"rustEnumType":        c.rustEnumType,
+		"rustMessageType":     c.rustMessageType,
+		"rustInterfaceType":   c.rustInterfaceType,
+		"rustFieldName":       c.rustFieldName,
+		"rustEnumValueName":   c.rustEnumValueName,
+		"canonicalEnumValues": canonicalEnumValues,
+		"enumAliasOf":         enumAliasOf,
+		"rustWireType":        c.rustWireType,
+		"rustWriteField":      c.rustWriteField,
+		"rustReadField":       c.rustReadField,
+		"comment":             c.rustComment,
+		"toCamel":             ToCamelCase,
+		"toPascal":            ToPascalCase,
+		"toSnake":             ToSnakeCase,
+		"generateComments":    func() bool { return c.Options.GenerateComments },
+		"generateMarshal":     func() bool { return c.Options.GenerateMarshal },
+		"hasSerde":            func() bool { return c.Options.GenerateJSON },
 	}
 }
 
@@ -210,7 +229,7 @@ func (c *rustContext) rustWireTypeForType(t schema.TypeRef) string {
 	switch typ := t.(type) {
 	case *schema.ScalarType:
 		switch typ.Name {
-		case "bool", "uint8", "uint16", "uint32", "uint", "uint64":
+		case "bool", "uint8", "byte", "uint16", "uint32", "uint", "uint64":
 			return "WireTypeV2::Varint" // Unsigned varint
 		case "int8", "int16", "int32", "int", "int64":
 			return "WireTypeV2::SVarint" // Signed zigzag varint
@@ -224,16 +243,9 @@ func (c *rustContext) rustWireTypeForType(t schema.TypeRef) string {
 			return "WireTypeV2::Bytes"
 		}
 	case *schema.NamedType:
-		// Named types (enums, messages) - enums are svarint, messages are bytes.
-		// Only check local enums when the type has no package qualifier.
-		// Cross-package types are assumed to be messages; cross-package enum
-		// detection requires access to imported schemas which is not yet supported.
-		if typ.Package == "" {
-			for _, e := range c.Schema.Enums {
-				if e.Name == typ.Name {
-					return "WireTypeV2::SVarint"
-				}
-			}
+		// Named types (enums, messages) - enums are svarint, messages are bytes
+		if enumOf(c.Schema, typ) != nil {
+			return "WireTypeV2::SVarint"
 		}
 		return "WireTypeV2::Bytes"
 	case *schema.ArrayType, *schema.MapType:
@@ -260,7 +272,7 @@ func (c *rustContext) rustWriteValueForSubWriter(t schema.TypeRef, value string)
 			return fmt.Sprintf("sub_writer.write_bool(*%s)", value)
 		case "int8", "int16", "int32", "int":
 			return fmt.Sprintf("sub_writer.write_svarint(*%s)", value)
-		case "uint8", "uint16", "uint32", "uint":
+		case "uint8", "byte", "uint16", "uint32", "uint":
 			return fmt.Sprintf("sub_writer.write_varint(*%s)", value)
 		case "int64":
 			return fmt.Sprintf("sub_writer.write_svarint64(*%s)", value)
@@ -278,15 +290,11 @@ func (c *rustContext) rustWriteValueForSubWriter(t schema.TypeRef, value string)
 			return fmt.Sprintf("sub_writer.write_string(%s)", value)
 		}
 	case *schema.NamedType:
-		// Check if it's a local enum (no package qualifier)
-		if typ.Package == "" {
-			for _, e := range c.Schema.Enums {
-				if e.Name == typ.Name {
-					return fmt.Sprintf("sub_writer.write_svarint(*%s as i32)", value)
-				}
-			}
+		// Enums, including those of imported schemas, are written inline
+		if enumOf(c.Schema, typ) != nil {
+			return fmt.Sprintf("sub_writer.write_svarint(*%s as i32)", value)
 		}
-		// It's a message (or cross-package enum, treated as message for now)
+		// Otherwise it's a message
 		return fmt.Sprintf("encode_%s(&mut sub_writer, %s)", ToSnakeCase(typ.Name), value)
 	default:
 		return fmt.Sprintf("sub_writer.write_string(&format!(\"{:?}\", %s))", value)
@@ -317,7 +325,7 @@ func (c *rustContext) rustWriteValue(t schema.TypeRef, value string, repeated bo
 			return fmt.Sprintf("writer.write_bool(%s)", value)
 		case "int8", "int16", "int32", "int":
 			return fmt.Sprintf("writer.write_svarint(%s)", value)
-		case "uint8", "uint16", "uint32", "uint":
+		case "uint8", "byte", "uint16", "uint32", "uint":
 			return fmt.Sprintf("writer.write_varint(%s)", value)
 		case "int64":
 			return fmt.Sprintf("writer.write_svarint64(%s)", value)
@@ -335,17 +343,17 @@ func (c *rustContext) rustWriteValue(t schema.TypeRef, value string, repeated bo
 			return fmt.Sprintf("writer.write_string(&%s)", value)
 		}
 	case *schema.NamedType:
-		// Check if it's a local enum (no package qualifier)
-		if typ.Package == "" {
-			for _, e := range c.Schema.Enums {
-				if e.Name == typ.Name {
-					return fmt.Sprintf("writer.write_svarint(%s as i32)", value)
-				}
-			}
+		// Enums, including those of imported schemas, are written inline
+		if enumOf(c.Schema, typ) != nil {
+			return fmt.Sprintf("writer.write_svarint(%s as i32)", value)
 		}
-		// It's a message (or cross-package enum, treated as message for now)
+		// Otherwise it's a message
 		return fmt.Sprintf("encode_%s(writer, &%s)", ToSnakeCase(typ.Name), value)
 	case *schema.ArrayType:
+		if isFixedByteArray(typ) {
+			// Written like bytes, matching the Go encoding of [N]byte
+			return fmt.Sprintf("writer.write_length_prefixed_bytes(&%s)", value)
+		}
 		return c.rustWriteValue(typ.Element, value, true)
 	case *schema.MapType:
 		keyWrite := c.rustWriteValueForSubWriter(typ.Key, "k")
@@ -402,7 +410,7 @@ func (c *rustContext) rustReadValue(t schema.TypeRef, repeated bool) string {
 			return "reader.read_bool()?"
 		case "int8", "int16", "int32", "int":
 			return "reader.read_svarint()?"
-		case "uint8", "uint16", "uint32", "uint":
+		case "uint8", "byte", "uint16", "uint32", "uint":
 			return "reader.read_varint()?"
 		case "int64":
 			return "reader.read_svarint64()?"
@@ -420,18 +428,18 @@ func (c *rustContext) rustReadValue(t schema.TypeRef, repeated bool) string {
 			return "reader.read_string()?.to_string()"
 		}
 	case *schema.NamedType:
-		// Check if it's a local enum (no package qualifier)
-		if typ.Package == "" {
-			for _, e := range c.Schema.Enums {
-				if e.Name == typ.Name {
-					enumType := c.rustEnumType(e)
-					return fmt.Sprintf("%s::from_i32(reader.read_svarint()?).unwrap_or(%s::%s)", enumType, enumType, ToPascalCase(e.Values[0].Name))
-				}
-			}
+		// Enums, including those of imported schemas, are read inline
+		if e := enumOf(c.Schema, typ); e != nil {
+			enumType := c.rustTypeInternal(typ, false)
+			return fmt.Sprintf("%s::from_i32(reader.read_svarint()?).unwrap_or(%s::%s)", enumType, enumType, ToPascalCase(e.Values[0].Name))
 		}
-		// It's a message (or cross-package enum, treated as message for now)
+		// Otherwise it's a message
 		return fmt.Sprintf("decode_%s(reader)?", ToSnakeCase(typ.Name))
 	case *schema.ArrayType:
+		if isFixedByteArray(typ) {
+			return fmt.Sprintf(`<[u8; %d]>::try_from(reader.read_length_prefixed_bytes()?)
+                .map_err(|_| cramberry::Error::custom("bytes length does not match fixed size %d"))?`, typ.Size, typ.Size)
+		}
 		return c.rustReadValue(typ.Element, true)
 	case *schema.MapType:
 		keyRead := c.rustReadValue(typ.Key, false)
@@ -466,6 +474,10 @@ const rustTemplate = `// Code generated by cramberry. DO NOT EDIT.
 {{if hasSerde}}use serde::{Deserialize, Serialize};
 {{end}}{{if generateMarshal}}use cramberry::{Reader, Result, WireTypeV2, Writer};
 {{end}}
+{{- with schemaHash}}
+/// Fingerprint of the schema this file was generated from.
+pub const SCHEMA_HASH: &str = "{{.}}";
+{{end}}
 {{$ctx := .}}
 {{range $enum := .Schema.Enums}}
 {{if generateComments}}{{range $enum.Comments}}{{if .IsDoc}}{{comment .Text}}
@@ -475,7 +487,7 @@ const rustTemplate = `// Code generated by cramberry. DO NOT EDIT.
 {{end}}#[repr(i32)]
 pub enum {{rustEnumType $enum}} {
 #[default]
-{{- range $enum.Values}}
+{{- range canonicalEnumValues $enum}}
 {{if generateComments}}{{range .Comments}}{{if .IsDoc}}    {{comment .Text}}
 {{end}}{{end}}{{end -}}
     {{rustEnumValueName .}} = {{.Number}},
@@ -483,9 +495,13 @@ pub enum {{rustEnumType $enum}} {
 }
 
 impl {{rustEnumType $enum}} {
+{{- range $v := $enum.Values}}{{with enumAliasOf $enum $v}}
+    #[allow(non_upper_case_globals)]
+    pub const {{rustEnumValueName $v}}: Self = Self::{{rustEnumValueName .}};
+{{- end}}{{end}}
     pub fn from_i32(value: i32) -> Option<Self> {
         match value {
-{{- range $enum.Values}}
+{{- range canonicalEnumValues $enum}}
             {{.Number}} => Some(Self::{{rustEnumValueName .}}),
 {{- end}}
             _ => None,
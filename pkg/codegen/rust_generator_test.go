@@ -106,6 +106,42 @@ func TestRustGeneratorEnum(t *testing.T) {
 	}
 }
 
+func TestRustGeneratorEnumAlias(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Enums: []*schema.Enum{
+			{
+				Name: "Status",
+				Options: []*schema.Option{
+					{Name: "allow_alias", Value: &schema.BoolValue{Value: true}},
+				},
+				Values: []*schema.EnumValue{
+					{Name: "UNKNOWN", Number: 0},
+					{Name: "STARTED", Number: 1},
+					{Name: "RUNNING", Number: 1},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewRustGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	// Rust rejects duplicate discriminants, so aliases become constants
+	if strings.Contains(output, "Running = 1,") {
+		t.Errorf("alias must not be an enum variant, got: %s", output)
+	}
+	if !strings.Contains(output, "pub const Running: Self = Self::Started;") {
+		t.Errorf("expected alias constant, got: %s", output)
+	}
+	if strings.Count(output, "1 => Some(") != 1 {
+		t.Errorf("expected a single match arm for 1, got: %s", output)
+	}
+}
+
 func TestRustGeneratorInterface(t *testing.T) {
 	s := &schema.Schema{
 		Package: &schema.Package{Name: "test"},
@@ -217,6 +253,40 @@ func TestRustGeneratorComplexTypes(t *testing.T) {
 	}
 }
 
+func TestRustGeneratorFixedByteArray(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{
+				Name: "Block",
+				Fields: []*schema.Field{
+					{Name: "hash", Number: 1, Type: &schema.ArrayType{Element: &schema.ScalarType{Name: "byte"}, Size: 32}},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewRustGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	// Encoded as raw bytes, like the Go output, not element by element
+	for _, want := range []string{
+		"pub hash: [u8; 32],",
+		"writer.write_length_prefixed_bytes(&msg.hash)",
+		"<[u8; 32]>::try_from(reader.read_length_prefixed_bytes()?)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "for elem in &msg.hash") {
+		t.Errorf("fixed byte array must be encoded as raw bytes, got:\n%s", output)
+	}
+}
+
 func TestRustGeneratorOptionalFields(t *testing.T) {
 	s := &schema.Schema{
 		Package: &schema.Package{Name: "test"},
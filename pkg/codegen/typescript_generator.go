@@ -29,6 +29,22 @@ func (g *TypeScriptGenerator) FileExtension() string {
 
 // Generate produces TypeScript code from a schema.
 func (g *TypeScriptGenerator) Generate(w io.Writer, s *schema.Schema, opts Options) error {
+	if usesAnyType(s) {
+		return fmt.Errorf("the any field type is not supported by the TypeScript generator")
+	}
+	if msg := lengthFramedMessage(s); msg != nil {
+		return fmt.Errorf("message %s: length framing is not supported by the TypeScript generator", msg.Name)
+	}
+	if e := fixed32Enum(s); e != nil {
+		return fmt.Errorf("enum %s: fixed32 encoding is not supported by the TypeScript generator", e.Name)
+	}
+	if msg, f := deltaField(s); f != nil {
+		return fmt.Errorf("field %s.%s: delta encoding is not supported by the TypeScript generator", msg.Name, f.Name)
+	}
+	if msg, f := defaultField(s); f != nil {
+		return fmt.Errorf("field %s.%s: default values are not supported by the TypeScript generator", msg.Name, f.Name)
+	}
+
 	ctx := &tsContext{
 		Schema:  s,
 		Options: opts,
@@ -51,6 +67,7 @@ type tsContext struct {
 func (c *tsContext) funcMap() template.FuncMap {
 	return template.FuncMap{
 		"tsType":           c.tsType,
+		"schemaHash":       func() string { return schemaHash(c.Schema) },
 		"tsFieldType":      c.tsFieldType,
 		"tsEnumType":       c.tsEnumType,
 		"tsMessageType":    c.tsMessageType,
@@ -97,6 +114,9 @@ func (c *tsContext) tsTypeInternal(t schema.TypeRef, _ bool) string {
 		}
 		return name
 	case *schema.ArrayType:
+		if isFixedByteArray(typ) {
+			return "Uint8Array"
+		}
 		elem := c.tsTypeInternal(typ.Element, true)
 		if typ.Size > 0 {
 			// Fixed-size arrays become tuples in TypeScript
@@ -186,7 +206,7 @@ func (c *tsContext) tsWireTypeForType(t schema.TypeRef) string {
 	switch typ := t.(type) {
 	case *schema.ScalarType:
 		switch typ.Name {
-		case "bool", "uint8", "uint16", "uint32", "uint", "uint64":
+		case "bool", "uint8", "byte", "uint16", "uint32", "uint", "uint64":
 			return "WireTypeV2.Varint" // Unsigned varint
 		case "int8", "int16", "int32", "int", "int64":
 			return "WireTypeV2.SVarint" // Signed zigzag varint
@@ -200,16 +220,9 @@ func (c *tsContext) tsWireTypeForType(t schema.TypeRef) string {
 			return "WireTypeV2.Bytes"
 		}
 	case *schema.NamedType:
-		// Named types (enums, messages) - enums are svarint, messages are bytes.
-		// Only check local enums when the type has no package qualifier.
-		// Cross-package types are assumed to be messages; cross-package enum
-		// detection requires access to imported schemas which is not yet supported.
-		if typ.Package == "" {
-			for _, e := range c.Schema.Enums {
-				if e.Name == typ.Name {
-					return "WireTypeV2.SVarint"
-				}
-			}
+		// Named types (enums, messages) - enums are svarint, messages are bytes
+		if enumOf(c.Schema, typ) != nil {
+			return "WireTypeV2.SVarint"
 		}
 		return "WireTypeV2.Bytes"
 	case *schema.ArrayType, *schema.MapType:
@@ -236,7 +249,7 @@ func (c *tsContext) tsWriteValueWithWriter(t schema.TypeRef, value string, write
 			return fmt.Sprintf("%s.writeBool(%s)", writerName, value)
 		case "int8", "int16", "int32", "int":
 			return fmt.Sprintf("%s.writeSVarint(%s)", writerName, value)
-		case "uint8", "uint16", "uint32", "uint":
+		case "uint8", "byte", "uint16", "uint32", "uint":
 			return fmt.Sprintf("%s.writeVarint(%s)", writerName, value)
 		case "int64":
 			return fmt.Sprintf("%s.writeSVarint64(%s)", writerName, value)
@@ -254,15 +267,11 @@ func (c *tsContext) tsWriteValueWithWriter(t schema.TypeRef, value string, write
 			return fmt.Sprintf("%s.writeString(%s)", writerName, value)
 		}
 	case *schema.NamedType:
-		// Check if it's a local enum (no package qualifier)
-		if typ.Package == "" {
-			for _, e := range c.Schema.Enums {
-				if e.Name == typ.Name {
-					return fmt.Sprintf("%s.writeSVarint(%s)", writerName, value)
-				}
-			}
+		// Enums, including those of imported schemas, are written inline
+		if enumOf(c.Schema, typ) != nil {
+			return fmt.Sprintf("%s.writeSVarint(%s)", writerName, value)
 		}
-		// It's a message (or cross-package enum, treated as message for now)
+		// Otherwise it's a message
 		return fmt.Sprintf("encode%s(%s, %s)", ToPascalCase(typ.Name), writerName, value)
 	default:
 		return fmt.Sprintf("%s.writeString(JSON.stringify(%s))", writerName, value)
@@ -286,7 +295,7 @@ func (c *tsContext) tsWriteValue(t schema.TypeRef, value string, repeated bool)
 			return fmt.Sprintf("writer.writeBool(%s)", value)
 		case "int8", "int16", "int32", "int":
 			return fmt.Sprintf("writer.writeSVarint(%s)", value)
-		case "uint8", "uint16", "uint32", "uint":
+		case "uint8", "byte", "uint16", "uint32", "uint":
 			return fmt.Sprintf("writer.writeVarint(%s)", value)
 		case "int64":
 			return fmt.Sprintf("writer.writeSVarint64(%s)", value)
@@ -304,17 +313,17 @@ func (c *tsContext) tsWriteValue(t schema.TypeRef, value string, repeated bool)
 			return fmt.Sprintf("writer.writeString(%s)", value)
 		}
 	case *schema.NamedType:
-		// Check if it's a local enum (no package qualifier)
-		if typ.Package == "" {
-			for _, e := range c.Schema.Enums {
-				if e.Name == typ.Name {
-					return fmt.Sprintf("writer.writeSVarint(%s)", value)
-				}
-			}
+		// Enums, including those of imported schemas, are written inline
+		if enumOf(c.Schema, typ) != nil {
+			return fmt.Sprintf("writer.writeSVarint(%s)", value)
 		}
-		// It's a message (or cross-package enum, treated as message for now)
+		// Otherwise it's a message
 		return fmt.Sprintf("encode%s(writer, %s)", ToPascalCase(typ.Name), value)
 	case *schema.ArrayType:
+		if isFixedByteArray(typ) {
+			// Written like bytes, matching the Go encoding of [N]byte
+			return fmt.Sprintf("writer.writeLengthPrefixedBytes(%s)", value)
+		}
 		return c.tsWriteValue(typ.Element, value, true)
 	case *schema.MapType:
 		keyWrite := c.tsWriteValueWithWriter(typ.Key, "k", "w")
@@ -348,7 +357,7 @@ func (c *tsContext) tsReadValue(t schema.TypeRef, repeated bool) string {
 			return "reader.readBool()"
 		case "int8", "int16", "int32", "int":
 			return "reader.readSVarint()"
-		case "uint8", "uint16", "uint32", "uint":
+		case "uint8", "byte", "uint16", "uint32", "uint":
 			return "reader.readVarint()"
 		case "int64":
 			return "reader.readSVarint64()"
@@ -366,17 +375,16 @@ func (c *tsContext) tsReadValue(t schema.TypeRef, repeated bool) string {
 			return "reader.readString()"
 		}
 	case *schema.NamedType:
-		// Check if it's a local enum (no package qualifier)
-		if typ.Package == "" {
-			for _, e := range c.Schema.Enums {
-				if e.Name == typ.Name {
-					return "reader.readSVarint()"
-				}
-			}
+		// Enums, including those of imported schemas, are read inline
+		if enumOf(c.Schema, typ) != nil {
+			return "reader.readSVarint()"
 		}
-		// It's a message (or cross-package enum, treated as message for now)
+		// Otherwise it's a message
 		return fmt.Sprintf("decode%s(reader)", ToPascalCase(typ.Name))
 	case *schema.ArrayType:
+		if isFixedByteArray(typ) {
+			return fmt.Sprintf("readFixedBytes(reader, %d)", typ.Size)
+		}
 		return c.tsReadValue(typ.Element, true)
 	case *schema.MapType:
 		keyRead := c.tsReadValue(typ.Key, false)
@@ -396,7 +404,7 @@ func init() {
 const tsTemplate = `// Code generated by cramberry. DO NOT EDIT.
 // Source: {{.Schema.Position.Filename}}
 {{if generateMarshal}}
-import { Writer, Reader, WireTypeV2 } from 'cramberry';
+import { Writer, Reader, WireTypeV2, DecodeError } from 'cramberry';
 
 // Helper functions for encoding/decoding
 function writeArray<T>(writer: Writer, arr: T[], writeElem: (w: Writer, v: T) => void): void {
@@ -419,6 +427,14 @@ function readArray<T>(reader: Reader, readElem: (r: Reader) => T): T[] {
   return result;
 }
 
+function readFixedBytes(reader: Reader, size: number): Uint8Array {
+  const data = reader.readLengthPrefixedBytes();
+  if (data.length !== size) {
+    throw new DecodeError("bytes length " + data.length + " does not match fixed size " + size);
+  }
+  return data;
+}
+
 function writeMap<K, V>(writer: Writer, map: Map<K, V> | Record<string, V>, writeKey: (w: Writer, k: K) => void, writeVal: (w: Writer, v: V) => void): void {
   const subWriter = new Writer();
   const entries = map instanceof Map ? Array.from(map.entries()) : Object.entries(map);
@@ -443,6 +459,10 @@ function readMap<K, V>(reader: Reader, readKey: (r: Reader) => K, readVal: (r: R
   return result;
 }
 {{end}}
+{{- with schemaHash}}
+/** Fingerprint of the schema this file was generated from. */
+export const SchemaHash = "{{.}}";
+{{end}}
 {{$ctx := .}}
 {{range $enum := .Schema.Enums}}
 {{if generateComments}}{{range $enum.Comments}}{{if .IsDoc}}{{comment .Text}}
@@ -188,6 +188,40 @@ func TestTypeScriptGeneratorComplexTypes(t *testing.T) {
 	}
 }
 
+func TestTypeScriptGeneratorFixedByteArray(t *testing.T) {
+	s := &schema.Schema{
+		Package: &schema.Package{Name: "test"},
+		Messages: []*schema.Message{
+			{
+				Name: "Block",
+				Fields: []*schema.Field{
+					{Name: "hash", Number: 1, Type: &schema.ArrayType{Element: &schema.ScalarType{Name: "byte"}, Size: 32}},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewTypeScriptGenerator().Generate(&buf, s, DefaultOptions()); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	output := buf.String()
+
+	// Encoded as raw bytes, like the Go output, not element by element
+	for _, want := range []string{
+		"hash: Uint8Array;",
+		"writer.writeLengthPrefixedBytes(msg.hash)",
+		"readFixedBytes(reader, 32)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "writeArray(writer, msg.hash") {
+		t.Errorf("fixed byte array must be encoded as raw bytes, got:\n%s", output)
+	}
+}
+
 func TestTypeScriptGeneratorOptionalFields(t *testing.T) {
 	s := &schema.Schema{
 		Package: &schema.Package{Name: "test"},
@@ -3,6 +3,7 @@ package cramberry
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"testing"
 )
 
@@ -206,6 +207,20 @@ func BenchmarkUnmarshalInt32Slice(b *testing.B) {
 	}
 }
 
+func BenchmarkUnmarshalStructSlice(b *testing.B) {
+	slice := make([]BenchSmall, 100)
+	for i := range slice {
+		slice[i] = BenchSmall{ID: int32(i), Name: "item"}
+	}
+	data, _ := Marshal(slice)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var result []BenchSmall
+		_ = Unmarshal(data, &result)
+	}
+}
+
 func BenchmarkMarshalStringSlice(b *testing.B) {
 	slice := make([]string, 50)
 	for i := range slice {
@@ -440,6 +455,28 @@ func BenchmarkReadInt32(b *testing.B) {
 	}
 }
 
+// BenchmarkReadUvarint decodes a run of varints of each length up to the
+// end of the unrolled fast path and one beyond it.
+func BenchmarkReadUvarint(b *testing.B) {
+	for _, size := range []int{1, 2, 3, 4, 5, 6} {
+		b.Run(fmt.Sprintf("%dbytes", size), func(b *testing.B) {
+			w := NewWriter()
+			for range 64 {
+				w.WriteUvarint(1 << (7 * (size - 1)))
+			}
+			data := w.BytesCopy()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r := NewReader(data)
+				for range 64 {
+					r.ReadUvarint()
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkReadString(b *testing.B) {
 	w := NewWriter()
 	w.WriteString("this is a test string for benchmarking")
@@ -451,3 +488,44 @@ func BenchmarkReadString(b *testing.B) {
 		r.ReadString()
 	}
 }
+
+// BenchmarkWriterPoolLargeMessage compares buffer regrowth for 128KB messages
+// between the default pool, a raised initial size, and the large-buffer tier.
+func BenchmarkWriterPoolLargeMessage(b *testing.B) {
+	payload := make([]byte, 128*1024)
+
+	encode := func(w *Writer) {
+		w.WriteBytes(payload)
+		_ = w.Bytes()
+	}
+
+	b.Run("Default", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			w := GetWriter()
+			encode(w)
+			PutWriter(w)
+		}
+	})
+
+	b.Run("InitialSize", func(b *testing.B) {
+		defer SetWriterPoolConfig(DefaultWriterPoolConfig)
+		SetWriterPoolSize(256 * 1024)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			w := GetWriter()
+			encode(w)
+			PutWriter(w)
+		}
+	})
+
+	b.Run("LargeTier", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			w := GetWriterWithHint(len(payload) + 16)
+			encode(w)
+			PutWriter(w)
+		}
+	})
+}
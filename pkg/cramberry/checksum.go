@@ -0,0 +1,50 @@
+package cramberry
+
+import (
+	"hash/crc32"
+	"reflect"
+
+	"github.com/blockberries/cramberry/internal/wire"
+)
+
+// castagnoli is the CRC-32C table, which most CPUs compute in hardware.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// MarshalWithCRC encodes v like Marshal and prefixes the encoding with its
+// CRC-32C checksum as a fixed 32-bit little-endian value. Use it for blobs
+// that are stored and read back whole; UnmarshalWithCRC verifies the
+// checksum before decoding.
+func MarshalWithCRC(v any) ([]byte, error) {
+	w := GetWriter()
+	defer PutWriter(w)
+
+	if err := encodeValue(w, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	if w.Err() != nil {
+		return nil, w.Err()
+	}
+
+	body := w.Bytes()
+	out := make([]byte, 0, Fixed32Size+len(body))
+	out = wire.AppendFixed32(out, crc32.Checksum(body, castagnoli))
+	return append(out, body...), nil
+}
+
+// UnmarshalWithCRC decodes data written by MarshalWithCRC into v. It returns
+// an error wrapping ErrChecksumMismatch, without touching v, if the body
+// doesn't match the checksum.
+func UnmarshalWithCRC(data []byte, v any) error {
+	if len(data) < Fixed32Size {
+		return NewDecodeError("data too short for checksum", ErrUnexpectedEOF)
+	}
+	want, err := wire.DecodeFixed32(data)
+	if err != nil {
+		return NewDecodeError("reading checksum", err)
+	}
+	body := data[Fixed32Size:]
+	if crc32.Checksum(body, castagnoli) != want {
+		return NewDecodeError("checksum does not match message body", ErrChecksumMismatch)
+	}
+	return Unmarshal(body, v)
+}
@@ -0,0 +1,81 @@
+package cramberry
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"reflect"
+	"testing"
+)
+
+type crcRecord struct {
+	ID    int64             `cramberry:"1"`
+	Name  string            `cramberry:"2"`
+	Tags  []string          `cramberry:"3"`
+	Attrs map[string]string `cramberry:"4"`
+}
+
+func TestMarshalWithCRCRoundtrip(t *testing.T) {
+	values := []crcRecord{
+		{},
+		{ID: 42, Name: "blob", Tags: []string{"a", "b"}, Attrs: map[string]string{"k": "v"}},
+	}
+
+	for _, want := range values {
+		data, err := MarshalWithCRC(want)
+		if err != nil {
+			t.Fatalf("MarshalWithCRC error: %v", err)
+		}
+
+		// The body is the plain encoding, after its CRC-32C
+		plain, err := Marshal(want)
+		if err != nil {
+			t.Fatalf("Marshal error: %v", err)
+		}
+		if !bytes.Equal(data[4:], plain) {
+			t.Errorf("body = %x, want %x", data[4:], plain)
+		}
+		sum := crc32.Checksum(plain, crc32.MakeTable(crc32.Castagnoli))
+		if got := uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16 | uint32(data[3])<<24; got != sum {
+			t.Errorf("checksum = %08x, want %08x", got, sum)
+		}
+
+		var got crcRecord
+		if err := UnmarshalWithCRC(data, &got); err != nil {
+			t.Fatalf("UnmarshalWithCRC error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("roundtrip mismatch:\n got %+v\nwant %+v", got, want)
+		}
+	}
+}
+
+func TestUnmarshalWithCRCCorruption(t *testing.T) {
+	original := crcRecord{ID: 7, Name: "stored", Tags: []string{"x"}}
+	data, err := MarshalWithCRC(original)
+	if err != nil {
+		t.Fatalf("MarshalWithCRC error: %v", err)
+	}
+
+	// Flipping any bit of the checksum or the body is detected
+	for i := range data {
+		corrupt := bytes.Clone(data)
+		corrupt[i] ^= 0x01
+
+		got := crcRecord{Name: "untouched"}
+		err := UnmarshalWithCRC(corrupt, &got)
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("byte %d flipped: got error %v, want ErrChecksumMismatch", i, err)
+		}
+		if got.Name != "untouched" {
+			t.Errorf("byte %d flipped: target was modified: %+v", i, got)
+		}
+	}
+
+	if err := UnmarshalWithCRC(data[:len(data)-1], &crcRecord{}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("truncated body: got %v, want ErrChecksumMismatch", err)
+	}
+	if err := UnmarshalWithCRC(data[:3], &crcRecord{}); !errors.Is(err, ErrUnexpectedEOF) {
+		t.Errorf("truncated checksum: got %v, want ErrUnexpectedEOF", err)
+	}
+}
@@ -0,0 +1,312 @@
+// Package conformance defines canonical Cramberry messages and the exact bytes
+// they must encode to. The vectors lock the wire format: the Go runtime is
+// checked against them in this package's tests, and GenerateVectors exports
+// them as JSON so the TypeScript and Rust runtimes can assert the same bytes.
+package conformance
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+)
+
+// Vector is a single conformance test case as exported to other runtimes.
+type Vector struct {
+	// Name identifies the test case.
+	Name string `json:"name"`
+
+	// Schema is the Cramberry schema source declaring the message type.
+	Schema string `json:"schema"`
+
+	// Value is the message value, keyed by schema field name. 64-bit
+	// integers are decimal strings, since JSON numbers lose precision
+	// beyond 2^53 in most runtimes.
+	Value json.RawMessage `json:"value"`
+
+	// Hex is the expected encoding as lowercase hexadecimal.
+	Hex string `json:"hex"`
+}
+
+// Scalars covers every scalar type.
+type Scalars struct {
+	Bool    bool    `cramberry:"1" json:"bool_val"`
+	Int32   int32   `cramberry:"2" json:"int32_val"`
+	Int64   int64   `cramberry:"3" json:"int64_val"`
+	Uint32  uint32  `cramberry:"4" json:"uint32_val"`
+	Uint64  uint64  `cramberry:"5" json:"uint64_val"`
+	Float32 float32 `cramberry:"6" json:"float32_val"`
+	Float64 float64 `cramberry:"7" json:"float64_val"`
+	String  string  `cramberry:"8" json:"string_val"`
+	Bytes   []byte  `cramberry:"9" json:"bytes_val"`
+}
+
+// PackedArrays covers packed repeated scalars.
+type PackedArrays struct {
+	Int32s   []int32   `cramberry:"1" json:"int32s"`
+	Uint64s  []uint64  `cramberry:"2" json:"uint64s"`
+	Float64s []float64 `cramberry:"3" json:"float64s"`
+	Bools    []bool    `cramberry:"4" json:"bools"`
+}
+
+// Maps covers map fields, which are encoded with sorted keys.
+type Maps struct {
+	Counts map[string]int32 `cramberry:"1" json:"counts"`
+	Names  map[int32]string `cramberry:"2" json:"names"`
+}
+
+// Point is a nested message.
+type Point struct {
+	X int32 `cramberry:"1" json:"x"`
+	Y int32 `cramberry:"2" json:"y"`
+}
+
+// Nested covers embedded messages and repeated messages.
+type Nested struct {
+	Name   string  `cramberry:"1" json:"name"`
+	Origin Point   `cramberry:"2" json:"origin"`
+	Path   []Point `cramberry:"3" json:"path"`
+}
+
+// Optional covers optional fields that are absent or present.
+type Optional struct {
+	ID     int32    `cramberry:"1" json:"id"`
+	Count  *int32   `cramberry:"2" json:"count"`
+	Origin *Point   `cramberry:"3" json:"origin"`
+	Total  *uint64  `cramberry:"4" json:"total"`
+	Ratio  *float64 `cramberry:"5" json:"ratio"`
+	Label  *string  `cramberry:"16" json:"label"`
+}
+
+// vector pairs a Go value with its golden encoding.
+type vector struct {
+	name   string
+	schema string
+	value  any
+	hex    string
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// vectors are the canonical test cases. Changing a hex string is a wire
+// format change and must be coordinated across all runtimes.
+var vectors = []vector{
+	{
+		name: "scalars",
+		schema: `message Scalars {
+    bool bool_val = 1;
+    int32 int32_val = 2;
+    int64 int64_val = 3;
+    uint32 uint32_val = 4;
+    uint64 uint64_val = 5;
+    float32 float32_val = 6;
+    float64 float64_val = 7;
+    string string_val = 8;
+    bytes bytes_val = 9;
+}`,
+		value: &Scalars{
+			Bool:    true,
+			Int32:   -42,
+			Int64:   1 << 40,
+			Uint32:  300,
+			Uint64:  1<<64 - 1,
+			Float32: 1.5,
+			Float64: -2.25,
+			String:  "héllo",
+			Bytes:   []byte{0xde, 0xad, 0xbe, 0xef},
+		},
+		hex: "100128533880808080804040ac0250ffffffffffffffffff01660000c03f7200000000000002c0840668c3a96c6c6f9404deadbeef00",
+	},
+	{
+		name:   "scalars_zero",
+		schema: `message Scalars { bool bool_val = 1; int32 int32_val = 2; string string_val = 8; }`,
+		value:  &Scalars{},
+		hex:    "00",
+	},
+	{
+		name: "packed_arrays",
+		schema: `message PackedArrays {
+    repeated int32 int32s = 1;
+    repeated uint64 uint64s = 2;
+    repeated float64 float64s = 3;
+    repeated bool bools = 4;
+}`,
+		value: &PackedArrays{
+			Int32s:   []int32{1, -1, 150},
+			Uint64s:  []uint64{0, 1 << 35},
+			Float64s: []float64{0.5},
+			Bools:    []bool{true, false, true},
+		},
+		hex: "14030201ac022402008080808080013401000000000000e03f440301000100",
+	},
+	{
+		name: "maps",
+		schema: `message Maps {
+    map[string]int32 counts = 1;
+    map[int32]string names = 2;
+}`,
+		value: &Maps{
+			Counts: map[string]int32{"b": 2, "a": 1, "c": -3},
+			Names:  map[int32]string{10: "ten", -1: "minus one"},
+		},
+		hex: "1403016102016204016305240201096d696e7573206f6e65140374656e00",
+	},
+	{
+		name: "nested",
+		schema: `message Point {
+    int32 x = 1;
+    int32 y = 2;
+}
+
+message Nested {
+    string name = 1;
+    Point origin = 2;
+    repeated Point path = 3;
+}`,
+		value: &Nested{
+			Name:   "route",
+			Origin: Point{X: 1, Y: -1},
+			Path:   []Point{{X: 2, Y: 3}, {X: 0, Y: 0}},
+		},
+		hex: "1405726f757465241802280100340218042806000000",
+	},
+	{
+		name: "optional_absent",
+		schema: `message Point {
+    int32 x = 1;
+    int32 y = 2;
+}
+
+message Optional {
+    int32 id = 1;
+    optional int32 count = 2;
+    *Point origin = 3;
+    optional uint64 total = 4;
+    optional float64 ratio = 5;
+    optional string label = 16;
+}`,
+		value: &Optional{ID: 7},
+		hex:   "180e00",
+	},
+	{
+		name: "optional_present",
+		schema: `message Point {
+    int32 x = 1;
+    int32 y = 2;
+}
+
+message Optional {
+    int32 id = 1;
+    optional int32 count = 2;
+    *Point origin = 3;
+    optional uint64 total = 4;
+    optional float64 ratio = 5;
+    optional string label = 16;
+}`,
+		value: &Optional{
+			ID:     7,
+			Count:  ptr(int32(-3)),
+			Origin: &Point{X: 5},
+			Total:  ptr(uint64(1<<64 - 1)),
+			Ratio:  ptr(0.5),
+			Label:  ptr("x"),
+		},
+		hex: "180e280534180a0040ffffffffffffffffff0152000000000000e03f0510017800",
+	},
+}
+
+// Vectors returns the canonical conformance vectors.
+func Vectors() ([]Vector, error) {
+	result := make([]Vector, 0, len(vectors))
+	for _, v := range vectors {
+		value, err := json.Marshal(jsonValue(reflect.ValueOf(v.value)))
+		if err != nil {
+			return nil, fmt.Errorf("conformance: vector %s: %w", v.name, err)
+		}
+		result = append(result, Vector{
+			Name:   v.name,
+			Schema: v.schema,
+			Value:  value,
+			Hex:    v.hex,
+		})
+	}
+	return result, nil
+}
+
+// jsonValue returns v in the form it is exported in: structs become objects
+// keyed by their json tags, and 64-bit integers become decimal strings.
+func jsonValue(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return jsonValue(v.Elem())
+	case reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Struct:
+		fields := make(map[string]any, v.NumField())
+		for i := range v.NumField() {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+			fields[name] = jsonValue(v.Field(i))
+		}
+		return fields
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = jsonValue(v.Index(i))
+		}
+		return items
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		entries := make(map[string]any, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			entries[fmt.Sprint(iter.Key().Interface())] = jsonValue(iter.Value())
+		}
+		return entries
+	default:
+		return v.Interface()
+	}
+}
+
+// Verify checks that Marshal produces the golden bytes for every vector.
+func Verify() error {
+	for _, v := range vectors {
+		data, err := cramberry.Marshal(v.value)
+		if err != nil {
+			return fmt.Errorf("conformance: vector %s: marshal: %w", v.name, err)
+		}
+		if got := hex.EncodeToString(data); got != v.hex {
+			return fmt.Errorf("conformance: vector %s: encoded %s, want %s", v.name, got, v.hex)
+		}
+	}
+	return nil
+}
+
+// GenerateVectors writes the conformance vectors to w as an indented JSON
+// array. It fails if the Go runtime no longer produces the golden bytes, so
+// exported vectors always agree with this implementation.
+func GenerateVectors(w io.Writer) error {
+	if err := Verify(); err != nil {
+		return err
+	}
+	vs, err := Vectors()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vs)
+}
@@ -0,0 +1,83 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+)
+
+func TestVectorsEncode(t *testing.T) {
+	for _, v := range vectors {
+		t.Run(v.name, func(t *testing.T) {
+			data, err := cramberry.Marshal(v.value)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if got := hex.EncodeToString(data); got != v.hex {
+				t.Errorf("encoding mismatch:\n got %s\nwant %s", got, v.hex)
+			}
+		})
+	}
+}
+
+func TestVectorsDecode(t *testing.T) {
+	for _, v := range vectors {
+		t.Run(v.name, func(t *testing.T) {
+			data, err := hex.DecodeString(v.hex)
+			if err != nil {
+				t.Fatalf("bad hex: %v", err)
+			}
+			got := reflect.New(reflect.TypeOf(v.value).Elem())
+			if err := cramberry.Unmarshal(data, got.Interface()); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(got.Interface(), v.value) {
+				t.Errorf("decoded %+v, want %+v", got.Elem().Interface(), reflect.ValueOf(v.value).Elem().Interface())
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	if err := Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateVectors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateVectors(&buf); err != nil {
+		t.Fatalf("GenerateVectors failed: %v", err)
+	}
+
+	var got []Vector
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(got) != len(vectors) {
+		t.Fatalf("got %d vectors, want %d", len(got), len(vectors))
+	}
+	for i, v := range got {
+		if v.Name != vectors[i].name || v.Hex != vectors[i].hex {
+			t.Errorf("vector %d = {%s %s}, want {%s %s}", i, v.Name, v.Hex, vectors[i].name, vectors[i].hex)
+		}
+		if v.Schema == "" || len(v.Value) == 0 {
+			t.Errorf("vector %s is missing its schema or value", v.Name)
+		}
+	}
+	// 64-bit integers are strings, which keeps uint64 max exact
+	var scalars map[string]any
+	if err := json.Unmarshal(got[0].Value, &scalars); err != nil {
+		t.Fatalf("scalars value: %v", err)
+	}
+	if scalars["int64_val"] != "1099511627776" || scalars["uint64_val"] != "18446744073709551615" {
+		t.Errorf("64-bit values = %v, %v, want decimal strings", scalars["int64_val"], scalars["uint64_val"])
+	}
+	if scalars["int32_val"] != -42.0 || scalars["bytes_val"] != "3q2+7w==" {
+		t.Errorf("other values = %v, %v", scalars["int32_val"], scalars["bytes_val"])
+	}
+}
@@ -0,0 +1,233 @@
+// Package cramtest provides test helpers for types encoded with Cramberry.
+//
+// The helpers replace the usual marshal, unmarshal and compare boilerplate:
+//
+//	func TestUserRoundTrip(t *testing.T) {
+//		cramtest.AssertRoundTrip(t, &User{Name: "alice", Tags: []string{"admin"}})
+//		cramtest.AssertDeterministic(t, &User{Labels: map[string]string{"a": "1", "b": "2"}})
+//	}
+//
+// Types with generated methods are encoded through EncodeTo, with a writer
+// that carries the options under test, and decoded through
+// UnmarshalCramberry; other types use cramberry.Marshal and
+// cramberry.Unmarshal.
+package cramtest
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+)
+
+// maxDiffs limits how many differing field paths a failure reports.
+const maxDiffs = 20
+
+// encoder is implemented by generated types. Encoding through EncodeTo
+// rather than MarshalCramberry lets the helpers choose the writer options.
+type encoder interface {
+	EncodeTo(w *cramberry.Writer)
+}
+
+type marshaler interface {
+	MarshalCramberry() ([]byte, error)
+}
+
+type unmarshaler interface {
+	UnmarshalCramberry([]byte) error
+}
+
+// AssertRoundTrip marshals v, unmarshals the result into a fresh value of
+// the same type and checks that the decoded value encodes to the same bytes.
+//
+// Comparing canonical encodings rather than values means representations
+// that encode identically, such as a nil and an empty slice, are treated as
+// equal. On mismatch the failure lists the field paths whose values differ.
+func AssertRoundTrip(t testing.TB, v any) {
+	t.Helper()
+
+	if v == nil {
+		t.Fatal("cramtest: AssertRoundTrip called with nil")
+		return
+	}
+
+	data, err := marshal(v, cramberry.DefaultOptions)
+	if err != nil {
+		t.Fatalf("cramtest: marshaling %T: %v", v, err)
+		return
+	}
+
+	typ := reflect.TypeOf(v)
+	isPtr := typ.Kind() == reflect.Ptr
+	if isPtr {
+		typ = typ.Elem()
+	}
+	decodedPtr := reflect.New(typ)
+	if err := unmarshal(data, decodedPtr.Interface()); err != nil {
+		t.Fatalf("cramtest: unmarshaling %T: %v", v, err)
+		return
+	}
+
+	decoded := decodedPtr.Interface()
+	if !isPtr {
+		decoded = decodedPtr.Elem().Interface()
+	}
+	again, err := marshal(decoded, cramberry.DefaultOptions)
+	if err != nil {
+		t.Fatalf("cramtest: re-marshaling decoded %T: %v", v, err)
+		return
+	}
+
+	if !bytes.Equal(data, again) {
+		t.Errorf("cramtest: %T does not round-trip\n%s\n original: %x\n  decoded: %x",
+			v, formatDiffs(diff(reflect.ValueOf(v), reflect.ValueOf(decoded))), data, again)
+	}
+}
+
+// AssertDeterministic marshals v twice with Deterministic set and checks
+// that both encodings are identical.
+func AssertDeterministic(t testing.TB, v any) {
+	t.Helper()
+
+	opts := cramberry.DefaultOptions
+	opts.Deterministic = true
+
+	first, err := marshal(v, opts)
+	if err != nil {
+		t.Fatalf("cramtest: marshaling %T: %v", v, err)
+		return
+	}
+	second, err := marshal(v, opts)
+	if err != nil {
+		t.Fatalf("cramtest: marshaling %T: %v", v, err)
+		return
+	}
+
+	if !bytes.Equal(first, second) {
+		t.Errorf("cramtest: %T encodes non-deterministically\n  first: %x\n second: %x", v, first, second)
+	}
+}
+
+func marshal(v any, opts cramberry.Options) ([]byte, error) {
+	if e, ok := v.(encoder); ok {
+		w := cramberry.NewWriterWithOptions(opts)
+		e.EncodeTo(w)
+		if err := w.Err(); err != nil {
+			return nil, err
+		}
+		return w.Bytes(), nil
+	}
+	if m, ok := v.(marshaler); ok {
+		return m.MarshalCramberry()
+	}
+	return cramberry.MarshalWithOptions(v, opts)
+}
+
+func unmarshal(data []byte, v any) error {
+	if u, ok := v.(unmarshaler); ok {
+		return u.UnmarshalCramberry(data)
+	}
+	return cramberry.Unmarshal(data, v)
+}
+
+// formatDiffs renders the differing paths as an indented list.
+func formatDiffs(diffs []string) string {
+	if len(diffs) == 0 {
+		return "values are equal but encode differently"
+	}
+	var b strings.Builder
+	b.WriteString("differences (original != decoded):")
+	for i, d := range diffs {
+		if i == maxDiffs {
+			fmt.Fprintf(&b, "\n  ... and %d more", len(diffs)-maxDiffs)
+			break
+		}
+		b.WriteString("\n  ")
+		b.WriteString(d)
+	}
+	return b.String()
+}
+
+// diff returns one line per path at which a and b differ.
+func diff(a, b reflect.Value) []string {
+	var diffs []string
+	diffValue(&diffs, "", a, b)
+	return diffs
+}
+
+func diffValue(diffs *[]string, path string, a, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			*diffs = append(*diffs, fmt.Sprintf("%s: %s != %s", pathOrRoot(path), describe(a), describe(b)))
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		*diffs = append(*diffs, fmt.Sprintf("%s: type %s != %s", pathOrRoot(path), a.Type(), b.Type()))
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				*diffs = append(*diffs, fmt.Sprintf("%s: %s != %s", pathOrRoot(path), describe(a), describe(b)))
+			}
+			return
+		}
+		diffValue(diffs, path, a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !a.Type().Field(i).IsExported() {
+				continue
+			}
+			diffValue(diffs, path+"."+a.Type().Field(i).Name, a.Field(i), b.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		if a.Kind() == reflect.Slice && a.Len() != b.Len() {
+			*diffs = append(*diffs, fmt.Sprintf("%s: len %d != %d", pathOrRoot(path), a.Len(), b.Len()))
+			return
+		}
+		for i := 0; i < a.Len(); i++ {
+			diffValue(diffs, fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i))
+		}
+	case reflect.Map:
+		keys := a.MapKeys()
+		for _, k := range b.MapKeys() {
+			if !a.MapIndex(k).IsValid() {
+				keys = append(keys, k)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			diffValue(diffs, fmt.Sprintf("%s[%#v]", path, k.Interface()), a.MapIndex(k), b.MapIndex(k))
+		}
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: %s != %s", pathOrRoot(path), describe(a), describe(b)))
+		}
+	}
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
+
+func describe(v reflect.Value) string {
+	if !v.IsValid() {
+		return "<missing>"
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "nil"
+	}
+	return fmt.Sprintf("%#v", v.Interface())
+}
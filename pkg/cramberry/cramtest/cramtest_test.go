@@ -0,0 +1,191 @@
+package cramtest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+)
+
+// recorder captures failures instead of failing the enclosing test.
+type recorder struct {
+	testing.TB
+	failed bool
+	msgs   []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failed = true
+	r.msgs = append(r.msgs, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) { r.Errorf(format, args...) }
+
+func (r *recorder) Fatal(args ...any) { r.Errorf("%s", fmt.Sprint(args...)) }
+
+func (r *recorder) output() string { return strings.Join(r.msgs, "\n") }
+
+type address struct {
+	Street string `cramberry:"1"`
+	City   string `cramberry:"2"`
+}
+
+type person struct {
+	Name    string            `cramberry:"1"`
+	Age     int32             `cramberry:"2"`
+	Tags    []string          `cramberry:"3"`
+	Home    *address          `cramberry:"4"`
+	Labels  map[string]string `cramberry:"5"`
+	private int
+}
+
+// lossy drops its Note when decoded.
+type lossy struct {
+	ID   int32
+	Note string
+}
+
+func (l *lossy) MarshalCramberry() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d:%s", l.ID, l.Note)), nil
+}
+
+func (l *lossy) UnmarshalCramberry(data []byte) error {
+	_, err := fmt.Sscanf(string(data), "%d:", &l.ID)
+	return err
+}
+
+// flaky encodes differently on every call.
+type flaky struct{ calls *int }
+
+func (f flaky) MarshalCramberry() ([]byte, error) {
+	*f.calls++
+	return []byte{byte(*f.calls)}, nil
+}
+
+func TestAssertRoundTrip(t *testing.T) {
+	values := []any{
+		&person{
+			Name:   "Alice",
+			Age:    30,
+			Tags:   []string{"admin", "ops"},
+			Home:   &address{Street: "1 Main St", City: "Springfield"},
+			Labels: map[string]string{"team": "core", "tier": "1"},
+		},
+		person{Name: "value receiver", private: 7},
+		&person{Tags: []string{}},
+		&address{},
+	}
+
+	for _, v := range values {
+		r := &recorder{TB: t}
+		AssertRoundTrip(r, v)
+		if r.failed {
+			t.Errorf("AssertRoundTrip(%T) failed:\n%s", v, r.output())
+		}
+	}
+}
+
+func TestAssertRoundTripFailure(t *testing.T) {
+	r := &recorder{TB: t}
+	AssertRoundTrip(r, &lossy{ID: 7, Note: "dropped"})
+
+	if !r.failed {
+		t.Fatal("expected AssertRoundTrip to fail for a lossy type")
+	}
+	out := r.output()
+	if !strings.Contains(out, `.Note: "dropped" != ""`) {
+		t.Errorf("expected the differing field path in the failure, got:\n%s", out)
+	}
+	if strings.Contains(out, ".ID") {
+		t.Errorf("equal fields must not be reported, got:\n%s", out)
+	}
+}
+
+func TestAssertRoundTripMarshalError(t *testing.T) {
+	r := &recorder{TB: t}
+	AssertRoundTrip(r, &struct{ C chan int }{})
+
+	if !r.failed || !strings.Contains(r.output(), "marshaling") {
+		t.Errorf("expected a marshal failure, got:\n%s", r.output())
+	}
+}
+
+func TestAssertDeterministic(t *testing.T) {
+	labels := make(map[string]string)
+	for i := 0; i < 50; i++ {
+		labels[fmt.Sprintf("key%d", i)] = fmt.Sprint(i)
+	}
+
+	r := &recorder{TB: t}
+	AssertDeterministic(r, &person{Name: "maps", Labels: labels})
+	if r.failed {
+		t.Errorf("AssertDeterministic failed:\n%s", r.output())
+	}
+
+	calls := 0
+	r = &recorder{TB: t}
+	AssertDeterministic(r, flaky{calls: &calls})
+	if !r.failed || !strings.Contains(r.output(), "non-deterministically") {
+		t.Errorf("expected AssertDeterministic to fail, got:\n%s", r.output())
+	}
+}
+
+// probe records the options of the writer it is encoded to. Its
+// MarshalCramberry fails, so the helpers must go through EncodeTo.
+type probe struct{ opts *[]cramberry.Options }
+
+func (p probe) EncodeTo(w *cramberry.Writer) {
+	*p.opts = append(*p.opts, w.Options())
+	w.WriteEndMarker()
+}
+
+func (p probe) MarshalCramberry() ([]byte, error) {
+	return nil, errors.New("MarshalCramberry must not be called")
+}
+
+func TestAssertDeterministicUsesEncodeTo(t *testing.T) {
+	var seen []cramberry.Options
+	r := &recorder{TB: t}
+	AssertDeterministic(r, probe{opts: &seen})
+	if r.failed {
+		t.Fatalf("AssertDeterministic failed:\n%s", r.output())
+	}
+	if len(seen) != 2 {
+		t.Fatalf("EncodeTo called %d times, want 2", len(seen))
+	}
+	for i, opts := range seen {
+		if !opts.Deterministic {
+			t.Errorf("encoding %d: writer options %+v, want Deterministic", i, opts)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := &person{
+		Name:   "a",
+		Tags:   []string{"x", "y"},
+		Home:   &address{City: "Paris"},
+		Labels: map[string]string{"k": "1", "only-a": "2"},
+	}
+	b := &person{
+		Name:   "b",
+		Tags:   []string{"x", "z"},
+		Labels: map[string]string{"k": "1"},
+	}
+
+	got := diff(reflect.ValueOf(a), reflect.ValueOf(b))
+	want := []string{
+		`.Name: "a" != "b"`,
+		`.Tags[1]: "y" != "z"`,
+		`.Home: &cramtest.address{Street:"", City:"Paris"} != nil`,
+		`.Labels["only-a"]: "2" != <missing>`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diff mismatch:\n got %q\nwant %q", got, want)
+	}
+}
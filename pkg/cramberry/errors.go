@@ -4,6 +4,8 @@ package cramberry
 import (
 	"errors"
 	"fmt"
+
+	"github.com/blockberries/cramberry/internal/wire"
 )
 
 // Sentinel errors for common conditions.
@@ -12,6 +14,13 @@ var (
 	// ErrInvalidVarint indicates the varint encoding is malformed.
 	ErrInvalidVarint = errors.New("cramberry: invalid varint")
 
+	// ErrVarintOverflow indicates a varint whose value does not fit in 64 bits.
+	// Both Reader and StreamReader report it when the 10th byte is greater than 1.
+	ErrVarintOverflow = wire.ErrVarintOverflow
+
+	// ErrVarintTooLong indicates a varint longer than MaxVarintLen64 bytes.
+	ErrVarintTooLong = wire.ErrVarintTooLong
+
 	// ErrUnexpectedEOF indicates the data was truncated unexpectedly.
 	ErrUnexpectedEOF = errors.New("cramberry: unexpected end of data")
 
@@ -51,6 +60,12 @@ var (
 	// ErrMaxMapSize indicates the maximum map size was exceeded.
 	ErrMaxMapSize = errors.New("cramberry: maximum map size exceeded")
 
+	// ErrMaxOutputSize indicates an encoder produced more output than allowed.
+	ErrMaxOutputSize = errors.New("cramberry: maximum output size exceeded")
+
+	// ErrMaxFramesExceeded indicates a stream held more messages than allowed.
+	ErrMaxFramesExceeded = errors.New("cramberry: maximum frame count exceeded")
+
 	// ErrInvalidUTF8 indicates a string contains invalid UTF-8.
 	ErrInvalidUTF8 = errors.New("cramberry: invalid UTF-8 string")
 
@@ -77,6 +92,15 @@ var (
 
 	// ErrNotImplemented indicates a feature is not yet implemented.
 	ErrNotImplemented = errors.New("cramberry: not implemented")
+
+	// ErrChecksumMismatch indicates data does not match the checksum stored
+	// with it, so it was corrupted or truncated.
+	ErrChecksumMismatch = errors.New("cramberry: checksum mismatch")
+
+	// ErrDuplicateFieldNumber indicates a struct declares the same field
+	// number on two fields. It is returned instead of a panic when strict
+	// field validation is disabled with SetStrictFieldValidation(false).
+	ErrDuplicateFieldNumber = errors.New("cramberry: duplicate field number")
 )
 
 // DecodeError provides detailed context for decoding failures.
@@ -334,7 +358,9 @@ func IsLimitExceeded(err error) bool {
 		errors.Is(err, ErrMaxStringLength),
 		errors.Is(err, ErrMaxBytesLength),
 		errors.Is(err, ErrMaxArrayLength),
-		errors.Is(err, ErrMaxMapSize):
+		errors.Is(err, ErrMaxMapSize),
+		errors.Is(err, ErrMaxOutputSize),
+		errors.Is(err, ErrMaxFramesExceeded):
 		return true
 	default:
 		return false
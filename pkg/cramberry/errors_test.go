@@ -338,6 +338,8 @@ func TestIsLimitExceeded(t *testing.T) {
 		ErrMaxBytesLength,
 		ErrMaxArrayLength,
 		ErrMaxMapSize,
+		ErrMaxOutputSize,
+		ErrMaxFramesExceeded,
 	}
 
 	for _, err := range limitErrors {
@@ -376,6 +378,8 @@ func TestSentinelErrors(t *testing.T) {
 		ErrMaxBytesLength,
 		ErrMaxArrayLength,
 		ErrMaxMapSize,
+		ErrMaxOutputSize,
+		ErrMaxFramesExceeded,
 		ErrInvalidUTF8,
 		ErrDuplicateType,
 		ErrDuplicateTypeID,
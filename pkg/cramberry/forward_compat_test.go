@@ -483,3 +483,42 @@ func TestForwardCompatLargeUnknownFields(t *testing.T) {
 		t.Errorf("ID = %d, want 42", v1.ID)
 	}
 }
+
+func TestForwardCompatWarnings(t *testing.T) {
+	type userDeprecated struct {
+		ID   int32  `cramberry:"1"`
+		Name string `cramberry:"2,deprecated"`
+	}
+
+	data, err := Marshal(UserV2{ID: 7, Name: "Bob", Email: "bob@example.com"})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	var warnings []Warning
+	opts := DefaultOptions
+	opts.Hooks = &Hooks{OnWarning: func(w Warning) { warnings = append(warnings, w) }}
+
+	var decoded userDeprecated
+	if err := UnmarshalWithOptions(data, &decoded, opts); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if decoded.ID != 7 || decoded.Name != "Bob" {
+		t.Errorf("decoded = %+v", decoded)
+	}
+
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	if warnings[0].Code != WarningDeprecatedField || warnings[0].FieldNum != 2 {
+		t.Errorf("warning 0 = %v, want deprecated field 2", warnings[0])
+	}
+	if warnings[1].Code != WarningUnknownField || warnings[1].FieldNum != 3 {
+		t.Errorf("warning 1 = %v, want unknown field 3", warnings[1])
+	}
+
+	// Without a callback the same data decodes silently
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+}
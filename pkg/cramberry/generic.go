@@ -0,0 +1,354 @@
+package cramberry
+
+import (
+	"bytes"
+
+	"github.com/blockberries/cramberry/internal/wire"
+)
+
+// maxGenericSteps bounds the work UnmarshalGeneric spends on rejected
+// guesses, so that adversarial input cannot make it run for exponential
+// time. Work on the shapes it keeps is linear in the input and not counted.
+const maxGenericSteps = 1 << 20
+
+// UnmarshalGeneric decodes a V2-encoded message without a schema, returning
+// its fields keyed by field number. It is meant for inspecting and debugging
+// encodings, not for application decoding.
+//
+// Values are decoded from the wire type alone:
+//   - Varint fields (bools, enums, unsigned integers) decode as uint64.
+//   - SVarint fields (signed integers) decode as int64.
+//   - Fixed32 and Fixed64 fields decode as their raw uint32 and uint64 bits;
+//     use math.Float32frombits or math.Float64frombits for floats.
+//   - Bytes fields decode as one of the shapes below.
+//
+// The Bytes wire type is shared by strings, byte slices, nested messages,
+// packed arrays, repeated fields and maps, and only some of these carry a
+// length prefix. UnmarshalGeneric tries the following shapes in order and
+// keeps the first one with which the rest of the enclosing message still
+// parses:
+//
+//  1. a nested message, decoded as map[int]any
+//  2. a length-prefixed string or byte slice, decoded as []byte
+//  3. a map with string keys, decoded as map[string]any whose values are
+//     nested messages, []byte or uint64 varints
+//  4. a repeated message field, decoded as []any of map[int]any
+//  5. a packed array of varints, decoded as []any of uint64
+//
+// The choice is a heuristic and can be wrong: a string whose bytes happen to
+// form a valid message decodes as a map, a packed array whose elements all
+// fit in one byte (or whose elements are fixed-width floats) decodes as
+// []byte, packed signed integers come back zigzag encoded, and an empty
+// message and an empty string have the same encoding. Input that cannot be
+// parsed as any combination of these shapes returns a DecodeError.
+func UnmarshalGeneric(data []byte) (map[int]any, error) {
+	d := genericDecoder{data: data}
+	fields := make(map[int]any)
+	if _, ok := d.fields(0, 0, true, fields); !ok {
+		if d.exhausted() {
+			return nil, NewDecodeError("message is too ambiguous to decode without a schema", nil)
+		}
+		return nil, NewDecodeError("data is not a well-formed message", nil)
+	}
+	return fields, nil
+}
+
+// genericDecoder holds the state of a single UnmarshalGeneric call.
+type genericDecoder struct {
+	data []byte
+
+	// work counts the fields and entries parsed, and wasted the part of
+	// that work that was thrown away when a guess was rejected.
+	work   int
+	wasted int
+
+	// messages caches the result of parsing a nested message at a given
+	// position and depth, which is the same whichever guess asks for it.
+	messages map[genericPos]genericMessage
+}
+
+// genericPos is a position and nesting depth in the data.
+type genericPos struct {
+	pos, depth int
+}
+
+// genericMessage is the result of genericDecoder.message.
+type genericMessage struct {
+	fields map[int]any
+	end    int
+	ok     bool
+}
+
+// genericField is a field parsed by genericDecoder.fields.
+type genericField struct {
+	num   int
+	value any
+}
+
+// genericChoice records a Bytes field whose shape was guessed, so that
+// genericDecoder.fields can come back and try the next shape.
+type genericChoice struct {
+	num    int // field number
+	pos    int // position of the value
+	fields int // number of fields parsed before this one
+	shape  int // next shape to try
+
+	// work and wasted at the start of the current guess
+	work   int
+	wasted int
+}
+
+// Shapes of a Bytes value, in the order documented on UnmarshalGeneric.
+const (
+	shapeMessage = iota
+	shapeBytes
+	shapeStringMap
+	shapeMessageList
+	shapeVarintList
+	numShapes
+)
+
+// exhausted reports whether the backtracking budget is used up.
+func (d *genericDecoder) exhausted() bool {
+	return d.wasted > maxGenericSteps
+}
+
+// reject charges the work done since the current guess of c began to the
+// backtracking budget. Work already charged by guesses nested in it is not
+// charged twice.
+func (d *genericDecoder) reject(c *genericChoice) {
+	d.wasted += (d.work - c.work) - (d.wasted - c.wasted)
+}
+
+// fields parses fields starting at pos up to and including the end marker,
+// storing them in out. It returns the position after the end marker.
+// The top-level message may omit its end marker and must consume all data.
+//
+// Fields are parsed in a loop. When the message cannot be completed, it
+// backtracks to the last Bytes field that has shapes left to try.
+func (d *genericDecoder) fields(pos, depth int, top bool, out map[int]any) (int, bool) {
+	var parsed []genericField
+	var choices []genericChoice
+	for {
+		if d.exhausted() {
+			return 0, false
+		}
+
+		var v any
+		next, ok := 0, false
+		fieldNum, wireType, n := 0, byte(0), 0
+		if pos < len(d.data) {
+			fieldNum, wireType, n = DecodeCompactTag(d.data[pos:])
+		}
+		if pos >= len(d.data) || fieldNum == 0 {
+			// End of the message
+			end := pos
+			if pos >= len(d.data) {
+				ok = top
+			} else if n != 0 && d.data[pos] == EndMarker && (!top || pos+1 == len(d.data)) {
+				end, ok = pos+1, true
+			}
+			if ok {
+				// A repeated field number keeps its last occurrence
+				for _, f := range parsed {
+					out[f.num] = f.value
+				}
+				return end, true
+			}
+		} else {
+			d.work++
+			if wireType == WireTypeV2Bytes {
+				choices = append(choices, genericChoice{num: fieldNum, pos: pos + n, fields: len(parsed)})
+				if v, next, ok = d.nextShape(&choices[len(choices)-1], depth); !ok {
+					choices = choices[:len(choices)-1]
+				}
+			} else {
+				v, next, ok = d.value(wireType, pos+n)
+			}
+		}
+
+		for !ok && len(choices) > 0 {
+			c := &choices[len(choices)-1]
+			d.reject(c)
+			parsed = parsed[:c.fields]
+			fieldNum = c.num
+			if v, next, ok = d.nextShape(c, depth); !ok {
+				choices = choices[:len(choices)-1]
+			}
+		}
+		if !ok {
+			return 0, false
+		}
+		parsed = append(parsed, genericField{num: fieldNum, value: v})
+		pos = next
+	}
+}
+
+// value decodes a value of a fixed-size or varint wire type at pos.
+func (d *genericDecoder) value(wireType byte, pos int) (any, int, bool) {
+	switch wireType {
+	case WireTypeV2Varint:
+		v, n, err := wire.DecodeUvarint(d.data[pos:])
+		return v, pos + n, err == nil
+
+	case WireTypeV2SVarint:
+		v, n, err := wire.DecodeSvarint(d.data[pos:])
+		return v, pos + n, err == nil
+
+	case WireTypeV2Fixed32:
+		v, err := wire.DecodeFixed32(d.data[pos:])
+		return v, pos + 4, err == nil
+
+	case WireTypeV2Fixed64:
+		v, err := wire.DecodeFixed64(d.data[pos:])
+		return v, pos + 8, err == nil
+	}
+	return nil, 0, false
+}
+
+// nextShape parses the Bytes value of c as the next shape that fits,
+// starting at c.shape.
+func (d *genericDecoder) nextShape(c *genericChoice, depth int) (any, int, bool) {
+	for ; c.shape < numShapes && !d.exhausted(); c.shape++ {
+		c.work, c.wasted = d.work, d.wasted
+		if v, end, ok := d.shape(c.shape, c.pos, depth); ok {
+			c.shape++
+			return v, end, true
+		}
+		d.reject(c)
+	}
+	return nil, 0, false
+}
+
+// shape parses the Bytes value at pos as the given shape.
+func (d *genericDecoder) shape(shape, pos, depth int) (any, int, bool) {
+	nested := depth+1 < DefaultLimits.MaxDepth
+	switch shape {
+	case shapeMessage:
+		if nested {
+			return d.message(pos, depth+1)
+		}
+
+	case shapeBytes:
+		if b, end, ok := d.lengthPrefixed(pos); ok {
+			return bytes.Clone(b), end, true
+		}
+
+	case shapeStringMap:
+		if count, start, ok := d.count(pos); ok && nested {
+			return d.stringMap(start, count, depth+1)
+		}
+
+	case shapeMessageList:
+		if count, start, ok := d.count(pos); ok && nested {
+			return d.messageList(start, count, depth+1)
+		}
+
+	case shapeVarintList:
+		if count, start, ok := d.count(pos); ok {
+			return d.varintList(start, count)
+		}
+	}
+	return nil, 0, false
+}
+
+// count parses the element count of a map or list.
+func (d *genericDecoder) count(pos int) (int, int, bool) {
+	count, n, err := wire.DecodeUvarint(d.data[pos:])
+	// Every element takes at least one byte
+	if err != nil || count == 0 || count > uint64(len(d.data)-pos-n) {
+		return 0, 0, false
+	}
+	return int(count), pos + n, true
+}
+
+// message parses a nested message terminated by an end marker.
+func (d *genericDecoder) message(pos, depth int) (map[int]any, int, bool) {
+	key := genericPos{pos, depth}
+	if m, ok := d.messages[key]; ok {
+		return m.fields, m.end, m.ok
+	}
+	fields := make(map[int]any)
+	end, ok := d.fields(pos, depth, false, fields)
+	if d.exhausted() {
+		// Not the real result, so not cached
+		return nil, 0, false
+	}
+	if d.messages == nil {
+		d.messages = make(map[genericPos]genericMessage)
+	}
+	d.messages[key] = genericMessage{fields, end, ok}
+	return fields, end, ok
+}
+
+// lengthPrefixed parses a varint length followed by that many bytes.
+func (d *genericDecoder) lengthPrefixed(pos int) ([]byte, int, bool) {
+	length, n, err := wire.DecodeUvarint(d.data[pos:])
+	if err != nil || length > uint64(len(d.data)-pos-n) {
+		return nil, 0, false
+	}
+	start := pos + n
+	end := start + int(length)
+	return d.data[start:end], end, true
+}
+
+// stringMap parses count key/value pairs with length-prefixed keys.
+func (d *genericDecoder) stringMap(pos, count, depth int) (map[string]any, int, bool) {
+	m := make(map[string]any, min(count, 64))
+	for range count {
+		d.work++
+		if d.exhausted() {
+			return nil, 0, false
+		}
+		key, next, ok := d.lengthPrefixed(pos)
+		if !ok {
+			return nil, 0, false
+		}
+		if _, dup := m[string(key)]; dup {
+			return nil, 0, false
+		}
+		pos = next
+
+		if msg, end, ok := d.message(pos, depth); ok {
+			m[string(key)] = msg
+			pos = end
+		} else if b, end, ok := d.lengthPrefixed(pos); ok {
+			m[string(key)] = bytes.Clone(b)
+			pos = end
+		} else if v, n, err := wire.DecodeUvarint(d.data[pos:]); err == nil {
+			m[string(key)] = v
+			pos += n
+		} else {
+			return nil, 0, false
+		}
+	}
+	return m, pos, true
+}
+
+// messageList parses count consecutive nested messages.
+func (d *genericDecoder) messageList(pos, count, depth int) ([]any, int, bool) {
+	list := make([]any, 0, min(count, 64))
+	for range count {
+		msg, end, ok := d.message(pos, depth)
+		if !ok {
+			return nil, 0, false
+		}
+		list = append(list, msg)
+		pos = end
+	}
+	return list, pos, true
+}
+
+// varintList parses count consecutive varints.
+func (d *genericDecoder) varintList(pos, count int) ([]any, int, bool) {
+	list := make([]any, 0, min(count, 64))
+	for range count {
+		v, n, err := wire.DecodeUvarint(d.data[pos:])
+		if err != nil {
+			return nil, 0, false
+		}
+		list = append(list, v)
+		pos += n
+	}
+	return list, pos, true
+}
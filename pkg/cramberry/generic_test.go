@@ -0,0 +1,149 @@
+package cramberry
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+type genericTimestamp struct {
+	Seconds int64 `cramberry:"1"`
+	Nanos   int32 `cramberry:"2"`
+}
+
+type genericContact struct {
+	Email  string            `cramberry:"1"`
+	Phones []string          `cramberry:"2"`
+	Labels map[string]string `cramberry:"3"`
+}
+
+type genericPerson struct {
+	ID        int64              `cramberry:"1"`
+	FirstName string             `cramberry:"2"`
+	LastName  string             `cramberry:"3"`
+	Contact   genericContact     `cramberry:"6"`
+	Status    uint32             `cramberry:"7"`
+	CreatedAt genericTimestamp   `cramberry:"8"`
+	History   []genericTimestamp `cramberry:"9"`
+	Scores    []int32            `cramberry:"10"`
+	Height    float64            `cramberry:"11"`
+	Active    bool               `cramberry:"20"`
+}
+
+func TestUnmarshalGenericPerson(t *testing.T) {
+	p := genericPerson{
+		ID:        42,
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Contact: genericContact{
+			Email:  "ada@example.com",
+			Labels: map[string]string{"role": "author"},
+		},
+		Status:    2,
+		CreatedAt: genericTimestamp{Seconds: 1700000000, Nanos: 5},
+		History:   []genericTimestamp{{Seconds: 1}, {Seconds: 2, Nanos: 3}},
+		Scores:    []int32{300, -1, 2},
+		Height:    1.65,
+		Active:    true,
+	}
+	data, err := Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	got, err := UnmarshalGeneric(data)
+	if err != nil {
+		t.Fatalf("UnmarshalGeneric failed: %v", err)
+	}
+
+	want := map[int]any{
+		1: int64(42),
+		2: []byte("Ada"),
+		3: []byte("Lovelace"),
+		6: map[int]any{
+			1: []byte("ada@example.com"),
+			3: map[string]any{"role": []byte("author")},
+		},
+		7: uint64(2),
+		8: map[int]any{1: int64(1700000000), 2: int64(5)},
+		9: []any{
+			map[int]any{1: int64(1)},
+			map[int]any{1: int64(2), 2: int64(3)},
+		},
+		// Packed signed integers come back zigzag encoded. 300 needs a
+		// two-byte varint; had every element fit in one byte, the array
+		// would be indistinguishable from a string and decode as []byte.
+		10: []any{uint64(600), uint64(1), uint64(4)},
+		11: math.Float64bits(1.65),
+		20: uint64(1),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnmarshalGeneric:\ngot  %#v\nwant %#v", got, want)
+	}
+}
+
+func TestUnmarshalGenericEmpty(t *testing.T) {
+	data, err := Marshal(genericPerson{})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	got, err := UnmarshalGeneric(data)
+	if err != nil {
+		t.Fatalf("UnmarshalGeneric failed: %v", err)
+	}
+	// Nested structs are always written, so they show up as empty messages
+	want := map[int]any{6: map[int]any{}, 8: map[int]any{}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnmarshalGeneric = %#v, want %#v", got, want)
+	}
+}
+
+func TestUnmarshalGenericMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated varint", []byte{0x10, 0x80}},
+		{"truncated fixed64", []byte{0x12, 0x01, 0x02}},
+		{"bytes past end", []byte{0x24, 0x05, 'a'}},
+		{"unknown wire type", []byte{0x1A, 0x01}},
+		{"trailing data", []byte{0x10, 0x01, 0x00, 0x10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UnmarshalGeneric(tt.data); err == nil {
+				t.Errorf("UnmarshalGeneric(%x) succeeded, want error", tt.data)
+			}
+		})
+	}
+}
+
+func TestUnmarshalGenericManyFields(t *testing.T) {
+	// Fields that are not Bytes leave nothing to guess, so a flat message
+	// is not limited by the backtracking budget however many it has
+	const n = 1 << 20
+	data := make([]byte, 0, n*5)
+	for i := range n / 4 {
+		data = append(data, 0x10, byte(i%100))            // field 1, varint
+		data = append(data, 0x28, 0x03)                   // field 2, svarint
+		data = append(data, 0x36, 1, 0, 0, 0)             // field 3, fixed32
+		data = append(data, 0x42, 2, 0, 0, 0, 0, 0, 0, 0) // field 4, fixed64
+	}
+	data = append(data, 0x54, 0x05, 'h', 'e', 'l', 'l', 'o') // field 5, bytes
+	data = append(data, EndMarker)
+
+	got, err := UnmarshalGeneric(data)
+	if err != nil {
+		t.Fatalf("UnmarshalGeneric failed: %v", err)
+	}
+	want := map[int]any{
+		1: uint64((n/4 - 1) % 100),
+		2: int64(-2),
+		3: uint32(1),
+		4: uint64(2),
+		5: []byte("hello"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnmarshalGeneric = %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package cramberry
+
+import "encoding/json"
+
+// JSONField decodes the value stored under key in m into dst, a pointer
+// to a field. It backs the FromJSON methods of generated messages, where m
+// is a map returned by ToJSON or decoded by encoding/json, so its values
+// may be Go values of the field's own type or generic JSON values such as
+// float64, string and map[string]any.
+//
+// The value is converted by encoding it as JSON and decoding that into dst,
+// which lets dst's type decide how it is read: numbers fill integer and
+// float fields, base64 strings fill byte slices, and objects fill messages
+// through their json tags. A missing key leaves dst unchanged.
+func JSONField(m map[string]any, key string, dst any) error {
+	v, ok := m[key]
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(data, dst)
+	}
+	if err != nil {
+		return NewDecodeError("JSON field "+key, err)
+	}
+	return nil
+}
+
+// JSONMessage decodes the value stored under key in m into dst with dst's
+// FromJSON method, so that a nested message is read by the same rules as
+// the message holding it, ordered maps and JSON names included. It backs
+// the FromJSON methods of generated messages and reports whether dst was
+// set: a missing key or null leaves dst unchanged.
+//
+// The value is normally an object, as returned by ToJSON or decoded by
+// encoding/json. Other values are converted as JSONField converts them.
+func JSONMessage(m map[string]any, key string, dst interface{ FromJSON(map[string]any) error }) (bool, error) {
+	v, ok := m[key]
+	if !ok || v == nil {
+		return false, nil
+	}
+	fields, ok := v.(map[string]any)
+	if !ok {
+		if err := JSONField(m, key, dst); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if err := dst.FromJSON(fields); err != nil {
+		return false, NewDecodeError("JSON field "+key, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,56 @@
+package cramberry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestJSONField(t *testing.T) {
+	type point struct {
+		X int32 `json:"x"`
+		Y int32 `json:"y"`
+	}
+
+	// Values as decoded by encoding/json
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(`{"id": 42, "data": "AQI=", "at": {"x": 1, "y": 2}, "note": null}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	var id int64
+	if err := JSONField(decoded, "id", &id); err != nil || id != 42 {
+		t.Errorf("id = %d, %v", id, err)
+	}
+	var data []byte
+	if err := JSONField(decoded, "data", &data); err != nil || !bytes.Equal(data, []byte{1, 2}) {
+		t.Errorf("data = %v, %v", data, err)
+	}
+	var at *point
+	if err := JSONField(decoded, "at", &at); err != nil || at == nil || *at != (point{1, 2}) {
+		t.Errorf("at = %v, %v", at, err)
+	}
+	note := new(string)
+	if err := JSONField(decoded, "note", &note); err != nil || note != nil {
+		t.Errorf("null note = %v, %v", note, err)
+	}
+
+	// Go values of the field's own type
+	count := Some[int32](0)
+	var got Optional[int32]
+	if err := JSONField(map[string]any{"count": count.Value}, "count", &got); err != nil || got != count {
+		t.Errorf("count = %v, %v", got, err)
+	}
+
+	// A missing key leaves the field alone
+	id = 7
+	if err := JSONField(decoded, "missing", &id); err != nil || id != 7 {
+		t.Errorf("missing key changed the field to %d, %v", id, err)
+	}
+
+	var de *DecodeError
+	if err := JSONField(map[string]any{"id": "x"}, "id", &id); !errors.As(err, &de) {
+		t.Errorf("expected a DecodeError for a string in an integer field, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // Marshal encodes a Go value into cramberry binary format.
@@ -15,6 +16,9 @@ import (
 // For struct types, fields are encoded in field number order.
 // Field numbers are assigned based on the "cramberry" struct tag,
 // or sequentially if no tag is present.
+//
+// To encode an interface value polymorphically, pass a pointer to it, as in
+// Marshal(&shape); the concrete type's registered type ID is written first.
 func Marshal(v any) ([]byte, error) {
 	return MarshalWithOptions(v, DefaultOptions)
 }
@@ -52,6 +56,20 @@ func MarshalAppendWithOptions(buf []byte, v any, opts Options) ([]byte, error) {
 	return w.Bytes(), nil
 }
 
+// MarshalT is a typed form of Marshal. Because v is passed as a T rather
+// than boxed in an any, an interface type parameter keeps its type: with
+// T = Shape, MarshalT(shape) writes the concrete type's registered ID just
+// as Marshal(&shape) does. For struct and pointer types the output is the
+// same as Marshal(v).
+func MarshalT[T any](v T) ([]byte, error) {
+	return Marshal(&v)
+}
+
+// AppendT is a typed form of MarshalAppend; see MarshalT.
+func AppendT[T any](buf []byte, v T) ([]byte, error) {
+	return MarshalAppend(buf, &v)
+}
+
 // encodeValue encodes a reflect.Value to the writer.
 func encodeValue(w *Writer, v reflect.Value) error {
 	return encodeValueWithRegistry(w, v, DefaultRegistry)
@@ -70,13 +88,17 @@ func encodeValueWithRegistry(w *Writer, v reflect.Value, reg *Registry) error {
 		return encodeInterface(w, v, reg)
 	}
 
-	// Dereference pointers
+	// Dereference pointers. A pointer to an interface, as in Marshal(&shape),
+	// keeps the type ID that passing the interface value itself would lose.
 	for v.Kind() == reflect.Ptr {
 		if v.IsNil() {
 			w.WriteNil()
 			return w.Err()
 		}
 		v = v.Elem()
+		if v.Kind() == reflect.Interface {
+			return encodeInterface(w, v, reg)
+		}
 	}
 
 	switch v.Kind() {
@@ -123,6 +145,9 @@ func encodeValueWithRegistry(w *Writer, v reflect.Value, reg *Registry) error {
 	case reflect.Map:
 		return encodeMap(w, v)
 	case reflect.Struct:
+		if isOrderedMapType(v.Type()) {
+			return v.Interface().(orderedMapCodec).encodeEntries(w)
+		}
 		return encodeStruct(w, v)
 	default:
 		return NewEncodeError("unsupported type: "+v.Type().String(), ErrNotImplemented)
@@ -130,8 +155,22 @@ func encodeValueWithRegistry(w *Writer, v reflect.Value, reg *Registry) error {
 	return w.Err()
 }
 
-// encodeInterface encodes an interface value with its type ID.
+// encodeInterface encodes an interface value with its type ID. Values of
+// type any are length-prefixed, as WriteAny writes them.
 func encodeInterface(w *Writer, v reflect.Value, reg *Registry) error {
+	if v.Type().NumMethod() != 0 {
+		return encodeInterfaceValue(w, v, reg)
+	}
+	frame := w.BeginMessage()
+	if err := encodeInterfaceValue(w, v, reg); err != nil {
+		return err
+	}
+	w.EndMessage(frame)
+	return w.Err()
+}
+
+// encodeInterfaceValue encodes the type ID and value of an interface.
+func encodeInterfaceValue(w *Writer, v reflect.Value, reg *Registry) error {
 	if v.IsNil() {
 		w.WriteTypeID(TypeIDNil)
 		return w.Err()
@@ -156,37 +195,96 @@ func encodeInterface(w *Writer, v reflect.Value, reg *Registry) error {
 	return encodeValueWithRegistry(w, elem, reg)
 }
 
-// encodeSlice encodes a slice value.
-func encodeSlice(w *Writer, v reflect.Value) error {
-	if v.IsNil() {
-		w.WriteArrayHeader(0)
-		return w.Err()
+// WriteAny writes v as a polymorphic value: a length prefix, then the type
+// ID registered for its concrete type in DefaultRegistry, followed by the
+// value itself. The prefix lets readers that don't know the field skip it.
+// A nil v is written as TypeIDNil. Types with an EncodeTo method, such as
+// generated messages, encode themselves; others are encoded by reflection.
+func (w *Writer) WriteAny(v any) {
+	frame := w.BeginMessage()
+	if frame < 0 {
+		return
+	}
+	w.writeAnyValue(v)
+	w.EndMessage(frame)
+}
+
+// writeAnyValue writes the type ID and value of v for WriteAny.
+func (w *Writer) writeAnyValue(v any) {
+	if v == nil {
+		w.WriteTypeID(TypeIDNil)
+		return
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		w.WriteTypeID(TypeIDNil)
+		return
 	}
 
-	// Use packed encoding for primitive types (no depth tracking needed for primitives)
+	typeID := DefaultRegistry.TypeIDFor(v)
+	if typeID == TypeIDNil {
+		w.setError(NewEncodeError("unregistered type: "+reflect.TypeOf(v).String(), ErrUnregisteredType))
+		return
+	}
+	w.WriteTypeID(typeID)
+
+	if enc, ok := v.(interface{ EncodeTo(*Writer) }); ok {
+		enc.EncodeTo(w)
+		return
+	}
+	if err := encodeValue(w, reflect.ValueOf(v)); err != nil {
+		w.setError(err)
+	}
+}
+
+// encodeSlice encodes a slice value.
+func encodeSlice(w *Writer, v reflect.Value) error {
+	// Use packed encoding for primitive types (no depth tracking needed for
+	// primitives); a nil slice is written as an empty one
 	if isPackableTypeCached(v.Type().Elem()) {
 		return encodePackedSlice(w, v)
 	}
 
-	// Check depth limit for non-primitive element types
+	// Check depth limit for non-primitive element types, nil slices
+	// included, as decodeSlice does
 	if !w.enterNested() {
 		return w.Err()
 	}
 	defer w.exitNested()
 
+	if v.IsNil() {
+		w.WriteArrayHeader(0)
+		return w.Err()
+	}
+
 	n := v.Len()
 	w.WriteArrayHeader(n)
 	if w.Err() != nil {
 		return w.Err()
 	}
 	for i := 0; i < n; i++ {
-		if err := encodeValue(w, v.Index(i)); err != nil {
+		if err := encodeElement(w, v.Index(i)); err != nil {
 			return err
 		}
 	}
 	return w.Err()
 }
 
+// encodeElement encodes an element of a slice, array or map. Pointer
+// elements are preceded by a nil or present marker, since the encoding of
+// the value they point to may start with any byte. A pointer to a pointer
+// has a marker for each level, so that a nil at any level round-trips.
+func encodeElement(w *Writer, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			w.WriteNil()
+			return w.Err()
+		}
+		w.WritePresent()
+		v = v.Elem()
+	}
+	return encodeValue(w, v)
+}
+
 // isPackableType returns true if the type can be packed in a contiguous byte sequence.
 // Packable types are fixed-size primitives: integers, floats, and bools.
 // isPackableTypeCached returns whether the type supports packed encoding, using cache.
@@ -277,7 +375,7 @@ func encodeArray(w *Writer, v reflect.Value) error {
 		return w.Err()
 	}
 	for i := 0; i < n; i++ {
-		if err := encodeValue(w, v.Index(i)); err != nil {
+		if err := encodeElement(w, v.Index(i)); err != nil {
 			return err
 		}
 	}
@@ -331,16 +429,17 @@ func encodePackedArray(w *Writer, v reflect.Value) error {
 // encodeMap encodes a map value.
 // If Deterministic option is enabled, keys are sorted for reproducible output.
 func encodeMap(w *Writer, v reflect.Value) error {
-	if v.IsNil() {
-		w.WriteMapHeader(0)
+	// Check depth limit. A nil map counts as a level like any other, since
+	// decodeMap counts the empty map it reads back.
+	if !w.enterNested() {
 		return w.Err()
 	}
+	defer w.exitNested()
 
-	// Check depth limit
-	if !w.enterNested() {
+	if v.IsNil() {
+		w.WriteMapHeader(0)
 		return w.Err()
 	}
-	defer w.exitNested()
 
 	// Validate that the key type is supported for encoding
 	keyType := v.Type().Key()
@@ -364,7 +463,7 @@ func encodeMap(w *Writer, v reflect.Value) error {
 		if err := encodeValue(w, key); err != nil {
 			return err
 		}
-		if err := encodeValue(w, v.MapIndex(key)); err != nil {
+		if err := encodeElement(w, v.MapIndex(key)); err != nil {
 			return err
 		}
 	}
@@ -380,16 +479,31 @@ func encodeStruct(w *Writer, v reflect.Value) error {
 	}
 	defer w.exitNested()
 
-	info := getStructInfo(v.Type())
+	info, err := getStructInfo(v.Type())
+	if err != nil {
+		return err
+	}
 
 	for _, field := range info.fields {
 		fv := v.Field(field.index)
 
-		// Handle OmitEmpty
-		if w.Options().OmitEmpty && isZeroValue(fv) {
+		// Unset optional wrappers are always omitted; set ones are written
+		// even when the value is zero
+		if field.optional {
+			if !fv.Field(optionalSetIndex).Bool() {
+				continue
+			}
+			fv = fv.Field(optionalValueIndex)
+		} else if w.Options().OmitEmpty && isZeroValue(fv) {
+			continue
+		} else if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			// Nil pointer fields are always left off, as in generated code,
+			// since a set pointer field is written as its plain value
 			continue
 		}
 
+		start := w.Len()
+
 		// Write compact field tag
 		w.WriteCompactTag(field.num, getWireTypeV2Cached(fv.Type()))
 		if w.Err() != nil {
@@ -400,10 +514,16 @@ func encodeStruct(w *Writer, v reflect.Value) error {
 		if err := encodeValue(w, fv); err != nil {
 			return err
 		}
+
+		if h := w.opts.Hooks; h != nil && h.OnField != nil && w.depth == 1 {
+			h.OnField(field.num, w.Len()-start)
+		}
 	}
 
-	// Write end marker
-	w.WriteEndMarker()
+	// Write end marker; depth 1 is the outermost struct
+	if !(w.opts.OmitTopLevelEndMarker && w.depth == 1) {
+		w.WriteEndMarker()
+	}
 	return w.Err()
 }
 
@@ -433,7 +553,10 @@ func computeWireTypeV2(t reflect.Type) byte {
 		return WireTypeV2Fixed64 // 2x float32 = 8 bytes
 	case reflect.Complex128, reflect.String, reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
 		return WireTypeV2Bytes
-	case reflect.Ptr, reflect.Interface:
+	case reflect.Ptr:
+		// A set pointer is encoded as the value it points to
+		return computeWireTypeV2(t.Elem())
+	case reflect.Interface:
 		return WireTypeV2Bytes
 	default:
 		return WireTypeV2Bytes
@@ -442,11 +565,13 @@ func computeWireTypeV2(t reflect.Type) byte {
 
 // fieldInfo holds metadata about a struct field.
 type fieldInfo struct {
-	name      string
-	num       int
-	index     int
-	omitEmpty bool
-	required  bool
+	name       string
+	num        int
+	index      int
+	omitEmpty  bool
+	required   bool
+	deprecated bool
+	optional   bool // an Optional wrapper, encoded as its value when set
 }
 
 // structInfo holds cached metadata about a struct type.
@@ -464,10 +589,24 @@ var wireTypeCache sync.Map
 // packableCache caches whether element types support packed encoding.
 var packableCache sync.Map
 
-// getStructInfo returns cached struct metadata.
-func getStructInfo(t reflect.Type) *structInfo {
+// lenientFieldValidation is set by SetStrictFieldValidation(false).
+var lenientFieldValidation atomic.Bool
+
+// SetStrictFieldValidation controls what happens when a struct type with
+// duplicate field numbers is first encoded or decoded. When strict, the
+// default, it panics so that the mistake surfaces early in development.
+// Otherwise the operation returns an error wrapping ErrDuplicateFieldNumber,
+// which lets servers reject the value without crashing.
+func SetStrictFieldValidation(strict bool) {
+	lenientFieldValidation.Store(!strict)
+}
+
+// getStructInfo returns cached struct metadata. Invalid struct types panic
+// unless strict field validation is disabled, in which case the error is
+// returned.
+func getStructInfo(t reflect.Type) (*structInfo, error) {
 	if cached, ok := structInfoCache.Load(t); ok {
-		return cached.(*structInfo)
+		return cached.(*structInfo), nil
 	}
 
 	info := &structInfo{
@@ -487,8 +626,9 @@ func getStructInfo(t reflect.Type) *structInfo {
 		}
 
 		fi := fieldInfo{
-			name:  f.Name,
-			index: i,
+			name:     f.Name,
+			index:    i,
+			optional: isOptionalType(f.Type),
 		}
 
 		// Parse tag
@@ -504,8 +644,12 @@ func getStructInfo(t reflect.Type) *structInfo {
 
 		// Validate field number uniqueness
 		if existingField, ok := seenFieldNums[fi.num]; ok {
-			panic(fmt.Sprintf("cramberry: duplicate field number %d in %s (fields %q and %q)",
-				fi.num, t.Name(), existingField, f.Name))
+			err := fmt.Errorf("%w %d in %s (fields %q and %q)",
+				ErrDuplicateFieldNumber, fi.num, t.Name(), existingField, f.Name)
+			if !lenientFieldValidation.Load() {
+				panic(err.Error())
+			}
+			return nil, err
 		}
 		seenFieldNums[fi.num] = f.Name
 
@@ -525,7 +669,7 @@ func getStructInfo(t reflect.Type) *structInfo {
 	}
 
 	structInfoCache.Store(t, info)
-	return info
+	return info, nil
 }
 
 // parseFieldTag parses a cramberry struct tag.
@@ -559,29 +703,18 @@ func parseFieldTag(tag string, fi fieldInfo, defaultNum int) fieldInfo {
 			fi.omitEmpty = true
 		case "required":
 			fi.required = true
+		case "deprecated":
+			fi.deprecated = true
 		}
 	}
 
 	return fi
 }
 
-// maxZeroValueDepth is the maximum recursion depth for isZeroValue.
-// This prevents stack overflow on deeply nested structures.
-const maxZeroValueDepth = 100
-
-// isZeroValue returns true if the value is the zero value for its type.
+// isZeroValue reports whether a field holding v is left off the wire when
+// OmitEmpty is set. Struct values other than ordered maps are nested
+// messages, which are always written, as generated code writes them.
 func isZeroValue(v reflect.Value) bool {
-	return isZeroValueWithDepth(v, 0)
-}
-
-// isZeroValueWithDepth returns true if the value is the zero value, with depth limiting.
-// If depth exceeds maxZeroValueDepth, returns false (assume not zero) which is conservative:
-// the field will be encoded rather than omitted.
-func isZeroValueWithDepth(v reflect.Value, depth int) bool {
-	if depth > maxZeroValueDepth {
-		return false // Conservative: assume not zero, encode the field
-	}
-
 	switch v.Kind() {
 	case reflect.Bool:
 		return !v.Bool()
@@ -602,13 +735,8 @@ func isZeroValueWithDepth(v reflect.Value, depth int) bool {
 	case reflect.Ptr, reflect.Interface:
 		return v.IsNil()
 	case reflect.Struct:
-		// Check all fields with increased depth
-		for i := 0; i < v.NumField(); i++ {
-			if !isZeroValueWithDepth(v.Field(i), depth+1) {
-				return false
-			}
-		}
-		return true
+		// Ordered maps are omitted when empty, like Go maps
+		return isOrderedMapType(v.Type()) && v.Field(orderedMapKeysIndex).Len() == 0
 	default:
 		return false
 	}
@@ -651,6 +779,72 @@ func sortMapKeys(keys []reflect.Value) []reflect.Value {
 	return keys
 }
 
+// SortedMapKeys returns the keys of m in the order the reflective encoder
+// writes them in deterministic mode.
+func SortedMapKeys[K comparable, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	if len(keys) <= 1 {
+		return keys
+	}
+	// String keys are by far the most common, so skip reflection for them
+	if s, ok := any(keys).([]string); ok {
+		sort.Strings(s)
+		return keys
+	}
+
+	values := make([]reflect.Value, len(keys))
+	for i := range keys {
+		values[i] = reflect.ValueOf(&keys[i]).Elem()
+	}
+	values = sortMapKeys(values)
+	sorted := make([]K, len(values))
+	for i, v := range values {
+		sorted[i] = v.Interface().(K)
+	}
+	return sorted
+}
+
+// ForEachMapEntry calls fn for each entry of m in the order w writes map
+// entries: sorted by key when w's options set Deterministic, as the
+// reflective encoder sorts them, and in Go's map order otherwise, which
+// saves sorting the keys. Generated code encodes maps with it so that both
+// paths produce the same bytes for the same map.
+func ForEachMapEntry[K comparable, V any](w *Writer, m map[K]V, fn func(K, V)) {
+	if !w.opts.Deterministic {
+		for k, v := range m {
+			fn(k, v)
+		}
+		return
+	}
+	for _, k := range SortedMapKeys(m) {
+		fn(k, m[k])
+	}
+}
+
+// PackBools returns a bitmask with bit i set when bits[i] is true. Code
+// generated with bool packing writes runs of bool fields as one such mask;
+// it holds at most 64 bools.
+func PackBools(bits ...bool) uint64 {
+	var mask uint64
+	for i, b := range bits {
+		if b {
+			mask |= 1 << i
+		}
+	}
+	return mask
+}
+
+// UnpackBools sets *bits[i] to whether bit i of mask is set, reversing
+// PackBools. Bits beyond len(bits) are ignored.
+func UnpackBools(mask uint64, bits ...*bool) {
+	for i, b := range bits {
+		*b = mask&(1<<i) != 0
+	}
+}
+
 // compareFloatKeys compares two float64 values with a total ordering that handles
 // NaN and -0.0 correctly for deterministic sorting:
 // - All NaN values sort to the end (after +Inf)
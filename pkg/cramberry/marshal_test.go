@@ -2,8 +2,11 @@ package cramberry
 
 import (
 	"bytes"
+	"errors"
 	"math"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -320,6 +323,125 @@ func TestMarshalUnmarshalMap(t *testing.T) {
 	})
 }
 
+func TestMarshalUnmarshalMapPointerValues(t *testing.T) {
+	type Address struct {
+		Street string `cramberry:"1"`
+		City   string `cramberry:"2"`
+	}
+	type Book struct {
+		Addresses map[string]*Address `cramberry:"1"`
+		Primary   *Address            `cramberry:"2"`
+	}
+
+	original := Book{
+		Addresses: map[string]*Address{
+			"home":  {Street: "1 Main St", City: "Springfield"},
+			"moved": nil,
+			"work":  {City: "Shelbyville"},
+		},
+	}
+	data, err := Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	var result Book
+	if err := Unmarshal(data, &result); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if !reflect.DeepEqual(result, original) {
+		t.Errorf("got %+v, want %+v", result, original)
+	}
+	moved, ok := result.Addresses["moved"]
+	if !ok {
+		t.Fatal("nil map value was dropped")
+	}
+	if moved != nil {
+		t.Errorf("nil map value decoded as %+v", moved)
+	}
+
+	t.Run("top-level map", func(t *testing.T) {
+		original := map[int32]*Address{1: nil, 2: {Street: "x"}}
+		data, err := Marshal(original)
+		if err != nil {
+			t.Fatalf("Marshal error: %v", err)
+		}
+		var result map[int32]*Address
+		if err := Unmarshal(data, &result); err != nil {
+			t.Fatalf("Unmarshal error: %v", err)
+		}
+		if !reflect.DeepEqual(result, original) {
+			t.Errorf("got %v, want %v", result, original)
+		}
+	})
+
+	t.Run("empty and zero values", func(t *testing.T) {
+		// The encodings of an empty struct and of a zero int32 are the
+		// same byte as the nil marker
+		type Digests struct {
+			Map     map[string]*Address `cramberry:"1"`
+			List    []*Address          `cramberry:"2"`
+			Counts  []*int32            `cramberry:"3"`
+			Primary *Address            `cramberry:"4"`
+		}
+		original := Digests{
+			Map:     map[string]*Address{"empty": {}, "nil": nil},
+			List:    []*Address{{}, nil, {City: "x"}},
+			Counts:  []*int32{new(int32), nil},
+			Primary: &Address{},
+		}
+		data, err := Marshal(original)
+		if err != nil {
+			t.Fatalf("Marshal error: %v", err)
+		}
+		if size := Size(original); size != len(data) {
+			t.Errorf("Size = %d, want %d", size, len(data))
+		}
+		var result Digests
+		if err := Unmarshal(data, &result); err != nil {
+			t.Fatalf("Unmarshal error: %v", err)
+		}
+		if !reflect.DeepEqual(result, original) {
+			t.Errorf("got %+v, want %+v", result, original)
+		}
+	})
+
+	t.Run("pointers to pointers", func(t *testing.T) {
+		// Each pointer level has its own marker, so a nil inner pointer
+		// stays nil instead of decoding as a pointer to a zero value
+		type Chains struct {
+			List []**Address         `cramberry:"1"`
+			Map  map[int32]**Address `cramberry:"2"`
+		}
+		var nilInner *Address
+		set := &Address{City: "x"}
+		original := Chains{
+			List: []**Address{&nilInner, nil, &set},
+			Map:  map[int32]**Address{1: &nilInner, 2: nil, 3: &set},
+		}
+		data, err := Marshal(original)
+		if err != nil {
+			t.Fatalf("Marshal error: %v", err)
+		}
+		if size := Size(original); size != len(data) {
+			t.Errorf("Size = %d, want %d", size, len(data))
+		}
+		var result Chains
+		if err := Unmarshal(data, &result); err != nil {
+			t.Fatalf("Unmarshal error: %v", err)
+		}
+		if !reflect.DeepEqual(result, original) {
+			t.Errorf("got %+v, want %+v", result, original)
+		}
+		if p := result.List[0]; p == nil || *p != nil {
+			t.Errorf("List[0] = %v, want a pointer to a nil pointer", p)
+		}
+		if p := result.Map[1]; p == nil || *p != nil {
+			t.Errorf("Map[1] = %v, want a pointer to a nil pointer", p)
+		}
+	})
+}
+
 func TestMarshalUnmarshalStruct(t *testing.T) {
 	t.Run("simple", func(t *testing.T) {
 		original := SimpleStruct{Name: "Alice", Age: 30}
@@ -538,6 +660,120 @@ func TestUnmarshalErrors(t *testing.T) {
 	})
 }
 
+// checkedOrder has a Validate method like generated messages with bounded
+// fields.
+type checkedOrder struct {
+	Quantity int32  `cramberry:"1"`
+	Note     string `cramberry:"2"`
+}
+
+func (o *checkedOrder) Validate() error {
+	if o.Quantity < 1 || o.Quantity > 100 {
+		return NewValidationError("checkedOrder", "quantity", "out of range")
+	}
+	return nil
+}
+
+func TestUnmarshalPresence(t *testing.T) {
+	type limits struct {
+		MaxTabs int32 `cramberry:"1"`
+	}
+	type settings struct {
+		Theme         string  `cramberry:"1"`
+		FontSize      int32   `cramberry:"2"`
+		Notifications bool    `cramberry:"3"`
+		Limits        limits  `cramberry:"4"`
+		Zoom          float64 `cramberry:"5"`
+	}
+
+	// Encode Theme as its zero value and leave FontSize and Zoom off the wire
+	w := NewWriter()
+	w.WriteCompactTag(1, WireTypeV2Bytes)
+	w.WriteString("")
+	w.WriteCompactTag(3, WireTypeV2Varint)
+	w.WriteBool(true)
+	w.WriteCompactTag(4, WireTypeV2Bytes)
+	w.WriteCompactTag(1, WireTypeV2SVarint)
+	w.WriteInt32(10)
+	w.WriteEndMarker()
+	w.WriteCompactTag(9, WireTypeV2Varint) // unknown
+	w.WriteUvarint(1)
+	w.WriteEndMarker()
+
+	var got settings
+	present, err := UnmarshalPresence(w.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]bool{1: true, 3: true, 4: true, 9: true}
+	if !reflect.DeepEqual(present, want) {
+		t.Errorf("present = %v, want %v", present, want)
+	}
+	if !got.Notifications || got.Limits.MaxTabs != 10 {
+		t.Errorf("decoded %+v", got)
+	}
+
+	// Fields written by Marshal are reported; omitted zero values are not
+	data, err := Marshal(&settings{Theme: "dark", Zoom: 1.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	present, err = UnmarshalPresence(data, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]bool{1: true, 4: true, 5: true}; !reflect.DeepEqual(present, want) {
+		t.Errorf("present = %v, want %v", present, want)
+	}
+
+	if present, err := UnmarshalPresence(data[:len(data)-1], &got); err == nil || present != nil {
+		t.Errorf("truncated data: present = %v, err = %v", present, err)
+	}
+	if _, err := UnmarshalPresence(data, got); !errors.Is(err, ErrNotPointer) {
+		t.Errorf("non-pointer target: err = %v", err)
+	}
+}
+
+func TestValidateOnDecode(t *testing.T) {
+	invalid, err := Marshal(&checkedOrder{Note: "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := Marshal(&checkedOrder{Quantity: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Off by default: the message decodes and is left for the caller to check
+	var o checkedOrder
+	if err := Unmarshal(invalid, &o); err != nil || o.Note != "none" {
+		t.Fatalf("without ValidateOnDecode: %+v, %v", o, err)
+	}
+
+	opts := DefaultOptions
+	opts.ValidateOnDecode = true
+	var ve *ValidationError
+	if err := UnmarshalWithOptions(invalid, &checkedOrder{}, opts); !errors.As(err, &ve) || ve.Field != "quantity" {
+		t.Errorf("invalid message: got %v, want a ValidationError for quantity", err)
+	}
+	if err := UnmarshalWithOptions(valid, &checkedOrder{}, opts); err != nil {
+		t.Errorf("valid message: %v", err)
+	}
+
+	// The value behind a pointer target is validated too
+	var p *checkedOrder
+	if err := UnmarshalWithOptions(invalid, &p, opts); !errors.As(err, &ve) {
+		t.Errorf("pointer target: got %v, want a ValidationError", err)
+	}
+
+	// Types without Validate are unaffected
+	var plain SimpleStruct
+	data, _ := Marshal(SimpleStruct{Name: "x"})
+	if err := UnmarshalWithOptions(data, &plain, opts); err != nil {
+		t.Errorf("type without Validate: %v", err)
+	}
+}
+
 func TestOmitEmpty(t *testing.T) {
 	original := SimpleStruct{Name: "", Age: 0}
 
@@ -563,6 +799,89 @@ func TestOmitEmpty(t *testing.T) {
 	}
 }
 
+func TestOmitEmptyKeepsNestedStructs(t *testing.T) {
+	type Inner struct {
+		ID int64 `cramberry:"1"`
+	}
+	type Outer struct {
+		Inner  Inner          `cramberry:"1"`
+		Labels map[string]int `cramberry:"2"`
+	}
+
+	// A zero nested struct is still written, as generated code writes it;
+	// an empty map is not
+	data, err := Marshal(Outer{Labels: map[string]int{}})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	tag := byte(1<<4 | WireTypeV2Bytes<<1)
+	want := []byte{tag, EndMarker, EndMarker}
+	if !bytes.Equal(data, want) {
+		t.Errorf("got %x, want %x", data, want)
+	}
+	if size := Size(Outer{}); size != len(want) {
+		t.Errorf("Size = %d, want %d", size, len(want))
+	}
+}
+
+func TestOnField(t *testing.T) {
+	type Attachment struct {
+		Name string `cramberry:"1"`
+		Data []byte `cramberry:"2"`
+	}
+	type Document struct {
+		ID          int64             `cramberry:"1"`
+		Title       string            `cramberry:"2"`
+		Body        string            `cramberry:"3"`
+		Attachments []Attachment      `cramberry:"4"`
+		Metadata    map[string]string `cramberry:"5"`
+		Draft       bool              `cramberry:"6"`
+	}
+	doc := Document{
+		ID:          42,
+		Title:       "Field sizes",
+		Body:        strings.Repeat("lorem ipsum ", 20),
+		Attachments: []Attachment{{Name: "a.png", Data: make([]byte, 300)}, {Name: "b.txt"}},
+		Metadata:    map[string]string{"lang": "en"},
+	}
+
+	for _, omitEnd := range []bool{false, true} {
+		sizes := make(map[int]int)
+		opts := DefaultOptions
+		opts.OmitTopLevelEndMarker = omitEnd
+		opts.Hooks = &Hooks{OnField: func(fieldNum, encodedBytes int) {
+			if _, dup := sizes[fieldNum]; dup {
+				t.Errorf("field %d reported twice", fieldNum)
+			}
+			sizes[fieldNum] = encodedBytes
+		}}
+
+		data, err := MarshalWithOptions(&doc, opts)
+		if err != nil {
+			t.Fatalf("Marshal error: %v", err)
+		}
+
+		// Nested fields are not reported, nor is the omitted Draft
+		if len(sizes) != 5 {
+			t.Errorf("got sizes for fields %v, want fields 1-5", sizes)
+		}
+		sum := 0
+		for _, n := range sizes {
+			sum += n
+		}
+		want := len(data)
+		if !omitEnd {
+			want-- // end marker
+		}
+		if sum != want {
+			t.Errorf("field sizes %v add up to %d, want %d of %d bytes", sizes, sum, want, len(data))
+		}
+		if sizes[4] <= sizes[3] {
+			t.Errorf("attachments took %d bytes, expected more than the %d of the body", sizes[4], sizes[3])
+		}
+	}
+}
+
 func TestSize(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -572,6 +891,14 @@ func TestSize(t *testing.T) {
 		{"string", "hello"},
 		{"struct", SimpleStruct{Name: "test", Age: 25}},
 		{"slice", []int32{1, 2, 3, 4, 5}},
+		{"nested", NestedStruct{ID: 7, Simple: &SimpleStruct{Name: "x", Age: -1}}},
+		{"nested nil", NestedStruct{ID: 7}},
+		{"struct slice", []SimpleStruct{{Name: "a"}, {Age: 3}}},
+		{"map", map[string]int32{"a": 1, "bb": -2}},
+		{"extended tags", struct {
+			A string           `cramberry:"16"`
+			B map[int32]string `cramberry:"200"`
+		}{A: "x", B: map[int32]string{1: "one"}}},
 	}
 
 	for _, tc := range tests {
@@ -667,6 +994,225 @@ type PolymorphicContainer struct {
 	Greeter Greeter `cramberry:"1"`
 }
 
+func TestSizePolymorphic(t *testing.T) {
+	DefaultRegistry.Clear()
+	defer DefaultRegistry.Clear()
+
+	RegisterOrGet[EnglishGreeter]()
+
+	for _, v := range []PolymorphicContainer{
+		{Greeter: &EnglishGreeter{Name: "Alice"}},
+		{},
+	} {
+		data, err := Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal error: %v", err)
+		}
+		if size := Size(v); size != len(data) {
+			t.Errorf("Size(%+v) = %d, but Marshal produced %d bytes", v, size, len(data))
+		}
+	}
+}
+
+func TestTopLevelInterface(t *testing.T) {
+	DefaultRegistry.Clear()
+	defer DefaultRegistry.Clear()
+
+	RegisterOrGet[EnglishGreeter]()
+	RegisterOrGet[SpanishGreeter]()
+	RegisterOrGet[SimpleStruct]()
+
+	for _, want := range []Greeter{&EnglishGreeter{Name: "Alice"}, &SpanishGreeter{Name: "Carlos"}, nil} {
+		data, err := Marshal(&want)
+		if err != nil {
+			t.Fatalf("Marshal(%#v) error: %v", want, err)
+		}
+		if size := Size(&want); size != len(data) {
+			t.Errorf("Size(%#v) = %d, but Marshal produced %d bytes", want, size, len(data))
+		}
+
+		var got Greeter
+		if err := Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%#v) error: %v", want, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Unmarshal = %#v, want %#v", got, want)
+		}
+	}
+
+	var unregistered Greeter = &EnglishGreeter{}
+	DefaultRegistry.Clear()
+	if _, err := Marshal(&unregistered); !errors.Is(err, ErrUnregisteredType) {
+		t.Errorf("Marshal of unregistered type = %v, want ErrUnregisteredType", err)
+	}
+
+	// A registered type that doesn't implement the interface is rejected
+	id := RegisterOrGet[SimpleStruct]()
+	w := NewWriter()
+	w.WriteTypeID(id)
+	w.WriteEndMarker()
+	var got Greeter
+	if err := Unmarshal(w.Bytes(), &got); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Unmarshal of non-implementing type = %v, want ErrTypeMismatch", err)
+	}
+	if err := Unmarshal([]byte{0x7f, 0x00}, &got); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Unmarshal of unregistered type ID = %v, want ErrUnknownType", err)
+	}
+}
+
+func TestTypedMarshal(t *testing.T) {
+	DefaultRegistry.Clear()
+	defer DefaultRegistry.Clear()
+
+	RegisterOrGet[EnglishGreeter]()
+
+	t.Run("struct", func(t *testing.T) {
+		v := SimpleStruct{Name: "Alice", Age: 30}
+		data, err := MarshalT(v)
+		if err != nil {
+			t.Fatalf("MarshalT error: %v", err)
+		}
+		if want, _ := Marshal(v); !bytes.Equal(data, want) {
+			t.Errorf("MarshalT = %x, Marshal = %x", data, want)
+		}
+		got, err := UnmarshalT[SimpleStruct](data)
+		if err != nil {
+			t.Fatalf("UnmarshalT error: %v", err)
+		}
+		if got != v {
+			t.Errorf("UnmarshalT = %+v, want %+v", got, v)
+		}
+	})
+
+	t.Run("pointer", func(t *testing.T) {
+		v := &SimpleStruct{Name: "Bob", Age: 41}
+		data, err := MarshalT(v)
+		if err != nil {
+			t.Fatalf("MarshalT error: %v", err)
+		}
+		if want, _ := Marshal(v); !bytes.Equal(data, want) {
+			t.Errorf("MarshalT = %x, Marshal = %x", data, want)
+		}
+		got, err := UnmarshalT[*SimpleStruct](data)
+		if err != nil {
+			t.Fatalf("UnmarshalT error: %v", err)
+		}
+		if got == nil || *got != *v {
+			t.Errorf("UnmarshalT = %+v, want %+v", got, v)
+		}
+	})
+
+	t.Run("interface", func(t *testing.T) {
+		var v Greeter = &EnglishGreeter{Name: "Carol"}
+		data, err := MarshalT(v)
+		if err != nil {
+			t.Fatalf("MarshalT error: %v", err)
+		}
+		if want, _ := Marshal(&v); !bytes.Equal(data, want) {
+			t.Errorf("MarshalT = %x, Marshal(&v) = %x", data, want)
+		}
+		got, err := UnmarshalT[Greeter](data)
+		if err != nil {
+			t.Fatalf("UnmarshalT error: %v", err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("UnmarshalT = %#v, want %#v", got, v)
+		}
+
+		DefaultRegistry.Clear()
+		if _, err := MarshalT(v); !errors.Is(err, ErrUnregisteredType) {
+			t.Errorf("MarshalT of unregistered type = %v, want ErrUnregisteredType", err)
+		}
+		if got, err := UnmarshalT[Greeter](data); !errors.Is(err, ErrUnknownType) || got != nil {
+			t.Errorf("UnmarshalT of unregistered type = %v, %v, want nil and ErrUnknownType", got, err)
+		}
+	})
+
+	t.Run("append", func(t *testing.T) {
+		v := SimpleStruct{Name: "Dave", Age: 7}
+		prefix := []byte{0xca, 0xfe}
+		data, err := AppendT(prefix, v)
+		if err != nil {
+			t.Fatalf("AppendT error: %v", err)
+		}
+		want, _ := MarshalAppend([]byte{0xca, 0xfe}, v)
+		if !bytes.Equal(data, want) {
+			t.Errorf("AppendT = %x, MarshalAppend = %x", data, want)
+		}
+	})
+}
+
+func TestWriteAnyReadAny(t *testing.T) {
+	DefaultRegistry.Clear()
+	defer DefaultRegistry.Clear()
+
+	RegisterOrGet[EnglishGreeter]()
+	RegisterOrGet[SimpleStruct]()
+
+	values := []any{&EnglishGreeter{Name: "Alice"}, &SimpleStruct{Name: "x", Age: 3}, nil}
+
+	w := NewWriter()
+	for _, v := range values {
+		w.WriteAny(v)
+	}
+	if w.Err() != nil {
+		t.Fatalf("WriteAny error: %v", w.Err())
+	}
+
+	r := NewReader(w.Bytes())
+	for _, want := range values {
+		got := r.ReadAny()
+		if r.Err() != nil {
+			t.Fatalf("ReadAny error: %v", r.Err())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ReadAny() = %#v, want %#v", got, want)
+		}
+	}
+
+	w = NewWriter()
+	w.WriteAny(&SpanishGreeter{Name: "Carlos"})
+	if !errors.Is(w.Err(), ErrUnregisteredType) {
+		t.Errorf("expected ErrUnregisteredType, got %v", w.Err())
+	}
+
+	r = NewReader([]byte{0x01, 0x7f})
+	if r.ReadAny() != nil || !errors.Is(r.Err(), ErrUnknownType) {
+		t.Errorf("expected ErrUnknownType, got %v", r.Err())
+	}
+
+	// The length prefix lets a reader that doesn't know an any field skip it
+	type withAny struct {
+		Payload any    `cramberry:"1"`
+		Name    string `cramberry:"2"`
+	}
+	type withoutAny struct {
+		Name string `cramberry:"2"`
+	}
+	original := withAny{Payload: &SimpleStruct{Name: "x", Age: 3}, Name: "after"}
+	data, err := Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if size := Size(original); size != len(data) {
+		t.Errorf("Size = %d, want %d", size, len(data))
+	}
+	var decoded withAny
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("got %+v, want %+v", decoded, original)
+	}
+	var skipped withoutAny
+	if err := Unmarshal(data, &skipped); err != nil {
+		t.Fatalf("Unmarshal skipping the any field: %v", err)
+	}
+	if skipped.Name != "after" {
+		t.Errorf("Name = %q after skipping the any field, want %q", skipped.Name, "after")
+	}
+}
+
 func TestPolymorphicEncoding(t *testing.T) {
 	// Clear and set up registry
 	DefaultRegistry.Clear()
@@ -915,4 +1461,124 @@ func TestFieldNumberUniqueness(t *testing.T) {
 		}()
 		_, _ = Marshal(WithSkipped{})
 	})
+
+	t.Run("lenient validation returns an error", func(t *testing.T) {
+		SetStrictFieldValidation(false)
+		defer SetStrictFieldValidation(true)
+
+		_, err := Marshal(DuplicateFieldNumber{})
+		if !errors.Is(err, ErrDuplicateFieldNumber) {
+			t.Fatalf("Marshal error = %v, want ErrDuplicateFieldNumber", err)
+		}
+		if msg := err.Error(); !strings.Contains(msg, "duplicate field number 1") || !strings.Contains(msg, "Field2") {
+			t.Errorf("error should name the field number and fields, got: %s", msg)
+		}
+
+		// Nested types and decoding report the error too
+		if _, err := Marshal([]DuplicateWithImplicit{{}}); !errors.Is(err, ErrDuplicateFieldNumber) {
+			t.Errorf("Marshal of nested type error = %v, want ErrDuplicateFieldNumber", err)
+		}
+		var got DuplicateFieldNumber
+		if err := Unmarshal([]byte{0x00}, &got); !errors.Is(err, ErrDuplicateFieldNumber) {
+			t.Errorf("Unmarshal error = %v, want ErrDuplicateFieldNumber", err)
+		}
+
+		// Valid types are unaffected
+		if _, err := Marshal(ValidFieldNumbers{A: "a"}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("strict validation is restored", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic after re-enabling strict validation")
+			}
+		}()
+		_, _ = Marshal(DuplicateWithImplicit{})
+	})
+}
+
+func TestPackBools(t *testing.T) {
+	if mask := PackBools(true, false, true, true); mask != 0b1101 {
+		t.Errorf("PackBools = %b, want 1101", mask)
+	}
+	if mask := PackBools(); mask != 0 {
+		t.Errorf("PackBools() = %b, want 0", mask)
+	}
+
+	bits := make([]bool, 64)
+	bits[0], bits[63] = true, true
+	mask := PackBools(bits...)
+	if mask != 1|1<<63 {
+		t.Errorf("PackBools of 64 bools = %x", mask)
+	}
+
+	got := make([]bool, 64)
+	ptrs := make([]*bool, len(got))
+	for i := range got {
+		got[i] = !bits[i] // every bool is overwritten
+		ptrs[i] = &got[i]
+	}
+	UnpackBools(mask, ptrs...)
+	if !slices.Equal(got, bits) {
+		t.Errorf("UnpackBools = %v, want %v", got, bits)
+	}
+}
+
+type statsLeaf struct {
+	A int32 `cramberry:"1"`
+}
+
+type statsTree struct {
+	Name   string      `cramberry:"1"`
+	Leaf   statsLeaf   `cramberry:"2"`
+	Leaves []statsLeaf `cramberry:"3"`
+	Extra  bool        `cramberry:"100"`
+}
+
+func TestStats(t *testing.T) {
+	v := statsTree{Name: "x", Leaf: statsLeaf{A: 1}, Leaves: []statsLeaf{{A: 2}, {}}, Extra: true}
+
+	opts := DefaultOptions
+	opts.CollectStats = true
+	w := NewWriterWithOptions(opts)
+	if err := encodeValue(w, reflect.ValueOf(v)); err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	// Name, Leaf, Leaf.A, Leaves, Leaves[0].A and the extended tag of
+	// Extra; the zero Leaves[1].A is omitted. The deepest nesting is an
+	// element of Leaves inside the slice inside the outer struct.
+	want := Stats{Bytes: w.Len(), Fields: 6, MaxCheckedDepth: 3}
+	if got := w.Stats(); got != want {
+		t.Errorf("Writer.Stats() = %+v, want %+v", got, want)
+	}
+
+	r := NewReaderWithOptions(w.Bytes(), opts)
+	var got statsTree
+	if err := decodeValue(r, reflect.ValueOf(&got).Elem()); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if stats := r.Stats(); stats != want {
+		t.Errorf("Reader.Stats() = %+v, want %+v", stats, want)
+	}
+
+	r.Reset(w.Bytes())
+	w.Reset()
+	if w.Stats() != (Stats{}) || r.Stats() != (Stats{}) {
+		t.Errorf("stats after Reset: writer %+v, reader %+v", w.Stats(), r.Stats())
+	}
+
+	// Without CollectStats nothing is counted
+	w = NewWriter()
+	if err := encodeValue(w, reflect.ValueOf(v)); err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	r = NewReader(w.Bytes())
+	if err := decodeValue(r, reflect.ValueOf(&got).Elem()); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if w.Stats() != (Stats{}) || w.stats != (Stats{}) || r.Stats() != (Stats{}) || r.stats != (Stats{}) {
+		t.Errorf("stats counted while disabled: writer %+v, reader %+v", w.stats, r.stats)
+	}
 }
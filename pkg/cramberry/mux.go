@@ -0,0 +1,237 @@
+package cramberry
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// StreamMux writes messages of several types to a single stream. Each frame
+// is the type ID of the message followed by its length-delimited encoding,
+// so a StreamDemux can route frames by type without decoding them.
+//
+// StreamMux is not safe for concurrent use.
+type StreamMux struct {
+	sw *StreamWriter
+}
+
+// NewStreamMux creates a StreamMux that writes frames to w.
+func NewStreamMux(w io.Writer) *StreamMux {
+	return &StreamMux{sw: NewStreamWriter(w)}
+}
+
+// NewStreamMuxWithOptions creates a StreamMux with the given options.
+func NewStreamMuxWithOptions(w io.Writer, opts Options) *StreamMux {
+	return &StreamMux{sw: NewStreamWriterWithOptions(w, opts)}
+}
+
+// Write marshals v and writes it as a frame tagged with the type ID
+// registered for its type in DefaultRegistry.
+func (m *StreamMux) Write(v any) error {
+	if err := m.sw.Err(); err != nil {
+		return err
+	}
+	if v == nil {
+		err := NewEncodeError("cannot write a nil message", ErrNilPointer)
+		m.sw.setError(err)
+		return err
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		err := NewEncodeError("cannot write a nil "+rv.Type().String(), ErrNilPointer)
+		m.sw.setError(err)
+		return err
+	}
+	id := DefaultRegistry.TypeIDFor(v)
+	if id == TypeIDNil {
+		err := NewEncodeError("unregistered type: "+reflect.TypeOf(v).String(), ErrUnregisteredType)
+		m.sw.setError(err)
+		return err
+	}
+	data, err := MarshalWithOptions(v, m.sw.opts)
+	if err != nil {
+		m.sw.setError(err)
+		return err
+	}
+	return m.WriteFrame(id, data)
+}
+
+// WriteFrame writes data, an already encoded message, as a frame tagged
+// with id. It lets frames be forwarded without decoding them.
+func (m *StreamMux) WriteFrame(id TypeID, data []byte) error {
+	if id == TypeIDNil {
+		m.sw.setError(NewEncodeError("frame type ID must not be nil", ErrUnregisteredType))
+		return m.sw.Err()
+	}
+	m.sw.WriteTypeID(id)
+	m.sw.WriteMessage(data)
+	return m.sw.Err()
+}
+
+// Flush writes any buffered frames to the underlying writer.
+func (m *StreamMux) Flush() error {
+	return m.sw.Flush()
+}
+
+// Err returns the first error that occurred while writing.
+func (m *StreamMux) Err() error {
+	return m.sw.Err()
+}
+
+// StreamDemux reads frames written by a StreamMux.
+//
+// StreamDemux is not safe for concurrent use.
+type StreamDemux struct {
+	sr *StreamReader
+}
+
+// NewStreamDemux creates a StreamDemux that reads frames from r.
+func NewStreamDemux(r io.Reader) *StreamDemux {
+	return &StreamDemux{sr: NewStreamReader(r)}
+}
+
+// NewStreamDemuxWithOptions creates a StreamDemux with the given options.
+func NewStreamDemuxWithOptions(r io.Reader, opts Options) *StreamDemux {
+	return &StreamDemux{sr: NewStreamReaderWithOptions(r, opts)}
+}
+
+// Next reads the next frame and returns its type ID and encoded body. It
+// returns io.EOF when the stream ends cleanly between frames.
+func (d *StreamDemux) Next() (TypeID, []byte, error) {
+	if err := d.sr.Err(); err != nil {
+		return TypeIDNil, nil, err
+	}
+	if d.sr.Buffered() == 0 {
+		if _, err := d.sr.Peek(1); err == io.EOF {
+			return TypeIDNil, nil, io.EOF
+		}
+	}
+
+	id := d.sr.ReadTypeID()
+	if d.sr.Err() == nil && id == TypeIDNil {
+		d.sr.setError(NewDecodeError("frame has nil type ID", ErrUnknownType))
+	}
+	data := d.sr.ReadMessage()
+	if err := d.sr.Err(); err != nil {
+		return TypeIDNil, nil, err
+	}
+	return id, data, nil
+}
+
+// NextValue reads the next frame and decodes it into a new value of the
+// type registered for its type ID in DefaultRegistry. It returns a pointer
+// to the value, or io.EOF when the stream ends cleanly between frames.
+func (d *StreamDemux) NextValue() (any, error) {
+	id, data, err := d.Next()
+	if err != nil {
+		return nil, err
+	}
+	v, ok := DefaultRegistry.NewValue(id)
+	if !ok {
+		return nil, NewDecodeError("unknown type ID: "+id.String(), ErrUnknownType)
+	}
+	if err := UnmarshalWithOptions(data, v, d.sr.opts); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Handler handles one request decoded by a Dispatcher. req is a pointer to
+// a new value of the request's registered type. The returned response is
+// written back as a frame, or nothing is written if it is nil; an error
+// stops Serve.
+type Handler func(req any) (any, error)
+
+// Dispatcher serves request streams by routing each frame to the handler
+// registered for its type ID. Requests and responses are StreamMux frames
+// whose types are registered in DefaultRegistry, so any io.Reader and
+// io.Writer pair, such as a net.Conn, can carry them.
+//
+// Register handlers before calling Serve. Serve may then be called
+// concurrently, one call per stream.
+type Dispatcher struct {
+	handlers map[TypeID]Handler
+	opts     Options
+}
+
+// NewDispatcher creates a Dispatcher with no handlers.
+func NewDispatcher() *Dispatcher {
+	return NewDispatcherWithOptions(DefaultOptions)
+}
+
+// NewDispatcherWithOptions creates a Dispatcher that decodes requests and
+// encodes responses with the given options.
+func NewDispatcherWithOptions(opts Options) *Dispatcher {
+	return &Dispatcher{handlers: make(map[TypeID]Handler), opts: opts}
+}
+
+// Handle registers h for requests of the type registered under id. It
+// fails if h is nil, or if the type is not registered or already has a
+// handler.
+func (d *Dispatcher) Handle(id TypeID, h Handler) error {
+	if h == nil {
+		return fmt.Errorf("cramberry: nil handler for type ID %s", id)
+	}
+	if _, ok := DefaultRegistry.Lookup(id); !ok {
+		return fmt.Errorf("%w: no type registered with ID %s", ErrUnregisteredType, id)
+	}
+	if _, ok := d.handlers[id]; ok {
+		return fmt.Errorf("cramberry: type ID %s already has a handler", id)
+	}
+	d.handlers[id] = h
+	return nil
+}
+
+// HandleName registers h for requests of the type registered under name,
+// the fully qualified Go type name such as "example.com/api.Ping".
+func (d *Dispatcher) HandleName(name string, h Handler) error {
+	reg, ok := DefaultRegistry.LookupName(name)
+	if !ok {
+		return fmt.Errorf("%w: no type registered with name %q", ErrUnregisteredType, name)
+	}
+	return d.Handle(reg.ID, h)
+}
+
+// Serve reads request frames from r until it ends, calls the handler for
+// each request and writes the responses to w, flushing after each one so
+// a client waiting for a reply receives it. It returns nil when r ends
+// cleanly between frames, and otherwise the first read, decode, handler or
+// write error. A frame whose type has no handler fails with ErrUnknownType.
+func (d *Dispatcher) Serve(r io.Reader, w io.Writer) error {
+	demux := NewStreamDemuxWithOptions(r, d.opts)
+	mux := NewStreamMuxWithOptions(w, d.opts)
+	for {
+		id, data, err := demux.Next()
+		if err == io.EOF {
+			return mux.Flush()
+		}
+		if err != nil {
+			return err
+		}
+
+		h, ok := d.handlers[id]
+		if !ok {
+			return NewDecodeError("no handler for type ID "+id.String(), ErrUnknownType)
+		}
+		req, ok := DefaultRegistry.NewValue(id)
+		if !ok {
+			return NewDecodeError("unknown type ID: "+id.String(), ErrUnknownType)
+		}
+		if err := UnmarshalWithOptions(data, req, d.opts); err != nil {
+			return err
+		}
+
+		resp, err := h(req)
+		if err != nil {
+			return err
+		}
+		if resp == nil {
+			continue
+		}
+		if err := mux.Write(resp); err != nil {
+			return err
+		}
+		if err := mux.Flush(); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package cramberry
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type muxPing struct {
+	Seq int64 `cramberry:"1"`
+}
+
+type muxChat struct {
+	From string `cramberry:"1"`
+	Text string `cramberry:"2"`
+}
+
+func TestStreamMuxDemux(t *testing.T) {
+	DefaultRegistry.Clear()
+	defer DefaultRegistry.Clear()
+
+	pingID := RegisterOrGetWithID[muxPing](200)
+	chatID := RegisterOrGetWithID[muxChat](201)
+
+	values := []any{
+		&muxPing{Seq: 1},
+		&muxChat{From: "alice", Text: "hi"},
+		&muxChat{From: "bob"},
+		&muxPing{Seq: 2},
+		&muxPing{},
+	}
+
+	var buf bytes.Buffer
+	mux := NewStreamMux(&buf)
+	for _, v := range values {
+		if err := mux.Write(v); err != nil {
+			t.Fatalf("Write(%+v) error: %v", v, err)
+		}
+	}
+	if err := mux.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	encoded := buf.Bytes()
+
+	// Frames come back in order with their type IDs, and route by type
+	demux := NewStreamDemux(bytes.NewReader(encoded))
+	wantIDs := []TypeID{pingID, chatID, chatID, pingID, pingID}
+	for i, want := range values {
+		id, data, err := demux.Next()
+		if err != nil {
+			t.Fatalf("Next() #%d error: %v", i, err)
+		}
+		if id != wantIDs[i] {
+			t.Errorf("frame %d type ID = %d, want %d", i, id, wantIDs[i])
+		}
+		got := reflect.New(reflect.TypeOf(want).Elem()).Interface()
+		if err := Unmarshal(data, got); err != nil {
+			t.Fatalf("Unmarshal frame %d error: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("frame %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if _, _, err := demux.Next(); err != io.EOF {
+		t.Errorf("Next() at end = %v, want io.EOF", err)
+	}
+
+	// NextValue decodes through the registry
+	demux = NewStreamDemux(bytes.NewReader(encoded))
+	for i, want := range values {
+		got, err := demux.NextValue()
+		if err != nil {
+			t.Fatalf("NextValue() #%d error: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("value %d = %#v, want %#v", i, got, want)
+		}
+	}
+	if _, err := demux.NextValue(); err != io.EOF {
+		t.Errorf("NextValue() at end = %v, want io.EOF", err)
+	}
+}
+
+func TestStreamMuxForwardFrames(t *testing.T) {
+	var src bytes.Buffer
+	mux := NewStreamMux(&src)
+	if err := mux.WriteFrame(300, []byte{0xde, 0xad}); err != nil {
+		t.Fatalf("WriteFrame error: %v", err)
+	}
+	if err := mux.WriteFrame(301, nil); err != nil {
+		t.Fatalf("WriteFrame error: %v", err)
+	}
+	if err := mux.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+
+	// Frames are copied to another stream without decoding their bodies
+	var dst bytes.Buffer
+	demux := NewStreamDemux(&src)
+	fwd := NewStreamMux(&dst)
+	for {
+		id, data, err := demux.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if err := fwd.WriteFrame(id, data); err != nil {
+			t.Fatalf("WriteFrame error: %v", err)
+		}
+	}
+	if err := fwd.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+
+	// 300 and 301 are two-byte varints
+	want := []byte{0xac, 0x02, 0x02, 0xde, 0xad, 0xad, 0x02, 0x00}
+	if !bytes.Equal(dst.Bytes(), want) {
+		t.Errorf("forwarded stream = %x, want %x", dst.Bytes(), want)
+	}
+}
+
+func TestStreamMuxErrors(t *testing.T) {
+	DefaultRegistry.Clear()
+	defer DefaultRegistry.Clear()
+
+	mux := NewStreamMux(io.Discard)
+	if err := mux.Write(&muxPing{}); !errors.Is(err, ErrUnregisteredType) {
+		t.Errorf("Write of unregistered type = %v, want ErrUnregisteredType", err)
+	}
+	if err := NewStreamMux(io.Discard).Write(nil); !errors.Is(err, ErrNilPointer) {
+		t.Errorf("Write(nil) = %v, want ErrNilPointer", err)
+	}
+	if err := NewStreamMux(io.Discard).Write((*muxPing)(nil)); !errors.Is(err, ErrNilPointer) {
+		t.Errorf("Write of nil pointer = %v, want ErrNilPointer", err)
+	}
+	if err := NewStreamMux(io.Discard).WriteFrame(TypeIDNil, nil); !errors.Is(err, ErrUnregisteredType) {
+		t.Errorf("WriteFrame with nil type ID = %v, want ErrUnregisteredType", err)
+	}
+
+	// A frame whose type isn't registered can still be read raw
+	frame := []byte{0xc8, 0x01, 0x02, 0x08, 0x02}
+	if _, err := NewStreamDemux(bytes.NewReader(frame)).NextValue(); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("NextValue of unregistered type = %v, want ErrUnknownType", err)
+	}
+	id, data, err := NewStreamDemux(bytes.NewReader(frame)).Next()
+	if err != nil || id != 200 || !bytes.Equal(data, []byte{0x08, 0x02}) {
+		t.Errorf("Next() = %d, %x, %v", id, data, err)
+	}
+
+	// A stream cut off inside a frame is an error, not a clean end
+	demux := NewStreamDemux(bytes.NewReader(frame[:3]))
+	if _, _, err := demux.Next(); err == nil || err == io.EOF {
+		t.Errorf("Next() on truncated frame = %v, want an error", err)
+	}
+
+	if _, _, err := NewStreamDemux(bytes.NewReader([]byte{0x00, 0x00})).Next(); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Next() on nil type ID = %v, want ErrUnknownType", err)
+	}
+}
+
+type muxPong struct {
+	Seq int64 `cramberry:"1"`
+}
+
+func TestDispatcherServe(t *testing.T) {
+	DefaultRegistry.Clear()
+	defer DefaultRegistry.Clear()
+
+	pingID := RegisterOrGetWithID[muxPing](200)
+	RegisterOrGetWithID[muxChat](201)
+	RegisterOrGetWithID[muxPong](202)
+
+	d := NewDispatcher()
+	if err := d.Handle(pingID, func(req any) (any, error) {
+		return &muxPong{Seq: req.(*muxPing).Seq}, nil
+	}); err != nil {
+		t.Fatalf("Handle error: %v", err)
+	}
+	if err := d.HandleName(typeName(reflect.TypeFor[muxChat]()), func(req any) (any, error) {
+		chat := req.(*muxChat)
+		if chat.Text == "" {
+			return nil, nil // nothing to reply
+		}
+		return &muxChat{From: "server", Text: chat.From + ": " + chat.Text}, nil
+	}); err != nil {
+		t.Fatalf("HandleName error: %v", err)
+	}
+
+	var requests bytes.Buffer
+	mux := NewStreamMux(&requests)
+	for _, v := range []any{
+		&muxPing{Seq: 1},
+		&muxChat{From: "alice", Text: "hi"},
+		&muxChat{From: "bob"},
+		&muxPing{Seq: 2},
+	} {
+		if err := mux.Write(v); err != nil {
+			t.Fatalf("Write(%+v) error: %v", v, err)
+		}
+	}
+	if err := mux.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+
+	var responses bytes.Buffer
+	if err := d.Serve(&requests, &responses); err != nil {
+		t.Fatalf("Serve error: %v", err)
+	}
+
+	demux := NewStreamDemux(&responses)
+	for i, want := range []any{
+		&muxPong{Seq: 1},
+		&muxChat{From: "server", Text: "alice: hi"},
+		&muxPong{Seq: 2},
+	} {
+		got, err := demux.NextValue()
+		if err != nil {
+			t.Fatalf("response %d error: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("response %d = %#v, want %#v", i, got, want)
+		}
+	}
+	if _, err := demux.NextValue(); err != io.EOF {
+		t.Errorf("after last response = %v, want io.EOF", err)
+	}
+}
+
+func TestDispatcherErrors(t *testing.T) {
+	DefaultRegistry.Clear()
+	defer DefaultRegistry.Clear()
+
+	pingID := RegisterOrGetWithID[muxPing](200)
+	chatID := RegisterOrGetWithID[muxChat](201)
+
+	d := NewDispatcher()
+	nop := func(any) (any, error) { return nil, nil }
+	if err := d.Handle(300, nop); !errors.Is(err, ErrUnregisteredType) {
+		t.Errorf("Handle of unregistered ID = %v, want ErrUnregisteredType", err)
+	}
+	if err := d.HandleName("nope.Missing", nop); !errors.Is(err, ErrUnregisteredType) {
+		t.Errorf("HandleName of unregistered name = %v, want ErrUnregisteredType", err)
+	}
+	errBoom := errors.New("boom")
+	if err := d.Handle(pingID, func(any) (any, error) { return nil, errBoom }); err != nil {
+		t.Fatalf("Handle error: %v", err)
+	}
+	if err := d.Handle(pingID, nop); err == nil {
+		t.Error("expected an error registering a second handler for the same type")
+	}
+	if err := d.Handle(chatID, nil); err == nil {
+		t.Error("expected an error registering a nil handler")
+	}
+
+	serve := func(v any) error {
+		var requests bytes.Buffer
+		mux := NewStreamMux(&requests)
+		if err := mux.Write(v); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+		if err := mux.Flush(); err != nil {
+			t.Fatalf("Flush error: %v", err)
+		}
+		return d.Serve(&requests, io.Discard)
+	}
+	if err := serve(&muxPing{Seq: 1}); err != errBoom {
+		t.Errorf("Serve with failing handler = %v, want %v", err, errBoom)
+	}
+	if err := serve(&muxChat{From: "alice"}); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Serve of unhandled type %d = %v, want ErrUnknownType", chatID, err)
+	}
+
+	// A truncated request is an error, an empty stream is not
+	if err := d.Serve(bytes.NewReader([]byte{0xc8, 0x01, 0x02, 0x08}), io.Discard); err == nil {
+		t.Error("expected an error serving a truncated request")
+	}
+	if err := d.Serve(bytes.NewReader(nil), io.Discard); err != nil {
+		t.Errorf("Serve of empty stream = %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package cramberry
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// Optional holds a value together with whether it was set, which tracks
+// presence without the heap allocation of a pointer. Generated code uses it
+// for optional scalar fields when optional wrappers are enabled.
+//
+// As a struct field, an unset Optional is left off the wire and a set one is
+// encoded as its value alone, even when that value is zero. This matches the
+// encoding generated for optional scalar fields declared as pointers.
+type Optional[T any] struct {
+	Value T
+	Set   bool
+}
+
+// Some returns an Optional holding v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{Value: v, Set: true}
+}
+
+// Get returns the value and whether it is set.
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.Set
+}
+
+// IsZero reports whether the value is unset, so that encoding/json leaves
+// an unset Optional out of fields tagged omitzero.
+func (o Optional[T]) IsZero() bool {
+	return !o.Set
+}
+
+// MarshalJSON encodes the value, or null if it is not set.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Set {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+// UnmarshalJSON decodes a value and marks it set; null unsets it.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*o = Optional[T]{}
+		return nil
+	}
+	if err := json.Unmarshal(data, &o.Value); err != nil {
+		return err
+	}
+	o.Set = true
+	return nil
+}
+
+func (*Optional[T]) optionalWrapper() {}
+
+// optionalWrapper is implemented by every Optional instantiation.
+type optionalWrapper interface{ optionalWrapper() }
+
+var optionalWrapperType = reflect.TypeOf((*optionalWrapper)(nil)).Elem()
+
+// Field indexes of Optional, used by the reflective encoder.
+const (
+	optionalValueIndex = 0
+	optionalSetIndex   = 1
+)
+
+// isOptionalType reports whether t is an instantiation of Optional.
+func isOptionalType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && reflect.PointerTo(t).Implements(optionalWrapperType)
+}
@@ -0,0 +1,82 @@
+package cramberry
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+type optionalScalars struct {
+	Count Optional[int32]  `cramberry:"1"`
+	Name  Optional[string] `cramberry:"2"`
+	Seq   int64            `cramberry:"3"`
+}
+
+func TestOptionalRoundtrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  optionalScalars
+		want []byte
+	}{
+		{"unset", optionalScalars{Seq: 1}, []byte{0x38, 0x02, 0x00}},
+		{"set", optionalScalars{Count: Some[int32](5), Name: Some("x")}, []byte{0x18, 0x0a, 0x24, 0x01, 'x', 0x00}},
+		{"set to zero", optionalScalars{Count: Some[int32](0)}, []byte{0x18, 0x00, 0x00}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Marshal(&tt.opt)
+			if err != nil {
+				t.Fatalf("Marshal error: %v", err)
+			}
+			if !bytes.Equal(data, tt.want) {
+				t.Errorf("Marshal = %x, want %x", data, tt.want)
+			}
+			if size := Size(&tt.opt); size != len(data) {
+				t.Errorf("Size = %d, encoded %d bytes", size, len(data))
+			}
+
+			var got optionalScalars
+			if err := Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if got != tt.opt {
+				t.Errorf("roundtrip = %+v, want %+v", got, tt.opt)
+			}
+		})
+	}
+}
+
+func TestOptionalJSON(t *testing.T) {
+	data, err := json.Marshal(optionalScalars{Count: Some[int32](0)})
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	if want := `{"Count":0,"Name":null,"Seq":0}`; string(data) != want {
+		t.Errorf("json.Marshal = %s, want %s", data, want)
+	}
+
+	var got optionalScalars
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+	if v, ok := got.Count.Get(); !ok || v != 0 {
+		t.Errorf("Count = %d, %v; want 0, true", v, ok)
+	}
+	if got.Name.Set {
+		t.Errorf("Name set from null: %+v", got.Name)
+	}
+
+	// omitzero leaves out unset values but keeps values set to zero
+	type tagged struct {
+		Count Optional[int32]  `json:"count,omitzero"`
+		Name  Optional[string] `json:"name,omitzero"`
+	}
+	data, err = json.Marshal(tagged{Count: Some[int32](0)})
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	if want := `{"count":0}`; string(data) != want {
+		t.Errorf("json.Marshal with omitzero = %s, want %s", data, want)
+	}
+}
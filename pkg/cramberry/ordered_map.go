@@ -0,0 +1,231 @@
+package cramberry
+
+import (
+	"bytes"
+	"encoding/json"
+	"iter"
+	"reflect"
+	"slices"
+)
+
+// OrderedMap is a map that remembers the order in which keys were first
+// inserted. Generated code uses it for map fields declared with the
+// ordered option, whose entries are encoded in insertion order instead of
+// sorted key order, so that documents such as configuration files keep
+// their layout across a round trip.
+//
+// Unlike a Go map, iteration order is deterministic and significant: two
+// OrderedMaps holding the same entries in a different order encode to
+// different bytes. The zero value is an empty map ready to use.
+//
+// The reflective Marshal and Unmarshal encode an OrderedMap like a Go map,
+// but in insertion order, as generated code does. In JSON it is an object
+// whose members are in insertion order.
+type OrderedMap[K comparable, V any] struct {
+	keys []K
+	m    map[K]V
+}
+
+// Len returns the number of entries.
+func (om *OrderedMap[K, V]) Len() int {
+	return len(om.keys)
+}
+
+// Get returns the value stored under key and whether it was present.
+func (om *OrderedMap[K, V]) Get(key K) (V, bool) {
+	v, ok := om.m[key]
+	return v, ok
+}
+
+// Set stores value under key. A new key is appended after the existing
+// ones; an existing key keeps its position and has its value replaced.
+func (om *OrderedMap[K, V]) Set(key K, value V) {
+	if om.m == nil {
+		om.m = make(map[K]V)
+	}
+	if _, ok := om.m[key]; !ok {
+		om.keys = append(om.keys, key)
+	}
+	om.m[key] = value
+}
+
+// Delete removes key, if present, preserving the order of the others.
+func (om *OrderedMap[K, V]) Delete(key K) {
+	if _, ok := om.m[key]; !ok {
+		return
+	}
+	delete(om.m, key)
+	i := slices.Index(om.keys, key)
+	om.keys = slices.Delete(om.keys, i, i+1)
+}
+
+// Clear removes all entries, keeping the allocated storage for reuse.
+func (om *OrderedMap[K, V]) Clear() {
+	clear(om.keys)
+	om.keys = om.keys[:0]
+	clear(om.m)
+}
+
+// Keys returns the keys in insertion order. The slice is shared with the
+// map and must not be modified.
+func (om *OrderedMap[K, V]) Keys() []K {
+	return om.keys
+}
+
+// All returns an iterator over the entries in insertion order.
+func (om *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, k := range om.keys {
+			if !yield(k, om.m[k]) {
+				return
+			}
+		}
+	}
+}
+
+// MarshalJSON encodes the entries as a JSON object in insertion order. Keys
+// are converted as encoding/json converts the keys of Go maps.
+func (om OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range om.keys {
+		// Encoding a single-entry map reuses encoding/json's key rules
+		entry, err := json.Marshal(map[K]V{k: om.m[k]})
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(entry[1 : len(entry)-1])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON replaces the entries with the members of a JSON object, in
+// the order they appear; null leaves the map empty.
+func (om *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	om.Clear()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('{') {
+		// Let encoding/json report the mismatch as it would for a map
+		return json.Unmarshal(data, new(map[K]V))
+	}
+	for dec.More() {
+		if tok, err = dec.Token(); err != nil {
+			return err
+		}
+		key, err := json.Marshal(tok)
+		if err != nil {
+			return err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		// Decoding a single-member object reuses encoding/json's key rules
+		var entry map[K]V
+		if err := json.Unmarshal(slices.Concat([]byte("{"), key, []byte(":"), value, []byte("}")), &entry); err != nil {
+			return err
+		}
+		for k, v := range entry {
+			om.Set(k, v)
+		}
+	}
+	return nil
+}
+
+// encodeEntries writes the entries like a Go map, in insertion order.
+func (om OrderedMap[K, V]) encodeEntries(w *Writer) error {
+	// Check depth limit, as encodeMap does
+	if !w.enterNested() {
+		return w.Err()
+	}
+	defer w.exitNested()
+
+	keyType := reflect.TypeFor[K]()
+	if !isValidMapKeyType(keyType) {
+		return NewEncodeError("unsupported map key type "+keyType.String()+" in "+reflect.TypeOf(om).String()+"; map keys must be string, integer, float, or bool", nil)
+	}
+
+	w.WriteMapHeader(len(om.keys))
+	for _, k := range om.keys {
+		v := om.m[k]
+		if err := encodeValue(w, reflect.ValueOf(&k).Elem()); err != nil {
+			return err
+		}
+		if err := encodeElement(w, reflect.ValueOf(&v).Elem()); err != nil {
+			return err
+		}
+	}
+	return w.Err()
+}
+
+// entriesSize returns the number of bytes encodeEntries writes.
+func (om OrderedMap[K, V]) entriesSize(opts Options) int {
+	size := SizeOfUvarint(uint64(len(om.keys)))
+	for _, k := range om.keys {
+		v := om.m[k]
+		size += sizeValue(reflect.ValueOf(&k).Elem(), opts)
+		size += sizeElement(reflect.ValueOf(&v).Elem(), opts)
+	}
+	return size
+}
+
+// decodeEntries replaces the entries with those of an encoded map, in the
+// order they were written.
+func (om *OrderedMap[K, V]) decodeEntries(r *Reader) error {
+	if !r.enterNested() {
+		return r.Err()
+	}
+	defer r.exitNested()
+
+	n := r.ReadMapHeader()
+	if r.Err() != nil {
+		return r.Err()
+	}
+	om.Clear()
+	for i := 0; i < n; i++ {
+		var k K
+		var v V
+		if err := decodeValue(r, reflect.ValueOf(&k).Elem()); err != nil {
+			return err
+		}
+		if err := decodeElement(r, reflect.ValueOf(&v).Elem()); err != nil {
+			return err
+		}
+		om.Set(k, v)
+	}
+	return r.Err()
+}
+
+// orderedMapCodec is implemented by every OrderedMap instantiation, letting
+// the reflective codec handle them without knowing their type parameters.
+type orderedMapCodec interface {
+	encodeEntries(w *Writer) error
+	entriesSize(opts Options) int
+}
+
+// orderedMapDecoder is implemented by pointers to OrderedMaps.
+type orderedMapDecoder interface {
+	decodeEntries(r *Reader) error
+}
+
+var orderedMapCodecType = reflect.TypeOf((*orderedMapCodec)(nil)).Elem()
+
+// orderedMapKeysIndex is the field index of OrderedMap's keys, used to find
+// empty maps without copying them.
+const orderedMapKeysIndex = 0
+
+// isOrderedMapType reports whether t is an instantiation of OrderedMap.
+func isOrderedMapType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.Implements(orderedMapCodecType)
+}
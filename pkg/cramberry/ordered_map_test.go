@@ -0,0 +1,151 @@
+package cramberry
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestOrderedMap(t *testing.T) {
+	var om OrderedMap[string, int]
+	if om.Len() != 0 {
+		t.Fatalf("zero value Len = %d, want 0", om.Len())
+	}
+
+	om.Set("b", 1)
+	om.Set("a", 2)
+	om.Set("c", 3)
+	om.Set("b", 4) // keeps its position
+	if got, want := om.Keys(), []string{"b", "a", "c"}; !slices.Equal(got, want) {
+		t.Errorf("Keys = %v, want %v", got, want)
+	}
+	if v, ok := om.Get("b"); !ok || v != 4 {
+		t.Errorf("Get(b) = %d, %v, want 4, true", v, ok)
+	}
+
+	om.Delete("a")
+	om.Delete("missing")
+	var keys []string
+	var values []int
+	for k, v := range om.All() {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	if !slices.Equal(keys, []string{"b", "c"}) || !slices.Equal(values, []int{4, 3}) {
+		t.Errorf("All = %v %v, want [b c] [4 3]", keys, values)
+	}
+
+	om.Clear()
+	if om.Len() != 0 {
+		t.Errorf("Len after Clear = %d, want 0", om.Len())
+	}
+	if _, ok := om.Get("b"); ok {
+		t.Error("Get found a key after Clear")
+	}
+	om.Set("z", 1)
+	if got := om.Keys(); !slices.Equal(got, []string{"z"}) {
+		t.Errorf("Keys after reuse = %v, want [z]", got)
+	}
+}
+
+func TestOrderedMapJSON(t *testing.T) {
+	type Config struct {
+		Settings OrderedMap[string, int] `json:"settings"`
+		Ports    OrderedMap[int, string] `json:"ports"`
+	}
+	var sent Config
+	sent.Settings.Set("zeta", 1)
+	sent.Settings.Set("alpha", 2)
+	sent.Settings.Set("mid", 3)
+	sent.Ports.Set(443, "https")
+	sent.Ports.Set(80, "http")
+
+	data, err := json.Marshal(sent)
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	want := `{"settings":{"zeta":1,"alpha":2,"mid":3},"ports":{"443":"https","80":"http"}}`
+	if string(data) != want {
+		t.Errorf("json.Marshal = %s, want %s", data, want)
+	}
+
+	var got Config
+	got.Settings.Set("stale", 9)
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+	if keys := got.Settings.Keys(); !slices.Equal(keys, []string{"zeta", "alpha", "mid"}) {
+		t.Errorf("Settings keys = %v, want insertion order", keys)
+	}
+	if keys := got.Ports.Keys(); !slices.Equal(keys, []int{443, 80}) {
+		t.Errorf("Ports keys = %v, want [443 80]", keys)
+	}
+	if v, _ := got.Settings.Get("alpha"); v != 2 {
+		t.Errorf("Settings[alpha] = %d, want 2", v)
+	}
+
+	if err := json.Unmarshal([]byte(`{"settings":null}`), &got); err != nil || got.Settings.Len() != 0 {
+		t.Errorf("null: Len = %d, err = %v, want an empty map", got.Settings.Len(), err)
+	}
+	if err := json.Unmarshal([]byte(`{"settings":[1]}`), &got); err == nil {
+		t.Error("expected an error for an array")
+	}
+	if err := json.Unmarshal([]byte(`{"settings":{"a":"x"}}`), &got); err == nil {
+		t.Error("expected an error for a string value in an int map")
+	}
+}
+
+func TestOrderedMapMarshal(t *testing.T) {
+	type Config struct {
+		Name     string                  `cramberry:"1"`
+		Settings OrderedMap[string, int] `cramberry:"2"`
+	}
+	var sent Config
+	sent.Name = "app"
+	sent.Settings.Set("zeta", 1)
+	sent.Settings.Set("alpha", 2)
+
+	data, err := Marshal(sent)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if size := Size(sent); size != len(data) {
+		t.Errorf("Size = %d, want %d", size, len(data))
+	}
+
+	// Entries are written like a Go map's, but in insertion order
+	w := NewWriter()
+	w.WriteMapHeader(2)
+	w.WriteString("zeta")
+	w.WriteInt64(1)
+	w.WriteString("alpha")
+	w.WriteInt64(2)
+	if !bytes.Contains(data, w.Bytes()) {
+		t.Errorf("encoding %x doesn't hold the entries in order %x", data, w.Bytes())
+	}
+
+	var got Config
+	if err := Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if got.Name != "app" || !slices.Equal(got.Settings.Keys(), []string{"zeta", "alpha"}) {
+		t.Errorf("round trip = %+v, want %+v", got, sent)
+	}
+
+	// An empty ordered map is omitted like an empty Go map
+	empty, err := Marshal(Config{Name: "app"})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	plain, err := Marshal(struct {
+		Name     string         `cramberry:"1"`
+		Settings map[string]int `cramberry:"2"`
+	}{Name: "app"})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if !bytes.Equal(empty, plain) {
+		t.Errorf("empty ordered map encodes as %x, want %x", empty, plain)
+	}
+}
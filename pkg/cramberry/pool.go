@@ -2,6 +2,7 @@ package cramberry
 
 import (
 	"sync"
+	"sync/atomic"
 )
 
 // Size-tiered buffer pools for efficient memory reuse.
@@ -68,9 +69,149 @@ func PutBuffer(buf []byte) {
 	}
 }
 
+// WriterPoolConfig controls the buffers of writers handed out by GetWriter
+// and retained by PutWriter.
+type WriterPoolConfig struct {
+	// InitialSize is the buffer capacity of writers returned by GetWriter.
+	InitialSize int
+
+	// MaxPooledSize is the largest buffer capacity returned to the regular
+	// writer pool. It is raised to InitialSize when smaller.
+	MaxPooledSize int
+
+	// MaxLargeSize is the largest buffer capacity kept in the large-buffer
+	// pool. Writers with bigger buffers are discarded. Zero disables the
+	// large-buffer pool.
+	MaxLargeSize int
+}
+
+// DefaultWriterPoolConfig is the writer pool configuration used at startup.
+var DefaultWriterPoolConfig = WriterPoolConfig{
+	InitialSize:   256,
+	MaxPooledSize: 64 * 1024,
+	MaxLargeSize:  4 * 1024 * 1024,
+}
+
+// writerPoolConfig holds the active writer pool configuration.
+var writerPoolConfig atomic.Pointer[WriterPoolConfig]
+
+// largeWriterPool holds writers whose buffers outgrew the regular pool.
+var largeWriterPool sync.Pool
+
+func init() {
+	cfg := DefaultWriterPoolConfig
+	writerPoolConfig.Store(&cfg)
+}
+
+// maxPooled returns the effective capacity limit of the regular pool.
+func (c *WriterPoolConfig) maxPooled() int {
+	if c.MaxPooledSize < c.InitialSize {
+		return c.InitialSize
+	}
+	return c.MaxPooledSize
+}
+
+// SetWriterPoolConfig replaces the writer pool configuration.
+// Non-positive InitialSize and MaxPooledSize fall back to the defaults.
+// It is safe to call concurrently with GetWriter and PutWriter; writers
+// already in the pool keep their buffers until reused.
+func SetWriterPoolConfig(cfg WriterPoolConfig) {
+	if cfg.InitialSize <= 0 {
+		cfg.InitialSize = DefaultWriterPoolConfig.InitialSize
+	}
+	if cfg.MaxPooledSize <= 0 {
+		cfg.MaxPooledSize = DefaultWriterPoolConfig.MaxPooledSize
+	}
+	if cfg.MaxLargeSize < 0 {
+		cfg.MaxLargeSize = 0
+	}
+	writerPoolConfig.Store(&cfg)
+}
+
+// GetWriterPoolConfig returns the current writer pool configuration.
+func GetWriterPoolConfig() WriterPoolConfig {
+	return *writerPoolConfig.Load()
+}
+
+// SetWriterPoolSize sets the initial buffer capacity of pooled writers.
+// Applications that consistently encode large messages can raise it to
+// avoid regrowing the buffer on every use.
+func SetWriterPoolSize(n int) {
+	cfg := GetWriterPoolConfig()
+	cfg.InitialSize = n
+	SetWriterPoolConfig(cfg)
+}
+
+// adaptiveSizing reports whether pooled writers are sized from the
+// average encoded length; adaptiveSize holds that average.
+var (
+	adaptiveSizing atomic.Bool
+	adaptiveSize   atomic.Int64
+)
+
+// EnableAdaptiveWriterSizing turns adaptive sizing of pooled writers on or
+// off. When on, PutWriter folds the length of each writer's output into an
+// exponentially weighted moving average, and writers handed out by
+// GetWriter start with room for that average plus a quarter, never less
+// than WriterPoolConfig.InitialSize nor more than MaxPooledSize.
+//
+// Writers reused from the pool keep their grown buffers either way, so this
+// helps servers encoding similarly sized messages once the pool loses its
+// writers, as it does on garbage collection or under bursts of concurrent
+// encodes: new writers then skip the regrowth from InitialSize. Turning it
+// off discards the average.
+func EnableAdaptiveWriterSizing(enabled bool) {
+	adaptiveSizing.Store(enabled)
+	if !enabled {
+		adaptiveSize.Store(0)
+	}
+}
+
+// recordWriterSize folds an encoded length into the adaptive average,
+// weighting it by 1/8.
+func recordWriterSize(n int) {
+	for {
+		old := adaptiveSize.Load()
+		avg := int64(n)
+		if old > 0 {
+			avg = old + (avg-old)/8
+		}
+		if adaptiveSize.CompareAndSwap(old, avg) {
+			return
+		}
+	}
+}
+
+// pooledWriterSize returns the buffer capacity of writers handed out by
+// GetWriter.
+func pooledWriterSize(cfg *WriterPoolConfig) int {
+	size := cfg.InitialSize
+	if adaptiveSizing.Load() {
+		if avg := int(adaptiveSize.Load()); avg > 0 {
+			size = min(max(size, avg+avg/4), cfg.maxPooled())
+		}
+	}
+	return size
+}
+
 // GetWriterWithHint gets a Writer with a pre-allocated buffer sized for the hint.
+// Hints larger than the regular pool's limit are served from the large-buffer
+// pool when a writer is available there.
 // The Writer should be returned with PutWriter when done.
 func GetWriterWithHint(sizeHint int) *Writer {
+	if sizeHint > writerPoolConfig.Load().maxPooled() {
+		if w, ok := largeWriterPool.Get().(*Writer); ok {
+			w.Reset()
+			if cap(w.buf) < sizeHint {
+				w.buf = make([]byte, 0, sizeHint)
+			}
+			return w
+		}
+		return &Writer{
+			buf:  make([]byte, 0, sizeHint),
+			opts: DefaultOptions,
+		}
+	}
 	buf := GetBuffer(sizeHint)
 	return &Writer{
 		buf:  buf,
@@ -1,6 +1,8 @@
 package cramberry
 
 import (
+	"fmt"
+	"io"
 	"math"
 	"unsafe"
 
@@ -18,6 +20,16 @@ type Reader struct {
 	depth      int
 	err        error
 	generation uint64 // Incremented on Reset() to invalidate zero-copy references
+
+	// eofEnded records that end of input has ended a message, which
+	// OmitTopLevelEndMarker allows only once: for the outermost message.
+	eofEnded bool
+
+	// present collects the field numbers of the outermost struct for
+	// UnmarshalPresence. It is nil otherwise.
+	present map[int]bool
+
+	stats Stats // counted only with Options.CollectStats
 }
 
 // ZeroCopyString is a string that references the Reader's buffer directly.
@@ -204,6 +216,8 @@ func (r *Reader) Reset(data []byte) {
 	r.pos = 0
 	r.depth = 0
 	r.err = nil
+	r.eofEnded = false
+	r.stats = Stats{}
 	r.generation++ // Invalidate all zero-copy references
 }
 
@@ -223,6 +237,32 @@ func (r *Reader) Options() Options {
 	return r.opts
 }
 
+// Warn reports a non-fatal condition about fieldNum to the OnWarning hook
+// of the reader's options. It does nothing if no hook is set.
+func (r *Reader) Warn(code WarningCode, fieldNum int, message string) {
+	if r.warns() {
+		r.opts.Hooks.OnWarning(Warning{Code: code, FieldNum: fieldNum, Pos: r.pos, Message: message})
+	}
+}
+
+// warns reports whether an OnWarning hook is set, so that callers can skip
+// building a warning message nobody reads.
+func (r *Reader) warns() bool {
+	return r.opts.Hooks != nil && r.opts.Hooks.OnWarning != nil
+}
+
+// Stats returns the bytes, fields and nesting depth read since the reader
+// was created or last reset. It returns zero Stats unless the reader's
+// options set CollectStats.
+func (r *Reader) Stats() Stats {
+	if !r.opts.CollectStats {
+		return Stats{}
+	}
+	s := r.stats
+	s.Bytes = min(r.pos, len(r.data))
+	return s
+}
+
 // Len returns the number of unread bytes.
 func (r *Reader) Len() int {
 	if r.pos >= len(r.data) {
@@ -249,6 +289,26 @@ func (r *Reader) Remaining() []byte {
 	return r.data[r.pos:]
 }
 
+// Tee starts recording the bytes consumed from the current position. Calling
+// the returned stop function yields the raw bytes read since Tee was called,
+// for example the exact encoding of a sub-message that must be verified
+// against a signature. The result aliases the reader's buffer and must not
+// be modified. stop returns nil if the reader was Reset in the meantime.
+func (r *Reader) Tee() (stop func() []byte) {
+	start := r.pos
+	generation := r.generation
+	return func() []byte {
+		if r.generation != generation {
+			return nil
+		}
+		end := min(r.pos, len(r.data))
+		if end < start {
+			return nil
+		}
+		return r.data[start:end:end]
+	}
+}
+
 // EOF returns true if all data has been read.
 func (r *Reader) EOF() bool {
 	return r.pos >= len(r.data)
@@ -259,6 +319,16 @@ func (r *Reader) Err() error {
 	return r.err
 }
 
+// ClearErr discards the sticky error so that reading can resume, leaving
+// the data, position, depth and generation as they are. It is meant for
+// advanced recovery, such as moving past a corrupt region of a buffer and
+// retrying at a known-good offset. The position after a failed read is not
+// guaranteed to be at a value boundary, so the caller must reposition the
+// reader (for example with Skip) before reading again.
+func (r *Reader) ClearErr() {
+	r.err = nil
+}
+
 // setError records the first error that occurs.
 func (r *Reader) setError(err error) {
 	if r.err == nil {
@@ -292,11 +362,14 @@ func (r *Reader) ensure(n int) bool {
 
 // enterNested increases the nesting depth and checks limits.
 func (r *Reader) enterNested() bool {
-	if r.opts.Limits.MaxDepth > 0 && r.depth >= r.opts.Limits.MaxDepth {
+	if limit := r.opts.Limits.decodeDepth(); limit > 0 && r.depth >= limit {
 		r.setError(ErrMaxDepthExceeded)
 		return false
 	}
 	r.depth++
+	if r.opts.CollectStats && r.depth > r.stats.MaxCheckedDepth {
+		r.stats.MaxCheckedDepth = r.depth
+	}
 	return true
 }
 
@@ -422,45 +495,108 @@ func (r *Reader) ReadUvarint() uint64 {
 	if !r.checkRead() {
 		return 0
 	}
+	if v, ok := r.uvarintFast(); ok {
+		return v
+	}
 	v, n, err := wire.DecodeUvarint(r.data[r.pos:])
 	if err != nil {
-		r.setErrorAt(err, "invalid varint")
+		r.setErrorAt(varintError(err), "invalid varint")
 		return 0
 	}
 	r.pos += n
 	return v
 }
 
-// ReadUvarintInline reads an unsigned varint with inlined fast path for 1-2 byte values.
-// This is faster for small values (< 16384) which are common.
-func (r *Reader) ReadUvarintInline() uint64 {
-	if r.err != nil || r.pos >= len(r.data) {
-		if r.err == nil {
-			r.setErrorAt(ErrUnexpectedEOF, "unexpected end of data")
+// uvarintFast decodes a varint, unrolling the first five bytes (values
+// below 2^35, which covers every uint32, length and count). It reports
+// false, leaving the position unchanged, for truncated or malformed
+// varints, which the callers decode again with the wire package to report
+// the error.
+func (r *Reader) uvarintFast() (uint64, bool) {
+	rest := r.data[r.pos:]
+	if len(rest) < 5 {
+		// Near the end of the data only the one- and two-byte forms are
+		// decoded here.
+		if len(rest) > 0 && rest[0] < 0x80 {
+			r.pos++
+			return uint64(rest[0]), true
 		}
-		return 0
+		if len(rest) > 1 && rest[1] < 0x80 {
+			r.pos += 2
+			return uint64(rest[0]&0x7f) | uint64(rest[1])<<7, true
+		}
+		return 0, false
 	}
 
-	// Fast path: single byte (value < 128)
-	b := r.data[r.pos]
+	b := rest[0]
 	if b < 0x80 {
 		r.pos++
-		return uint64(b)
+		return uint64(b), true
 	}
+	v := uint64(b & 0x7f)
+	b = rest[1]
+	if b < 0x80 {
+		r.pos += 2
+		return v | uint64(b)<<7, true
+	}
+	v |= uint64(b&0x7f) << 7
+	b = rest[2]
+	if b < 0x80 {
+		r.pos += 3
+		return v | uint64(b)<<14, true
+	}
+	v |= uint64(b&0x7f) << 14
+	b = rest[3]
+	if b < 0x80 {
+		r.pos += 4
+		return v | uint64(b)<<21, true
+	}
+	v |= uint64(b&0x7f) << 21
+	b = rest[4]
+	if b < 0x80 {
+		r.pos += 5
+		return v | uint64(b)<<28, true
+	}
+	v |= uint64(b&0x7f) << 28
 
-	// Fast path: two bytes (value < 16384)
-	if r.pos+1 < len(r.data) {
-		b2 := r.data[r.pos+1]
-		if b2 < 0x80 {
-			r.pos += 2
-			return uint64(b&0x7f) | uint64(b2)<<7
+	// Longer varints continue from the sixth byte rather than starting
+	// over. At most five more bytes can follow, holding the top 29 bits;
+	// anything else is malformed and left for the caller to report.
+	hi, n, err := wire.DecodeUvarint(rest[5:])
+	if err != nil || n > 5 || hi >= 1<<29 {
+		return 0, false
+	}
+	r.pos += 5 + n
+	return v | hi<<35, true
+}
+
+// varintError maps a wire varint error onto the error reported for the same
+// input by StreamReader, so truncated input is ErrUnexpectedEOF for both.
+func varintError(err error) error {
+	if err == wire.ErrVarintTruncated {
+		return ErrUnexpectedEOF
+	}
+	return err
+}
+
+// ReadUvarintInline reads an unsigned varint with an unrolled fast path for
+// values of up to five bytes, which covers nearly all lengths, counts and
+// IDs. Longer varints are decoded by the wire package.
+func (r *Reader) ReadUvarintInline() uint64 {
+	if r.err != nil || r.pos >= len(r.data) {
+		if r.err == nil {
+			r.setErrorAt(ErrUnexpectedEOF, "unexpected end of data")
 		}
+		return 0
+	}
+	if v, ok := r.uvarintFast(); ok {
+		return v
 	}
 
 	// Slow path: delegate to wire package
 	v, n, err := wire.DecodeUvarint(r.data[r.pos:])
 	if err != nil {
-		r.setErrorAt(err, "invalid varint")
+		r.setErrorAt(varintError(err), "invalid varint")
 		return 0
 	}
 	r.pos += n
@@ -472,9 +608,13 @@ func (r *Reader) ReadSvarint() int64 {
 	if !r.checkRead() {
 		return 0
 	}
+	if u, ok := r.uvarintFast(); ok {
+		// ZigZag decode: (u >> 1) ^ -(u & 1)
+		return int64(u>>1) ^ -int64(u&1)
+	}
 	v, n, err := wire.DecodeSvarint(r.data[r.pos:])
 	if err != nil {
-		r.setErrorAt(err, "invalid signed varint")
+		r.setErrorAt(varintError(err), "invalid signed varint")
 		return 0
 	}
 	r.pos += n
@@ -580,7 +720,14 @@ func (r *Reader) ReadString() string {
 	if !r.ensure(n) {
 		return ""
 	}
-	s := string(r.data[r.pos : r.pos+n])
+	var s string
+	if r.opts.Allocator == nil {
+		s = string(r.data[r.pos : r.pos+n])
+	} else if n > 0 {
+		buf := allocBytes(r.opts.Allocator, n)
+		copy(buf, r.data[r.pos:r.pos+n])
+		s = unsafe.String(&buf[0], n)
+	}
 	r.pos += n
 	// Validate UTF-8 if required
 	if r.opts.ValidateUTF8 && !isValidUTF8(s) {
@@ -680,12 +827,74 @@ func (r *Reader) ReadBytes() []byte {
 		return nil
 	}
 	// Return a copy to avoid aliasing
-	result := make([]byte, n)
+	result := allocBytes(r.opts.Allocator, n)
 	copy(result, r.data[r.pos:r.pos+n])
 	r.pos += n
 	return result
 }
 
+// ReadBytesTo reads a length-prefixed byte slice and writes it to dst, such
+// as a temporary file, instead of allocating a copy, and returns the number
+// of bytes written. A custom or generated decoder can call it for fields
+// that may hold large attachments, keeping their size off the heap.
+//
+// limit caps the length accepted from the prefix; zero or a negative limit
+// disables the cap, and the reader's MaxBytesLength limit is not applied
+// since the bytes are not held in memory. A longer field is recorded as an
+// error wrapping ErrMaxBytesLength without writing anything. Errors from
+// dst are recorded as the reader's error and returned, like decode errors.
+func (r *Reader) ReadBytesTo(dst io.Writer, limit int) (int, error) {
+	if !r.checkRead() {
+		return 0, r.err
+	}
+	length := r.ReadUvarint()
+	if r.err != nil {
+		return 0, r.err
+	}
+	if length > uint64(MaxInt) {
+		r.setErrorAt(ErrOverflow, "bytes length overflow")
+		return 0, r.err
+	}
+	n := int(length)
+	if limit > 0 && n > limit {
+		r.setErrorAt(ErrMaxBytesLength, fmt.Sprintf("bytes length %d exceeds %d", n, limit))
+		return 0, r.err
+	}
+	if !r.ensure(n) {
+		return 0, r.err
+	}
+	written, err := dst.Write(r.data[r.pos : r.pos+n])
+	r.pos += n
+	if err != nil {
+		r.setError(err)
+		return written, r.err
+	}
+	return written, nil
+}
+
+// ReadBytesInto reads a length-prefixed byte slice into dst, typically a
+// slice of a fixed-size array such as a hash. The encoded length must equal
+// len(dst); a mismatch is recorded as an error wrapping ErrTypeMismatch and
+// leaves dst unchanged.
+func (r *Reader) ReadBytesInto(dst []byte) {
+	if !r.checkRead() {
+		return
+	}
+	length := r.ReadUvarint()
+	if r.err != nil {
+		return
+	}
+	if length != uint64(len(dst)) {
+		r.setErrorAt(ErrTypeMismatch, fmt.Sprintf("bytes length %d does not match fixed size %d", length, len(dst)))
+		return
+	}
+	if !r.ensure(len(dst)) {
+		return
+	}
+	copy(dst, r.data[r.pos:r.pos+len(dst)])
+	r.pos += len(dst)
+}
+
 // ReadBytesNoCopy reads a length-prefixed byte slice without copying.
 //
 // SAFETY WARNING: The returned slice points directly into the Reader's buffer.
@@ -746,6 +955,38 @@ func (r *Reader) ReadBytesNoCopy() ZeroCopyBytes {
 	}
 }
 
+// ReadFixedBytesNoCopy reads a length-prefixed byte slice of a known size,
+// such as a hash, without copying. The encoded length must equal size; a
+// mismatch is recorded as an error wrapping ErrTypeMismatch. The returned
+// view is subject to the same rules as one from ReadBytesNoCopy.
+func (r *Reader) ReadFixedBytesNoCopy(size int) ZeroCopyBytes {
+	if size < 0 {
+		r.setError(ErrNegativeLength)
+		return ZeroCopyBytes{}
+	}
+	if !r.checkRead() {
+		return ZeroCopyBytes{}
+	}
+	length := r.ReadUvarint()
+	if r.err != nil {
+		return ZeroCopyBytes{}
+	}
+	if length != uint64(size) {
+		r.setErrorAt(ErrTypeMismatch, fmt.Sprintf("bytes length %d does not match fixed size %d", length, size))
+		return ZeroCopyBytes{}
+	}
+	if !r.ensure(size) {
+		return ZeroCopyBytes{}
+	}
+	result := r.data[r.pos : r.pos+size]
+	r.pos += size
+	return ZeroCopyBytes{
+		b:          result,
+		generation: r.generation,
+		reader:     r,
+	}
+}
+
 // ReadRawBytes reads exactly n bytes without a length prefix.
 func (r *Reader) ReadRawBytes(n int) []byte {
 	if n < 0 {
@@ -755,7 +996,7 @@ func (r *Reader) ReadRawBytes(n int) []byte {
 	if !r.ensure(n) {
 		return nil
 	}
-	result := make([]byte, n)
+	result := allocBytes(r.opts.Allocator, n)
 	copy(result, r.data[r.pos:r.pos+n])
 	r.pos += n
 	return result
@@ -793,6 +1034,9 @@ func (r *Reader) ReadTag() (fieldNum int, wireType WireType) {
 		r.setErrorAt(err, "invalid field tag")
 		return 0, 0
 	}
+	if r.opts.CollectStats {
+		r.stats.Fields++
+	}
 	r.pos += n
 	return fn, WireType(wt)
 }
@@ -806,6 +1050,26 @@ func (r *Reader) ReadTypeID() TypeID {
 	return TypeID(v)
 }
 
+// ReadNil reads the marker that precedes a pointer element of a slice,
+// array or map and reports whether the element is nil. Encoders write the
+// marker with WriteNil for a nil element and with WritePresent for a set
+// one, whose value then follows.
+func (r *Reader) ReadNil() bool {
+	if !r.ensure(1) {
+		return false
+	}
+	switch r.data[r.pos] {
+	case byte(TypeIDNil):
+		r.pos++
+		return true
+	case presentMarker:
+		r.pos++
+		return false
+	}
+	r.setErrorAt(ErrTypeMismatch, fmt.Sprintf("invalid pointer marker 0x%02x", r.data[r.pos]))
+	return false
+}
+
 // BeginMessage starts reading a length-prefixed message.
 // Returns the end position that should be passed to EndMessage.
 // The reader will be limited to reading within the message bounds.
@@ -816,24 +1080,47 @@ func (r *Reader) BeginMessage() int {
 	if !r.enterNested() {
 		return -1
 	}
+	msgLen, ok := r.readMessageLength()
+	if !ok {
+		return -1
+	}
+	return r.pos + msgLen
+}
+
+// readMessageLength reads the length prefix of a message and checks it
+// against MaxMessageSize and the bytes remaining.
+func (r *Reader) readMessageLength() (int, bool) {
 	length := r.ReadUvarint()
 	if r.err != nil {
-		return -1
+		return 0, false
 	}
 	if length > uint64(MaxInt) {
 		r.setErrorAt(ErrOverflow, "message length overflow")
-		return -1
+		return 0, false
 	}
 	msgLen := int(length)
 	// Check message size limit
 	if r.opts.Limits.MaxMessageSize > 0 && int64(msgLen) > r.opts.Limits.MaxMessageSize {
 		r.setError(ErrMaxSizeExceeded)
-		return -1
+		return 0, false
 	}
 	if !r.ensure(msgLen) {
-		return -1
+		return 0, false
+	}
+	return msgLen, true
+}
+
+// SkipMessage advances past a length-prefixed message without decoding it,
+// like StreamReader.SkipMessage. The prefix is checked as BeginMessage
+// checks it, so scanning an index of concatenated messages stops with an
+// error at a length that is too large or runs past the buffer.
+func (r *Reader) SkipMessage() {
+	if !r.checkRead() {
+		return
+	}
+	if msgLen, ok := r.readMessageLength(); ok {
+		r.pos += msgLen
 	}
-	return r.pos + msgLen
 }
 
 // EndMessage finishes reading a length-prefixed message.
@@ -852,7 +1139,32 @@ func (r *Reader) EndMessage(endPos int) {
 	}
 }
 
-// ReadArrayHeader reads the length of an array/slice.
+// ReadLengthPrefixedInto reads a length-prefixed message and unmarshals it
+// into v, which must be a non-nil pointer. It is the buffered counterpart of
+// StreamReader.ReadDelimited, for messages embedded in a larger binary
+// format: the message is decoded with the reader's options from exactly the
+// number of bytes given by the prefix, and the reader is left just past
+// them. A decode failure is recorded as the reader's error and returned.
+func (r *Reader) ReadLengthPrefixedInto(v any) error {
+	if !r.checkRead() {
+		return r.err
+	}
+	msgLen, ok := r.readMessageLength()
+	if !ok {
+		return r.err
+	}
+	data := r.data[r.pos : r.pos+msgLen]
+	r.pos += msgLen
+	if err := UnmarshalWithOptions(data, v, r.opts); err != nil {
+		r.setError(err)
+	}
+	return r.err
+}
+
+// ReadArrayHeader reads the length of an array/slice. Every element
+// occupies at least one byte, so a length greater than the number of bytes
+// remaining is reported as ErrUnexpectedEOF before the caller allocates for
+// it.
 func (r *Reader) ReadArrayHeader() int {
 	if !r.checkRead() {
 		return 0
@@ -871,10 +1183,15 @@ func (r *Reader) ReadArrayHeader() int {
 		r.setError(ErrMaxArrayLength)
 		return 0
 	}
+	if n > r.Len() {
+		r.setErrorAt(ErrUnexpectedEOF, fmt.Sprintf("array header declares %d elements but only %d bytes remain", n, r.Len()))
+		return 0
+	}
 	return n
 }
 
-// ReadMapHeader reads the size of a map.
+// ReadMapHeader reads the size of a map. Like ReadArrayHeader, it rejects
+// a size greater than the number of bytes remaining.
 func (r *Reader) ReadMapHeader() int {
 	if !r.checkRead() {
 		return 0
@@ -893,9 +1210,83 @@ func (r *Reader) ReadMapHeader() int {
 		r.setError(ErrMaxMapSize)
 		return 0
 	}
+	if n > r.Len() {
+		r.setErrorAt(ErrUnexpectedEOF, fmt.Sprintf("map header declares %d entries but only %d bytes remain", n, r.Len()))
+		return 0
+	}
 	return n
 }
 
+// ReadMap reads a map header and calls fn once per declared entry to decode
+// a key/value pair. A header that declares more entries than bytes remain
+// is rejected up front by ReadMapHeader, and running
+// out of data before an entry is reported as ErrUnexpectedEOF along with how
+// many entries were read. Decoding stops at the first error returned by fn
+// or recorded on the reader.
+func (r *Reader) ReadMap(fn func(r *Reader) error) error {
+	return r.ReadMapEntries(r.ReadMapHeader(), fn)
+}
+
+// ReadMapEntries calls fn once for each of the n entries of a map whose
+// header was read with ReadMapHeader, as ReadMap does. Reading the header
+// separately lets callers size the map before decoding into it.
+func (r *Reader) ReadMapEntries(n int, fn func(r *Reader) error) error {
+	if r.err != nil {
+		return r.err
+	}
+	for i := 0; i < n; i++ {
+		if r.Len() == 0 {
+			r.setErrorAt(ErrUnexpectedEOF, fmt.Sprintf("map truncated after %d of %d entries", i, n))
+			return r.err
+		}
+		if err := fn(r); err != nil {
+			r.setErrorAt(err, fmt.Sprintf("map entry %d", i))
+			return r.err
+		}
+		if r.err != nil {
+			return r.err
+		}
+	}
+	return nil
+}
+
+// RangeMap reads a map header and calls fn once per entry with two readers
+// that span exactly the entry's key and value, without building the map.
+// Map entries carry no tags, so keyType and valueType give the V2 wire types
+// used to find where each key and value ends; entries fn doesn't decode are
+// skipped cheaply. Values must be delimited by their wire type, which rules
+// out marker-framed message values. MaxMapSize is enforced as in
+// ReadMapHeader, and iteration stops at the first error.
+func (r *Reader) RangeMap(keyType, valueType byte, fn func(keyR, valR *Reader) error) error {
+	n := r.ReadMapHeader()
+	if r.err != nil {
+		return r.err
+	}
+	for i := 0; i < n; i++ {
+		keyR := r.spanV2(keyType)
+		valR := r.spanV2(valueType)
+		if r.err != nil {
+			return r.err
+		}
+		if err := fn(keyR, valR); err != nil {
+			r.setErrorAt(err, fmt.Sprintf("map entry %d", i))
+			return r.err
+		}
+	}
+	return nil
+}
+
+// spanV2 skips one value of the given V2 wire type and returns a reader over
+// its bytes.
+func (r *Reader) spanV2(wireType byte) *Reader {
+	start := r.pos
+	r.SkipValueV2(wireType)
+	if r.err != nil {
+		return nil
+	}
+	return &Reader{data: r.data[start:r.pos:r.pos], opts: r.opts}
+}
+
 // SkipValue skips a value based on its wire type.
 func (r *Reader) SkipValue(wireType WireType) {
 	if !r.checkRead() {
@@ -941,6 +1332,25 @@ func (r *Reader) SubReader(length int) *Reader {
 	return sub
 }
 
+// Clone returns a Reader over the same data that starts at r's position,
+// nesting depth and error state but tracks them separately from then on,
+// so that several goroutines can decode parts of one buffer in parallel
+// with a clone each. The clone has its own generation: resetting either
+// reader does not invalidate zero-copy values obtained from the other.
+//
+// The data is shared, not copied, and must not be modified while any of
+// the readers or the zero-copy values they returned are in use.
+func (r *Reader) Clone() *Reader {
+	return &Reader{
+		data:     r.data,
+		pos:      r.pos,
+		opts:     r.opts,
+		depth:    r.depth,
+		err:      r.err,
+		eofEnded: r.eofEnded,
+	}
+}
+
 // MaxInt is the maximum value of int (platform dependent).
 const MaxInt = int(^uint(0) >> 1)
 
@@ -1070,3 +1480,28 @@ func (r *Reader) ReadPackedFixed64(count int) []uint64 {
 	}
 	return result
 }
+
+// ReadPackedDeltaInt64 reads count int64 values written by
+// WritePackedDeltaInt64.
+func (r *Reader) ReadPackedDeltaInt64(count int) []int64 {
+	if count <= 0 {
+		return nil
+	}
+	// Every value takes at least one byte
+	if count > r.Len() {
+		r.setErrorAt(ErrUnexpectedEOF, "unexpected end of data")
+		return nil
+	}
+
+	result := make([]int64, count)
+	v := r.ReadSvarint()
+	result[0] = v
+	for i := 1; i < count; i++ {
+		v += int64(r.ReadUvarint())
+		result[i] = v
+	}
+	if r.err != nil {
+		return nil
+	}
+	return result
+}
@@ -2,7 +2,12 @@ package cramberry
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"math"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -159,6 +164,58 @@ func TestReadSvarint(t *testing.T) {
 	}
 }
 
+// TestReadUvarintLengths checks every varint length on both sides of the
+// five-byte unrolled path, with the varint at the end of the data and
+// followed by more bytes, and truncated at every byte.
+func TestReadUvarintLengths(t *testing.T) {
+	var values []uint64
+	for n := 1; n <= 10; n++ {
+		shift := 7 * (n - 1)
+		values = append(values, 1<<shift)
+		if n < 10 {
+			values = append(values, 1<<(shift+7)-1)
+		}
+	}
+	values = append(values, math.MaxUint64, 1<<35-1, 1<<35)
+
+	readers := map[string]func(r *Reader) uint64{
+		"ReadUvarint":       (*Reader).ReadUvarint,
+		"ReadUvarintInline": (*Reader).ReadUvarintInline,
+		"ReadSvarint": func(r *Reader) uint64 {
+			// Undo the ZigZag decoding to compare the raw varint
+			x := r.ReadSvarint()
+			return uint64(x<<1) ^ uint64(x>>63)
+		},
+	}
+
+	for _, v := range values {
+		w := NewWriter()
+		w.WriteUvarint(v)
+		enc := w.BytesCopy()
+		for _, tail := range [][]byte{nil, {0x80, 0x80, 0x80, 0x80, 0x80, 0x80}} {
+			data := append(bytes.Clone(enc), tail...)
+			for name, read := range readers {
+				r := NewReader(data)
+				if got := read(r); got != v || r.Err() != nil {
+					t.Errorf("%s(%x) = %d (%v), want %d", name, data, got, r.Err(), v)
+				}
+				if r.Pos() != len(enc) {
+					t.Errorf("%s(%x) consumed %d bytes, want %d", name, data, r.Pos(), len(enc))
+				}
+			}
+		}
+		for cut := range len(enc) {
+			for name, read := range readers {
+				r := NewReader(enc[:cut])
+				read(r)
+				if !errors.Is(r.Err(), ErrUnexpectedEOF) || r.Pos() != 0 {
+					t.Errorf("%s(%x): err %v at %d, want ErrUnexpectedEOF at 0", name, enc[:cut], r.Err(), r.Pos())
+				}
+			}
+		}
+	}
+}
+
 func TestReadUint(t *testing.T) {
 	// Write values and read them back
 	w := NewWriter()
@@ -420,6 +477,95 @@ func TestReadBytes(t *testing.T) {
 	}
 }
 
+func TestReadBytesInto(t *testing.T) {
+	w := NewWriter()
+	w.WriteBytes([]byte{1, 2, 3, 4})
+	data := w.BytesCopy()
+
+	var dst [4]byte
+	r := NewReader(data)
+	r.ReadBytesInto(dst[:])
+	if r.Err() != nil {
+		t.Errorf("ReadBytesInto error: %v", r.Err())
+	}
+	if dst != [4]byte{1, 2, 3, 4} {
+		t.Errorf("ReadBytesInto = %v", dst)
+	}
+
+	for _, size := range []int{3, 5} {
+		dst := make([]byte, size)
+		r := NewReader(data)
+		r.ReadBytesInto(dst)
+		if !errors.Is(r.Err(), ErrTypeMismatch) {
+			t.Errorf("size %d: expected ErrTypeMismatch, got %v", size, r.Err())
+		}
+		if !bytes.Equal(dst, make([]byte, size)) {
+			t.Errorf("size %d: dst modified on error: %v", size, dst)
+		}
+	}
+
+	// Truncated data
+	var short [4]byte
+	r = NewReader(data[:3])
+	r.ReadBytesInto(short[:])
+	if !errors.Is(r.Err(), ErrUnexpectedEOF) {
+		t.Errorf("expected ErrUnexpectedEOF, got %v", r.Err())
+	}
+}
+
+// failingWriter accepts n bytes and then fails.
+type failingWriter struct{ n int }
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > f.n {
+		return f.n, errors.New("disk full")
+	}
+	f.n -= len(p)
+	return len(p), nil
+}
+
+func TestReadBytesTo(t *testing.T) {
+	payload := make([]byte, 5<<20)
+	for i := range payload {
+		payload[i] = byte(i * 31)
+	}
+	w := NewWriter()
+	w.WriteBytes(payload)
+	w.WriteInt32(7)
+	data := w.BytesCopy()
+
+	// The reader's MaxBytesLength does not apply to bytes sent to a writer
+	r := NewReaderWithOptions(data, Options{Limits: Limits{MaxBytesLength: 1 << 20}})
+	var sink bytes.Buffer
+	n, err := r.ReadBytesTo(&sink, 0)
+	if err != nil {
+		t.Fatalf("ReadBytesTo: %v", err)
+	}
+	if n != len(payload) || !bytes.Equal(sink.Bytes(), payload) {
+		t.Errorf("wrote %d bytes, want %d matching the payload", n, len(payload))
+	}
+	if v := r.ReadInt32(); v != 7 || r.Err() != nil {
+		t.Errorf("following field = %d, %v", v, r.Err())
+	}
+
+	sink.Reset()
+	r = NewReader(data)
+	if n, err := r.ReadBytesTo(&sink, len(payload)-1); !errors.Is(err, ErrMaxBytesLength) || n != 0 || sink.Len() != 0 {
+		t.Errorf("over limit: wrote %d (%d buffered), err %v", n, sink.Len(), err)
+	}
+
+	r = NewReader(data)
+	n, err = r.ReadBytesTo(&failingWriter{n: 1000}, 0)
+	if err == nil || err.Error() != "disk full" || n != 1000 || r.Err() != err {
+		t.Errorf("failing writer: wrote %d, err %v, sticky %v", n, err, r.Err())
+	}
+
+	r = NewReader(data[:1000])
+	if _, err := r.ReadBytesTo(&sink, 0); !errors.Is(err, ErrUnexpectedEOF) {
+		t.Errorf("truncated data: got %v, want ErrUnexpectedEOF", err)
+	}
+}
+
 func TestReadBytesNoCopy(t *testing.T) {
 	w := NewWriter()
 	w.WriteBytes([]byte{1, 2, 3, 4, 5})
@@ -438,6 +584,50 @@ func TestReadBytesNoCopy(t *testing.T) {
 	}
 }
 
+func TestReadFixedBytesNoCopy(t *testing.T) {
+	hash := bytes.Repeat([]byte{0xab}, 32)
+	w := NewWriter()
+	w.WriteBytes(hash)
+	w.WriteInt32(7)
+	data := w.BytesCopy()
+
+	r := NewReader(data)
+	got := r.ReadFixedBytesNoCopy(32)
+	if r.Err() != nil {
+		t.Fatalf("ReadFixedBytesNoCopy error: %v", r.Err())
+	}
+	if !bytes.Equal(got.Bytes(), hash) {
+		t.Errorf("ReadFixedBytesNoCopy = %x, want %x", got.Bytes(), hash)
+	}
+	if &got.Bytes()[0] != &data[1] {
+		t.Error("ReadFixedBytesNoCopy copied the bytes")
+	}
+	if v := r.ReadInt32(); v != 7 || r.Err() != nil {
+		t.Errorf("following field = %d, %v", v, r.Err())
+	}
+	r.Reset(data)
+	if got.Valid() {
+		t.Error("view still valid after Reset")
+	}
+
+	for _, size := range []int{31, 33, 0} {
+		r := NewReader(data)
+		if got := r.ReadFixedBytesNoCopy(size); !errors.Is(r.Err(), ErrTypeMismatch) || !got.IsEmpty() {
+			t.Errorf("size %d: got %d bytes, err %v, want ErrTypeMismatch", size, got.Len(), r.Err())
+		}
+	}
+
+	r = NewReader(data)
+	if r.ReadFixedBytesNoCopy(-1); !errors.Is(r.Err(), ErrNegativeLength) {
+		t.Errorf("negative size: got %v, want ErrNegativeLength", r.Err())
+	}
+
+	r = NewReader(data[:20])
+	if r.ReadFixedBytesNoCopy(32); !errors.Is(r.Err(), ErrUnexpectedEOF) {
+		t.Errorf("truncated data: got %v, want ErrUnexpectedEOF", r.Err())
+	}
+}
+
 func TestReadRawBytes(t *testing.T) {
 	data := []byte{1, 2, 3, 4, 5}
 	r := NewReader(data)
@@ -515,6 +705,32 @@ func TestReadTypeID(t *testing.T) {
 	}
 }
 
+func TestReadNil(t *testing.T) {
+	w := NewWriter()
+	w.WriteNil()
+	w.WritePresent()
+	w.WriteInt32(0) // encoded like the nil marker
+
+	r := NewReader(w.Bytes())
+	if !r.ReadNil() {
+		t.Error("ReadNil = false for a nil marker")
+	}
+	if r.ReadNil() {
+		t.Error("ReadNil = true for a present marker")
+	}
+	if v := r.ReadInt32(); v != 0 || r.Err() != nil {
+		t.Errorf("ReadInt32 = %d, %v, want 0", v, r.Err())
+	}
+	if r.ReadNil() || !errors.Is(r.Err(), ErrUnexpectedEOF) {
+		t.Errorf("ReadNil at end of data: err %v, want ErrUnexpectedEOF", r.Err())
+	}
+
+	r = NewReader([]byte{5})
+	if r.ReadNil() || !errors.Is(r.Err(), ErrTypeMismatch) {
+		t.Errorf("ReadNil of an invalid marker: err %v, want ErrTypeMismatch", r.Err())
+	}
+}
+
 func TestReaderBeginEndMessage(t *testing.T) {
 	// Create a message
 	w := NewWriter()
@@ -577,9 +793,128 @@ func TestReaderNestedMessages(t *testing.T) {
 	}
 }
 
+func TestReaderReadLengthPrefixedInto(t *testing.T) {
+	type Header struct {
+		Kind  string `cramberry:"1"`
+		Flags []int  `cramberry:"2"`
+	}
+	want := Header{Kind: "block", Flags: []int{3, 1, 4}}
+	msg, err := Marshal(&want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A container format: magic, embedded message, trailer
+	w := NewWriter()
+	w.WriteFixed32(0xCAFEF00D)
+	w.WriteBytes(msg)
+	w.WriteString("trailer")
+
+	r := NewReader(w.Bytes())
+	if magic := r.ReadFixed32(); magic != 0xCAFEF00D {
+		t.Fatalf("magic = %#x", magic)
+	}
+	var got Header
+	if err := r.ReadLengthPrefixedInto(&got); err != nil {
+		t.Fatalf("ReadLengthPrefixedInto: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if trailer := r.ReadString(); trailer != "trailer" || !r.EOF() || r.Err() != nil {
+		t.Errorf("trailer = %q, EOF %v, err %v", trailer, r.EOF(), r.Err())
+	}
+
+	// The prefix bounds the message: a truncated blob fails to decode
+	// rather than reading into the bytes that follow it
+	w.Reset()
+	w.WriteUvarint(uint64(len(msg) - 1))
+	w.WriteRawBytes(msg[:len(msg)-1])
+	w.WriteRawBytes(msg[len(msg)-1:])
+	r = NewReader(w.Bytes())
+	if err := r.ReadLengthPrefixedInto(&got); err == nil || r.Err() != err {
+		t.Errorf("truncated message: err %v, sticky %v", err, r.Err())
+	}
+
+	// The prefix cannot claim more than the buffer holds
+	w.Reset()
+	w.WriteUvarint(uint64(len(msg) + 1))
+	w.WriteRawBytes(msg)
+	r = NewReader(w.Bytes())
+	if err := r.ReadLengthPrefixedInto(&got); !errors.Is(err, ErrUnexpectedEOF) {
+		t.Errorf("short buffer: got %v, want ErrUnexpectedEOF", err)
+	}
+
+	w.Reset()
+	w.WriteBytes(msg)
+	r = NewReaderWithOptions(w.Bytes(), Options{Limits: Limits{MaxMessageSize: int64(len(msg) - 1)}})
+	if err := r.ReadLengthPrefixedInto(&got); !errors.Is(err, ErrMaxSizeExceeded) {
+		t.Errorf("oversized message: got %v, want ErrMaxSizeExceeded", err)
+	}
+}
+
+func TestReaderSkipMessage(t *testing.T) {
+	type Entry struct {
+		Seq  int64  `cramberry:"1"`
+		Body string `cramberry:"2"`
+	}
+
+	// Three length-prefixed messages back to back
+	w := NewWriter()
+	var sizes []int
+	for i, body := range []string{"first", "second entry", "third"} {
+		msg, err := Marshal(&Entry{Seq: int64(i + 1), Body: body})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.WriteBytes(msg)
+		sizes = append(sizes, len(msg))
+	}
+	data := w.Bytes()
+
+	r := NewReader(data)
+	r.SkipMessage()
+	if r.Err() != nil {
+		t.Fatalf("SkipMessage: %v", r.Err())
+	}
+	if want := 1 + sizes[0]; r.Pos() != want {
+		t.Errorf("position after skip = %d, want %d", r.Pos(), want)
+	}
+	var got Entry
+	if err := r.ReadLengthPrefixedInto(&got); err != nil {
+		t.Fatalf("ReadLengthPrefixedInto: %v", err)
+	}
+	if got != (Entry{Seq: 2, Body: "second entry"}) {
+		t.Errorf("decoded %+v after skipping the first message", got)
+	}
+	r.SkipMessage()
+	if !r.EOF() || r.Err() != nil {
+		t.Errorf("after skipping the last message: EOF %v, err %v", r.EOF(), r.Err())
+	}
+
+	// A prefix running past the buffer is an error and the error is sticky
+	r = NewReader(data[:1+sizes[0]-1])
+	r.SkipMessage()
+	if !errors.Is(r.Err(), ErrUnexpectedEOF) {
+		t.Errorf("truncated message: got %v, want ErrUnexpectedEOF", r.Err())
+	}
+	pos := r.Pos()
+	r.SkipMessage()
+	if r.Pos() != pos {
+		t.Error("SkipMessage advanced after an error")
+	}
+
+	r = NewReaderWithOptions(data, Options{Limits: Limits{MaxMessageSize: int64(sizes[0] - 1)}})
+	r.SkipMessage()
+	if !errors.Is(r.Err(), ErrMaxSizeExceeded) {
+		t.Errorf("oversized message: got %v, want ErrMaxSizeExceeded", r.Err())
+	}
+}
+
 func TestReadArrayHeader(t *testing.T) {
 	w := NewWriter()
 	w.WriteArrayHeader(10)
+	w.WriteRawBytes(make([]byte, 10))
 
 	r := NewReader(w.Bytes())
 	n := r.ReadArrayHeader()
@@ -589,11 +924,18 @@ func TestReadArrayHeader(t *testing.T) {
 	if n != 10 {
 		t.Errorf("ReadArrayHeader = %d, want 10", n)
 	}
+
+	// Each of the elements needs at least one byte
+	r = NewReader(w.Bytes()[:10])
+	if n := r.ReadArrayHeader(); n != 0 || !errors.Is(r.Err(), ErrUnexpectedEOF) {
+		t.Errorf("short ReadArrayHeader = %d, error %v, want ErrUnexpectedEOF", n, r.Err())
+	}
 }
 
 func TestReadMapHeader(t *testing.T) {
 	w := NewWriter()
 	w.WriteMapHeader(10)
+	w.WriteRawBytes(make([]byte, 10))
 
 	r := NewReader(w.Bytes())
 	n := r.ReadMapHeader()
@@ -603,6 +945,184 @@ func TestReadMapHeader(t *testing.T) {
 	if n != 10 {
 		t.Errorf("ReadMapHeader = %d, want 10", n)
 	}
+
+	// Each of the entries needs at least one byte
+	r = NewReader(w.Bytes()[:10])
+	if n := r.ReadMapHeader(); n != 0 || !errors.Is(r.Err(), ErrUnexpectedEOF) {
+		t.Errorf("short ReadMapHeader = %d, error %v, want ErrUnexpectedEOF", n, r.Err())
+	}
+}
+
+func TestReadMap(t *testing.T) {
+	w := NewWriter()
+	w.WriteMapHeader(2)
+	w.WriteString("a")
+	w.WriteInt32(1)
+	w.WriteString("b")
+	w.WriteInt32(2)
+
+	r := NewReader(w.Bytes())
+	got := make(map[string]int32)
+	calls := 0
+	err := r.ReadMap(func(r *Reader) error {
+		calls++
+		k := r.ReadString()
+		got[k] = r.ReadInt32()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadMap error: %v", err)
+	}
+	if calls != 2 || got["a"] != 1 || got["b"] != 2 {
+		t.Errorf("ReadMap decoded %v in %d calls", got, calls)
+	}
+	if !r.EOF() {
+		t.Errorf("ReadMap left %d bytes unread", r.Len())
+	}
+}
+
+func TestRangeMap(t *testing.T) {
+	const entries = 10000
+	m := make(map[string]int64, entries)
+	for i := 0; i < entries; i++ {
+		m[fmt.Sprintf("host-%05d", i)] = int64(i)
+	}
+	m["metric.cpu"] = -7
+	m["metric.mem"] = 42
+	data, err := Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	// Only the entries whose key matches are decoded
+	r := NewReader(data)
+	calls := 0
+	metrics := make(map[string]int64)
+	err = r.RangeMap(WireTypeV2Bytes, WireTypeV2SVarint, func(keyR, valR *Reader) error {
+		calls++
+		key := keyR.ReadString()
+		if strings.HasPrefix(key, "metric.") {
+			metrics[key] = valR.ReadInt64()
+		}
+		return keyR.Err()
+	})
+	if err != nil {
+		t.Fatalf("RangeMap error: %v", err)
+	}
+	if calls != len(m) {
+		t.Errorf("fn called %d times, want %d", calls, len(m))
+	}
+	if len(metrics) != 2 || metrics["metric.cpu"] != -7 || metrics["metric.mem"] != 42 {
+		t.Errorf("selected entries = %v", metrics)
+	}
+	if !r.EOF() {
+		t.Errorf("RangeMap left %d bytes unread", r.Len())
+	}
+
+	// Each reader spans exactly one value
+	r = NewReader(data)
+	err = r.RangeMap(WireTypeV2Bytes, WireTypeV2SVarint, func(keyR, valR *Reader) error {
+		keyR.ReadString()
+		valR.ReadInt64()
+		if !keyR.EOF() || !valR.EOF() {
+			return fmt.Errorf("entry readers have %d and %d bytes left", keyR.Len(), valR.Len())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("RangeMap error: %v", err)
+	}
+
+	// Errors from fn stop the iteration
+	stop := errors.New("stop")
+	calls = 0
+	r = NewReader(data)
+	err = r.RangeMap(WireTypeV2Bytes, WireTypeV2SVarint, func(keyR, valR *Reader) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("RangeMap = %v after %d calls, want stop after 1", err, calls)
+	}
+
+	opts := DefaultOptions
+	opts.Limits.MaxMapSize = 100
+	r = NewReaderWithOptions(data, opts)
+	err = r.RangeMap(WireTypeV2Bytes, WireTypeV2SVarint, func(keyR, valR *Reader) error {
+		t.Fatal("fn called for a map over MaxMapSize")
+		return nil
+	})
+	if !errors.Is(err, ErrMaxMapSize) {
+		t.Errorf("RangeMap over MaxMapSize = %v, want ErrMaxMapSize", err)
+	}
+
+	r = NewReader(data[:len(data)/2])
+	if err := r.RangeMap(WireTypeV2Bytes, WireTypeV2SVarint, func(keyR, valR *Reader) error { return nil }); err == nil {
+		t.Error("expected an error for truncated data")
+	}
+}
+
+func TestReadMapTruncated(t *testing.T) {
+	t.Run("header exceeds remaining bytes", func(t *testing.T) {
+		w := NewWriter()
+		w.WriteMapHeader(1000)
+		w.WriteString("a")
+		w.WriteInt32(1)
+
+		r := NewReader(w.Bytes())
+		calls := 0
+		err := r.ReadMap(func(r *Reader) error {
+			calls++
+			return nil
+		})
+		if !errors.Is(err, ErrUnexpectedEOF) {
+			t.Errorf("expected ErrUnexpectedEOF, got %v", err)
+		}
+		if calls != 0 {
+			t.Errorf("fn called %d times for an impossible header", calls)
+		}
+	})
+
+	t.Run("data ends between entries", func(t *testing.T) {
+		w := NewWriter()
+		w.WriteMapHeader(3)
+		w.WriteUint8(1)
+		w.WriteUint8(10)
+		w.WriteUint8(2)
+
+		r := NewReader(w.Bytes())
+		calls := 0
+		err := r.ReadMap(func(r *Reader) error {
+			calls++
+			r.ReadUint8()
+			r.ReadUint8()
+			return nil
+		})
+		if !errors.Is(err, ErrUnexpectedEOF) {
+			t.Errorf("expected ErrUnexpectedEOF, got %v", err)
+		}
+		if calls != 2 {
+			t.Errorf("expected fn to stop after 2 calls, got %d", calls)
+		}
+		if r.Err() != err {
+			t.Errorf("ReadMap error should be recorded on the reader, got %v", r.Err())
+		}
+	})
+
+	t.Run("fn error", func(t *testing.T) {
+		w := NewWriter()
+		w.WriteMapHeader(1)
+		w.WriteUint8(1)
+		w.WriteUint8(2)
+
+		r := NewReader(w.Bytes())
+		err := r.ReadMap(func(r *Reader) error {
+			return ErrInvalidUTF8
+		})
+		if !errors.Is(err, ErrInvalidUTF8) {
+			t.Errorf("expected fn error, got %v", err)
+		}
+	})
 }
 
 func TestReaderLimits(t *testing.T) {
@@ -805,6 +1325,67 @@ func TestSubReader(t *testing.T) {
 	}
 }
 
+func TestReaderClone(t *testing.T) {
+	w := NewWriter()
+	w.WriteString("skip")
+	w.WriteInt64(-42)
+	w.WriteString("alpha")
+	w.WriteUint32(7)
+	data := w.BytesCopy()
+
+	r := NewReader(data)
+	r.ReadString()
+	clone := r.Clone()
+	if clone.Pos() != r.Pos() || clone.Generation() != 0 {
+		t.Errorf("clone at %d with generation %d, want %d and 0", clone.Pos(), clone.Generation(), r.Pos())
+	}
+
+	// Each reader advances on its own
+	if v := r.ReadInt64(); v != -42 {
+		t.Errorf("original ReadInt64 = %d, want -42", v)
+	}
+	if v := clone.ReadInt64(); v != -42 {
+		t.Errorf("clone ReadInt64 = %d, want -42", v)
+	}
+	if v := clone.ReadString(); v != "alpha" {
+		t.Errorf("clone ReadString = %q, want alpha", v)
+	}
+	if r.Pos() == clone.Pos() {
+		t.Error("reading from the clone moved the original")
+	}
+
+	// Errors and resets stay with the reader they happen on
+	clone.ReadUint32()
+	clone.ReadUint32()
+	if clone.Err() == nil || r.Err() != nil {
+		t.Errorf("clone error %v, original error %v: want only the clone to fail", clone.Err(), r.Err())
+	}
+	zc := r.ReadStringZeroCopy()
+	clone.Reset(data)
+	if !zc.Valid() || zc.String() != "alpha" {
+		t.Error("resetting the clone invalidated the original's zero-copy string")
+	}
+
+	// Clones decode the same buffer concurrently
+	base := NewReader(data)
+	base.ReadString()
+	var wg sync.WaitGroup
+	results := make([]int64, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(c *Reader) {
+			defer wg.Done()
+			results[i] = c.ReadInt64()
+		}(base.Clone())
+	}
+	wg.Wait()
+	for i, v := range results {
+		if v != -42 {
+			t.Errorf("goroutine %d read %d, want -42", i, v)
+		}
+	}
+}
+
 func TestReaderRemaining(t *testing.T) {
 	data := []byte{1, 2, 3, 4, 5}
 	r := NewReader(data)
@@ -815,6 +1396,56 @@ func TestReaderRemaining(t *testing.T) {
 	}
 }
 
+func TestReaderTee(t *testing.T) {
+	original := NestedStruct{ID: 9, Simple: &SimpleStruct{Name: "signed", Age: 30}}
+	data, err := Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	r := NewReader(data)
+	if fieldNum, _ := r.ReadCompactTag(); fieldNum != 1 {
+		t.Fatalf("expected field 1, got %d", fieldNum)
+	}
+	r.ReadInt64()
+	if fieldNum, _ := r.ReadCompactTag(); fieldNum != 2 {
+		t.Fatalf("expected field 2, got %d", fieldNum)
+	}
+
+	// Capture the nested message while decoding it
+	stop := r.Tee()
+	var nested SimpleStruct
+	if err := decodeValue(r, reflect.ValueOf(&nested).Elem()); err != nil {
+		t.Fatalf("decode nested error: %v", err)
+	}
+	raw := stop()
+
+	want, err := Marshal(original.Simple)
+	if err != nil {
+		t.Fatalf("Marshal nested error: %v", err)
+	}
+	if !bytes.Equal(raw, want) {
+		t.Errorf("captured %x, want %x", raw, want)
+	}
+
+	// The captured bytes decode on their own
+	var standalone SimpleStruct
+	if err := Unmarshal(raw, &standalone); err != nil {
+		t.Fatalf("Unmarshal captured bytes error: %v", err)
+	}
+	if standalone != *original.Simple {
+		t.Errorf("decoded %+v, want %+v", standalone, *original.Simple)
+	}
+
+	// A reset invalidates the recording
+	stop = r.Tee()
+	r.Reset([]byte{1, 2, 3})
+	r.Skip(2)
+	if got := stop(); got != nil {
+		t.Errorf("expected nil after Reset, got %x", got)
+	}
+}
+
 func TestReaderEOF(t *testing.T) {
 	r := NewReader([]byte{1, 2})
 	if r.EOF() {
@@ -847,6 +1478,35 @@ func TestReaderErrorSticky(t *testing.T) {
 	}
 }
 
+func TestReaderClearErr(t *testing.T) {
+	// A corrupt varint followed by a valid string
+	corrupt := bytes.Repeat([]byte{0xFF}, 11)
+	w := NewWriter()
+	w.WriteRawBytes(corrupt)
+	w.WriteString("ok")
+	r := NewReader(w.Bytes())
+	gen := r.Generation()
+
+	_ = r.ReadUvarint()
+	if r.Err() == nil {
+		t.Fatal("expected error reading corrupt varint")
+	}
+	pos := r.Pos()
+
+	r.ClearErr()
+	if r.Err() != nil {
+		t.Fatalf("Err() after ClearErr = %v", r.Err())
+	}
+	if r.Pos() != pos || r.Generation() != gen {
+		t.Errorf("ClearErr moved pos %d->%d or generation %d->%d", pos, r.Pos(), gen, r.Generation())
+	}
+
+	r.Skip(len(corrupt) - pos)
+	if got := r.ReadString(); got != "ok" || r.Err() != nil {
+		t.Errorf("ReadString after recovery = %q, %v", got, r.Err())
+	}
+}
+
 func TestReadWriteRoundTrip(t *testing.T) {
 	// Write a complex structure
 	w := NewWriter()
@@ -1604,3 +2264,90 @@ func BenchmarkReader(b *testing.B) {
 		}
 	})
 }
+
+// countingAllocator records the allocations made through it.
+type countingAllocator struct {
+	calls int
+	bytes int
+}
+
+func (a *countingAllocator) Alloc(n int) []byte {
+	a.calls++
+	a.bytes += n
+	return make([]byte, n)
+}
+
+func TestReaderAllocator(t *testing.T) {
+	w := NewWriter()
+	w.WriteString("hello")
+	w.WriteBytes([]byte{1, 2, 3})
+	w.WriteString("world")
+	w.WriteBytes([]byte{4, 5})
+	data := w.Bytes()
+
+	alloc := &countingAllocator{}
+	opts := DefaultOptions
+	opts.Allocator = alloc
+	r := NewReaderWithOptions(data, opts)
+
+	if s := r.ReadString(); s != "hello" {
+		t.Errorf("ReadString = %q, want %q", s, "hello")
+	}
+	if b := r.ReadBytes(); !bytes.Equal(b, []byte{1, 2, 3}) {
+		t.Errorf("ReadBytes = %v", b)
+	}
+	if alloc.calls != 2 || alloc.bytes != 8 {
+		t.Errorf("allocator got %d calls for %d bytes, want 2 calls for 8 bytes", alloc.calls, alloc.bytes)
+	}
+
+	// Zero-copy reads point into the input and don't allocate
+	if s := r.ReadStringZeroCopy(); s.String() != "world" {
+		t.Errorf("ReadStringZeroCopy = %q, want %q", s.String(), "world")
+	}
+	if b := r.ReadBytesNoCopy(); !bytes.Equal(b.Bytes(), []byte{4, 5}) {
+		t.Errorf("ReadBytesNoCopy = %v", b.Bytes())
+	}
+	if r.Err() != nil {
+		t.Fatalf("unexpected error: %v", r.Err())
+	}
+	if alloc.calls != 2 {
+		t.Errorf("zero-copy reads called the allocator: %d calls", alloc.calls)
+	}
+}
+
+func TestUnmarshalAllocator(t *testing.T) {
+	type Record struct {
+		Name string `cramberry:"1"`
+		Blob []byte `cramberry:"2"`
+	}
+	data, err := Marshal(Record{Name: "abc", Blob: []byte{9, 8}})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	alloc := &countingAllocator{}
+	opts := DefaultOptions
+	opts.Allocator = alloc
+
+	var got Record
+	if err := UnmarshalWithOptions(data, &got, opts); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if got.Name != "abc" || !bytes.Equal(got.Blob, []byte{9, 8}) {
+		t.Errorf("got %+v", got)
+	}
+	if alloc.calls != 2 || alloc.bytes != 5 {
+		t.Errorf("allocator got %d calls for %d bytes, want 2 calls for 5 bytes", alloc.calls, alloc.bytes)
+	}
+
+	// The stream reader draws on the allocator too
+	alloc = &countingAllocator{}
+	opts.Allocator = alloc
+	sr := NewStreamReaderWithOptions(bytes.NewReader([]byte{3, 'x', 'y', 'z'}), opts)
+	if s := sr.ReadString(); s != "xyz" {
+		t.Errorf("StreamReader.ReadString = %q, want %q", s, "xyz")
+	}
+	if alloc.calls != 1 {
+		t.Errorf("allocator got %d calls, want 1", alloc.calls)
+	}
+}
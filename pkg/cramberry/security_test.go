@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"math"
+	"runtime"
 	"testing"
 
 	"github.com/blockberries/cramberry/internal/wire"
@@ -324,6 +325,49 @@ func TestSecurityDepthLimiting(t *testing.T) {
 		}
 	})
 
+	t.Run("AsymmetricDepth", func(t *testing.T) {
+		type Nested struct {
+			Value int32   `cramberry:"1"`
+			Inner *Nested `cramberry:"2"`
+		}
+
+		root := &Nested{Value: 1}
+		current := root
+		for i := 0; i < 50; i++ {
+			current.Inner = &Nested{Value: int32(i + 2)}
+			current = current.Inner
+		}
+
+		// Generous encode depth, tight decode depth
+		opts := Options{
+			Limits: Limits{
+				MaxDepth:       10,
+				MaxEncodeDepth: 100,
+			},
+		}
+
+		data, err := MarshalWithOptions(root, opts)
+		if err != nil {
+			t.Fatalf("encode within MaxEncodeDepth failed: %v", err)
+		}
+
+		var result Nested
+		err = UnmarshalWithOptions(data, &result, opts)
+		if !errors.Is(err, ErrMaxDepthExceeded) {
+			t.Errorf("expected ErrMaxDepthExceeded on decode, got %v", err)
+		}
+
+		// MaxDecodeDepth overrides MaxDepth the same way
+		opts.Limits = Limits{MaxDepth: 100, MaxDecodeDepth: 10}
+		if _, err := MarshalWithOptions(root, opts); err != nil {
+			t.Fatalf("encode within MaxDepth failed: %v", err)
+		}
+		err = UnmarshalWithOptions(data, &result, opts)
+		if !errors.Is(err, ErrMaxDepthExceeded) {
+			t.Errorf("expected ErrMaxDepthExceeded with MaxDecodeDepth, got %v", err)
+		}
+	})
+
 	t.Run("DeepNestedSliceEncode", func(t *testing.T) {
 		// Test depth limiting on slices of structs
 		type Node struct {
@@ -395,6 +439,64 @@ func TestSecurityDepthLimiting(t *testing.T) {
 			t.Errorf("expected ErrMaxDepthExceeded, got %v", err)
 		}
 	})
+
+	t.Run("MapsOfMapsAtLimit", func(t *testing.T) {
+		// Every map level counts once, whether reached directly or as the
+		// value of another map, and the innermost one counts even when nil
+		const limit = 10
+		opts := Options{Limits: Limits{MaxDepth: limit}}
+
+		for _, innermost := range []depthMap{{}, nil} {
+			data, err := MarshalWithOptions(nestedDepthMap(limit, innermost), opts)
+			if err != nil {
+				t.Fatalf("encoding %d levels (innermost nil: %t): %v", limit, innermost == nil, err)
+			}
+			var got depthMap
+			if err := UnmarshalWithOptions(data, &got, opts); err != nil {
+				t.Errorf("decoding %d levels (innermost nil: %t): %v", limit, innermost == nil, err)
+			}
+
+			_, err = MarshalWithOptions(nestedDepthMap(limit+1, innermost), opts)
+			if !errors.Is(err, ErrMaxDepthExceeded) {
+				t.Errorf("encoding %d levels (innermost nil: %t): got %v, want ErrMaxDepthExceeded",
+					limit+1, innermost == nil, err)
+			}
+		}
+	})
+
+	t.Run("SlicesOfNilSlicesAtLimit", func(t *testing.T) {
+		const limit = 3
+		opts := Options{Limits: Limits{MaxDepth: limit}}
+
+		// Three levels of slices, the innermost nil, fit the limit
+		atLimit := [][][]string{{nil}}
+		data, err := MarshalWithOptions(atLimit, opts)
+		if err != nil {
+			t.Fatalf("encoding at the limit: %v", err)
+		}
+		var got [][][]string
+		if err := UnmarshalWithOptions(data, &got, opts); err != nil {
+			t.Errorf("decoding at the limit: %v", err)
+		}
+
+		overLimit := [][][][]string{{{nil}}}
+		if _, err := MarshalWithOptions(overLimit, opts); !errors.Is(err, ErrMaxDepthExceeded) {
+			t.Errorf("encoding over the limit: got %v, want ErrMaxDepthExceeded", err)
+		}
+	})
+}
+
+// depthMap is a map whose values are maps of the same type.
+type depthMap map[string]depthMap
+
+// nestedDepthMap returns levels maps nested as values of each other, with
+// innermost as the last level.
+func nestedDepthMap(levels int, innermost depthMap) depthMap {
+	m := innermost
+	for range levels - 1 {
+		m = depthMap{"k": m}
+	}
+	return m
 }
 
 // =============================================================================
@@ -549,6 +651,76 @@ func TestSecurityResourceLimits(t *testing.T) {
 		_ = err // Silence unused variable warning - test exercises code path
 	})
 
+	t.Run("MaxOutputSize", func(t *testing.T) {
+		type item struct {
+			ID   int64  `cramberry:"1"`
+			Name string `cramberry:"2"`
+		}
+		type batch struct {
+			Items []item `cramberry:"1"`
+		}
+		var b batch
+		for i := range 100 {
+			b.Items = append(b.Items, item{ID: int64(i), Name: "item"})
+		}
+		data, err := Marshal(b)
+		if err != nil {
+			t.Fatalf("Marshal error: %v", err)
+		}
+
+		// Every nested message is far below MaxMessageSize; only the
+		// total output is over the limit
+		opts := Options{
+			Limits: Limits{
+				MaxMessageSize: 1 << 20,
+				MaxOutputSize:  int64(len(data) / 2),
+			},
+		}
+		if _, err := MarshalWithOptions(b, opts); !errors.Is(err, ErrMaxOutputSize) {
+			t.Errorf("MarshalWithOptions error = %v, want ErrMaxOutputSize", err)
+		}
+
+		// Output of exactly the limit is accepted
+		opts.Limits.MaxOutputSize = 0
+		full, err := MarshalWithOptions(b, opts)
+		if err != nil {
+			t.Fatalf("MarshalWithOptions error: %v", err)
+		}
+		opts.Limits.MaxOutputSize = int64(len(full))
+		if _, err := MarshalWithOptions(b, opts); err != nil {
+			t.Errorf("MarshalWithOptions within MaxOutputSize failed: %v", err)
+		}
+		opts.Limits.MaxOutputSize = int64(len(full) - 1)
+		if _, err := MarshalWithOptions(b, opts); !errors.Is(err, ErrMaxOutputSize) {
+			t.Errorf("MarshalWithOptions one byte over: error = %v, want ErrMaxOutputSize", err)
+		}
+
+		// Varints, and length prefixes before their length is known, count
+		// the bytes they end up taking
+		w := NewWriterWithOptions(Options{Limits: Limits{MaxOutputSize: 5}})
+		w.WriteMessageFunc(func(w *Writer) {
+			w.WriteUvarint(1)
+			w.WriteSvarint(-1)
+			w.WriteCompactTag(1, WireTypeV2Varint)
+		})
+		w.WriteUvarint(0)
+		if w.Err() != nil || w.Len() != 5 {
+			t.Fatalf("writing exactly MaxOutputSize: Len() = %d, Err() = %v", w.Len(), w.Err())
+		}
+
+		// The limit covers everything written, across messages
+		w = NewWriterWithOptions(Options{Limits: Limits{MaxOutputSize: 8}})
+		w.WriteRawBytes([]byte("1234"))
+		w.WriteRawBytes([]byte("5678"))
+		if w.Err() != nil {
+			t.Fatalf("writing up to MaxOutputSize failed: %v", w.Err())
+		}
+		w.WriteRawBytes([]byte("9"))
+		if !errors.Is(w.Err(), ErrMaxOutputSize) {
+			t.Errorf("Err() = %v, want ErrMaxOutputSize", w.Err())
+		}
+	})
+
 	t.Run("MaxStringLength", func(t *testing.T) {
 		opts := Options{
 			Limits: Limits{
@@ -732,3 +904,125 @@ func TestSecurityEdgeCases(t *testing.T) {
 		}
 	})
 }
+
+// =============================================================================
+// SecureLimits Conformance
+// =============================================================================
+
+type secureBig struct {
+	Pad [256]int64 `cramberry:"1"`
+}
+
+type secureTarget struct {
+	Name  string           `cramberry:"1"`
+	Data  []byte           `cramberry:"2"`
+	IDs   []int64          `cramberry:"3"`
+	Items []secureBig      `cramberry:"4"`
+	Tags  map[string]int64 `cramberry:"5"`
+}
+
+type secureNode struct {
+	Children []secureNode `cramberry:"1"`
+}
+
+// allocatedBytes returns the number of bytes allocated while running fn.
+func allocatedBytes(fn func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	fn()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+// TestSecuritySecureLimits checks that decoding with SecureOptions rejects
+// hand-built payloads whose length prefixes promise far more data than they
+// carry, without allocating for the promised sizes.
+func TestSecuritySecureLimits(t *testing.T) {
+	// Well above anything a rejected payload should cost, and far below the
+	// sizes the payloads claim
+	const maxAlloc = 64 * 1024
+
+	field := func(num int, wt byte, body func(w *Writer)) []byte {
+		w := NewWriter()
+		w.WriteCompactTag(num, wt)
+		body(w)
+		return w.BytesCopy()
+	}
+
+	vectors := []struct {
+		name    string
+		payload []byte
+		want    error
+	}{
+		{"HugeString", field(1, WireTypeV2Bytes, func(w *Writer) { w.WriteUvarint(1 << 40) }), ErrMaxStringLength},
+		{"HugeBytes", field(2, WireTypeV2Bytes, func(w *Writer) { w.WriteUvarint(1 << 40) }), ErrMaxBytesLength},
+		{"HugePackedArray", field(3, WireTypeV2Bytes, func(w *Writer) { w.WriteUvarint(1 << 40) }), ErrMaxArrayLength},
+		{"HugeArray", field(4, WireTypeV2Bytes, func(w *Writer) { w.WriteUvarint(1 << 40) }), ErrMaxArrayLength},
+		{"HugeMap", field(5, WireTypeV2Bytes, func(w *Writer) { w.WriteUvarint(1 << 40) }), ErrMaxMapSize},
+
+		// Lengths within the limits that the payload can't back
+		{"ShortString", field(1, WireTypeV2Bytes, func(w *Writer) { w.WriteUvarint(uint64(SecureLimits.MaxStringLength)); w.WriteRawBytes([]byte("abc")) }), ErrUnexpectedEOF},
+		{"ShortBytes", field(2, WireTypeV2Bytes, func(w *Writer) { w.WriteUvarint(uint64(SecureLimits.MaxBytesLength)); w.WriteRawBytes([]byte("abc")) }), ErrUnexpectedEOF},
+		{"ShortPackedArray", field(3, WireTypeV2Bytes, func(w *Writer) { w.WriteArrayHeader(SecureLimits.MaxArrayLength); w.WriteInt64(1) }), ErrUnexpectedEOF},
+		{"ShortArray", field(4, WireTypeV2Bytes, func(w *Writer) { w.WriteArrayHeader(SecureLimits.MaxArrayLength); w.WriteEndMarker() }), ErrUnexpectedEOF},
+		{"ShortMap", field(5, WireTypeV2Bytes, func(w *Writer) { w.WriteMapHeader(SecureLimits.MaxMapSize); w.WriteString("k") }), ErrUnexpectedEOF},
+	}
+	for _, v := range vectors {
+		t.Run(v.name, func(t *testing.T) {
+			var err error
+			n := allocatedBytes(func() {
+				var target secureTarget
+				err = UnmarshalWithOptions(v.payload, &target, SecureOptions)
+			})
+			if !errors.Is(err, v.want) {
+				t.Errorf("error = %v, want %v", err, v.want)
+			}
+			if n > maxAlloc {
+				t.Errorf("rejecting a %d-byte payload allocated %d bytes", len(v.payload), n)
+			}
+		})
+	}
+
+	t.Run("HugeMessage", func(t *testing.T) {
+		w := NewWriter()
+		w.WriteUvarint(1 << 40)
+		r := NewReaderWithOptions(w.BytesCopy(), SecureOptions)
+		if end := r.BeginMessage(); end >= 0 || !errors.Is(r.Err(), ErrMaxSizeExceeded) {
+			t.Errorf("BeginMessage = %d, error %v, want ErrMaxSizeExceeded", end, r.Err())
+		}
+
+		w.Reset()
+		w.WriteUvarint(uint64(SecureLimits.MaxMessageSize))
+		r = NewReaderWithOptions(w.BytesCopy(), SecureOptions)
+		if end := r.BeginMessage(); end >= 0 || !errors.Is(r.Err(), ErrUnexpectedEOF) {
+			t.Errorf("BeginMessage = %d, error %v, want ErrUnexpectedEOF", end, r.Err())
+		}
+	})
+
+	t.Run("DepthBomb", func(t *testing.T) {
+		// Each level is a one-element Children list holding the next node
+		const levels = 10_000
+		w := NewWriter()
+		for range levels {
+			w.WriteCompactTag(1, WireTypeV2Bytes)
+			w.WriteArrayHeader(1)
+		}
+		for range levels + 1 {
+			w.WriteEndMarker()
+		}
+		payload := w.BytesCopy()
+
+		var err error
+		n := allocatedBytes(func() {
+			var root secureNode
+			err = UnmarshalWithOptions(payload, &root, SecureOptions)
+		})
+		if !errors.Is(err, ErrMaxDepthExceeded) {
+			t.Errorf("error = %v, want ErrMaxDepthExceeded", err)
+		}
+		if n > maxAlloc {
+			t.Errorf("rejecting the depth bomb allocated %d bytes", n)
+		}
+	})
+}
@@ -2,8 +2,11 @@ package cramberry
 
 import (
 	"bufio"
+	"encoding/json"
 	"io"
+	"reflect"
 	"sync"
+	"unsafe"
 
 	"github.com/blockberries/cramberry/internal/wire"
 )
@@ -21,6 +24,16 @@ type StreamWriter struct {
 	closed bool
 	// scratch is used for encoding varints without allocation
 	scratch [MaxVarintLen64]byte
+
+	// flushEvery and flushBytes are the auto-flush thresholds; zero disables.
+	flushEvery int
+	flushBytes int
+	// pending counts messages written since the last flush
+	pending int
+
+	// typeNames prefixes each WriteDelimited frame with the registered
+	// type name of the value.
+	typeNames bool
 }
 
 // streamWriterPool provides pooled writers for reduced allocations.
@@ -71,7 +84,9 @@ func PutStreamWriter(sw *StreamWriter) {
 	streamWriterPool.Put(sw)
 }
 
-// Reset resets the StreamWriter to write to a new io.Writer.
+// Reset resets the StreamWriter to write to a new io.Writer. The automatic
+// flush thresholds and type names are cleared, so a pooled writer doesn't
+// keep the settings of its previous user.
 func (sw *StreamWriter) Reset(w io.Writer) {
 	if sw.w == nil {
 		sw.w = bufio.NewWriterSize(w, 4096)
@@ -81,6 +96,10 @@ func (sw *StreamWriter) Reset(w io.Writer) {
 	sw.depth = 0
 	sw.err = nil
 	sw.closed = false
+	sw.pending = 0
+	sw.flushEvery = 0
+	sw.flushBytes = 0
+	sw.typeNames = false
 }
 
 // SetOptions updates the writer's options.
@@ -93,11 +112,38 @@ func (sw *StreamWriter) Options() Options {
 	return sw.opts
 }
 
+// SetFlushPolicy makes the writer flush automatically after every n messages
+// written with WriteMessage or WriteDelimited. Zero or a negative n disables
+// the message count threshold.
+func (sw *StreamWriter) SetFlushPolicy(everyN int) {
+	sw.flushEvery = max(everyN, 0)
+}
+
+// SetFlushBytes makes the writer flush automatically once at least n bytes
+// are buffered. The threshold is checked after each message, so a message is
+// never split across flushes by it. Zero or a negative n disables the byte
+// threshold. Independently of this setting, the buffer is still flushed
+// whenever it fills up.
+func (sw *StreamWriter) SetFlushBytes(n int) {
+	sw.flushBytes = max(n, 0)
+}
+
+// SetTypeNames makes WriteDelimited prefix each frame with the name the
+// value's type is registered under in DefaultRegistry, written as a
+// length-prefixed string. The stream then describes itself and can be read
+// back with StreamReader.ReadDelimitedTyped without knowing the order of
+// types in advance, which suits long-lived archives. Values of unregistered
+// types are rejected while it is enabled.
+func (sw *StreamWriter) SetTypeNames(enabled bool) {
+	sw.typeNames = enabled
+}
+
 // Flush writes any buffered data to the underlying writer.
 func (sw *StreamWriter) Flush() error {
 	if sw.err != nil {
 		return sw.err
 	}
+	sw.pending = 0
 	if err := sw.w.Flush(); err != nil {
 		sw.err = NewEncodeError("flush failed", err)
 		return sw.err
@@ -105,6 +151,18 @@ func (sw *StreamWriter) Flush() error {
 	return nil
 }
 
+// messageWritten applies the auto-flush thresholds after a complete message.
+func (sw *StreamWriter) messageWritten() {
+	if sw.err != nil {
+		return
+	}
+	sw.pending++
+	if (sw.flushEvery > 0 && sw.pending >= sw.flushEvery) ||
+		(sw.flushBytes > 0 && sw.w.Buffered() >= sw.flushBytes) {
+		_ = sw.Flush()
+	}
+}
+
 // Close flushes and releases resources.
 // The underlying io.Writer is not closed.
 func (sw *StreamWriter) Close() error {
@@ -403,19 +461,38 @@ func (sw *StreamWriter) WriteMessage(data []byte) {
 		return
 	}
 	sw.write(data)
+	sw.messageWritten()
 }
 
 // WriteDelimited writes a marshaled value with a length prefix.
 // This enables streaming multiple messages to the same writer.
+// If SetTypeNames is enabled, the frame starts with the type name.
 func (sw *StreamWriter) WriteDelimited(v any) error {
 	if !sw.checkWrite() {
 		return sw.err
 	}
+	var name string
+	if sw.typeNames {
+		if v == nil {
+			sw.setError(NewEncodeError("cannot name the type of a nil value", ErrUnregisteredType))
+			return sw.err
+		}
+		reg, ok := DefaultRegistry.LookupType(reflect.TypeOf(v))
+		if !ok {
+			err := NewEncodeError("unregistered type: "+reflect.TypeOf(v).String(), ErrUnregisteredType)
+			sw.setError(err)
+			return err
+		}
+		name = reg.Name
+	}
 	data, err := Marshal(v)
 	if err != nil {
 		sw.setError(err)
 		return err
 	}
+	if sw.typeNames {
+		sw.WriteString(name)
+	}
 	sw.WriteMessage(data)
 	return sw.err
 }
@@ -430,6 +507,8 @@ type StreamReader struct {
 	opts    Options
 	depth   int
 	err     error
+	inFrame bool // a frame has been started but not completely read
+	frames  int  // frames started so far, checked against Limits.MaxFrames
 	scratch [MaxVarintLen64]byte
 }
 
@@ -490,6 +569,8 @@ func (sr *StreamReader) Reset(r io.Reader) {
 	}
 	sr.depth = 0
 	sr.err = nil
+	sr.inFrame = false
+	sr.frames = 0
 }
 
 // SetOptions updates the reader's options.
@@ -526,7 +607,7 @@ func (sr *StreamReader) readFull(b []byte) bool {
 	}
 	_, err := io.ReadFull(sr.r, b)
 	if err != nil {
-		if err == io.EOF {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
 			sr.setError(ErrUnexpectedEOF)
 		} else {
 			sr.setError(NewDecodeError("read failed", err))
@@ -592,10 +673,17 @@ func (sr *StreamReader) ReadUvarint() uint64 {
 			}
 			return 0
 		}
-		if i == 9 && b > 1 {
-			// 10th byte can only be 0 or 1 for valid uint64
-			sr.setError(wire.ErrVarintOverflow)
-			return 0
+		if i == 9 {
+			// 10th byte can only be 0 or 1 for valid uint64; check the
+			// continuation bit first to match wire.DecodeUvarint.
+			if b >= 0x80 {
+				sr.setError(ErrVarintTooLong)
+				return 0
+			}
+			if b > 1 {
+				sr.setError(ErrVarintOverflow)
+				return 0
+			}
 		}
 		result |= uint64(b&0x7F) << shift
 		if b < 0x80 {
@@ -603,7 +691,7 @@ func (sr *StreamReader) ReadUvarint() uint64 {
 		}
 		shift += 7
 	}
-	sr.setError(wire.ErrVarintTooLong)
+	sr.setError(ErrVarintTooLong)
 	return 0
 }
 
@@ -761,11 +849,16 @@ func (sr *StreamReader) ReadString() string {
 		return ""
 	}
 	// Read string data
-	buf := make([]byte, n)
+	buf := allocBytes(sr.opts.Allocator, n)
 	if !sr.readFull(buf) {
 		return ""
 	}
-	s := string(buf)
+	var s string
+	if sr.opts.Allocator == nil {
+		s = string(buf)
+	} else if n > 0 {
+		s = unsafe.String(&buf[0], n)
+	}
 	// Validate UTF-8 if required
 	if sr.opts.ValidateUTF8 && !isValidUTF8(s) {
 		sr.setError(ErrInvalidUTF8)
@@ -791,7 +884,7 @@ func (sr *StreamReader) ReadBytes() []byte {
 		return nil
 	}
 	// Read byte data
-	buf := make([]byte, n)
+	buf := allocBytes(sr.opts.Allocator, n)
 	if !sr.readFull(buf) {
 		return nil
 	}
@@ -804,7 +897,7 @@ func (sr *StreamReader) ReadRawBytes(n int) []byte {
 		sr.setError(ErrNegativeLength)
 		return nil
 	}
-	buf := make([]byte, n)
+	buf := allocBytes(sr.opts.Allocator, n)
 	if !sr.readFull(buf) {
 		return nil
 	}
@@ -873,9 +966,44 @@ func (sr *StreamReader) ReadMapHeader() int {
 	return n
 }
 
+// AtFrameBoundary reports whether the reader is between two frames, that
+// is, whether every frame started by ReadMessage, ReadDelimited,
+// ReadDelimitedTyped or SkipMessage has been read completely. Together with
+// ErrUnexpectedEOF it tells a stream that ended cleanly after its last frame
+// apart from one that was cut off partway through a frame.
+func (sr *StreamReader) AtFrameBoundary() bool {
+	return !sr.inFrame
+}
+
+// beginFrame marks the start of a frame. At the end of the input it records
+// ErrUnexpectedEOF without leaving the frame boundary, since no byte of a
+// new frame has been consumed. A frame beyond Limits.MaxFrames records
+// ErrMaxFramesExceeded instead.
+func (sr *StreamReader) beginFrame() bool {
+	if !sr.checkRead() {
+		return false
+	}
+	if !sr.inFrame {
+		if _, err := sr.r.Peek(1); err == io.EOF {
+			sr.setError(ErrUnexpectedEOF)
+			return false
+		}
+		if limit := sr.opts.Limits.MaxFrames; limit > 0 && sr.frames >= limit {
+			sr.setError(ErrMaxFramesExceeded)
+			return false
+		}
+		sr.frames++
+		sr.inFrame = true
+	}
+	return true
+}
+
 // ReadMessage reads a length-prefixed message and returns the raw bytes.
 // This is useful for streaming multiple messages from the same reader.
 func (sr *StreamReader) ReadMessage() []byte {
+	if !sr.beginFrame() {
+		return nil
+	}
 	length := sr.ReadUvarint()
 	if sr.err != nil {
 		return nil
@@ -895,9 +1023,44 @@ func (sr *StreamReader) ReadMessage() []byte {
 	if !sr.readFull(buf) {
 		return nil
 	}
+	sr.inFrame = false
 	return buf
 }
 
+// PeekMessageLen returns the length of the next length-prefixed message
+// without consuming it, so that the caller can size a buffer or decide
+// whether to read the message with ReadMessage, skip it with SkipMessage,
+// or give up on the stream. It must be called between frames. The length
+// isn't checked against Limits.MaxMessageSize, which ReadMessage still
+// enforces.
+//
+// Errors are returned without being recorded in Err. At the end of the
+// stream, or if the stream ends inside the length prefix, the error is
+// ErrUnexpectedEOF.
+func (sr *StreamReader) PeekMessageLen() (int, error) {
+	if !sr.checkRead() {
+		return 0, sr.err
+	}
+	if sr.inFrame {
+		return 0, NewDecodeError("cannot peek a message length inside a frame", nil)
+	}
+	// Peek returns what is left along with an error if the stream ends
+	// within MaxVarintLen64 bytes; a short final message is still valid.
+	prefix, peekErr := sr.r.Peek(MaxVarintLen64)
+	length, _, err := wire.DecodeUvarint(prefix)
+	switch {
+	case err == wire.ErrVarintTruncated && peekErr == io.EOF:
+		return 0, ErrUnexpectedEOF
+	case err == wire.ErrVarintTruncated && peekErr != nil:
+		return 0, NewDecodeError("read failed", peekErr)
+	case err != nil:
+		return 0, err
+	case length > uint64(MaxInt):
+		return 0, ErrOverflow
+	}
+	return int(length), nil
+}
+
 // ReadDelimited reads a length-prefixed message and unmarshals it.
 // This enables streaming multiple messages from the same reader.
 func (sr *StreamReader) ReadDelimited(v any) error {
@@ -908,8 +1071,35 @@ func (sr *StreamReader) ReadDelimited(v any) error {
 	return Unmarshal(data, v)
 }
 
+// ReadDelimitedTyped reads a frame written by WriteDelimited with
+// SetTypeNames enabled. It resolves the type name through DefaultRegistry
+// and returns the name together with a pointer to a new decoded value of
+// that type. If the name is not registered, the frame is still consumed
+// and the name is returned along with an error wrapping ErrUnknownType, so
+// the caller can skip it and continue.
+func (sr *StreamReader) ReadDelimitedTyped() (name string, v any, err error) {
+	sr.beginFrame()
+	name = sr.ReadString()
+	data := sr.ReadMessage()
+	if sr.err != nil {
+		return "", nil, sr.err
+	}
+	reg, ok := DefaultRegistry.LookupName(name)
+	if !ok {
+		return name, nil, NewDecodeError("unknown type name: "+name, ErrUnknownType)
+	}
+	v = reflect.New(reg.Type).Interface()
+	if err := UnmarshalWithOptions(data, v, sr.opts); err != nil {
+		return name, nil, err
+	}
+	return name, v, nil
+}
+
 // SkipMessage skips a length-prefixed message without reading its contents.
 func (sr *StreamReader) SkipMessage() {
+	if !sr.beginFrame() {
+		return
+	}
 	length := sr.ReadUvarint()
 	if sr.err != nil {
 		return
@@ -923,7 +1113,9 @@ func (sr *StreamReader) SkipMessage() {
 	discarded, err := sr.r.Discard(n)
 	if err != nil || discarded < n {
 		sr.setError(NewDecodeError("skip message failed", err))
+		return
 	}
+	sr.inFrame = false
 }
 
 // Peek returns the next n bytes without advancing the reader.
@@ -944,6 +1136,7 @@ func (sr *StreamReader) Buffered() int {
 type MessageIterator struct {
 	reader *StreamReader
 	err    error
+	frame  []byte // message matched by SkipUntil
 }
 
 // NewMessageIterator creates an iterator for reading delimited messages.
@@ -953,30 +1146,115 @@ func NewMessageIterator(r io.Reader) *MessageIterator {
 	}
 }
 
+// NewMessageIteratorWithOptions creates an iterator that reads messages
+// with the given options. Limits.MaxFrames bounds the number of messages
+// the iterator will read; once it is reached, reading another message fails
+// with ErrMaxFramesExceeded.
+func NewMessageIteratorWithOptions(r io.Reader, opts Options) *MessageIterator {
+	return &MessageIterator{
+		reader: NewStreamReaderWithOptions(r, opts),
+	}
+}
+
+// fail records err as the iteration error unless it only means that the
+// stream ended cleanly between two messages. A stream cut off inside a
+// message, including inside its length prefix, is reported as
+// ErrUnexpectedEOF.
+func (it *MessageIterator) fail(err error) {
+	if it.reader.Err() == ErrUnexpectedEOF && it.reader.AtFrameBoundary() {
+		return
+	}
+	it.err = err
+}
+
 // Next reads the next message and returns true if successful.
-// Returns false on EOF or error.
+// Returns false at the end of the stream or on error; Err distinguishes
+// the two.
 func (it *MessageIterator) Next(v any) bool {
-	// Check for buffered data first
-	if it.reader.Buffered() == 0 {
-		// Try to peek to detect EOF
-		_, err := it.reader.Peek(1)
-		if err == io.EOF {
-			return false
-		}
+	if err := it.reader.ReadDelimited(v); err != nil {
+		it.fail(err)
+		return false
 	}
-	err := it.reader.ReadDelimited(v)
-	if err != nil {
-		if err == ErrUnexpectedEOF && it.reader.Buffered() == 0 {
-			// Clean EOF
+	return true
+}
+
+// SkipUntil reads messages until pred returns true for one, without
+// decoding the others. pred is given a Reader positioned at the start of each
+// message, from which it can read as many fields as it needs. The matching
+// message is then available via Value. SkipUntil returns false at the end of
+// the stream or on error.
+func (it *MessageIterator) SkipUntil(pred func(*Reader) bool) bool {
+	it.frame = nil
+	for {
+		data := it.reader.ReadMessage()
+		if err := it.reader.Err(); err != nil {
+			it.fail(err)
 			return false
 		}
-		it.err = err
-		return false
+		if pred(NewReaderWithOptions(data, it.reader.opts)) {
+			it.frame = data
+			return true
+		}
 	}
-	return true
+}
+
+// Value decodes the message matched by the last successful SkipUntil into v,
+// using the iterator's options.
+func (it *MessageIterator) Value(v any) error {
+	if it.frame == nil {
+		return NewDecodeError("no message matched by SkipUntil", nil)
+	}
+	return UnmarshalWithOptions(it.frame, v, it.reader.opts)
 }
 
 // Err returns any error that occurred during iteration.
 func (it *MessageIterator) Err() error {
 	return it.err
 }
+
+// ReadAllDelimited reads the length-delimited messages written to r by
+// StreamWriter.WriteDelimited until the stream ends cleanly, and returns
+// them decoded as values of type T. A stream holding more than maxMessages
+// messages fails with ErrMaxFramesExceeded, which bounds the memory a long
+// or hostile stream can use; maxMessages <= 0 reads without a limit. On
+// error, the messages decoded before it are returned along with it.
+func ReadAllDelimited[T any](r io.Reader, maxMessages int) ([]T, error) {
+	opts := DefaultOptions
+	opts.Limits.MaxFrames = max(maxMessages, 0)
+	it := NewMessageIteratorWithOptions(r, opts)
+	var values []T
+	for {
+		var v T
+		if !it.Next(&v) {
+			return values, it.Err()
+		}
+		values = append(values, v)
+	}
+}
+
+// StreamToJSONLines reads length-delimited messages from src and writes each
+// one to dst as a single line of JSON. proto is a value or pointer of the
+// message type carried by the stream; a fresh value of that type is decoded
+// for every message. Decoding stops at the first error, which is returned.
+// A clean end of stream returns nil.
+func StreamToJSONLines(dst io.Writer, src io.Reader, proto any) error {
+	if proto == nil {
+		return ErrNilPointer
+	}
+	typ := reflect.TypeOf(proto)
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	enc := json.NewEncoder(dst)
+	it := NewMessageIterator(src)
+	for {
+		v := reflect.New(typ).Interface()
+		if !it.Next(v) {
+			return it.Err()
+		}
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+}
@@ -2,7 +2,12 @@ package cramberry
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -321,6 +326,87 @@ func TestStreamDelimitedMessages(t *testing.T) {
 	}
 }
 
+type archiveOrder struct {
+	ID    int64  `cramberry:"1"`
+	Payer string `cramberry:"2"`
+}
+
+type archiveRefund struct {
+	OrderID int64 `cramberry:"1"`
+	Amount  int64 `cramberry:"2"`
+}
+
+func TestStreamDelimitedTyped(t *testing.T) {
+	DefaultRegistry.Clear()
+	defer DefaultRegistry.Clear()
+
+	RegisterOrGet[archiveOrder]()
+	RegisterOrGet[archiveRefund]()
+	orderName := typeName(reflect.TypeOf(archiveOrder{}))
+	refundName := typeName(reflect.TypeOf(archiveRefund{}))
+
+	values := []any{
+		&archiveOrder{ID: 1, Payer: "alice"},
+		&archiveRefund{OrderID: 1, Amount: 250},
+		&archiveOrder{ID: 2},
+	}
+	wantNames := []string{orderName, refundName, orderName}
+
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	sw.SetTypeNames(true)
+	for _, v := range values {
+		if err := sw.WriteDelimited(v); err != nil {
+			t.Fatalf("WriteDelimited(%+v) error: %v", v, err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+
+	sr := NewStreamReader(bytes.NewReader(buf.Bytes()))
+	for i, want := range values {
+		name, got, err := sr.ReadDelimitedTyped()
+		if err != nil {
+			t.Fatalf("ReadDelimitedTyped #%d error: %v", i, err)
+		}
+		if name != wantNames[i] {
+			t.Errorf("frame %d name = %q, want %q", i, name, wantNames[i])
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("frame %d = %#v, want %#v", i, got, want)
+		}
+	}
+
+	// Unregistered types are rejected while type names are enabled
+	type unregistered struct {
+		X int32 `cramberry:"1"`
+	}
+	sw = NewStreamWriter(io.Discard)
+	sw.SetTypeNames(true)
+	if err := sw.WriteDelimited(&unregistered{X: 1}); !errors.Is(err, ErrUnregisteredType) {
+		t.Errorf("WriteDelimited(unregistered) error = %v, want ErrUnregisteredType", err)
+	}
+
+	// Reset turns type names off again
+	sw.Reset(io.Discard)
+	if err := sw.WriteDelimited(&unregistered{X: 1}); err != nil {
+		t.Errorf("WriteDelimited(unregistered) after Reset error: %v", err)
+	}
+
+	// A name unknown to the reader is reported and its frame skipped
+	DefaultRegistry.Clear()
+	RegisterOrGet[archiveRefund]()
+	sr = NewStreamReader(bytes.NewReader(buf.Bytes()))
+	name, _, err := sr.ReadDelimitedTyped()
+	if name != orderName || !errors.Is(err, ErrUnknownType) {
+		t.Errorf("ReadDelimitedTyped = %q, %v; want %q, ErrUnknownType", name, err, orderName)
+	}
+	if name, _, err := sr.ReadDelimitedTyped(); err != nil || name != refundName {
+		t.Errorf("ReadDelimitedTyped after unknown frame = %q, %v", name, err)
+	}
+}
+
 func TestMessageIterator(t *testing.T) {
 	type Message struct {
 		ID int32 `cramberry:"1"`
@@ -358,6 +444,403 @@ func TestMessageIterator(t *testing.T) {
 	}
 }
 
+func TestMessageIteratorMaxFrames(t *testing.T) {
+	type Message struct {
+		ID int32 `cramberry:"1"`
+	}
+
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	for i := int32(1); i <= 5; i++ {
+		if err := sw.WriteDelimited(&Message{ID: i}); err != nil {
+			t.Fatalf("write delimited error: %v", err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+	stream := buf.Bytes()
+
+	opts := DefaultOptions
+	opts.Limits.MaxFrames = 3
+
+	it := NewMessageIteratorWithOptions(bytes.NewReader(stream), opts)
+	count := 0
+	var msg Message
+	for it.Next(&msg) {
+		count++
+	}
+	if count != 3 {
+		t.Errorf("Next read %d messages, want 3", count)
+	}
+	if !errors.Is(it.Err(), ErrMaxFramesExceeded) {
+		t.Errorf("Err() = %v, want ErrMaxFramesExceeded", it.Err())
+	}
+	if it.Next(&msg) {
+		t.Error("Next succeeded after the frame limit was reached")
+	}
+
+	// Skipped messages count towards the limit too
+	it = NewMessageIteratorWithOptions(bytes.NewReader(stream), opts)
+	if it.SkipUntil(func(*Reader) bool { return false }) {
+		t.Error("SkipUntil matched a message")
+	}
+	if !errors.Is(it.Err(), ErrMaxFramesExceeded) {
+		t.Errorf("SkipUntil: Err() = %v, want ErrMaxFramesExceeded", it.Err())
+	}
+
+	// A stream with exactly MaxFrames messages ends cleanly
+	opts.Limits.MaxFrames = 5
+	it = NewMessageIteratorWithOptions(bytes.NewReader(stream), opts)
+	count = 0
+	for it.Next(&msg) {
+		count++
+	}
+	if count != 5 || it.Err() != nil {
+		t.Errorf("read %d messages with err %v, want 5 and nil", count, it.Err())
+	}
+}
+
+func TestStreamReaderMaxFrames(t *testing.T) {
+	type Message struct {
+		ID int32 `cramberry:"1"`
+	}
+
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	for i := int32(1); i <= 4; i++ {
+		if err := sw.WriteDelimited(&Message{ID: i}); err != nil {
+			t.Fatalf("write delimited error: %v", err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+	stream := buf.Bytes()
+
+	opts := DefaultOptions
+	opts.Limits.MaxFrames = 3
+
+	// ReadMessage, ReadDelimited and SkipMessage all count towards the limit
+	sr := NewStreamReaderWithOptions(bytes.NewReader(stream), opts)
+	if data := sr.ReadMessage(); data == nil {
+		t.Fatalf("ReadMessage error: %v", sr.Err())
+	}
+	var msg Message
+	if err := sr.ReadDelimited(&msg); err != nil || msg.ID != 2 {
+		t.Fatalf("ReadDelimited = %+v, %v", msg, err)
+	}
+	sr.SkipMessage()
+	if err := sr.ReadDelimited(&msg); !errors.Is(err, ErrMaxFramesExceeded) {
+		t.Errorf("ReadDelimited past the limit error = %v, want ErrMaxFramesExceeded", err)
+	}
+
+	sr = NewStreamReaderWithOptions(bytes.NewReader(stream), opts)
+	for range 3 {
+		sr.SkipMessage()
+	}
+	if data := sr.ReadMessage(); data != nil || !errors.Is(sr.Err(), ErrMaxFramesExceeded) {
+		t.Errorf("ReadMessage past the limit = %v, %v, want ErrMaxFramesExceeded", data, sr.Err())
+	}
+
+	// Reset starts the count again
+	sr.Reset(bytes.NewReader(stream))
+	if err := sr.ReadDelimited(&msg); err != nil || msg.ID != 1 {
+		t.Errorf("ReadDelimited after Reset = %+v, %v", msg, err)
+	}
+
+	// The end of a stream within the limit is not a limit error
+	sr = NewStreamReaderWithOptions(bytes.NewReader(stream[:len(stream)/2]), opts)
+	for sr.ReadMessage() != nil {
+	}
+	if !errors.Is(sr.Err(), ErrUnexpectedEOF) || !sr.AtFrameBoundary() {
+		t.Errorf("end of stream: err = %v, at boundary %v", sr.Err(), sr.AtFrameBoundary())
+	}
+}
+
+func TestReadAllDelimited(t *testing.T) {
+	type Entry struct {
+		Seq  int64  `cramberry:"1"`
+		Text string `cramberry:"2"`
+	}
+
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	want := []Entry{{1, "start"}, {2, "work"}, {3, "stop"}}
+	for i := range want {
+		if err := sw.WriteDelimited(&want[i]); err != nil {
+			t.Fatalf("write delimited error: %v", err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+	stream := buf.Bytes()
+
+	for _, limit := range []int{0, 3, 10} {
+		got, err := ReadAllDelimited[Entry](bytes.NewReader(stream), limit)
+		if err != nil || !slices.Equal(got, want) {
+			t.Errorf("ReadAllDelimited(max %d) = %v, %v, want %v", limit, got, err, want)
+		}
+	}
+
+	got, err := ReadAllDelimited[Entry](bytes.NewReader(stream), 2)
+	if !errors.Is(err, ErrMaxFramesExceeded) || !slices.Equal(got, want[:2]) {
+		t.Errorf("ReadAllDelimited(max 2) = %v, %v, want the first 2 entries and ErrMaxFramesExceeded", got, err)
+	}
+
+	got, err = ReadAllDelimited[Entry](bytes.NewReader(stream[:len(stream)-1]), 0)
+	if !errors.Is(err, ErrUnexpectedEOF) || !slices.Equal(got, want[:2]) {
+		t.Errorf("ReadAllDelimited(truncated) = %v, %v, want the first 2 entries and ErrUnexpectedEOF", got, err)
+	}
+
+	if got, err := ReadAllDelimited[Entry](bytes.NewReader(nil), 1); err != nil || len(got) != 0 {
+		t.Errorf("ReadAllDelimited(empty) = %v, %v, want no entries", got, err)
+	}
+}
+
+func TestMessageIteratorSkipUntil(t *testing.T) {
+	type LogEntry struct {
+		Seq     int64  `cramberry:"1"`
+		Level   int32  `cramberry:"2"`
+		Message string `cramberry:"3"`
+	}
+	const levelError = 3
+
+	entries := []LogEntry{
+		{Seq: 1, Level: 1, Message: "starting"},
+		{Seq: 2, Level: 2, Message: "slow disk"},
+		{Seq: 3, Level: levelError, Message: "disk failed"},
+		{Seq: 4, Level: 1, Message: "retrying"},
+		{Seq: 5, Level: levelError, Message: "shutdown"},
+	}
+
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	for i := range entries {
+		if err := sw.WriteDelimited(&entries[i]); err != nil {
+			t.Fatalf("write delimited error: %v", err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+
+	// Only the level field is read from each message
+	visited := 0
+	isLevel := func(level int32) func(*Reader) bool {
+		return func(r *Reader) bool {
+			visited++
+			for {
+				fieldNum, wireType := r.ReadCompactTag()
+				if fieldNum == 0 {
+					return false
+				}
+				if fieldNum == 2 {
+					return r.ReadInt32() == level
+				}
+				r.SkipValueV2(wireType)
+			}
+		}
+	}
+
+	it := NewMessageIterator(&buf)
+	var got []int64
+	for it.SkipUntil(isLevel(levelError)) {
+		var entry LogEntry
+		if err := it.Value(&entry); err != nil {
+			t.Fatalf("Value error: %v", err)
+		}
+		got = append(got, entry.Seq)
+	}
+	if it.Err() != nil {
+		t.Fatalf("iterator error: %v", it.Err())
+	}
+	if len(got) != 2 || got[0] != 3 || got[1] != 5 {
+		t.Errorf("matched messages %v, want [3 5]", got)
+	}
+	if visited != len(entries) {
+		t.Errorf("predicate saw %d messages, want %d", visited, len(entries))
+	}
+	if err := it.Value(&LogEntry{}); err == nil {
+		t.Error("expected an error from Value after SkipUntil returned false")
+	}
+
+	// Value decodes with the iterator's options
+	var data bytes.Buffer
+	sw = NewStreamWriter(&data)
+	if err := sw.WriteDelimited(&entries[2]); err != nil {
+		t.Fatalf("write delimited error: %v", err)
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+	opts := DefaultOptions
+	opts.Limits.MaxStringLength = 4
+	it = NewMessageIteratorWithOptions(&data, opts)
+	if !it.SkipUntil(isLevel(levelError)) {
+		t.Fatalf("SkipUntil found no message: %v", it.Err())
+	}
+	if err := it.Value(&LogEntry{}); !errors.Is(err, ErrMaxStringLength) {
+		t.Errorf("Value error = %v, want ErrMaxStringLength", err)
+	}
+
+	// A stream cut off inside a message is an error
+	buf.Reset()
+	sw = NewStreamWriter(&buf)
+	if err := sw.WriteDelimited(&entries[0]); err != nil {
+		t.Fatalf("write delimited error: %v", err)
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+	truncated := buf.Bytes()[:buf.Len()-2]
+	it = NewMessageIterator(bytes.NewReader(truncated))
+	if it.SkipUntil(isLevel(levelError)) || it.Err() == nil {
+		t.Errorf("SkipUntil on truncated stream: err = %v, want an error", it.Err())
+	}
+}
+
+func TestMessageIteratorTruncation(t *testing.T) {
+	type Message struct {
+		ID   int32  `cramberry:"1"`
+		Body string `cramberry:"2"`
+	}
+
+	// Bodies over 127 bytes give each frame a two-byte length prefix
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	for i := int32(1); i <= 2; i++ {
+		if err := sw.WriteDelimited(&Message{ID: i, Body: strings.Repeat("x", 200)}); err != nil {
+			t.Fatalf("write delimited error: %v", err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+	stream := buf.Bytes()
+	first := len(stream) / 2
+
+	tests := []struct {
+		name    string
+		cut     int
+		want    int
+		wantErr error
+	}{
+		{"empty", 0, 0, nil},
+		{"between frames", first, 1, nil},
+		{"complete", len(stream), 2, nil},
+		{"inside length prefix", first + 1, 1, ErrUnexpectedEOF},
+		{"inside body", len(stream) - 1, 1, ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := stream[:tt.cut]
+
+			it := NewMessageIterator(bytes.NewReader(data))
+			count := 0
+			var msg Message
+			for it.Next(&msg) {
+				count++
+			}
+			if count != tt.want || !errors.Is(it.Err(), tt.wantErr) {
+				t.Errorf("Next read %d messages with err %v, want %d and %v", count, it.Err(), tt.want, tt.wantErr)
+			}
+
+			it = NewMessageIterator(bytes.NewReader(data))
+			count = 0
+			for it.SkipUntil(func(*Reader) bool { return true }) {
+				count++
+			}
+			if count != tt.want || !errors.Is(it.Err(), tt.wantErr) {
+				t.Errorf("SkipUntil matched %d messages with err %v, want %d and %v", count, it.Err(), tt.want, tt.wantErr)
+			}
+
+			sr := NewStreamReader(bytes.NewReader(data))
+			for range tt.want {
+				sr.SkipMessage()
+			}
+			if !sr.AtFrameBoundary() {
+				t.Error("AtFrameBoundary() = false after complete frames")
+			}
+			sr.ReadMessage()
+			if sr.Err() == nil || sr.AtFrameBoundary() != (tt.wantErr == nil) {
+				t.Errorf("after reading past the cut: err = %v, AtFrameBoundary() = %v", sr.Err(), sr.AtFrameBoundary())
+			}
+		})
+	}
+}
+
+func TestStreamToJSONLines(t *testing.T) {
+	type LogEntry struct {
+		Level   string `cramberry:"1" json:"level"`
+		Message string `cramberry:"2" json:"message"`
+		Seq     int64  `cramberry:"3" json:"seq"`
+	}
+
+	entries := []LogEntry{
+		{Level: "info", Message: "starting", Seq: 1},
+		{Level: "warn", Message: "slow disk", Seq: 2},
+		{Level: "error", Message: "shutdown", Seq: 3},
+	}
+
+	var src bytes.Buffer
+	sw := NewStreamWriter(&src)
+	for i := range entries {
+		if err := sw.WriteDelimited(&entries[i]); err != nil {
+			t.Fatalf("write delimited error: %v", err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+
+	var dst bytes.Buffer
+	if err := StreamToJSONLines(&dst, &src, LogEntry{}); err != nil {
+		t.Fatalf("StreamToJSONLines error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(dst.String(), "\n"), "\n")
+	if len(lines) != len(entries) {
+		t.Fatalf("expected %d lines, got %d: %q", len(entries), len(lines), dst.String())
+	}
+	for i, line := range lines {
+		var got LogEntry
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("line %d: invalid JSON %q: %v", i, line, err)
+		}
+		if got != entries[i] {
+			t.Errorf("line %d: expected %+v, got %+v", i, entries[i], got)
+		}
+	}
+}
+
+func TestStreamToJSONLinesDecodeError(t *testing.T) {
+	type LogEntry struct {
+		Message string `cramberry:"1"`
+	}
+
+	var src bytes.Buffer
+	sw := NewStreamWriter(&src)
+	if err := sw.WriteDelimited(&LogEntry{Message: "ok"}); err != nil {
+		t.Fatalf("write delimited error: %v", err)
+	}
+	// Length prefix claims more bytes than follow
+	sw.WriteUvarint(10)
+	sw.WriteRawBytes([]byte{0x01})
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+
+	var dst bytes.Buffer
+	if err := StreamToJSONLines(&dst, &src, &LogEntry{}); err == nil {
+		t.Fatal("expected decode error")
+	}
+	if n := strings.Count(dst.String(), "\n"); n != 1 {
+		t.Errorf("expected 1 line before the error, got %d", n)
+	}
+}
+
 func TestStreamWriterClose(t *testing.T) {
 	var buf bytes.Buffer
 	sw := NewStreamWriter(&buf)
@@ -480,6 +963,26 @@ func TestStreamWriterReset(t *testing.T) {
 	}
 }
 
+func TestStreamWriterResetClearsFlushPolicy(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(io.Discard)
+	sw.SetFlushPolicy(1)
+	sw.SetFlushBytes(1)
+	sw.Reset(&buf)
+	for i := 0; i < 3; i++ {
+		sw.WriteMessage([]byte{1, 2, 3})
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no auto-flush after Reset, got %d bytes", buf.Len())
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	if buf.Len() != 12 {
+		t.Errorf("expected 12 bytes after Flush, got %d", buf.Len())
+	}
+}
+
 func TestStreamReaderSkipMessage(t *testing.T) {
 	type Message struct {
 		ID int32 `cramberry:"1"`
@@ -547,6 +1050,77 @@ func TestStreamWriterBufferSize(t *testing.T) {
 	}
 }
 
+func TestStreamWriterFlushPolicy(t *testing.T) {
+	type msg struct {
+		ID int32 `cramberry:"1"`
+	}
+
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	sw.SetFlushPolicy(3)
+
+	var sizes []int
+	for i := 1; i <= 7; i++ {
+		if err := sw.WriteDelimited(&msg{ID: int32(i)}); err != nil {
+			t.Fatalf("WriteDelimited error: %v", err)
+		}
+		sizes = append(sizes, buf.Len())
+	}
+
+	// Each message is 4 bytes: length prefix, tag, value, end marker
+	want := []int{0, 0, 12, 12, 12, 24, 24}
+	for i := range want {
+		if sizes[i] != want[i] {
+			t.Errorf("after message %d: underlying writer has %d bytes, want %d", i+1, sizes[i], want[i])
+		}
+	}
+
+	// A manual flush resets the count
+	sw.Flush()
+	sw.WriteDelimited(&msg{ID: 8})
+	sw.WriteDelimited(&msg{ID: 9})
+	if buf.Len() != 28 {
+		t.Errorf("expected no auto-flush two messages after a manual flush, got %d bytes", buf.Len())
+	}
+	sw.WriteDelimited(&msg{ID: 10})
+	if buf.Len() != 40 {
+		t.Errorf("expected auto-flush on the third message, got %d bytes", buf.Len())
+	}
+}
+
+func TestStreamWriterFlushBytes(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	sw.SetFlushBytes(10)
+
+	// 1 length byte + 3 payload bytes per message
+	payload := []byte{1, 2, 3}
+	sw.WriteMessage(payload)
+	sw.WriteMessage(payload)
+	if buf.Len() != 0 {
+		t.Fatalf("expected nothing flushed below the threshold, got %d bytes", buf.Len())
+	}
+	sw.WriteMessage(payload)
+	if buf.Len() != 12 {
+		t.Fatalf("expected flush once 12 bytes were buffered, got %d bytes", buf.Len())
+	}
+
+	// Disabling the threshold goes back to explicit flushing
+	sw.SetFlushBytes(0)
+	for i := 0; i < 5; i++ {
+		sw.WriteMessage(payload)
+	}
+	if buf.Len() != 12 {
+		t.Errorf("expected no auto-flush when disabled, got %d bytes", buf.Len())
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	if buf.Len() != 32 {
+		t.Errorf("expected 32 bytes after Flush, got %d", buf.Len())
+	}
+}
+
 func TestStreamReaderPeek(t *testing.T) {
 	var buf bytes.Buffer
 	sw := NewStreamWriter(&buf)
@@ -571,6 +1145,54 @@ func TestStreamReaderPeek(t *testing.T) {
 	}
 }
 
+func TestStreamReaderPeekMessageLen(t *testing.T) {
+	small := []byte{1, 2, 3}
+	large := bytes.Repeat([]byte{0xab}, 300) // two-byte length prefix
+
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	sw.WriteMessage(small)
+	sw.WriteMessage(large)
+	sw.Flush()
+
+	sr := NewStreamReader(&buf)
+	for _, want := range [][]byte{small, large} {
+		// Peeking repeatedly leaves the stream where it was
+		for range 2 {
+			n, err := sr.PeekMessageLen()
+			if err != nil {
+				t.Fatalf("peek error: %v", err)
+			}
+			if n != len(want) {
+				t.Fatalf("PeekMessageLen() = %d, want %d", n, len(want))
+			}
+		}
+		if got := sr.ReadMessage(); !bytes.Equal(got, want) {
+			t.Fatalf("ReadMessage after peeking returned %d bytes, want %d", len(got), len(want))
+		}
+	}
+
+	if _, err := sr.PeekMessageLen(); !errors.Is(err, ErrUnexpectedEOF) {
+		t.Errorf("peek at end of stream: got %v, want ErrUnexpectedEOF", err)
+	}
+	if sr.Err() != nil || !sr.AtFrameBoundary() {
+		t.Errorf("peeking at the end recorded an error: %v", sr.Err())
+	}
+
+	// A length prefix cut off by the end of the stream
+	sr = NewStreamReader(bytes.NewReader([]byte{0x80}))
+	if _, err := sr.PeekMessageLen(); !errors.Is(err, ErrUnexpectedEOF) {
+		t.Errorf("truncated prefix: got %v, want ErrUnexpectedEOF", err)
+	}
+
+	// Inside a frame there is no length to peek
+	sr = NewStreamReader(bytes.NewReader([]byte{3, 1, 2, 3}))
+	sr.beginFrame()
+	if _, err := sr.PeekMessageLen(); err == nil {
+		t.Error("expected an error when peeking inside a frame")
+	}
+}
+
 func BenchmarkStreamWriter(b *testing.B) {
 	var buf bytes.Buffer
 	sw := NewStreamWriter(&buf)
@@ -676,3 +1298,52 @@ func TestStreamReaderSlowSource(t *testing.T) {
 		t.Fatalf("unexpected error: %v", sr.Err())
 	}
 }
+
+func TestVarintErrorsMatchReader(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", []byte{}, ErrUnexpectedEOF},
+		{"truncated 1 byte", []byte{0x80}, ErrUnexpectedEOF},
+		{"truncated 9 bytes", bytes.Repeat([]byte{0xFF}, 9), ErrUnexpectedEOF},
+		{"10th byte overflow", append(bytes.Repeat([]byte{0xFF}, 9), 0x02), ErrVarintOverflow},
+		{"10th byte max overflow", append(bytes.Repeat([]byte{0xFF}, 9), 0x7F), ErrVarintOverflow},
+		{"10th byte continuation", append(bytes.Repeat([]byte{0xFF}, 9), 0x81), ErrVarintTooLong},
+		{"11 bytes", append(bytes.Repeat([]byte{0x80}, 10), 0x00), ErrVarintTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(tt.data)
+			r.ReadUvarint()
+			if !errors.Is(r.Err(), tt.want) {
+				t.Errorf("Reader: expected %v, got %v", tt.want, r.Err())
+			}
+
+			ri := NewReader(tt.data)
+			ri.ReadUvarintInline()
+			if !errors.Is(ri.Err(), tt.want) {
+				t.Errorf("Reader inline: expected %v, got %v", tt.want, ri.Err())
+			}
+
+			sr := NewStreamReader(bytes.NewReader(tt.data))
+			sr.ReadUvarint()
+			if !errors.Is(sr.Err(), tt.want) {
+				t.Errorf("StreamReader: expected %v, got %v", tt.want, sr.Err())
+			}
+		})
+	}
+
+	// The largest valid uint64 decodes identically.
+	maxVarint := append(bytes.Repeat([]byte{0xFF}, 9), 0x01)
+	r := NewReader(maxVarint)
+	sr := NewStreamReader(bytes.NewReader(maxVarint))
+	if got := r.ReadUvarint(); got != ^uint64(0) || r.Err() != nil {
+		t.Errorf("Reader: expected max uint64, got %d (%v)", got, r.Err())
+	}
+	if got := sr.ReadUvarint(); got != ^uint64(0) || sr.Err() != nil {
+		t.Errorf("StreamReader: expected max uint64, got %d (%v)", got, sr.Err())
+	}
+}
@@ -119,10 +119,27 @@ type Limits struct {
 	// A value of 0 means no limit.
 	MaxMessageSize int64
 
-	// MaxDepth is the maximum nesting depth for structs/slices/maps.
+	// MaxOutputSize is the maximum number of bytes a Writer may hold,
+	// counting everything written since it was created or reset, however
+	// many messages that spans. Each write is checked against the bytes it
+	// adds, so output of exactly MaxOutputSize bytes is accepted.
+	// A value of 0 means no limit.
+	MaxOutputSize int64
+
+	// MaxDepth is the maximum nesting depth for structs/slices/maps,
+	// applied to both encoding and decoding unless overridden by
+	// MaxEncodeDepth or MaxDecodeDepth.
 	// A value of 0 means no limit.
 	MaxDepth int
 
+	// MaxEncodeDepth, when non-zero, replaces MaxDepth for encoding.
+	MaxEncodeDepth int
+
+	// MaxDecodeDepth, when non-zero, replaces MaxDepth for decoding.
+	// Only decode input comes from outside the process, so this is the
+	// limit to tighten when accepting untrusted data.
+	MaxDecodeDepth int
+
 	// MaxStringLength is the maximum length of a string in bytes.
 	// A value of 0 means no limit.
 	MaxStringLength int
@@ -138,6 +155,27 @@ type Limits struct {
 	// MaxMapSize is the maximum number of entries in a map.
 	// A value of 0 means no limit.
 	MaxMapSize int
+
+	// MaxFrames is the maximum number of length-delimited messages a
+	// StreamReader or MessageIterator reads from one stream.
+	// A value of 0 means no limit.
+	MaxFrames int
+}
+
+// encodeDepth returns the nesting limit that applies to encoding.
+func (l Limits) encodeDepth() int {
+	if l.MaxEncodeDepth != 0 {
+		return l.MaxEncodeDepth
+	}
+	return l.MaxDepth
+}
+
+// decodeDepth returns the nesting limit that applies to decoding.
+func (l Limits) decodeDepth() int {
+	if l.MaxDecodeDepth != 0 {
+		return l.MaxDecodeDepth
+	}
+	return l.MaxDepth
 }
 
 // DefaultLimits are the default resource limits.
@@ -165,6 +203,67 @@ var SecureLimits = Limits{
 // Use with caution - only for trusted input.
 var NoLimits = Limits{}
 
+// WarningCode identifies the kind of condition reported by a Warning.
+type WarningCode uint8
+
+const (
+	// WarningUnknownField reports a field that the decoder skipped because
+	// the target type does not declare it.
+	WarningUnknownField WarningCode = iota + 1
+
+	// WarningDeprecatedField reports the presence of a field marked as
+	// deprecated.
+	WarningDeprecatedField
+)
+
+// String returns the name of the warning code.
+func (c WarningCode) String() string {
+	switch c {
+	case WarningUnknownField:
+		return "UnknownField"
+	case WarningDeprecatedField:
+		return "DeprecatedField"
+	default:
+		return "Unknown"
+	}
+}
+
+// Warning describes a non-fatal condition found while decoding.
+type Warning struct {
+	// Code identifies the kind of condition.
+	Code WarningCode
+
+	// FieldNum is the number of the field involved.
+	FieldNum int
+
+	// Pos is the reader position after the field's tag.
+	Pos int
+
+	// Message is a human-readable description.
+	Message string
+}
+
+// String formats the warning for logging.
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: field %d at position %d: %s", w.Code, w.FieldNum, w.Pos, w.Message)
+}
+
+// Hooks holds callbacks that Options refers to by pointer, so that Options
+// stays comparable with ==. A nil Hooks or a nil callback costs nothing.
+type Hooks struct {
+	// OnWarning, when set, is called for non-fatal conditions found while
+	// decoding, such as skipped unknown fields. Decoding continues after
+	// the callback returns.
+	OnWarning func(Warning)
+
+	// OnField, when set, is called by the reflective encoder after each
+	// field of the outermost struct is written, with the field number and
+	// the bytes the field took on the wire, tag included. Omitted fields
+	// are not reported, so the sizes add up to the encoded message less its
+	// end marker. Generated encoders don't call it.
+	OnField func(fieldNum int, encodedBytes int)
+}
+
 // Options configures encoding/decoding behavior.
 type Options struct {
 	// Limits specifies resource limits.
@@ -173,6 +272,13 @@ type Options struct {
 	// StrictMode rejects unknown fields during decoding.
 	StrictMode bool
 
+	// ValidateOnDecode makes Unmarshal call the Validate method of the
+	// decoded value, when it has one, and return its error. Generated
+	// messages with required or bounded fields have such a method, so a
+	// value outside a field's min and max fails to decode instead of having
+	// to be checked afterwards. Nested messages are not validated.
+	ValidateOnDecode bool
+
 	// ValidateUTF8 validates that strings are valid UTF-8.
 	ValidateUTF8 bool
 
@@ -188,6 +294,74 @@ type Options struct {
 	// This is enabled by default for reproducible encoding.
 	// Disable for better performance when determinism is not required.
 	Deterministic bool
+
+	// OmitTopLevelEndMarker drops the end marker of the outermost struct,
+	// saving one byte per message when the caller already frames messages
+	// by length. Nested structs keep their markers.
+	//
+	// When decoding, this option makes end of input at a field boundary
+	// terminate the outermost struct; a nested struct cut off there is still
+	// reported as ErrUnexpectedEOF, as is any missing end marker without the
+	// option. Data written with this option can therefore only be
+	// read by decoders that enable it too, and because a message truncated
+	// between two fields is indistinguishable from a complete one, the
+	// framing must guarantee that the whole message is present.
+	OmitTopLevelEndMarker bool
+
+	// Hooks, when set, holds callbacks for events during encoding and
+	// decoding.
+	Hooks *Hooks
+
+	// Allocator, when set, supplies the storage for strings and byte slices
+	// that decoding copies out of the input. Zero-copy reads don't use it.
+	// A nil Allocator allocates with make.
+	Allocator Allocator
+
+	// CollectStats makes Readers and Writers count the fields they process
+	// and the deepest nesting they reach, reported with the bytes they
+	// consumed or produced by their Stats methods. Without it nothing is
+	// counted.
+	CollectStats bool
+}
+
+// Stats describes the work done by a Reader or Writer with
+// Options.CollectStats set, since it was created or last reset.
+type Stats struct {
+	// Bytes is the number of bytes read or written.
+	Bytes int
+
+	// Fields is the number of field tags read or written. End markers are
+	// not counted.
+	Fields int
+
+	// MaxCheckedDepth is the deepest nesting counted against
+	// Limits.MaxDepth: the messages and collections of the reflective
+	// codec, and messages framed with BeginMessage. Generated EncodeTo and
+	// DecodeFrom methods of messages ended by an end marker don't check
+	// their depth, so their nesting is not counted.
+	MaxCheckedDepth int
+}
+
+// Allocator provides backing storage for decoded strings and byte slices,
+// letting applications route decode allocations through an arena or an
+// accounting allocator.
+//
+// Alloc must return a slice of length n that nothing else uses. Decoded
+// strings are built over that storage without copying, so it must not be
+// modified or reused while they are in use.
+type Allocator interface {
+	Alloc(n int) []byte
+}
+
+// allocBytes returns n bytes of storage from a, or from make if a is nil or
+// returns too little.
+func allocBytes(a Allocator, n int) []byte {
+	if a != nil {
+		if b := a.Alloc(n); len(b) >= n {
+			return b[:n:n]
+		}
+	}
+	return make([]byte, n)
 }
 
 // DefaultOptions are the default encoding/decoding options.
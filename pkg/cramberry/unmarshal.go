@@ -6,6 +6,10 @@ import (
 
 // Unmarshal decodes cramberry binary data into a Go value.
 // The target must be a non-nil pointer to the value to decode into.
+//
+// If the target points to an interface, the data must start with a type ID,
+// as written by Marshal(&shape). The registered concrete type is allocated,
+// decoded and assigned to the interface.
 func Unmarshal(data []byte, v any) error {
 	return UnmarshalWithOptions(data, v, DefaultOptions)
 }
@@ -24,7 +28,63 @@ func UnmarshalWithOptions(data []byte, v any, opts Options) error {
 	if err := decodeValue(r, rv.Elem()); err != nil {
 		return err
 	}
-	return r.Err()
+	if r.Err() != nil || !opts.ValidateOnDecode {
+		return r.Err()
+	}
+	return validateDecoded(rv)
+}
+
+// UnmarshalPresence decodes data into v like Unmarshal and also returns the
+// numbers of the fields of the outermost struct that were present in data,
+// unknown fields included. Since zero values are usually left off the wire,
+// this tells a field that was sent as zero from one that was not sent at
+// all, without making the field a pointer, which is what partial updates
+// need. On error present is nil.
+func UnmarshalPresence(data []byte, v any) (present map[int]bool, err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return nil, ErrNotPointer
+	}
+	if rv.IsNil() {
+		return nil, ErrNilPointer
+	}
+
+	r := NewReader(data)
+	r.present = make(map[int]bool)
+	if err := decodeValue(r, rv.Elem()); err != nil {
+		return nil, err
+	}
+	if r.Err() != nil {
+		return nil, r.Err()
+	}
+	return r.present, nil
+}
+
+// validateDecoded calls the Validate method of the value rv points to, or
+// of the value held by the pointer or interface rv points to.
+func validateDecoded(rv reflect.Value) error {
+	target := rv.Interface()
+	if elem := rv.Elem(); (elem.Kind() == reflect.Interface || elem.Kind() == reflect.Ptr) && !elem.IsNil() {
+		target = elem.Interface()
+	}
+	if v, ok := target.(interface{ Validate() error }); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// UnmarshalT is a typed form of Unmarshal that decodes data into a new
+// value of type T and returns it. T may be a struct, a pointer, or an
+// interface whose implementations are registered, in which case the result
+// holds the concrete type named by the type ID. On error the zero T is
+// returned.
+func UnmarshalT[T any](data []byte) (T, error) {
+	var v T
+	if err := Unmarshal(data, &v); err != nil {
+		var zero T
+		return zero, err
+	}
+	return v, nil
 }
 
 // decodeValue decodes a value from the reader into the reflect.Value.
@@ -79,6 +139,9 @@ func decodeValue(r *Reader, v reflect.Value) error {
 	case reflect.Map:
 		return decodeMap(r, v)
 	case reflect.Struct:
+		if isOrderedMapType(v.Type()) {
+			return v.Addr().Interface().(orderedMapDecoder).decodeEntries(r)
+		}
 		return decodeStruct(r, v)
 	case reflect.Interface:
 		return decodeInterface(r, v)
@@ -88,20 +151,10 @@ func decodeValue(r *Reader, v reflect.Value) error {
 	return r.Err()
 }
 
-// decodePointer decodes a pointer value.
+// decodePointer decodes a pointer value, which is encoded as the value it
+// points to. Nil pointers are left off or, in collections, marked as nil;
+// see decodeElement.
 func decodePointer(r *Reader, v reflect.Value) error {
-	// Peek at the first byte to check for nil
-	if r.Len() == 0 {
-		return r.Err()
-	}
-
-	// Check if this is a nil value (TypeIDNil encoded as varint 0)
-	if r.Remaining()[0] == 0 {
-		r.Skip(1) // Consume the nil marker
-		v.SetZero()
-		return r.Err()
-	}
-
 	// Allocate if needed
 	if v.IsNil() {
 		v.Set(reflect.New(v.Type().Elem()))
@@ -110,6 +163,27 @@ func decodePointer(r *Reader, v reflect.Value) error {
 	return decodeValue(r, v.Elem())
 }
 
+// decodeElement decodes an element of a slice, array or map. Pointer
+// elements start with the markers written by encodeElement, one for each
+// level, and are left nil at the level whose marker says so.
+func decodeElement(r *Reader, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr {
+		isNil := r.ReadNil()
+		if r.Err() != nil {
+			return r.Err()
+		}
+		if isNil {
+			v.SetZero()
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return decodeValue(r, v)
+}
+
 // decodeSlice decodes a slice value.
 func decodeSlice(r *Reader, v reflect.Value) error {
 	// Use packed decoding for primitive types (no depth tracking needed for primitives)
@@ -137,7 +211,7 @@ func decodeSlice(r *Reader, v reflect.Value) error {
 	slice := reflect.MakeSlice(v.Type(), n, n)
 
 	for i := 0; i < n; i++ {
-		if err := decodeValue(r, slice.Index(i)); err != nil {
+		if err := decodeElement(r, slice.Index(i)); err != nil {
 			return err
 		}
 	}
@@ -220,7 +294,7 @@ func decodeArray(r *Reader, v reflect.Value) error {
 	}
 
 	for i := 0; i < n; i++ {
-		if err := decodeValue(r, v.Index(i)); err != nil {
+		if err := decodeElement(r, v.Index(i)); err != nil {
 			return err
 		}
 	}
@@ -314,7 +388,7 @@ func decodeMap(r *Reader, v reflect.Value) error {
 		}
 
 		elem := reflect.New(elemType).Elem()
-		if err := decodeValue(r, elem); err != nil {
+		if err := decodeElement(r, elem); err != nil {
 			return err
 		}
 
@@ -333,7 +407,10 @@ func decodeStruct(r *Reader, v reflect.Value) error {
 	}
 	defer r.exitNested()
 
-	info := getStructInfo(v.Type())
+	info, err := getStructInfo(v.Type())
+	if err != nil {
+		return err
+	}
 
 	// Track which fields were set (for required field checking)
 	fieldsSeen := make(map[int]bool)
@@ -349,6 +426,9 @@ func decodeStruct(r *Reader, v reflect.Value) error {
 		if fieldNum == 0 {
 			break
 		}
+		if r.present != nil && r.depth == 1 {
+			r.present[fieldNum] = true
+		}
 
 		fi, ok := info.fieldByNum[fieldNum]
 		if !ok {
@@ -356,13 +436,23 @@ func decodeStruct(r *Reader, v reflect.Value) error {
 			if r.Options().StrictMode {
 				return NewFieldDecodeError(v.Type().Name(), "", fieldNum, r.Pos(), "unknown field", ErrUnknownField)
 			}
+			if r.warns() {
+				r.Warn(WarningUnknownField, fieldNum, "skipped unknown field of "+v.Type().String())
+			}
 			r.SkipValueV2(wireType)
 			continue
 		}
+		if fi.deprecated && r.warns() {
+			r.Warn(WarningDeprecatedField, fieldNum, "deprecated field "+fi.name+" is present")
+		}
 
 		fieldsSeen[fieldNum] = true
 		fv := v.Field(fi.index)
 
+		if fi.optional {
+			fv.Field(optionalSetIndex).SetBool(true)
+			fv = fv.Field(optionalValueIndex)
+		}
 		if err := decodeValue(r, fv); err != nil {
 			return err
 		}
@@ -383,8 +473,22 @@ func decodeInterface(r *Reader, v reflect.Value) error {
 	return decodeInterfaceWithRegistry(r, v, DefaultRegistry)
 }
 
-// decodeInterfaceWithRegistry decodes an interface value using the specified registry.
+// decodeInterfaceWithRegistry decodes an interface value using the specified
+// registry. Values of type any are length-prefixed, as ReadAny reads them.
 func decodeInterfaceWithRegistry(r *Reader, v reflect.Value, reg *Registry) error {
+	if v.Type().NumMethod() != 0 {
+		return decodeInterfaceValue(r, v, reg)
+	}
+	end := r.BeginMessage()
+	if err := decodeInterfaceValue(r, v, reg); err != nil {
+		return err
+	}
+	r.EndMessage(end)
+	return r.Err()
+}
+
+// decodeInterfaceValue decodes the type ID and value of an interface.
+func decodeInterfaceValue(r *Reader, v reflect.Value, reg *Registry) error {
 	// Read the type ID
 	typeID := r.ReadTypeID()
 	if r.Err() != nil {
@@ -404,6 +508,9 @@ func decodeInterfaceWithRegistry(r *Reader, v reflect.Value, reg *Registry) erro
 
 	// Create a new instance of the concrete type
 	newVal := reflect.New(registration.Type)
+	if !newVal.Type().AssignableTo(v.Type()) {
+		return NewDecodeError(registration.Type.String()+" does not implement "+v.Type().String(), ErrTypeMismatch)
+	}
 
 	// Decode into the new value
 	if err := decodeValue(r, newVal.Elem()); err != nil {
@@ -415,30 +522,90 @@ func decodeInterfaceWithRegistry(r *Reader, v reflect.Value, reg *Registry) erro
 	return r.Err()
 }
 
-// Size returns the encoded size of a value without actually encoding it.
-// This can be used to pre-allocate buffers.
+// ReadAny reads a value written by WriteAny. It returns a pointer to a new
+// value of the type registered in DefaultRegistry under the decoded type ID,
+// or nil for TypeIDNil or on error. Types with a DecodeFrom method decode
+// themselves; others are decoded by reflection.
+func (r *Reader) ReadAny() any {
+	end := r.BeginMessage()
+	if end < 0 {
+		return nil
+	}
+	v := r.readAnyValue()
+	r.EndMessage(end)
+	if r.err != nil {
+		return nil
+	}
+	return v
+}
+
+// readAnyValue reads the type ID and value of a value for ReadAny.
+func (r *Reader) readAnyValue() any {
+	typeID := r.ReadTypeID()
+	if r.err != nil || typeID == TypeIDNil {
+		return nil
+	}
+
+	v, ok := DefaultRegistry.NewValue(typeID)
+	if !ok {
+		r.setError(NewDecodeError("unknown type ID: "+typeID.String(), ErrUnknownType))
+		return nil
+	}
+
+	if dec, ok := v.(interface{ DecodeFrom(*Reader) }); ok {
+		dec.DecodeFrom(r)
+	} else if err := decodeValue(r, reflect.ValueOf(v).Elem()); err != nil {
+		r.setError(err)
+	}
+	if r.err != nil {
+		return nil
+	}
+	return v
+}
+
+// Size returns the number of bytes Marshal produces for a value without
+// actually encoding it. This can be used to pre-allocate buffers.
 func Size(v any) int {
 	return SizeWithOptions(v, DefaultOptions)
 }
 
 // SizeWithOptions returns the encoded size with the specified options.
 func SizeWithOptions(v any, opts Options) int {
-	return sizeValue(reflect.ValueOf(v), opts)
+	rv := reflect.ValueOf(v)
+	n := sizeValue(rv, opts)
+	if opts.OmitTopLevelEndMarker {
+		for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+			rv = rv.Elem()
+		}
+		if rv.Kind() == reflect.Struct {
+			n--
+		}
+	}
+	return n
 }
 
 // sizeValue calculates the encoded size of a reflect.Value.
+// It mirrors encodeValue, so the result equals the length of Marshal's output.
 func sizeValue(v reflect.Value, opts Options) int {
 	// Handle nil interface or invalid values
 	if !v.IsValid() {
 		return 1 // nil marker
 	}
 
+	// Interfaces are prefixed with the type ID of their concrete value
+	if v.Kind() == reflect.Interface {
+		return sizeInterface(v, opts)
+	}
+
 	// Dereference pointers
-	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+	for v.Kind() == reflect.Ptr {
 		if v.IsNil() {
 			return 1 // nil marker
 		}
 		v = v.Elem()
+		if v.Kind() == reflect.Interface {
+			return sizeInterface(v, opts)
+		}
 	}
 
 	switch v.Kind() {
@@ -478,12 +645,37 @@ func sizeValue(v reflect.Value, opts Options) int {
 	case reflect.Map:
 		return sizeMap(v, opts)
 	case reflect.Struct:
+		if isOrderedMapType(v.Type()) {
+			return v.Interface().(orderedMapCodec).entriesSize(opts)
+		}
 		return sizeStruct(v, opts)
 	default:
 		return 0
 	}
 }
 
+// sizeInterface calculates the encoded size of an interface value,
+// including its type ID as looked up in DefaultRegistry and, for values of
+// type any, its length prefix.
+func sizeInterface(v reflect.Value, opts Options) int {
+	if v.Type().NumMethod() != 0 {
+		return sizeInterfaceValue(v, opts)
+	}
+	n := sizeInterfaceValue(v, opts)
+	return SizeOfUvarint(uint64(n)) + n
+}
+
+// sizeInterfaceValue calculates the encoded size of the type ID and value of
+// an interface.
+func sizeInterfaceValue(v reflect.Value, opts Options) int {
+	if v.IsNil() {
+		return SizeOfUvarint(uint64(TypeIDNil))
+	}
+	elem := v.Elem()
+	typeID := DefaultRegistry.TypeIDFor(elem.Interface())
+	return SizeOfUvarint(uint64(typeID)) + sizeValue(elem, opts)
+}
+
 func sizeSlice(v reflect.Value, opts Options) int {
 	if v.IsNil() {
 		return SizeOfUvarint(0)
@@ -491,7 +683,7 @@ func sizeSlice(v reflect.Value, opts Options) int {
 	n := v.Len()
 	size := SizeOfUvarint(uint64(n))
 	for i := 0; i < n; i++ {
-		size += sizeValue(v.Index(i), opts)
+		size += sizeElement(v.Index(i), opts)
 	}
 	return size
 }
@@ -500,7 +692,7 @@ func sizeArray(v reflect.Value, opts Options) int {
 	n := v.Len()
 	size := SizeOfUvarint(uint64(n))
 	for i := 0; i < n; i++ {
-		size += sizeValue(v.Index(i), opts)
+		size += sizeElement(v.Index(i), opts)
 	}
 	return size
 }
@@ -514,19 +706,43 @@ func sizeMap(v reflect.Value, opts Options) int {
 	iter := v.MapRange()
 	for iter.Next() {
 		size += sizeValue(iter.Key(), opts)
-		size += sizeValue(iter.Value(), opts)
+		size += sizeElement(iter.Value(), opts)
 	}
 	return size
 }
 
+// sizeElement calculates the encoded size of a slice, array or map element,
+// including the markers that precede each level of a pointer element.
+func sizeElement(v reflect.Value, opts Options) int {
+	size := 0
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return size + 1 // nil marker
+		}
+		size++ // present marker
+		v = v.Elem()
+	}
+	return size + sizeValue(v, opts)
+}
+
 // sizeStruct calculates the encoded size of a struct.
 func sizeStruct(v reflect.Value, opts Options) int {
-	info := getStructInfo(v.Type())
+	info, err := getStructInfo(v.Type())
+	if err != nil {
+		return 0 // Marshal reports the error
+	}
 
 	size := 0
 	for _, field := range info.fields {
 		fv := v.Field(field.index)
-		if opts.OmitEmpty && isZeroValue(fv) {
+		if field.optional {
+			if !fv.Field(optionalSetIndex).Bool() {
+				continue
+			}
+			fv = fv.Field(optionalValueIndex)
+		} else if opts.OmitEmpty && isZeroValue(fv) {
+			continue
+		} else if fv.Kind() == reflect.Ptr && fv.IsNil() {
 			continue
 		}
 		// Compact tag size + value size
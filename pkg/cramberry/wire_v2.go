@@ -159,6 +159,9 @@ func (w *Writer) WriteCompactTag(fieldNum int, wireType byte) {
 		w.setError(ErrInvalidFieldNumber)
 		return
 	}
+	if w.opts.CollectStats {
+		w.stats.Fields++
+	}
 
 	if fieldNum <= maxCompactFieldNum {
 		// Compact format: single byte
@@ -170,7 +173,7 @@ func (w *Writer) WriteCompactTag(fieldNum int, wireType byte) {
 
 	// Extended format: marker byte + varint field number
 	marker := (wireType << tagWireTypeShift) | tagExtendedBit
-	w.grow(1 + MaxVarintLen64)
+	w.grow(1 + SizeOfUvarint(uint64(fieldNum)))
 	w.buf = append(w.buf, marker)
 
 	// Write varint field number
@@ -192,9 +195,21 @@ func (w *Writer) WriteEndMarker() {
 }
 
 // ReadCompactTag reads a compact tag from the reader.
-// Returns fieldNum=0 for end marker or on error.
+// Returns fieldNum=0 for end marker or on error. End of input is an error
+// unless OmitTopLevelEndMarker is set, in which case it acts as the end
+// marker of the outermost message.
 func (r *Reader) ReadCompactTag() (fieldNum int, wireType byte) {
-	if r.err != nil || r.pos >= len(r.data) {
+	if r.err != nil {
+		return 0, 0
+	}
+	if r.pos >= len(r.data) {
+		// Only one message can end at end of input. If a nested message
+		// does, its parent is left without an end and decoding fails.
+		if r.opts.OmitTopLevelEndMarker && !r.eofEnded {
+			r.eofEnded = true
+		} else {
+			r.setError(ErrUnexpectedEOF)
+		}
 		return 0, 0
 	}
 
@@ -210,6 +225,9 @@ func (r *Reader) ReadCompactTag() (fieldNum int, wireType byte) {
 
 	if tag&tagExtendedBit == 0 {
 		// Compact format
+		if r.opts.CollectStats {
+			r.stats.Fields++
+		}
 		fieldNum = int(tag >> tagFieldNumShift)
 		return fieldNum, wireType
 	}
@@ -236,6 +254,9 @@ func (r *Reader) ReadCompactTag() (fieldNum int, wireType byte) {
 
 		fieldNum |= int(b&0x7F) << shift
 		if b < 0x80 {
+			if r.opts.CollectStats {
+				r.stats.Fields++
+			}
 			return fieldNum, wireType
 		}
 		shift += 7
@@ -2,6 +2,7 @@ package cramberry
 
 import (
 	"bytes"
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -528,3 +529,141 @@ func TestDeterministicOption(t *testing.T) {
 		}
 	}
 }
+
+func TestSortedMapKeys(t *testing.T) {
+	if got := SortedMapKeys(map[string]int{"z": 1, "a": 2, "m": 3}); !reflect.DeepEqual(got, []string{"a", "m", "z"}) {
+		t.Errorf("string keys = %v", got)
+	}
+
+	type Level int32
+	if got := SortedMapKeys(map[Level]bool{3: true, -1: false, 0: true}); !reflect.DeepEqual(got, []Level{-1, 0, 3}) {
+		t.Errorf("named integer keys = %v", got)
+	}
+	if got := SortedMapKeys(map[bool]string{true: "y", false: "n"}); !reflect.DeepEqual(got, []bool{false, true}) {
+		t.Errorf("bool keys = %v", got)
+	}
+	if got := SortedMapKeys(map[string]int(nil)); len(got) != 0 {
+		t.Errorf("nil map keys = %v", got)
+	}
+
+	// The order matches the reflective encoder's
+	m := map[uint16]string{500: "a", 2: "b", 70: "c"}
+	w := NewWriter()
+	w.WriteMapHeader(len(m))
+	for _, k := range SortedMapKeys(m) {
+		w.WriteUint16(k)
+		w.WriteString(m[k])
+	}
+	want, err := Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if !bytes.Equal(w.Bytes(), want) {
+		t.Errorf("got %x, want %x", w.Bytes(), want)
+	}
+}
+
+func TestForEachMapEntry(t *testing.T) {
+	m := map[string]int{"z": 1, "a": 2, "m": 3, "q": 4, "c": 5}
+
+	var keys []string
+	ForEachMapEntry(NewWriter(), m, func(k string, v int) {
+		if m[k] != v {
+			t.Errorf("ForEachMapEntry passed %q with %d, want %d", k, v, m[k])
+		}
+		keys = append(keys, k)
+	})
+	if want := []string{"a", "c", "m", "q", "z"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("deterministic keys = %v, want %v", keys, want)
+	}
+
+	// Without Deterministic every entry is visited, in map order
+	seen := make(map[string]int)
+	ForEachMapEntry(NewWriterWithOptions(FastOptions), m, func(k string, v int) {
+		seen[k] = v
+	})
+	if !reflect.DeepEqual(seen, m) {
+		t.Errorf("visited %v, want %v", seen, m)
+	}
+}
+
+func TestOmitTopLevelEndMarker(t *testing.T) {
+	type Inner struct {
+		X int32 `cramberry:"1"`
+	}
+	type Outer struct {
+		Name  string `cramberry:"1"`
+		Inner Inner  `cramberry:"2"`
+	}
+
+	original := Outer{Name: "a", Inner: Inner{X: 3}}
+
+	opts := DefaultOptions
+	opts.OmitTopLevelEndMarker = true
+
+	full, err := Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	short, err := MarshalWithOptions(original, opts)
+	if err != nil {
+		t.Fatalf("MarshalWithOptions error: %v", err)
+	}
+
+	// Only the outermost marker is dropped
+	if len(short) != len(full)-1 || !bytes.Equal(short, full[:len(full)-1]) {
+		t.Fatalf("expected %x without its final byte, got %x", full, short)
+	}
+	if short[len(short)-1] != EndMarker {
+		t.Error("nested struct should keep its end marker")
+	}
+	if got := SizeWithOptions(original, opts); got != len(short) {
+		t.Errorf("SizeWithOptions = %d, want %d", got, len(short))
+	}
+
+	t.Run("decode without marker", func(t *testing.T) {
+		var decoded Outer
+		if err := UnmarshalWithOptions(short, &decoded, opts); err != nil {
+			t.Fatalf("Unmarshal error: %v", err)
+		}
+		if decoded != original {
+			t.Errorf("got %+v, want %+v", decoded, original)
+		}
+	})
+
+	t.Run("decode with marker", func(t *testing.T) {
+		var decoded Outer
+		if err := UnmarshalWithOptions(full, &decoded, opts); err != nil {
+			t.Fatalf("Unmarshal error: %v", err)
+		}
+		if decoded != original {
+			t.Errorf("got %+v, want %+v", decoded, original)
+		}
+	})
+
+	t.Run("missing marker rejected by default", func(t *testing.T) {
+		var decoded Outer
+		err := Unmarshal(short, &decoded)
+		if !errors.Is(err, ErrUnexpectedEOF) {
+			t.Errorf("expected ErrUnexpectedEOF, got %v", err)
+		}
+	})
+
+	t.Run("truncated nested message rejected", func(t *testing.T) {
+		var decoded Outer
+		err := UnmarshalWithOptions(short[:len(short)-1], &decoded, opts)
+		if !errors.Is(err, ErrUnexpectedEOF) {
+			t.Errorf("expected ErrUnexpectedEOF, got %v", err)
+		}
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		var decoded Outer
+		if err := UnmarshalWithOptions(nil, &decoded, opts); err != nil {
+			t.Errorf("Unmarshal error with the option: %v", err)
+		}
+		if err := Unmarshal(nil, &decoded); !errors.Is(err, ErrUnexpectedEOF) {
+			t.Errorf("expected ErrUnexpectedEOF without the option, got %v", err)
+		}
+	})
+}
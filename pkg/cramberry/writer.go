@@ -3,6 +3,7 @@ package cramberry
 import (
 	"math"
 	"sync"
+	"unsafe"
 
 	"github.com/blockberries/cramberry/internal/wire"
 )
@@ -17,14 +18,20 @@ type Writer struct {
 	opts   Options
 	depth  int
 	err    error
-	frozen bool // prevents further writes after Bytes() is called
+	frozen bool  // prevents further writes after Bytes() is called
+	stats  Stats // counted only with Options.CollectStats
+
+	// slack is the part of the length placeholders of open BeginMessage
+	// frames that EndMessage may remove, which doesn't count toward
+	// MaxOutputSize
+	slack int
 }
 
 // writerPool provides pooled writers for reduced allocations.
 var writerPool = sync.Pool{
 	New: func() any {
 		return &Writer{
-			buf:  make([]byte, 0, 256),
+			buf:  make([]byte, 0, pooledWriterSize(writerPoolConfig.Load())),
 			opts: DefaultOptions,
 		}
 	},
@@ -55,34 +62,60 @@ func NewWriterWithBuffer(buf []byte, opts Options) *Writer {
 	}
 }
 
-// GetWriter gets a Writer from the pool.
+// GetWriter gets a Writer with DefaultOptions from the pool.
 // The Writer should be returned with PutWriter when done.
 func GetWriter() *Writer {
 	w := writerPool.Get().(*Writer)
 	w.Reset()
+	// Writers pooled before the initial or adaptive size was raised are
+	// grown up front
+	if size := pooledWriterSize(writerPoolConfig.Load()); cap(w.buf) < size {
+		w.buf = make([]byte, 0, size)
+	}
 	return w
 }
 
 // PutWriter returns a Writer to the pool.
 // The Writer must not be used after calling this.
+//
+// The writer's options are restored to DefaultOptions, so settings such as
+// Deterministic or ValidateUTF8 chosen by one user of the pool are not
+// inherited by the next.
+//
+// Writers whose buffer grew past WriterPoolConfig.MaxPooledSize are kept in
+// a separate large-buffer pool, which GetWriterWithHint draws from for large
+// size hints. Buffers beyond MaxLargeSize are left to the garbage collector.
 func PutWriter(w *Writer) {
 	if w == nil {
 		return
 	}
-	// Don't pool large buffers to avoid memory bloat
-	if cap(w.buf) > 64*1024 {
+	cfg := writerPoolConfig.Load()
+	c := cap(w.buf)
+	if c > cfg.maxPooled() && c > cfg.MaxLargeSize {
+		// Don't pool huge buffers to avoid memory bloat
 		return
 	}
+	if adaptiveSizing.Load() && len(w.buf) > 0 {
+		recordWriterSize(len(w.buf))
+	}
 	w.Reset()
+	// Options set with SetOptions must not carry over to the next user
+	w.opts = DefaultOptions
+	if c > cfg.maxPooled() {
+		largeWriterPool.Put(w)
+		return
+	}
 	writerPool.Put(w)
 }
 
-// Reset clears the writer for reuse.
+// Reset clears the writer for reuse, keeping its options.
 func (w *Writer) Reset() {
 	w.buf = w.buf[:0]
 	w.depth = 0
 	w.err = nil
 	w.frozen = false
+	w.stats = Stats{}
+	w.slack = 0
 }
 
 // SetOptions updates the writer's options.
@@ -95,6 +128,18 @@ func (w *Writer) Options() Options {
 	return w.opts
 }
 
+// Stats returns the bytes, fields and nesting depth written since the
+// writer was created or last reset. It returns zero Stats unless the
+// writer's options set CollectStats.
+func (w *Writer) Stats() Stats {
+	if !w.opts.CollectStats {
+		return Stats{}
+	}
+	s := w.stats
+	s.Bytes = len(w.buf)
+	return s
+}
+
 // Len returns the current length of the encoded data.
 func (w *Writer) Len() int {
 	return len(w.buf)
@@ -145,8 +190,13 @@ func (w *Writer) checkWrite() bool {
 	return true
 }
 
-// grow ensures the buffer has room for n more bytes.
+// grow ensures the buffer has room for n more bytes, which the caller is
+// about to write.
 func (w *Writer) grow(n int) {
+	if limit := w.opts.Limits.MaxOutputSize; limit > 0 && int64(len(w.buf)-w.slack+n) > limit {
+		w.setError(ErrMaxOutputSize)
+		return
+	}
 	if len(w.buf)+n <= cap(w.buf) {
 		return
 	}
@@ -171,11 +221,14 @@ func (w *Writer) grow(n int) {
 
 // enterNested increases the nesting depth and checks limits.
 func (w *Writer) enterNested() bool {
-	if w.opts.Limits.MaxDepth > 0 && w.depth >= w.opts.Limits.MaxDepth {
+	if limit := w.opts.Limits.encodeDepth(); limit > 0 && w.depth >= limit {
 		w.setError(ErrMaxDepthExceeded)
 		return false
 	}
 	w.depth++
+	if w.opts.CollectStats && w.depth > w.stats.MaxCheckedDepth {
+		w.stats.MaxCheckedDepth = w.depth
+	}
 	return true
 }
 
@@ -262,7 +315,7 @@ func (w *Writer) WriteUvarint(v uint64) {
 	if !w.checkWrite() {
 		return
 	}
-	w.grow(MaxVarintLen64)
+	w.grow(SizeOfUvarint(v))
 	w.buf = wire.AppendUvarint(w.buf, v)
 }
 
@@ -271,7 +324,7 @@ func (w *Writer) WriteSvarint(v int64) {
 	if !w.checkWrite() {
 		return
 	}
-	w.grow(MaxVarintLen64)
+	w.grow(SizeOfSvarint(v))
 	w.buf = wire.AppendSvarint(w.buf, v)
 }
 
@@ -366,6 +419,16 @@ func (w *Writer) WriteString(s string) {
 	w.buf = append(w.buf, s...)
 }
 
+// WriteStringBytes writes b as a string, exactly as WriteString(string(b))
+// would, including the MaxStringLength and ValidateUTF8 checks, but without
+// converting b to a string first. It suits encoders that build text in a
+// byte buffer.
+func (w *Writer) WriteStringBytes(b []byte) {
+	// WriteString copies the bytes into the buffer and keeps no reference
+	// to s, so b may be reused once it returns
+	w.WriteString(unsafe.String(unsafe.SliceData(b), len(b)))
+}
+
 // WriteBytes writes a length-prefixed byte slice.
 func (w *Writer) WriteBytes(b []byte) {
 	if !w.checkWrite() {
@@ -404,7 +467,10 @@ func (w *Writer) WriteTag(fieldNum int, wireType WireType) {
 		w.setError(ErrInvalidFieldNumber)
 		return
 	}
-	w.grow(MaxTagSize)
+	if w.opts.CollectStats {
+		w.stats.Fields++
+	}
+	w.grow(SizeOfTag(fieldNum))
 	w.buf = wire.AppendTag(w.buf, fieldNum, wire.Type(wireType))
 }
 
@@ -416,6 +482,22 @@ func (w *Writer) WriteNil() {
 	w.WriteUvarint(uint64(TypeIDNil))
 }
 
+// presentMarker precedes a set pointer element of a slice or map, where a
+// nil one is written as a nil marker.
+const presentMarker byte = 0x01
+
+// WritePresent writes the marker that precedes a set pointer element of a
+// slice, array or map. A nil element is written with WriteNil instead. The
+// marker is needed because the element's own encoding may start with any
+// byte, including the nil marker.
+func (w *Writer) WritePresent() {
+	if !w.checkWrite() {
+		return
+	}
+	w.grow(1)
+	w.buf = append(w.buf, presentMarker)
+}
+
 // WriteTypeID writes a type ID for polymorphic encoding.
 func (w *Writer) WriteTypeID(id TypeID) {
 	if !w.checkWrite() {
@@ -434,8 +516,10 @@ func (w *Writer) BeginMessage() int {
 		return -1
 	}
 	// Reserve space for length (we'll fill it in later)
-	// We reserve MaxVarintLen64 bytes to handle any message size
+	// We reserve MaxVarintLen64 bytes to handle any message size, of which
+	// only the one every length takes counts toward MaxOutputSize for now
 	checkpoint := len(w.buf)
+	w.slack += MaxVarintLen64 - 1
 	w.grow(MaxVarintLen64)
 	w.buf = append(w.buf, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
 	return checkpoint
@@ -469,6 +553,25 @@ func (w *Writer) EndMessage(checkpoint int) {
 
 	// Write the length prefix
 	copy(w.buf[checkpoint:], lenBytes)
+
+	// The length is now known, so check it against the output limit
+	w.slack -= MaxVarintLen64 - 1
+	if limit := w.opts.Limits.MaxOutputSize; limit > 0 && int64(len(w.buf)-w.slack) > limit {
+		w.setError(ErrMaxOutputSize)
+	}
+}
+
+// WriteMessageFunc writes a length-prefixed nested message whose content is
+// written by encode. The bytes are identical to calling WriteBytes with the
+// message's own encoding, but no intermediate buffer is needed. The message
+// counts toward the depth limit, and errors recorded by encode remain on w.
+func (w *Writer) WriteMessageFunc(encode func(*Writer)) {
+	checkpoint := w.BeginMessage()
+	if checkpoint < 0 {
+		return
+	}
+	encode(w)
+	w.EndMessage(checkpoint)
 }
 
 // WriteArrayHeader writes the length of an array/slice.
@@ -808,3 +911,119 @@ func (w *Writer) WritePackedFixed64(values []uint64) {
 			byte(v>>56))
 	}
 }
+
+// growPacked prepares for n packed elements of size bytes each, failing if
+// n is negative or above maxLen. It reports whether there is anything to
+// write.
+func (w *Writer) growPacked(n, maxLen, size int) bool {
+	if !w.checkWrite() {
+		return false
+	}
+	if n < 0 {
+		w.setError(ErrNegativeLength)
+		return false
+	}
+	if n == 0 {
+		return false
+	}
+	if n > maxLen || n > math.MaxInt/size {
+		w.setError(ErrMaxArrayLength)
+		return false
+	}
+	w.grow(n * size)
+	return true
+}
+
+// WritePackedFloat32Func writes n float32 values as a packed array, taking
+// element i from get(i). It produces the same bytes as WritePackedFloat32
+// without requiring the values to be collected into a slice first.
+func (w *Writer) WritePackedFloat32Func(n int, get func(i int) float32) {
+	if !w.growPacked(n, MaxPackedFloat32Length, 4) {
+		return
+	}
+	for i := range n {
+		bits := wire.CanonicalFloat32Bits(get(i))
+		w.buf = append(w.buf,
+			byte(bits),
+			byte(bits>>8),
+			byte(bits>>16),
+			byte(bits>>24))
+	}
+}
+
+// WritePackedFloat64Func writes n float64 values as a packed array, taking
+// element i from get(i). It produces the same bytes as WritePackedFloat64.
+func (w *Writer) WritePackedFloat64Func(n int, get func(i int) float64) {
+	if !w.growPacked(n, MaxPackedFloat64Length, 8) {
+		return
+	}
+	for i := range n {
+		bits := wire.CanonicalFloat64Bits(get(i))
+		w.buf = append(w.buf,
+			byte(bits),
+			byte(bits>>8),
+			byte(bits>>16),
+			byte(bits>>24),
+			byte(bits>>32),
+			byte(bits>>40),
+			byte(bits>>48),
+			byte(bits>>56))
+	}
+}
+
+// WritePackedFixed32Func writes n fixed 32-bit values as a packed array,
+// taking element i from get(i). It produces the same bytes as
+// WritePackedFixed32.
+func (w *Writer) WritePackedFixed32Func(n int, get func(i int) uint32) {
+	if !w.growPacked(n, MaxPackedFixed32Length, 4) {
+		return
+	}
+	for i := range n {
+		v := get(i)
+		w.buf = append(w.buf,
+			byte(v),
+			byte(v>>8),
+			byte(v>>16),
+			byte(v>>24))
+	}
+}
+
+// WritePackedFixed64Func writes n fixed 64-bit values as a packed array,
+// taking element i from get(i). It produces the same bytes as
+// WritePackedFixed64.
+func (w *Writer) WritePackedFixed64Func(n int, get func(i int) uint64) {
+	if !w.growPacked(n, MaxPackedFixed64Length, 8) {
+		return
+	}
+	for i := range n {
+		v := get(i)
+		w.buf = append(w.buf,
+			byte(v),
+			byte(v>>8),
+			byte(v>>16),
+			byte(v>>24),
+			byte(v>>32),
+			byte(v>>40),
+			byte(v>>48),
+			byte(v>>56))
+	}
+}
+
+// WritePackedDeltaInt64 writes a packed array of int64 values as the first
+// value in signed varint form followed by the difference between each value
+// and the one before it as an unsigned varint. For sorted values, such as
+// lists of IDs, the differences are small and the array is often much
+// smaller than with one varint per value. ReadPackedDeltaInt64 reverses it.
+//
+// The values must be sorted in ascending order for the encoding to be
+// compact. Unsorted values still round-trip, since the differences wrap
+// around, but every decrease costs ten bytes.
+func (w *Writer) WritePackedDeltaInt64(values []int64) {
+	if !w.checkWrite() || len(values) == 0 {
+		return
+	}
+	w.WriteSvarint(values[0])
+	for i := 1; i < len(values); i++ {
+		w.WriteUvarint(uint64(values[i] - values[i-1]))
+	}
+}
@@ -2,7 +2,11 @@ package cramberry
 
 import (
 	"bytes"
+	"errors"
 	"math"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -63,6 +67,177 @@ func TestWriterPool(t *testing.T) {
 	PutWriter(nil)
 }
 
+func TestWriterPoolResetsOptions(t *testing.T) {
+	defer SetWriterPoolConfig(DefaultWriterPoolConfig)
+	SetWriterPoolConfig(WriterPoolConfig{InitialSize: 256, MaxPooledSize: 1024, MaxLargeSize: 1 << 20})
+
+	custom := DefaultOptions
+	custom.Deterministic = false
+	custom.ValidateUTF8 = false
+	custom.Hooks = &Hooks{OnField: func(int, int) {}}
+
+	isDefault := func(w *Writer) bool {
+		return w.Options() == DefaultOptions
+	}
+
+	for _, size := range []int{16, 64 * 1024} { // regular and large tier
+		w := GetWriterWithHint(size)
+		w.SetOptions(custom)
+		w.WriteRawBytes(make([]byte, size))
+		PutWriter(w)
+		if !isDefault(w) {
+			t.Errorf("size %d: options kept after PutWriter: %+v", size, w.Options())
+		}
+
+		for range 4 {
+			next := GetWriterWithHint(size)
+			if !isDefault(next) {
+				t.Errorf("size %d: pooled writer leaked options %+v", size, next.Options())
+			}
+			PutWriter(next)
+		}
+	}
+
+	// Reset keeps the options of a writer still in use
+	w := NewWriterWithOptions(custom)
+	w.WriteBool(true)
+	w.Reset()
+	if w.Options().Deterministic || w.Options().ValidateUTF8 {
+		t.Error("Reset discarded the writer's options")
+	}
+}
+
+func TestWriterPoolConfig(t *testing.T) {
+	defer SetWriterPoolConfig(DefaultWriterPoolConfig)
+
+	if got := GetWriterPoolConfig(); got != DefaultWriterPoolConfig {
+		t.Fatalf("initial config = %+v, want %+v", got, DefaultWriterPoolConfig)
+	}
+
+	SetWriterPoolSize(8192)
+	if got := GetWriterPoolConfig().InitialSize; got != 8192 {
+		t.Errorf("InitialSize = %d, want 8192", got)
+	}
+	w := GetWriter()
+	if cap(w.buf) < 8192 {
+		t.Errorf("GetWriter buffer capacity = %d, want >= 8192", cap(w.buf))
+	}
+	PutWriter(w)
+
+	// Non-positive sizes fall back to defaults
+	SetWriterPoolConfig(WriterPoolConfig{})
+	cfg := GetWriterPoolConfig()
+	if cfg.InitialSize != DefaultWriterPoolConfig.InitialSize || cfg.MaxPooledSize != DefaultWriterPoolConfig.MaxPooledSize {
+		t.Errorf("zero config not normalized: %+v", cfg)
+	}
+	if cfg.MaxLargeSize != 0 {
+		t.Errorf("MaxLargeSize = %d, want 0", cfg.MaxLargeSize)
+	}
+
+	// A raised initial size also raises the regular pool limit
+	cfg = WriterPoolConfig{InitialSize: 128 * 1024, MaxPooledSize: 1024}
+	if got := cfg.maxPooled(); got != 128*1024 {
+		t.Errorf("maxPooled() = %d, want %d", got, 128*1024)
+	}
+}
+
+func TestAdaptiveWriterSizing(t *testing.T) {
+	defer EnableAdaptiveWriterSizing(false)
+
+	type record struct {
+		ID   int64    `cramberry:"1"`
+		Body string   `cramberry:"2"`
+		Tags []string `cramberry:"3"`
+	}
+	records := make([]record, 50)
+	for i := range records {
+		records[i] = record{ID: int64(i), Body: strings.Repeat("x", 1000+i*10), Tags: []string{"a", "b"}}
+	}
+
+	var want [][]byte
+	for _, r := range records {
+		data, err := Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, data)
+	}
+
+	EnableAdaptiveWriterSizing(true)
+	for i, r := range records {
+		data, err := Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want[i]) {
+			t.Fatalf("record %d encodes differently with adaptive sizing", i)
+		}
+	}
+
+	// Writers handed out now have room for the typical record
+	avg := int(adaptiveSize.Load())
+	if avg < 1000 || avg > 1600 {
+		t.Fatalf("average size = %d, want about the encoded record size", avg)
+	}
+	w := GetWriter()
+	if cap(w.buf) < avg {
+		t.Errorf("GetWriter buffer capacity = %d, want >= %d", cap(w.buf), avg)
+	}
+	PutWriter(w)
+
+	// The reserve never exceeds the regular pool's limit
+	if got := pooledWriterSize(&WriterPoolConfig{InitialSize: 64, MaxPooledSize: 512}); got != 512 {
+		t.Errorf("pooledWriterSize = %d, want 512", got)
+	}
+
+	EnableAdaptiveWriterSizing(false)
+	if adaptiveSize.Load() != 0 {
+		t.Error("disabling adaptive sizing kept the average")
+	}
+	if got := pooledWriterSize(writerPoolConfig.Load()); got != DefaultWriterPoolConfig.InitialSize {
+		t.Errorf("pooledWriterSize = %d after disabling, want %d", got, DefaultWriterPoolConfig.InitialSize)
+	}
+}
+
+func TestWriterPoolLargeTier(t *testing.T) {
+	defer SetWriterPoolConfig(DefaultWriterPoolConfig)
+	SetWriterPoolConfig(WriterPoolConfig{
+		InitialSize:   256,
+		MaxPooledSize: 1024,
+		MaxLargeSize:  1 << 20,
+	})
+
+	// A writer that outgrew the regular pool is kept in the large tier
+	w := GetWriter()
+	w.WriteRawBytes(make([]byte, 64*1024))
+	PutWriter(w)
+
+	w2 := GetWriterWithHint(32 * 1024)
+	if cap(w2.buf) < 32*1024 {
+		t.Errorf("GetWriterWithHint buffer capacity = %d, want >= %d", cap(w2.buf), 32*1024)
+	}
+	if w2.Len() != 0 {
+		t.Errorf("large pooled writer not reset, Len() = %d", w2.Len())
+	}
+	w2.WriteString("large")
+	if w2.Err() != nil {
+		t.Errorf("large pooled writer unusable: %v", w2.Err())
+	}
+	PutWriter(w2)
+
+	// Writers beyond MaxLargeSize are dropped rather than pooled
+	w3 := GetWriter()
+	w3.WriteRawBytes(make([]byte, 2<<20))
+	PutWriter(w3)
+	for i := 0; i < 4; i++ {
+		w4 := GetWriterWithHint(16 * 1024)
+		if cap(w4.buf) > 1<<20 {
+			t.Fatalf("writer with %d byte buffer should not have been pooled", cap(w4.buf))
+		}
+		PutWriter(w4)
+	}
+}
+
 func TestWriterReset(t *testing.T) {
 	w := NewWriter()
 	w.WriteBool(true)
@@ -374,6 +549,54 @@ func TestWriteStringInvalidUTF8(t *testing.T) {
 	}
 }
 
+func TestWriteStringBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		value string
+	}{
+		{"empty", DefaultOptions, ""},
+		{"ascii", DefaultOptions, "hello, world!"},
+		{"multi-byte", DefaultOptions, "日本語 🎉"},
+		{"invalid UTF-8 unchecked", Options{Limits: DefaultLimits}, "\xff\xfe"},
+		{"invalid UTF-8 checked", Options{ValidateUTF8: true, Limits: DefaultLimits}, "\xff\xfe"},
+		{"over the limit", Options{Limits: Limits{MaxStringLength: 4}}, "hello"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			want := NewWriterWithOptions(tc.opts)
+			want.WriteString(tc.value)
+			got := NewWriterWithOptions(tc.opts)
+			got.WriteStringBytes([]byte(tc.value))
+
+			if !errors.Is(got.Err(), want.Err()) {
+				t.Errorf("WriteStringBytes error = %v, WriteString error = %v", got.Err(), want.Err())
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Errorf("WriteStringBytes = %x, WriteString = %x", got.Bytes(), want.Bytes())
+			}
+		})
+	}
+
+	// The written bytes don't alias the argument
+	b := []byte("abc")
+	w := NewWriter()
+	w.WriteStringBytes(b)
+	copy(b, "xyz")
+	if r := NewReader(w.Bytes()); r.ReadString() != "abc" {
+		t.Error("WriteStringBytes output changed with its argument")
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		w.Reset()
+		w.WriteStringBytes(b)
+	})
+	if allocs != 0 {
+		t.Errorf("WriteStringBytes allocated %.1f times per run, want 0", allocs)
+	}
+}
+
 func TestWriteBytes(t *testing.T) {
 	tests := [][]byte{
 		{},
@@ -485,6 +708,73 @@ func TestNestedMessages(t *testing.T) {
 	}
 }
 
+func TestWriteMessageFunc(t *testing.T) {
+	type inner struct {
+		Name  string  `cramberry:"1"`
+		Score int32   `cramberry:"2"`
+		Tags  []int64 `cramberry:"3"`
+	}
+	messages := []inner{
+		{},
+		{Name: "alice", Score: -7, Tags: []int64{1, 2, 3}},
+		{Name: string(make([]byte, 200))}, // length prefix needs two bytes
+	}
+
+	for _, msg := range messages {
+		data, err := Marshal(msg)
+		if err != nil {
+			t.Fatalf("Marshal error: %v", err)
+		}
+		want := NewWriter()
+		want.WriteInt32(1)
+		want.WriteBytes(data)
+		want.WriteInt32(2)
+
+		w := NewWriter()
+		w.WriteInt32(1)
+		w.WriteMessageFunc(func(w *Writer) {
+			if err := encodeValue(w, reflect.ValueOf(msg)); err != nil {
+				t.Fatalf("encodeValue error: %v", err)
+			}
+		})
+		w.WriteInt32(2)
+
+		if w.Err() != nil {
+			t.Fatalf("WriteMessageFunc error: %v", w.Err())
+		}
+		if !bytes.Equal(w.Bytes(), want.Bytes()) {
+			t.Errorf("WriteMessageFunc = %x, want %x", w.Bytes(), want.Bytes())
+		}
+	}
+
+	t.Run("errors propagate", func(t *testing.T) {
+		w := NewWriter()
+		w.WriteMessageFunc(func(w *Writer) {
+			w.WriteArrayHeader(-1)
+		})
+		if !errors.Is(w.Err(), ErrNegativeLength) {
+			t.Errorf("expected ErrNegativeLength, got %v", w.Err())
+		}
+	})
+
+	t.Run("depth limit", func(t *testing.T) {
+		w := NewWriterWithOptions(Options{Limits: Limits{MaxDepth: 2}})
+		calls := 0
+		var nest func(w *Writer)
+		nest = func(w *Writer) {
+			calls++
+			w.WriteMessageFunc(nest)
+		}
+		w.WriteMessageFunc(nest)
+		if !errors.Is(w.Err(), ErrMaxDepthExceeded) {
+			t.Errorf("expected ErrMaxDepthExceeded, got %v", w.Err())
+		}
+		if calls != 2 {
+			t.Errorf("encode called %d times, want 2", calls)
+		}
+	})
+}
+
 func TestWriteArrayHeader(t *testing.T) {
 	w := NewWriter()
 	w.WriteArrayHeader(10)
@@ -949,6 +1239,148 @@ func TestWritePackedFixed64Basic(t *testing.T) {
 	}
 }
 
+// TestWritePackedFunc tests that the callback-based packed writers produce
+// the same bytes as the slice-based ones.
+func TestWritePackedFunc(t *testing.T) {
+	f32 := []float32{1.5, float32(math.NaN()), float32(math.Copysign(0, -1)), -2}
+	f64 := []float64{1.5, math.NaN(), math.Copysign(0, -1), math.Inf(-1)}
+	u32 := []uint32{0, 1, math.MaxUint32}
+	u64 := []uint64{0, 1 << 40, math.MaxUint64}
+
+	tests := []struct {
+		name      string
+		slice, fn func(w *Writer)
+	}{
+		{"float32",
+			func(w *Writer) { w.WritePackedFloat32(f32) },
+			func(w *Writer) { w.WritePackedFloat32Func(len(f32), func(i int) float32 { return f32[i] }) }},
+		{"float64",
+			func(w *Writer) { w.WritePackedFloat64(f64) },
+			func(w *Writer) { w.WritePackedFloat64Func(len(f64), func(i int) float64 { return f64[i] }) }},
+		{"fixed32",
+			func(w *Writer) { w.WritePackedFixed32(u32) },
+			func(w *Writer) { w.WritePackedFixed32Func(len(u32), func(i int) uint32 { return u32[i] }) }},
+		{"fixed64",
+			func(w *Writer) { w.WritePackedFixed64(u64) },
+			func(w *Writer) { w.WritePackedFixed64Func(len(u64), func(i int) uint64 { return u64[i] }) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, got := NewWriter(), NewWriter()
+			tt.slice(want)
+			tt.fn(got)
+			if got.Err() != nil {
+				t.Fatalf("unexpected error: %v", got.Err())
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Errorf("got %x, want %x", got.Bytes(), want.Bytes())
+			}
+		})
+	}
+
+	w := NewWriter()
+	w.WritePackedFloat64Func(0, nil)
+	if w.Err() != nil || w.Len() != 0 {
+		t.Errorf("empty array: err %v, %d bytes", w.Err(), w.Len())
+	}
+	w.WritePackedFixed32Func(-1, nil)
+	if !errors.Is(w.Err(), ErrNegativeLength) {
+		t.Errorf("negative length: err = %v, want ErrNegativeLength", w.Err())
+	}
+}
+
+// TestWritePackedFuncNoAlloc tests that computed packed arrays are written
+// without allocating once the buffer has grown.
+func TestWritePackedFuncNoAlloc(t *testing.T) {
+	w := NewWriter()
+	get := func(i int) float64 { return float64(i) * 0.5 }
+	w.WritePackedFloat64Func(1024, get)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		w.Reset()
+		w.WritePackedFloat64Func(1024, get)
+	})
+	if allocs != 0 {
+		t.Errorf("WritePackedFloat64Func allocated %.1f times per run, want 0", allocs)
+	}
+}
+
+func TestWritePackedDeltaInt64(t *testing.T) {
+	// Sorted IDs, as in a list of collaborators
+	ids := make([]int64, 100)
+	for i := range ids {
+		ids[i] = 1_700_000_000_000 + int64(i*i)
+	}
+
+	tests := []struct {
+		name   string
+		values []int64
+	}{
+		{"sorted ids", ids},
+		{"single", []int64{-42}},
+		{"negative", []int64{-1000, -10, 0, 10}},
+		{"duplicates", []int64{5, 5, 5, 6}},
+		{"extremes", []int64{math.MinInt64, 0, math.MaxInt64}},
+		{"unsorted", []int64{10, 3, math.MaxInt64, math.MinInt64, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWriter()
+			w.WritePackedDeltaInt64(tt.values)
+			w.WriteUint8(0xAB) // sentinel after the array
+			if w.Err() != nil {
+				t.Fatalf("write error: %v", w.Err())
+			}
+
+			r := NewReader(w.Bytes())
+			got := r.ReadPackedDeltaInt64(len(tt.values))
+			if r.Err() != nil {
+				t.Fatalf("read error: %v", r.Err())
+			}
+			if !slices.Equal(got, tt.values) {
+				t.Errorf("got %v, want %v", got, tt.values)
+			}
+			if b := r.ReadUint8(); b != 0xAB {
+				t.Errorf("read past the array: sentinel %#x", b)
+			}
+		})
+	}
+
+	// Sorted values are much smaller than one varint per value
+	delta, plain := NewWriter(), NewWriter()
+	delta.WritePackedDeltaInt64(ids)
+	for _, v := range ids {
+		plain.WriteInt64(v)
+	}
+	if delta.Len()*2 > plain.Len() {
+		t.Errorf("delta encoding is %d bytes, plain packed %d; want less than half", delta.Len(), plain.Len())
+	}
+	t.Logf("%d sorted ids: %d bytes delta encoded, %d bytes plain", len(ids), delta.Len(), plain.Len())
+
+	w := NewWriter()
+	w.WritePackedDeltaInt64(nil)
+	if w.Err() != nil || w.Len() != 0 {
+		t.Errorf("empty array: err %v, %d bytes", w.Err(), w.Len())
+	}
+
+	// Truncated input and impossible counts fail cleanly
+	w.WritePackedDeltaInt64([]int64{1, 300, 70000})
+	data := w.Bytes()
+	for _, tc := range []struct {
+		data  []byte
+		count int
+	}{
+		{data[:len(data)-1], 3},
+		{data, 4},
+		{data, math.MaxInt},
+	} {
+		r := NewReader(tc.data)
+		if got := r.ReadPackedDeltaInt64(tc.count); got != nil || !errors.Is(r.Err(), ErrUnexpectedEOF) {
+			t.Errorf("ReadPackedDeltaInt64(%x, %d) = %v, %v; want ErrUnexpectedEOF", tc.data, tc.count, got, r.Err())
+		}
+	}
+}
+
 func BenchmarkWriter(b *testing.B) {
 	b.Run("Primitives", func(b *testing.B) {
 		w := NewWriter()
@@ -976,6 +1408,17 @@ func BenchmarkWriter(b *testing.B) {
 		}
 	})
 
+	b.Run("StringBytes", func(b *testing.B) {
+		w := NewWriter()
+		text := []byte("hello world")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			w.Reset()
+			w.WriteStringBytes(text)
+			_ = w.Bytes()
+		}
+	})
+
 	b.Run("Message", func(b *testing.B) {
 		w := NewWriter()
 		for i := 0; i < b.N; i++ {
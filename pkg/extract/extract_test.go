@@ -447,3 +447,78 @@ func TestTypeIDAutoAssignment(t *testing.T) {
 		}
 	}
 }
+
+// TestExtractWithPackageFilters tests scoping extraction to package import paths.
+func TestExtractWithPackageFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected []string
+		excluded []string
+	}{
+		{
+			name:     "include models subtree",
+			include:  []string{".../multipkg/models/*"},
+			expected: []string{"Account", "Invoice"},
+			excluded: []string{"Request"},
+		},
+		{
+			name:     "exclude one package",
+			exclude:  []string{"*/billing"},
+			expected: []string{"Account", "Request"},
+			excluded: []string{"Invoice"},
+		},
+		{
+			name:     "include and exclude",
+			include:  []string{"*/models/*"},
+			exclude:  []string{"*/accounts"},
+			expected: []string{"Invoice"},
+			excluded: []string{"Account", "Request"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractor := NewExtractor()
+			s, err := extractor.Extract(&ExtractorConfig{
+				Config:          DefaultConfig(),
+				Patterns:        []string{"./testdata/multipkg/..."},
+				IncludePackages: tt.include,
+				ExcludePackages: tt.exclude,
+				Package:         "multipkg",
+			})
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+
+			names := make(map[string]bool)
+			for _, msg := range s.Messages {
+				names[msg.Name] = true
+			}
+			for _, name := range tt.expected {
+				if !names[name] {
+					t.Errorf("expected message %s, got %v", name, names)
+				}
+			}
+			for _, name := range tt.excluded {
+				if names[name] {
+					t.Errorf("message %s should have been filtered out", name)
+				}
+			}
+		})
+	}
+}
+
+// TestExtractWithPackageFiltersNoMatch tests that filtering out every package is an error.
+func TestExtractWithPackageFiltersNoMatch(t *testing.T) {
+	extractor := NewExtractor()
+	_, err := extractor.Extract(&ExtractorConfig{
+		Config:          DefaultConfig(),
+		Patterns:        []string{"./testdata/multipkg/..."},
+		IncludePackages: []string{"example.com/nothing/*"},
+	})
+	if err == nil {
+		t.Fatal("expected error when no packages match package filters")
+	}
+}
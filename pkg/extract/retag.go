@@ -0,0 +1,348 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/blockberries/cramberry/pkg/schema"
+)
+
+// TagChange records a cramberry struct tag written by Retag.
+type TagChange struct {
+	Struct string
+	Field  string
+	From   int // Previous field number, 0 if the field had none
+	To     int
+}
+
+func (c TagChange) String() string {
+	if c.From == 0 {
+		return fmt.Sprintf("%s.%s: tagged %d", c.Struct, c.Field, c.To)
+	}
+	return fmt.Sprintf("%s.%s: %d -> %d", c.Struct, c.Field, c.From, c.To)
+}
+
+// RetagResult reports what Retag changed, what it could not match and
+// which structs it left alone because retagging them would give two fields
+// the same number.
+type RetagResult struct {
+	Changes    []TagChange
+	Mismatches []string
+	Conflicts  []string
+
+	changed map[*ast.File]bool
+}
+
+// Retag aligns the cramberry tags of Go structs with the field numbers in
+// a schema. Each message in s is matched to the struct of the same name in
+// files, and each of its fields to the exported struct field whose name
+// equals it once case and underscores are ignored, so user_id matches
+// UserID. Matched fields get a cramberry tag carrying the schema's field
+// number; options after the number and tags for other keys are kept.
+//
+// Messages without a struct, schema fields without a Go field and exported
+// Go fields without a schema field are reported as mismatches and left
+// alone, as are fields tagged cramberry:"-". If a field left alone keeps a
+// number that a matched field would be given, the struct is not changed at
+// all and the clash is reported as a conflict. The files are edited in
+// place.
+func Retag(s *schema.Schema, files []*ast.File) *RetagResult {
+	result := &RetagResult{changed: make(map[*ast.File]bool)}
+
+	type structDecl struct {
+		file *ast.File
+		st   *ast.StructType
+	}
+	structs := make(map[string]structDecl)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if st, ok := typeSpec.Type.(*ast.StructType); ok {
+					structs[typeSpec.Name.Name] = structDecl{file, st}
+				}
+			}
+		}
+	}
+
+	for _, msg := range s.Messages {
+		decl, ok := structs[msg.Name]
+		if !ok {
+			result.mismatch("message %s has no Go struct", msg.Name)
+			continue
+		}
+
+		schemaFields := make(map[string]*schema.Field, len(msg.Fields))
+		for _, f := range msg.Fields {
+			schemaFields[retagKey(f.Name)] = f
+		}
+		matched := make(map[*schema.Field]bool)
+
+		// Matched fields to tag, and the numbers kept by fields left alone
+		type retag struct {
+			field *ast.Field
+			name  string
+			num   int
+		}
+		var retags []retag
+		kept := make(map[int]string)
+
+		for _, field := range decl.st.Fields.List {
+			if len(field.Names) == 0 || currentTag(field) == "-" {
+				continue
+			}
+			if len(field.Names) > 1 {
+				for _, name := range field.Names {
+					if f := schemaFields[retagKey(name.Name)]; f != nil && name.IsExported() {
+						matched[f] = true
+						result.mismatch("%s.%s shares its declaration with other fields and cannot be tagged on its own", msg.Name, name.Name)
+					}
+				}
+				if num := tagNumber(field); num > 0 && field.Names[0].IsExported() {
+					kept[num] = field.Names[0].Name
+				}
+				continue
+			}
+			name := field.Names[0]
+			if !name.IsExported() {
+				continue
+			}
+			f := schemaFields[retagKey(name.Name)]
+			if f == nil {
+				result.mismatch("%s.%s has no schema field", msg.Name, name.Name)
+				if num := tagNumber(field); num > 0 {
+					kept[num] = name.Name
+				}
+				continue
+			}
+			matched[f] = true
+			retags = append(retags, retag{field, name.Name, f.Number})
+		}
+
+		conflict := false
+		for _, r := range retags {
+			if other, ok := kept[r.num]; ok {
+				result.Conflicts = append(result.Conflicts, fmt.Sprintf(
+					"%s.%s needs number %d, which %s.%s already has", msg.Name, r.name, r.num, msg.Name, other))
+				conflict = true
+			}
+		}
+		if !conflict {
+			for _, r := range retags {
+				if from := setFieldNumber(r.field, r.num); from != r.num {
+					result.Changes = append(result.Changes, TagChange{Struct: msg.Name, Field: r.name, From: from, To: r.num})
+					result.changed[decl.file] = true
+				}
+			}
+		}
+
+		for _, f := range msg.Fields {
+			if !matched[f] {
+				result.mismatch("schema field %s.%s (number %d) has no Go field", msg.Name, f.Name, f.Number)
+			}
+		}
+	}
+	return result
+}
+
+func (r *RetagResult) mismatch(format string, args ...any) {
+	r.Mismatches = append(r.Mismatches, fmt.Sprintf(format, args...))
+}
+
+// RetagPackages runs Retag over the Go packages matching patterns and
+// writes back the files whose tags changed.
+func RetagPackages(s *schema.Schema, patterns []string) (*RetagResult, error) {
+	fset := token.NewFileSet()
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax,
+		Fset: fset,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no packages matched patterns: %v", patterns)
+	}
+
+	var files []*ast.File
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("package errors: %v", pkg.Errors[0])
+		}
+		files = append(files, pkg.Syntax...)
+	}
+
+	result := Retag(s, files)
+	for _, file := range files {
+		if !result.changed[file] {
+			continue
+		}
+		src, err := printFile(fset, file)
+		if err != nil {
+			return nil, err
+		}
+		path := fset.File(file.Pos()).Name()
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, src, info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return result, nil
+}
+
+// printFile prints file the way gofmt does.
+func printFile(fset *token.FileSet, file *ast.File) ([]byte, error) {
+	var buf bytes.Buffer
+	pc := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := pc.Fprint(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// retagKey normalizes a schema or Go field name for matching.
+func retagKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// currentTag returns the cramberry tag of field, or "" if it has none.
+func currentTag(field *ast.Field) string {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	value, _ := lookupTag(tag, "cramberry")
+	return value
+}
+
+// tagNumber returns the field number in the cramberry tag of field, or 0
+// if it has none.
+func tagNumber(field *ast.Field) int {
+	numPart, _, _ := strings.Cut(currentTag(field), ",")
+	num, err := strconv.Atoi(numPart)
+	if err != nil || num < 0 {
+		return 0
+	}
+	return num
+}
+
+// setFieldNumber rewrites the cramberry tag of field to carry num, keeping
+// any options after it, and returns the number it carried before.
+func setFieldNumber(field *ast.Field, num int) int {
+	var tag string
+	if field.Tag != nil {
+		tag, _ = strconv.Unquote(field.Tag.Value)
+	}
+	value, _ := lookupTag(tag, "cramberry")
+	numPart, opts, hasOpts := strings.Cut(value, ",")
+	from, err := strconv.Atoi(numPart)
+	if err != nil || from < 0 {
+		from = 0
+	}
+	if from == num {
+		return from
+	}
+
+	value = strconv.Itoa(num)
+	if hasOpts {
+		value += "," + opts
+	}
+	tag = setTag(tag, "cramberry", value)
+	lit := "`" + tag + "`"
+	if strings.Contains(tag, "`") {
+		lit = strconv.Quote(tag)
+	}
+	if field.Tag == nil {
+		field.Tag = &ast.BasicLit{ValuePos: field.Type.End(), Kind: token.STRING}
+	}
+	field.Tag.Value = lit
+	return from
+}
+
+// tagPair locates one key:"value" pair of a struct tag. start and end
+// bound the whole pair and quoted bounds its quoted value.
+type tagPair struct {
+	key        string
+	start, end int
+	quoted     int
+}
+
+// tagPairs splits a struct tag into its key:"value" pairs using the
+// conventional syntax read by reflect.StructTag.
+func tagPairs(tag string) []tagPair {
+	var pairs []tagPair
+	i := 0
+	for {
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		if i >= len(tag) {
+			return pairs
+		}
+		start := i
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == start || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return pairs
+		}
+		key := tag[start:i]
+		i++
+		quoted := i
+		for i++; i < len(tag) && tag[i] != '"'; i++ {
+			if tag[i] == '\\' {
+				i++
+			}
+		}
+		if i >= len(tag) {
+			return pairs
+		}
+		i++
+		pairs = append(pairs, tagPair{key: key, start: start, end: i, quoted: quoted})
+	}
+}
+
+// lookupTag returns the value stored under key in tag.
+func lookupTag(tag, key string) (string, bool) {
+	for _, p := range tagPairs(tag) {
+		if p.key == key {
+			value, err := strconv.Unquote(tag[p.quoted:p.end])
+			return value, err == nil
+		}
+	}
+	return "", false
+}
+
+// setTag stores value under key in tag, replacing the existing value in
+// place or appending a new pair.
+func setTag(tag, key, value string) string {
+	pair := key + ":" + strconv.Quote(value)
+	for _, p := range tagPairs(tag) {
+		if p.key == key {
+			return tag[:p.start] + pair + tag[p.end:]
+		}
+	}
+	if tag = strings.TrimRight(tag, " "); tag != "" {
+		tag += " "
+	}
+	return tag + pair
+}
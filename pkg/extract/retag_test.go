@@ -0,0 +1,148 @@
+package extract
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/schema"
+)
+
+const retagSchema = `package models;
+
+message User {
+  int64 id = 1;
+  string user_name = 2;
+  string email = 3;
+  []string tags = 4;
+  bool active = 5;
+}
+
+message Order {
+  int64 id = 1;
+}
+`
+
+const retagSource = "package models\n\n" +
+	"type User struct {\n" +
+	"\tID       int64\n" +
+	"\tUserName string `json:\"user_name\" cramberry:\"3,omitempty\"`\n" +
+	"\tEmail    string `cramberry:\"3\"` // contact address\n" +
+	"\tTags     []string `json:\"tags\"`\n" +
+	"\tInternal string `cramberry:\"-\"`\n" +
+	"\tNickname string\n" +
+	"\tcache    string\n" +
+	"}\n"
+
+func TestRetag(t *testing.T) {
+	s, errs := schema.ParseFile("models.cram", retagSchema)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "models.go", retagSource, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := Retag(s, []*ast.File{file})
+
+	wantChanges := []string{
+		"User.ID: tagged 1",
+		"User.UserName: 3 -> 2",
+		"User.Tags: tagged 4",
+	}
+	if len(result.Changes) != len(wantChanges) {
+		t.Fatalf("got changes %v, want %v", result.Changes, wantChanges)
+	}
+	for i, c := range result.Changes {
+		if c.String() != wantChanges[i] {
+			t.Errorf("change %d = %q, want %q", i, c, wantChanges[i])
+		}
+	}
+
+	wantMismatches := []string{
+		"User.Nickname has no schema field",
+		"schema field User.active (number 5) has no Go field",
+		"message Order has no Go struct",
+	}
+	if strings.Join(result.Mismatches, "\n") != strings.Join(wantMismatches, "\n") {
+		t.Errorf("mismatches = %q, want %q", result.Mismatches, wantMismatches)
+	}
+
+	src, err := printFile(fset, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(src)
+	for _, want := range []string{
+		"ID       int64    `cramberry:\"1\"`",
+		"`json:\"user_name\" cramberry:\"2,omitempty\"`",
+		"`cramberry:\"3\"` // contact address",
+		"`json:\"tags\" cramberry:\"4\"`",
+		"`cramberry:\"-\"`",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %s:\n%s", want, out)
+		}
+	}
+
+	// A second pass has nothing left to change
+	if again := Retag(s, []*ast.File{file}); len(again.Changes) != 0 {
+		t.Errorf("second pass changed %v", again.Changes)
+	}
+}
+
+func TestRetagConflict(t *testing.T) {
+	s, errs := schema.ParseFile("models.cram", retagSchema)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	// Nickname has no schema field and keeps number 4, which the schema
+	// gives to tags
+	src := "package models\n\n" +
+		"type User struct {\n" +
+		"\tID       int64\n" +
+		"\tTags     []string\n" +
+		"\tNickname string `cramberry:\"4\"`\n" +
+		"}\n"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "models.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := Retag(s, []*ast.File{file})
+	wantConflicts := []string{"User.Tags needs number 4, which User.Nickname already has"}
+	if strings.Join(result.Conflicts, "\n") != strings.Join(wantConflicts, "\n") {
+		t.Errorf("conflicts = %q, want %q", result.Conflicts, wantConflicts)
+	}
+	if len(result.Changes) != 0 || result.changed[file] {
+		t.Errorf("conflicting struct was changed: %v", result.Changes)
+	}
+	out, err := printFile(fset, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != src {
+		t.Errorf("source changed:\n%s", out)
+	}
+}
+
+func TestSetTag(t *testing.T) {
+	tests := []struct {
+		tag, value, want string
+	}{
+		{"", "1", `cramberry:"1"`},
+		{`json:"a"`, "1", `json:"a" cramberry:"1"`},
+		{`json:"a" cramberry:"2" yaml:"b"`, "1", `json:"a" cramberry:"1" yaml:"b"`},
+		{`cramberry:"2,required"`, "1,required", `cramberry:"1,required"`},
+	}
+	for _, tt := range tests {
+		if got := setTag(tt.tag, "cramberry", tt.value); got != tt.want {
+			t.Errorf("setTag(%q, %q) = %q, want %q", tt.tag, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// Package api contains transport types for package filter tests.
+package api
+
+// Request is an API request envelope.
+type Request struct {
+	Method string `cramberry:"1"`
+	Body   []byte `cramberry:"2"`
+}
@@ -0,0 +1,8 @@
+// Package accounts contains account models for package filter tests.
+package accounts
+
+// Account is a customer account.
+type Account struct {
+	ID    int64  `cramberry:"1"`
+	Owner string `cramberry:"2"`
+}
@@ -0,0 +1,8 @@
+// Package billing contains billing models for package filter tests.
+package billing
+
+// Invoice is a bill issued to an account.
+type Invoice struct {
+	ID     int64 `cramberry:"1"`
+	Amount int64 `cramberry:"2"`
+}
@@ -5,6 +5,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
 
 	"github.com/blockberries/cramberry/pkg/schema"
 )
@@ -23,10 +26,12 @@ func NewExtractor() *Extractor {
 
 // ExtractorConfig configures the extraction process.
 type ExtractorConfig struct {
-	Config     *Config  // Type collector configuration
-	Patterns   []string // Go package patterns to load
-	OutputPath string   // Output file path (empty for stdout)
-	Package    string   // Package name for generated schema
+	Config          *Config  // Type collector configuration
+	Patterns        []string // Go package patterns to load
+	IncludePackages []string // Package import path patterns to include (glob)
+	ExcludePackages []string // Package import path patterns to exclude (glob)
+	OutputPath      string   // Output file path (empty for stdout)
+	Package         string   // Package name for generated schema
 }
 
 // Extract extracts a schema from Go packages.
@@ -41,6 +46,13 @@ func (e *Extractor) Extract(cfg *ExtractorConfig) (*schema.Schema, error) {
 		return nil, fmt.Errorf("no packages matched patterns: %v", cfg.Patterns)
 	}
 
+	// Filter by package import path before any type-level filtering
+	pkgs = filterPackages(pkgs, cfg.IncludePackages, cfg.ExcludePackages)
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no packages left after package filters (include: %v, exclude: %v)",
+			cfg.IncludePackages, cfg.ExcludePackages)
+	}
+
 	// Collect types
 	collectorCfg := cfg.Config
 	if collectorCfg == nil {
@@ -67,6 +79,52 @@ func (e *Extractor) Extract(cfg *ExtractorConfig) (*schema.Schema, error) {
 	return s, nil
 }
 
+// filterPackages keeps the packages whose import path matches at least one
+// include pattern (or all packages when there are none) and no exclude pattern.
+func filterPackages(pkgs []*packages.Package, include, exclude []string) []*packages.Package {
+	if len(include) == 0 && len(exclude) == 0 {
+		return pkgs
+	}
+
+	var result []*packages.Package
+	for _, pkg := range pkgs {
+		if matchesPackagePatterns(pkg.PkgPath, include, exclude) {
+			result = append(result, pkg)
+		}
+	}
+	return result
+}
+
+func matchesPackagePatterns(pkgPath string, include, exclude []string) bool {
+	if len(include) > 0 {
+		matched := false
+		for _, pattern := range include {
+			if matchPackageGlob(pattern, pkgPath) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	for _, pattern := range exclude {
+		if matchPackageGlob(pattern, pkgPath) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchPackageGlob matches an import path against a glob pattern. In addition
+// to "*", the Go package wildcard "..." matches any sequence, so patterns
+// such as ".../models/*" work as expected.
+func matchPackageGlob(pattern, pkgPath string) bool {
+	return matchGlob(strings.ReplaceAll(pattern, "...", "*"), pkgPath)
+}
+
 // ExtractAndWrite extracts a schema and writes it to the specified output.
 func (e *Extractor) ExtractAndWrite(cfg *ExtractorConfig) error {
 	s, err := e.Extract(cfg)
@@ -62,6 +62,10 @@ type Import struct {
 	EndPos   Position
 	Path     string
 	Alias    string // Optional alias for the import
+
+	// Public re-exports the imported schema: files that import this one
+	// can refer to its types as if they had imported it themselves.
+	Public bool
 }
 
 func (i *Import) Pos() Position { return i.Position }
@@ -78,7 +82,7 @@ type Option struct {
 func (o *Option) Pos() Position { return o.Position }
 func (o *Option) End() Position { return o.EndPos }
 
-// Value represents an option value (string, number, bool, or list).
+// Value represents an option value (string, number, bool, identifier, or list).
 type Value interface {
 	Node
 	valueNode()
@@ -107,6 +111,19 @@ func (v *NumberValue) Pos() Position { return v.Position }
 func (v *NumberValue) End() Position { return v.EndPos }
 func (v *NumberValue) valueNode()    {}
 
+// BytesValue is a byte literal value, written as 0x followed by hex digits
+// or as b64"..." holding base64.
+type BytesValue struct {
+	Position Position
+	EndPos   Position
+	Value    []byte
+	Base64   bool // Written in base64 rather than hex
+}
+
+func (v *BytesValue) Pos() Position { return v.Position }
+func (v *BytesValue) End() Position { return v.EndPos }
+func (v *BytesValue) valueNode()    {}
+
 // BoolValue is a boolean literal value.
 type BoolValue struct {
 	Position Position
@@ -118,6 +135,19 @@ func (v *BoolValue) Pos() Position { return v.Position }
 func (v *BoolValue) End() Position { return v.EndPos }
 func (v *BoolValue) valueNode()    {}
 
+// IdentValue is an identifier value naming an enum value. The name may be
+// qualified with the enum name, and with an import alias before that, as in
+// ACTIVE, Status.ACTIVE or common.Status.ACTIVE.
+type IdentValue struct {
+	Position Position
+	EndPos   Position
+	Name     string
+}
+
+func (v *IdentValue) Pos() Position { return v.Position }
+func (v *IdentValue) End() Position { return v.EndPos }
+func (v *IdentValue) valueNode()    {}
+
 // ListValue is a list of values.
 type ListValue struct {
 	Position Position
@@ -131,18 +161,47 @@ func (v *ListValue) valueNode()    {}
 
 // Message represents a message (struct) definition.
 type Message struct {
-	Position Position
-	EndPos   Position
-	Name     string
-	Fields   []*Field
-	Options  []*Option
-	Comments []*Comment
-	TypeID   int // Assigned type ID (0 = auto-assign)
+	Position   Position
+	EndPos     Position
+	Name       string
+	Fields     []*Field
+	Options    []*Option
+	Comments   []*Comment
+	Directives []*Directive
+	TypeID     int // Assigned type ID (0 = auto-assign)
+
+	// Group records that the message was declared inline by a group field
+	// of another message rather than at the top level.
+	Group bool
 }
 
 func (m *Message) Pos() Position { return m.Position }
 func (m *Message) End() Position { return m.EndPos }
 
+// Values of the message-level "framing" option.
+const (
+	// FramingMarker terminates the message with an end marker (default).
+	FramingMarker = "marker"
+
+	// FramingLength prefixes the message with its encoded length, so that
+	// readers can skip it without decoding its fields.
+	FramingLength = "length"
+)
+
+// Framing returns the value of the message's framing option, or
+// FramingMarker if the option is not set.
+func (m *Message) Framing() string {
+	for _, opt := range m.Options {
+		if opt.Name != "framing" {
+			continue
+		}
+		if sv, ok := opt.Value.(*StringValue); ok {
+			return sv.Value
+		}
+	}
+	return FramingMarker
+}
+
 // Field represents a field within a message.
 type Field struct {
 	Position   Position
@@ -152,17 +211,147 @@ type Field struct {
 	Type       TypeRef
 	Options    []*Option
 	Comments   []*Comment
+	Directives []*Directive
 	Required   bool
 	Repeated   bool
 	Optional   bool
 	MapKey     TypeRef // For map types
 	MapValue   TypeRef // For map types
 	Deprecated bool
+
+	// Group is the message declared inline by a group field, which is
+	// also the message named by Type.
+	Group *Message
+
+	// BlankLineBefore records that a blank line separated the field from
+	// the previous one in the source, starting a new group of fields.
+	BlankLineBefore bool
 }
 
 func (f *Field) Pos() Position { return f.Position }
 func (f *Field) End() Position { return f.EndPos }
 
+// FieldAnnotations holds the semantic annotations of a field, given by its
+// unit, min and max options.
+type FieldAnnotations struct {
+	// Unit names the unit of the value, such as "bytes" or "ms".
+	Unit string
+
+	// Min and Max are the inclusive bounds of a numeric field, or nil
+	// when unset.
+	Min *NumberValue
+	Max *NumberValue
+
+	// MinItems and MaxItems are the inclusive bounds on the number of
+	// elements of a repeated, slice or map field, or nil when unset.
+	MinItems *NumberValue
+	MaxItems *NumberValue
+}
+
+// HasRange reports whether either bound is set.
+func (a FieldAnnotations) HasRange() bool {
+	return a.Min != nil || a.Max != nil
+}
+
+// HasItemRange reports whether either element count bound is set.
+func (a FieldAnnotations) HasItemRange() bool {
+	return a.MinItems != nil || a.MaxItems != nil
+}
+
+// Annotations returns the field's unit, range and element count options.
+// Options with a value of the wrong kind are ignored here; the validator
+// reports them.
+func (f *Field) Annotations() FieldAnnotations {
+	var a FieldAnnotations
+	for _, opt := range f.Options {
+		switch opt.Name {
+		case "unit":
+			if sv, ok := opt.Value.(*StringValue); ok {
+				a.Unit = sv.Value
+			}
+		case "min":
+			if nv, ok := opt.Value.(*NumberValue); ok {
+				a.Min = nv
+			}
+		case "max":
+			if nv, ok := opt.Value.(*NumberValue); ok {
+				a.Max = nv
+			}
+		case "min_items":
+			if nv, ok := opt.Value.(*NumberValue); ok {
+				a.MinItems = nv
+			}
+		case "max_items":
+			if nv, ok := opt.Value.(*NumberValue); ok {
+				a.MaxItems = nv
+			}
+		}
+	}
+	return a
+}
+
+// Ordered reports whether a map field sets option ordered = true, which
+// keeps its entries in insertion order rather than sorting them by key.
+func (f *Field) Ordered() bool {
+	for _, opt := range f.Options {
+		if opt.Name != "ordered" {
+			continue
+		}
+		if bv, ok := opt.Value.(*BoolValue); ok {
+			return bv.Value
+		}
+	}
+	return false
+}
+
+// Delta reports whether a field of sorted int64 values sets option
+// delta = true, which encodes each value as its difference from the
+// previous one.
+func (f *Field) Delta() bool {
+	for _, opt := range f.Options {
+		if opt.Name != "delta" {
+			continue
+		}
+		if bv, ok := opt.Value.(*BoolValue); ok {
+			return bv.Value
+		}
+	}
+	return false
+}
+
+// Default returns the bytes given by a field's default option, which a
+// decoder starts from before reading the field. It returns nil if the
+// field has no byte literal default.
+func (f *Field) Default() []byte {
+	for _, opt := range f.Options {
+		if opt.Name != "default" {
+			continue
+		}
+		if bv, ok := opt.Value.(*BytesValue); ok {
+			return bv.Value
+		}
+	}
+	return nil
+}
+
+// DeltaElement returns the element type of a field that can be delta
+// encoded: a repeated int64 field or an []int64 slice. It returns nil for
+// other fields.
+func (f *Field) DeltaElement() *ScalarType {
+	t := f.Type
+	if !f.Repeated {
+		arr, ok := t.(*ArrayType)
+		if !ok || arr.Size != 0 {
+			return nil
+		}
+		t = arr.Element
+	}
+	if st, ok := t.(*ScalarType); ok && st.Name == "int64" {
+		return st
+	}
+	return nil
+}
+
 // TypeRef represents a type reference.
 type TypeRef interface {
 	Node
@@ -189,6 +378,12 @@ type NamedType struct {
 	Package  string // Optional package prefix
 	Name     string
 	TypeArgs []TypeRef // For generic types (future)
+
+	// The definition the name refers to, set when the schema is validated
+	// and left nil if it is undefined. At most one of them is non-nil.
+	Message   *Message
+	Enum      *Enum
+	Interface *Interface
 }
 
 func (t *NamedType) Pos() Position { return t.Position }
@@ -246,25 +441,70 @@ func (t *PointerType) String() string { return "*" + t.Element.String() }
 
 // Enum represents an enum definition.
 type Enum struct {
-	Position Position
-	EndPos   Position
-	Name     string
-	Values   []*EnumValue
-	Options  []*Option
-	Comments []*Comment
+	Position   Position
+	EndPos     Position
+	Name       string
+	Values     []*EnumValue
+	Options    []*Option
+	Comments   []*Comment
+	Directives []*Directive
 }
 
 func (e *Enum) Pos() Position { return e.Position }
 func (e *Enum) End() Position { return e.EndPos }
 
+// AllowAlias reports whether the enum sets option allow_alias = true, which
+// lets several value names share the same number.
+func (e *Enum) AllowAlias() bool {
+	for _, opt := range e.Options {
+		if opt.Name != "allow_alias" {
+			continue
+		}
+		if bv, ok := opt.Value.(*BoolValue); ok {
+			return bv.Value
+		}
+	}
+	return false
+}
+
+// Enum encoding option values.
+const (
+	// EnumEncodingVarint encodes enum values as signed varints. This is the
+	// default.
+	EnumEncodingVarint = "varint"
+
+	// EnumEncodingFixed32 encodes enum values as 4-byte little-endian
+	// integers, which is smaller for large values and keeps the layout fixed.
+	EnumEncodingFixed32 = "fixed32"
+)
+
+// Encoding returns the value of the enum's enum_encoding option, or
+// EnumEncodingVarint if the option is not set.
+func (e *Enum) Encoding() string {
+	for _, opt := range e.Options {
+		if opt.Name != "enum_encoding" {
+			continue
+		}
+		if sv, ok := opt.Value.(*StringValue); ok {
+			return sv.Value
+		}
+	}
+	return EnumEncodingVarint
+}
+
 // EnumValue represents a single enum value.
 type EnumValue struct {
-	Position Position
-	EndPos   Position
-	Name     string
-	Number   int
-	Options  []*Option
-	Comments []*Comment
+	Position   Position
+	EndPos     Position
+	Name       string
+	Number     int
+	Options    []*Option
+	Comments   []*Comment
+	Directives []*Directive
+
+	// BlankLineBefore records that a blank line separated the value from
+	// the previous one in the source.
+	BlankLineBefore bool
 }
 
 func (v *EnumValue) Pos() Position { return v.Position }
@@ -278,6 +518,7 @@ type Interface struct {
 	Implementations []*Implementation
 	Options         []*Option
 	Comments        []*Comment
+	Directives      []*Directive
 }
 
 func (i *Interface) Pos() Position { return i.Position }
@@ -285,11 +526,16 @@ func (i *Interface) End() Position { return i.EndPos }
 
 // Implementation maps a type ID to a message type.
 type Implementation struct {
-	Position Position
-	EndPos   Position
-	TypeID   int
-	Type     *NamedType
-	Comments []*Comment
+	Position   Position
+	EndPos     Position
+	TypeID     int
+	Type       *NamedType
+	Comments   []*Comment
+	Directives []*Directive
+
+	// BlankLineBefore records that a blank line separated the
+	// implementation from the previous one in the source.
+	BlankLineBefore bool
 }
 
 func (i *Implementation) Pos() Position { return i.Position }
@@ -306,6 +552,31 @@ type Comment struct {
 func (c *Comment) Pos() Position { return c.Position }
 func (c *Comment) End() Position { return c.EndPos }
 
+// Directive is a ///@ comment attached to the declaration that follows it,
+// such as ///@json:"customName". Directives carry hints for code
+// generators without extending the grammar; generators ignore keys they
+// do not recognize. Value is empty for a bare ///@key.
+type Directive struct {
+	Position Position
+	EndPos   Position
+	Key      string
+	Value    string
+}
+
+func (d *Directive) Pos() Position { return d.Position }
+func (d *Directive) End() Position { return d.EndPos }
+
+// LookupDirective returns the value of the last directive with the given
+// key in directives, and whether there was one.
+func LookupDirective(directives []*Directive, key string) (string, bool) {
+	for i := len(directives) - 1; i >= 0; i-- {
+		if directives[i].Key == key {
+			return directives[i].Value, true
+		}
+	}
+	return "", false
+}
+
 // ScalarTypes defines the built-in scalar types.
 var ScalarTypes = map[string]bool{
 	"bool":       true,
@@ -315,6 +586,7 @@ var ScalarTypes = map[string]bool{
 	"int64":      true,
 	"int":        true,
 	"uint8":      true,
+	"byte":       true, // alias for uint8
 	"uint16":     true,
 	"uint32":     true,
 	"uint64":     true,
@@ -325,6 +597,7 @@ var ScalarTypes = map[string]bool{
 	"complex128": true,
 	"string":     true,
 	"bytes":      true,
+	"any":        true, // any registered polymorphic value
 }
 
 // IsScalar returns true if the type name is a scalar type.
@@ -2,6 +2,7 @@ package schema
 
 import (
 	"fmt"
+	"slices"
 )
 
 // BreakingChangeType indicates the kind of breaking change detected.
@@ -188,13 +189,29 @@ func checkMessageCompat(oldMsg, newMsg *Message, report *CompatibilityReport) {
 	for num, oldF := range oldFields {
 		if newF, exists := newFields[num]; exists {
 			// Field number exists in both - check type compatibility
-			if !areTypesCompatible(oldF.Type, newF.Type) {
+			switch compareTypes(oldF.Type, newF.Type) {
+			case typeIncompatible:
 				report.Breaking = append(report.Breaking, BreakingChange{
 					Type: FieldTypeChanged,
 					Message: fmt.Sprintf("field %q type changed from %s to %s",
 						oldF.Name, oldF.Type.String(), newF.Type.String()),
 					Location: fmt.Sprintf("%s.%s", oldMsg.Name, oldF.Name),
 				})
+				continue
+			case typeWidened:
+				// New readers accept old data, but readers still on the old
+				// schema can't decode values outside the old type's range
+				report.Warnings = append(report.Warnings,
+					fmt.Sprintf("field %s.%s widened from %s to %s",
+						oldMsg.Name, oldF.Name, oldF.Type.String(), newF.Type.String()))
+			}
+			if oldF.Delta() != newF.Delta() {
+				// Delta-encoded lists are not readable as plain lists
+				report.Breaking = append(report.Breaking, BreakingChange{
+					Type:     FieldTypeChanged,
+					Message:  fmt.Sprintf("field %q delta encoding changed", oldF.Name),
+					Location: fmt.Sprintf("%s.%s", oldMsg.Name, oldF.Name),
+				})
 			}
 		} else {
 			// Field was removed
@@ -318,46 +335,94 @@ func checkInterfaceCompat(oldIface, newIface *Interface, report *CompatibilityRe
 	}
 }
 
-// areTypesCompatible checks if two types are compatible for wire format.
-// Some type changes are safe (e.g., int32 -> int64 for reading), others are not.
-func areTypesCompatible(oldType, newType TypeRef) bool {
-	// Exact match is always compatible
+// typeChange classifies a change to a field's type by whether data written
+// with the old type can be read with the new one.
+type typeChange int
+
+const (
+	// typeUnchanged means both types have the same encoding and range.
+	typeUnchanged typeChange = iota
+	// typeWidened means the new type reads every value of the old type,
+	// but not the other way around.
+	typeWidened
+	// typeIncompatible means old data can't be read, or can be read
+	// wrongly, with the new type.
+	typeIncompatible
+)
+
+// scalarAliases maps scalar types to the type they encode identically to.
+var scalarAliases = map[string]string{
+	"byte": "uint8",
+	"int":  "int64",
+	"uint": "uint64",
+}
+
+// scalarWidenings lists, for each scalar type, the types that decode all of
+// its encoded values unchanged. Signed integers from int16 up share the
+// zigzag varint encoding and unsigned ones from uint16 up the plain varint
+// encoding, so a wider type of the same signedness reads a narrower one.
+// int8 and uint8 are written as a single raw byte and only widen into each
+// other's aliases; bool is a raw 0 or 1 byte, which reads the same as a
+// uint8 or a varint. Floats are fixed-width and don't widen, and a string
+// is readable as bytes but bytes need not be valid UTF-8.
+var scalarWidenings = map[string][]string{
+	"bool":   {"uint8", "uint16", "uint32", "uint64"},
+	"int16":  {"int32", "int64"},
+	"int32":  {"int64"},
+	"uint16": {"uint32", "uint64"},
+	"uint32": {"uint64"},
+	"string": {"bytes"},
+}
+
+// compareTypes classifies the change from oldType to newType. Making a type
+// optional or required is safe for reading; collections compare by their
+// element, key and value types.
+func compareTypes(oldType, newType TypeRef) typeChange {
 	if oldType.String() == newType.String() {
-		return true
+		return typeUnchanged
+	}
+	if p, ok := oldType.(*PointerType); ok {
+		return compareTypes(p.Element, newType)
+	}
+	if p, ok := newType.(*PointerType); ok {
+		return compareTypes(oldType, p.Element)
 	}
 
-	// Handle some safe upgrades
-	oldBase := baseTypeName(oldType)
-	newBase := baseTypeName(newType)
-
-	// Same base type with different modifiers might be compatible
-	if oldBase == newBase {
-		// Check if it's just a pointer/optional change
-		oldOpt := isOptionalType(oldType)
-		newOpt := isOptionalType(newType)
-		if oldOpt != newOpt {
-			// Changing optionality is generally safe for reading
-			return true
+	switch o := oldType.(type) {
+	case *ArrayType:
+		n, ok := newType.(*ArrayType)
+		if !ok || n.Size != o.Size {
+			return typeIncompatible
+		}
+		return compareTypes(o.Element, n.Element)
+	case *MapType:
+		n, ok := newType.(*MapType)
+		if !ok {
+			return typeIncompatible
 		}
+		return max(compareTypes(o.Key, n.Key), compareTypes(o.Value, n.Value))
 	}
+	return compareScalars(baseTypeName(oldType), baseTypeName(newType))
+}
 
-	// Integer widening is safe for reading (smaller -> larger)
-	intWidening := map[string]int{
-		"int8": 1, "int16": 2, "int32": 3, "int64": 4,
-		"uint8": 1, "uint16": 2, "uint32": 3, "uint64": 4,
+// compareScalars classifies the change between two scalar type names using
+// scalarAliases and scalarWidenings. Any other change, including narrowing
+// and changing signedness, is incompatible.
+func compareScalars(oldName, newName string) typeChange {
+	if alias, ok := scalarAliases[oldName]; ok {
+		oldName = alias
 	}
-	if oldW, ok := intWidening[oldBase]; ok {
-		if newW, ok := intWidening[newBase]; ok {
-			// Check sign compatibility
-			oldSigned := oldBase[0] != 'u'
-			newSigned := newBase[0] != 'u'
-			if oldSigned == newSigned && newW >= oldW {
-				return true
-			}
-		}
+	if alias, ok := scalarAliases[newName]; ok {
+		newName = alias
+	}
+	switch {
+	case oldName == newName:
+		return typeUnchanged
+	case slices.Contains(scalarWidenings[oldName], newName):
+		return typeWidened
+	default:
+		return typeIncompatible
 	}
-
-	return false
 }
 
 // baseTypeName extracts the base type name, stripping modifiers.
@@ -63,6 +63,30 @@ func TestCheckCompatibility_FieldTypeChanged(t *testing.T) {
 	}
 }
 
+func TestCheckCompatibility_DeltaChanged(t *testing.T) {
+	ids := func(opts ...*Option) *Schema {
+		return &Schema{
+			Messages: []*Message{
+				{
+					Name: "Team",
+					Fields: []*Field{
+						{Name: "members", Number: 1, Type: &ArrayType{Element: &ScalarType{Name: "int64"}}, Options: opts},
+					},
+				},
+			},
+		}
+	}
+	delta := &Option{Name: "delta", Value: &BoolValue{Value: true}}
+
+	report := CheckCompatibility(ids(), ids(delta))
+	if report.IsCompatible() || report.Breaking[0].Type != FieldTypeChanged {
+		t.Errorf("expected FieldTypeChanged when enabling delta encoding, got %v", report.Breaking)
+	}
+	if report := CheckCompatibility(ids(delta), ids(delta)); !report.IsCompatible() {
+		t.Errorf("unchanged delta field reported breaking: %v", report.Breaking)
+	}
+}
+
 func TestCheckCompatibility_RequiredFieldAdded(t *testing.T) {
 	old := &Schema{
 		Messages: []*Message{
@@ -357,6 +381,85 @@ func TestCheckCompatibility_IntWidening(t *testing.T) {
 	}
 }
 
+func TestCheckCompatibility_ScalarTransitions(t *testing.T) {
+	const (
+		safe     = "safe"
+		widened  = "widened"
+		breaking = "breaking"
+	)
+	scalar := func(name string) TypeRef { return &ScalarType{Name: name} }
+	list := func(name string) TypeRef { return &ArrayType{Element: scalar(name)} }
+	tests := []struct {
+		old, new TypeRef
+		want     string
+	}{
+		// Aliases encode identically
+		{scalar("byte"), scalar("uint8"), safe},
+		{scalar("int"), scalar("int64"), safe},
+		{scalar("uint64"), scalar("uint"), safe},
+
+		// Widening within the signed and unsigned varint families
+		{scalar("int16"), scalar("int32"), widened},
+		{scalar("int16"), scalar("int64"), widened},
+		{scalar("int32"), scalar("int64"), widened},
+		{scalar("int32"), scalar("int"), widened},
+		{scalar("uint16"), scalar("uint32"), widened},
+		{scalar("uint32"), scalar("uint64"), widened},
+		{scalar("uint32"), scalar("uint"), widened},
+		{scalar("bool"), scalar("uint8"), widened},
+		{scalar("bool"), scalar("uint32"), widened},
+		{scalar("string"), scalar("bytes"), widened},
+
+		// Narrowing
+		{scalar("int64"), scalar("int32"), breaking},
+		{scalar("int32"), scalar("int16"), breaking},
+		{scalar("uint64"), scalar("uint32"), breaking},
+		{scalar("uint8"), scalar("bool"), breaking},
+		{scalar("bytes"), scalar("string"), breaking},
+
+		// Different encodings
+		{scalar("int8"), scalar("int16"), breaking},
+		{scalar("uint8"), scalar("uint16"), breaking},
+		{scalar("int32"), scalar("uint32"), breaking},
+		{scalar("uint32"), scalar("int64"), breaking},
+		{scalar("float32"), scalar("float64"), breaking},
+		{scalar("int64"), scalar("float64"), breaking},
+		{scalar("int32"), scalar("string"), breaking},
+
+		// Modifiers and collections compare their element types
+		{&PointerType{Element: scalar("int32")}, scalar("int64"), widened},
+		{scalar("int32"), &PointerType{Element: scalar("int32")}, safe},
+		{list("int32"), list("int64"), widened},
+		{list("int64"), list("int32"), breaking},
+		{&ArrayType{Element: scalar("int32"), Size: 4}, list("int32"), breaking},
+		{&MapType{Key: scalar("string"), Value: scalar("uint32")}, &MapType{Key: scalar("string"), Value: scalar("uint64")}, widened},
+		{&MapType{Key: scalar("int64"), Value: scalar("string")}, &MapType{Key: scalar("int32"), Value: scalar("string")}, breaking},
+	}
+	for _, tt := range tests {
+		schemaWith := func(typ TypeRef) *Schema {
+			return &Schema{Messages: []*Message{{
+				Name:   "Counter",
+				Fields: []*Field{{Name: "value", Number: 1, Type: typ}},
+			}}}
+		}
+		report := CheckCompatibility(schemaWith(tt.old), schemaWith(tt.new))
+		got := safe
+		switch {
+		case !report.IsCompatible():
+			got = breaking
+			if report.Breaking[0].Type != FieldTypeChanged {
+				t.Errorf("%s -> %s: breaking change %v, want FieldTypeChanged", tt.old, tt.new, report.Breaking[0].Type)
+			}
+		case len(report.Warnings) > 0:
+			got = widened
+		}
+		if got != tt.want {
+			t.Errorf("%s -> %s: %s (breaking %v, warnings %v), want %s",
+				tt.old, tt.new, got, report.Breaking, report.Warnings, tt.want)
+		}
+	}
+}
+
 func TestBreakingChangeType_String(t *testing.T) {
 	tests := []struct {
 		changeType BreakingChangeType
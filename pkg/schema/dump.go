@@ -0,0 +1,318 @@
+package schema
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+)
+
+// dumpHexBytes is the number of bytes shown on each line of a dump; longer
+// values are cut short with an ellipsis.
+const dumpHexBytes = 8
+
+// DumpWithSchema returns an annotated hex dump of data, a V2-encoded
+// message of the type named messageName in s. Each line shows the offset
+// and bytes of one field together with the field's name and decoded value;
+// enum values are shown with their names, and nested messages, collections
+// and maps are expanded with one element per line.
+//
+// Fields that the schema doesn't define are listed by number and skipped
+// using their wire type. Fields of type any, interface fields and types
+// imported from other schemas can't be decoded from s alone and make the
+// dump fail, as does data that doesn't match the schema.
+func DumpWithSchema(data []byte, s *Schema, messageName string) (string, error) {
+	d := &dumper{
+		data:     data,
+		r:        cramberry.NewReader(data),
+		messages: make(map[string]*Message),
+		enums:    make(map[string]*Enum),
+	}
+	for _, msg := range s.Messages {
+		d.messages[msg.Name] = msg
+	}
+	for _, enum := range s.Enums {
+		d.enums[enum.Name] = enum
+	}
+
+	msg, ok := d.messages[messageName]
+	if !ok {
+		return "", fmt.Errorf("message %q is not defined in the schema", messageName)
+	}
+	if err := d.message(msg, messageName+" {", 0, 0, true); err != nil {
+		return d.b.String(), err
+	}
+	if rest := len(data) - d.r.Pos(); rest > 0 {
+		return d.b.String(), fmt.Errorf("%d trailing bytes after message %s", rest, messageName)
+	}
+	return d.b.String(), nil
+}
+
+// dumper holds the state of a single DumpWithSchema call.
+type dumper struct {
+	data     []byte
+	r        *cramberry.Reader
+	b        strings.Builder
+	messages map[string]*Message
+	enums    map[string]*Enum
+}
+
+// line writes text at the given depth, annotated with the bytes read since
+// start.
+func (d *dumper) line(start, depth int, text string) {
+	raw := d.data[start:d.r.Pos()]
+	shown := hex.EncodeToString(raw[:min(len(raw), dumpHexBytes)])
+	var cols []string
+	for i := 0; i < len(shown); i += 2 {
+		cols = append(cols, shown[i:i+2])
+	}
+	bytesCol := strings.Join(cols, " ")
+	if len(raw) > dumpHexBytes {
+		bytesCol += " ..."
+	}
+	fmt.Fprintf(&d.b, "%04x  %-*s  %s%s\n", start, dumpHexBytes*3+3, bytesCol, strings.Repeat("  ", depth), text)
+}
+
+// fail wraps the reader's error, if any, with the offset it occurred at.
+func (d *dumper) fail(start int) error {
+	if err := d.r.Err(); err != nil {
+		return fmt.Errorf("offset %d: %w", start, err)
+	}
+	return nil
+}
+
+// message dumps the fields of msg, preceded by header. The top-level
+// message may end at the end of the data instead of at an end marker.
+func (d *dumper) message(msg *Message, header string, start, depth int, top bool) error {
+	if depth >= cramberry.DefaultLimits.MaxDepth {
+		return fmt.Errorf("offset %d: %w", start, cramberry.ErrMaxDepthExceeded)
+	}
+	end := -1
+	if msg.Framing() == FramingLength {
+		end = d.r.BeginMessage()
+		if err := d.fail(start); err != nil {
+			return err
+		}
+	}
+	d.line(start, depth, header)
+
+	byNumber := make(map[int]*Field, len(msg.Fields))
+	for _, f := range msg.Fields {
+		byNumber[f.Number] = f
+	}
+	for {
+		start := d.r.Pos()
+		if (end >= 0 && start >= end) || (top && end < 0 && d.r.EOF()) {
+			d.r.EndMessage(end)
+			d.line(start, depth, "}")
+			return d.fail(start)
+		}
+
+		fieldNum, wireType := d.r.ReadCompactTag()
+		if err := d.fail(start); err != nil {
+			return err
+		}
+		if fieldNum == 0 {
+			if end >= 0 {
+				return fmt.Errorf("offset %d: end marker inside length-framed message %s", start, msg.Name)
+			}
+			d.line(start, depth, "}")
+			return nil
+		}
+
+		f, ok := byNumber[fieldNum]
+		if !ok {
+			d.r.SkipValueV2(wireType)
+			if err := d.fail(start); err != nil {
+				return err
+			}
+			d.line(start, depth+1, fmt.Sprintf("%d: unknown field", fieldNum))
+			continue
+		}
+		label := fmt.Sprintf("%s (%d)", f.Name, f.Number)
+		var err error
+		if f.Delta() && f.DeltaElement() != nil {
+			err = d.deltaList(label, start, depth+1)
+		} else if f.Repeated {
+			err = d.list(f.Type, label, start, depth+1)
+		} else {
+			err = d.value(f.Type, label, start, depth+1, false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// value dumps a value of type t labelled label. Pointer elements of
+// collections start with a nil or present marker.
+func (d *dumper) value(t TypeRef, label string, start, depth int, elem bool) error {
+	switch t := t.(type) {
+	case *ScalarType:
+		text, err := d.scalar(t.Name, start)
+		if err != nil {
+			return err
+		}
+		d.line(start, depth, label+": "+text)
+		return nil
+
+	case *NamedType:
+		if t.Package != "" {
+			return fmt.Errorf("offset %d: %s: imported type %s can't be dumped", start, label, t)
+		}
+		if enum, ok := d.enums[t.Name]; ok {
+			text, err := d.enum(enum, start)
+			if err != nil {
+				return err
+			}
+			d.line(start, depth, label+": "+text)
+			return nil
+		}
+		if msg, ok := d.messages[t.Name]; ok {
+			return d.message(msg, label+": "+msg.Name+" {", start, depth, false)
+		}
+		return fmt.Errorf("offset %d: %s: type %s can't be dumped", start, label, t.Name)
+
+	case *ArrayType:
+		if elem, ok := t.Element.(*ScalarType); ok && t.Size > 0 && (elem.Name == "byte" || elem.Name == "uint8") {
+			text, err := d.scalar("bytes", start)
+			if err != nil {
+				return err
+			}
+			d.line(start, depth, label+": "+text)
+			return nil
+		}
+		return d.list(t.Element, label, start, depth)
+
+	case *MapType:
+		n := d.r.ReadMapHeader()
+		if err := d.fail(start); err != nil {
+			return err
+		}
+		d.line(start, depth, fmt.Sprintf("%s: map of %d {", label, n))
+		for range n {
+			entry := d.r.Pos()
+			key, err := d.key(t.Key, entry)
+			if err != nil {
+				return err
+			}
+			if err := d.value(t.Value, "["+key+"]", entry, depth+1, true); err != nil {
+				return err
+			}
+		}
+		d.line(d.r.Pos(), depth, "}")
+		return nil
+
+	case *PointerType:
+		if elem {
+			isNil := d.r.ReadNil()
+			if err := d.fail(start); err != nil {
+				return err
+			}
+			if isNil {
+				d.line(start, depth, label+": nil")
+				return nil
+			}
+		}
+		return d.value(t.Element, label, start, depth, elem)
+	}
+	return fmt.Errorf("offset %d: %s: type %s can't be dumped", start, label, t)
+}
+
+// list dumps a count-prefixed sequence of elements of type t.
+func (d *dumper) list(t TypeRef, label string, start, depth int) error {
+	n := d.r.ReadArrayHeader()
+	if err := d.fail(start); err != nil {
+		return err
+	}
+	d.line(start, depth, fmt.Sprintf("%s: list of %d [", label, n))
+	for i := range n {
+		if err := d.value(t, fmt.Sprintf("[%d]", i), d.r.Pos(), depth+1, true); err != nil {
+			return err
+		}
+	}
+	d.line(d.r.Pos(), depth, "]")
+	return nil
+}
+
+// deltaList dumps a list of int64 values written with the delta option.
+// The values are only known once the whole list is read, so its bytes are
+// shown on the first line.
+func (d *dumper) deltaList(label string, start, depth int) error {
+	values := d.r.ReadPackedDeltaInt64(d.r.ReadArrayHeader())
+	if err := d.fail(start); err != nil {
+		return err
+	}
+	d.line(start, depth, fmt.Sprintf("%s: delta list of %d [", label, len(values)))
+	for i, v := range values {
+		d.line(d.r.Pos(), depth+1, fmt.Sprintf("[%d]: %d", i, v))
+	}
+	d.line(d.r.Pos(), depth, "]")
+	return nil
+}
+
+// key decodes a map key, which is a scalar or an enum.
+func (d *dumper) key(t TypeRef, start int) (string, error) {
+	switch t := t.(type) {
+	case *ScalarType:
+		return d.scalar(t.Name, start)
+	case *NamedType:
+		if enum, ok := d.enums[t.Name]; ok && t.Package == "" {
+			return d.enum(enum, start)
+		}
+	}
+	return "", fmt.Errorf("offset %d: map key type %s can't be dumped", start, t)
+}
+
+// enum decodes an enum value, showing its name if it has one.
+func (d *dumper) enum(enum *Enum, start int) (string, error) {
+	var n int64
+	if enum.Encoding() == EnumEncodingFixed32 {
+		n = int64(int32(d.r.ReadFixed32()))
+	} else {
+		n = int64(d.r.ReadInt32())
+	}
+	if err := d.fail(start); err != nil {
+		return "", err
+	}
+	for _, v := range enum.Values {
+		if int64(v.Number) == n {
+			return fmt.Sprintf("%s (%d)", v.Name, n), nil
+		}
+	}
+	return fmt.Sprintf("%d (not a %s value)", n, enum.Name), nil
+}
+
+// scalar decodes a value of a built-in type.
+func (d *dumper) scalar(name string, start int) (string, error) {
+	var text string
+	switch name {
+	case "bool":
+		text = strconv.FormatBool(d.r.ReadBool())
+	case "int8":
+		text = strconv.FormatInt(int64(d.r.ReadInt8()), 10)
+	case "int16", "int32", "int64", "int":
+		text = strconv.FormatInt(d.r.ReadSvarint(), 10)
+	case "uint8", "byte":
+		text = strconv.FormatUint(uint64(d.r.ReadUint8()), 10)
+	case "uint16", "uint32", "uint64", "uint":
+		text = strconv.FormatUint(d.r.ReadUvarint(), 10)
+	case "float32":
+		text = strconv.FormatFloat(float64(d.r.ReadFloat32()), 'g', -1, 32)
+	case "float64":
+		text = strconv.FormatFloat(d.r.ReadFloat64(), 'g', -1, 64)
+	case "string":
+		text = strconv.Quote(d.r.ReadString())
+	case "bytes":
+		b := d.r.ReadBytes()
+		text = fmt.Sprintf("%d bytes %x", len(b), b)
+	default:
+		return "", fmt.Errorf("offset %d: values of type %s can't be dumped", start, name)
+	}
+	if err := d.fail(start); err != nil {
+		return "", err
+	}
+	return text, nil
+}
@@ -0,0 +1,174 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+)
+
+const dumpSchema = `
+package docs;
+
+enum Status {
+  STATUS_UNKNOWN = 0;
+  STATUS_DRAFT = 1;
+  STATUS_PUBLISHED = 2;
+}
+
+message Timestamp {
+  int64 seconds = 1;
+  int32 nanos = 2;
+}
+
+message Tag {
+  string name = 1;
+}
+
+message Document {
+  int64 id = 1;
+  string title = 2;
+  Status status = 5;
+  repeated Tag tags = 7;
+  map[string]Status reviews = 10;
+  []int64 collaborators = 11;
+  Timestamp created_at = 12;
+  optional float64 score = 13;
+}
+`
+
+type dumpTimestamp struct {
+	Seconds int64 `cramberry:"1"`
+	Nanos   int32 `cramberry:"2"`
+}
+
+type dumpTag struct {
+	Name string `cramberry:"1"`
+}
+
+type dumpDocument struct {
+	ID            int64            `cramberry:"1"`
+	Title         string           `cramberry:"2"`
+	Status        int32            `cramberry:"5"`
+	Tags          []dumpTag        `cramberry:"7"`
+	Reviews       map[string]int32 `cramberry:"10"`
+	Collaborators []int64          `cramberry:"11"`
+	CreatedAt     dumpTimestamp    `cramberry:"12"`
+	Score         *float64         `cramberry:"13"`
+	Extra         uint32           `cramberry:"15"`
+}
+
+func parseDumpSchema(t *testing.T) *Schema {
+	t.Helper()
+	s, errs := ParseFile("docs.cram", dumpSchema)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	return s
+}
+
+func TestDumpWithSchema(t *testing.T) {
+	s := parseDumpSchema(t)
+	score := 0.5
+	data, err := cramberry.Marshal(dumpDocument{
+		ID:            42,
+		Title:         "Design notes",
+		Status:        2,
+		Tags:          []dumpTag{{Name: "go"}, {Name: "wire"}},
+		Reviews:       map[string]int32{"ada": 1, "bob": 7},
+		Collaborators: []int64{3, -4},
+		CreatedAt:     dumpTimestamp{Seconds: 1700000000},
+		Score:         &score,
+		Extra:         9,
+	})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	out, err := DumpWithSchema(data, s, "Document")
+	if err != nil {
+		t.Fatalf("DumpWithSchema error: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		"0000  " + strings.Repeat(" ", dumpHexBytes*3+3) + "  Document {",
+		"0000  18 54                          id (1): 42",
+		`title (2): "Design notes"`,
+		"status (5): STATUS_PUBLISHED (2)",
+		"tags (7): list of 2 [",
+		"[0]: Tag {",
+		`name (1): "go"`,
+		"reviews (10): map of 2 {",
+		"[\"ada\"]: STATUS_DRAFT (1)",
+		"[\"bob\"]: 7 (not a Status value)",
+		"collaborators (11): list of 2 [",
+		"[1]: -4",
+		"created_at (12): Timestamp {",
+		"seconds (1): 1700000000",
+		"score (13): 0.5",
+		"15: unknown field",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in dump:\n%s", want, out)
+		}
+	}
+	// Long values are cut short
+	if !strings.Contains(out, "24 0c 44 65 73 69 67 6e ...") {
+		t.Errorf("expected truncated bytes for title in dump:\n%s", out)
+	}
+}
+
+func TestDumpWithSchemaDelta(t *testing.T) {
+	s, errs := ParseFile("team.cram", `
+package docs;
+
+message Team {
+  []int64 members = 1 [delta = true];
+}
+`)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	// Written the way generated code writes a delta list
+	w := cramberry.NewWriter()
+	w.WriteCompactTag(1, cramberry.WireTypeV2Bytes)
+	w.WriteUvarint(3)
+	w.WritePackedDeltaInt64([]int64{1000, 1001, 1005})
+	w.WriteUint8(cramberry.EndMarker)
+	if err := w.Err(); err != nil {
+		t.Fatalf("Writer error: %v", err)
+	}
+
+	out, err := DumpWithSchema(w.Bytes(), s, "Team")
+	if err != nil {
+		t.Fatalf("DumpWithSchema error: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		"0000  14 03 d0 0f 01 04              members (1): delta list of 3 [",
+		"[0]: 1000",
+		"[1]: 1001",
+		"[2]: 1005",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in dump:\n%s", want, out)
+		}
+	}
+}
+
+func TestDumpWithSchemaErrors(t *testing.T) {
+	s := parseDumpSchema(t)
+	data, err := cramberry.Marshal(dumpDocument{ID: 1, Title: "x"})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	if _, err := DumpWithSchema(data, s, "Missing"); err == nil {
+		t.Error("expected an error for an undefined message")
+	}
+	if _, err := DumpWithSchema(data[:3], s, "Document"); !errors.Is(err, cramberry.ErrUnexpectedEOF) {
+		t.Errorf("truncated data: err = %v, want ErrUnexpectedEOF", err)
+	}
+	if _, err := DumpWithSchema(append(data, 0x01), s, "Document"); err == nil {
+		t.Error("expected an error for trailing bytes")
+	}
+}
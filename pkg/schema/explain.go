@@ -0,0 +1,388 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Explain returns a human-readable description of the message, enum or
+// interface named typeName in s.
+//
+// For messages the description lists every field with its number, type,
+// modifiers, wire type and encoded size including the tag, followed by the
+// size of the whole message and the types it references. Sizes are
+// estimates: the minimum assumes that only required fields are present, and
+// the maximum that every field is present at its largest encoding. Sizes that
+// depend on the length of strings, bytes or collections have no maximum and
+// are shown as "N+".
+func Explain(s *Schema, typeName string) (string, error) {
+	e := &explainer{
+		schema:   s,
+		messages: make(map[string]*Message),
+		enums:    make(map[string]*Enum),
+		ifaces:   make(map[string]*Interface),
+		visiting: make(map[string]bool),
+	}
+	for _, msg := range s.Messages {
+		e.messages[msg.Name] = msg
+	}
+	for _, enum := range s.Enums {
+		e.enums[enum.Name] = enum
+	}
+	for _, iface := range s.Interfaces {
+		e.ifaces[iface.Name] = iface
+	}
+
+	var b strings.Builder
+	if msg, ok := e.messages[typeName]; ok {
+		e.explainMessage(&b, msg)
+	} else if enum, ok := e.enums[typeName]; ok {
+		e.explainEnum(&b, enum)
+	} else if iface, ok := e.ifaces[typeName]; ok {
+		e.explainInterface(&b, iface)
+	} else {
+		return "", fmt.Errorf("type %q is not defined in the schema", typeName)
+	}
+	return b.String(), nil
+}
+
+// sizeRange is the range of sizes, in bytes, that an encoding can take.
+type sizeRange struct {
+	min, max  int
+	unbounded bool
+}
+
+func exactSize(n int) sizeRange { return sizeRange{min: n, max: n} }
+
+func (r sizeRange) plus(o sizeRange) sizeRange {
+	return sizeRange{min: r.min + o.min, max: r.max + o.max, unbounded: r.unbounded || o.unbounded}
+}
+
+func (r sizeRange) String() string {
+	switch {
+	case r.unbounded:
+		return fmt.Sprintf("%d+", r.min)
+	case r.min == r.max:
+		return fmt.Sprintf("%d", r.min)
+	default:
+		return fmt.Sprintf("%d-%d", r.min, r.max)
+	}
+}
+
+// uvarintSize returns the number of bytes in the varint encoding of v.
+func uvarintSize(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
+
+// tagSize returns the size of the compact tag for a field number. Fields
+// 1-15 fit in one byte; larger numbers add a varint after a marker byte.
+func tagSize(fieldNum int) int {
+	if fieldNum <= 15 {
+		return 1
+	}
+	return 1 + uvarintSize(uint64(fieldNum))
+}
+
+// scalarEncodings maps scalar types to their wire type and size.
+var scalarEncodings = map[string]struct {
+	wire string
+	size sizeRange
+}{
+	"bool":       {"varint", exactSize(1)},
+	"uint8":      {"varint", exactSize(1)},
+	"byte":       {"varint", exactSize(1)},
+	"int8":       {"svarint", exactSize(1)},
+	"int16":      {"svarint", sizeRange{min: 1, max: 3}},
+	"uint16":     {"varint", sizeRange{min: 1, max: 3}},
+	"int32":      {"svarint", sizeRange{min: 1, max: 5}},
+	"uint32":     {"varint", sizeRange{min: 1, max: 5}},
+	"int64":      {"svarint", sizeRange{min: 1, max: 10}},
+	"int":        {"svarint", sizeRange{min: 1, max: 10}},
+	"uint64":     {"varint", sizeRange{min: 1, max: 10}},
+	"uint":       {"varint", sizeRange{min: 1, max: 10}},
+	"float32":    {"fixed32", exactSize(4)},
+	"float64":    {"fixed64", exactSize(8)},
+	"complex64":  {"fixed64", exactSize(8)},
+	"complex128": {"bytes", exactSize(16)},
+	"string":     {"bytes", sizeRange{min: 1, unbounded: true}},
+	"bytes":      {"bytes", sizeRange{min: 1, unbounded: true}},
+	"any":        {"bytes", sizeRange{min: 1, unbounded: true}},
+}
+
+type explainer struct {
+	schema   *Schema
+	messages map[string]*Message
+	enums    map[string]*Enum
+	ifaces   map[string]*Interface
+
+	// visiting holds the messages whose size is being computed, so that
+	// recursive messages are reported as unbounded instead of looping.
+	visiting map[string]bool
+}
+
+func (e *explainer) explainMessage(b *strings.Builder, msg *Message) {
+	e.writeHeader(b, "message", msg.Name, msg.Position, msg.Comments)
+	if msg.Framing() == FramingLength {
+		b.WriteString("Framing: length prefix\n")
+	}
+
+	b.WriteString("\nFields:\n")
+	tw := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  #\tNAME\tTYPE\tMODIFIERS\tWIRE\tSIZE")
+	for _, f := range msg.Fields {
+		wire, size := e.fieldEncoding(f)
+		fmt.Fprintf(tw, "  %d\t%s\t%s\t%s\t%s\t%s\n",
+			f.Number, f.Name, f.Type, fieldModifiers(f), wire, size)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(b, "\nEncoded size: %s bytes\n", e.messageSize(msg))
+
+	var refs []TypeRef
+	for _, f := range msg.Fields {
+		refs = append(refs, f.Type)
+	}
+	e.writeReferences(b, refs)
+}
+
+func (e *explainer) explainEnum(b *strings.Builder, enum *Enum) {
+	e.writeHeader(b, "enum", enum.Name, enum.Position, enum.Comments)
+	wire, size := e.enumEncoding(enum)
+	fmt.Fprintf(b, "Encoding: %s, %s bytes\n", wire, size)
+
+	b.WriteString("\nValues:\n")
+	tw := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	names := make(map[int]string, len(enum.Values))
+	for _, v := range enum.Values {
+		if first, ok := names[v.Number]; ok {
+			fmt.Fprintf(tw, "  %d\t%s\t(alias of %s)\n", v.Number, v.Name, first)
+			continue
+		}
+		names[v.Number] = v.Name
+		fmt.Fprintf(tw, "  %d\t%s\n", v.Number, v.Name)
+	}
+	tw.Flush()
+}
+
+func (e *explainer) explainInterface(b *strings.Builder, iface *Interface) {
+	e.writeHeader(b, "interface", iface.Name, iface.Position, iface.Comments)
+
+	b.WriteString("\nImplementations:\n")
+	tw := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  TYPE ID\tTYPE\tSIZE")
+	var refs []TypeRef
+	for _, impl := range iface.Implementations {
+		_, size := e.typeEncoding(impl.Type)
+		fmt.Fprintf(tw, "  %d\t%s\t%s\n", impl.TypeID, impl.Type, exactSize(uvarintSize(uint64(impl.TypeID))).plus(size))
+		refs = append(refs, impl.Type)
+	}
+	tw.Flush()
+
+	_, size := e.interfaceEncoding(iface)
+	fmt.Fprintf(b, "\nEncoded size: %s bytes, including the type ID\n", size)
+	e.writeReferences(b, refs)
+}
+
+func (e *explainer) writeHeader(b *strings.Builder, kind, name string, pos Position, comments []*Comment) {
+	fmt.Fprintf(b, "%s %s", kind, name)
+	if e.schema.Package != nil {
+		fmt.Fprintf(b, " (package %s)", e.schema.Package.Name)
+	}
+	if pos.Line > 0 {
+		fmt.Fprintf(b, "\nDefined at %s", formatPosition(pos))
+	}
+	b.WriteString("\n")
+	for _, c := range comments {
+		if c.IsDoc {
+			fmt.Fprintf(b, "  %s\n", c.Text)
+		}
+	}
+}
+
+// writeReferences lists the named types reachable from refs, with their kind.
+func (e *explainer) writeReferences(b *strings.Builder, refs []TypeRef) {
+	seen := make(map[string]string)
+	var collect func(TypeRef)
+	collect = func(t TypeRef) {
+		switch typ := t.(type) {
+		case *NamedType:
+			seen[typ.String()] = e.kindOf(typ)
+		case *ArrayType:
+			collect(typ.Element)
+		case *MapType:
+			collect(typ.Key)
+			collect(typ.Value)
+		case *PointerType:
+			collect(typ.Element)
+		}
+	}
+	for _, t := range refs {
+		collect(t)
+	}
+	if len(seen) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString("\nReferences:\n")
+	tw := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, seen[name])
+	}
+	tw.Flush()
+}
+
+func (e *explainer) kindOf(t *NamedType) string {
+	switch {
+	case t.Package != "":
+		return "imported"
+	case e.messages[t.Name] != nil:
+		return "message"
+	case e.enums[t.Name] != nil:
+		return "enum"
+	case e.ifaces[t.Name] != nil:
+		return "interface"
+	default:
+		return "undefined"
+	}
+}
+
+func fieldModifiers(f *Field) string {
+	var mods []string
+	if f.Required {
+		mods = append(mods, "required")
+	}
+	if f.Optional {
+		mods = append(mods, "optional")
+	}
+	if f.Repeated {
+		mods = append(mods, "repeated")
+	}
+	if f.Deprecated {
+		mods = append(mods, "deprecated")
+	}
+	if len(mods) == 0 {
+		return "-"
+	}
+	return strings.Join(mods, ",")
+}
+
+// fieldEncoding returns the wire type of a field and its size, tag included.
+func (e *explainer) fieldEncoding(f *Field) (string, sizeRange) {
+	tag := exactSize(tagSize(f.Number))
+	if f.Repeated {
+		return "bytes", tag.plus(sizeRange{min: 1, unbounded: true})
+	}
+	wire, size := e.typeEncoding(f.Type)
+	return wire, tag.plus(size)
+}
+
+// typeEncoding returns the wire type of a value of type t and its size.
+func (e *explainer) typeEncoding(t TypeRef) (string, sizeRange) {
+	switch typ := t.(type) {
+	case *ScalarType:
+		if enc, ok := scalarEncodings[typ.Name]; ok {
+			return enc.wire, enc.size
+		}
+	case *NamedType:
+		if typ.Package != "" {
+			break
+		}
+		if msg, ok := e.messages[typ.Name]; ok {
+			return "bytes", e.messageSize(msg)
+		}
+		if enum, ok := e.enums[typ.Name]; ok {
+			return e.enumEncoding(enum)
+		}
+		if iface, ok := e.ifaces[typ.Name]; ok {
+			return e.interfaceEncoding(iface)
+		}
+	case *ArrayType:
+		if typ.Size == 0 {
+			break
+		}
+		// Fixed-size arrays carry their length, then the elements
+		size := exactSize(uvarintSize(uint64(typ.Size)))
+		_, elem := e.typeEncoding(typ.Element)
+		return "bytes", size.plus(sizeRange{
+			min:       elem.min * typ.Size,
+			max:       elem.max * typ.Size,
+			unbounded: elem.unbounded,
+		})
+	case *PointerType:
+		return e.typeEncoding(typ.Element)
+	}
+	// Slices, maps and types defined elsewhere
+	return "bytes", sizeRange{min: 1, unbounded: true}
+}
+
+// enumEncoding returns the wire type of an enum and the sizes of its
+// smallest and largest values.
+func (e *explainer) enumEncoding(enum *Enum) (string, sizeRange) {
+	if enum.Encoding() == EnumEncodingFixed32 {
+		return "fixed32", exactSize(4)
+	}
+	if len(enum.Values) == 0 {
+		return "svarint", exactSize(1)
+	}
+	lo, hi := enum.Values[0].Number, enum.Values[0].Number
+	for _, v := range enum.Values {
+		lo, hi = min(lo, v.Number), max(hi, v.Number)
+	}
+	// Values are non-negative, so their ZigZag encoding is twice the value
+	return "svarint", sizeRange{min: uvarintSize(uint64(lo) * 2), max: uvarintSize(uint64(hi) * 2)}
+}
+
+// interfaceEncoding returns the size of a polymorphic value: a type ID
+// followed by the implementation, or a single nil marker.
+func (e *explainer) interfaceEncoding(iface *Interface) (string, sizeRange) {
+	size := exactSize(1)
+	for _, impl := range iface.Implementations {
+		_, implSize := e.typeEncoding(impl.Type)
+		implSize = exactSize(uvarintSize(uint64(impl.TypeID))).plus(implSize)
+		size.max = max(size.max, implSize.max)
+		size.unbounded = size.unbounded || implSize.unbounded
+	}
+	return "bytes", size
+}
+
+// messageSize returns the encoded size of msg: its fields followed by an end
+// marker, or preceded by their length when the message uses length framing.
+func (e *explainer) messageSize(msg *Message) sizeRange {
+	if e.visiting[msg.Name] {
+		return sizeRange{min: 1, unbounded: true}
+	}
+	e.visiting[msg.Name] = true
+	defer delete(e.visiting, msg.Name)
+
+	var body sizeRange
+	for _, f := range msg.Fields {
+		_, size := e.fieldEncoding(f)
+		if f.Required {
+			body.min += size.min
+		}
+		body.max += size.max
+		body.unbounded = body.unbounded || size.unbounded
+	}
+
+	if msg.Framing() == FramingLength {
+		return sizeRange{
+			min:       body.min + uvarintSize(uint64(body.min)),
+			max:       body.max + uvarintSize(uint64(body.max)),
+			unbounded: body.unbounded,
+		}
+	}
+	return body.plus(exactSize(1))
+}
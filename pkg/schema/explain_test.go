@@ -0,0 +1,177 @@
+package schema
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const explainInput = `
+package demo;
+
+/// Status of an account.
+enum Status {
+  UNKNOWN = 0;
+  ACTIVE = 1;
+  BANNED = 100;
+}
+
+enum Code {
+  option enum_encoding = "fixed32";
+  NONE = 0;
+}
+
+message Address {
+  string street = 1;
+}
+
+/// User is an account holder.
+message User {
+  required string name = 1;
+  int32 age = 2;
+  repeated string tags = 3;
+  optional Address home = 4;
+  Status status = 5;
+  [32]byte digest = 6;
+  float64 score = 7;
+  deprecated bool legacy = 20;
+  Code code = 21;
+  User manager = 22;
+}
+
+message Header {
+  option framing = "length";
+  required int64 id = 1;
+}
+
+interface Principal {
+  128 = User;
+  129 = Header;
+}
+`
+
+func parseExplainSchema(t *testing.T) *Schema {
+	t.Helper()
+	s, errs := ParseFile("demo.cram", explainInput)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	return s
+}
+
+// explainRow matches a field row by number, name and the remaining columns.
+func explainRow(num, name, typ, mods, wire, size string) *regexp.Regexp {
+	return regexp.MustCompile(`(?m)^  ` + num + ` +` + name + ` +` + regexp.QuoteMeta(typ) +
+		` +` + mods + ` +` + wire + ` +` + regexp.QuoteMeta(size) + `$`)
+}
+
+func TestExplainMessage(t *testing.T) {
+	out, err := Explain(parseExplainSchema(t), "User")
+	if err != nil {
+		t.Fatalf("Explain error: %v", err)
+	}
+
+	for _, want := range []string{
+		"message User (package demo)",
+		"Defined at demo.cram:",
+		"  User is an account holder.",
+		"Encoded size: 3+ bytes",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	// Sizes include the tag, which takes two bytes from field 16 on
+	for _, row := range []*regexp.Regexp{
+		explainRow("1", "name", "string", "required", "bytes", "2+"),
+		explainRow("2", "age", "int32", "-", "svarint", "2-6"),
+		explainRow("3", "tags", "string", "repeated", "bytes", "2+"),
+		explainRow("4", "home", "Address", "optional", "bytes", "2+"),
+		explainRow("5", "status", "Status", "-", "svarint", "2-3"),
+		explainRow("6", "digest", "[32]byte", "-", "bytes", "34"),
+		explainRow("7", "score", "float64", "-", "fixed64", "9"),
+		explainRow("20", "legacy", "bool", "deprecated", "varint", "3"),
+		explainRow("21", "code", "Code", "-", "fixed32", "6"),
+		explainRow("22", "manager", "User", "-", "bytes", "5+"),
+	} {
+		if !row.MatchString(out) {
+			t.Errorf("expected a row matching %s in output:\n%s", row, out)
+		}
+	}
+
+	refs := out[strings.Index(out, "References:"):]
+	for _, want := range []string{"Address  message", "Code     enum", "Status   enum", "User     message"} {
+		if !strings.Contains(refs, want) {
+			t.Errorf("expected reference %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestExplainLengthFramedMessage(t *testing.T) {
+	out, err := Explain(parseExplainSchema(t), "Header")
+	if err != nil {
+		t.Fatalf("Explain error: %v", err)
+	}
+	if !strings.Contains(out, "Framing: length prefix") {
+		t.Errorf("expected framing in output:\n%s", out)
+	}
+	if !explainRow("1", "id", "int64", "required", "svarint", "2-11").MatchString(out) {
+		t.Errorf("expected the id field in output:\n%s", out)
+	}
+	// The length prefix replaces the end marker
+	if !strings.Contains(out, "Encoded size: 3-12 bytes") {
+		t.Errorf("expected the framed size in output:\n%s", out)
+	}
+}
+
+func TestExplainEnum(t *testing.T) {
+	s := parseExplainSchema(t)
+
+	out, err := Explain(s, "Status")
+	if err != nil {
+		t.Fatalf("Explain error: %v", err)
+	}
+	for _, want := range []string{
+		"enum Status (package demo)",
+		"  Status of an account.",
+		"Encoding: svarint, 1-2 bytes",
+		"  100  BANNED",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	out, err = Explain(s, "Code")
+	if err != nil {
+		t.Fatalf("Explain error: %v", err)
+	}
+	if !strings.Contains(out, "Encoding: fixed32, 4 bytes") {
+		t.Errorf("expected fixed32 encoding in output:\n%s", out)
+	}
+}
+
+func TestExplainInterface(t *testing.T) {
+	out, err := Explain(parseExplainSchema(t), "Principal")
+	if err != nil {
+		t.Fatalf("Explain error: %v", err)
+	}
+	for _, want := range []string{
+		"interface Principal (package demo)",
+		"  128      User    5+",
+		"  129      Header  5-14",
+		"Encoded size: 1+ bytes, including the type ID",
+		"Header  message",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestExplainUnknownType(t *testing.T) {
+	if _, err := Explain(parseExplainSchema(t), "Missing"); err == nil {
+		t.Error("expected an error for an undefined type")
+	}
+}
@@ -0,0 +1,149 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SchemaHashOption is the file-level option that asks generators to emit
+// the schema's fingerprint as a SchemaHash constant.
+const SchemaHashOption = "schema_hash"
+
+// GenerateSchemaHash reports whether the file sets option schema_hash = true.
+func (s *Schema) GenerateSchemaHash() bool {
+	for _, opt := range s.Options {
+		if opt.Name != SchemaHashOption {
+			continue
+		}
+		if bv, ok := opt.Value.(*BoolValue); ok {
+			return bv.Value
+		}
+	}
+	return false
+}
+
+// SchemaFingerprint returns a hex-encoded SHA-256 hash of the structure of
+// the schema: its package, options, and every message, enum and interface
+// with their field numbers, names, types, enum values and type IDs.
+//
+// Comments, whitespace and the order of declarations don't affect the
+// fingerprint, so reformatting a schema keeps it stable while any change
+// that could alter generated code or the wire format changes it. Imported
+// schemas are not included.
+func SchemaFingerprint(s *Schema) string {
+	var b strings.Builder
+
+	if s.Package != nil {
+		fmt.Fprintf(&b, "package %s\n", s.Package.Name)
+	}
+	imports := make([]string, len(s.Imports))
+	for i, imp := range s.Imports {
+		imports[i] = fmt.Sprintf("import %q %q\n", imp.Path, imp.Alias)
+		if imp.Public {
+			// Only public imports are marked, so existing fingerprints
+			// don't change
+			imports[i] = fmt.Sprintf("import public %q %q\n", imp.Path, imp.Alias)
+		}
+	}
+	slices.Sort(imports)
+	b.WriteString(strings.Join(imports, ""))
+	writeCanonicalOptions(&b, "", s.Options)
+
+	var decls []string
+
+	for _, enum := range s.Enums {
+		var d strings.Builder
+		fmt.Fprintf(&d, "enum %s\n", enum.Name)
+		writeCanonicalOptions(&d, "  ", enum.Options)
+		values := slices.Clone(enum.Values)
+		slices.SortStableFunc(values, func(a, b *EnumValue) int {
+			if a.Number != b.Number {
+				return a.Number - b.Number
+			}
+			return strings.Compare(a.Name, b.Name)
+		})
+		for _, v := range values {
+			fmt.Fprintf(&d, "  value %d %s\n", v.Number, v.Name)
+			writeCanonicalOptions(&d, "    ", v.Options)
+		}
+		decls = append(decls, d.String())
+	}
+
+	for _, msg := range s.Messages {
+		var d strings.Builder
+		fmt.Fprintf(&d, "message %s %d\n", msg.Name, msg.TypeID)
+		writeCanonicalOptions(&d, "  ", msg.Options)
+		fields := slices.Clone(msg.Fields)
+		slices.SortStableFunc(fields, func(a, b *Field) int { return a.Number - b.Number })
+		for _, f := range fields {
+			fmt.Fprintf(&d, "  field %d %s %s %s\n", f.Number, f.Name, fieldModifiers(f), f.Type)
+			writeCanonicalOptions(&d, "    ", f.Options)
+		}
+		decls = append(decls, d.String())
+	}
+
+	for _, iface := range s.Interfaces {
+		var d strings.Builder
+		fmt.Fprintf(&d, "interface %s\n", iface.Name)
+		writeCanonicalOptions(&d, "  ", iface.Options)
+		impls := slices.Clone(iface.Implementations)
+		slices.SortStableFunc(impls, func(a, b *Implementation) int { return a.TypeID - b.TypeID })
+		for _, impl := range impls {
+			fmt.Fprintf(&d, "  impl %d %s\n", impl.TypeID, impl.Type)
+		}
+		decls = append(decls, d.String())
+	}
+
+	// Declarations start with their kind and name, which are unique
+	slices.Sort(decls)
+	for _, d := range decls {
+		b.WriteString(d)
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeCanonicalOptions writes options sorted by name, one per line. The
+// schema_hash option is left out so that turning it on keeps the hash.
+func writeCanonicalOptions(b *strings.Builder, indent string, opts []*Option) {
+	lines := make([]string, 0, len(opts))
+	for _, opt := range opts {
+		if opt.Name == SchemaHashOption {
+			continue
+		}
+		lines = append(lines, indent+"option "+opt.Name+" = "+canonicalValue(opt.Value)+"\n")
+	}
+	slices.Sort(lines)
+	for _, line := range lines {
+		b.WriteString(line)
+	}
+}
+
+func canonicalValue(v Value) string {
+	switch v := v.(type) {
+	case *StringValue:
+		return strconv.Quote(v.Value)
+	case *NumberValue:
+		return v.Value
+	case *BytesValue:
+		// Hex and base64 spellings of the same bytes hash alike
+		return "0x" + hex.EncodeToString(v.Value)
+	case *BoolValue:
+		return strconv.FormatBool(v.Value)
+	case *IdentValue:
+		return v.Name
+	case *ListValue:
+		elems := make([]string, len(v.Values))
+		for i, e := range v.Values {
+			elems[i] = canonicalValue(e)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"regexp"
+	"testing"
+)
+
+const fingerprintInput = `package demo;
+
+enum Status {
+  UNKNOWN = 0;
+  ACTIVE = 1;
+}
+
+message User {
+  option framing = "length";
+  required string name = 1;
+  repeated int64 ids = 2;
+  Status status = 3;
+}
+
+interface Principal {
+  128 = User;
+}
+`
+
+// Same structure as fingerprintInput with comments, different spacing and
+// declarations in another order.
+const fingerprintReformatted = `// Account types
+package   demo;
+
+option schema_hash = true;
+
+interface Principal { 128 = User; }
+
+/// User is an account holder.
+message User {
+  Status status = 3;  // current state
+  required string name = 1;
+  option framing = "length";
+  repeated int64 ids = 2;
+}
+
+enum Status { ACTIVE = 1; UNKNOWN = 0; }
+`
+
+func fingerprintOf(t *testing.T, input string) string {
+	t.Helper()
+	s, errs := ParseFile("demo.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	return SchemaFingerprint(s)
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	base := fingerprintOf(t, fingerprintInput)
+	if !regexp.MustCompile(`^[0-9a-f]{64}$`).MatchString(base) {
+		t.Fatalf("fingerprint %q is not a hex SHA-256", base)
+	}
+	if got := fingerprintOf(t, fingerprintReformatted); got != base {
+		t.Errorf("reformatted schema fingerprint = %s, want %s", got, base)
+	}
+
+	// Structural changes change the fingerprint
+	for name, input := range map[string]string{
+		"field number": `package demo;
+enum Status { UNKNOWN = 0; ACTIVE = 1; }
+message User { option framing = "length"; required string name = 1; repeated int64 ids = 4; Status status = 3; }
+interface Principal { 128 = User; }`,
+		"field type": `package demo;
+enum Status { UNKNOWN = 0; ACTIVE = 1; }
+message User { option framing = "length"; required string name = 1; repeated int32 ids = 2; Status status = 3; }
+interface Principal { 128 = User; }`,
+		"modifier": `package demo;
+enum Status { UNKNOWN = 0; ACTIVE = 1; }
+message User { option framing = "length"; string name = 1; repeated int64 ids = 2; Status status = 3; }
+interface Principal { 128 = User; }`,
+		"enum value": `package demo;
+enum Status { UNKNOWN = 0; ACTIVE = 2; }
+message User { option framing = "length"; required string name = 1; repeated int64 ids = 2; Status status = 3; }
+interface Principal { 128 = User; }`,
+		"option": `package demo;
+enum Status { UNKNOWN = 0; ACTIVE = 1; }
+message User { required string name = 1; repeated int64 ids = 2; Status status = 3; }
+interface Principal { 128 = User; }`,
+		"type ID": `package demo;
+enum Status { UNKNOWN = 0; ACTIVE = 1; }
+message User { option framing = "length"; required string name = 1; repeated int64 ids = 2; Status status = 3; }
+interface Principal { 129 = User; }`,
+		"package": `package other;
+enum Status { UNKNOWN = 0; ACTIVE = 1; }
+message User { option framing = "length"; required string name = 1; repeated int64 ids = 2; Status status = 3; }
+interface Principal { 128 = User; }`,
+	} {
+		if got := fingerprintOf(t, input); got == base {
+			t.Errorf("changing the %s kept the fingerprint %s", name, got)
+		}
+	}
+}
+
+func TestGenerateSchemaHash(t *testing.T) {
+	for input, want := range map[string]bool{
+		"package demo;": false,
+		"package demo;\noption schema_hash = true;":  true,
+		"package demo;\noption schema_hash = false;": false,
+	} {
+		s, errs := ParseFile("demo.cram", input)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors: %v", errs)
+		}
+		if got := s.GenerateSchemaHash(); got != want {
+			t.Errorf("GenerateSchemaHash() for %q = %v, want %v", input, got, want)
+		}
+	}
+
+	s, _ := ParseFile("demo.cram", "package demo;\noption schema_hash = \"yes\";")
+	v := NewValidator(s)
+	v.Validate()
+	if !v.HasErrors() {
+		t.Error("expected an error for a non-boolean schema_hash option")
+	}
+}
@@ -73,3 +73,27 @@ func FuzzLexer(f *testing.F) {
 		}
 	})
 }
+
+// FuzzFormatSchema tests that formatting a parsed schema yields a schema
+// that parses and formats to the same text.
+func FuzzFormatSchema(f *testing.F) {
+	f.Add("package p;\nmessage M {\n  int32 x = 1 [min = 0];\n}\n")
+	f.Add("package p;\nimport \"a.cram\" as a;\noption s = \"a\\0b\\t\";\n")
+	f.Add("/// Doc.\n///\nenum E {\n  option allow_alias = true;\n  A = 0;\n  B = 0;\n}\n")
+	f.Add("interface I {\n  /// Impl.\n  128 = M;\n}\nmessage M @7 {\n  optional *M next = 1;\n}\n")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		schema, errs := ParseFile("fuzz.cram", input)
+		if len(errs) > 0 {
+			return
+		}
+		once := FormatSchema(schema)
+		schema, errs = ParseFile("fuzz.cram", once)
+		if len(errs) > 0 {
+			t.Fatalf("formatted schema does not parse: %v\n%s", errs, once)
+		}
+		if twice := FormatSchema(schema); twice != once {
+			t.Fatalf("formatting is not idempotent\n--- once:\n%s\n--- twice:\n%s", once, twice)
+		}
+	})
+}
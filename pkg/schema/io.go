@@ -1,11 +1,22 @@
 package schema
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode/utf8"
+)
+
+// StdinPath is the input path that names standard input, and StdinName
+// the file name that positions in a schema read from it carry.
+const (
+	StdinPath = "-"
+	StdinName = "<stdin>"
 )
 
 // Loader loads and resolves schema files.
@@ -13,6 +24,10 @@ type Loader struct {
 	// SearchPaths are directories to search for imported schemas.
 	SearchPaths []string
 
+	// Stdin is read, once, for the schema at StdinPath. Its imports are
+	// resolved relative to the working directory. If nil, os.Stdin is used.
+	Stdin io.Reader
+
 	// Loaded caches loaded schemas by their resolved path.
 	loaded map[string]*Schema
 
@@ -30,13 +45,51 @@ func NewLoader(searchPaths ...string) *Loader {
 }
 
 // LoadFile loads a schema file and all its imports.
+// The path StdinPath reads the schema from l.Stdin instead.
 func (l *Loader) LoadFile(path string) (*Schema, []error) {
-	absPath, err := filepath.Abs(path)
+	absPath, err := l.resolvePath(path)
 	if err != nil {
 		return nil, []error{fmt.Errorf("failed to resolve path: %w", err)}
 	}
 
-	return l.loadFileInternal(absPath, nil)
+	var schema *Schema
+	var errs []error
+	if path == StdinPath {
+		schema, errs = l.loadStdin(absPath)
+	} else {
+		schema, errs = l.loadFileInternal(absPath, nil)
+	}
+	if schema != nil {
+		// errs may be the cached slice, so never append to it in place
+		errs = append(errs[:len(errs):len(errs)], l.checkDefinitions(absPath)...)
+	}
+	return schema, errs
+}
+
+// resolvePath returns the key under which the schema at path is cached.
+// Standard input is cached as a file named StdinName in the working
+// directory, which is also where its imports are resolved.
+func (l *Loader) resolvePath(path string) (string, error) {
+	if path == StdinPath {
+		path = StdinName
+	}
+	return filepath.Abs(path)
+}
+
+// loadStdin loads the schema on standard input, caching it under key.
+func (l *Loader) loadStdin(key string) (*Schema, []error) {
+	if schema, ok := l.loaded[key]; ok {
+		return schema, l.loadedErrors[key]
+	}
+	r := l.Stdin
+	if r == nil {
+		r = os.Stdin
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to read standard input: %w", err)}
+	}
+	return l.loadSource(key, StdinName, content, nil)
 }
 
 // loadFileInternal loads a schema file, tracking the import chain to detect cycles.
@@ -58,9 +111,14 @@ func (l *Loader) loadFileInternal(absPath string, importChain []string) (*Schema
 	if err != nil {
 		return nil, []error{fmt.Errorf("failed to read file %s: %w", absPath, err)}
 	}
+	return l.loadSource(absPath, absPath, content, importChain)
+}
 
+// loadSource parses content, named filename in positions, and resolves
+// its imports, caching the result under absPath.
+func (l *Loader) loadSource(absPath, filename string, content []byte, importChain []string) (*Schema, []error) {
 	// Parse
-	schema, parseErrors := ParseFile(absPath, string(content))
+	schema, parseErrors := ParseFile(filename, string(content))
 	var allErrors []error
 	for _, e := range parseErrors {
 		allErrors = append(allErrors, e)
@@ -78,13 +136,14 @@ func (l *Loader) loadFileInternal(absPath string, importChain []string) (*Schema
 	// Resolve imports
 	baseDir := filepath.Dir(absPath)
 	importedSchemas := make(map[string]*Schema)
+	var importedPaths []string
 	newChain := append(importChain, absPath)
 
 	for _, imp := range schema.Imports {
 		importPath := l.resolveImportPath(imp.Path, baseDir)
 		if importPath == "" {
 			allErrors = append(allErrors, fmt.Errorf("%s:%d: import not found: %s",
-				absPath, imp.Position.Line, imp.Path))
+				filename, imp.Position.Line, imp.Path))
 			continue
 		}
 
@@ -98,8 +157,10 @@ func (l *Loader) loadFileInternal(absPath string, importChain []string) (*Schema
 				key = imp.Path
 			}
 			importedSchemas[key] = importedSchema
+			importedPaths = append(importedPaths, importPath)
 		}
 	}
+	l.addPublicImports(importedPaths, importedSchemas)
 
 	// Validate with imports
 	valErrors := ValidateWithImports(schema, importedSchemas)
@@ -113,6 +174,135 @@ func (l *Loader) loadFileInternal(absPath string, importChain []string) (*Schema
 	return schema, allErrors
 }
 
+// addPublicImports adds to imported the schemas re-exported with import
+// public by the files at paths, and those they re-export in turn, keyed by
+// alias or path as in the file that declares the public import. Direct
+// imports take precedence over re-exported ones with the same key.
+func (l *Loader) addPublicImports(paths []string, imported map[string]*Schema) {
+	seen := make(map[string]bool)
+	var visit func(path string)
+	visit = func(path string) {
+		s := l.loaded[path]
+		if seen[path] || s == nil {
+			return
+		}
+		seen[path] = true
+		for _, imp := range s.Imports {
+			if !imp.Public {
+				continue
+			}
+			importPath := l.resolveImportPath(imp.Path, filepath.Dir(path))
+			if importPath == "" || l.loaded[importPath] == nil {
+				continue
+			}
+			key := imp.Alias
+			if key == "" {
+				key = imp.Path
+			}
+			if _, ok := imported[key]; !ok {
+				imported[key] = l.loaded[importPath]
+			}
+			visit(importPath)
+		}
+	}
+	for _, path := range paths {
+		visit(path)
+	}
+}
+
+// messageDefinition is a message together with the file that defines it.
+type messageDefinition struct {
+	path string
+	msg  *Message
+}
+
+// checkDefinitions reports messages that are defined by more than one file
+// reachable from root under the same package and name, but disagree on the
+// number, name or type of a field. Each file is valid on its own, so this
+// catches inconsistencies that only show up when the files are built
+// together.
+func (l *Loader) checkDefinitions(root string) []error {
+	var order []string
+	seen := make(map[string]bool)
+	var visit func(path string)
+	visit = func(path string) {
+		s := l.loaded[path]
+		if seen[path] || s == nil {
+			return
+		}
+		seen[path] = true
+		order = append(order, path)
+		for _, imp := range s.Imports {
+			if importPath := l.resolveImportPath(imp.Path, filepath.Dir(path)); importPath != "" {
+				visit(importPath)
+			}
+		}
+	}
+	visit(root)
+
+	var errs []error
+	defined := make(map[string]messageDefinition)
+	for _, path := range order {
+		s := l.loaded[path]
+		pkg := ""
+		if s.Package != nil {
+			pkg = s.Package.Name
+		}
+		for _, msg := range s.Messages {
+			key := pkg + "." + msg.Name
+			first, ok := defined[key]
+			if !ok {
+				defined[key] = messageDefinition{path: path, msg: msg}
+				continue
+			}
+			if first.path != path {
+				errs = append(errs, compareDefinitions(key, first.msg, msg)...)
+			}
+		}
+	}
+	return errs
+}
+
+// compareDefinitions reports the fields on which two definitions of the
+// message named key disagree.
+func compareDefinitions(key string, first, other *Message) []error {
+	byName := make(map[string]*Field, len(first.Fields))
+	byNumber := make(map[int]*Field, len(first.Fields))
+	for _, f := range first.Fields {
+		byName[f.Name] = f
+		byNumber[f.Number] = f
+	}
+
+	var errs []error
+	conflict := func(f, prev *Field, format string, args ...any) {
+		errs = append(errs, ValidationError{
+			Position: f.Position,
+			Message: fmt.Sprintf("conflicting definitions of message %s: %s (previously defined at %s)",
+				key, fmt.Sprintf(format, args...), formatPosition(prev.Position)),
+			Severity: SeverityError,
+		})
+	}
+	for _, f := range other.Fields {
+		if prev, ok := byName[f.Name]; ok {
+			if prev.Number != f.Number {
+				conflict(f, prev, "field %s has number %d, previously %d", f.Name, f.Number, prev.Number)
+			} else if prev.Type.String() != f.Type.String() {
+				conflict(f, prev, "field %s has type %s, previously %s", f.Name, f.Type, prev.Type)
+			}
+			continue
+		}
+		if prev, ok := byNumber[f.Number]; ok {
+			conflict(f, prev, "field number %d is used by %s, previously by %s", f.Number, f.Name, prev.Name)
+		}
+	}
+	return errs
+}
+
+// formatPosition formats a position as file:line:column.
+func formatPosition(p Position) string {
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
 // resolveImportPath resolves an import path to an absolute file path.
 func (l *Loader) resolveImportPath(importPath, baseDir string) string {
 	// Try relative to current file first
@@ -136,7 +326,7 @@ func (l *Loader) resolveImportPath(importPath, baseDir string) string {
 
 // GetSchema returns a loaded schema by its path.
 func (l *Loader) GetSchema(path string) *Schema {
-	absPath, _ := filepath.Abs(path)
+	absPath, _ := l.resolvePath(path)
 	return l.loaded[absPath]
 }
 
@@ -150,10 +340,11 @@ func (l *Loader) AllSchemas() map[string]*Schema {
 }
 
 // GetImportedSchemas returns the imported schemas for a given schema file,
-// mapped by their import aliases. This is useful for code generators that
+// mapped by their import aliases, including schemas re-exported by its
+// imports with import public. This is useful for code generators that
 // need to know whether imported types are from the same package.
 func (l *Loader) GetImportedSchemas(path string) map[string]*Schema {
-	absPath, err := filepath.Abs(path)
+	absPath, err := l.resolvePath(path)
 	if err != nil {
 		return nil
 	}
@@ -165,6 +356,7 @@ func (l *Loader) GetImportedSchemas(path string) map[string]*Schema {
 
 	result := make(map[string]*Schema)
 	baseDir := filepath.Dir(absPath)
+	var paths []string
 
 	for _, imp := range s.Imports {
 		importPath := l.resolveImportPath(imp.Path, baseDir)
@@ -179,8 +371,10 @@ func (l *Loader) GetImportedSchemas(path string) map[string]*Schema {
 				key = imp.Path
 			}
 			result[key] = importedSchema
+			paths = append(paths, importPath)
 		}
 	}
+	l.addPublicImports(paths, result)
 
 	return result
 }
@@ -211,10 +405,14 @@ func (w *Writer) WriteSchema(out io.Writer, schema *Schema) error {
 
 	// Write imports
 	for _, imp := range schema.Imports {
+		modifier := ""
+		if imp.Public {
+			modifier = "public "
+		}
 		if imp.Alias != "" {
-			fmt.Fprintf(out, "import %q as %s;\n", imp.Path, imp.Alias)
+			fmt.Fprintf(out, "import %s%s as %s;\n", modifier, quoteString(imp.Path), imp.Alias)
 		} else {
-			fmt.Fprintf(out, "import %q;\n", imp.Path)
+			fmt.Fprintf(out, "import %s%s;\n", modifier, quoteString(imp.Path))
 		}
 	}
 	if len(schema.Imports) > 0 {
@@ -229,10 +427,17 @@ func (w *Writer) WriteSchema(out io.Writer, schema *Schema) error {
 		fmt.Fprintln(out)
 	}
 
-	// Write messages
-	for i, msg := range schema.Messages {
+	// Write messages. Those declared by group fields are written inline
+	// with the field.
+	var messages []*Message
+	for _, msg := range schema.Messages {
+		if !msg.Group {
+			messages = append(messages, msg)
+		}
+	}
+	for i, msg := range messages {
 		w.writeMessage(out, msg)
-		if i < len(schema.Messages)-1 || len(schema.Enums) > 0 || len(schema.Interfaces) > 0 {
+		if i < len(messages)-1 || len(schema.Enums) > 0 || len(schema.Interfaces) > 0 {
 			fmt.Fprintln(out)
 		}
 	}
@@ -256,14 +461,38 @@ func (w *Writer) WriteSchema(out io.Writer, schema *Schema) error {
 	return nil
 }
 
+// writeDirectives writes directives as ///@ comments at the given
+// indentation, quoting values so that they read back unchanged.
+func (w *Writer) writeDirectives(out io.Writer, indent string, directives []*Directive) {
+	for _, d := range directives {
+		if d.Value == "" {
+			fmt.Fprintf(out, "%s///@%s\n", indent, d.Key)
+		} else {
+			fmt.Fprintf(out, "%s///@%s:%s\n", indent, d.Key, strconv.Quote(d.Value))
+		}
+	}
+}
+
+// writeDocComments writes the doc comments among comments at the given
+// indentation. Empty lines are written without a trailing space.
+func (w *Writer) writeDocComments(out io.Writer, indent string, comments []*Comment) {
+	for _, comment := range comments {
+		if !comment.IsDoc {
+			continue
+		}
+		if comment.Text == "" {
+			fmt.Fprintf(out, "%s///\n", indent)
+		} else {
+			fmt.Fprintf(out, "%s/// %s\n", indent, comment.Text)
+		}
+	}
+}
+
 // writeMessage writes a message definition.
 func (w *Writer) writeMessage(out io.Writer, msg *Message) {
 	// Write doc comments
-	for _, comment := range msg.Comments {
-		if comment.IsDoc {
-			fmt.Fprintf(out, "/// %s\n", comment.Text)
-		}
-	}
+	w.writeDocComments(out, "", msg.Comments)
+	w.writeDirectives(out, "", msg.Directives)
 
 	// Write message header
 	if msg.TypeID > 0 {
@@ -272,27 +501,32 @@ func (w *Writer) writeMessage(out io.Writer, msg *Message) {
 		fmt.Fprintf(out, "message %s {\n", msg.Name)
 	}
 
+	w.writeMessageBody(out, w.indent, msg)
+	fmt.Fprintln(out, "}")
+}
+
+// writeMessageBody writes the options and fields of a message at the given
+// indentation.
+func (w *Writer) writeMessageBody(out io.Writer, indent string, msg *Message) {
 	// Write options
 	for _, opt := range msg.Options {
-		fmt.Fprintf(out, "%soption %s = %s;\n", w.indent, opt.Name, w.formatValue(opt.Value))
+		fmt.Fprintf(out, "%soption %s = %s;\n", indent, opt.Name, w.formatValue(opt.Value))
 	}
 
-	// Write fields
-	for _, field := range msg.Fields {
-		w.writeField(out, field)
+	// Write fields, keeping the blank lines that separate groups of them
+	for i, field := range msg.Fields {
+		if i > 0 && field.BlankLineBefore {
+			fmt.Fprintln(out)
+		}
+		w.writeField(out, indent, field)
 	}
-
-	fmt.Fprintln(out, "}")
 }
 
-// writeField writes a field definition.
-func (w *Writer) writeField(out io.Writer, field *Field) {
+// writeField writes a field definition at the given indentation.
+func (w *Writer) writeField(out io.Writer, indent string, field *Field) {
 	// Write doc comments
-	for _, comment := range field.Comments {
-		if comment.IsDoc {
-			fmt.Fprintf(out, "%s/// %s\n", w.indent, comment.Text)
-		}
-	}
+	w.writeDocComments(out, indent, field.Comments)
+	w.writeDirectives(out, indent, field.Directives)
 
 	var modifiers []string
 	if field.Required {
@@ -325,17 +559,20 @@ func (w *Writer) writeField(out io.Writer, field *Field) {
 		optStr = " [" + strings.Join(optParts, ", ") + "]"
 	}
 
-	fmt.Fprintf(out, "%s%s%s %s = %d%s;\n", w.indent, modStr, typeStr, field.Name, field.Number, optStr)
+	if field.Group != nil {
+		fmt.Fprintf(out, "%s%sgroup %s = %d%s {\n", indent, modStr, field.Name, field.Number, optStr)
+		w.writeMessageBody(out, indent+w.indent, field.Group)
+		fmt.Fprintf(out, "%s}\n", indent)
+		return
+	}
+	fmt.Fprintf(out, "%s%s%s %s = %d%s;\n", indent, modStr, typeStr, field.Name, field.Number, optStr)
 }
 
 // writeEnum writes an enum definition.
 func (w *Writer) writeEnum(out io.Writer, enum *Enum) {
 	// Write doc comments
-	for _, comment := range enum.Comments {
-		if comment.IsDoc {
-			fmt.Fprintf(out, "/// %s\n", comment.Text)
-		}
-	}
+	w.writeDocComments(out, "", enum.Comments)
+	w.writeDirectives(out, "", enum.Directives)
 
 	fmt.Fprintf(out, "enum %s {\n", enum.Name)
 
@@ -345,12 +582,12 @@ func (w *Writer) writeEnum(out io.Writer, enum *Enum) {
 	}
 
 	// Write values
-	for _, val := range enum.Values {
-		for _, comment := range val.Comments {
-			if comment.IsDoc {
-				fmt.Fprintf(out, "%s/// %s\n", w.indent, comment.Text)
-			}
+	for i, val := range enum.Values {
+		if i > 0 && val.BlankLineBefore {
+			fmt.Fprintln(out)
 		}
+		w.writeDocComments(out, w.indent, val.Comments)
+		w.writeDirectives(out, w.indent, val.Directives)
 		fmt.Fprintf(out, "%s%s = %d;\n", w.indent, val.Name, val.Number)
 	}
 
@@ -360,11 +597,8 @@ func (w *Writer) writeEnum(out io.Writer, enum *Enum) {
 // writeInterface writes an interface definition.
 func (w *Writer) writeInterface(out io.Writer, iface *Interface) {
 	// Write doc comments
-	for _, comment := range iface.Comments {
-		if comment.IsDoc {
-			fmt.Fprintf(out, "/// %s\n", comment.Text)
-		}
-	}
+	w.writeDocComments(out, "", iface.Comments)
+	w.writeDirectives(out, "", iface.Directives)
 
 	fmt.Fprintf(out, "interface %s {\n", iface.Name)
 
@@ -374,12 +608,12 @@ func (w *Writer) writeInterface(out io.Writer, iface *Interface) {
 	}
 
 	// Write implementations
-	for _, impl := range iface.Implementations {
-		for _, comment := range impl.Comments {
-			if comment.IsDoc {
-				fmt.Fprintf(out, "%s/// %s\n", w.indent, comment.Text)
-			}
+	for i, impl := range iface.Implementations {
+		if i > 0 && impl.BlankLineBefore {
+			fmt.Fprintln(out)
 		}
+		w.writeDocComments(out, w.indent, impl.Comments)
+		w.writeDirectives(out, w.indent, impl.Directives)
 		fmt.Fprintf(out, "%s%d = %s;\n", w.indent, impl.TypeID, impl.Type.String())
 	}
 
@@ -390,14 +624,24 @@ func (w *Writer) writeInterface(out io.Writer, iface *Interface) {
 func (w *Writer) formatValue(v Value) string {
 	switch val := v.(type) {
 	case *StringValue:
-		return fmt.Sprintf("%q", val.Value)
+		if strings.Contains(val.Value, "\n") && canWriteRaw(val.Value) {
+			return `"""` + "\n" + val.Value + `"""`
+		}
+		return quoteString(val.Value)
 	case *NumberValue:
 		return val.Value
+	case *BytesValue:
+		if val.Base64 {
+			return `b64"` + base64.StdEncoding.EncodeToString(val.Value) + `"`
+		}
+		return "0x" + hex.EncodeToString(val.Value)
 	case *BoolValue:
 		if val.Value {
 			return "true"
 		}
 		return "false"
+	case *IdentValue:
+		return val.Name
 	case *ListValue:
 		var parts []string
 		for _, elem := range val.Values {
@@ -409,6 +653,41 @@ func (w *Writer) formatValue(v Value) string {
 	}
 }
 
+// quoteString quotes s as a schema string literal. It only uses the escapes
+// the lexer understands; everything else, including other control characters
+// and non-printable runes, is written as is, which the lexer also accepts.
+func quoteString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case 0:
+			sb.WriteString(`\0`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// canWriteRaw reports whether s reads back unchanged from a triple-quoted
+// string, which keeps multi-line text readable.
+func canWriteRaw(s string) bool {
+	return utf8.ValidString(s) && !strings.Contains(s, `"""`) && !strings.HasSuffix(s, `"`) &&
+		!strings.ContainsAny(s, "\r\x00")
+}
+
 // WriteToFile writes a schema to a file.
 func WriteToFile(path string, schema *Schema) error {
 	f, err := os.Create(path)
@@ -422,6 +701,9 @@ func WriteToFile(path string, schema *Schema) error {
 }
 
 // FormatSchema returns a formatted string representation of a schema.
+// Fields, enum values and interface implementations that were separated by
+// blank lines in the source keep a single blank line between their groups;
+// other whitespace is normalized.
 func FormatSchema(schema *Schema) string {
 	var sb strings.Builder
 	writer := NewWriter()
@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -183,6 +184,7 @@ func TestWriterImports(t *testing.T) {
 		Imports: []*Import{
 			{Path: "other.cram"},
 			{Path: "types.cram", Alias: "types"},
+			{Path: "base.cram", Public: true},
 		},
 	}
 
@@ -194,6 +196,9 @@ func TestWriterImports(t *testing.T) {
 	if !strings.Contains(output, `import "types.cram" as types;`) {
 		t.Error("expected import with alias")
 	}
+	if !strings.Contains(output, `import public "base.cram";`) {
+		t.Error("expected public import")
+	}
 }
 
 func TestWriterOptions(t *testing.T) {
@@ -339,6 +344,104 @@ func TestWriterDocComments(t *testing.T) {
 	}
 }
 
+func TestWriterMultiLineStrings(t *testing.T) {
+	input := `package test;
+
+option description = """
+Orders placed by customers.
+Each order has "lines".""";
+option quoted = "tab\tand \"quotes\"";
+option windows = "one\r\ntwo";
+
+message Order {
+  string id = 1;
+}
+`
+	s, errs := ParseFile("test.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if got := s.Options[0].Value.(*StringValue).Value; got != "Orders placed by customers.\nEach order has \"lines\"." {
+		t.Errorf("description = %q", got)
+	}
+
+	// Multi-line text stays raw unless it can't be, as with carriage returns
+	output := FormatSchema(s)
+	if output != input {
+		t.Errorf("output differs from input:\n%s", output)
+	}
+
+	again, errs := ParseFile("test.cram", output)
+	if len(errs) > 0 {
+		t.Fatalf("reparse errors: %v", errs)
+	}
+	for i, opt := range again.Options {
+		if got, want := opt.Value.(*StringValue).Value, s.Options[i].Value.(*StringValue).Value; got != want {
+			t.Errorf("option %s = %q after reformatting, want %q", opt.Name, got, want)
+		}
+	}
+}
+
+func TestWriterGroups(t *testing.T) {
+	input := `package test;
+
+message Order {
+  string id = 1;
+  /// Where to send the bill.
+  optional group billing_address = 2 {
+    option framing = "length";
+    string street = 1;
+
+    group geo = 2 [since = "v2"] {
+      float64 lat = 1;
+    }
+  }
+}
+
+message Customer {
+  string name = 1;
+}
+`
+	s, errs := ParseFile("test.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if output := FormatSchema(s); output != input {
+		t.Errorf("groups were not written back inline:\n%s", output)
+	}
+}
+
+func TestWriterDirectives(t *testing.T) {
+	input := `package test;
+
+///@table:"users"
+message User {
+  /// Unique identifier.
+  ///@json:"userId"
+  ///@inline
+  int32 id = 1;
+}
+`
+	s, errs := ParseFile("test.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	output := FormatSchema(s)
+	for _, want := range []string{"///@table:\"users\"\nmessage User {", "  /// Unique identifier.\n  ///@json:\"userId\"\n  ///@inline\n  int32 id = 1;"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in:\n%s", want, output)
+		}
+	}
+
+	again, errs := ParseFile("test.cram", output)
+	if len(errs) > 0 {
+		t.Fatalf("reparse errors: %v", errs)
+	}
+	if FormatSchema(again) != output {
+		t.Errorf("formatting is not stable:\n%s", FormatSchema(again))
+	}
+}
+
 func TestWriterListValue(t *testing.T) {
 	schema := &Schema{
 		Package: &Package{Name: "test"},
@@ -503,6 +606,122 @@ message User {
 	}
 }
 
+func TestLoaderStdin(t *testing.T) {
+	tmpDir := t.TempDir()
+	typesPath := filepath.Join(tmpDir, "types.cram")
+	if err := os.WriteFile(typesPath, []byte("package types;\n\nmessage Address {\n  string street = 1;\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader(tmpDir)
+	loader.Stdin = strings.NewReader(`package main;
+
+import "types.cram" as types;
+
+message User {
+  int32 id = 1;
+  types.Address address = 2;
+}
+
+enum Role {
+  ROLE_UNKNOWN = 0;
+}
+`)
+	s, errs := loader.LoadFile(StdinPath)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(s.Messages) != 1 || len(s.Enums) != 1 {
+		t.Fatalf("expected 1 message and 1 enum, got %d and %d", len(s.Messages), len(s.Enums))
+	}
+	if got := s.Messages[0].Position.Filename; got != StdinName {
+		t.Errorf("position filename = %q, want %q", got, StdinName)
+	}
+	if imported := loader.GetImportedSchemas(StdinPath); imported["types"] == nil {
+		t.Errorf("imported schemas = %v, want types", imported)
+	}
+
+	// Standard input is read once; loading it again returns the same schema
+	again, errs := loader.LoadFile(StdinPath)
+	if again != s || len(errs) > 0 {
+		t.Errorf("second load returned %p, %v; want %p", again, errs, s)
+	}
+
+	loader = NewLoader()
+	loader.Stdin = strings.NewReader("package main;\n\nmessage User {\n  Missing m = 1;\n}\n")
+	if _, errs := loader.LoadFile(StdinPath); len(errs) == 0 || !strings.HasPrefix(errs[0].Error(), StdinName+":") {
+		t.Errorf("expected an error positioned in %s, got %v", StdinName, errs)
+	}
+}
+
+func TestLoaderPublicImports(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	write("base.cram", `
+package base;
+message Address { string street = 1; }
+`)
+	write("shared.cram", `
+package shared;
+message Name { string first = 1; }
+`)
+	// api re-exports base but keeps shared to itself
+	write("api.cram", `
+package api;
+import public "base.cram" as base;
+import "shared.cram" as shared;
+message Request { base.Address from = 1; shared.Name name = 2; }
+`)
+	// facade re-exports api, and with it base
+	write("facade.cram", `
+package facade;
+import public "api.cram" as api;
+`)
+
+	userPath := write("user.cram", `
+package user;
+import "api.cram" as api;
+message User { api.Request last = 1; base.Address home = 2; }
+`)
+	loader := NewLoader()
+	if _, errs := loader.LoadFile(userPath); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	imported := loader.GetImportedSchemas(userPath)
+	if imported["base"] == nil || imported["base"].Package.Name != "base" {
+		t.Errorf("expected base among imported schemas, got %v", imported)
+	}
+	if _, ok := imported["shared"]; ok {
+		t.Error("non-public import shared leaked into importing schema")
+	}
+
+	transitivePath := write("transitive.cram", `
+package transitive;
+import "facade.cram";
+message T { api.Request r = 1; base.Address a = 2; }
+`)
+	if _, errs := NewLoader().LoadFile(transitivePath); len(errs) > 0 {
+		t.Errorf("unexpected errors for transitive public import: %v", errs)
+	}
+
+	privatePath := write("private.cram", `
+package private;
+import "api.cram" as api;
+message P { shared.Name name = 1; }
+`)
+	_, errs := NewLoader().LoadFile(privatePath)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `unknown package "shared"`) {
+		t.Errorf("expected unknown package error for non-public import, got %v", errs)
+	}
+}
+
 func TestLoaderMissingImport(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -570,6 +789,105 @@ message B { int32 y = 1; }
 	}
 }
 
+func TestLoaderConflictingDefinitions(t *testing.T) {
+	const shared = `
+package shared;
+
+message Address {
+  string street = 1;
+  string city = 2;
+}
+`
+	tests := []struct {
+		name  string
+		other string
+		want  []string
+	}{
+		{
+			name: "consistent",
+			other: `
+package shared;
+
+message Address {
+  string street = 1;
+  string city = 2;
+  string zip = 3;
+}
+`,
+		},
+		{
+			name: "conflicting",
+			other: `
+package shared;
+
+message Address {
+  string street = 5;
+  int32 city = 2;
+  string zip = 1;
+}
+`,
+			want: []string{
+				"other.cram:5:3: error: conflicting definitions of message shared.Address: field street has number 5, previously 1 (previously defined at ",
+				"shared.cram:5:3)",
+				"field city has type int32, previously string",
+				"field number 1 is used by zip, previously by street",
+			},
+		},
+		{
+			name: "different package",
+			other: `
+package elsewhere;
+
+message Address {
+  int64 street = 1;
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			files := map[string]string{
+				"shared.cram": shared,
+				"other.cram":  tt.other,
+				"main.cram": `
+package main;
+
+import "shared.cram" as a;
+import "other.cram" as b;
+
+message User {
+  a.Address home = 1;
+}
+`,
+			}
+			for name, content := range files {
+				if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, errs := NewLoader().LoadFile(filepath.Join(tmpDir, "main.cram"))
+			if len(tt.want) == 0 {
+				if len(errs) > 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 3 {
+				t.Errorf("expected 3 errors, got %v", errs)
+			}
+			combined := fmt.Sprint(errs)
+			for _, want := range tt.want {
+				if !strings.Contains(combined, want) {
+					t.Errorf("expected %q in errors, got %v", want, errs)
+				}
+			}
+		})
+	}
+}
+
 func TestLoaderSearchPaths(t *testing.T) {
 	tmpDir := t.TempDir()
 	libDir := filepath.Join(tmpDir, "lib")
@@ -697,3 +1015,173 @@ message User {
 		t.Errorf("expected package 'test', got %q", schema.Package.Name)
 	}
 }
+
+// formatTwice formats input, reparses the result and formats it again.
+func formatTwice(t *testing.T, name, input string) (once, twice string) {
+	t.Helper()
+	s, errs := ParseFile(name, input)
+	if len(errs) > 0 {
+		t.Fatalf("%s: parse errors: %v", name, errs)
+	}
+	once = FormatSchema(s)
+	s, errs = ParseFile(name, once)
+	if len(errs) > 0 {
+		t.Fatalf("%s: formatted output does not parse: %v\n%s", name, errs, once)
+	}
+	return once, FormatSchema(s)
+}
+
+func TestFormatSchemaIdempotent(t *testing.T) {
+	var files []string
+	for _, pattern := range []string{
+		"../../tests/testdata/*.cram",
+		"../../examples/*.cram",
+		"../../examples/*/*.cram",
+		"../../benchmark/schemas/*.cram",
+	} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		t.Fatal("no schema files found")
+	}
+
+	for _, path := range files {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			once, twice := formatTwice(t, path, string(data))
+			if once != twice {
+				t.Errorf("formatting is not idempotent\n--- once:\n%s\n--- twice:\n%s", once, twice)
+			}
+		})
+	}
+}
+
+func TestFormatSchemaBlankLineGroups(t *testing.T) {
+	input := `package p;
+
+message User {
+  int64 id = 1;
+  string name = 2;
+
+
+
+  // contact details
+  string email = 3;
+  /// Phone number.
+  string phone = 4;
+
+  /// Postal address.
+
+  string address = 5;
+}
+
+enum Level {
+
+  LOW = 0;
+  MEDIUM = 1;
+
+  HIGH = 2;
+}
+
+interface Shape {
+  1 = User;
+
+  2 = User;
+}
+`
+	want := `package p;
+
+message User {
+  int64 id = 1;
+  string name = 2;
+
+  string email = 3;
+  /// Phone number.
+  string phone = 4;
+
+  /// Postal address.
+  string address = 5;
+}
+
+enum Level {
+  LOW = 0;
+  MEDIUM = 1;
+
+  HIGH = 2;
+}
+
+interface Shape {
+  1 = User;
+
+  2 = User;
+}
+`
+	once, twice := formatTwice(t, "test.cram", input)
+	if once != want {
+		t.Errorf("formatted output:\n%s\nwant:\n%s", once, want)
+	}
+	if once != twice {
+		t.Errorf("formatting is not idempotent\n--- once:\n%s\n--- twice:\n%s", once, twice)
+	}
+}
+
+func TestFormatSchemaRegressions(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string // expected to appear in the first formatting
+	}{
+		{
+			// Go-style quoting wrote \x00, which the lexer rejects
+			name:  "NUL in string",
+			input: "package p;\noption sep = \"a\\0b\";\n",
+			want:  `option sep = "a\0b";`,
+		},
+		{
+			// Go-style quoting wrote \x01 and \u00a0 escapes, which the lexer rejects
+			name:  "control and non-printable characters",
+			input: "package p;\noption s = \"\x01\u00a0\\t\\\"\\\\\";\n",
+			want:  "option s = \"\x01\u00a0\\t\\\"\\\\\";",
+		},
+		{
+			name:  "escaped import path",
+			input: "package p;\nimport \"dir\\\\a.cram\" as a;\n",
+			want:  `import "dir\\a.cram" as a;`,
+		},
+		{
+			// Empty doc comment lines were written with a trailing space
+			name:  "empty doc comment line",
+			input: "package p;\n/// First.\n///\n/// Second.\nmessage M {\n  ///\n  int32 x = 1;\n}\n",
+			want:  "/// First.\n///\n/// Second.\nmessage M {\n  ///\n  int32 x = 1;\n}\n",
+		},
+		{
+			name:  "hex byte literal",
+			input: "package p;\nmessage M {\n  bytes b = 1 [default = 0x0A1B];\n}\n",
+			want:  "bytes b = 1 [default = 0x0a1b];",
+		},
+		{
+			name:  "base64 byte literal",
+			input: "package p;\nmessage M {\n  bytes b = 1 [default = b64\"Chs=\"];\n}\n",
+			want:  `bytes b = 1 [default = b64"Chs="];`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			once, twice := formatTwice(t, "test.cram", tt.input)
+			if !strings.Contains(once, tt.want) {
+				t.Errorf("expected %q in output:\n%s", tt.want, once)
+			}
+			if once != twice {
+				t.Errorf("formatting is not idempotent\n--- once:\n%s\n--- twice:\n%s", once, twice)
+			}
+		})
+	}
+}
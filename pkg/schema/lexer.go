@@ -1,7 +1,11 @@
 package schema
 
 import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -20,6 +24,7 @@ const (
 	TokenInt    // integer literal
 	TokenFloat  // float literal
 	TokenString // string literal
+	TokenBytes  // byte string literal: 0x0a1b or b64"Chs="
 
 	// Keywords
 	TokenPackage    // package
@@ -55,6 +60,7 @@ const (
 	// Comments
 	TokenComment    // // comment
 	TokenDocComment // /// doc comment
+	TokenDirective  // ///@key:"value" directive
 )
 
 // String returns a human-readable name for the token type.
@@ -72,6 +78,8 @@ func (t TokenType) String() string {
 		return "Float"
 	case TokenString:
 		return "String"
+	case TokenBytes:
+		return "Bytes"
 	case TokenPackage:
 		return "package"
 	case TokenImport:
@@ -130,6 +138,8 @@ func (t TokenType) String() string {
 		return "Comment"
 	case TokenDocComment:
 		return "DocComment"
+	case TokenDirective:
+		return "Directive"
 	default:
 		return fmt.Sprintf("Token(%d)", t)
 	}
@@ -140,6 +150,10 @@ type Token struct {
 	Type     TokenType
 	Value    string
 	Position Position
+
+	// BlankLineBefore is set when the whitespace before the token holds
+	// at least one empty line.
+	BlankLineBefore bool
 }
 
 // String returns a string representation of the token.
@@ -168,6 +182,19 @@ var keywords = map[string]TokenType{
 	"deprecated": TokenDeprecated,
 }
 
+// isKeyword reports whether name is a reserved keyword of the schema language.
+func isKeyword(name string) bool {
+	_, ok := keywords[name]
+	return ok
+}
+
+// isKeywordToken reports whether tok is a keyword rather than, say, a string
+// with the same text.
+func isKeywordToken(tok Token) bool {
+	tt, ok := keywords[tok.Value]
+	return ok && tt == tok.Type
+}
+
 // Lexer tokenizes schema source code.
 type Lexer struct {
 	filename string
@@ -177,6 +204,7 @@ type Lexer struct {
 	column   int      // current column number (1-based)
 	start    int      // start position of current token
 	startPos Position // position of current token start
+	blank    bool     // whether a blank line precedes the current token
 }
 
 // NewLexer creates a new lexer for the given input.
@@ -213,12 +241,20 @@ func (l *Lexer) Next() Token {
 		return l.scanIdent()
 	}
 
+	// Handle hex byte literals before numbers
+	if ch == '0' && l.pos+1 < len(l.input) && (l.input[l.pos+1] == 'x' || l.input[l.pos+1] == 'X') {
+		return l.scanHexBytes()
+	}
+
 	// Handle numbers
 	if isDigit(ch) || (ch == '-' && l.pos+1 < len(l.input) && isDigit(rune(l.input[l.pos+1]))) {
 		return l.scanNumber()
 	}
 
 	// Handle strings
+	if strings.HasPrefix(l.input[l.pos:], `"""`) {
+		return l.scanRawString()
+	}
 	if ch == '"' {
 		return l.scanString()
 	}
@@ -280,11 +316,15 @@ func (l *Lexer) scanComment() Token {
 	l.advance()
 	l.advance()
 
-	// Check for doc comment ///
-	isDoc := false
+	// Check for doc comment /// and directive ///@
+	isDoc, isDirective := false, false
 	if l.pos < len(l.input) && l.input[l.pos] == '/' {
 		isDoc = true
 		l.advance()
+		if l.pos < len(l.input) && l.input[l.pos] == '@' {
+			isDirective = true
+			l.advance()
+		}
 	}
 
 	// Scan until end of line
@@ -294,12 +334,45 @@ func (l *Lexer) scanComment() Token {
 	}
 
 	text := strings.TrimSpace(l.input[start:l.pos])
+	if isDirective {
+		// Text that isn't a directive, such as ///@see Foo, was written as
+		// documentation and stays a doc comment.
+		if _, _, err := parseDirective(text); err == nil {
+			return l.token(TokenDirective, text)
+		}
+		text = "@" + text
+	}
 	if isDoc {
 		return l.token(TokenDocComment, text)
 	}
 	return l.token(TokenComment, text)
 }
 
+// parseDirective splits the text of a directive after ///@ into its key
+// and value. The key is an identifier, optionally followed by a colon and
+// either a quoted string or the rest of the line.
+func parseDirective(text string) (key, value string, err error) {
+	key, value, hasValue := strings.Cut(text, ":")
+	if key == "" {
+		return "", "", errors.New("missing key")
+	}
+	for i, ch := range key {
+		if ch != '_' && ch != '.' && !isLetter(ch) && (i == 0 || !isDigit(ch)) {
+			return "", "", fmt.Errorf("invalid key %q", key)
+		}
+	}
+	if !hasValue {
+		return key, "", nil
+	}
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, `"`) {
+		if value, err = strconv.Unquote(value); err != nil {
+			return "", "", errors.New("malformed quoted value")
+		}
+	}
+	return key, value, nil
+}
+
 // scanIdent scans an identifier or keyword.
 func (l *Lexer) scanIdent() Token {
 	for l.pos < len(l.input) {
@@ -312,6 +385,11 @@ func (l *Lexer) scanIdent() Token {
 
 	ident := l.input[l.start:l.pos]
 
+	// b64 directly followed by a quote starts a base64 byte literal
+	if ident == "b64" && l.pos < len(l.input) && l.input[l.pos] == '"' {
+		return l.scanBase64Bytes()
+	}
+
 	// Check for keywords
 	if tokType, ok := keywords[ident]; ok {
 		return l.token(tokType, ident)
@@ -372,7 +450,7 @@ func (l *Lexer) scanString() Token {
 	var sb strings.Builder
 	for {
 		if l.pos >= len(l.input) {
-			return l.errorf("unterminated string")
+			return l.errorf("unterminated string literal")
 		}
 
 		ch := l.input[l.pos]
@@ -382,7 +460,7 @@ func (l *Lexer) scanString() Token {
 		}
 
 		if ch == '\n' {
-			return l.errorf("newline in string literal")
+			return l.errorf(`newline in string literal (use """ for multi-line strings)`)
 		}
 
 		if ch == '\\' {
@@ -423,6 +501,99 @@ func (l *Lexer) scanString() Token {
 	return l.token(TokenString, sb.String())
 }
 
+// scanRawString scans a string literal enclosed in triple quotes. Its
+// content is taken as is, without escapes, and may span lines. A newline
+// directly after the opening quotes is dropped so that the content can
+// start on its own line, and carriage returns are discarded.
+func (l *Lexer) scanRawString() Token {
+	l.advance()
+	l.advance()
+	l.advance()
+	if strings.HasPrefix(l.input[l.pos:], "\r\n") {
+		l.advance()
+	}
+	if l.pos < len(l.input) && l.input[l.pos] == '\n' {
+		l.advance()
+	}
+
+	var sb strings.Builder
+	for {
+		if l.pos >= len(l.input) {
+			return l.errorf(`unterminated raw string literal: missing closing """`)
+		}
+		if strings.HasPrefix(l.input[l.pos:], `"""`) {
+			l.advance()
+			l.advance()
+			l.advance()
+			break
+		}
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if r == utf8.RuneError && size == 1 {
+			return l.errorf("invalid UTF-8 sequence in string")
+		}
+		if r != '\r' {
+			sb.WriteRune(r)
+		}
+		l.advance()
+	}
+
+	return l.token(TokenString, sb.String())
+}
+
+// scanHexBytes scans a byte literal written as 0x followed by pairs of
+// hex digits.
+func (l *Lexer) scanHexBytes() Token {
+	l.advance() // 0
+	l.advance() // x
+	for l.pos < len(l.input) && (isLetter(l.peek()) || isDigit(l.peek())) {
+		l.advance()
+	}
+	return l.bytesToken()
+}
+
+// scanBase64Bytes scans the quoted part of a b64"..." byte literal. The
+// text between the quotes is taken as is; base64 needs no escapes.
+func (l *Lexer) scanBase64Bytes() Token {
+	l.advance() // opening quote
+	for {
+		if l.pos >= len(l.input) {
+			return l.errorf("unterminated byte literal")
+		}
+		ch := l.input[l.pos]
+		if ch == '\n' {
+			return l.errorf("newline in byte literal")
+		}
+		l.advance()
+		if ch == '"' {
+			return l.bytesToken()
+		}
+	}
+}
+
+// bytesToken returns a TokenBytes holding the source text of the literal
+// just scanned, or an error if it doesn't decode.
+func (l *Lexer) bytesToken() Token {
+	text := l.input[l.start:l.pos]
+	if _, err := decodeBytesLiteral(text); err != nil {
+		return l.errorf("invalid byte literal %s: %v", text, err)
+	}
+	return l.token(TokenBytes, text)
+}
+
+// decodeBytesLiteral decodes the source text of a byte literal, either 0x
+// followed by pairs of hex digits or b64"..." holding standard padded
+// base64.
+func decodeBytesLiteral(text string) ([]byte, error) {
+	if rest, ok := strings.CutPrefix(text, `b64"`); ok {
+		return base64.StdEncoding.DecodeString(strings.TrimSuffix(rest, `"`))
+	}
+	digits := text[2:]
+	if digits == "" || len(digits)%2 != 0 {
+		return nil, errors.New("want an even, non-zero number of hex digits")
+	}
+	return hex.DecodeString(digits)
+}
+
 // Helper methods
 
 func (l *Lexer) currentPos() Position {
@@ -456,22 +627,29 @@ func (l *Lexer) advance() {
 	l.pos += size
 }
 
+// skipWhitespace skips whitespace, noting whether it spans a blank line.
 func (l *Lexer) skipWhitespace() {
+	newlines := 0
 	for l.pos < len(l.input) {
 		ch := l.input[l.pos]
 		if ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' {
+			if ch == '\n' {
+				newlines++
+			}
 			l.advance()
 		} else {
 			break
 		}
 	}
+	l.blank = newlines > 1
 }
 
 func (l *Lexer) token(typ TokenType, value string) Token {
 	return Token{
-		Type:     typ,
-		Value:    value,
-		Position: l.startPos,
+		Type:            typ,
+		Value:           value,
+		Position:        l.startPos,
+		BlankLineBefore: l.blank,
 	}
 }
 
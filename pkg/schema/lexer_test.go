@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -126,14 +127,116 @@ func TestLexerStringErrors(t *testing.T) {
 		{`"unterminated`, "unterminated string"},
 		{`"with\x escape"`, "unknown escape sequence"},
 		{"\"with\nnewline\"", "newline in string literal"},
+		{`"""never closed`, `unterminated raw string literal: missing closing """`},
+		{"\"\"\"\nspans\nlines\"\"", `missing closing """`},
 	}
 
 	for _, tt := range tests {
-		lexer := NewLexer("test.cram", tt.input)
+		lexer := NewLexer("test.cram", "option x = "+tt.input)
+		lexer.Next()
+		lexer.Next()
+		lexer.Next()
 		tok := lexer.Next()
 		if tok.Type != TokenError {
 			t.Errorf("input %q: expected Error, got %v", tt.input, tok.Type)
 		}
+		if !strings.Contains(tok.Value, tt.err) {
+			t.Errorf("input %q: error %q does not mention %q", tt.input, tok.Value, tt.err)
+		}
+		// Errors point at the opening quote
+		if tok.Position.Line != 1 || tok.Position.Column != 12 {
+			t.Errorf("input %q: error at %d:%d, want 1:12", tt.input, tok.Position.Line, tok.Position.Column)
+		}
+	}
+}
+
+func TestLexerRawStrings(t *testing.T) {
+	tests := []struct {
+		input string
+		value string
+	}{
+		{`""""""`, ""},
+		{`"""one line"""`, "one line"},
+		{"\"\"\"\nfirst\n  second\n\"\"\"", "first\n  second\n"},
+		{"\"\"\"\r\nwindows\r\nlines\"\"\"", "windows\nlines"},
+		{`"""no \n escapes or "quotes" here"""`, `no \n escapes or "quotes" here`},
+		{"\"\"\"\n\nblank first line\"\"\"", "\nblank first line"},
+	}
+
+	for _, tt := range tests {
+		lexer := NewLexer("test.cram", tt.input+";")
+		tok := lexer.Next()
+		if tok.Type != TokenString {
+			t.Errorf("input %q: expected String, got %v (%s)", tt.input, tok.Type, tok.Value)
+			continue
+		}
+		if tok.Value != tt.value {
+			t.Errorf("input %q: expected value %q, got %q", tt.input, tt.value, tok.Value)
+		}
+		if next := lexer.Next(); next.Type != TokenSemicolon {
+			t.Errorf("input %q: expected Semicolon after the string, got %v", tt.input, next)
+		}
+	}
+
+	// Lines inside a raw string count toward positions after it
+	lexer := NewLexer("test.cram", "\"\"\"\na\nb\"\"\" x")
+	lexer.Next()
+	if tok := lexer.Next(); tok.Position.Line != 3 || tok.Position.Column != 6 {
+		t.Errorf("token after raw string at %d:%d, want 3:6", tok.Position.Line, tok.Position.Column)
+	}
+}
+
+func TestLexerBytes(t *testing.T) {
+	tests := []struct {
+		input string
+		value string
+	}{
+		{"0x0A1B2C", "0x0A1B2C"},
+		{"0xff", "0xff"},
+		{"0X00", "0X00"},
+		{`b64"ChssAA=="`, `b64"ChssAA=="`},
+		{`b64""`, `b64""`},
+	}
+
+	for _, tt := range tests {
+		lexer := NewLexer("test.cram", tt.input+";")
+		tok := lexer.Next()
+		if tok.Type != TokenBytes {
+			t.Errorf("input %q: expected Bytes, got %v (%s)", tt.input, tok.Type, tok.Value)
+		}
+		if tok.Value != tt.value {
+			t.Errorf("input %q: expected value %q, got %q", tt.input, tt.value, tok.Value)
+		}
+		if next := lexer.Next(); next.Type != TokenSemicolon {
+			t.Errorf("input %q: expected ; after the literal, got %v", tt.input, next.Type)
+		}
+	}
+
+	// b64 on its own is still an identifier
+	if tok := NewLexer("test.cram", "b64 \"x\"").Next(); tok.Type != TokenIdent {
+		t.Errorf("expected b64 followed by a space to be an Ident, got %v", tok.Type)
+	}
+}
+
+func TestLexerBytesErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"0x", "even, non-zero number of hex digits"},
+		{"0xabc", "even, non-zero number of hex digits"},
+		{"0x0g", "invalid byte"},
+		{`b64"not base64!"`, "illegal base64 data"},
+		{`b64"Chs`, "unterminated byte literal"},
+		{"b64\"Ch\ns=\"", "newline in byte literal"},
+	}
+
+	for _, tt := range tests {
+		lexer := NewLexer("test.cram", tt.input)
+		tok := lexer.Next()
+		if tok.Type != TokenError || !strings.Contains(tok.Value, tt.err) {
+			t.Errorf("input %q: expected an error containing %q, got %v %q", tt.input, tt.err, tok.Type, tok.Value)
+		}
 	}
 }
 
@@ -184,6 +287,36 @@ func TestLexerComments(t *testing.T) {
 	}
 }
 
+func TestLexerDirectives(t *testing.T) {
+	tests := []struct {
+		input string
+		value string
+	}{
+		{`///@json:"customName"`, `json:"customName"`},
+		{"///@inline", "inline"},
+		{"///@go.type:  big.Int  ", "go.type:  big.Int"},
+	}
+	for _, tt := range tests {
+		tok := NewLexer("test.cram", tt.input).Next()
+		if tok.Type != TokenDirective || tok.Value != tt.value {
+			t.Errorf("input %q: got %v %q, want Directive %q", tt.input, tok.Type, tok.Value, tt.value)
+		}
+	}
+
+	// A space after /// makes an ordinary doc comment
+	if tok := NewLexer("test.cram", "/// @json").Next(); tok.Type != TokenDocComment {
+		t.Errorf("expected a doc comment, got %v", tok.Type)
+	}
+
+	// Text that doesn't parse as a directive is a doc comment too
+	for _, input := range []string{"///@", "///@:x", "///@1st", "///@see Foo", `///@json:"open`} {
+		tok := NewLexer("test.cram", input).Next()
+		if want := strings.TrimPrefix(input, "///"); tok.Type != TokenDocComment || tok.Value != want {
+			t.Errorf("input %q: got %v %q, want DocComment %q", input, tok.Type, tok.Value, want)
+		}
+	}
+}
+
 func TestLexerPositions(t *testing.T) {
 	input := "package foo\nmessage Bar {\n  int32 x = 1;\n}"
 
@@ -304,11 +437,17 @@ func TestLexerWhitespaceHandling(t *testing.T) {
 	if tok1.Value != "foo" {
 		t.Errorf("expected 'foo', got %q", tok1.Value)
 	}
+	if !tok1.BlankLineBefore {
+		t.Error("expected a blank line before 'foo'")
+	}
 
 	tok2 := lexer.Next()
 	if tok2.Value != "bar" {
 		t.Errorf("expected 'bar', got %q", tok2.Value)
 	}
+	if tok2.BlankLineBefore {
+		t.Error("unexpected blank line before 'bar'")
+	}
 
 	tok3 := lexer.Next()
 	if tok3.Type != TokenEOF {
@@ -343,6 +482,7 @@ func TestTokenTypeString(t *testing.T) {
 		{TokenInt, "Int"},
 		{TokenFloat, "Float"},
 		{TokenString, "String"},
+		{TokenBytes, "Bytes"},
 		{TokenPackage, "package"},
 		{TokenMessage, "message"},
 		{TokenLBrace, "{"},
@@ -3,15 +3,24 @@ package schema
 import (
 	"fmt"
 	"strconv"
+	"strings"
+
+	"github.com/blockberries/cramberry/internal/naming"
 )
 
 // Parser parses schema source code into an AST.
 type Parser struct {
-	lexer    *Lexer
-	current  Token
-	previous Token
-	errors   []ParseError
-	comments []*Comment // Collected comments
+	lexer      *Lexer
+	current    Token
+	previous   Token
+	errors     []ParseError
+	comments   []*Comment   // Collected comments
+	directives []*Directive // Directives awaiting their declaration
+	groups     []*Message   // Messages declared by group fields
+
+	// blankLine records whether a blank line separates the current token
+	// from the previous one, looking through any comments between them.
+	blankLine bool
 }
 
 // ParseError represents a parsing error.
@@ -53,7 +62,7 @@ func (p *Parser) Parse() (*Schema, []ParseError) {
 	}
 
 	// Parse imports and top-level options (can be intermixed in any order)
-	for p.check(TokenImport) || p.check(TokenOption) {
+	for p.collectComments(); p.check(TokenImport) || p.check(TokenOption); p.collectComments() {
 		if p.check(TokenImport) {
 			imp, err := p.parseImport()
 			if err != nil {
@@ -85,7 +94,9 @@ func (p *Parser) Parse() (*Schema, []ParseError) {
 				p.synchronize()
 			} else {
 				schema.Messages = append(schema.Messages, msg)
+				schema.Messages = append(schema.Messages, p.groups...)
 			}
+			p.groups = nil
 		case p.check(TokenEnum):
 			enum, err := p.parseEnum()
 			if err != nil {
@@ -102,7 +113,7 @@ func (p *Parser) Parse() (*Schema, []ParseError) {
 			} else {
 				schema.Interfaces = append(schema.Interfaces, iface)
 			}
-		case p.check(TokenComment), p.check(TokenDocComment):
+		case p.check(TokenComment), p.check(TokenDocComment), p.check(TokenDirective):
 			p.advance()
 		case p.check(TokenEOF):
 			break
@@ -115,12 +126,14 @@ func (p *Parser) Parse() (*Schema, []ParseError) {
 		}
 	}
 
+	p.orphanDirectives()
 	schema.Comments = p.comments
 	return schema, p.errors
 }
 
 // parsePackage parses: 'package' identifier ';'
 func (p *Parser) parsePackage() (*Package, *ParseError) {
+	p.orphanDirectives()
 	startPos := p.current.Position
 	p.advance() // consume 'package'
 
@@ -144,9 +157,18 @@ func (p *Parser) parsePackage() (*Package, *ParseError) {
 
 // parseImport parses: 'import' string ('as' identifier)? ';'
 func (p *Parser) parseImport() (*Import, *ParseError) {
+	p.orphanDirectives()
 	startPos := p.current.Position
 	p.advance() // consume 'import'
 
+	// 'public' is not a keyword, so that it stays usable as a name; here
+	// it can only be a modifier, since the path must be a string
+	public := false
+	if p.check(TokenIdent) && p.current.Value == "public" {
+		public = true
+		p.advance()
+	}
+
 	if !p.check(TokenString) {
 		return nil, p.error("expected import path string")
 	}
@@ -173,19 +195,20 @@ func (p *Parser) parseImport() (*Import, *ParseError) {
 		EndPos:   endPos,
 		Path:     path,
 		Alias:    alias,
+		Public:   public,
 	}, nil
 }
 
 // parseOption parses: 'option' identifier '=' value ';'
 func (p *Parser) parseOption() (*Option, *ParseError) {
+	p.orphanDirectives()
 	startPos := p.current.Position
 	p.advance() // consume 'option'
 
-	if !p.check(TokenIdent) {
-		return nil, p.error("expected option name")
+	name, err := p.parseOptionName()
+	if err != nil {
+		return nil, err
 	}
-	name := p.current.Value
-	p.advance()
 
 	if !p.consume(TokenEquals, "expected '=' after option name") {
 		return nil, p.error("expected '=' after option name")
@@ -209,7 +232,70 @@ func (p *Parser) parseOption() (*Option, *ParseError) {
 	}, nil
 }
 
-// parseValue parses a value (string, number, bool, or list).
+// parseOptionName parses: ( identifier | '(' dottedName ')' ) ( '.' word )*
+//
+// Parenthesized and dotted names, as in option (go.package) = "..." or
+// option a.b.c = 1, are kept verbatim so that schemas can carry options for
+// other generators and tools. Options nothing recognizes are ignored.
+func (p *Parser) parseOptionName() (string, *ParseError) {
+	var name strings.Builder
+	if p.check(TokenLParen) {
+		p.advance()
+		inner, err := p.parseDottedWords()
+		if err != nil {
+			return "", err
+		}
+		if !p.consume(TokenRParen, "expected ')' after option name") {
+			return "", p.error("expected ')' after option name")
+		}
+		name.WriteString("(" + inner + ")")
+	} else {
+		if !p.check(TokenIdent) {
+			return "", p.error("expected option name")
+		}
+		name.WriteString(p.current.Value)
+		p.advance()
+	}
+	for p.check(TokenDot) {
+		p.advance()
+		if !p.checkWord() {
+			return "", p.error("expected option name after '.'")
+		}
+		name.WriteString("." + p.current.Value)
+		p.advance()
+	}
+	return name.String(), nil
+}
+
+// parseDottedWords parses: word ( '.' word )*
+func (p *Parser) parseDottedWords() (string, *ParseError) {
+	if !p.checkWord() {
+		return "", p.error("expected option name")
+	}
+	words := []string{p.current.Value}
+	p.advance()
+	for p.check(TokenDot) {
+		p.advance()
+		if !p.checkWord() {
+			return "", p.error("expected option name after '.'")
+		}
+		words = append(words, p.current.Value)
+		p.advance()
+	}
+	return strings.Join(words, "."), nil
+}
+
+// checkWord reports whether the current token is an identifier or a
+// keyword, either of which may appear within a qualified option name.
+func (p *Parser) checkWord() bool {
+	if p.check(TokenIdent) {
+		return true
+	}
+	typ, ok := keywords[p.current.Value]
+	return ok && typ == p.current.Type
+}
+
+// parseValue parses a value (string, number, bool, identifier, or list).
 func (p *Parser) parseValue() (Value, *ParseError) {
 	startPos := p.current.Position
 
@@ -238,6 +324,20 @@ func (p *Parser) parseValue() (Value, *ParseError) {
 			IsFloat:  isFloat,
 		}, nil
 
+	case TokenBytes:
+		text := p.current.Value
+		// The lexer has already checked that the literal decodes
+		value, _ := decodeBytesLiteral(text)
+		endPos := p.current.Position
+		endPos.Column += len(text)
+		p.advance()
+		return &BytesValue{
+			Position: startPos,
+			EndPos:   endPos,
+			Value:    value,
+			Base64:   strings.HasPrefix(text, "b64"),
+		}, nil
+
 	case TokenTrue:
 		endPos := p.current.Position
 		endPos.Column += 4
@@ -258,6 +358,27 @@ func (p *Parser) parseValue() (Value, *ParseError) {
 			Value:    false,
 		}, nil
 
+	case TokenIdent:
+		name := p.current.Value
+		endPos := p.current.Position
+		endPos.Column += len(name)
+		p.advance()
+		for p.check(TokenDot) {
+			p.advance()
+			if !p.check(TokenIdent) {
+				return nil, p.error("expected identifier after '.'")
+			}
+			name += "." + p.current.Value
+			endPos = p.current.Position
+			endPos.Column += len(p.current.Value)
+			p.advance()
+		}
+		return &IdentValue{
+			Position: startPos,
+			EndPos:   endPos,
+			Name:     name,
+		}, nil
+
 	case TokenLBracket:
 		return p.parseListValue()
 
@@ -301,6 +422,7 @@ func (p *Parser) parseListValue() (*ListValue, *ParseError) {
 // parseMessage parses: 'message' identifier '{' field* '}'
 func (p *Parser) parseMessage() (*Message, *ParseError) {
 	docComments := p.getDocComments()
+	directives := p.getDirectives()
 	startPos := p.current.Position
 	p.advance() // consume 'message'
 
@@ -329,6 +451,31 @@ func (p *Parser) parseMessage() (*Message, *ParseError) {
 		return nil, p.error("expected '{' after message name")
 	}
 
+	fields, options, err := p.parseMessageBody(name)
+	if err != nil {
+		return nil, err
+	}
+
+	endPos := p.current.Position
+	if !p.consume(TokenRBrace, "expected '}'") {
+		return nil, p.error("expected '}'")
+	}
+
+	return &Message{
+		Position:   startPos,
+		EndPos:     endPos,
+		Name:       name,
+		Fields:     fields,
+		Options:    options,
+		Comments:   docComments,
+		Directives: directives,
+		TypeID:     typeID,
+	}, nil
+}
+
+// parseMessageBody parses the options and fields of the message named
+// name, up to but not including its closing brace.
+func (p *Parser) parseMessageBody(name string) ([]*Field, []*Option, *ParseError) {
 	var fields []*Field
 	var options []*Option
 	for !p.check(TokenRBrace) && !p.check(TokenEOF) {
@@ -337,40 +484,33 @@ func (p *Parser) parseMessage() (*Message, *ParseError) {
 		if p.check(TokenOption) {
 			opt, err := p.parseOption()
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			options = append(options, opt)
 		} else if p.check(TokenRBrace) {
+			p.orphanDirectives()
 			break
 		} else {
-			field, err := p.parseField()
+			field, err := p.parseField(name)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			fields = append(fields, field)
 		}
 	}
-
-	endPos := p.current.Position
-	if !p.consume(TokenRBrace, "expected '}'") {
-		return nil, p.error("expected '}'")
-	}
-
-	return &Message{
-		Position: startPos,
-		EndPos:   endPos,
-		Name:     name,
-		Fields:   fields,
-		Options:  options,
-		Comments: docComments,
-		TypeID:   typeID,
-	}, nil
+	return fields, options, nil
 }
 
 // parseField parses: modifier? type identifier '=' number options? ';'
-func (p *Parser) parseField() (*Field, *ParseError) {
+// or, for a group: modifier? 'group' identifier '=' number options? '{' body '}'
+//
+// parent names the enclosing message, which prefixes the names of the
+// messages that groups declare.
+func (p *Parser) parseField(parent string) (*Field, *ParseError) {
 	docComments := p.getDocComments()
+	directives := p.getDirectives()
 	startPos := p.current.Position
+	blankLine := p.blankLine
 
 	// Parse modifiers
 	var required, repeated, optional, deprecated bool
@@ -400,8 +540,9 @@ parseType:
 		return nil, err
 	}
 
-	// Parse field name
-	if !p.check(TokenIdent) {
+	// Parse field name. A keyword is taken as the name so that the
+	// validator can reject it clearly rather than the parser losing its place
+	if !p.check(TokenIdent) && !isKeywordToken(p.current) {
 		return nil, p.error("expected field name")
 	}
 	name := p.current.Value
@@ -420,6 +561,11 @@ parseType:
 	if parseErr != nil {
 		return nil, p.error("invalid field number")
 	}
+	// Field numbers are encoded unsigned in tags. The rest of the field
+	// still parses, so the error is recorded without aborting.
+	if num <= 0 {
+		p.errors = append(p.errors, *p.error(fmt.Sprintf("field number must be positive, got %d", num)))
+	}
 	p.advance()
 
 	// Parse optional field options
@@ -432,23 +578,44 @@ parseType:
 		options = opts
 	}
 
+	// A field of type "group" with a body declares a group. Without a body
+	// it refers to a type named group, so group is only a keyword here.
+	nt, _ := typeRef.(*NamedType)
+	group := p.check(TokenLBrace) && nt != nil && nt.Package == "" && nt.Name == "group"
+
+	// A group's type is the message it declares, named after the field
+	var groupMsg *Message
+	if group {
+		nt.Name = parent + naming.Pascal(name)
+		msg, err := p.parseGroupBody(nt.Name, startPos)
+		if err != nil {
+			return nil, err
+		}
+		groupMsg = msg
+	}
+
 	endPos := p.current.Position
-	if !p.consume(TokenSemicolon, "expected ';' after field") {
+	if group {
+		endPos = groupMsg.EndPos
+	} else if !p.consume(TokenSemicolon, "expected ';' after field") {
 		return nil, p.error("expected ';' after field")
 	}
 
 	field := &Field{
-		Position:   startPos,
-		EndPos:     endPos,
-		Name:       name,
-		Number:     num,
-		Type:       typeRef,
-		Options:    options,
-		Comments:   docComments,
-		Required:   required,
-		Repeated:   repeated,
-		Optional:   optional,
-		Deprecated: deprecated,
+		Position:        startPos,
+		EndPos:          endPos,
+		Name:            name,
+		Number:          num,
+		Type:            typeRef,
+		Options:         options,
+		Comments:        docComments,
+		Directives:      directives,
+		BlankLineBefore: blankLine,
+		Required:        required,
+		Repeated:        repeated,
+		Optional:        optional,
+		Deprecated:      deprecated,
+		Group:           groupMsg,
 	}
 
 	// Handle map type specially
@@ -460,6 +627,33 @@ parseType:
 	return field, nil
 }
 
+// parseGroupBody parses the braced body of a group field into the message
+// named name, which is queued to follow the enclosing top-level message.
+func (p *Parser) parseGroupBody(name string, startPos Position) (*Message, *ParseError) {
+	p.advance() // consume '{'
+
+	fields, options, err := p.parseMessageBody(name)
+	if err != nil {
+		return nil, err
+	}
+
+	endPos := p.current.Position
+	if !p.consume(TokenRBrace, "expected '}'") {
+		return nil, p.error("expected '}'")
+	}
+
+	msg := &Message{
+		Position: startPos,
+		EndPos:   endPos,
+		Name:     name,
+		Fields:   fields,
+		Options:  options,
+		Group:    true,
+	}
+	p.groups = append(p.groups, msg)
+	return msg, nil
+}
+
 // parseFieldOptions parses: '[' (identifier '=' value)* ']'
 func (p *Parser) parseFieldOptions() ([]*Option, *ParseError) {
 	p.advance() // consume '['
@@ -468,11 +662,10 @@ func (p *Parser) parseFieldOptions() ([]*Option, *ParseError) {
 	for !p.check(TokenRBracket) && !p.check(TokenEOF) {
 		startPos := p.current.Position
 
-		if !p.check(TokenIdent) {
-			return nil, p.error("expected option name")
+		name, err := p.parseOptionName()
+		if err != nil {
+			return nil, err
 		}
-		name := p.current.Value
-		p.advance()
 
 		if !p.consume(TokenEquals, "expected '=' after option name") {
 			return nil, p.error("expected '=' after option name")
@@ -619,6 +812,7 @@ func (p *Parser) parseTypeRef() (TypeRef, *ParseError) {
 // parseEnum parses: 'enum' identifier '{' enumValue* '}'
 func (p *Parser) parseEnum() (*Enum, *ParseError) {
 	docComments := p.getDocComments()
+	directives := p.getDirectives()
 	startPos := p.current.Position
 	p.advance() // consume 'enum'
 
@@ -644,6 +838,7 @@ func (p *Parser) parseEnum() (*Enum, *ParseError) {
 			}
 			options = append(options, opt)
 		} else if p.check(TokenRBrace) {
+			p.orphanDirectives()
 			break
 		} else {
 			val, err := p.parseEnumValue()
@@ -660,19 +855,22 @@ func (p *Parser) parseEnum() (*Enum, *ParseError) {
 	}
 
 	return &Enum{
-		Position: startPos,
-		EndPos:   endPos,
-		Name:     name,
-		Values:   values,
-		Options:  options,
-		Comments: docComments,
+		Position:   startPos,
+		EndPos:     endPos,
+		Name:       name,
+		Values:     values,
+		Options:    options,
+		Comments:   docComments,
+		Directives: directives,
 	}, nil
 }
 
 // parseEnumValue parses: identifier '=' number ';'
 func (p *Parser) parseEnumValue() (*EnumValue, *ParseError) {
 	docComments := p.getDocComments()
+	directives := p.getDirectives()
 	startPos := p.current.Position
+	blankLine := p.blankLine
 
 	if !p.check(TokenIdent) {
 		return nil, p.error("expected enum value name")
@@ -699,17 +897,20 @@ func (p *Parser) parseEnumValue() (*EnumValue, *ParseError) {
 	}
 
 	return &EnumValue{
-		Position: startPos,
-		EndPos:   endPos,
-		Name:     name,
-		Number:   num,
-		Comments: docComments,
+		Position:        startPos,
+		EndPos:          endPos,
+		Name:            name,
+		Number:          num,
+		Comments:        docComments,
+		Directives:      directives,
+		BlankLineBefore: blankLine,
 	}, nil
 }
 
 // parseInterface parses: 'interface' identifier '{' implementation* '}'
 func (p *Parser) parseInterface() (*Interface, *ParseError) {
 	docComments := p.getDocComments()
+	directives := p.getDirectives()
 	startPos := p.current.Position
 	p.advance() // consume 'interface'
 
@@ -735,6 +936,7 @@ func (p *Parser) parseInterface() (*Interface, *ParseError) {
 			}
 			options = append(options, opt)
 		} else if p.check(TokenRBrace) {
+			p.orphanDirectives()
 			break
 		} else {
 			impl, err := p.parseImplementation()
@@ -757,13 +959,16 @@ func (p *Parser) parseInterface() (*Interface, *ParseError) {
 		Implementations: implementations,
 		Options:         options,
 		Comments:        docComments,
+		Directives:      directives,
 	}, nil
 }
 
 // parseImplementation parses: number '=' identifier ';'
 func (p *Parser) parseImplementation() (*Implementation, *ParseError) {
 	docComments := p.getDocComments()
+	directives := p.getDirectives()
 	startPos := p.current.Position
+	blankLine := p.blankLine
 
 	if !p.check(TokenInt) {
 		return nil, p.error("expected type ID")
@@ -813,7 +1018,9 @@ func (p *Parser) parseImplementation() (*Implementation, *ParseError) {
 			Package:  pkg,
 			Name:     name,
 		},
-		Comments: docComments,
+		Comments:        docComments,
+		Directives:      directives,
+		BlankLineBefore: blankLine,
 	}, nil
 }
 
@@ -822,10 +1029,12 @@ func (p *Parser) parseImplementation() (*Implementation, *ParseError) {
 func (p *Parser) advance() {
 	p.previous = p.current
 	p.current = p.lexer.Next()
+	p.blankLine = p.current.BlankLineBefore
 
 	// Skip regular comments, but remember doc comments
 	for p.current.Type == TokenComment {
 		p.current = p.lexer.Next()
+		p.blankLine = p.blankLine || p.current.BlankLineBefore
 	}
 }
 
@@ -851,6 +1060,8 @@ func (p *Parser) error(msg string) *ParseError {
 // synchronize skips tokens until we find a likely sync point at the top level.
 // It tracks brace depth to avoid stopping at inner closing braces.
 func (p *Parser) synchronize() {
+	// Directives read before the error belong to the declaration that failed
+	p.directives = nil
 	braceDepth := 0
 
 	for !p.check(TokenEOF) {
@@ -882,16 +1093,26 @@ func (p *Parser) synchronize() {
 
 // collectComments collects doc comments preceding the current position.
 func (p *Parser) collectComments() {
-	for p.current.Type == TokenDocComment || p.current.Type == TokenComment {
-		if p.current.Type == TokenDocComment {
+	for p.current.Type == TokenDocComment || p.current.Type == TokenComment || p.current.Type == TokenDirective {
+		switch p.current.Type {
+		case TokenDocComment:
 			p.comments = append(p.comments, &Comment{
 				Position: p.current.Position,
 				EndPos:   p.current.Position,
 				Text:     p.current.Value,
 				IsDoc:    true,
 			})
+		case TokenDirective:
+			key, value, _ := parseDirective(p.current.Value)
+			p.directives = append(p.directives, &Directive{
+				Position: p.current.Position,
+				EndPos:   p.current.Position,
+				Key:      key,
+				Value:    value,
+			})
 		}
 		p.current = p.lexer.Next()
+		p.blankLine = p.blankLine || p.current.BlankLineBefore
 	}
 }
 
@@ -905,6 +1126,26 @@ func (p *Parser) getDocComments() []*Comment {
 	return result
 }
 
+// getDirectives returns the directives collected since the last
+// declaration, which apply to the next one.
+func (p *Parser) getDirectives() []*Directive {
+	result := p.directives
+	p.directives = nil
+	return result
+}
+
+// orphanDirectives reports the directives collected since the last
+// declaration as errors, since what follows them is not a declaration they
+// can apply to, such as a package statement, an option or a closing brace.
+func (p *Parser) orphanDirectives() {
+	for _, d := range p.getDirectives() {
+		p.errors = append(p.errors, ParseError{
+			Position: d.Position,
+			Message:  fmt.Sprintf("directive @%s is not followed by a declaration", d.Key),
+		})
+	}
+}
+
 // ParseFile is a convenience function that parses a schema file.
 func ParseFile(filename, input string) (*Schema, []ParseError) {
 	parser := NewParser(filename, input)
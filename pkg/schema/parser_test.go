@@ -1,6 +1,9 @@
 package schema
 
 import (
+	"bytes"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -51,6 +54,36 @@ import "another.cram" as another;
 	}
 }
 
+func TestParsePublicImport(t *testing.T) {
+	input := `
+package test;
+import public "base.cram";
+import public "types.cram" as types;
+import "other.cram";
+message public { int32 public = 1; }
+`
+
+	schema, errors := ParseFile("test.cram", input)
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+
+	want := []Import{
+		{Path: "base.cram", Public: true},
+		{Path: "types.cram", Alias: "types", Public: true},
+		{Path: "other.cram"},
+	}
+	if len(schema.Imports) != len(want) {
+		t.Fatalf("expected %d imports, got %d", len(want), len(schema.Imports))
+	}
+	for i, imp := range schema.Imports {
+		if imp.Path != want[i].Path || imp.Alias != want[i].Alias || imp.Public != want[i].Public {
+			t.Errorf("import %d: got path %q alias %q public %v, want %q %q %v",
+				i, imp.Path, imp.Alias, imp.Public, want[i].Path, want[i].Alias, want[i].Public)
+		}
+	}
+}
+
 func TestParseOption(t *testing.T) {
 	input := `
 package test;
@@ -93,6 +126,78 @@ option max_size = 1024;
 	}
 }
 
+func TestParseQualifiedOptionNames(t *testing.T) {
+	input := `package test;
+
+option (go.package) = "example.com/test";
+option a.b.c = 1;
+
+message Event {
+  option (acme.table).name = "events";
+
+  int64 id = 1 [(validate.rules) = "gt 0", x.y = true];
+}
+`
+
+	s, errs := ParseFile("test.cram", input)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	names := func(opts []*Option) []string {
+		var out []string
+		for _, opt := range opts {
+			out = append(out, opt.Name)
+		}
+		return out
+	}
+	msg := s.Messages[0]
+	for _, tt := range []struct {
+		got, want []string
+	}{
+		{names(s.Options), []string{"(go.package)", "a.b.c"}},
+		{names(msg.Options), []string{"(acme.table).name"}},
+		{names(msg.Fields[0].Options), []string{"(validate.rules)", "x.y"}},
+	} {
+		if strings.Join(tt.got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("option names = %q, want %q", tt.got, tt.want)
+		}
+	}
+	if errs := Validate(s); len(errs) > 0 {
+		t.Errorf("custom options should be ignored by validation: %v", errs)
+	}
+
+	out := FormatSchema(s)
+	for _, want := range []string{
+		`option (go.package) = "example.com/test";`,
+		"option a.b.c = 1;",
+		`option (acme.table).name = "events";`,
+		`[(validate.rules) = "gt 0", x.y = true]`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("formatted schema missing %q:\n%s", want, out)
+		}
+	}
+	again, errs := ParseFile("test.cram", out)
+	if len(errs) > 0 {
+		t.Fatalf("reparse errors: %v", errs)
+	}
+	if FormatSchema(again) != out {
+		t.Errorf("formatting is not stable:\n%s", FormatSchema(again))
+	}
+
+	for _, bad := range []string{
+		"option (go.package = 1;",
+		"option () = 1;",
+		"option a. = 1;",
+		"option .a = 1;",
+	} {
+		if _, errs := ParseFile("test.cram", "package test;\n"+bad+"\n"); len(errs) == 0 {
+			t.Errorf("expected a parse error for %q", bad)
+		}
+	}
+}
+
 func TestParseImportsAndOptionsIntermixed(t *testing.T) {
 	// Test that imports can come before, after, and intermixed with options
 	tests := []struct {
@@ -330,6 +435,40 @@ message User @128 {
 	}
 }
 
+func TestParseMessageFramingOption(t *testing.T) {
+	input := `
+package test;
+
+message Header {
+  option framing = "length";
+  int32 id = 1;
+}
+
+message Body {
+  string text = 1;
+}
+`
+
+	schema, errors := ParseFile("test.cram", input)
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+
+	header := schema.Messages[0]
+	if len(header.Options) != 1 || header.Options[0].Name != "framing" {
+		t.Fatalf("expected framing option, got %v", header.Options)
+	}
+	if len(header.Fields) != 1 {
+		t.Errorf("expected 1 field, got %d", len(header.Fields))
+	}
+	if got := header.Framing(); got != FramingLength {
+		t.Errorf("Header.Framing() = %q, want %q", got, FramingLength)
+	}
+	if got := schema.Messages[1].Framing(); got != FramingMarker {
+		t.Errorf("Body.Framing() = %q, want %q", got, FramingMarker)
+	}
+}
+
 func TestParseMessageWithFieldOptions(t *testing.T) {
 	input := `
 package test;
@@ -407,6 +546,74 @@ enum Status {
 	}
 }
 
+func TestParseEnumAllowAlias(t *testing.T) {
+	input := `
+package test;
+
+enum Status {
+  option allow_alias = true;
+  UNKNOWN = 0;
+  STARTED = 1;
+  RUNNING = 1;
+}
+
+enum Plain {
+  NONE = 0;
+}
+`
+
+	schema, errors := ParseFile("test.cram", input)
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+
+	status := schema.Enums[0]
+	if len(status.Options) != 1 || status.Options[0].Name != "allow_alias" {
+		t.Fatalf("expected allow_alias option, got %v", status.Options)
+	}
+	if len(status.Values) != 3 {
+		t.Fatalf("expected 3 enum values, got %d", len(status.Values))
+	}
+	if !status.AllowAlias() {
+		t.Error("Status.AllowAlias() = false, want true")
+	}
+	if schema.Enums[1].AllowAlias() {
+		t.Error("Plain.AllowAlias() = true, want false")
+	}
+}
+
+func TestParseEnumEncoding(t *testing.T) {
+	input := `
+package test;
+
+enum Code {
+  option enum_encoding = "fixed32";
+  NONE = 0;
+  LARGE = 100000000;
+}
+
+enum Plain {
+  NONE = 0;
+}
+`
+
+	schema, errors := ParseFile("test.cram", input)
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+
+	code := schema.Enums[0]
+	if len(code.Options) != 1 || code.Options[0].Name != "enum_encoding" {
+		t.Fatalf("expected enum_encoding option, got %v", code.Options)
+	}
+	if got := code.Encoding(); got != EnumEncodingFixed32 {
+		t.Errorf("Code.Encoding() = %q, want %q", got, EnumEncodingFixed32)
+	}
+	if got := schema.Enums[1].Encoding(); got != EnumEncodingVarint {
+		t.Errorf("Plain.Encoding() = %q, want %q", got, EnumEncodingVarint)
+	}
+}
+
 func TestParseInterface(t *testing.T) {
 	input := `
 package test;
@@ -560,6 +767,85 @@ option string_list = ["a", "b", "c"];
 	}
 }
 
+func TestParseBytesOption(t *testing.T) {
+	input := `
+package test;
+message Frame {
+  bytes magic = 1 [default = 0x0A1B2C];
+  bytes prefix = 2 [default = b64"ChsC"];
+}
+`
+
+	schema, errors := ParseFile("test.cram", input)
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+	fields := schema.Messages[0].Fields
+
+	bv, ok := fields[0].Options[0].Value.(*BytesValue)
+	if !ok {
+		t.Fatalf("expected BytesValue, got %T", fields[0].Options[0].Value)
+	}
+	if !bytes.Equal(bv.Value, []byte{0x0a, 0x1b, 0x2c}) || bv.Base64 {
+		t.Errorf("unexpected hex value %#v", bv)
+	}
+	if bv.End().Column-bv.Pos().Column != len("0x0A1B2C") {
+		t.Errorf("expected the value to span %d columns, got %v to %v", len("0x0A1B2C"), bv.Pos(), bv.End())
+	}
+	if !bytes.Equal(fields[0].Default(), []byte{0x0a, 0x1b, 0x2c}) {
+		t.Errorf("Default() = %x", fields[0].Default())
+	}
+
+	bv, ok = fields[1].Options[0].Value.(*BytesValue)
+	if !ok || !bytes.Equal(bv.Value, []byte{0x0a, 0x1b, 0x02}) || !bv.Base64 {
+		t.Errorf("unexpected base64 value %#v", fields[1].Options[0].Value)
+	}
+}
+
+func TestParseIdentOption(t *testing.T) {
+	input := `
+package test;
+option default_status = ACTIVE;
+option fallback = Status.UNKNOWN;
+option choices = [common.Status.ACTIVE, BANNED];
+`
+
+	schema, errors := ParseFile("test.cram", input)
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+	if len(schema.Options) != 3 {
+		t.Fatalf("expected 3 options, got %d", len(schema.Options))
+	}
+
+	iv, ok := schema.Options[0].Value.(*IdentValue)
+	if !ok {
+		t.Fatalf("expected IdentValue, got %T", schema.Options[0].Value)
+	}
+	if iv.Name != "ACTIVE" {
+		t.Errorf("expected ACTIVE, got %q", iv.Name)
+	}
+	if iv.End().Column-iv.Pos().Column != len("ACTIVE") {
+		t.Errorf("expected the value to span %d columns, got %v to %v", len("ACTIVE"), iv.Pos(), iv.End())
+	}
+
+	if iv, ok := schema.Options[1].Value.(*IdentValue); !ok || iv.Name != "Status.UNKNOWN" {
+		t.Errorf("expected Status.UNKNOWN, got %#v", schema.Options[1].Value)
+	}
+
+	lv, ok := schema.Options[2].Value.(*ListValue)
+	if !ok || len(lv.Values) != 2 {
+		t.Fatalf("expected a list of 2 values, got %#v", schema.Options[2].Value)
+	}
+	if iv, ok := lv.Values[0].(*IdentValue); !ok || iv.Name != "common.Status.ACTIVE" {
+		t.Errorf("expected common.Status.ACTIVE, got %#v", lv.Values[0])
+	}
+
+	if _, errors := ParseFile("test.cram", "option x = Status.;"); len(errors) == 0 {
+		t.Error("expected an error for a trailing '.'")
+	}
+}
+
 func TestParseErrors(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -593,6 +879,43 @@ func TestParseErrors(t *testing.T) {
 	}
 }
 
+func TestParseFieldNumber(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantError string
+		column    int
+	}{
+		{name: "positive", input: "message Foo { int32 x = 7; }"},
+		{name: "zero", input: "message Foo { int32 x = 0; }", wantError: "field number must be positive, got 0", column: 25},
+		{name: "negative", input: "message Foo { int32 x = -1; }", wantError: "field number must be positive, got -1", column: 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, errors := ParseFile("test.cram", tt.input)
+			if tt.wantError == "" {
+				if len(errors) > 0 {
+					t.Fatalf("unexpected errors: %v", errors)
+				}
+				if got := schema.Messages[0].Fields[0].Number; got != 7 {
+					t.Errorf("field number = %d, want 7", got)
+				}
+				return
+			}
+			if len(errors) != 1 {
+				t.Fatalf("expected one error, got %v", errors)
+			}
+			if errors[0].Message != tt.wantError {
+				t.Errorf("error = %q, want %q", errors[0].Message, tt.wantError)
+			}
+			if pos := errors[0].Position; pos.Line != 1 || pos.Column != tt.column {
+				t.Errorf("error at %d:%d, want 1:%d", pos.Line, pos.Column, tt.column)
+			}
+		})
+	}
+}
+
 func TestTypeRefString(t *testing.T) {
 	tests := []struct {
 		typeRef TypeRef
@@ -722,6 +1045,7 @@ message AllScalars {
   complex128 c128 = 15;
   string s = 16;
   bytes bs = 17;
+  any a = 18;
 }
 `
 
@@ -731,15 +1055,15 @@ message AllScalars {
 	}
 
 	msg := schema.Messages[0]
-	if len(msg.Fields) != 17 {
-		t.Fatalf("expected 17 fields, got %d", len(msg.Fields))
+	if len(msg.Fields) != 18 {
+		t.Fatalf("expected 18 fields, got %d", len(msg.Fields))
 	}
 
 	expectedTypes := []string{
 		"bool", "int8", "int16", "int32", "int64", "int",
 		"uint8", "uint16", "uint32", "uint64", "uint",
 		"float32", "float64", "complex64", "complex128",
-		"string", "bytes",
+		"string", "bytes", "any",
 	}
 
 	for i, exp := range expectedTypes {
@@ -1107,6 +1431,215 @@ message User {
 	}
 }
 
+func TestParseDirectives(t *testing.T) {
+	input := `
+package test;
+
+/// A user.
+///@table:users
+message User {
+  ///@json:"userId"
+  /// The user's unique identifier.
+  ///@inline
+  int32 id = 1;
+  string name = 2;
+}
+
+///@json:"lower"
+enum Kind {
+  ///@alias:"none"
+  KIND_UNKNOWN = 0;
+}
+`
+
+	schema, errors := ParseFile("test.cram", input)
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+
+	msg := schema.Messages[0]
+	if len(msg.Directives) != 1 || msg.Directives[0].Key != "table" || msg.Directives[0].Value != "users" {
+		t.Errorf("message directives = %v", msg.Directives)
+	}
+	if len(msg.Comments) != 1 {
+		t.Errorf("expected the doc comment alongside the directive, got %d comments", len(msg.Comments))
+	}
+
+	id := msg.Fields[0]
+	if v, ok := LookupDirective(id.Directives, "json"); !ok || v != "userId" {
+		t.Errorf("json directive = %q, %v", v, ok)
+	}
+	if v, ok := LookupDirective(id.Directives, "inline"); !ok || v != "" {
+		t.Errorf("inline directive = %q, %v", v, ok)
+	}
+	if id.Directives[0].Position.Line != 7 {
+		t.Errorf("directive at line %d, want 7", id.Directives[0].Position.Line)
+	}
+	if len(msg.Fields[1].Directives) != 0 {
+		t.Errorf("directives leaked to the next field: %v", msg.Fields[1].Directives)
+	}
+
+	if v, _ := LookupDirective(schema.Enums[0].Directives, "json"); v != "lower" {
+		t.Errorf("enum json directive = %q", v)
+	}
+	if v, _ := LookupDirective(schema.Enums[0].Values[0].Directives, "alias"); v != "none" {
+		t.Errorf("enum value alias directive = %q", v)
+	}
+}
+
+func TestParseOrphanDirectives(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{"before closing brace", `
+package test;
+
+message User {
+  int32 id = 1;
+  ///@json:"x"
+}
+
+message Next {
+  int32 id = 1;
+}
+`, 6},
+		{"before package", `
+///@json:"x"
+package test;
+
+message Next {
+  int32 id = 1;
+}
+`, 2},
+		{"before option", `
+package test;
+
+///@json:"x"
+option go_package = "example.com/test";
+
+message Next {
+  int32 id = 1;
+}
+`, 4},
+		{"end of file", `
+package test;
+
+message Next {
+  int32 id = 1;
+}
+
+///@json:"x"
+`, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, errors := ParseFile("test.cram", tt.input)
+			if len(errors) != 1 {
+				t.Fatalf("expected 1 error, got %v", errors)
+			}
+			if errors[0].Position.Line != tt.line || !strings.Contains(errors[0].Message, "@json") {
+				t.Errorf("error = %v, want one for @json at line %d", errors[0], tt.line)
+			}
+			// The directive must not apply to the next declaration
+			next := schema.Messages[len(schema.Messages)-1]
+			if len(next.Directives) != 0 || len(next.Fields[0].Directives) != 0 {
+				t.Errorf("directive leaked to %s: %v %v", next.Name, next.Directives, next.Fields[0].Directives)
+			}
+		})
+	}
+}
+
+func TestParseGroups(t *testing.T) {
+	input := `
+package test;
+
+message Order {
+  string id = 1;
+  /// Where to send the bill.
+  optional group billing_address = 2 {
+    string street = 1;
+    group geo = 2 {
+      float64 lat = 1;
+    }
+  }
+  repeated group lines = 3 [since = "v2"] {
+    string sku = 1;
+  }
+}
+
+message Empty {}
+`
+
+	schema, errors := ParseFile("test.cram", input)
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+
+	var names []string
+	for _, msg := range schema.Messages {
+		names = append(names, msg.Name)
+	}
+	want := []string{"Order", "OrderBillingAddressGeo", "OrderBillingAddress", "OrderLines", "Empty"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("messages = %v, want %v", names, want)
+	}
+
+	order := schema.Messages[0]
+	billing := order.Fields[1]
+	if nt, ok := billing.Type.(*NamedType); !ok || nt.Name != "OrderBillingAddress" {
+		t.Errorf("billing_address type = %v", billing.Type)
+	}
+	if billing.Group != schema.Messages[2] || !billing.Optional || len(billing.Comments) != 1 {
+		t.Errorf("billing_address = %+v", billing)
+	}
+	if !billing.Group.Group || len(billing.Group.Fields) != 2 || billing.Group.Fields[1].Group != schema.Messages[1] {
+		t.Errorf("billing_address group = %+v", billing.Group)
+	}
+	lines := order.Fields[2]
+	if !lines.Repeated || len(lines.Options) != 1 || lines.Group.Name != "OrderLines" {
+		t.Errorf("lines = %+v", lines)
+	}
+	if schema.Messages[0].Group || schema.Messages[4].Group {
+		t.Error("top-level messages marked as groups")
+	}
+
+	// Without a body, group names a type, and it remains usable as a name
+	schema, errors = ParseFile("test.cram", `
+message group {
+  string group = 1;
+}
+
+message M {
+  group g = 1;
+  group group = 2 {
+    int32 x = 1;
+  }
+}
+`)
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+	names = names[:0]
+	for _, msg := range schema.Messages {
+		names = append(names, msg.Name)
+	}
+	if want := []string{"group", "M", "MGroup"}; !slices.Equal(names, want) {
+		t.Fatalf("messages = %v, want %v", names, want)
+	}
+	if f := schema.Messages[0].Fields[0]; f.Name != "group" {
+		t.Errorf("field named group = %+v", f)
+	}
+	m := schema.Messages[1]
+	if nt, ok := m.Fields[0].Type.(*NamedType); !ok || nt.Name != "group" || m.Fields[0].Group != nil {
+		t.Errorf("field of type group = %+v", m.Fields[0])
+	}
+	if f := m.Fields[1]; f.Name != "group" || f.Group != schema.Messages[2] {
+		t.Errorf("group named group = %+v", f)
+	}
+}
+
 func TestParseMultipleCombinedModifiers(t *testing.T) {
 	input := `
 package test;
@@ -1132,3 +1665,90 @@ message Request {
 		t.Error("expected deprecated modifier")
 	}
 }
+
+func TestParseFieldAnnotations(t *testing.T) {
+	input := `
+package test;
+message Metrics {
+  int64 total_bytes = 1 [unit = "bytes", min = 0, max = 100];
+  float64 ratio = 2 [min = -0.5];
+  string name = 3;
+}
+`
+	schema, errors := ParseFile("test.cram", input)
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+	fields := schema.Messages[0].Fields
+
+	a := fields[0].Annotations()
+	if a.Unit != "bytes" {
+		t.Errorf("expected unit bytes, got %q", a.Unit)
+	}
+	if a.Min == nil || a.Min.Value != "0" || a.Max == nil || a.Max.Value != "100" {
+		t.Errorf("expected range 0 to 100, got %+v to %+v", a.Min, a.Max)
+	}
+
+	a = fields[1].Annotations()
+	if a.Min == nil || a.Min.Value != "-0.5" || !a.Min.IsFloat || a.Max != nil {
+		t.Errorf("expected min -0.5 and no max, got %+v to %+v", a.Min, a.Max)
+	}
+
+	if a := fields[2].Annotations(); a.Unit != "" || a.HasRange() || a.HasItemRange() {
+		t.Errorf("expected no annotations, got %+v", a)
+	}
+}
+
+func TestParseItemRangeOptions(t *testing.T) {
+	input := `
+package test;
+message Batch {
+  []string items = 1 [min_items = 1, max_items = 100];
+  map[string]int32 counts = 2 [max_items = 8];
+  repeated int64 ids = 3;
+}
+`
+	schema, errors := ParseFile("test.cram", input)
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+	fields := schema.Messages[0].Fields
+
+	a := fields[0].Annotations()
+	if a.MinItems == nil || a.MinItems.Value != "1" || a.MaxItems == nil || a.MaxItems.Value != "100" {
+		t.Errorf("expected 1 to 100 items, got %+v to %+v", a.MinItems, a.MaxItems)
+	}
+	if a.HasRange() {
+		t.Errorf("expected no value range, got %+v", a)
+	}
+
+	a = fields[1].Annotations()
+	if a.MinItems != nil || a.MaxItems == nil || a.MaxItems.Value != "8" {
+		t.Errorf("expected at most 8 items, got %+v to %+v", a.MinItems, a.MaxItems)
+	}
+
+	if fields[2].Annotations().HasItemRange() {
+		t.Errorf("expected no item range on %s", fields[2].Name)
+	}
+}
+
+func TestParseOrderedMapField(t *testing.T) {
+	input := `
+package test;
+message Config {
+  map[string]string settings = 1 [ordered = true];
+  map[string]int32 counts = 2;
+}
+`
+	schema, errors := ParseFile("test.cram", input)
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+	fields := schema.Messages[0].Fields
+	if !fields[0].Ordered() {
+		t.Error("expected settings to be ordered")
+	}
+	if fields[1].Ordered() {
+		t.Error("expected counts not to be ordered")
+	}
+}
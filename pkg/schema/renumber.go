@@ -0,0 +1,92 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+)
+
+// FieldNumberGaps reports the field numbers each message in s skips, as
+// warnings positioned at the message. Numbering is expected to start at 1
+// and run without gaps, so a run of unused numbers below a message's
+// highest field number is reported once.
+//
+// Gaps are often deliberate: the number of a removed field must not be
+// given to a new field while old data may still be read. The warnings are
+// meant for review and are not part of Validate.
+func FieldNumberGaps(s *Schema) []ValidationError {
+	var gaps []ValidationError
+	for _, msg := range s.Messages {
+		next := 1
+		for _, n := range sortedFieldNumbers(msg) {
+			if n > next {
+				gaps = append(gaps, ValidationError{
+					Position: msg.Position,
+					Message:  fmt.Sprintf("message %s skips %s", msg.Name, numberRange(next, n-1)),
+					Severity: SeverityWarning,
+				})
+			}
+			next = max(next, n+1)
+		}
+	}
+	return gaps
+}
+
+// numberRange describes the field numbers from first to last.
+func numberRange(first, last int) string {
+	if first == last {
+		return fmt.Sprintf("field number %d", first)
+	}
+	return fmt.Sprintf("field numbers %d-%d", first, last)
+}
+
+// sortedFieldNumbers returns the field numbers of msg in increasing order.
+func sortedFieldNumbers(msg *Message) []int {
+	numbers := make([]int, len(msg.Fields))
+	for i, f := range msg.Fields {
+		numbers[i] = f.Number
+	}
+	slices.Sort(numbers)
+	return numbers
+}
+
+// FieldRenumbering records a field number changed by RenumberFields.
+type FieldRenumbering struct {
+	Message string
+	Field   string
+	From    int
+	To      int
+}
+
+func (r FieldRenumbering) String() string {
+	return fmt.Sprintf("%s.%s: %d -> %d", r.Message, r.Field, r.From, r.To)
+}
+
+// RenumberFields closes the gaps reported by FieldNumberGaps by rewriting
+// the field numbers of every message in s to run from 1, keeping their
+// relative order. The fields themselves stay where they are in the
+// message, so FormatSchema prints the renumbered schema in its original
+// layout. It returns the changes it made, in message order.
+//
+// Renumbering changes the wire format: data encoded with the old numbers
+// decodes into the wrong fields, or fails to decode, with the new ones. It
+// is only safe for schemas whose encoded data is never stored or exchanged
+// with code generated from an earlier version. s should be valid; fields
+// sharing a number are given consecutive numbers in declaration order.
+func RenumberFields(s *Schema) []FieldRenumbering {
+	var changes []FieldRenumbering
+	for _, msg := range s.Messages {
+		fields := slices.Clone(msg.Fields)
+		slices.SortStableFunc(fields, func(a, b *Field) int { return a.Number - b.Number })
+		renumbered := make(map[*Field]int, len(fields))
+		for i, f := range fields {
+			renumbered[f] = i + 1
+		}
+		for _, f := range msg.Fields {
+			if to := renumbered[f]; to != f.Number {
+				changes = append(changes, FieldRenumbering{Message: msg.Name, Field: f.Name, From: f.Number, To: to})
+				f.Number = to
+			}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,97 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+const gappedSchema = `package test;
+
+message Contiguous {
+  int32 a = 1;
+  int32 b = 2;
+}
+
+message Gapped {
+  string name = 4;
+  int64 id = 1;
+  []string tags = 9;
+  string email = 2;
+}
+`
+
+func TestFieldNumberGaps(t *testing.T) {
+	s, errs := ParseFile("test.cram", gappedSchema)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	gaps := FieldNumberGaps(s)
+	want := []string{
+		"message Gapped skips field number 3",
+		"message Gapped skips field numbers 5-8",
+	}
+	if len(gaps) != len(want) {
+		t.Fatalf("got %d gaps, want %d: %v", len(gaps), len(want), gaps)
+	}
+	for i, gap := range gaps {
+		if gap.Message != want[i] {
+			t.Errorf("gap %d = %q, want %q", i, gap.Message, want[i])
+		}
+		if gap.Severity != SeverityWarning || gap.Position.Line != 8 {
+			t.Errorf("gap %d: severity %s at line %d, want a warning at line 8", i, gap.Severity, gap.Position.Line)
+		}
+	}
+
+	// A message starting above 1 has a gap too
+	s, errs = ParseFile("test.cram", "package test;\nmessage M {\n  int32 a = 3;\n}\n")
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if gaps := FieldNumberGaps(s); len(gaps) != 1 || !strings.HasSuffix(gaps[0].Message, "field numbers 1-2") {
+		t.Errorf("unexpected gaps: %v", gaps)
+	}
+}
+
+func TestRenumberFields(t *testing.T) {
+	s, errs := ParseFile("test.cram", gappedSchema)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	changes := RenumberFields(s)
+	want := []string{
+		"Gapped.name: 4 -> 3",
+		"Gapped.tags: 9 -> 4",
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %v", len(changes), len(want), changes)
+	}
+	for i, c := range changes {
+		if c.String() != want[i] {
+			t.Errorf("change %d = %q, want %q", i, c, want[i])
+		}
+	}
+	if gaps := FieldNumberGaps(s); len(gaps) != 0 {
+		t.Errorf("gaps left after renumbering: %v", gaps)
+	}
+	if errs := Validate(s); len(errs) != 0 {
+		t.Errorf("renumbered schema is invalid: %v", errs)
+	}
+
+	// Fields keep their declaration order
+	out := FormatSchema(s)
+	order := []string{"string name = 3;", "int64 id = 1;", "[]string tags = 4;", "string email = 2;"}
+	last := -1
+	for _, line := range order {
+		i := strings.Index(out, line)
+		if i < 0 || i < last {
+			t.Fatalf("formatted output missing or misordered %q:\n%s", line, out)
+		}
+		last = i
+	}
+
+	if changes := RenumberFields(s); len(changes) != 0 {
+		t.Errorf("renumbering a contiguous schema changed %v", changes)
+	}
+}
@@ -0,0 +1,237 @@
+package schema
+
+import "fmt"
+
+// DefaultLargeMessageFields is the field count above which the stats
+// command reports a message as large.
+const DefaultLargeMessageFields = 50
+
+// SchemaStats summarizes the size and shape of a schema, as computed by
+// Stats.
+type SchemaStats struct {
+	// Messages holds the statistics of each message, in declaration order.
+	Messages []MessageStats
+
+	// Enums and Interfaces count the enums and interfaces defined.
+	Enums      int
+	Interfaces int
+
+	// Fields is the total number of fields across all messages.
+	Fields int
+
+	// MaxFieldNumber is the highest field number used by any message.
+	MaxFieldNumber int
+
+	// MaxDepth is the largest Depth of any message.
+	MaxDepth int
+
+	// UnusedEnums lists the enums that no field of the schema refers to,
+	// in declaration order. Other schemas importing this one may still
+	// use them.
+	UnusedEnums []string
+}
+
+// MessageStats summarizes a single message.
+type MessageStats struct {
+	Name           string
+	Fields         int
+	MaxFieldNumber int
+
+	// Depth is the number of message levels in the deepest value of the
+	// message: 1 for a message whose fields hold no other messages, 2 for
+	// one holding such a message, and so on. Messages reached through
+	// collections, maps, pointers and interfaces count as nested, and
+	// types imported from other schemas are not counted.
+	//
+	// Messages that refer to each other recursively share one Depth: the
+	// number of messages in the cycle, each counted once, plus the depth of
+	// the deepest message they hold outside it.
+	Depth int
+}
+
+// LargeMessages returns the messages that have more than limit fields.
+func (st SchemaStats) LargeMessages(limit int) []MessageStats {
+	var large []MessageStats
+	for _, m := range st.Messages {
+		if m.Fields > limit {
+			large = append(large, m)
+		}
+	}
+	return large
+}
+
+// Concerns describes the parts of the schema that may need attention:
+// messages with more than limit fields and unused enums.
+func (st SchemaStats) Concerns(limit int) []string {
+	var concerns []string
+	for _, m := range st.LargeMessages(limit) {
+		concerns = append(concerns, fmt.Sprintf("message %s has %d fields (more than %d)", m.Name, m.Fields, limit))
+	}
+	for _, name := range st.UnusedEnums {
+		concerns = append(concerns, fmt.Sprintf("enum %s is not used by any field", name))
+	}
+	return concerns
+}
+
+// Stats computes statistics for the messages, enums and interfaces defined
+// in s.
+func Stats(s *Schema) SchemaStats {
+	sc := &statsCounter{
+		messages: make(map[string]*Message),
+		ifaces:   make(map[string]*Interface),
+		depths:   make(map[string]int),
+		index:    make(map[string]int),
+		low:      make(map[string]int),
+		onStack:  make(map[string]bool),
+		used:     make(map[string]bool),
+	}
+	for _, msg := range s.Messages {
+		sc.messages[msg.Name] = msg
+	}
+	for _, iface := range s.Interfaces {
+		sc.ifaces[iface.Name] = iface
+	}
+
+	st := SchemaStats{Enums: len(s.Enums), Interfaces: len(s.Interfaces)}
+	for _, msg := range s.Messages {
+		ms := MessageStats{Name: msg.Name, Fields: len(msg.Fields), Depth: sc.messageDepth(msg)}
+		for _, f := range msg.Fields {
+			ms.MaxFieldNumber = max(ms.MaxFieldNumber, f.Number)
+			sc.markUsed(f.Type)
+		}
+		st.Messages = append(st.Messages, ms)
+		st.Fields += ms.Fields
+		st.MaxFieldNumber = max(st.MaxFieldNumber, ms.MaxFieldNumber)
+		st.MaxDepth = max(st.MaxDepth, ms.Depth)
+	}
+	for _, enum := range s.Enums {
+		if !sc.used[enum.Name] {
+			st.UnusedEnums = append(st.UnusedEnums, enum.Name)
+		}
+	}
+	return st
+}
+
+// statsCounter holds the state of a single Stats call.
+type statsCounter struct {
+	messages map[string]*Message
+	ifaces   map[string]*Interface
+	depths   map[string]int  // computed message depths
+	used     map[string]bool // local type names referenced by fields
+
+	// Depths are computed a strongly connected component at a time, with
+	// Tarjan's algorithm, so that each message is visited once even when
+	// many messages share nested types or refer to each other.
+	index   map[string]int // visit order of each message
+	low     map[string]int // lowest index reachable from each message
+	onStack map[string]bool
+	stack   []*Message
+}
+
+// messageDepth returns the Depth of msg.
+func (sc *statsCounter) messageDepth(msg *Message) int {
+	if _, ok := sc.depths[msg.Name]; !ok {
+		sc.visit(msg)
+	}
+	return sc.depths[msg.Name]
+}
+
+// visit computes the depth of msg and of every message it reaches that
+// has no depth yet.
+func (sc *statsCounter) visit(msg *Message) {
+	sc.index[msg.Name] = len(sc.index)
+	sc.low[msg.Name] = sc.index[msg.Name]
+	sc.stack = append(sc.stack, msg)
+	sc.onStack[msg.Name] = true
+
+	for _, ref := range sc.references(msg) {
+		if _, seen := sc.index[ref.Name]; !seen {
+			sc.visit(ref)
+			sc.low[msg.Name] = min(sc.low[msg.Name], sc.low[ref.Name])
+		} else if sc.onStack[ref.Name] {
+			sc.low[msg.Name] = min(sc.low[msg.Name], sc.index[ref.Name])
+		}
+	}
+	if sc.low[msg.Name] != sc.index[msg.Name] {
+		return
+	}
+
+	// msg is the first visited message of a cycle, or a message in no
+	// cycle; the messages above it on the stack make up the cycle. Every
+	// message they hold outside it already has its depth.
+	var cycle []*Message
+	for {
+		m := sc.stack[len(sc.stack)-1]
+		sc.stack = sc.stack[:len(sc.stack)-1]
+		sc.onStack[m.Name] = false
+		cycle = append(cycle, m)
+		if m == msg {
+			break
+		}
+	}
+	nested := 0
+	for _, m := range cycle {
+		for _, ref := range sc.references(m) {
+			if d, ok := sc.depths[ref.Name]; ok {
+				nested = max(nested, d)
+			}
+		}
+	}
+	for _, m := range cycle {
+		sc.depths[m.Name] = len(cycle) + nested
+	}
+}
+
+// references returns the local messages that the fields of msg hold.
+func (sc *statsCounter) references(msg *Message) []*Message {
+	var refs []*Message
+	for _, f := range msg.Fields {
+		refs = sc.typeReferences(f.Type, refs)
+	}
+	return refs
+}
+
+// typeReferences appends the local messages that a value of type t holds
+// to refs.
+func (sc *statsCounter) typeReferences(t TypeRef, refs []*Message) []*Message {
+	switch t := t.(type) {
+	case *NamedType:
+		if t.Package != "" {
+			return refs
+		}
+		if msg, ok := sc.messages[t.Name]; ok {
+			return append(refs, msg)
+		}
+		if iface, ok := sc.ifaces[t.Name]; ok {
+			for _, impl := range iface.Implementations {
+				if msg, ok := sc.messages[impl.Type.Name]; ok && impl.Type.Package == "" {
+					refs = append(refs, msg)
+				}
+			}
+		}
+	case *PointerType:
+		return sc.typeReferences(t.Element, refs)
+	case *ArrayType:
+		return sc.typeReferences(t.Element, refs)
+	case *MapType:
+		return sc.typeReferences(t.Value, sc.typeReferences(t.Key, refs))
+	}
+	return refs
+}
+
+// markUsed records the local named types that t refers to.
+func (sc *statsCounter) markUsed(t TypeRef) {
+	switch t := t.(type) {
+	case *NamedType:
+		if t.Package == "" {
+			sc.used[t.Name] = true
+		}
+	case *PointerType:
+		sc.markUsed(t.Element)
+	case *ArrayType:
+		sc.markUsed(t.Element)
+	case *MapType:
+		sc.markUsed(t.Key)
+		sc.markUsed(t.Value)
+	}
+}
@@ -0,0 +1,134 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const statsSchema = `package test;
+
+enum Status {
+  ACTIVE = 0;
+  CLOSED = 1;
+}
+
+enum Unused {
+  NONE = 0;
+}
+
+message Address {
+  string street = 1;
+  string city = 2;
+}
+
+message Person {
+  string name = 1;
+  map[string]Status statuses = 2;
+  []Address addresses = 17;
+  *Person manager = 3;
+}
+
+message Team {
+  []Person members = 1;
+  Shape logo = 2;
+}
+
+message Circle {
+  *Address center = 1;
+}
+
+interface Shape {
+  1 = Circle;
+}
+`
+
+func TestStats(t *testing.T) {
+	s, errs := ParseFile("test.cram", statsSchema)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	st := Stats(s)
+	want := SchemaStats{
+		Messages: []MessageStats{
+			{Name: "Address", Fields: 2, MaxFieldNumber: 2, Depth: 1},
+			{Name: "Person", Fields: 4, MaxFieldNumber: 17, Depth: 2},
+			{Name: "Team", Fields: 2, MaxFieldNumber: 2, Depth: 3},
+			{Name: "Circle", Fields: 1, MaxFieldNumber: 1, Depth: 2},
+		},
+		Enums:          2,
+		Interfaces:     1,
+		Fields:         9,
+		MaxFieldNumber: 17,
+		MaxDepth:       3,
+		UnusedEnums:    []string{"Unused"},
+	}
+	if !reflect.DeepEqual(st, want) {
+		t.Errorf("Stats =\n%+v\nwant\n%+v", st, want)
+	}
+
+	if large := st.LargeMessages(3); len(large) != 1 || large[0].Name != "Person" {
+		t.Errorf("LargeMessages(3) = %v, want Person", large)
+	}
+	wantConcerns := []string{
+		"message Person has 4 fields (more than 3)",
+		"enum Unused is not used by any field",
+	}
+	if got := st.Concerns(3); !reflect.DeepEqual(got, wantConcerns) {
+		t.Errorf("Concerns(3) = %q, want %q", got, wantConcerns)
+	}
+	if got := st.Concerns(DefaultLargeMessageFields); len(got) != 1 {
+		t.Errorf("Concerns(%d) = %q, want only the unused enum", DefaultLargeMessageFields, got)
+	}
+}
+
+func TestStatsDepthWideSchema(t *testing.T) {
+	// Every message of a level holds every message of the next one, so a
+	// walk that doesn't reuse depths visits 3^levels paths
+	const levels, width = 60, 3
+	var b strings.Builder
+	b.WriteString("package test;\n")
+	for l := 0; l < levels; l++ {
+		for i := 0; i < width; i++ {
+			fmt.Fprintf(&b, "message M%d_%d {\n", l, i)
+			if l+1 < levels {
+				for j := 0; j < width; j++ {
+					fmt.Fprintf(&b, "  []M%d_%d f%d = %d;\n", l+1, j, j, j+1)
+				}
+			}
+			b.WriteString("}\n")
+		}
+	}
+	// A cycle of three messages, one of which holds the first level
+	b.WriteString(`
+message Ring1 { *Ring2 next = 1; }
+message Ring2 { *Ring3 next = 1; }
+message Ring3 { *Ring1 next = 1; M0_0 payload = 2; }
+`)
+	s, errs := ParseFile("wide.cram", b.String())
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	st := Stats(s)
+	if st.MaxDepth != levels+3 {
+		t.Errorf("MaxDepth = %d, want %d", st.MaxDepth, levels+3)
+	}
+	depths := make(map[string]int)
+	for _, m := range st.Messages {
+		depths[m.Name] = m.Depth
+	}
+	for name, want := range map[string]int{
+		"M0_0":                         levels,
+		"M0_2":                         levels,
+		fmt.Sprintf("M%d_1", levels-1): 1,
+		"Ring1":                        levels + 3,
+		"Ring3":                        levels + 3,
+	} {
+		if depths[name] != want {
+			t.Errorf("%s depth = %d, want %d", name, depths[name], want)
+		}
+	}
+}
@@ -2,7 +2,13 @@ package schema
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blockberries/cramberry/internal/naming"
 )
 
 // ValidationError represents a schema validation error.
@@ -102,6 +108,13 @@ func (v *Validator) Validate() []ValidationError {
 	// First pass: collect all type definitions
 	v.collectTypes()
 
+	v.checkEnumOnlyOptions(v.schema.Options, "a file")
+	for _, opt := range v.schema.Options {
+		if _, ok := opt.Value.(*BoolValue); opt.Name == SchemaHashOption && !ok {
+			v.addError(opt.Position, "schema_hash option must be true or false")
+		}
+	}
+
 	// Validate messages
 	for _, msg := range v.schema.Messages {
 		v.validateMessage(msg)
@@ -117,6 +130,8 @@ func (v *Validator) Validate() []ValidationError {
 		v.validateInterface(iface)
 	}
 
+	v.validateIdentValues()
+
 	// Sort errors by position
 	sort.Slice(v.errors, func(i, j int) bool {
 		if v.errors[i].Position.Line != v.errors[j].Position.Line {
@@ -132,6 +147,7 @@ func (v *Validator) Validate() []ValidationError {
 func (v *Validator) collectTypes() {
 	// Collect messages
 	for _, msg := range v.schema.Messages {
+		v.checkTypeName("message", msg.Name, msg.Position)
 		if existing, ok := v.types[msg.Name]; ok {
 			v.addError(msg.Position, "duplicate type name %q (previously defined at %d:%d)",
 				msg.Name, existing.Position.Line, existing.Position.Column)
@@ -147,6 +163,7 @@ func (v *Validator) collectTypes() {
 
 	// Collect enums
 	for _, enum := range v.schema.Enums {
+		v.checkTypeName("enum", enum.Name, enum.Position)
 		if existing, ok := v.types[enum.Name]; ok {
 			v.addError(enum.Position, "duplicate type name %q (previously defined at %d:%d)",
 				enum.Name, existing.Position.Line, existing.Position.Column)
@@ -161,6 +178,7 @@ func (v *Validator) collectTypes() {
 
 	// Collect interfaces
 	for _, iface := range v.schema.Interfaces {
+		v.checkTypeName("interface", iface.Name, iface.Position)
 		if existing, ok := v.types[iface.Name]; ok {
 			v.addError(iface.Position, "duplicate type name %q (previously defined at %d:%d)",
 				iface.Name, existing.Position.Line, existing.Position.Column)
@@ -174,13 +192,26 @@ func (v *Validator) collectTypes() {
 	}
 }
 
+// checkTypeName reports a type named after a built-in scalar, which
+// references to the scalar would shadow.
+func (v *Validator) checkTypeName(kind, name string, pos Position) {
+	if IsScalar(name) {
+		v.addError(pos, "%s name %q is reserved for the built-in scalar type", kind, name)
+	}
+}
+
 // validateMessage validates a message definition.
 func (v *Validator) validateMessage(msg *Message) {
 	// Check for duplicate field numbers
 	fieldNumbers := make(map[int]string) // number -> field name
 	fieldNames := make(map[string]bool)
+	fieldIdents := make(map[string]string) // generated identifier -> field name
 
 	for _, field := range msg.Fields {
+		if isKeyword(field.Name) {
+			v.addError(field.Position, "field name %q in message %s is a reserved keyword", field.Name, msg.Name)
+		}
+
 		// Check field number is valid
 		if field.Number <= 0 {
 			v.addError(field.Position, "field number must be positive, got %d", field.Number)
@@ -209,6 +240,15 @@ func (v *Validator) validateMessage(msg *Message) {
 			v.addError(field.Position, "duplicate field name %q", field.Name)
 		} else {
 			fieldNames[field.Name] = true
+
+			// Distinct names can still map to the same generated identifier
+			ident := naming.Pascal(field.Name)
+			if existing, ok := fieldIdents[ident]; ok {
+				v.addError(field.Position, "field name %q collides with %q after case conversion (both become %q)",
+					field.Name, existing, ident)
+			} else {
+				fieldIdents[ident] = field.Name
+			}
 		}
 
 		// Validate field type
@@ -225,10 +265,33 @@ func (v *Validator) validateMessage(msg *Message) {
 		if modifierCount > 1 {
 			v.addError(field.Position, "field cannot be both required and optional")
 		}
+		if arr, ok := field.Type.(*ArrayType); ok && field.Repeated && arr.Size == 0 {
+			v.addError(field.Position, "repeated field %s.%s cannot have slice type %s; use []%s for a list of lists",
+				msg.Name, field.Name, arr, arr)
+		}
+		if field.Optional && isAnyType(field.Type) {
+			v.addError(field.Position, "field %s.%s of type any cannot be optional; a nil value is already absent",
+				msg.Name, field.Name)
+		}
 
-		// Validate map key type
-		if mt, ok := field.Type.(*MapType); ok {
-			v.validateMapKeyType(mt.Key, msg.Name, field.Name)
+		v.checkEnumOnlyOptions(field.Options, "field "+msg.Name+"."+field.Name)
+		v.validateFieldAnnotations(msg, field)
+		v.validateItemRange(msg, field)
+		v.validateOrdered(msg, field)
+		v.validateDelta(msg, field)
+		v.validateDefault(msg, field)
+	}
+
+	// Check message options
+	v.checkEnumOnlyOptions(msg.Options, "message "+msg.Name)
+	for _, opt := range msg.Options {
+		if opt.Name != "framing" {
+			continue
+		}
+		sv, ok := opt.Value.(*StringValue)
+		if !ok || (sv.Value != FramingMarker && sv.Value != FramingLength) {
+			v.addError(opt.Position, "framing option of message %s must be %q or %q",
+				msg.Name, FramingLength, FramingMarker)
 		}
 	}
 
@@ -242,6 +305,7 @@ func (v *Validator) validateMessage(msg *Message) {
 func (v *Validator) validateEnum(enum *Enum) {
 	valueNumbers := make(map[int]string) // number -> value name
 	valueNames := make(map[string]bool)
+	valueIdents := make(map[string]string) // generated identifier -> value name
 
 	// Check for zero value
 	hasZero := false
@@ -255,16 +319,37 @@ func (v *Validator) validateEnum(enum *Enum) {
 		v.addWarning(enum.Position, "enum %q should have a zero value (conventionally for unknown/default)", enum.Name)
 	}
 
+	for _, opt := range enum.Options {
+		switch opt.Name {
+		case "allow_alias":
+			if _, ok := opt.Value.(*BoolValue); !ok {
+				v.addError(opt.Position, "allow_alias option of enum %s must be true or false", enum.Name)
+			}
+		case "enum_encoding":
+			sv, ok := opt.Value.(*StringValue)
+			if !ok || (sv.Value != EnumEncodingVarint && sv.Value != EnumEncodingFixed32) {
+				v.addError(opt.Position, "enum_encoding option of enum %s must be %q or %q",
+					enum.Name, EnumEncodingVarint, EnumEncodingFixed32)
+			}
+		}
+	}
+	allowAlias := enum.AllowAlias()
+	hasAlias := false
+
 	for _, val := range enum.Values {
 		// Check for negative values
 		if val.Number < 0 {
 			v.addError(val.Position, "enum value number must be non-negative, got %d", val.Number)
 		}
 
-		// Check for duplicate numbers
+		// Check for duplicate numbers; aliases are allowed only when opted in
 		if existing, ok := valueNumbers[val.Number]; ok {
-			v.addError(val.Position, "duplicate enum value number %d (also used by %q)",
-				val.Number, existing)
+			if allowAlias {
+				hasAlias = true
+			} else {
+				v.addError(val.Position, "duplicate enum value number %d (also used by %q); set option allow_alias = true to allow aliases",
+					val.Number, existing)
+			}
 		} else {
 			valueNumbers[val.Number] = val.Name
 		}
@@ -274,8 +359,311 @@ func (v *Validator) validateEnum(enum *Enum) {
 			v.addError(val.Position, "duplicate enum value name %q", val.Name)
 		} else {
 			valueNames[val.Name] = true
+
+			ident := naming.Pascal(val.Name)
+			if existing, ok := valueIdents[ident]; ok {
+				v.addError(val.Position, "enum value name %q collides with %q after case conversion (both become %q)",
+					val.Name, existing, ident)
+			} else {
+				valueIdents[ident] = val.Name
+			}
 		}
 	}
+
+	if allowAlias && !hasAlias {
+		v.addWarning(enum.Position, "enum %q sets allow_alias but has no aliased values", enum.Name)
+	}
+}
+
+// integerRanges gives the bounds of the integer scalar types.
+var integerRanges = map[string][2]*big.Int{
+	"int8":   {big.NewInt(math.MinInt8), big.NewInt(math.MaxInt8)},
+	"int16":  {big.NewInt(math.MinInt16), big.NewInt(math.MaxInt16)},
+	"int32":  {big.NewInt(math.MinInt32), big.NewInt(math.MaxInt32)},
+	"int64":  {big.NewInt(math.MinInt64), big.NewInt(math.MaxInt64)},
+	"int":    {big.NewInt(math.MinInt64), big.NewInt(math.MaxInt64)},
+	"uint8":  {big.NewInt(0), big.NewInt(math.MaxUint8)},
+	"byte":   {big.NewInt(0), big.NewInt(math.MaxUint8)},
+	"uint16": {big.NewInt(0), big.NewInt(math.MaxUint16)},
+	"uint32": {big.NewInt(0), big.NewInt(math.MaxUint32)},
+	"uint64": {big.NewInt(0), new(big.Int).SetUint64(math.MaxUint64)},
+	"uint":   {big.NewInt(0), new(big.Int).SetUint64(math.MaxUint64)},
+}
+
+// validateFieldAnnotations checks the unit, min and max options of a field.
+// Bounds must be numbers that fit the field's type, and only numeric scalar
+// fields can have them.
+func (v *Validator) validateFieldAnnotations(msg *Message, field *Field) {
+	where := msg.Name + "." + field.Name
+	for _, opt := range field.Options {
+		switch opt.Name {
+		case "unit":
+			if _, ok := opt.Value.(*StringValue); !ok {
+				v.addError(opt.Position, "unit option of field %s must be a string", where)
+			}
+		case "min", "max":
+			if _, ok := opt.Value.(*NumberValue); !ok {
+				v.addError(opt.Position, "%s option of field %s must be a number", opt.Name, where)
+			}
+		}
+	}
+
+	a := field.Annotations()
+	if !a.HasRange() {
+		return
+	}
+	var typeName string
+	if st, ok := field.Type.(*ScalarType); ok {
+		typeName = st.Name
+	}
+	_, isInt := integerRanges[typeName]
+	if field.Repeated || (!isInt && typeName != "float32" && typeName != "float64") {
+		v.addError(field.Position, "min and max options of field %s require a numeric scalar type, got %s",
+			where, field.Type)
+		return
+	}
+
+	bound := func(nv *NumberValue, name string) *big.Rat {
+		if nv == nil {
+			return nil
+		}
+		if isInt {
+			n, ok := new(big.Int).SetString(nv.Value, 0)
+			if !ok || nv.IsFloat {
+				v.addError(nv.Position, "%s of integer field %s must be an integer, got %s", name, where, nv.Value)
+				return nil
+			}
+			r := integerRanges[typeName]
+			if n.Cmp(r[0]) < 0 || n.Cmp(r[1]) > 0 {
+				v.addError(nv.Position, "%s %s of field %s is out of range for %s", name, nv.Value, where, typeName)
+				return nil
+			}
+			return new(big.Rat).SetInt(n)
+		}
+		bits := 64
+		if typeName == "float32" {
+			bits = 32
+		}
+		r, ok := new(big.Rat).SetString(nv.Value)
+		if _, err := strconv.ParseFloat(nv.Value, bits); !ok || err != nil {
+			v.addError(nv.Position, "%s %s of field %s is out of range for %s", name, nv.Value, where, typeName)
+			return nil
+		}
+		return r
+	}
+	lo, hi := bound(a.Min, "min"), bound(a.Max, "max")
+	if lo != nil && hi != nil && lo.Cmp(hi) > 0 {
+		v.addError(field.Position, "min %s of field %s exceeds max %s", a.Min.Value, where, a.Max.Value)
+	}
+}
+
+// validateItemRange checks the min_items and max_items options of a field.
+// They must be non-negative integers and only apply to repeated, slice and
+// map fields.
+func (v *Validator) validateItemRange(msg *Message, field *Field) {
+	where := msg.Name + "." + field.Name
+	valid := true
+	for _, opt := range field.Options {
+		if opt.Name != "min_items" && opt.Name != "max_items" {
+			continue
+		}
+		nv, ok := opt.Value.(*NumberValue)
+		if !ok || nv.IsFloat {
+			v.addError(opt.Position, "%s option of field %s must be an integer", opt.Name, where)
+			valid = false
+			continue
+		}
+		if n, err := strconv.ParseInt(nv.Value, 0, 32); err != nil || n < 0 {
+			v.addError(opt.Position, "%s %s of field %s must be between 0 and %d", opt.Name, nv.Value, where, math.MaxInt32)
+			valid = false
+		}
+	}
+
+	a := field.Annotations()
+	if !a.HasItemRange() {
+		return
+	}
+	container := field.Repeated
+	switch t := field.Type.(type) {
+	case *ArrayType:
+		container = container || t.Size == 0
+	case *MapType:
+		container = true
+	}
+	if !container {
+		v.addError(field.Position, "min_items and max_items options of field %s require a repeated, slice or map type, got %s",
+			where, field.Type)
+		return
+	}
+	if valid && a.MinItems != nil && a.MaxItems != nil {
+		lo, _ := strconv.ParseInt(a.MinItems.Value, 0, 32)
+		hi, _ := strconv.ParseInt(a.MaxItems.Value, 0, 32)
+		if lo > hi {
+			v.addError(field.Position, "min_items %s of field %s exceeds max_items %s", a.MinItems.Value, where, a.MaxItems.Value)
+		}
+	}
+}
+
+// validateOrdered checks the ordered option of a field, which only applies
+// to map fields.
+func (v *Validator) validateOrdered(msg *Message, field *Field) {
+	for _, opt := range field.Options {
+		if opt.Name != "ordered" {
+			continue
+		}
+		if _, ok := opt.Value.(*BoolValue); !ok {
+			v.addError(opt.Position, "ordered option of field %s.%s must be true or false", msg.Name, field.Name)
+			continue
+		}
+		if _, isMap := field.Type.(*MapType); field.Ordered() && (!isMap || field.Repeated) {
+			v.addError(opt.Position, "ordered option of field %s.%s requires a map type, got %s",
+				msg.Name, field.Name, field.Type)
+		}
+	}
+}
+
+// validateDelta checks the delta option, which only applies to lists of
+// int64 values.
+func (v *Validator) validateDelta(msg *Message, field *Field) {
+	for _, opt := range field.Options {
+		if opt.Name != "delta" {
+			continue
+		}
+		if _, ok := opt.Value.(*BoolValue); !ok {
+			v.addError(opt.Position, "delta option of field %s.%s must be true or false", msg.Name, field.Name)
+			continue
+		}
+		if field.Delta() && field.DeltaElement() == nil {
+			v.addError(opt.Position, "delta option of field %s.%s requires a list of int64, got %s",
+				msg.Name, field.Name, field.Type)
+		}
+	}
+}
+
+// validateDefault checks the default option, which gives a bytes field a
+// byte literal to start from when it is absent from the data.
+func (v *Validator) validateDefault(msg *Message, field *Field) {
+	for _, opt := range field.Options {
+		if opt.Name != "default" {
+			continue
+		}
+		if _, ok := opt.Value.(*BytesValue); !ok {
+			v.addError(opt.Position, "default option of field %s.%s must be a byte literal such as 0x0a1b or b64\"Chs=\"",
+				msg.Name, field.Name)
+			continue
+		}
+		st, ok := field.Type.(*ScalarType)
+		if !ok || st.Name != "bytes" || field.Repeated {
+			typ := field.Type.String()
+			if field.Repeated {
+				typ = "repeated " + typ
+			}
+			v.addError(opt.Position, "default option of field %s.%s only applies to bytes fields, got %s",
+				msg.Name, field.Name, typ)
+		} else if field.Optional {
+			v.addError(opt.Position, "optional field %s.%s cannot have a default; it would never be absent",
+				msg.Name, field.Name)
+		}
+	}
+}
+
+// checkEnumOnlyOptions reports options that only apply to enums but were
+// set elsewhere.
+func (v *Validator) checkEnumOnlyOptions(opts []*Option, where string) {
+	for _, opt := range opts {
+		if opt.Name == "enum_encoding" {
+			v.addError(opt.Position, "enum_encoding option is only valid on enums, not on %s", where)
+		}
+	}
+}
+
+// validateIdentValues checks that every identifier used as an option value
+// names a known enum value.
+func (v *Validator) validateIdentValues() {
+	var check func(val Value)
+	check = func(val Value) {
+		switch val := val.(type) {
+		case *IdentValue:
+			v.resolveIdentValue(val)
+		case *ListValue:
+			for _, elem := range val.Values {
+				check(elem)
+			}
+		}
+	}
+	checkAll := func(opts []*Option) {
+		for _, opt := range opts {
+			check(opt.Value)
+		}
+	}
+
+	checkAll(v.schema.Options)
+	for _, msg := range v.schema.Messages {
+		checkAll(msg.Options)
+		for _, field := range msg.Fields {
+			checkAll(field.Options)
+		}
+	}
+	for _, enum := range v.schema.Enums {
+		checkAll(enum.Options)
+		for _, val := range enum.Values {
+			checkAll(val.Options)
+		}
+	}
+	for _, iface := range v.schema.Interfaces {
+		checkAll(iface.Options)
+	}
+}
+
+// resolveIdentValue reports an error unless the identifier names exactly
+// one enum value. A bare value name is looked up in every enum of the
+// schema and its same-package imports; Enum.VALUE names the enum, and
+// alias.Enum.VALUE an enum of the import with that alias.
+func (v *Validator) resolveIdentValue(val *IdentValue) {
+	parts := strings.Split(val.Name, ".")
+
+	var enums []*Enum
+	switch len(parts) {
+	case 1, 2:
+		enums = append(enums, v.schema.Enums...)
+		for _, imported := range v.imports {
+			if imported != nil && imported.Package != nil && v.schema.Package != nil &&
+				imported.Package.Name == v.schema.Package.Name {
+				enums = append(enums, imported.Enums...)
+			}
+		}
+	case 3:
+		imported, ok := v.imports[parts[0]]
+		if !ok || imported == nil {
+			v.addError(val.Position, "unknown package %q in identifier %s", parts[0], val.Name)
+			return
+		}
+		enums = imported.Enums
+		parts = parts[1:]
+	default:
+		v.addError(val.Position, "identifier %s has too many parts; use VALUE, Enum.VALUE or alias.Enum.VALUE", val.Name)
+		return
+	}
+
+	var matches []string
+	for _, enum := range enums {
+		if len(parts) == 2 && enum.Name != parts[0] {
+			continue
+		}
+		for _, ev := range enum.Values {
+			if ev.Name == parts[len(parts)-1] {
+				matches = append(matches, enum.Name)
+			}
+		}
+	}
+
+	switch {
+	case len(matches) == 0:
+		v.addError(val.Position, "identifier %s does not name a known enum value", val.Name)
+	case len(matches) > 1:
+		v.addError(val.Position, "identifier %s is ambiguous: it is a value of enums %s; qualify it with the enum name",
+			val.Name, strings.Join(matches, ", "))
+	}
 }
 
 // validateInterface validates an interface definition.
@@ -296,42 +684,27 @@ func (v *Validator) validateInterface(iface *Interface) {
 			typeIDs[impl.TypeID] = impl.Type.Name
 		}
 
-		// Validate that the referenced type exists and is a message
-		typeName := impl.Type.Name
-		if impl.Type.Package != "" {
-			// Qualified type - check imported schema
-			importedSchema, ok := v.imports[impl.Type.Package]
-			if !ok {
-				v.addError(impl.Position, "unknown package %q", impl.Type.Package)
-				continue
-			}
-			// Check type exists in imported schema
-			found := false
-			for _, msg := range importedSchema.Messages {
-				if msg.Name == impl.Type.Name {
-					found = true
-					break
-				}
-			}
-			if !found {
-				v.addError(impl.Position, "type %q not found in package %q",
-					impl.Type.Name, impl.Type.Package)
-			}
-		} else {
-			// Local type - check it exists locally or in same-package imports
-			typeDef, ok := v.types[typeName]
-			if ok {
-				if typeDef.Kind != TypeDefMessage {
-					v.addError(impl.Position, "interface implementation must reference a message, not %s %q",
-						typeDef.Kind, typeName)
-				}
-			} else {
-				// Check if the type exists in any imported schema from the same package
-				found := v.findMessageInSamePackageImports(typeName)
-				if !found {
-					v.addError(impl.Position, "undefined type %q", typeName)
-				}
-			}
+		// Validate that the referenced type exists and is a message, here
+		// or in an import
+		if impl.Type.Package == "" && IsScalar(impl.Type.Name) {
+			v.addError(impl.Position, "interface implementation must reference a message, not scalar type %q",
+				impl.Type.Name)
+			continue
+		}
+		if _, ok := v.imports[impl.Type.Package]; impl.Type.Package != "" && !ok {
+			v.addError(impl.Position, "unknown package %q", impl.Type.Package)
+			continue
+		}
+		kind, ok := v.namedTypeKind(impl.Type)
+		switch {
+		case !ok && impl.Type.Package != "":
+			v.addError(impl.Position, "type %q not found in package %q",
+				impl.Type.Name, impl.Type.Package)
+		case !ok:
+			v.addError(impl.Position, "undefined type %q", impl.Type.Name)
+		case kind != TypeDefMessage:
+			v.addError(impl.Position, "interface implementation must reference a message, not %s %q",
+				kind, impl.Type.String())
 		}
 	}
 }
@@ -343,22 +716,19 @@ func (v *Validator) validateTypeRef(typeRef TypeRef, msgName, fieldName string)
 		// Scalar types are always valid (checked during parsing)
 
 	case *NamedType:
-		if t.Package != "" {
-			// Qualified type - check imported schema
-			if _, ok := v.imports[t.Package]; !ok {
-				v.addError(t.Position, "unknown package %q in field %s.%s",
-					t.Package, msgName, fieldName)
-			}
-		} else {
-			// Local type - check it exists locally or in same-package imports
-			if _, ok := v.types[t.Name]; !ok {
-				// Check if the type exists in any imported schema from the same package
-				found := v.findTypeInSamePackageImports(t.Name)
-				if !found {
-					v.addError(t.Position, "undefined type %q in field %s.%s",
-						t.Name, msgName, fieldName)
-				}
-			}
+		// Qualified types must name an imported schema, and every named
+		// type must be defined there, locally or in a same-package import.
+		// An import that failed to load has a nil schema; its types can't
+		// be resolved, and the failure is reported where it was loaded
+		imported, ok := v.imports[t.Package]
+		switch {
+		case t.Package != "" && !ok:
+			v.addError(t.Position, "unknown package %q in field %s.%s",
+				t.Package, msgName, fieldName)
+		case t.Package != "" && imported == nil:
+		case !v.resolveNamedType(t):
+			v.addError(t.Position, "undefined type %q in field %s.%s",
+				t.String(), msgName, fieldName)
 		}
 
 	case *ArrayType:
@@ -369,31 +739,43 @@ func (v *Validator) validateTypeRef(typeRef TypeRef, msgName, fieldName string)
 
 	case *MapType:
 		v.validateTypeRef(t.Key, msgName, fieldName)
+		v.validateMapKeyType(t.Key, msgName, fieldName)
 		v.validateTypeRef(t.Value, msgName, fieldName)
 
 	case *PointerType:
 		v.validateTypeRef(t.Element, msgName, fieldName)
+		if isAnyType(t.Element) {
+			v.addError(t.Position, "pointer to any is not allowed in field %s.%s", msgName, fieldName)
+		}
 	}
 }
 
-// validateMapKeyType ensures map key types are valid (must be comparable).
+// isAnyType reports whether t is the dynamic any type.
+func isAnyType(t TypeRef) bool {
+	st, ok := t.(*ScalarType)
+	return ok && st.Name == "any"
+}
+
+// validateMapKeyType ensures map key types are valid. Keys must be
+// comparable scalars or enums, which the runtimes can order to encode maps
+// deterministically; messages, interfaces, arrays, maps and pointers are
+// rejected here rather than when a value is first encoded. It is called for
+// every map type, including maps nested in other types.
 func (v *Validator) validateMapKeyType(keyType TypeRef, msgName, fieldName string) {
 	switch t := keyType.(type) {
 	case *ScalarType:
 		// Most scalar types are valid keys
 		switch t.Name {
-		case "bytes", "float32", "float64", "complex64", "complex128":
+		case "bytes", "float32", "float64", "complex64", "complex128", "any":
 			v.addError(t.Position, "map key type %q is not comparable in field %s.%s",
 				t.Name, msgName, fieldName)
 		}
 
 	case *NamedType:
 		// Named types can only be enums for keys
-		if t.Package == "" {
-			if typeDef, ok := v.types[t.Name]; ok && typeDef.Kind != TypeDefEnum {
-				v.addError(t.Position, "map key type must be scalar or enum, not %s in field %s.%s",
-					typeDef.Kind, msgName, fieldName)
-			}
+		if kind, ok := v.namedTypeKind(t); ok && kind != TypeDefEnum {
+			v.addError(t.Position, "map key type must be scalar or enum, not %s %q in field %s.%s",
+				kind, t.String(), msgName, fieldName)
 		}
 
 	case *ArrayType, *MapType, *PointerType:
@@ -402,68 +784,93 @@ func (v *Validator) validateMapKeyType(keyType TypeRef, msgName, fieldName strin
 	}
 }
 
-// findTypeInSamePackageImports checks if a type exists in any imported schema
-// that has the same package name as the current schema. This allows unqualified
-// references to types from same-package imports.
-func (v *Validator) findTypeInSamePackageImports(typeName string) bool {
+// namedTypeKind resolves a named type to the kind of its definition, looking
+// in this schema, the import it is qualified with, or same-package imports.
+// It returns false if the type is undefined, which validateTypeRef reports.
+func (v *Validator) namedTypeKind(t *NamedType) (TypeDefKind, bool) {
+	return schemaTypeKind(v.namedTypeSchema(t), t.Name)
+}
+
+// namedTypeSchema returns the schema defining a named type, or nil if the
+// type is undefined.
+func (v *Validator) namedTypeSchema(t *NamedType) *Schema {
+	if t.Package != "" {
+		if s := v.imports[t.Package]; s != nil {
+			if _, ok := schemaTypeKind(s, t.Name); ok {
+				return s
+			}
+		}
+		return nil
+	}
+	if _, ok := v.types[t.Name]; ok {
+		return v.schema
+	}
 	if v.schema.Package == nil {
-		return false
+		return nil
 	}
-	currentPkg := v.schema.Package.Name
-
 	for _, importedSchema := range v.imports {
-		if importedSchema == nil || importedSchema.Package == nil {
+		if importedSchema == nil || importedSchema.Package == nil ||
+			importedSchema.Package.Name != v.schema.Package.Name {
 			continue
 		}
-		// Only check imports from the same package
-		if importedSchema.Package.Name != currentPkg {
-			continue
+		if _, ok := schemaTypeKind(importedSchema, t.Name); ok {
+			return importedSchema
 		}
-		// Check if the type exists in this imported schema
-		for _, msg := range importedSchema.Messages {
-			if msg.Name == typeName {
-				return true
-			}
+	}
+	return nil
+}
+
+// resolveNamedType points t at the message, enum or interface it names, so
+// code generators can tell enums from messages even when they are defined
+// in an imported schema. It reports whether the definition was found.
+func (v *Validator) resolveNamedType(t *NamedType) bool {
+	t.Message, t.Enum, t.Interface = nil, nil, nil
+	s := v.namedTypeSchema(t)
+	if s == nil {
+		return false
+	}
+	for _, msg := range s.Messages {
+		if msg.Name == t.Name {
+			t.Message = msg
+			return true
 		}
-		for _, enum := range importedSchema.Enums {
-			if enum.Name == typeName {
-				return true
-			}
+	}
+	for _, enum := range s.Enums {
+		if enum.Name == t.Name {
+			t.Enum = enum
+			return true
 		}
-		for _, iface := range importedSchema.Interfaces {
-			if iface.Name == typeName {
-				return true
-			}
+	}
+	for _, iface := range s.Interfaces {
+		if iface.Name == t.Name {
+			t.Interface = iface
+			return true
 		}
 	}
 	return false
 }
 
-// findMessageInSamePackageImports checks if a message exists in any imported schema
-// that has the same package name as the current schema. This is used for interface
-// implementations which must reference messages.
-func (v *Validator) findMessageInSamePackageImports(typeName string) bool {
-	if v.schema.Package == nil {
-		return false
+// schemaTypeKind returns the kind of the type named name defined in s.
+func schemaTypeKind(s *Schema, name string) (TypeDefKind, bool) {
+	if s == nil {
+		return 0, false
 	}
-	currentPkg := v.schema.Package.Name
-
-	for _, importedSchema := range v.imports {
-		if importedSchema == nil || importedSchema.Package == nil {
-			continue
+	for _, msg := range s.Messages {
+		if msg.Name == name {
+			return TypeDefMessage, true
 		}
-		// Only check imports from the same package
-		if importedSchema.Package.Name != currentPkg {
-			continue
+	}
+	for _, enum := range s.Enums {
+		if enum.Name == name {
+			return TypeDefEnum, true
 		}
-		// Check if the message exists in this imported schema
-		for _, msg := range importedSchema.Messages {
-			if msg.Name == typeName {
-				return true
-			}
+	}
+	for _, iface := range s.Interfaces {
+		if iface.Name == name {
+			return TypeDefInterface, true
 		}
 	}
-	return false
+	return 0, false
 }
 
 func (v *Validator) addError(pos Position, format string, args ...any) {
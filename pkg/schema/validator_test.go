@@ -1,6 +1,8 @@
 package schema
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -88,17 +90,12 @@ message User {
 }
 
 func TestValidateZeroFieldNumber(t *testing.T) {
-	input := `
-package test;
-
-message User {
-  int32 id = 0;
-}
-`
-
-	schema, parseErrors := ParseFile("test.cram", input)
-	if len(parseErrors) > 0 {
-		t.Fatalf("parse errors: %v", parseErrors)
+	// The parser rejects this number, so build the schema directly
+	schema := &Schema{
+		Package: &Package{Name: "test"},
+		Messages: []*Message{{Name: "User", Fields: []*Field{
+			{Name: "id", Number: 0, Type: &ScalarType{Name: "int32"}},
+		}}},
 	}
 
 	errors := Validate(schema)
@@ -114,17 +111,12 @@ message User {
 }
 
 func TestValidateNegativeFieldNumber(t *testing.T) {
-	input := `
-package test;
-
-message User {
-  int32 id = -1;
-}
-`
-
-	schema, parseErrors := ParseFile("test.cram", input)
-	if len(parseErrors) > 0 {
-		t.Fatalf("parse errors: %v", parseErrors)
+	// The parser rejects this number, so build the schema directly
+	schema := &Schema{
+		Package: &Package{Name: "test"},
+		Messages: []*Message{{Name: "User", Fields: []*Field{
+			{Name: "id", Number: -1, Type: &ScalarType{Name: "int32"}},
+		}}},
 	}
 
 	errors := Validate(schema)
@@ -266,6 +258,164 @@ enum Status {
 	}
 }
 
+func TestValidateEnumAllowAlias(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantError   string
+		wantWarning string
+	}{
+		{
+			name: "aliased values",
+			input: `
+enum Status {
+  option allow_alias = true;
+  UNKNOWN = 0;
+  STARTED = 1;
+  RUNNING = 1;
+}`,
+		},
+		{
+			name: "duplicate without allow_alias",
+			input: `
+enum Status {
+  UNKNOWN = 0;
+  STARTED = 1;
+  RUNNING = 1;
+}`,
+			wantError: "set option allow_alias = true",
+		},
+		{
+			name: "allow_alias disabled",
+			input: `
+enum Status {
+  option allow_alias = false;
+  UNKNOWN = 0;
+  STARTED = 1;
+  RUNNING = 1;
+}`,
+			wantError: "duplicate enum value number 1",
+		},
+		{
+			name: "allow_alias not a bool",
+			input: `
+enum Status {
+  option allow_alias = "yes";
+  UNKNOWN = 0;
+}`,
+			wantError: "must be true or false",
+		},
+		{
+			name: "allow_alias without aliases",
+			input: `
+enum Status {
+  option allow_alias = true;
+  UNKNOWN = 0;
+  STARTED = 1;
+}`,
+			wantWarning: "has no aliased values",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, parseErrors := ParseFile("test.cram", "package test;\n"+tt.input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+
+			validator := NewValidator(schema)
+			errs := validator.Validate()
+			if tt.wantError == "" {
+				if validator.HasErrors() {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+			} else if !validator.HasErrors() || !strings.Contains(fmt.Sprint(errs), tt.wantError) {
+				t.Errorf("expected error containing %q, got %v", tt.wantError, errs)
+			}
+
+			if tt.wantWarning != "" && !strings.Contains(fmt.Sprint(validator.Warnings()), tt.wantWarning) {
+				t.Errorf("expected warning containing %q, got %v", tt.wantWarning, validator.Warnings())
+			}
+		})
+	}
+}
+
+func TestValidateEnumEncoding(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantError string
+	}{
+		{
+			name: "fixed32",
+			input: `
+enum Code {
+  option enum_encoding = "fixed32";
+  NONE = 0;
+}`,
+		},
+		{
+			name: "varint",
+			input: `
+enum Code {
+  option enum_encoding = "varint";
+  NONE = 0;
+}`,
+		},
+		{
+			name: "unknown encoding",
+			input: `
+enum Code {
+  option enum_encoding = "fixed64";
+  NONE = 0;
+}`,
+			wantError: `must be "varint" or "fixed32"`,
+		},
+		{
+			name: "on a message",
+			input: `
+message Event {
+  option enum_encoding = "fixed32";
+  int32 id = 1;
+}`,
+			wantError: "only valid on enums, not on message Event",
+		},
+		{
+			name: "on a field",
+			input: `
+message Event {
+  int32 id = 1 [enum_encoding = "fixed32"];
+}`,
+			wantError: "only valid on enums, not on field Event.id",
+		},
+		{
+			name:      "on a file",
+			input:     `option enum_encoding = "fixed32";`,
+			wantError: "only valid on enums, not on a file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, parseErrors := ParseFile("test.cram", "package test;\n"+tt.input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+
+			validator := NewValidator(schema)
+			errs := validator.Validate()
+			if tt.wantError == "" {
+				if validator.HasErrors() {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+			} else if !validator.HasErrors() || !strings.Contains(fmt.Sprint(errs), tt.wantError) {
+				t.Errorf("expected error containing %q, got %v", tt.wantError, errs)
+			}
+		})
+	}
+}
+
 func TestValidateEnumDuplicateName(t *testing.T) {
 	input := `
 package test;
@@ -289,6 +439,101 @@ enum Status {
 	}
 }
 
+func TestValidateCaseConversionCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+		names   []string
+	}{
+		{
+			name: "enum values collide",
+			input: `
+package test;
+
+enum Mode {
+  UNKNOWN = 0;
+  foo_bar = 1;
+  fooBar = 2;
+}
+`,
+			wantErr: true,
+			names:   []string{"foo_bar", "fooBar"},
+		},
+		{
+			name: "enum values distinct",
+			input: `
+package test;
+
+enum Mode {
+  UNKNOWN = 0;
+  FOO_BAR = 1;
+  FOOBAR = 2;
+}
+`,
+			wantErr: false,
+		},
+		{
+			name: "field names collide",
+			input: `
+package test;
+
+message User {
+  string user_id = 1;
+  string userId = 2;
+}
+`,
+			wantErr: true,
+			names:   []string{"user_id", "userId"},
+		},
+		{
+			name: "field names distinct",
+			input: `
+package test;
+
+message User {
+  string user_id = 1;
+  string user_ids = 2;
+  string a_bc = 3;
+  string ab_c = 4;
+}
+`,
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, parseErrors := ParseFile("test.cram", tt.input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+
+			var collisions []ValidationError
+			for _, err := range Validate(schema) {
+				if err.Severity == SeverityError && strings.Contains(err.Message, "after case conversion") {
+					collisions = append(collisions, err)
+				}
+			}
+
+			if !tt.wantErr {
+				if len(collisions) > 0 {
+					t.Errorf("unexpected collision errors: %v", collisions)
+				}
+				return
+			}
+			if len(collisions) != 1 {
+				t.Fatalf("expected 1 collision error, got %v", collisions)
+			}
+			for _, name := range tt.names {
+				if !strings.Contains(collisions[0].Message, fmt.Sprintf("%q", name)) {
+					t.Errorf("error %q should mention %q", collisions[0].Message, name)
+				}
+			}
+		})
+	}
+}
+
 func TestValidateEnumMissingZero(t *testing.T) {
 	input := `
 package test;
@@ -419,6 +664,63 @@ interface Animal {
 	}
 }
 
+func TestValidateInterfaceImplementations(t *testing.T) {
+	other, errs := ParseFile("other.cram", `package other;
+message Bird { string name = 1; }
+enum Color { RED = 0; }
+`)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	shared, errs := ParseFile("shared.cram", `package zoo;
+message Fish { string name = 1; }
+enum Habitat { SEA = 0; }
+`)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	tests := []struct {
+		name  string
+		impls string
+		err   string // empty for a valid set
+	}{
+		{"local, imported and same-package messages", "1 = Dog; 2 = other.Bird; 3 = Fish;", ""},
+		{"unresolved local type", "1 = Dog; 2 = Wolf;", `zoo.cram:7:1: error: undefined type "Wolf"`},
+		{"unresolved imported type", "1 = other.Wolf;", `type "Wolf" not found in package "other"`},
+		{"unknown package", "1 = wild.Wolf;", `unknown package "wild"`},
+		{"duplicate type ID", "1 = Dog; 1 = other.Bird;", `duplicate type ID 1 (also used by "Dog")`},
+		{"imported enum", "1 = other.Color;", `must reference a message, not enum "other.Color"`},
+		{"same-package enum", "1 = Habitat;", `must reference a message, not enum "Habitat"`},
+		{"scalar", "1 = string;", `must reference a message, not scalar type "string"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "package zoo;\nimport \"other.cram\" as other;\nimport \"shared.cram\";\nmessage Dog { string name = 1; }\ninterface Animal {\n" +
+				strings.ReplaceAll(tt.impls, "; ", ";\n") + "\n}\n"
+			s, errs := ParseFile("zoo.cram", input)
+			if len(errs) > 0 {
+				t.Fatalf("parse errors: %v", errs)
+			}
+			v := NewValidator(s)
+			v.AddImport("other.cram", "other", other)
+			v.AddImport("shared.cram", "", shared)
+			verrs := v.Validate()
+
+			if tt.err == "" {
+				if v.HasErrors() {
+					t.Errorf("unexpected errors: %v", verrs)
+				}
+				return
+			}
+			if len(verrs) != 1 || !strings.Contains(verrs[0].Error(), tt.err) {
+				t.Errorf("errors = %v, want one containing %q", verrs, tt.err)
+			}
+		})
+	}
+}
+
 func TestValidateMapKeyType(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -431,6 +733,14 @@ func TestValidateMapKeyType(t *testing.T) {
 		{"bytes key", "map[bytes]string", true},     // bytes not comparable
 		{"float32 key", "map[float32]string", true}, // floats not comparable
 		{"float64 key", "map[float64]string", true},
+		{"any key", "map[any]string", true},
+		{"any value", "map[string]any", false},
+		{"enum key", "map[Color]string", false},
+		{"message key", "map[Point]int32", true},
+		{"interface key", "map[Shape]int32", true},
+		{"pointer key", "map[*int32]string", true},
+		{"nested message key", "map[string]map[Point]int32", true},
+		{"message value", "map[Color]Point", false},
 	}
 
 	for _, tt := range tests {
@@ -438,6 +748,18 @@ func TestValidateMapKeyType(t *testing.T) {
 			input := `
 package test;
 
+enum Color {
+  RED = 0;
+}
+
+message Point {
+  int32 x = 1;
+}
+
+interface Shape {
+  128 = Point;
+}
+
 message Test {
   ` + tt.keyType + ` data = 1;
 }
@@ -460,6 +782,127 @@ message Test {
 	}
 }
 
+func TestValidateImportedMapKeyType(t *testing.T) {
+	mainInput := `
+package main;
+
+import "other.cram" as other;
+
+message Index {
+  map[other.Kind]string byKind = 1;
+  map[other.Address]string byAddress = 2;
+}
+`
+	otherInput := `
+package other;
+
+enum Kind {
+  HOME = 0;
+}
+
+message Address {
+  string street = 1;
+}
+`
+	mainSchema, parseErrors := ParseFile("main.cram", mainInput)
+	if len(parseErrors) > 0 {
+		t.Fatalf("parse errors: %v", parseErrors)
+	}
+	otherSchema, parseErrors := ParseFile("other.cram", otherInput)
+	if len(parseErrors) > 0 {
+		t.Fatalf("parse errors: %v", parseErrors)
+	}
+
+	validator := NewValidator(mainSchema)
+	validator.AddImport("other.cram", "other", otherSchema)
+	errors := validator.Validate()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got %v", errors)
+	}
+	if errors[0].Position.Line != 8 || !strings.Contains(errors[0].Message, `not message "other.Address"`) {
+		t.Errorf("unexpected error: %v", errors[0])
+	}
+}
+
+func TestValidateAnyField(t *testing.T) {
+	tests := []struct {
+		name      string
+		field     string
+		expectErr bool
+	}{
+		{"plain", "any payload = 1;", false},
+		{"repeated", "repeated any payload = 1;", false},
+		{"required", "required any payload = 1;", false},
+		{"optional", "optional any payload = 1;", true},
+		{"pointer", "*any payload = 1;", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `
+package test;
+
+message Test {
+  ` + tt.field + `
+}
+`
+			schema, parseErrors := ParseFile("test.cram", input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+
+			validator := NewValidator(schema)
+			errors := validator.Validate()
+
+			if tt.expectErr && !validator.HasErrors() {
+				t.Errorf("expected error for %s", tt.field)
+			}
+			if !tt.expectErr && validator.HasErrors() {
+				t.Errorf("unexpected error for %s: %v", tt.field, errors)
+			}
+		})
+	}
+}
+
+func TestValidateFramingOption(t *testing.T) {
+	tests := []struct {
+		value     string
+		expectErr bool
+	}{
+		{`"length"`, false},
+		{`"marker"`, false},
+		{`"none"`, true},
+		{`1`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			input := `
+package test;
+
+message Test {
+  option framing = ` + tt.value + `;
+  int32 id = 1;
+}
+`
+			schema, parseErrors := ParseFile("test.cram", input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+
+			validator := NewValidator(schema)
+			errors := validator.Validate()
+
+			if tt.expectErr && !validator.HasErrors() {
+				t.Errorf("expected error for framing = %s", tt.value)
+			}
+			if !tt.expectErr && validator.HasErrors() {
+				t.Errorf("unexpected error for framing = %s: %v", tt.value, errors)
+			}
+		})
+	}
+}
+
 func TestValidateModifierCombinations(t *testing.T) {
 	input := `
 package test;
@@ -521,6 +964,110 @@ message Address {
 	}
 }
 
+func TestValidateResolvesNamedTypes(t *testing.T) {
+	mainInput := `
+package shop;
+
+import "common.cram" as common;
+import "types.cram";
+
+enum Priority {
+  LOW = 0;
+}
+
+message Note {
+  string text = 1;
+}
+
+message Order {
+  Priority priority = 1;
+  Note note = 2;
+  common.Status status = 3;
+  []common.Address addresses = 4;
+  map[string]Color colors = 5;
+  common.Missing missing = 6;
+}
+`
+	commonInput := `
+package common;
+
+enum Status {
+  UNKNOWN = 0;
+}
+
+message Address {
+  string street = 1;
+}
+`
+	typesInput := `
+package shop;
+
+enum Color {
+  RED = 0;
+}
+`
+
+	parse := func(name, input string) *Schema {
+		s, errs := ParseFile(name, input)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors in %s: %v", name, errs)
+		}
+		return s
+	}
+	mainSchema := parse("main.cram", mainInput)
+	commonSchema := parse("common.cram", commonInput)
+	typesSchema := parse("types.cram", typesInput)
+
+	validator := NewValidator(mainSchema)
+	validator.AddImport("common.cram", "common", commonSchema)
+	validator.AddImport("types.cram", "types.cram", typesSchema)
+	errs := validator.Validate()
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, `undefined type "common.Missing" in field Order.missing`) {
+		t.Errorf("expected one undefined type error, got %v", errs)
+	}
+
+	fields := mainSchema.Messages[1].Fields
+	named := func(i int) *NamedType {
+		switch typ := fields[i].Type.(type) {
+		case *NamedType:
+			return typ
+		case *ArrayType:
+			return typ.Element.(*NamedType)
+		case *MapType:
+			return typ.Value.(*NamedType)
+		}
+		t.Fatalf("field %s has no named type", fields[i].Name)
+		return nil
+	}
+	if typ := named(0); typ.Enum != mainSchema.Enums[0] || typ.Message != nil {
+		t.Errorf("priority resolved to %+v, %+v", typ.Enum, typ.Message)
+	}
+	if typ := named(1); typ.Message != mainSchema.Messages[0] || typ.Enum != nil {
+		t.Errorf("note resolved to %+v, %+v", typ.Enum, typ.Message)
+	}
+	if typ := named(2); typ.Enum != commonSchema.Enums[0] {
+		t.Errorf("status resolved to %+v, want the imported enum", typ.Enum)
+	}
+	if typ := named(3); typ.Message != commonSchema.Messages[0] {
+		t.Errorf("addresses resolved to %+v, want the imported message", typ.Message)
+	}
+	if typ := named(4); typ.Enum != typesSchema.Enums[0] {
+		t.Errorf("colors resolved to %+v, want the same-package enum", typ.Enum)
+	}
+	if typ := named(5); typ.Enum != nil || typ.Message != nil || typ.Interface != nil {
+		t.Errorf("undefined type resolved to %+v", typ)
+	}
+
+	// Types of an import that failed to load are left unresolved without
+	// an error of their own
+	validator = NewValidator(parse("main.cram", mainInput))
+	validator.AddImport("common.cram", "common", nil)
+	validator.AddImport("types.cram", "types.cram", typesSchema)
+	if errs := validator.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors for types of a failed import, got %v", errs)
+	}
+}
+
 func TestValidateSamePackageImport(t *testing.T) {
 	// Main schema imports another schema from the same package
 	// Types from same-package imports can be used without qualification
@@ -758,6 +1305,41 @@ message Test {
 	}
 }
 
+func TestValidateRepeatedSlice(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		wantErr string
+	}{
+		{"repeated scalar", "repeated int32 rows = 1;", ""},
+		{"slice of slices", "[][]int32 rows = 1;", ""},
+		{"repeated fixed array", "repeated [4]byte rows = 1;", ""},
+		{"repeated slice", "repeated []int32 rows = 1;", "repeated field M.rows cannot have slice type []int32; use [][]int32"},
+		{"repeated slice of messages", "repeated []M rows = 1;", "cannot have slice type []M"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "package test;\nmessage M {\n  " + tt.field + "\n}\n"
+			schema, parseErrors := ParseFile("test.cram", input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+
+			errs := Validate(schema)
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Message, tt.wantErr) {
+				t.Errorf("expected one error containing %q, got %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
+
 func TestValidatePointerToUndefined(t *testing.T) {
 	input := `
 package test;
@@ -976,3 +1558,319 @@ message OtherType {
 		t.Error("expected error: unqualified type from different package should be rejected")
 	}
 }
+
+func TestValidateIdentOptionValues(t *testing.T) {
+	const enums = `
+package test;
+
+enum Status {
+  UNKNOWN = 0;
+  ACTIVE = 1;
+}
+
+enum Level {
+  UNKNOWN = 0;
+  HIGH = 1;
+}
+`
+	tests := []struct {
+		name    string
+		option  string
+		wantErr string
+	}{
+		{"bare value", "option default_status = ACTIVE;", ""},
+		{"qualified value", "option default_status = Level.UNKNOWN;", ""},
+		{"value in list", "option defaults = [ACTIVE, HIGH];", ""},
+		{"unknown value", "option default_status = MISSING;", "does not name a known enum value"},
+		{"value of another enum", "option default_status = Status.HIGH;", "does not name a known enum value"},
+		{"ambiguous value", "option default_status = UNKNOWN;", "ambiguous"},
+		{"unknown package", "option default_status = other.Status.ACTIVE;", "unknown package"},
+		{"unknown value in list", "option defaults = [ACTIVE, LOW];", "LOW does not name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := enums + "\nmessage M {\n  " + tt.option + "\n  int32 x = 1;\n}\n"
+			schema, parseErrors := ParseFile("test.cram", input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+
+			errs := Validate(schema)
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Message, tt.wantErr) {
+				t.Errorf("expected one error containing %q, got %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
+
+func TestValidateFieldAnnotations(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		wantErr string
+	}{
+		{"bounded int", `int32 x = 1 [unit = "ms", min = -5, max = 5];`, ""},
+		{"bounded float", "float32 x = 1 [min = 0.5, max = 1e3];", ""},
+		{"unit on string", `string x = 1 [unit = "bytes"];`, ""},
+		{"unit not a string", "int32 x = 1 [unit = 3];", "unit option of field M.x must be a string"},
+		{"bound not a number", `int32 x = 1 [max = "10"];`, "max option of field M.x must be a number"},
+		{"bound on string", "string x = 1 [max = 10];", "require a numeric scalar type, got string"},
+		{"bound on repeated", "[]int32 x = 1 [max = 10];", "require a numeric scalar type"},
+		{"float bound on int", "int32 x = 1 [max = 1.5];", "max of integer field M.x must be an integer"},
+		{"negative bound on unsigned", "uint8 x = 1 [min = -1];", "min -1 of field M.x is out of range for uint8"},
+		{"bound overflows type", "int8 x = 1 [max = 1000];", "max 1000 of field M.x is out of range for int8"},
+		{"bound overflows float32", "float32 x = 1 [max = 1e39];", "out of range for float32"},
+		{"min above max", "int64 x = 1 [min = 10, max = 1];", "min 10 of field M.x exceeds max 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "package test;\nmessage M {\n  " + tt.field + "\n}\n"
+			schema, parseErrors := ParseFile("test.cram", input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+
+			errs := Validate(schema)
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Message, tt.wantErr) {
+				t.Errorf("expected one error containing %q, got %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
+
+func TestValidateItemRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		wantErr string
+	}{
+		{"slice", "[]string x = 1 [min_items = 1, max_items = 100];", ""},
+		{"repeated", "repeated int32 x = 1 [max_items = 0];", ""},
+		{"map", "map[string]int32 x = 1 [min_items = 2];", ""},
+		{"equal bounds", "[]bytes x = 1 [min_items = 3, max_items = 3];", ""},
+		{"not a number", `[]string x = 1 [min_items = "1"];`, "min_items option of field M.x must be an integer"},
+		{"float", "[]string x = 1 [max_items = 1.5];", "max_items option of field M.x must be an integer"},
+		{"negative", "[]string x = 1 [min_items = -1];", "min_items -1 of field M.x must be between 0 and 2147483647"},
+		{"too large", "[]string x = 1 [max_items = 4294967296];", "max_items 4294967296 of field M.x must be between 0 and 2147483647"},
+		{"scalar", "int32 x = 1 [max_items = 10];", "require a repeated, slice or map type, got int32"},
+		{"fixed array", "[4]byte x = 1 [max_items = 10];", "require a repeated, slice or map type, got [4]byte"},
+		{"min above max", "[]string x = 1 [min_items = 10, max_items = 1];", "min_items 10 of field M.x exceeds max_items 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "package test;\nmessage M {\n  " + tt.field + "\n}\n"
+			schema, parseErrors := ParseFile("test.cram", input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+
+			errs := Validate(schema)
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Message, tt.wantErr) {
+				t.Errorf("expected one error containing %q, got %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
+
+func TestValidateOrderedOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		wantErr string
+	}{
+		{"ordered map", "map[string]int32 x = 1 [ordered = true];", ""},
+		{"unordered map", "map[string]int32 x = 1 [ordered = false];", ""},
+		{"not a bool", `map[string]int32 x = 1 [ordered = "yes"];`, "ordered option of field M.x must be true or false"},
+		{"not a map", "[]string x = 1 [ordered = true];", "ordered option of field M.x requires a map type, got []string"},
+		{"repeated map", "repeated map[string]int32 x = 1 [ordered = true];", "requires a map type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "package test;\nmessage M {\n  " + tt.field + "\n}\n"
+			schema, parseErrors := ParseFile("test.cram", input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+
+			errs := Validate(schema)
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Message, tt.wantErr) {
+				t.Errorf("expected one error containing %q, got %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
+
+func TestValidateDeltaOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		wantErr string
+	}{
+		{"slice", "[]int64 ids = 1 [delta = true];", ""},
+		{"repeated", "repeated int64 ids = 1 [delta = true];", ""},
+		{"disabled", "[]int32 ids = 1 [delta = false];", ""},
+		{"not a bool", "[]int64 ids = 1 [delta = 1];", "delta option of field M.ids must be true or false"},
+		{"int32 elements", "[]int32 ids = 1 [delta = true];", "delta option of field M.ids requires a list of int64, got []int32"},
+		{"scalar", "int64 ids = 1 [delta = true];", "requires a list of int64"},
+		{"fixed array", "[4]int64 ids = 1 [delta = true];", "requires a list of int64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "package test;\nmessage M {\n  " + tt.field + "\n}\n"
+			schema, parseErrors := ParseFile("test.cram", input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+
+			errs := Validate(schema)
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Message, tt.wantErr) {
+				t.Errorf("expected one error containing %q, got %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
+
+func TestValidateIdentOptionImportedEnum(t *testing.T) {
+	common, errs := ParseFile("common.cram", "package common;\nenum Status {\n  UNKNOWN = 0;\n  ACTIVE = 1;\n}\n")
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	schema, errs := ParseFile("test.cram", "package test;\nimport \"common.cram\" as common;\noption default_status = common.Status.ACTIVE;\n")
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	validator := NewValidator(schema)
+	validator.AddImport("common.cram", "common", common)
+	if verrs := validator.Validate(); len(verrs) > 0 {
+		t.Errorf("unexpected errors: %v", verrs)
+	}
+}
+
+func TestValidateScalarTypeNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"message", "message string {\n  int32 x = 1;\n}", `message name "string" is reserved`},
+		{"enum", "enum int32 {\n  A = 0;\n}", `enum name "int32" is reserved`},
+		{"interface", "interface bool {\n}", `interface name "bool" is reserved`},
+		{"any", "message any {\n}", `message name "any" is reserved`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, parseErrors := ParseFile("test.cram", "package test;\n"+tt.input+"\n")
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+			errs := Validate(schema)
+			if len(errs) == 0 || !strings.Contains(errs[0].Message, tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, errs)
+			}
+		})
+	}
+}
+
+func TestValidateKeywordFieldNames(t *testing.T) {
+	for _, keyword := range []string{"map", "message", "enum", "optional", "package", "true"} {
+		t.Run(keyword, func(t *testing.T) {
+			input := "package test;\nmessage M {\n  int32 " + keyword + " = 1;\n  string ok = 2;\n}\n"
+			schema, parseErrors := ParseFile("test.cram", input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+			if len(schema.Messages[0].Fields) != 2 {
+				t.Fatalf("expected parsing to continue past the keyword, got %d fields", len(schema.Messages[0].Fields))
+			}
+
+			errs := Validate(schema)
+			want := `field name "` + keyword + `" in message M is a reserved keyword`
+			if len(errs) != 1 || errs[0].Message != want {
+				t.Errorf("expected %q, got %v", want, errs)
+			}
+		})
+	}
+
+	// Scalar type names aren't keywords and remain valid field names
+	schema, parseErrors := ParseFile("test.cram", "package test;\nmessage M {\n  int32 string = 1;\n}\n")
+	if len(parseErrors) > 0 {
+		t.Fatalf("parse errors: %v", parseErrors)
+	}
+	if errs := Validate(schema); len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestValidateDefaultOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		wantErr string
+	}{
+		{"hex", "bytes magic = 1 [default = 0x0A1B2C];", ""},
+		{"base64", `bytes magic = 1 [default = b64"Chss"];`, ""},
+		{"required", "required bytes magic = 1 [default = 0xff];", ""},
+		{"string literal", `bytes magic = 1 [default = "abc"];`, "default option of field M.magic must be a byte literal"},
+		{"string field", "string magic = 1 [default = 0x0a];", "only applies to bytes fields, got string"},
+		{"repeated", "repeated bytes magic = 1 [default = 0x0a];", "got repeated bytes"},
+		{"optional", "optional bytes magic = 1 [default = 0x0a];", "cannot have a default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "package test;\nmessage M {\n  " + tt.field + "\n}\n"
+			schema, parseErrors := ParseFile("test.cram", input)
+			if len(parseErrors) > 0 {
+				t.Fatalf("parse errors: %v", parseErrors)
+			}
+
+			errs := Validate(schema)
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Message, tt.wantErr) {
+				t.Errorf("expected one error containing %q, got %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestItemRangeValidation verifies that generated Validate methods enforce
+// the min_items and max_items options of slice and map fields, including
+// after a round trip through the wire format.
+func TestItemRangeValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		msg   interop.BatchRequest
+		field string // field the validation error names, empty if valid
+	}{
+		{"minimum entries", interop.BatchRequest{Entries: []string{"a"}}, ""},
+		{"maximum entries and labels", interop.BatchRequest{
+			Entries: []string{"a", "b", "c"},
+			Labels:  map[string]string{"env": "prod", "team": "core"},
+		}, ""},
+		{"unbounded field", interop.BatchRequest{Entries: []string{"a"}, Checksums: make([]int64, 100)}, ""},
+		{"no entries", interop.BatchRequest{}, "entries"},
+		{"too many entries", interop.BatchRequest{Entries: []string{"a", "b", "c", "d"}}, "entries"},
+		{"too many labels", interop.BatchRequest{
+			Entries: []string{"a"},
+			Labels:  map[string]string{"a": "1", "b": "2", "c": "3"},
+		}, "labels"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := func(how string, err error) {
+				t.Helper()
+				if tt.field == "" {
+					if err != nil {
+						t.Errorf("%s: unexpected error: %v", how, err)
+					}
+					return
+				}
+				var verr *cramberry.ValidationError
+				if !errors.As(err, &verr) || verr.Field != tt.field {
+					t.Errorf("%s: error = %v, want a validation error for %s", how, err, tt.field)
+				}
+			}
+			check("Validate", tt.msg.Validate())
+
+			data, err := tt.msg.MarshalCramberry()
+			if err != nil {
+				t.Fatalf("MarshalCramberry error: %v", err)
+			}
+			var got interop.BatchRequest
+			if err := got.UnmarshalCramberry(data); err != nil {
+				t.Fatalf("UnmarshalCramberry error: %v", err)
+			}
+			check("Validate after decoding", got.Validate())
+		})
+	}
+}
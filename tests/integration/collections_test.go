@@ -0,0 +1,341 @@
+package integration
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestNestedSlicesRoundtrip verifies generated encoding of slices of slices,
+// including empty and ragged inner slices.
+func TestNestedSlicesRoundtrip(t *testing.T) {
+	original := &interop.Matrix{
+		Rows:  [][]int32{{1, 2, 3}, {}, {-4}, {5, -6, 7, -8, 9}},
+		Grid:  [][]int32{{100}, {200, 300}},
+		Words: [][]string{{"a", "bb"}, {"ccc"}, {}},
+	}
+
+	data, err := original.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	var decoded interop.Matrix
+	if err := decoded.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+
+	if len(decoded.Rows) != len(original.Rows) {
+		t.Fatalf("Rows: expected %d inner slices, got %d", len(original.Rows), len(decoded.Rows))
+	}
+	for i := range original.Rows {
+		if len(decoded.Rows[i]) != len(original.Rows[i]) {
+			t.Errorf("Rows[%d]: expected length %d, got %d", i, len(original.Rows[i]), len(decoded.Rows[i]))
+		}
+	}
+	if !reflect.DeepEqual(decoded.Rows, original.Rows) {
+		t.Errorf("Rows mismatch: got %v, want %v", decoded.Rows, original.Rows)
+	}
+	if !reflect.DeepEqual(decoded.Grid, original.Grid) {
+		t.Errorf("Grid mismatch: got %v, want %v", decoded.Grid, original.Grid)
+	}
+	if !reflect.DeepEqual(decoded.Words, original.Words) {
+		t.Errorf("Words mismatch: got %v, want %v", decoded.Words, original.Words)
+	}
+}
+
+// TestMapFieldsRoundtrip verifies generated encoding of map fields whose
+// values are scalars, slices, and maps.
+func TestMapFieldsRoundtrip(t *testing.T) {
+	original := &interop.Index{
+		Counts:   map[string]int32{"a": 1, "b": -2},
+		Postings: map[string][]int32{"x": {1, 2, 3}, "y": {}},
+		Nested: map[string]map[string]int32{
+			"outer": {"inner": 7, "other": -7},
+			"empty": {},
+		},
+	}
+
+	data, err := original.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	var decoded interop.Index
+	if err := decoded.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, original) {
+		t.Errorf("Index mismatch: got %+v, want %+v", &decoded, original)
+	}
+}
+
+// TestOrderedMapRoundtrip verifies that ordered map fields keep their
+// insertion order through encoding and decoding, and that a plain map field
+// reads the same bytes.
+func TestOrderedMapRoundtrip(t *testing.T) {
+	var original interop.Settings
+	original.Values.Set("zeta", "last")
+	original.Values.Set("alpha", "first")
+	original.Values.Set("mid", "middle")
+	original.Ranges.Set("b", []int32{1, 2})
+	original.Ranges.Set("a", []int32{})
+
+	data, err := original.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	var decoded interop.Settings
+	if err := decoded.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if got, want := decoded.Values.Keys(), []string{"zeta", "alpha", "mid"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Values keys = %v, want %v", got, want)
+	}
+	for k, v := range original.Values.All() {
+		if got, _ := decoded.Values.Get(k); got != v {
+			t.Errorf("Values[%q] = %q, want %q", k, got, v)
+		}
+	}
+	if got, want := decoded.Ranges.Keys(), []string{"b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Ranges keys = %v, want %v", got, want)
+	}
+	if got, _ := decoded.Ranges.Get("b"); !reflect.DeepEqual(got, []int32{1, 2}) {
+		t.Errorf("Ranges[b] = %v, want [1 2]", got)
+	}
+
+	// The wire format is that of a plain map, so schemas without the
+	// option still read it
+	var plain struct {
+		Values map[string]string `cramberry:"1"`
+	}
+	if err := cramberry.Unmarshal(data, &plain); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if want := map[string]string{"zeta": "last", "alpha": "first", "mid": "middle"}; !reflect.DeepEqual(plain.Values, want) {
+		t.Errorf("plain map = %v, want %v", plain.Values, want)
+	}
+
+	// The reflective codec writes and reads ordered maps as generated code
+	// does
+	reflective, err := cramberry.Marshal(&original)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if !bytes.Equal(reflective, data) {
+		t.Errorf("Marshal = %x, want %x", reflective, data)
+	}
+	var viaReflection interop.Settings
+	if err := cramberry.Unmarshal(data, &viaReflection); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if got, want := viaReflection.Values.Keys(), []string{"zeta", "alpha", "mid"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal Values keys = %v, want %v", got, want)
+	}
+}
+
+func TestDeltaListRoundtrip(t *testing.T) {
+	original := interop.Team{Scores: []int64{90, -3, 12}}
+	for i := range 200 {
+		original.Members = append(original.Members, 9_000_000_000+int64(i*7))
+	}
+
+	data, err := original.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+	var decoded interop.Team
+	if err := decoded.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("decoded %+v, want %+v", decoded, original)
+	}
+
+	// Without the option each member costs a full varint
+	plain, err := cramberry.Marshal(struct {
+		Members []int64 `cramberry:"1"`
+		Scores  []int64 `cramberry:"2"`
+	}{original.Members, original.Scores})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if len(data)*3 > len(plain) {
+		t.Errorf("delta encoded message is %d bytes, plain %d; want under a third", len(data), len(plain))
+	}
+}
+
+// TestMapFieldLyingHeader verifies that a map header declaring more entries
+// than the data holds fails cleanly instead of over-reading.
+func TestMapFieldLyingHeader(t *testing.T) {
+	w := cramberry.NewWriter()
+	w.WriteCompactTag(1, cramberry.WireTypeV2Bytes)
+	w.WriteMapHeader(1000)
+	w.WriteString("a")
+	w.WriteInt32(1)
+	w.WriteEndMarker()
+
+	var decoded interop.Index
+	err := decoded.UnmarshalCramberry(w.Bytes())
+	if !errors.Is(err, cramberry.ErrUnexpectedEOF) {
+		t.Fatalf("expected ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+// TestFixedByteArrayRoundtrip verifies that fixed-size byte arrays encode as
+// a single length-prefixed byte string and decode back into the array.
+func TestFixedByteArrayRoundtrip(t *testing.T) {
+	original := interop.Digest{Algorithm: "sha256"}
+	for i := range original.Sum {
+		original.Sum[i] = byte(i)
+	}
+	original.Crc = &[4]uint8{0xde, 0xad, 0xbe, 0xef}
+
+	data, err := original.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	// Tag, length, then the raw bytes with no per-element encoding
+	want := append([]byte{0x14, 0x20}, original.Sum[:]...)
+	if !bytes.HasPrefix(data, want) {
+		t.Errorf("encoding = %x, want prefix %x", data, want)
+	}
+
+	var decoded interop.Digest
+	if err := decoded.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("roundtrip mismatch:\n got %+v\nwant %+v", decoded, original)
+	}
+
+	// The reflective decoder reads the same bytes
+	var reflected interop.Digest
+	if err := cramberry.Unmarshal(data, &reflected); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if !reflect.DeepEqual(reflected, original) {
+		t.Errorf("reflective decode mismatch:\n got %+v\nwant %+v", reflected, original)
+	}
+}
+
+// TestFixedByteArrayLengthMismatch verifies that decoding a byte string of
+// the wrong length into a fixed-size array fails.
+func TestFixedByteArrayLengthMismatch(t *testing.T) {
+	data, err := cramberry.Marshal(struct {
+		Sum []byte `cramberry:"1"`
+	}{Sum: make([]byte, 31)})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	var decoded interop.Digest
+	if err := decoded.UnmarshalCramberry(data); !errors.Is(err, cramberry.ErrTypeMismatch) {
+		t.Errorf("expected ErrTypeMismatch, got %v", err)
+	}
+}
+
+// TestNilPointerElementsRoundtrip verifies that nil elements in maps and
+// slices of pointers decode as nil in both generated and reflective code,
+// and that empty messages, whose encoding is the nil marker byte, don't.
+func TestNilPointerElementsRoundtrip(t *testing.T) {
+	original := interop.Directory{
+		Entries: map[string]*interop.Digest{
+			"deleted": nil,
+			"current": {Algorithm: "sha256"},
+			"empty":   {},
+		},
+		History: []*interop.Digest{nil, {Algorithm: "md5"}, {}, nil},
+	}
+
+	data, err := original.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	var decoded interop.Directory
+	if err := decoded.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("roundtrip mismatch:\n got %+v\nwant %+v", decoded, original)
+	}
+
+	// The reflective decoder reads the generated nil markers
+	var reflected interop.Directory
+	if err := cramberry.Unmarshal(data, &reflected); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if !reflect.DeepEqual(reflected, original) {
+		t.Errorf("reflective decode mismatch:\n got %+v\nwant %+v", reflected, original)
+	}
+
+	// And the generated decoder reads the reflective encoding
+	data, err = cramberry.Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	decoded = interop.Directory{}
+	if err := decoded.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("decode of reflective encoding mismatch:\n got %+v\nwant %+v", decoded, original)
+	}
+}
+
+// TestOptionalMessageFieldPresence verifies that optional message fields
+// decode as nil when their tag is absent, and as an allocated message when
+// the tag is present, even if the message itself is empty.
+func TestOptionalMessageFieldPresence(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive interop.Archive
+	}{
+		{"absent", interop.Archive{Name: "none"}},
+		{"empty", interop.Archive{Latest: &interop.Digest{}, Previous: &interop.Digest{}}},
+		{"populated", interop.Archive{
+			Latest:   &interop.Digest{Algorithm: "sha256"},
+			Previous: &interop.Digest{Algorithm: "md5"},
+		}},
+		{"mixed", interop.Archive{Previous: &interop.Digest{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.archive.MarshalCramberry()
+			if err != nil {
+				t.Fatalf("MarshalCramberry error: %v", err)
+			}
+
+			var decoded interop.Archive
+			if err := decoded.UnmarshalCramberry(data); err != nil {
+				t.Fatalf("UnmarshalCramberry error: %v", err)
+			}
+			if (decoded.Latest == nil) != (tt.archive.Latest == nil) {
+				t.Errorf("Latest = %v, want %v", decoded.Latest, tt.archive.Latest)
+			}
+			if (decoded.Previous == nil) != (tt.archive.Previous == nil) {
+				t.Errorf("Previous = %v, want %v", decoded.Previous, tt.archive.Previous)
+			}
+			if !reflect.DeepEqual(decoded, tt.archive) {
+				t.Errorf("roundtrip mismatch:\n got %+v\nwant %+v", decoded, tt.archive)
+			}
+		})
+	}
+
+	// Only the name is on the wire, so nothing is allocated
+	data, err := (&interop.Archive{Name: "x"}).MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+	if want := []byte{0x34, 0x01, 'x', 0x00}; !bytes.Equal(data, want) {
+		t.Errorf("Archive encoding = %x, want %x", data, want)
+	}
+}
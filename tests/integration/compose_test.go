@@ -0,0 +1,48 @@
+package integration
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestSharedReaderDecode verifies that generated messages written back to
+// back with EncodeTo can be read one after the other with DecodeCramberry
+// from a single Reader, leaving it positioned after each message.
+func TestSharedReaderDecode(t *testing.T) {
+	first := interop.Frame{
+		Header: interop.Header{Id: 1, Kind: "first"},
+		Body:   "one",
+	}
+	second := interop.Frame{
+		Header:  interop.Header{Id: 2},
+		Body:    "two",
+		History: []interop.Header{{Id: 1, Kind: "first"}},
+	}
+
+	w := cramberry.NewWriter()
+	first.EncodeTo(w)
+	second.EncodeTo(w)
+	if err := w.Err(); err != nil {
+		t.Fatalf("EncodeTo error: %v", err)
+	}
+
+	r := cramberry.NewReader(w.Bytes())
+	var gotFirst, gotSecond interop.Frame
+	gotFirst.DecodeCramberry(r)
+	gotSecond.DecodeCramberry(r)
+	if err := r.Err(); err != nil {
+		t.Fatalf("DecodeCramberry error: %v", err)
+	}
+	if !r.EOF() {
+		t.Errorf("%d bytes left after decoding both messages", r.Len())
+	}
+	if !reflect.DeepEqual(gotFirst, first) {
+		t.Errorf("first message:\n got %+v\nwant %+v", gotFirst, first)
+	}
+	if !reflect.DeepEqual(gotSecond, second) {
+		t.Errorf("second message:\n got %+v\nwant %+v", gotSecond, second)
+	}
+}
@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// registerAnimals registers the generated animal types under their schema
+// type IDs for the duration of a test.
+func registerAnimals(t *testing.T) {
+	t.Helper()
+	cramberry.DefaultRegistry.Clear()
+	t.Cleanup(cramberry.DefaultRegistry.Clear)
+
+	cramberry.RegisterOrGetWithID[interop.Dog](128)
+	cramberry.RegisterOrGetWithID[interop.Cat](129)
+}
+
+// TestAnyFieldRoundtrip verifies that any fields carry different registered
+// types and decode them back to their concrete types.
+func TestAnyFieldRoundtrip(t *testing.T) {
+	registerAnimals(t)
+
+	tests := []struct {
+		name     string
+		envelope interop.Envelope
+	}{
+		{"dog", interop.Envelope{Topic: "pets", Payload: &interop.Dog{Name: "Rex", Breed: "collie"}}},
+		{"cat", interop.Envelope{Topic: "pets", Payload: &interop.Cat{Name: "Tom", Lives: 9}}},
+		{"empty", interop.Envelope{Topic: "none"}},
+		{"mixed items", interop.Envelope{
+			Payload: &interop.Cat{Name: "Felix"},
+			Items:   []any{&interop.Dog{Name: "Fido"}, nil, &interop.Cat{Lives: 3}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.envelope.MarshalCramberry()
+			if err != nil {
+				t.Fatalf("MarshalCramberry error: %v", err)
+			}
+
+			var decoded interop.Envelope
+			if err := decoded.UnmarshalCramberry(data); err != nil {
+				t.Fatalf("UnmarshalCramberry error: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.envelope) {
+				t.Errorf("roundtrip mismatch:\n got %#v\nwant %#v", decoded, tt.envelope)
+			}
+
+			// The reflective path produces the same bytes
+			reflected, err := cramberry.Marshal(tt.envelope)
+			if err != nil {
+				t.Fatalf("Marshal error: %v", err)
+			}
+			if !reflect.DeepEqual(reflected, data) {
+				t.Errorf("reflective encoding differs:\n got %x\nwant %x", reflected, data)
+			}
+		})
+	}
+}
+
+// TestAnyFieldUnregistered verifies that any fields only accept types known
+// to the registry.
+func TestAnyFieldUnregistered(t *testing.T) {
+	registerAnimals(t)
+
+	type unknown struct{ X int32 }
+	_, err := (&interop.Envelope{Payload: &unknown{X: 1}}).MarshalCramberry()
+	if !errors.Is(err, cramberry.ErrUnregisteredType) {
+		t.Errorf("expected ErrUnregisteredType, got %v", err)
+	}
+
+	data, err := (&interop.Envelope{Payload: &interop.Dog{Name: "Rex"}}).MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+	cramberry.DefaultRegistry.Clear()
+
+	var decoded interop.Envelope
+	if err := decoded.UnmarshalCramberry(data); !errors.Is(err, cramberry.ErrUnknownType) {
+		t.Errorf("expected ErrUnknownType, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package integration
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestEnumEncodingRoundtrip verifies that enums declared with
+// enum_encoding = "fixed32" are written as 4-byte integers while other enums
+// keep the signed varint encoding.
+func TestEnumEncodingRoundtrip(t *testing.T) {
+	original := interop.Instruction{
+		Opcode:   interop.OpcodeCall,
+		Severity: interop.SeverityError,
+		Trace:    []interop.Opcode{interop.OpcodeReturn, interop.OpcodeNop},
+	}
+
+	data, err := original.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	var decoded interop.Instruction
+	if err := decoded.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("roundtrip mismatch:\n got %+v\nwant %+v", decoded, original)
+	}
+
+	data, err = (&interop.Instruction{Opcode: interop.OpcodeCall, Severity: interop.SeverityError}).MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+	want := []byte{
+		0x16, 0x00, 0x00, 0x00, 0x01, // field 1, fixed32 16777216
+		0x28, 0x04, // field 2, svarint 2
+		0x00, // end marker
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("Instruction encoding = %x, want %x", data, want)
+	}
+
+	// Readers that don't know the opcode skip it by its wire type
+	var severityOnly struct {
+		Severity int32 `cramberry:"2"`
+	}
+	if err := cramberry.Unmarshal(data, &severityOnly); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if severityOnly.Severity != int32(interop.SeverityError) {
+		t.Errorf("Severity = %d, want %d", severityOnly.Severity, interop.SeverityError)
+	}
+}
+
+// TestPackedEnumList verifies that a repeated enum field round-trips and
+// is written as the reflective encoder writes a slice of int32-kind
+// values: a count followed by one svarint per value.
+func TestPackedEnumList(t *testing.T) {
+	original := interop.Instruction{
+		History: []interop.Severity{interop.SeverityError, interop.SeverityInfo, interop.SeverityError},
+	}
+
+	data, err := original.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+	want := []byte{
+		0x44, 0x03, // field 4, 3 values
+		0x04, 0x00, 0x04, // svarint 2, 0, 2
+		0x00, // end marker
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("Instruction encoding = %x, want %x", data, want)
+	}
+
+	var decoded interop.Instruction
+	if err := decoded.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("roundtrip mismatch:\n got %+v\nwant %+v", decoded, original)
+	}
+
+	var reflective struct {
+		History []interop.Severity `cramberry:"4"`
+	}
+	reflective.History = original.History
+	if got, err := cramberry.Marshal(&reflective); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("reflective encoding = %x, %v, want %x", got, err, want)
+	}
+}
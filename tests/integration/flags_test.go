@@ -0,0 +1,76 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// unpackedFlags has the fields of FeatureFlags, encoded reflectively with
+// one bool per field.
+type unpackedFlags struct {
+	Account  string `cramberry:"1"`
+	DarkMode bool   `cramberry:"2"`
+	Beta     bool   `cramberry:"3"`
+	Metrics  bool   `cramberry:"4"`
+	Offline  bool   `cramberry:"5"`
+	Version  int32  `cramberry:"6"`
+	Trial    bool   `cramberry:"7"`
+}
+
+// TestPackBools verifies that code generated with -pack-bools keeps every
+// bool of a packed run through a round trip, writes fewer bytes than one
+// field per bool, and still decodes bools written one per field.
+func TestPackBools(t *testing.T) {
+	for bits := range 32 {
+		msg := interop.FeatureFlags{
+			Account:  "acme",
+			DarkMode: bits&1 != 0,
+			Beta:     bits&2 != 0,
+			Metrics:  bits&4 != 0,
+			Offline:  bits&8 != 0,
+			Version:  3,
+			Trial:    bits&16 != 0,
+		}
+
+		data, err := msg.MarshalCramberry()
+		if err != nil {
+			t.Fatalf("MarshalCramberry error: %v", err)
+		}
+		var got interop.FeatureFlags
+		if err := got.UnmarshalCramberry(data); err != nil {
+			t.Fatalf("UnmarshalCramberry(%+v) error: %v", msg, err)
+		}
+		if got != msg {
+			t.Errorf("round trip = %+v, want %+v", got, msg)
+		}
+
+		unpacked, err := cramberry.Marshal(&unpackedFlags{
+			Account:  msg.Account,
+			DarkMode: msg.DarkMode,
+			Beta:     msg.Beta,
+			Metrics:  msg.Metrics,
+			Offline:  msg.Offline,
+			Version:  msg.Version,
+			Trial:    msg.Trial,
+		})
+		if err != nil {
+			t.Fatalf("Marshal error: %v", err)
+		}
+		if len(data) > len(unpacked) {
+			t.Errorf("%+v: packed %d bytes, unpacked %d", msg, len(data), len(unpacked))
+		}
+		if bits&15 == 15 && len(data) != len(unpacked)-6 {
+			t.Errorf("all packed bools set: packed %d bytes, want 6 fewer than %d", len(data), len(unpacked))
+		}
+
+		got = interop.FeatureFlags{}
+		if err := got.UnmarshalCramberry(unpacked); err != nil {
+			t.Fatalf("UnmarshalCramberry of unpacked data error: %v", err)
+		}
+		if got != msg {
+			t.Errorf("decoded unpacked data = %+v, want %+v", got, msg)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package integration
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestFramingRoundtrip verifies that messages with length framing decode
+// back to the original value, both on their own and nested in messages that
+// use end markers.
+func TestFramingRoundtrip(t *testing.T) {
+	original := interop.Frame{
+		Header: interop.Header{Id: 7, Kind: "data"},
+		Body:   "payload",
+		History: []interop.Header{
+			{Id: 1},
+			{Kind: "empty-id"},
+			{},
+		},
+	}
+
+	data, err := original.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	var decoded interop.Frame
+	if err := decoded.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("roundtrip mismatch:\n got %+v\nwant %+v", decoded, original)
+	}
+
+	header := interop.Header{Id: 1}
+	data, err = header.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+	// Length prefix, then the field, and no end marker
+	if want := []byte{0x02, 0x18, 0x02}; !bytes.Equal(data, want) {
+		t.Errorf("Header encoding = %x, want %x", data, want)
+	}
+	var decodedHeader interop.Header
+	if err := decodedHeader.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if decodedHeader != header {
+		t.Errorf("got %+v, want %+v", decodedHeader, header)
+	}
+}
+
+// TestFramingSkip verifies that a reader which doesn't know a length-framed
+// field skips it by its length prefix.
+func TestFramingSkip(t *testing.T) {
+	frame := interop.Frame{
+		Header: interop.Header{Id: 42, Kind: "skipped"},
+		Body:   "kept",
+	}
+	data, err := frame.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	var bodyOnly struct {
+		Body string `cramberry:"2"`
+	}
+	if err := cramberry.Unmarshal(data, &bodyOnly); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if bodyOnly.Body != frame.Body {
+		t.Errorf("Body = %q, want %q", bodyOnly.Body, frame.Body)
+	}
+}
@@ -0,0 +1,747 @@
+// Code generated by cramberry. DO NOT EDIT.
+// Source: tests/testdata/collections.cram
+
+package interop
+
+import (
+	"github.com/blockberries/cramberry/pkg/cramberry"
+)
+
+// Matrix tests nested slices with ragged inner lengths.
+type Matrix struct {
+	Rows  [][]int32  `cramberry:"1" json:"rows"`
+	Grid  [][]int32  `cramberry:"2" json:"grid"`
+	Words [][]string `cramberry:"3" json:"words"`
+}
+
+// MarshalCramberry encodes the message to binary format using optimized V2 encoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Matrix) MarshalCramberry() ([]byte, error) {
+	w := cramberry.GetWriter()
+	defer cramberry.PutWriter(w)
+
+	m.EncodeTo(w)
+
+	if w.Err() != nil {
+		return nil, w.Err()
+	}
+	return w.BytesCopy(), nil
+}
+
+// EncodeTo encodes the message directly to the writer using V2 format.
+func (m *Matrix) EncodeTo(w *cramberry.Writer) {
+	if len(m.Rows) > 0 {
+		w.WriteCompactTag(1, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(len(m.Rows)))
+		for _, v := range m.Rows {
+			w.WriteUvarint(uint64(len(v)))
+			for _, v1 := range v {
+				w.WriteInt32(v1)
+			}
+		}
+	}
+	if len(m.Grid) > 0 {
+		w.WriteCompactTag(2, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(len(m.Grid)))
+		for _, v := range m.Grid {
+			w.WriteUvarint(uint64(len(v)))
+			for _, v1 := range v {
+				w.WriteInt32(v1)
+			}
+		}
+	}
+	if len(m.Words) > 0 {
+		w.WriteCompactTag(3, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(len(m.Words)))
+		for _, v := range m.Words {
+			w.WriteUvarint(uint64(len(v)))
+			for _, v1 := range v {
+				w.WriteString(v1)
+			}
+		}
+	}
+	w.WriteEndMarker()
+}
+
+// UnmarshalCramberry decodes the message from binary format using optimized V2 decoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Matrix) UnmarshalCramberry(data []byte) error {
+	r := cramberry.NewReaderWithOptions(data, cramberry.DefaultOptions)
+	m.DecodeFrom(r)
+	return r.Err()
+}
+
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Matrix) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
+// DecodeFrom decodes the message from the reader using V2 format.
+func (m *Matrix) DecodeFrom(r *cramberry.Reader) {
+	for {
+		fieldNum, wireType := r.ReadCompactTag()
+		if fieldNum == 0 {
+			break
+		}
+		switch fieldNum {
+		case 1:
+			{
+				n := r.ReadArrayHeader()
+				m.Rows = make([][]int32, n)
+				for i := 0; i < n; i++ {
+					{
+						n := r.ReadArrayHeader()
+						m.Rows[i] = make([]int32, n)
+						for i1 := 0; i1 < n; i1++ {
+							m.Rows[i][i1] = r.ReadInt32()
+						}
+					}
+				}
+			}
+		case 2:
+			{
+				n := r.ReadArrayHeader()
+				m.Grid = make([][]int32, n)
+				for i := 0; i < n; i++ {
+					{
+						n := r.ReadArrayHeader()
+						m.Grid[i] = make([]int32, n)
+						for i1 := 0; i1 < n; i1++ {
+							m.Grid[i][i1] = r.ReadInt32()
+						}
+					}
+				}
+			}
+		case 3:
+			{
+				n := r.ReadArrayHeader()
+				m.Words = make([][]string, n)
+				for i := 0; i < n; i++ {
+					{
+						n := r.ReadArrayHeader()
+						m.Words[i] = make([]string, n)
+						for i1 := 0; i1 < n; i1++ {
+							m.Words[i][i1] = r.ReadString()
+						}
+					}
+				}
+			}
+		default:
+			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Matrix")
+			r.SkipValueV2(wireType)
+		}
+		if r.Err() != nil {
+			return
+		}
+	}
+}
+
+// Index tests map fields, including maps with collection values.
+type Index struct {
+	Counts   map[string]int32            `cramberry:"1" json:"counts"`
+	Postings map[string][]int32          `cramberry:"2" json:"postings"`
+	Nested   map[string]map[string]int32 `cramberry:"3" json:"nested"`
+}
+
+// MarshalCramberry encodes the message to binary format using optimized V2 encoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Index) MarshalCramberry() ([]byte, error) {
+	w := cramberry.GetWriter()
+	defer cramberry.PutWriter(w)
+
+	m.EncodeTo(w)
+
+	if w.Err() != nil {
+		return nil, w.Err()
+	}
+	return w.BytesCopy(), nil
+}
+
+// EncodeTo encodes the message directly to the writer using V2 format.
+func (m *Index) EncodeTo(w *cramberry.Writer) {
+	if len(m.Counts) > 0 {
+		w.WriteCompactTag(1, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(len(m.Counts)))
+		cramberry.ForEachMapEntry(w, m.Counts, func(k string, v int32) {
+			w.WriteString(k)
+			w.WriteInt32(v)
+		})
+	}
+	if len(m.Postings) > 0 {
+		w.WriteCompactTag(2, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(len(m.Postings)))
+		cramberry.ForEachMapEntry(w, m.Postings, func(k string, v []int32) {
+			w.WriteString(k)
+			w.WriteUvarint(uint64(len(v)))
+			for _, v1 := range v {
+				w.WriteInt32(v1)
+			}
+		})
+	}
+	if len(m.Nested) > 0 {
+		w.WriteCompactTag(3, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(len(m.Nested)))
+		cramberry.ForEachMapEntry(w, m.Nested, func(k string, v map[string]int32) {
+			w.WriteString(k)
+			w.WriteUvarint(uint64(len(v)))
+			cramberry.ForEachMapEntry(w, v, func(k1 string, v1 int32) {
+				w.WriteString(k1)
+				w.WriteInt32(v1)
+			})
+		})
+	}
+	w.WriteEndMarker()
+}
+
+// UnmarshalCramberry decodes the message from binary format using optimized V2 decoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Index) UnmarshalCramberry(data []byte) error {
+	r := cramberry.NewReaderWithOptions(data, cramberry.DefaultOptions)
+	m.DecodeFrom(r)
+	return r.Err()
+}
+
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Index) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
+// DecodeFrom decodes the message from the reader using V2 format.
+func (m *Index) DecodeFrom(r *cramberry.Reader) {
+	for {
+		fieldNum, wireType := r.ReadCompactTag()
+		if fieldNum == 0 {
+			break
+		}
+		switch fieldNum {
+		case 1:
+			{
+				n := r.ReadMapHeader()
+				m.Counts = make(map[string]int32, n)
+				_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {
+					var k string
+					k = r.ReadString()
+					var v int32
+					v = r.ReadInt32()
+					m.Counts[k] = v
+					return nil
+				})
+			}
+		case 2:
+			{
+				n := r.ReadMapHeader()
+				m.Postings = make(map[string][]int32, n)
+				_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {
+					var k string
+					k = r.ReadString()
+					var v []int32
+					{
+						n := r.ReadArrayHeader()
+						v = make([]int32, n)
+						for i1 := 0; i1 < n; i1++ {
+							v[i1] = r.ReadInt32()
+						}
+					}
+					m.Postings[k] = v
+					return nil
+				})
+			}
+		case 3:
+			{
+				n := r.ReadMapHeader()
+				m.Nested = make(map[string]map[string]int32, n)
+				_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {
+					var k string
+					k = r.ReadString()
+					var v map[string]int32
+					{
+						n := r.ReadMapHeader()
+						v = make(map[string]int32, n)
+						_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {
+							var k1 string
+							k1 = r.ReadString()
+							var v1 int32
+							v1 = r.ReadInt32()
+							v[k1] = v1
+							return nil
+						})
+					}
+					m.Nested[k] = v
+					return nil
+				})
+			}
+		default:
+			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Index")
+			r.SkipValueV2(wireType)
+		}
+		if r.Err() != nil {
+			return
+		}
+	}
+}
+
+// Digest tests fixed-size byte arrays, which encode as raw bytes.
+type Digest struct {
+	Sum       [32]uint8 `cramberry:"1" json:"sum"`
+	Crc       *[4]uint8 `cramberry:"2,omitempty" json:"crc,omitempty"`
+	Algorithm string    `cramberry:"3" json:"algorithm"`
+}
+
+// MarshalCramberry encodes the message to binary format using optimized V2 encoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Digest) MarshalCramberry() ([]byte, error) {
+	w := cramberry.GetWriter()
+	defer cramberry.PutWriter(w)
+
+	m.EncodeTo(w)
+
+	if w.Err() != nil {
+		return nil, w.Err()
+	}
+	return w.BytesCopy(), nil
+}
+
+// EncodeTo encodes the message directly to the writer using V2 format.
+func (m *Digest) EncodeTo(w *cramberry.Writer) {
+	w.WriteCompactTag(1, cramberry.WireTypeV2Bytes)
+	w.WriteBytes(m.Sum[:])
+	if m.Crc != nil {
+		w.WriteCompactTag(2, cramberry.WireTypeV2Bytes)
+		w.WriteBytes(m.Crc[:])
+	}
+	if m.Algorithm != "" {
+		w.WriteCompactTag(3, cramberry.WireTypeV2Bytes)
+		w.WriteString(m.Algorithm)
+	}
+	w.WriteEndMarker()
+}
+
+// UnmarshalCramberry decodes the message from binary format using optimized V2 decoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Digest) UnmarshalCramberry(data []byte) error {
+	r := cramberry.NewReaderWithOptions(data, cramberry.DefaultOptions)
+	m.DecodeFrom(r)
+	return r.Err()
+}
+
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Digest) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
+// DecodeFrom decodes the message from the reader using V2 format.
+func (m *Digest) DecodeFrom(r *cramberry.Reader) {
+	for {
+		fieldNum, wireType := r.ReadCompactTag()
+		if fieldNum == 0 {
+			break
+		}
+		switch fieldNum {
+		case 1:
+			r.ReadBytesInto(m.Sum[:])
+		case 2:
+			var tmp [4]uint8
+			r.ReadBytesInto(tmp[:])
+			m.Crc = &tmp
+		case 3:
+			m.Algorithm = r.ReadString()
+		default:
+			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Digest")
+			r.SkipValueV2(wireType)
+		}
+		if r.Err() != nil {
+			return
+		}
+	}
+}
+
+// Directory tests collections of pointers, which may hold nil elements.
+type Directory struct {
+	Entries map[string]*Digest `cramberry:"1" json:"entries"`
+	History []*Digest          `cramberry:"2" json:"history"`
+}
+
+// MarshalCramberry encodes the message to binary format using optimized V2 encoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Directory) MarshalCramberry() ([]byte, error) {
+	w := cramberry.GetWriter()
+	defer cramberry.PutWriter(w)
+
+	m.EncodeTo(w)
+
+	if w.Err() != nil {
+		return nil, w.Err()
+	}
+	return w.BytesCopy(), nil
+}
+
+// EncodeTo encodes the message directly to the writer using V2 format.
+func (m *Directory) EncodeTo(w *cramberry.Writer) {
+	if len(m.Entries) > 0 {
+		w.WriteCompactTag(1, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(len(m.Entries)))
+		cramberry.ForEachMapEntry(w, m.Entries, func(k string, v *Digest) {
+			w.WriteString(k)
+			if v == nil {
+				w.WriteNil()
+			} else {
+				w.WritePresent()
+				v.EncodeTo(w)
+			}
+		})
+	}
+	if len(m.History) > 0 {
+		w.WriteCompactTag(2, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(len(m.History)))
+		for _, v := range m.History {
+			if v == nil {
+				w.WriteNil()
+			} else {
+				w.WritePresent()
+				v.EncodeTo(w)
+			}
+		}
+	}
+	w.WriteEndMarker()
+}
+
+// UnmarshalCramberry decodes the message from binary format using optimized V2 decoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Directory) UnmarshalCramberry(data []byte) error {
+	r := cramberry.NewReaderWithOptions(data, cramberry.DefaultOptions)
+	m.DecodeFrom(r)
+	return r.Err()
+}
+
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Directory) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
+// DecodeFrom decodes the message from the reader using V2 format.
+func (m *Directory) DecodeFrom(r *cramberry.Reader) {
+	for {
+		fieldNum, wireType := r.ReadCompactTag()
+		if fieldNum == 0 {
+			break
+		}
+		switch fieldNum {
+		case 1:
+			{
+				n := r.ReadMapHeader()
+				m.Entries = make(map[string]*Digest, n)
+				_ = r.ReadMapEntries(n, func(r *cramberry.Reader) error {
+					var k string
+					k = r.ReadString()
+					var v *Digest
+					if !r.ReadNil() {
+						var v1 Digest
+						v1.DecodeFrom(r)
+						v = &v1
+					}
+					m.Entries[k] = v
+					return nil
+				})
+			}
+		case 2:
+			{
+				n := r.ReadArrayHeader()
+				m.History = make([]*Digest, n)
+				for i := 0; i < n; i++ {
+					if !r.ReadNil() {
+						var v1 Digest
+						v1.DecodeFrom(r)
+						m.History[i] = &v1
+					}
+				}
+			}
+		default:
+			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Directory")
+			r.SkipValueV2(wireType)
+		}
+		if r.Err() != nil {
+			return
+		}
+	}
+}
+
+// Archive tests optional message fields, which stay nil when absent.
+type Archive struct {
+	Latest   *Digest `cramberry:"1,omitempty" json:"latest,omitempty"`
+	Previous *Digest `cramberry:"2" json:"previous"`
+	Name     string  `cramberry:"3" json:"name"`
+}
+
+// MarshalCramberry encodes the message to binary format using optimized V2 encoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Archive) MarshalCramberry() ([]byte, error) {
+	w := cramberry.GetWriter()
+	defer cramberry.PutWriter(w)
+
+	m.EncodeTo(w)
+
+	if w.Err() != nil {
+		return nil, w.Err()
+	}
+	return w.BytesCopy(), nil
+}
+
+// EncodeTo encodes the message directly to the writer using V2 format.
+func (m *Archive) EncodeTo(w *cramberry.Writer) {
+	if m.Latest != nil {
+		w.WriteCompactTag(1, cramberry.WireTypeV2Bytes)
+		m.Latest.EncodeTo(w)
+	}
+	if m.Previous != nil {
+		w.WriteCompactTag(2, cramberry.WireTypeV2Bytes)
+		m.Previous.EncodeTo(w)
+	}
+	if m.Name != "" {
+		w.WriteCompactTag(3, cramberry.WireTypeV2Bytes)
+		w.WriteString(m.Name)
+	}
+	w.WriteEndMarker()
+}
+
+// UnmarshalCramberry decodes the message from binary format using optimized V2 decoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Archive) UnmarshalCramberry(data []byte) error {
+	r := cramberry.NewReaderWithOptions(data, cramberry.DefaultOptions)
+	m.DecodeFrom(r)
+	return r.Err()
+}
+
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Archive) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
+// DecodeFrom decodes the message from the reader using V2 format.
+func (m *Archive) DecodeFrom(r *cramberry.Reader) {
+	for {
+		fieldNum, wireType := r.ReadCompactTag()
+		if fieldNum == 0 {
+			break
+		}
+		switch fieldNum {
+		case 1:
+			var tmp Digest
+			tmp.DecodeFrom(r)
+			m.Latest = &tmp
+		case 2:
+			{
+				var v Digest
+				v.DecodeFrom(r)
+				m.Previous = &v
+			}
+		case 3:
+			m.Name = r.ReadString()
+		default:
+			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Archive")
+			r.SkipValueV2(wireType)
+		}
+		if r.Err() != nil {
+			return
+		}
+	}
+}
+
+// Settings tests ordered maps, which keep their insertion order on the wire.
+type Settings struct {
+	Values cramberry.OrderedMap[string, string]  `cramberry:"1" json:"values"`
+	Ranges cramberry.OrderedMap[string, []int32] `cramberry:"2" json:"ranges"`
+}
+
+// MarshalCramberry encodes the message to binary format using optimized V2 encoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Settings) MarshalCramberry() ([]byte, error) {
+	w := cramberry.GetWriter()
+	defer cramberry.PutWriter(w)
+
+	m.EncodeTo(w)
+
+	if w.Err() != nil {
+		return nil, w.Err()
+	}
+	return w.BytesCopy(), nil
+}
+
+// EncodeTo encodes the message directly to the writer using V2 format.
+func (m *Settings) EncodeTo(w *cramberry.Writer) {
+	if m.Values.Len() > 0 {
+		w.WriteCompactTag(1, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(m.Values.Len()))
+		for k, v := range m.Values.All() {
+			w.WriteString(k)
+			w.WriteString(v)
+		}
+	}
+	if m.Ranges.Len() > 0 {
+		w.WriteCompactTag(2, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(m.Ranges.Len()))
+		for k, v := range m.Ranges.All() {
+			w.WriteString(k)
+			w.WriteUvarint(uint64(len(v)))
+			for _, v1 := range v {
+				w.WriteInt32(v1)
+			}
+		}
+	}
+	w.WriteEndMarker()
+}
+
+// UnmarshalCramberry decodes the message from binary format using optimized V2 decoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Settings) UnmarshalCramberry(data []byte) error {
+	r := cramberry.NewReaderWithOptions(data, cramberry.DefaultOptions)
+	m.DecodeFrom(r)
+	return r.Err()
+}
+
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Settings) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
+// DecodeFrom decodes the message from the reader using V2 format.
+func (m *Settings) DecodeFrom(r *cramberry.Reader) {
+	for {
+		fieldNum, wireType := r.ReadCompactTag()
+		if fieldNum == 0 {
+			break
+		}
+		switch fieldNum {
+		case 1:
+			m.Values.Clear()
+			_ = r.ReadMap(func(r *cramberry.Reader) error {
+				var k string
+				k = r.ReadString()
+				var v string
+				v = r.ReadString()
+				m.Values.Set(k, v)
+				return nil
+			})
+		case 2:
+			m.Ranges.Clear()
+			_ = r.ReadMap(func(r *cramberry.Reader) error {
+				var k string
+				k = r.ReadString()
+				var v []int32
+				{
+					n := r.ReadArrayHeader()
+					v = make([]int32, n)
+					for i1 := 0; i1 < n; i1++ {
+						v[i1] = r.ReadInt32()
+					}
+				}
+				m.Ranges.Set(k, v)
+				return nil
+			})
+		default:
+			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Settings")
+			r.SkipValueV2(wireType)
+		}
+		if r.Err() != nil {
+			return
+		}
+	}
+}
+
+// Team tests delta-encoded lists of sorted IDs.
+type Team struct {
+	Members []int64 `cramberry:"1" json:"members"`
+	Scores  []int64 `cramberry:"2" json:"scores"`
+}
+
+// MarshalCramberry encodes the message to binary format using optimized V2 encoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Team) MarshalCramberry() ([]byte, error) {
+	w := cramberry.GetWriter()
+	defer cramberry.PutWriter(w)
+
+	m.EncodeTo(w)
+
+	if w.Err() != nil {
+		return nil, w.Err()
+	}
+	return w.BytesCopy(), nil
+}
+
+// EncodeTo encodes the message directly to the writer using V2 format.
+func (m *Team) EncodeTo(w *cramberry.Writer) {
+	if len(m.Members) > 0 {
+		w.WriteCompactTag(1, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(len(m.Members)))
+		w.WritePackedDeltaInt64(m.Members)
+	}
+	if len(m.Scores) > 0 {
+		w.WriteCompactTag(2, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(len(m.Scores)))
+		for _, v := range m.Scores {
+			w.WriteInt64(v)
+		}
+	}
+	w.WriteEndMarker()
+}
+
+// UnmarshalCramberry decodes the message from binary format using optimized V2 decoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Team) UnmarshalCramberry(data []byte) error {
+	r := cramberry.NewReaderWithOptions(data, cramberry.DefaultOptions)
+	m.DecodeFrom(r)
+	return r.Err()
+}
+
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Team) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
+// DecodeFrom decodes the message from the reader using V2 format.
+func (m *Team) DecodeFrom(r *cramberry.Reader) {
+	for {
+		fieldNum, wireType := r.ReadCompactTag()
+		if fieldNum == 0 {
+			break
+		}
+		switch fieldNum {
+		case 1:
+			m.Members = r.ReadPackedDeltaInt64(r.ReadArrayHeader())
+		case 2:
+			{
+				n := r.ReadArrayHeader()
+				m.Scores = make([]int64, n)
+				for i := 0; i < n; i++ {
+					m.Scores[i] = r.ReadInt64()
+				}
+			}
+		default:
+			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Team")
+			r.SkipValueV2(wireType)
+		}
+		if r.Err() != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Code generated by cramberry. DO NOT EDIT.
+// Source: tests/testdata/dynamic.cram
+
+package interop
+
+import (
+	"github.com/blockberries/cramberry/pkg/cramberry"
+)
+
+// Envelope carries payloads of any registered type.
+type Envelope struct {
+	Topic   string `cramberry:"1" json:"topic"`
+	Payload any    `cramberry:"2" json:"payload"`
+	Items   []any  `cramberry:"3" json:"items"`
+}
+
+// MarshalCramberry encodes the message to binary format using optimized V2 encoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Envelope) MarshalCramberry() ([]byte, error) {
+	w := cramberry.GetWriter()
+	defer cramberry.PutWriter(w)
+
+	m.EncodeTo(w)
+
+	if w.Err() != nil {
+		return nil, w.Err()
+	}
+	return w.BytesCopy(), nil
+}
+
+// EncodeTo encodes the message directly to the writer using V2 format.
+func (m *Envelope) EncodeTo(w *cramberry.Writer) {
+	if m.Topic != "" {
+		w.WriteCompactTag(1, cramberry.WireTypeV2Bytes)
+		w.WriteString(m.Topic)
+	}
+	if m.Payload != nil {
+		w.WriteCompactTag(2, cramberry.WireTypeV2Bytes)
+		w.WriteAny(m.Payload)
+	}
+	if len(m.Items) > 0 {
+		w.WriteCompactTag(3, cramberry.WireTypeV2Bytes)
+		w.WriteUvarint(uint64(len(m.Items)))
+		for _, v := range m.Items {
+			w.WriteAny(v)
+		}
+	}
+	w.WriteEndMarker()
+}
+
+// UnmarshalCramberry decodes the message from binary format using optimized V2 decoding.
+// This method uses direct field access without reflection for maximum performance.
+func (m *Envelope) UnmarshalCramberry(data []byte) error {
+	r := cramberry.NewReaderWithOptions(data, cramberry.DefaultOptions)
+	m.DecodeFrom(r)
+	return r.Err()
+}
+
+// DecodeCramberry decodes the message from r and leaves r positioned after
+// it, so that a message can be read out of a buffer shared with other
+// values. Errors are recorded in r.
+func (m *Envelope) DecodeCramberry(r *cramberry.Reader) {
+	m.DecodeFrom(r)
+}
+
+// DecodeFrom decodes the message from the reader using V2 format.
+func (m *Envelope) DecodeFrom(r *cramberry.Reader) {
+	for {
+		fieldNum, wireType := r.ReadCompactTag()
+		if fieldNum == 0 {
+			break
+		}
+		switch fieldNum {
+		case 1:
+			m.Topic = r.ReadString()
+		case 2:
+			m.Payload = r.ReadAny()
+		case 3:
+			n := r.ReadArrayHeader()
+			if r.Err() != nil {
+				return
+			}
+			m.Items = make([]any, n)
+			for i := 0; i < n; i++ {
+				m.Items[i] = r.ReadAny()
+			}
+		default:
+			// Skip unknown field for forward compatibility
+			r.Warn(cramberry.WarningUnknownField, fieldNum, "skipped unknown field of Envelope")
+			r.SkipValueV2(wireType)
+		}
+		if r.Err() != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestJSONConverters verifies that a message survives a round trip through
+// ToJSON, encoding/json and FromJSON, and that unset optional fields are
+// left out while optional fields set to zero are kept.
+func TestJSONConverters(t *testing.T) {
+	id := int64(1)<<53 + 1 // not exactly representable as a float64
+	age := int32(0)
+	sent := interop.Profile{
+		Id:      &id,
+		Name:    "ada",
+		Age:     &age,
+		Avatar:  []byte{0xca, 0xfe},
+		Mood:    interop.MoodMoodGrumpy,
+		Home:    interop.Geo{Lat: 51.5, Lng: -0.12},
+		Visited: []interop.Geo{{Lat: 1, Lng: 2}, {Lat: 3, Lng: 4}},
+		Scores:  map[string]int64{"chess": 2100},
+	}
+	sent.Labels.Set("role", "admin")
+	sent.Labels.Set("team", "core")
+	// Ordered maps of nested messages survive too, including those of
+	// messages in lists, which encoding/json converts
+	sent.Home.Tags.Set("city", "london")
+	sent.Home.Tags.Set("zone", "1")
+	sent.Visited[1].Tags.Set("trip", "2024")
+
+	fields := sent.ToJSON()
+	if _, ok := fields["nickname"]; ok {
+		t.Error("unset optional field nickname is in the map")
+	}
+	if _, ok := fields["work"]; ok {
+		t.Error("unset pointer field work is in the map")
+	}
+	if fields["age"] != int32(0) {
+		t.Errorf("age = %#v, want int32(0)", fields["age"])
+	}
+	if home, ok := fields["home"].(map[string]any); !ok || home["lat"] != 51.5 {
+		t.Errorf("home = %#v, want a map from Geo.ToJSON", fields["home"])
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	var decoded map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // keeps int64 values above 2^53 exact
+	if err := dec.Decode(&decoded); err != nil {
+		t.Fatalf("json decode error: %v", err)
+	}
+
+	var got interop.Profile
+	if err := got.FromJSON(decoded); err != nil {
+		t.Fatalf("FromJSON error: %v", err)
+	}
+	if !reflect.DeepEqual(got, sent) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", got, sent)
+	}
+
+	// The map from ToJSON converts back directly as well
+	var direct interop.Profile
+	if err := direct.FromJSON(fields); err != nil {
+		t.Fatalf("FromJSON of ToJSON map error: %v", err)
+	}
+	if !reflect.DeepEqual(direct, sent) {
+		t.Errorf("direct round trip mismatch:\n got %+v\nwant %+v", direct, sent)
+	}
+
+	// A set pointer to a message converts with the message's FromJSON
+	sent.Work = &interop.Geo{Lat: 48.9, Lng: 2.35}
+	sent.Work.Tags.Set("floor", "3")
+	data, err = json.Marshal(sent.ToJSON())
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	decoded = nil
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+	got = interop.Profile{}
+	if err := got.FromJSON(decoded); err != nil {
+		t.Fatalf("FromJSON error: %v", err)
+	}
+	if got.Work == nil || !reflect.DeepEqual(*got.Work, *sent.Work) {
+		t.Errorf("work = %+v, want %+v", got.Work, sent.Work)
+	}
+
+	if err := got.FromJSON(map[string]any{"age": "old"}); err == nil {
+		t.Error("expected an error for a string in an integer field")
+	}
+}
@@ -0,0 +1,97 @@
+package integration
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+func makeLegacyRecord() interop.LegacyRecord {
+	note := "paid by card"
+	return interop.LegacyRecord{
+		Id:       -42,
+		Name:     "invoice",
+		Kind:     interop.LegacyKindRefund,
+		Amount:   19.99,
+		Rate:     0.2,
+		Settled:  true,
+		Primary:  interop.LegacyLine{Sku: "A-1", Quantity: 3},
+		Lines:    []interop.LegacyLine{{Sku: "B-2", Quantity: 1}, {Sku: "C-3"}},
+		Totals:   map[string]int32{"net": 1666, "tax": 333},
+		Note:     &note,
+		Tags:     []string{"eu", "card"},
+		Sequence: 1 << 40,
+	}
+}
+
+// TestLegacyWireRoundTrip verifies that code generated with -wire v1
+// decodes what it encodes.
+func TestLegacyWireRoundTrip(t *testing.T) {
+	sent := makeLegacyRecord()
+	data, err := sent.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	var got interop.LegacyRecord
+	if err := got.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if !reflect.DeepEqual(got, sent) {
+		t.Errorf("got %+v, want %+v", got, sent)
+	}
+}
+
+// TestLegacyWireLayout checks the V1 layout: a length prefix in place of
+// the end marker, and classic tags that ReadTag understands.
+func TestLegacyWireLayout(t *testing.T) {
+	msg := interop.LegacyLine{Sku: "A-1", Quantity: 3}
+	data, err := msg.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	r := cramberry.NewReader(data)
+	end := r.BeginMessage()
+	if end != len(data) {
+		t.Fatalf("length prefix ends at %d, want %d", end, len(data))
+	}
+	if num, wt := r.ReadTag(); num != 1 || wt != cramberry.WireBytes {
+		t.Fatalf("first tag = (%d, %v), want (1, bytes)", num, wt)
+	}
+	if s := r.ReadString(); s != "A-1" {
+		t.Errorf("sku = %q", s)
+	}
+	if num, wt := r.ReadTag(); num != 2 || wt != cramberry.WireVarint {
+		t.Fatalf("second tag = (%d, %v), want (2, varint)", num, wt)
+	}
+	if q := r.ReadUint32(); q != 3 {
+		t.Errorf("quantity = %d", q)
+	}
+	r.EndMessage(end)
+	if r.Err() != nil || len(r.Remaining()) != 0 {
+		t.Errorf("err = %v, %d bytes left", r.Err(), len(r.Remaining()))
+	}
+}
+
+// TestLegacyWireSkipsUnknownFields verifies that an older V1 reader skips
+// the fields it doesn't know, including a whole nested message, a map and a
+// repeated field.
+func TestLegacyWireSkipsUnknownFields(t *testing.T) {
+	sent := makeLegacyRecord()
+	data, err := sent.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	var old interop.LegacyRecordV0
+	if err := old.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	want := interop.LegacyRecordV0{Id: sent.Id, Name: sent.Name, Lines: sent.Lines}
+	if !reflect.DeepEqual(old, want) {
+		t.Errorf("got %+v, want %+v", old, want)
+	}
+}
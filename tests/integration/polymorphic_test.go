@@ -0,0 +1,71 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestInterfaceDispatchRoundtrip verifies the generated MarshalAnimal and
+// UnmarshalAnimal helpers select the concrete type by its type ID.
+func TestInterfaceDispatchRoundtrip(t *testing.T) {
+	animals := []interop.Animal{
+		&interop.Dog{Name: "Rex", Breed: "collie"},
+		&interop.Cat{Name: "Tom", Lives: 9},
+	}
+
+	for _, original := range animals {
+		data, err := interop.MarshalAnimal(original)
+		if err != nil {
+			t.Fatalf("MarshalAnimal(%T) error: %v", original, err)
+		}
+
+		r := cramberry.NewReader(data)
+		if id := r.ReadTypeID(); id != interop.AnimalTypeID(original) {
+			t.Errorf("%T: expected type ID prefix %d, got %d", original, interop.AnimalTypeID(original), id)
+		}
+
+		decoded, err := interop.UnmarshalAnimal(data)
+		if err != nil {
+			t.Fatalf("UnmarshalAnimal(%T) error: %v", original, err)
+		}
+
+		switch want := original.(type) {
+		case *interop.Dog:
+			got, ok := decoded.(*interop.Dog)
+			if !ok {
+				t.Fatalf("expected *Dog, got %T", decoded)
+			}
+			if *got != *want {
+				t.Errorf("Dog mismatch: got %+v, want %+v", got, want)
+			}
+		case *interop.Cat:
+			got, ok := decoded.(*interop.Cat)
+			if !ok {
+				t.Fatalf("expected *Cat, got %T", decoded)
+			}
+			if *got != *want {
+				t.Errorf("Cat mismatch: got %+v, want %+v", got, want)
+			}
+		}
+	}
+}
+
+func TestInterfaceDispatchErrors(t *testing.T) {
+	if _, err := interop.MarshalAnimal(nil); !errors.Is(err, cramberry.ErrNilPointer) {
+		t.Errorf("MarshalAnimal(nil): expected ErrNilPointer, got %v", err)
+	}
+
+	w := cramberry.NewWriter()
+	w.WriteTypeID(999)
+	w.WriteEndMarker()
+	if _, err := interop.UnmarshalAnimal(w.Bytes()); !errors.Is(err, cramberry.ErrUnknownType) {
+		t.Errorf("unknown type ID: expected ErrUnknownType, got %v", err)
+	}
+
+	if _, err := interop.UnmarshalAnimal(nil); err == nil {
+		t.Error("empty input: expected error")
+	}
+}
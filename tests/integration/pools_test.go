@@ -0,0 +1,58 @@
+package integration
+
+import (
+	"reflect"
+	"testing"
+
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestPoolReset verifies that returning a message to its pool clears every
+// field while keeping slice and map storage for reuse.
+func TestPoolReset(t *testing.T) {
+	limit := int32(10)
+	m := interop.GetBatch()
+	*m = interop.Batch{
+		Seq:     42,
+		Source:  "sensor",
+		Keys:    []string{"a", "b", "c"},
+		Payload: []byte{1, 2, 3, 4},
+		Counts:  map[string]int64{"a": 1, "b": 2},
+		Limit:   &limit,
+		Cursor:  interop.Cursor{Offset: 99},
+		Crc:     [4]uint8{0xde, 0xad, 0xbe, 0xef},
+	}
+	keys, payload, counts := m.Keys, m.Payload, m.Counts
+
+	interop.PutBatch(m)
+
+	if m.Seq != 0 || m.Source != "" || m.Limit != nil || m.Cursor != (interop.Cursor{}) || m.Crc != [4]uint8{} {
+		t.Errorf("scalar fields not reset: %+v", m)
+	}
+	if len(m.Keys) != 0 || cap(m.Keys) != cap(keys) {
+		t.Errorf("Keys: len %d cap %d, want len 0 cap %d", len(m.Keys), cap(m.Keys), cap(keys))
+	}
+	if keys[0] != "" {
+		t.Errorf("old Keys elements not zeroed: %q", keys)
+	}
+	if len(m.Payload) != 0 || cap(m.Payload) != cap(payload) {
+		t.Errorf("Payload: len %d cap %d, want len 0 cap %d", len(m.Payload), cap(m.Payload), cap(payload))
+	}
+	if len(m.Counts) != 0 || reflect.ValueOf(m.Counts).Pointer() != reflect.ValueOf(counts).Pointer() {
+		t.Errorf("Counts not emptied in place: %v", m.Counts)
+	}
+
+	// A reset message decodes like a fresh one
+	data, err := (&interop.Batch{Seq: 7, Keys: []string{"x"}}).MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+	if err := m.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if m.Seq != 7 || !reflect.DeepEqual(m.Keys, []string{"x"}) || len(m.Counts) != 0 {
+		t.Errorf("decode into reset message = %+v", m)
+	}
+
+	interop.PutBatch(nil)
+}
@@ -0,0 +1,63 @@
+package integration
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestDecodeWithPresence verifies that code generated with -presence-decode
+// reports the fields present on the wire, agreeing with the reflective
+// UnmarshalPresence.
+func TestDecodeWithPresence(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  interop.SettingsPatch
+		want map[int]bool
+	}{
+		{"empty", interop.SettingsPatch{}, map[int]bool{4: true}},
+		{"some fields", interop.SettingsPatch{Theme: "dark", Notifications: true}, map[int]bool{1: true, 3: true, 4: true}},
+		{"all fields", interop.SettingsPatch{Theme: "light", FontSize: 14, Notifications: true, Limits: interop.SettingsLimits{MaxTabs: 8}}, map[int]bool{1: true, 2: true, 3: true, 4: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.msg.MarshalCramberry()
+			if err != nil {
+				t.Fatalf("MarshalCramberry error: %v", err)
+			}
+
+			var got interop.SettingsPatch
+			present, err := got.DecodeWithPresence(data)
+			if err != nil {
+				t.Fatalf("DecodeWithPresence error: %v", err)
+			}
+			if !maps.Equal(present, tt.want) {
+				t.Errorf("present = %v, want %v", present, tt.want)
+			}
+			if got != tt.msg {
+				t.Errorf("decoded %+v, want %+v", got, tt.msg)
+			}
+
+			var reflected interop.SettingsPatch
+			present, err = cramberry.UnmarshalPresence(data, &reflected)
+			if err != nil {
+				t.Fatalf("UnmarshalPresence error: %v", err)
+			}
+			if !maps.Equal(present, tt.want) {
+				t.Errorf("UnmarshalPresence present = %v, want %v", present, tt.want)
+			}
+		})
+	}
+
+	data, err := tests[1].msg.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+	var got interop.SettingsPatch
+	if present, err := got.DecodeWithPresence(data[:len(data)-1]); err == nil || present != nil {
+		t.Errorf("truncated data: present = %v, err = %v", present, err)
+	}
+}
@@ -0,0 +1,46 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestRequiredGetters verifies that the getters of required fields report
+// fields missing from decoded data and return the value of present ones,
+// including zero values.
+func TestRequiredGetters(t *testing.T) {
+	amount := int64(0)
+	sent := interop.Transfer{Amount: &amount, Memo: "rent"}
+	data, err := sent.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	var got interop.Transfer
+	if err := got.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+
+	if v, err := got.GetAmountErr(); err != nil || v != 0 {
+		t.Errorf("GetAmountErr() = %d, %v, want 0, nil", v, err)
+	}
+	if v := got.MustGetAmount(); v != 0 {
+		t.Errorf("MustGetAmount() = %d, want 0", v)
+	}
+
+	v, err := got.GetFromErr()
+	var verr *cramberry.ValidationError
+	if !errors.As(err, &verr) || verr.Field != "from" || v != "" {
+		t.Errorf("GetFromErr() = %q, %v, want a validation error for from", v, err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("MustGetFrom did not panic for a missing required field")
+		}
+	}()
+	got.MustGetFrom()
+}
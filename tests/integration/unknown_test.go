@@ -0,0 +1,70 @@
+package integration
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestPreserveUnknownFields verifies that a reader built with an older
+// schema can decode, modify and re-encode a message without dropping the
+// fields it doesn't know about.
+func TestPreserveUnknownFields(t *testing.T) {
+	sent := interop.TicketV2{
+		Id:       7,
+		Owner:    "alice",
+		Priority: 0.75,
+		Digest:   []byte{0xca, 0xfe},
+		Note:     interop.TicketNote{Text: "seen twice"},
+		Flags:    3,
+	}
+	data, err := sent.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+
+	// An unmodified message re-encodes to the same bytes
+	var proxy interop.TicketV1
+	if err := proxy.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if proxy.Id != 7 || proxy.Owner != "alice" {
+		t.Fatalf("known fields = %+v", proxy)
+	}
+	forwarded, err := proxy.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+	if !bytes.Equal(forwarded, data) {
+		t.Errorf("re-encoded = %x, want %x", forwarded, data)
+	}
+
+	// Changes to known fields keep the unknown ones
+	proxy.Owner = "dave"
+	forwarded, err = proxy.MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+	var received interop.TicketV2
+	if err := received.UnmarshalCramberry(forwarded); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	want := sent
+	want.Owner = "dave"
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("received %+v, want %+v", received, want)
+	}
+
+	// Decoding again replaces the unknown fields rather than adding to them
+	if err := proxy.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if err := proxy.UnmarshalCramberry(data); err != nil {
+		t.Fatalf("UnmarshalCramberry error: %v", err)
+	}
+	if forwarded, _ := proxy.MarshalCramberry(); !bytes.Equal(forwarded, data) {
+		t.Errorf("re-encoded after two decodes = %x, want %x", forwarded, data)
+	}
+}
@@ -0,0 +1,61 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestValidateOnDecode verifies that code generated with -validate-on-decode
+// rejects decoded data missing a required field or holding an out-of-range
+// value, while code generated without it leaves the check to the caller.
+func TestValidateOnDecode(t *testing.T) {
+	carrier := "post"
+	tests := []struct {
+		name  string
+		msg   interop.Shipment
+		field string // field the validation error names, empty if valid
+	}{
+		{"valid", interop.Shipment{Carrier: &carrier, Parcels: 3}, ""},
+		{"missing required field", interop.Shipment{Parcels: 3, Note: "no carrier"}, "carrier"},
+		{"out of range", interop.Shipment{Carrier: &carrier, Parcels: 51}, "parcels"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.msg.MarshalCramberry()
+			if err != nil {
+				t.Fatalf("MarshalCramberry error: %v", err)
+			}
+
+			var got interop.Shipment
+			err = got.UnmarshalCramberry(data)
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("UnmarshalCramberry error: %v", err)
+				}
+				return
+			}
+			var verr *cramberry.ValidationError
+			if !errors.As(err, &verr) || verr.Field != tt.field {
+				t.Errorf("UnmarshalCramberry error = %v, want a validation error for %s", err, tt.field)
+			}
+		})
+	}
+
+	// Without the option, a missing required field decodes
+	amount := int64(5)
+	data, err := (&interop.Transfer{Amount: &amount}).MarshalCramberry()
+	if err != nil {
+		t.Fatalf("MarshalCramberry error: %v", err)
+	}
+	var transfer interop.Transfer
+	if err := transfer.UnmarshalCramberry(data); err != nil {
+		t.Errorf("UnmarshalCramberry without -validate-on-decode: %v", err)
+	}
+	if transfer.Validate() == nil {
+		t.Error("Validate accepted a transfer missing from")
+	}
+}
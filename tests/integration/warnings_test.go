@@ -0,0 +1,43 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestGeneratedDecodeWarnings verifies that generated decoders report skipped
+// unknown fields to the OnWarning callback.
+func TestGeneratedDecodeWarnings(t *testing.T) {
+	type dogV2 struct {
+		Name  string `cramberry:"1"`
+		Breed string `cramberry:"2"`
+		Age   int32  `cramberry:"3"`
+	}
+	data, err := cramberry.Marshal(dogV2{Name: "Rex", Age: 4})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	var warnings []cramberry.Warning
+	opts := cramberry.DefaultOptions
+	opts.Hooks = &cramberry.Hooks{OnWarning: func(w cramberry.Warning) { warnings = append(warnings, w) }}
+
+	var dog interop.Dog
+	r := cramberry.NewReaderWithOptions(data, opts)
+	dog.DecodeFrom(r)
+	if err := r.Err(); err != nil {
+		t.Fatalf("DecodeFrom error: %v", err)
+	}
+	if dog.Name != "Rex" {
+		t.Errorf("Name = %q, want %q", dog.Name, "Rex")
+	}
+
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", warnings)
+	}
+	if w := warnings[0]; w.Code != cramberry.WarningUnknownField || w.FieldNum != 3 {
+		t.Errorf("warning = %v, want unknown field 3", w)
+	}
+}
@@ -0,0 +1,59 @@
+package integration
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/blockberries/cramberry/pkg/cramberry"
+	interop "github.com/blockberries/cramberry/tests/integration/gen"
+)
+
+// TestOptionalWrapperPresence verifies that generated Optional fields keep
+// set and unset apart, including values set to zero.
+func TestOptionalWrapperPresence(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  interop.Reading
+	}{
+		{"unset", interop.Reading{Sensor: "a"}},
+		{"set", interop.Reading{
+			Sensor:     "b",
+			Level:      cramberry.Some[int32](-7),
+			Celsius:    cramberry.Some(21.5),
+			Note:       cramberry.Some("ok"),
+			Calibrated: cramberry.Some(true),
+		}},
+		{"set to zero", interop.Reading{
+			Level:      cramberry.Some[int32](0),
+			Celsius:    cramberry.Some(0.0),
+			Note:       cramberry.Some(""),
+			Calibrated: cramberry.Some(false),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.msg.MarshalCramberry()
+			if err != nil {
+				t.Fatalf("MarshalCramberry error: %v", err)
+			}
+
+			var got interop.Reading
+			if err := got.UnmarshalCramberry(data); err != nil {
+				t.Fatalf("UnmarshalCramberry error: %v", err)
+			}
+			if got != tt.msg {
+				t.Errorf("roundtrip = %+v, want %+v", got, tt.msg)
+			}
+
+			// The reflective path writes the same bytes
+			reflected, err := cramberry.Marshal(&tt.msg)
+			if err != nil {
+				t.Fatalf("Marshal error: %v", err)
+			}
+			if !bytes.Equal(reflected, data) {
+				t.Errorf("reflective encoding = %x, generated = %x", reflected, data)
+			}
+		})
+	}
+}